@@ -0,0 +1,38 @@
+// Command fakestripe serves the in-memory fake Stripe API for offline development.
+//
+// Point the backend at it with STRIPE_API_BASE_URL, STRIPE_SECRET_KEY=sk_test_fake
+// and STRIPE_WEBHOOK_SECRET=whsec_fake. Opening a checkout URL completes the
+// checkout and delivers the webhooks to the backend.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"react-golang-starter/internal/testutil"
+)
+
+func main() {
+	addr := os.Getenv("FAKE_STRIPE_ADDR")
+	if addr == "" {
+		addr = "localhost:12111"
+	}
+
+	fake := testutil.NewFakeStripe()
+	fake.BaseURL = "http://" + addr
+	fake.WebhookURL = os.Getenv("FAKE_STRIPE_WEBHOOK_URL")
+	if fake.WebhookURL == "" {
+		fake.WebhookURL = "http://localhost:8080/api/webhooks/stripe"
+	}
+	if secret := os.Getenv("STRIPE_WEBHOOK_SECRET"); secret != "" {
+		fake.WebhookSecret = secret
+	}
+
+	log.Printf("fake Stripe listening on %s, delivering webhooks to %s", fake.BaseURL, fake.WebhookURL)
+	server := &http.Server{Addr: addr, Handler: fake.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"react-golang-starter/internal/ai"
+	"react-golang-starter/internal/audit"
 	"react-golang-starter/internal/auth"
 	"react-golang-starter/internal/cache"
 	"react-golang-starter/internal/config"
@@ -22,6 +23,7 @@ import (
 	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/ratelimit"
 	"react-golang-starter/internal/services"
+	"react-golang-starter/internal/storage"
 	"react-golang-starter/internal/stripe"
 	"react-golang-starter/internal/websocket"
 
@@ -277,6 +279,38 @@ func main() {
 			Msg("stripe service initialized")
 	}
 
+	// Initialize the entitlements plan catalog (built-in plans < entitlements.yaml < plan_entitlements table)
+	planCatalog, err := services.LoadPlanCatalog("")
+	if err != nil {
+		zerologlog.Warn().Err(err).Msg("failed to load plan catalog file, using built-in plans")
+	}
+	planCatalog.AssignPrice(string(models.OrgPlanPro), stripeConfig.PremiumPriceID)
+	planCatalog.AssignPrice(string(models.OrgPlanEnterprise), stripeConfig.EnterprisePriceID)
+	if err := planCatalog.LoadFromDB(context.Background(), database.DB); err != nil {
+		zerologlog.Warn().Err(err).Msg("failed to load plan entitlements from database")
+	}
+	services.SetEntitlementsService(services.NewEntitlementsService(database.DB, planCatalog))
+
+	// Initialize usage costs, the units plan limits are expressed in (config.yaml < usage_costs table),
+	// and the AI model prices used to estimate the cost of AI token usage
+	usageCosts := services.DefaultUsageCostTable()
+	if appConfig, err := config.LoadWithFile(os.Getenv("CONFIG_FILE")); err != nil {
+		zerologlog.Warn().Err(err).Msg("failed to load config file, charging 1 unit per usage event")
+	} else {
+		if usageCosts, err = services.NewUsageCostTable(appConfig.UsageCosts); err != nil {
+			zerologlog.Warn().Err(err).Msg("invalid usage costs in config file, charging 1 unit per usage event")
+		}
+		if aiPricing, err := services.NewAIPricingTable(appConfig.AIPricing); err != nil {
+			zerologlog.Warn().Err(err).Msg("invalid ai pricing in config file, using default model prices")
+		} else {
+			services.SetAIPricingTable(aiPricing)
+		}
+	}
+	if err := usageCosts.LoadFromDB(context.Background(), database.DB); err != nil {
+		zerologlog.Warn().Err(err).Msg("failed to load usage costs from database")
+	}
+	services.SetUsageCostTable(usageCosts)
+
 	// Initialize AI service (Gemini)
 	aiConfig := ai.LoadConfig()
 	if err := ai.Initialize(aiConfig); err != nil {
@@ -316,12 +350,61 @@ func main() {
 	usageService.SetHub(wsHub)
 	zerologlog.Info().Msg("usage service initialized")
 
+	// Flush shared usage counters (Redis) to usage periods from the job queue
+	jobs.SetUsageCounterFlusher(usageService)
+
+	// Rebuild usage rollups and enforce usage retention from the job queue
+	jobs.SetUsageMaintainer(usageService)
+
+	// Deliver usage alerts to organization webhooks from the job queue
+	jobs.SetUsageAlertWebhookDeliverer(usageService)
+
+	// Initialize quota enforcement on top of the usage service (nil disables enforcement)
+	var quotaService *services.QuotaService
+	if services.QuotaEnforcementEnabled() {
+		quotaService = services.NewQuotaService(database.DB, usageService)
+		zerologlog.Info().Msg("usage quota enforcement enabled")
+	}
+
+	// Initialize organization service (created here so the deletion purge can be registered with the job queue)
+	orgService := services.NewOrgService(database.DB)
+	orgService.SetHub(wsHub) // Enable WebSocket broadcasts for org/member updates
+	orgService.SetDeletionConfig(services.LoadOrgDeletionConfig())
+	if stripe.IsAvailable() {
+		orgService.SetSubscriptionCanceler(stripe.GetService())
+		orgService.SetSeatQuantityUpdater(stripe.GetService())
+	}
+
+	// Debounced seat quantity syncs after membership changes
+	jobs.SetSeatQuantitySyncer(orgService)
+
+	// Purge organizations past their deletion grace period from the job queue
+	jobs.SetOrganizationPurger(orgService)
+
 	// Initialize cache broadcaster for real-time cache invalidation via WebSocket
 	cache.InitBroadcaster(&HubBroadcaster{hub: wsHub})
 
 	// Set WebSocket hub for Stripe billing events
 	stripe.SetHub(wsHub)
 
+	// Process queued Stripe webhook events with the billing handlers
+	jobs.SetStripeEventHandler(stripe.NewEventHandler())
+	jobs.SetStripeEventSweeper(stripe.NewEventSweeper())
+
+	// Grace period and reminders for failed payments
+	stripe.ConfigureDunning(stripeConfig)
+	if stripe.IsAvailable() {
+		jobs.SetDunningProcessor(stripe.NewDunningProcessor(database.DB))
+	}
+
+	// Downgrade trials that ended without payment if the Stripe webhook was missed
+	jobs.SetTrialExpirer(stripe.NewTrialProcessor(database.DB, stripe.GetService()))
+
+	// Report metered usage to Stripe billing meters when any are configured
+	if stripe.IsAvailable() && stripeConfig.HasMeters() {
+		jobs.SetMeteredUsageReporter(stripe.NewUsageReporter(database.DB, stripe.GetService(), stripeConfig))
+	}
+
 	// Create Chi router
 	r := chi.NewRouter()
 
@@ -344,9 +427,9 @@ func main() {
 	// CORS middleware for React frontend (MUST be before rate limiting so preflight OPTIONS requests work)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   config.GetAllowedOrigins(),
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Requested-With", "X-Request-ID", "Origin"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Requested-With", "X-Request-ID", "Origin", "Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset"},
+		ExposedHeaders:   []string{"Link", "Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Metadata", "Upload-Expires", "X-File-Id"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -376,7 +459,8 @@ func main() {
 	}
 
 	// Request body size limit middleware (prevents memory exhaustion attacks)
-	r.Use(middleware.MaxBodySize(middleware.DefaultMaxBodySize))
+	// File uploads are exempt: they stream and enforce the maximum file size themselves
+	r.Use(middleware.MaxBodySizeExcept(middleware.DefaultMaxBodySize, "/api/files/upload", "/api/v1/files/upload"))
 	zerologlog.Info().Int64("max_bytes", middleware.DefaultMaxBodySize).Msg("request body size limit enabled")
 
 	// Initialize OAuth
@@ -389,10 +473,12 @@ func main() {
 	}
 
 	// Initialize file service
-	fileService, err := services.NewFileService()
+	fileService, err := services.NewFileService(storage.LoadConfig())
 	if err != nil {
 		zerologlog.Fatal().Err(err).Msg("failed to initialize file service")
 	}
+	zerologlog.Info().Str("storage_type", fileService.GetStorageType()).Msg("file storage initialized")
+	jobs.SetFileUploadCleaner(fileService)
 
 	// Initialize the service with dependencies
 	appService := handlers.NewService()
@@ -411,7 +497,7 @@ func main() {
 	r.Get("/ws", websocket.Handler(wsHub))
 
 	// Routes
-	setupRoutes(r, rateLimitConfig, stripeConfig, appService, fileService, wsHub, usageService)
+	setupRoutes(r, rateLimitConfig, stripeConfig, appService, fileService, wsHub, usageService, quotaService, orgService)
 
 	// Create server with timeouts to prevent slowloris and other DoS attacks
 	server := &http.Server{
@@ -441,6 +527,17 @@ func main() {
 	// Start periodic export cleanup (hourly)
 	jobs.StartExportCleanup(ctx, database.DB, 1*time.Hour)
 
+	// Flush in-process usage counters, and the shared ones when the flush job is not running
+	usageService.StartCounterFlush(ctx, jobsConfig.UsageFlushInterval, !jobs.IsAvailable() || jobsConfig.UsageFlushInterval == 0)
+
+	// Without the job queue, rebuild usage rollups, enforce usage retention, remove expired
+	// resumable uploads and purge deleted organizations in process
+	if !jobs.IsAvailable() {
+		usageService.StartUsageMaintenance(ctx, jobsConfig.UsageRollupInterval, jobsConfig.UsageRetentionInterval)
+		fileService.StartUploadCleanup(ctx, jobsConfig.FileUploadCleanupInterval)
+		orgService.StartDeletionPurge(ctx, jobsConfig.OrgDeletionPurgeInterval)
+	}
+
 	// Graceful shutdown handling
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -457,7 +554,7 @@ func main() {
 		wsHub.Stop()
 		zerologlog.Info().Msg("WebSocket hub stopped")
 
-		// Stop usage service workers
+		// Flush pending usage counters
 		usageService.Shutdown()
 		zerologlog.Info().Msg("usage service stopped")
 
@@ -484,7 +581,7 @@ func main() {
 	zerologlog.Info().Msg("server stopped gracefully")
 }
 
-func setupRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfig *stripe.Config, appService *handlers.Service, fileService *services.FileService, wsHub *websocket.Hub, usageService *services.UsageService) {
+func setupRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfig *stripe.Config, appService *handlers.Service, fileService *services.FileService, wsHub *websocket.Hub, usageService *services.UsageService, quotaService *services.QuotaService, orgService *services.OrgService) {
 	// Simple test route at root level
 	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
@@ -497,7 +594,7 @@ func setupRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfig *
 	apiRoutes := func(r chi.Router) {
 		// setupAPIRoutes must be called FIRST because it registers middleware with r.Use()
 		// Chi requires all middleware to be defined before any routes
-		setupAPIRoutes(r, rateLimitConfig, stripeConfig, appService, fileService, wsHub, usageService)
+		setupAPIRoutes(r, rateLimitConfig, stripeConfig, appService, fileService, wsHub, usageService, quotaService, orgService)
 
 		// These routes come after setupAPIRoutes to ensure middleware is registered first
 		r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
@@ -529,15 +626,25 @@ func setupRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfig *
 }
 
 // setupAPIRoutes configures all API endpoints
-func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfig *stripe.Config, appService *handlers.Service, fileService *services.FileService, wsHub *websocket.Hub, usageService *services.UsageService) {
-	// Initialize organization service and handlers
-	orgService := services.NewOrgService(database.DB)
-	orgService.SetHub(wsHub) // Enable WebSocket broadcasts for org/member updates
+func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfig *stripe.Config, appService *handlers.Service, fileService *services.FileService, wsHub *websocket.Hub, usageService *services.UsageService, quotaService *services.QuotaService, orgService *services.OrgService) {
+	// Initialize organization handlers
 	orgHandler := handlers.NewOrgHandler(orgService)
 	tenantMiddleware := auth.NewTenantMiddleware(database.DB)
 
 	// Initialize usage handler (service passed from main for graceful shutdown)
 	usageHandler := handlers.NewUsageHandler(usageService)
+	quotaHandler := handlers.NewQuotaHandler(quotaService)
+
+	// Product routes mount tenantMiddleware.OptionalOrganization after AuthMiddleware, so
+	// usage and quotas follow the active organization of the token or X-Organization-Slug
+
+	// Plan quota enforcement, mounted after AuthMiddleware on metered product routes only;
+	// account, security, GDPR, organization and billing routes stay reachable over quota
+	apiQuota := middleware.QuotaMiddleware(quotaService, services.UsageTypeAPICall)
+	// Storage quota, checked at the declared upload size before any content is read
+	storageQuota := middleware.StorageQuotaMiddleware(quotaService)
+	// Monthly AI token budget; requests are refused once the plan or organization budget is used up
+	aiBudget := middleware.QuotaMiddleware(quotaService, services.UsageTypeAITokens)
 
 	// Usage metering middleware (records API calls for authenticated users)
 	r.Use(middleware.UsageMiddleware(usageService))
@@ -569,7 +676,8 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 		r.Group(func(r chi.Router) {
 			r.Use(auth.AuthMiddleware)
 			r.Use(ratelimit.NewUserRateLimitMiddleware(rateLimitConfig))
-			r.Get("/me", auth.GetCurrentUser) // GET /api/auth/me
+			r.Get("/me", auth.GetCurrentUser)              // GET /api/auth/me
+			r.Post("/switch-org", auth.SwitchOrganization) // POST /api/auth/switch-org - Reissue token scoped to an organization
 		})
 
 		// OAuth routes
@@ -690,27 +798,57 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 		// File upload - requires authentication for security
 		r.Route("/upload", func(r chi.Router) {
 			r.Use(auth.AuthMiddleware)
+			r.Use(tenantMiddleware.OptionalOrganization)
 			r.Use(ratelimit.NewUserRateLimitMiddleware(rateLimitConfig))
+			r.Use(middleware.QuotaMiddleware(quotaService, services.UsageTypeFileUpload))
+			r.Use(storageQuota)
+			r.Use(middleware.MeterUsage(usageService, services.UsageTypeFileUpload))
 			r.Post("/", handlers.NewFileHandler(fileService).UploadFile) // POST /api/files/upload
 		})
 
+		// Resumable uploads (tus 1.0) - requires authentication, except protocol discovery
+		r.Route("/uploads", func(r chi.Router) {
+			r.Options("/", handlers.NewFileHandler(fileService).TusOptions)     // OPTIONS /api/files/uploads
+			r.Options("/{id}", handlers.NewFileHandler(fileService).TusOptions) // OPTIONS /api/files/uploads/{id}
+
+			r.Group(func(r chi.Router) {
+				r.Use(auth.AuthMiddleware)
+				r.Use(tenantMiddleware.OptionalOrganization)
+				r.Use(ratelimit.NewUserRateLimitMiddleware(rateLimitConfig))
+				r.With(
+					middleware.QuotaMiddleware(quotaService, services.UsageTypeFileUpload),
+					storageQuota,
+					middleware.MeterUsage(usageService, services.UsageTypeFileUpload),
+				).Post("/", handlers.NewFileHandler(fileService).CreateUpload) // POST /api/files/uploads
+				r.Head("/{id}", handlers.NewFileHandler(fileService).GetUploadOffset) // HEAD /api/files/uploads/{id}
+				r.Patch("/{id}", handlers.NewFileHandler(fileService).PatchUpload)    // PATCH /api/files/uploads/{id}
+				r.Delete("/{id}", handlers.NewFileHandler(fileService).DeleteUpload)  // DELETE /api/files/uploads/{id}
+			})
+		})
+
 		// File operations - all require authentication for security
 		r.Route("/{id}", func(r chi.Router) {
 			r.Use(auth.AuthMiddleware)
+			r.Use(tenantMiddleware.OptionalOrganization)
 			r.Use(ratelimit.NewUserRateLimitMiddleware(rateLimitConfig))
-			r.Get("/download", handlers.NewFileHandler(fileService).DownloadFile) // GET /api/files/{id}/download
-			r.Get("/url", handlers.NewFileHandler(fileService).GetFileURL)        // GET /api/files/{id}/url
-			r.Get("/", handlers.NewFileHandler(fileService).GetFileInfo)          // GET /api/files/{id}
-			r.Delete("/", handlers.NewFileHandler(fileService).DeleteFile)        // DELETE /api/files/{id}
+			r.With(apiQuota).Get("/download", handlers.NewFileHandler(fileService).DownloadFile) // GET /api/files/{id}/download
+			r.With(apiQuota).Get("/url", handlers.NewFileHandler(fileService).GetFileURL)        // GET /api/files/{id}/url
+			r.With(apiQuota).Get("/", handlers.NewFileHandler(fileService).GetFileInfo)          // GET /api/files/{id}
+			r.Delete("/", handlers.NewFileHandler(fileService).DeleteFile)                       // DELETE /api/files/{id} - not quota checked so storage can be freed
 		})
 
 		// List files - requires authentication
 		r.Route("/", func(r chi.Router) {
 			r.Use(auth.AuthMiddleware)
+			r.Use(tenantMiddleware.OptionalOrganization)
 			r.Use(ratelimit.NewUserRateLimitMiddleware(rateLimitConfig))
+			r.Use(apiQuota)
 			r.Get("/", handlers.NewFileHandler(fileService).ListFiles) // GET /api/files
 		})
 
+		// Signed download links for local storage - authorized by the URL signature
+		r.Get("/signed/{id}", handlers.NewFileHandler(fileService).DownloadSignedFile) // GET /api/files/signed/{id}
+
 		// Storage status - public endpoint
 		r.Get("/storage/status", handlers.NewFileHandler(fileService).GetStorageStatus) // GET /api/files/storage/status
 	})
@@ -731,6 +869,16 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 			r.Post("/checkout", stripe.CreateCheckoutSession(stripeConfig)) // POST /api/billing/checkout
 			r.Post("/portal", stripe.CreatePortalSession(stripeConfig))     // POST /api/billing/portal
 			r.Get("/subscription", stripe.GetSubscription())                // GET /api/billing/subscription
+			r.Get("/invoices", stripe.GetInvoices())                        // GET /api/billing/invoices
+
+			// Promotion codes
+			r.Post("/promotion-codes/validate", stripe.ValidatePromotionCode()) // POST /api/billing/promotion-codes/validate
+
+			// Plan changes and cancellation
+			r.Post("/subscription/preview-change", stripe.PreviewSubscriptionChange()) // POST /api/billing/subscription/preview-change
+			r.Post("/subscription/change", stripe.ChangeSubscription())                // POST /api/billing/subscription/change
+			r.Post("/subscription/cancel", stripe.CancelSubscription())                // POST /api/billing/subscription/cancel
+			r.Post("/subscription/resume", stripe.ResumeSubscription())                // POST /api/billing/subscription/resume
 		})
 	})
 
@@ -745,16 +893,21 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 		r.Get("/", usageHandler.GetCurrentUsage)        // GET /api/usage - Current period usage
 		r.Post("/record", usageHandler.RecordUsage)     // POST /api/usage/record - Record usage event
 		r.Get("/history", usageHandler.GetUsageHistory) // GET /api/usage/history - Usage history
+		r.Get("/ai", usageHandler.GetAIUsage)           // GET /api/usage/ai - AI token usage by model
 
 		// Alerts
 		r.Get("/alerts", usageHandler.GetAlerts)                          // GET /api/usage/alerts
 		r.Post("/alerts/{id}/acknowledge", usageHandler.AcknowledgeAlert) // POST /api/usage/alerts/{id}/acknowledge
+		r.Get("/alerts/preferences", usageHandler.GetAlertPreferences)    // GET /api/usage/alerts/preferences
+		r.Put("/alerts/preferences", usageHandler.UpdateAlertPreferences) // PUT /api/usage/alerts/preferences
 	})
 
 	// Notification center routes
 	r.Route("/notifications", func(r chi.Router) {
 		r.Use(auth.AuthMiddleware)
+		r.Use(tenantMiddleware.OptionalOrganization)
 		r.Use(ratelimit.NewUserRateLimitMiddleware(rateLimitConfig))
+		r.Use(apiQuota)
 
 		r.Get("/", handlers.GetNotifications)                  // GET /api/notifications - List user notifications
 		r.Post("/read-all", handlers.MarkAllNotificationsRead) // POST /api/notifications/read-all - Mark all as read
@@ -765,7 +918,10 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 	// AI routes (Gemini) - require authentication with separate rate limit tier
 	r.Route("/ai", func(r chi.Router) {
 		r.Use(auth.AuthMiddleware)
+		r.Use(tenantMiddleware.OptionalOrganization)
 		r.Use(ratelimit.NewAIRateLimitMiddleware(rateLimitConfig))
+		r.Use(apiQuota)
+		r.Use(aiBudget)
 
 		r.Post("/chat", handlers.AIChat)                  // POST /api/ai/chat - Chat completion
 		r.Post("/chat/stream", handlers.AIChatStream)     // POST /api/ai/chat/stream - Streaming chat (SSE)
@@ -777,6 +933,7 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 	// Feature flags - public endpoint for current user
 	r.Route("/feature-flags", func(r chi.Router) {
 		r.Use(auth.AuthMiddleware)
+		r.Use(tenantMiddleware.OptionalOrganization)
 		r.Get("/", handlers.GetFeatureFlagsForUser) // GET /api/feature-flags - Get flags for current user
 	})
 
@@ -816,6 +973,33 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 			r.Delete("/{key}", handlers.DeleteFeatureFlag) // DELETE /api/admin/feature-flags/{key}
 		})
 
+		// Stripe webhook event store
+		r.Route("/stripe/events", func(r chi.Router) {
+			r.Get("/failed", stripe.ListFailedEvents())       // GET /api/admin/stripe/events/failed
+			r.Post("/{eventId}/replay", stripe.ReplayEvent()) // POST /api/admin/stripe/events/{eventId}/replay
+		})
+
+		// Coupons redeemable at checkout
+		r.Route("/coupons", func(r chi.Router) {
+			r.Get("/", stripe.ListCoupons())             // GET /api/admin/coupons
+			r.Post("/", stripe.CreateCoupon())           // POST /api/admin/coupons
+			r.Delete("/{id}", stripe.DeactivateCoupon()) // DELETE /api/admin/coupons/{id}
+		})
+
+		// Usage quota exemptions
+		r.Route("/quota-exemptions", func(r chi.Router) {
+			r.Get("/", quotaHandler.ListExemptions)         // GET /api/admin/quota-exemptions
+			r.Post("/", quotaHandler.CreateExemption)       // POST /api/admin/quota-exemptions
+			r.Delete("/{id}", quotaHandler.DeleteExemption) // DELETE /api/admin/quota-exemptions/{id}
+		})
+
+		// Organization feature flag overrides
+		r.Route("/organizations/{id}/feature-flags", func(r chi.Router) {
+			r.Get("/", handlers.GetOrganizationFeatureFlagOverrides)           // GET /api/admin/organizations/{id}/feature-flags
+			r.Put("/{key}", handlers.SetOrganizationFeatureFlagOverride)       // PUT /api/admin/organizations/{id}/feature-flags/{key}
+			r.Delete("/{key}", handlers.DeleteOrganizationFeatureFlagOverride) // DELETE /api/admin/organizations/{id}/feature-flags/{key}
+		})
+
 		// System settings management
 		r.Route("/settings", func(r chi.Router) {
 			r.Get("/", handlers.GetAllSettings)                  // GET /api/admin/settings
@@ -890,6 +1074,7 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 	r.Route("/organizations", func(r chi.Router) {
 		r.Use(auth.AuthMiddleware)
 		r.Use(ratelimit.NewAPIRateLimitMiddleware(rateLimitConfig))
+		r.Use(audit.RequestContext)
 
 		// List user's organizations and create new ones
 		r.Get("/", orgHandler.ListOrganizations)   // GET /api/organizations
@@ -899,36 +1084,62 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 		r.Route("/{orgSlug}", func(r chi.Router) {
 			r.Use(tenantMiddleware.RequireOrganization)
 
+			// Organization management is not quota checked, so over-limit organizations
+			// can still manage members and upgrade their plan
 			r.Get("/", orgHandler.GetOrganization)         // GET /api/organizations/{orgSlug}
 			r.Post("/leave", orgHandler.LeaveOrganization) // POST /api/organizations/{orgSlug}/leave
 
+			// Usage metering (admin+)
+			r.Route("/usage", func(r chi.Router) {
+				r.Use(tenantMiddleware.RequireOrgRole(models.OrgRoleAdmin))
+				r.Get("/", usageHandler.GetOrgUsage)                                 // GET /api/organizations/{orgSlug}/usage
+				r.Get("/history", usageHandler.GetOrgUsageHistory)                   // GET /api/organizations/{orgSlug}/usage/history
+				r.Get("/alerts", usageHandler.GetOrgAlerts)                          // GET /api/organizations/{orgSlug}/usage/alerts
+				r.Post("/alerts/{id}/acknowledge", usageHandler.AcknowledgeOrgAlert) // POST /api/organizations/{orgSlug}/usage/alerts/{id}/acknowledge
+				r.Get("/alerts/preferences", usageHandler.GetOrgAlertPreferences)    // GET /api/organizations/{orgSlug}/usage/alerts/preferences
+				r.Put("/alerts/preferences", usageHandler.UpdateOrgAlertPreferences) // PUT /api/organizations/{orgSlug}/usage/alerts/preferences
+				r.Get("/ai", usageHandler.GetOrgAIUsage)                             // GET /api/organizations/{orgSlug}/usage/ai
+				r.Put("/ai", usageHandler.UpdateOrgAIBudget)                         // PUT /api/organizations/{orgSlug}/usage/ai
+			})
+
 			// Admin+ only routes
 			r.Group(func(r chi.Router) {
 				r.Use(tenantMiddleware.RequireOrgRole(models.OrgRoleAdmin))
 				r.Put("/", orgHandler.UpdateOrganization) // PUT /api/organizations/{orgSlug}
 
 				// Member management
-				r.Get("/members", orgHandler.ListMembers)                    // GET /api/organizations/{orgSlug}/members
-				r.Post("/members/invite", orgHandler.InviteMember)           // POST /api/organizations/{orgSlug}/members/invite
-				r.Put("/members/{userId}/role", orgHandler.UpdateMemberRole) // PUT /api/organizations/{orgSlug}/members/{userId}/role
-				r.Delete("/members/{userId}", orgHandler.RemoveMember)       // DELETE /api/organizations/{orgSlug}/members/{userId}
+				r.Get("/members", orgHandler.ListMembers)                           // GET /api/organizations/{orgSlug}/members
+				r.Post("/members/invite", orgHandler.InviteMember)                  // POST /api/organizations/{orgSlug}/members/invite
+				r.Put("/members/{userId}/role", orgHandler.UpdateMemberRole)        // PUT /api/organizations/{orgSlug}/members/{userId}/role
+				r.Delete("/members/{userId}", orgHandler.RemoveMember)              // DELETE /api/organizations/{orgSlug}/members/{userId}
+				r.Post("/members/{userId}/suspend", orgHandler.SuspendMember)       // POST /api/organizations/{orgSlug}/members/{userId}/suspend
+				r.Post("/members/{userId}/reactivate", orgHandler.ReactivateMember) // POST /api/organizations/{orgSlug}/members/{userId}/reactivate
 
 				// Invitation management
 				r.Get("/invitations", orgHandler.ListInvitations)                    // GET /api/organizations/{orgSlug}/invitations
 				r.Delete("/invitations/{invitationId}", orgHandler.CancelInvitation) // DELETE /api/organizations/{orgSlug}/invitations/{invitationId}
 
 				// Billing (view only for admin+)
-				r.Get("/billing", orgHandler.GetOrganizationBilling) // GET /api/organizations/{orgSlug}/billing
+				r.Get("/billing", orgHandler.GetOrganizationBilling)           // GET /api/organizations/{orgSlug}/billing
+				r.Get("/billing/invoices", orgHandler.GetOrganizationInvoices) // GET /api/organizations/{orgSlug}/billing/invoices
+
+				// Audit trail
+				r.Get("/audit-logs", orgHandler.ListAuditLogs) // GET /api/organizations/{orgSlug}/audit-logs
 			})
 
 			// Owner only routes
 			r.Group(func(r chi.Router) {
 				r.Use(tenantMiddleware.RequireOrgRole(models.OrgRoleOwner))
-				r.Delete("/", orgHandler.DeleteOrganization) // DELETE /api/organizations/{orgSlug}
+				r.Delete("/", orgHandler.DeleteOrganization)       // DELETE /api/organizations/{orgSlug} - Schedule deletion
+				r.Post("/restore", orgHandler.RestoreOrganization) // POST /api/organizations/{orgSlug}/restore - Cancel scheduled deletion
 
 				// Billing management (owner only)
-				r.Post("/billing/checkout", orgHandler.CreateOrganizationCheckout)    // POST /api/organizations/{orgSlug}/billing/checkout
-				r.Post("/billing/portal", orgHandler.CreateOrganizationBillingPortal) // POST /api/organizations/{orgSlug}/billing/portal
+				r.Post("/billing/checkout", orgHandler.CreateOrganizationCheckout)                               // POST /api/organizations/{orgSlug}/billing/checkout
+				r.Post("/billing/portal", orgHandler.CreateOrganizationBillingPortal)                            // POST /api/organizations/{orgSlug}/billing/portal
+				r.Post("/billing/subscription/preview-change", orgHandler.PreviewOrganizationSubscriptionChange) // POST /api/organizations/{orgSlug}/billing/subscription/preview-change
+				r.Post("/billing/subscription/change", orgHandler.ChangeOrganizationSubscription)                // POST /api/organizations/{orgSlug}/billing/subscription/change
+				r.Post("/billing/subscription/cancel", orgHandler.CancelOrganizationSubscription)                // POST /api/organizations/{orgSlug}/billing/subscription/cancel
+				r.Post("/billing/subscription/resume", orgHandler.ResumeOrganizationSubscription)                // POST /api/organizations/{orgSlug}/billing/subscription/resume
 			})
 		})
 	})
@@ -936,6 +1147,7 @@ func setupAPIRoutes(r chi.Router, rateLimitConfig *ratelimit.Config, stripeConfi
 	// Invitation acceptance (separate from org routes - user may not be a member yet)
 	r.Route("/invitations", func(r chi.Router) {
 		r.Use(auth.AuthMiddleware)
+		r.Use(audit.RequestContext)
 		r.Post("/accept", orgHandler.AcceptInvitation) // POST /api/invitations/accept?token=xxx
 	})
 }
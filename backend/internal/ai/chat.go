@@ -150,19 +150,21 @@ func (s *geminiService) parseResponse(resp *genai.GenerateContentResponse) *Resp
 		}
 	}
 
-	// Extract usage metadata
-	var usage *Usage
-	if resp.UsageMetadata != nil {
-		usage = &Usage{
-			InputTokens:  int(resp.UsageMetadata.PromptTokenCount),
-			OutputTokens: int(resp.UsageMetadata.CandidatesTokenCount),
-			TotalTokens:  int(resp.UsageMetadata.TotalTokenCount),
-		}
-	}
-
 	return &Response{
 		Content: content,
 		Model:   s.config.Model,
-		Usage:   usage,
+		Usage:   usageFromMetadata(resp.UsageMetadata),
+	}
+}
+
+// usageFromMetadata converts Gemini usage metadata to our Usage
+func usageFromMetadata(meta *genai.GenerateContentResponseUsageMetadata) *Usage {
+	if meta == nil {
+		return nil
+	}
+	return &Usage{
+		InputTokens:  int(meta.PromptTokenCount),
+		OutputTokens: int(meta.CandidatesTokenCount),
+		TotalTokens:  int(meta.TotalTokenCount),
 	}
 }
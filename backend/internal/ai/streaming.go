@@ -25,6 +25,9 @@ func (s *geminiService) StreamChat(ctx context.Context, messages []Message, opts
 	go func() {
 		defer close(chunks)
 
+		// Usage metadata is cumulative; the last chunk carrying it has the final counts
+		var usage *Usage
+
 		// Stream content using Go 1.23+ range-over-func
 		// GenerateContentStream returns iter.Seq2[*GenerateContentResponse, error]
 		for resp, err := range s.client.Models.GenerateContentStream(ctx, s.config.Model, contents, config) {
@@ -33,6 +36,10 @@ func (s *geminiService) StreamChat(ctx context.Context, messages []Message, opts
 				return
 			}
 
+			if resp != nil && resp.UsageMetadata != nil {
+				usage = usageFromMetadata(resp.UsageMetadata)
+			}
+
 			// Extract text from response chunk
 			text := s.extractTextFromResponse(resp)
 			if text != "" {
@@ -41,7 +48,7 @@ func (s *geminiService) StreamChat(ctx context.Context, messages []Message, opts
 		}
 
 		// Stream completed
-		chunks <- StreamChunk{Done: true}
+		chunks <- StreamChunk{Done: true, Usage: usage}
 	}()
 
 	return chunks, nil
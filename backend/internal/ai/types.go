@@ -1,6 +1,9 @@
 package ai
 
-import "errors"
+import (
+	"errors"
+	"unicode/utf8"
+)
 
 // Common errors
 var (
@@ -76,10 +79,23 @@ type Usage struct {
 	TotalTokens  int `json:"totalTokens"`
 }
 
+// EstimateTokens approximates the tokens in texts at about four characters per token, for
+// operations whose API response does not report token counts (e.g. embeddings)
+func EstimateTokens(texts []string) int {
+	tokens := 0
+	for _, text := range texts {
+		if chars := utf8.RuneCountInString(text); chars > 0 {
+			tokens += (chars + 3) / 4
+		}
+	}
+	return tokens
+}
+
 // StreamChunk represents a single chunk in a streaming response
 type StreamChunk struct {
 	Token string `json:"token,omitempty"`
 	Done  bool   `json:"done,omitempty"`
+	Usage *Usage `json:"usage,omitempty"` // Set on the final chunk when reported
 	Error error  `json:"-"`
 }
 
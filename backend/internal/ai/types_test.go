@@ -208,6 +208,29 @@ func TestStreamChunk_Creation(t *testing.T) {
 	}
 }
 
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  int
+	}{
+		{"empty", nil, 0},
+		{"empty text", []string{""}, 0},
+		{"short text rounds up", []string{"hi"}, 1},
+		{"four characters per token", []string{"12345678"}, 2},
+		{"counts runes not bytes", []string{"héllo wörld"}, 3},
+		{"sums texts", []string{"abcd", "abcdefgh"}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.texts); got != tt.want {
+				t.Errorf("EstimateTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 // ============ FunctionDeclaration Tests ============
 
 func TestFunctionDeclaration_Creation(t *testing.T) {
@@ -1,12 +1,14 @@
 package audit
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"time"
 
 	"react-golang-starter/internal/database"
 	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/pagination"
 
 	"github.com/rs/zerolog/log"
 )
@@ -143,6 +145,97 @@ func LogRoleChange(actorUserID uint, targetUserID uint, oldRole string, newRole
 	LogEntry(&actorUserID, models.AuditTargetUser, &targetUserID, models.AuditActionRoleChange, changes, r)
 }
 
+// requestInfoKey is the context key for request details captured by RequestContext
+type requestInfoKey struct{}
+
+// requestInfo holds the request details recorded on audit entries
+type requestInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// RequestContext middleware stores the client IP and user agent in the request context
+// so that services which only receive a context can still record them on audit entries
+func RequestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithRequest(r.Context(), r)))
+	})
+}
+
+// WithRequest returns a copy of ctx carrying the client IP and user agent of r
+func WithRequest(ctx context.Context, r *http.Request) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, requestInfoKey{}, requestInfo{
+		IPAddress: getClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+}
+
+// requestInfoFromContext returns the request details stored by WithRequest, if any
+func requestInfoFromContext(ctx context.Context) requestInfo {
+	if ctx == nil {
+		return requestInfo{}
+	}
+	info, _ := ctx.Value(requestInfoKey{}).(requestInfo)
+	return info
+}
+
+// OrgEntry describes an audit event scoped to an organization
+type OrgEntry struct {
+	OrganizationID uint
+	ActorID        *uint // nil for system actions (webhooks, background jobs)
+	TargetType     string
+	TargetID       *uint
+	Action         string
+	Changes        interface{}
+	Metadata       interface{}
+}
+
+// LogOrgEntry creates an organization-scoped audit log entry.
+// Request details are read from ctx when it was prepared with WithRequest.
+func LogOrgEntry(ctx context.Context, e OrgEntry) {
+	go logOrgEntryAsync(e, requestInfoFromContext(ctx))
+}
+
+func logOrgEntryAsync(e OrgEntry, info requestInfo) {
+	if database.DB == nil {
+		return
+	}
+
+	orgID := e.OrganizationID
+	entry := models.AuditLog{
+		UserID:         e.ActorID,
+		OrganizationID: &orgID,
+		TargetType:     e.TargetType,
+		TargetID:       e.TargetID,
+		Action:         e.Action,
+		IPAddress:      info.IPAddress,
+		UserAgent:      info.UserAgent,
+		CreatedAt:      time.Now().Format(time.RFC3339),
+	}
+
+	if e.Changes != nil {
+		if changesJSON, err := json.Marshal(e.Changes); err == nil {
+			entry.Changes = string(changesJSON)
+		}
+	}
+	if e.Metadata != nil {
+		if metadataJSON, err := json.Marshal(e.Metadata); err == nil {
+			entry.Metadata = string(metadataJSON)
+		}
+	}
+
+	if err := database.DB.Create(&entry).Error; err != nil {
+		log.Error().Err(err).
+			Uint("org_id", orgID).
+			Str("target_type", e.TargetType).
+			Str("action", e.Action).
+			Msg("Failed to create organization audit log entry")
+	}
+}
+
 // getClientIP extracts the client IP from the request
 func getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header first (for proxied requests)
@@ -172,6 +265,9 @@ func GetAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, int64, error
 	if filter.UserID != nil {
 		query = query.Where("user_id = ?", *filter.UserID)
 	}
+	if filter.OrganizationID != nil {
+		query = query.Where("organization_id = ?", *filter.OrganizationID)
+	}
 	if filter.TargetType != "" {
 		query = query.Where("target_type = ?", filter.TargetType)
 	}
@@ -224,3 +320,63 @@ func GetUserAuditLogs(userID uint, page, limit int) ([]models.AuditLog, int64, e
 	}
 	return GetAuditLogs(filter)
 }
+
+// GetOrgAuditLogs retrieves audit logs for an organization using cursor pagination.
+// Page and OrganizationID on the filter are ignored; orgID always scopes the query.
+// Results are newest first; hasMore reports whether another page exists in the
+// requested direction.
+func GetOrgAuditLogs(orgID uint, filter models.AuditLogFilter, cursor *pagination.Cursor, direction string, limit int) ([]models.AuditLog, bool, error) {
+	if limit < 1 || limit > pagination.MaxLimit {
+		limit = pagination.DefaultLimit
+	}
+
+	query := database.DB.Model(&models.AuditLog{}).Where("organization_id = ?", orgID)
+
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.StartDate != "" {
+		query = query.Where("created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != "" {
+		query = query.Where("created_at <= ?", filter.EndDate)
+	}
+
+	cq := pagination.BuildCursorQuery(cursor, direction)
+	if cq.Where != "" {
+		query = query.Where(cq.Where, cq.Args...)
+	}
+
+	// Fetch one extra row to detect whether another page exists
+	var logs []models.AuditLog
+	if err := query.
+		Preload("User").
+		Order(cq.Order).
+		Limit(limit + 1).
+		Find(&logs).Error; err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(logs) > limit
+	if hasMore {
+		logs = logs[:limit]
+	}
+
+	// Backwards pages are fetched oldest first; flip them so callers always get newest first
+	if cursor != nil && direction == "prev" {
+		for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+			logs[i], logs[j] = logs[j], logs[i]
+		}
+	}
+
+	return logs, hasMore, nil
+}
@@ -1,6 +1,7 @@
 package audit
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,6 +9,7 @@ import (
 
 	"react-golang-starter/internal/database"
 	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/pagination"
 	"react-golang-starter/internal/testutil"
 )
 
@@ -90,6 +92,28 @@ func TestGetClientIP_NilRequest(t *testing.T) {
 	t.Skip("getClientIP expects a non-nil request")
 }
 
+func TestWithRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/organizations/acme/members/invite", nil)
+	req.Header.Set("X-Real-IP", "203.0.113.7")
+	req.Header.Set("User-Agent", "TestAgent/2.0")
+
+	info := requestInfoFromContext(WithRequest(context.Background(), req))
+	if info.IPAddress != "203.0.113.7" {
+		t.Errorf("Expected IP 203.0.113.7, got %s", info.IPAddress)
+	}
+	if info.UserAgent != "TestAgent/2.0" {
+		t.Errorf("Expected User-Agent TestAgent/2.0, got %s", info.UserAgent)
+	}
+
+	// A context without request details yields an empty value
+	if empty := requestInfoFromContext(context.Background()); empty != (requestInfo{}) {
+		t.Errorf("Expected empty request info, got %+v", empty)
+	}
+	if same := WithRequest(context.Background(), nil); requestInfoFromContext(same) != (requestInfo{}) {
+		t.Error("Expected nil request to leave context unchanged")
+	}
+}
+
 // Integration tests - require database
 func TestLogEntry_Integration(t *testing.T) {
 	testutil.SkipIfNotIntegration(t)
@@ -398,6 +422,110 @@ func TestGetUserAuditLogs_Integration(t *testing.T) {
 	}
 }
 
+func TestGetOrgAuditLogs_Integration(t *testing.T) {
+	testutil.SkipIfNotIntegration(t)
+
+	db := testutil.SetupTestDB(t)
+	tx := testutil.NewTestTransaction(t, db)
+	database.DB = tx.DB
+
+	seeder := testutil.NewTestSeeder(t, tx.DB)
+	owner := seeder.SeedUser(testutil.WithUserEmail("org-audit-owner@test.local"))
+	org := seeder.SeedOrganization("Audit Org", owner)
+	other := seeder.SeedOrganization("Other Org", owner)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/organizations/audit-org", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.5")
+	info := requestInfoFromContext(WithRequest(context.Background(), req))
+
+	for i := 0; i < 5; i++ {
+		logOrgEntryAsync(OrgEntry{
+			OrganizationID: org.ID,
+			ActorID:        &owner.ID,
+			TargetType:     models.AuditTargetOrganization,
+			TargetID:       &org.ID,
+			Action:         models.AuditActionUpdate,
+			Changes:        map[string]int{"revision": i},
+		}, info)
+	}
+	logOrgEntryAsync(OrgEntry{
+		OrganizationID: org.ID,
+		ActorID:        &owner.ID,
+		TargetType:     models.AuditTargetInvitation,
+		Action:         models.AuditActionInvite,
+	}, info)
+	logOrgEntryAsync(OrgEntry{
+		OrganizationID: other.ID,
+		ActorID:        &owner.ID,
+		TargetType:     models.AuditTargetOrganization,
+		TargetID:       &other.ID,
+		Action:         models.AuditActionUpdate,
+	}, info)
+
+	t.Run("scopes to organization", func(t *testing.T) {
+		logs, hasMore, err := GetOrgAuditLogs(org.ID, models.AuditLogFilter{}, nil, "next", 20)
+		if err != nil {
+			t.Fatalf("GetOrgAuditLogs error: %v", err)
+		}
+		if len(logs) != 6 {
+			t.Errorf("Expected 6 logs, got %d", len(logs))
+		}
+		if hasMore {
+			t.Error("Expected hasMore to be false")
+		}
+		for _, l := range logs {
+			if l.OrganizationID == nil || *l.OrganizationID != org.ID {
+				t.Errorf("Expected organization_id %d, got %v", org.ID, l.OrganizationID)
+			}
+			if l.IPAddress != "10.0.0.5" {
+				t.Errorf("Expected IP 10.0.0.5, got %s", l.IPAddress)
+			}
+		}
+	})
+
+	t.Run("filter by action", func(t *testing.T) {
+		logs, _, err := GetOrgAuditLogs(org.ID, models.AuditLogFilter{Action: models.AuditActionInvite}, nil, "next", 20)
+		if err != nil {
+			t.Fatalf("GetOrgAuditLogs error: %v", err)
+		}
+		if len(logs) != 1 {
+			t.Errorf("Expected 1 invite log, got %d", len(logs))
+		}
+	})
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		first, hasMore, err := GetOrgAuditLogs(org.ID, models.AuditLogFilter{}, nil, "next", 4)
+		if err != nil {
+			t.Fatalf("GetOrgAuditLogs error: %v", err)
+		}
+		if len(first) != 4 || !hasMore {
+			t.Fatalf("Expected 4 logs with more, got %d (hasMore=%v)", len(first), hasMore)
+		}
+
+		last := first[len(first)-1]
+		createdAt, err := time.Parse(time.RFC3339Nano, last.CreatedAt)
+		if err != nil {
+			t.Fatalf("Failed to parse created_at: %v", err)
+		}
+		cursor := &pagination.Cursor{ID: last.ID, CreatedAt: createdAt}
+
+		second, hasMore, err := GetOrgAuditLogs(org.ID, models.AuditLogFilter{}, cursor, "next", 4)
+		if err != nil {
+			t.Fatalf("GetOrgAuditLogs error: %v", err)
+		}
+		if len(second) != 2 || hasMore {
+			t.Errorf("Expected 2 remaining logs without more, got %d (hasMore=%v)", len(second), hasMore)
+		}
+		for _, l := range second {
+			for _, f := range first {
+				if l.ID == f.ID {
+					t.Errorf("Log %d returned on both pages", l.ID)
+				}
+			}
+		}
+	})
+}
+
 // Convenience function tests
 func TestLogLogin_Integration(t *testing.T) {
 	testutil.SkipIfNotIntegration(t)
@@ -34,9 +34,20 @@ type Claims struct {
 	Email          string `json:"email"`
 	Role           string `json:"role"`
 	OriginalUserID uint   `json:"original_user_id,omitempty"` // Set when impersonating
+
+	// Active organization context (set via POST /api/auth/switch-org)
+	OrgID   uint   `json:"org_id,omitempty"`
+	OrgSlug string `json:"org_slug,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
+// HasOrganization reports whether the token carries an active organization context
+func (c *Claims) HasOrganization() bool {
+	return c.OrgID != 0 && c.OrgRole != ""
+}
+
 // HashPassword hashes a password using bcrypt
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -55,6 +66,12 @@ func CheckPassword(password, hashedPassword string) bool {
 // GenerateJWT generates a JWT access token for the given user
 // Access tokens are short-lived (default 15 minutes) for security
 func GenerateJWT(user *models.User) (string, error) {
+	return GenerateOrgJWT(user, nil)
+}
+
+// GenerateOrgJWT generates a JWT access token scoped to the given organization membership.
+// The membership's Organization must be loaded. A nil membership produces a token without org context.
+func GenerateOrgJWT(user *models.User, membership *models.OrganizationMember) (string, error) {
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
 		return "", errors.New("JWT_SECRET environment variable is not set")
@@ -73,6 +90,12 @@ func GenerateJWT(user *models.User) (string, error) {
 		},
 	}
 
+	if membership != nil && membership.Organization != nil {
+		claims.OrgID = membership.OrganizationID
+		claims.OrgSlug = membership.Organization.Slug
+		claims.OrgRole = string(membership.Role)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(jwtSecret))
 	if err != nil {
@@ -143,6 +166,28 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// parseClaimsAllowExpired verifies a token's signature and returns its claims without
+// enforcing expiry. Used to carry the active organization across token refreshes.
+func parseClaimsAllowExpired(tokenString string) (*Claims, error) {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		return nil, errors.New("JWT_SECRET environment variable is not set")
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(jwtSecret), nil
+	}, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
 // GenerateVerificationToken generates a random verification token
 func GenerateVerificationToken() (string, error) {
 	bytes := make([]byte, 32)
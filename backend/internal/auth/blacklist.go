@@ -133,6 +133,7 @@ func IsTokenBlacklisted(token string) bool {
 }
 
 // CleanupExpiredBlacklistEntries removes expired entries from the blacklist
+// and org token revocations that can no longer match an unexpired token
 // This should be run periodically (e.g., daily) to prevent table bloat
 func CleanupExpiredBlacklistEntries() error {
 	// Skip if database is not initialized (for testing)
@@ -152,7 +153,7 @@ func CleanupExpiredBlacklistEntries() error {
 		log.Info().Int64("count", result.RowsAffected).Msg("cleaned up expired blacklist entries")
 	}
 
-	return nil
+	return cleanupExpiredOrgRevocations()
 }
 
 // RevokeAllUserTokens adds all of a user's active tokens to the blacklist
@@ -658,8 +658,9 @@ func RefreshAccessToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate new access token
-	token, err := GenerateJWT(&user)
+	// Generate new access token, keeping the active organization if the user is still a member
+	membership := carriedOrgMembership(r, user.ID)
+	token, err := GenerateOrgJWT(&user, membership)
 	if err != nil {
 		writeInternalError(w, r, "Failed to generate token")
 		return
@@ -687,9 +688,10 @@ func RefreshAccessToken(w http.ResponseWriter, r *http.Request) {
 
 	// Return response (refresh token now in httpOnly cookie, not exposed in response)
 	response := models.AuthResponse{
-		User:      user.ToUserResponse(),
-		Token:     token,
-		ExpiresIn: int64(GetAccessTokenExpirationTime().Seconds()),
+		User:         user.ToUserResponse(),
+		Token:        token,
+		ExpiresIn:    int64(GetAccessTokenExpirationTime().Seconds()),
+		Organization: activeOrganization(membership),
 	}
 
 	writeJSON(w, http.StatusOK, response)
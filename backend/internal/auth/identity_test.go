@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"react-golang-starter/internal/models"
+)
+
+// ============ RequestIdentity Tests ============
+
+func TestRequestIdentity_RecordsUserAndActiveOrg(t *testing.T) {
+	ctx, identity := WithRequestIdentity(context.Background())
+
+	recordUser(ctx, 7, &Claims{UserID: 7, OrgID: 3, OrgSlug: "acme", OrgRole: string(models.OrgRoleMember)})
+
+	if identity.UserID() != 7 {
+		t.Errorf("UserID() = %d, want 7", identity.UserID())
+	}
+	orgID, slug := identity.Organization()
+	if orgID == nil || *orgID != 3 || slug != "acme" {
+		t.Errorf("Organization() = %v, %q, want 3, acme", orgID, slug)
+	}
+	if identity.ResolvedByTenant() {
+		t.Error("ResolvedByTenant() = true for an org claim")
+	}
+}
+
+func TestRequestIdentity_TenantOverridesClaim(t *testing.T) {
+	ctx, identity := WithRequestIdentity(context.Background())
+
+	recordUser(ctx, 7, &Claims{UserID: 7, OrgID: 3, OrgSlug: "acme", OrgRole: string(models.OrgRoleMember)})
+	recordOrganization(ctx, 5, "globex")
+
+	orgID, slug := identity.Organization()
+	if orgID == nil || *orgID != 5 || slug != "globex" {
+		t.Errorf("Organization() = %v, %q, want 5, globex", orgID, slug)
+	}
+	if !identity.ResolvedByTenant() {
+		t.Error("ResolvedByTenant() = false after TenantMiddleware")
+	}
+}
+
+func TestRequestIdentity_NoOrgClaim(t *testing.T) {
+	ctx, identity := WithRequestIdentity(context.Background())
+
+	recordUser(ctx, 7, &Claims{UserID: 7})
+
+	if orgID, _ := identity.Organization(); orgID != nil {
+		t.Errorf("Organization() = %d, want nil", *orgID)
+	}
+}
+
+func TestRequestIdentity_NotInstalled(t *testing.T) {
+	// Recording without an installed identity is a no-op
+	recordUser(context.Background(), 7, nil)
+	recordOrganization(context.Background(), 3, "acme")
+}
+
+// ============ ActiveOrganizationID Tests ============
+
+func TestActiveOrganizationID(t *testing.T) {
+	user := &models.User{ID: 7, Email: "user@example.com"}
+	orgClaims := &Claims{UserID: 7, OrgID: 3, OrgSlug: "acme", OrgRole: string(models.OrgRoleMember)}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want uint
+	}{
+		{
+			name: "no organization",
+			ctx:  SetUserContext(context.Background(), user),
+		},
+		{
+			name: "active org claim",
+			ctx:  SetClaimsContext(SetUserContext(context.Background(), user), orgClaims),
+			want: 3,
+		},
+		{
+			name: "claim for another user",
+			ctx:  SetClaimsContext(SetUserContext(context.Background(), &models.User{ID: 8}), orgClaims),
+		},
+		{
+			name: "tenant organization wins",
+			ctx: context.WithValue(
+				SetClaimsContext(SetUserContext(context.Background(), user), orgClaims),
+				OrganizationContextKey, &models.Organization{ID: 5, Slug: "globex"}),
+			want: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ActiveOrganizationID(tt.ctx)
+			if tt.want == 0 {
+				if got != nil {
+					t.Errorf("ActiveOrganizationID() = %d, want nil", *got)
+				}
+				return
+			}
+			if got == nil || *got != tt.want {
+				t.Errorf("ActiveOrganizationID() = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
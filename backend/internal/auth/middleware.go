@@ -61,6 +61,12 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Org-scoped tokens are revoked when the member's role changes or they leave the org
+		if IsOrgTokenRevoked(claims) {
+			response.TokenInvalid(w, r, "Token has been revoked")
+			return
+		}
+
 		// Try to get user from cache first, fallback to database
 		cacheKey := fmt.Sprintf("user:%d", claims.UserID)
 		var user models.User
@@ -91,6 +97,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		ctx = context.WithValue(ctx, UserEmailContextKey, user.Email)
 		ctx = context.WithValue(ctx, UserRoleContextKey, user.Role)
 		ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+		recordUser(ctx, user.ID, claims)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -114,7 +121,7 @@ func OptionalAuthMiddleware(next http.Handler) http.Handler {
 
 		if tokenString != "" {
 			claims, err := ValidateJWT(tokenString)
-			if err == nil {
+			if err == nil && !IsOrgTokenRevoked(claims) {
 				// Try to get user from cache first, fallback to database
 				cacheKey := fmt.Sprintf("user:%d", claims.UserID)
 				var user models.User
@@ -142,6 +149,7 @@ func OptionalAuthMiddleware(next http.Handler) http.Handler {
 					ctx = context.WithValue(ctx, UserEmailContextKey, user.Email)
 					ctx = context.WithValue(ctx, UserRoleContextKey, user.Role)
 					ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+					recordUser(ctx, user.ID, claims)
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
@@ -171,11 +179,15 @@ func SetUserContext(ctx context.Context, user *models.User) context.Context {
 	ctx = context.WithValue(ctx, UserIDContextKey, user.ID)
 	ctx = context.WithValue(ctx, UserEmailContextKey, user.Email)
 	ctx = context.WithValue(ctx, UserRoleContextKey, user.Role)
+	recordUser(ctx, user.ID, nil)
 	return ctx
 }
 
 // SetClaimsContext adds claims to the context (primarily for testing)
 func SetClaimsContext(ctx context.Context, claims *Claims) context.Context {
+	if claims != nil {
+		recordUser(ctx, claims.UserID, claims)
+	}
 	return context.WithValue(ctx, ClaimsContextKey, claims)
 }
 
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"react-golang-starter/internal/cache"
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm/clause"
+)
+
+// orgRevocationCachePrefix is the cache key prefix for org token revocation cutoffs.
+const orgRevocationCachePrefix = "org_token_revoked:"
+
+func orgRevocationCacheKey(userID, orgID uint) string {
+	return orgRevocationCachePrefix + strconv.FormatUint(uint64(userID), 10) + ":" + strconv.FormatUint(uint64(orgID), 10)
+}
+
+// RevokeOrgTokens invalidates every access token scoped to orgID that was issued to userID
+// up to now. Call this when a member's role changes or they are removed from an organization.
+// Tokens without org context are unaffected; the next refresh re-checks membership.
+// JWT issue times have second precision, so a token issued within the second of the
+// revocation is rejected as well; a refresh after that second issues a valid one.
+func RevokeOrgTokens(ctx context.Context, userID, orgID uint, reason string) error {
+	// Skip if database is not initialized (for testing)
+	if database.DB == nil {
+		return nil
+	}
+
+	revokedAt := time.Now()
+	entry := models.OrgTokenRevocation{
+		UserID:         userID,
+		OrganizationID: orgID,
+		RevokedAt:      revokedAt,
+		Reason:         reason,
+	}
+
+	if err := database.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "organization_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "reason"}),
+	}).Create(&entry).Error; err != nil {
+		return err
+	}
+
+	// Cache the cutoff for faster lookups; tokens older than the access token lifetime are expired anyway
+	cacheKey := orgRevocationCacheKey(userID, orgID)
+	if err := cache.Set(ctx, cacheKey, []byte(strconv.FormatInt(revokedAt.UnixNano(), 10)), GetAccessTokenExpirationTime()); err != nil {
+		log.Debug().Err(err).Str("key", cacheKey).Msg("failed to cache org token revocation")
+	}
+
+	log.Info().Uint("user_id", userID).Uint("org_id", orgID).Str("reason", reason).Msg("revoked org-scoped tokens")
+	return nil
+}
+
+// IsOrgTokenRevoked reports whether the organization context in claims has been revoked.
+// Claims without org context are never considered revoked.
+// On database error, behavior follows TOKEN_BLACKLIST_FAIL_MODE like IsTokenBlacklisted.
+func IsOrgTokenRevoked(claims *Claims) bool {
+	if claims == nil || !claims.HasOrganization() || database.DB == nil {
+		return false
+	}
+
+	issuedAt := time.Time{}
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// Check cache first (fast path)
+	cacheKey := orgRevocationCacheKey(claims.UserID, claims.OrgID)
+	if cached, err := cache.Get(ctx, cacheKey); err == nil {
+		if unixNano, err := strconv.ParseInt(string(cached), 10, 64); err == nil {
+			return issuedBefore(issuedAt, time.Unix(0, unixNano))
+		}
+	}
+
+	// Cache miss - check database
+	var entry models.OrgTokenRevocation
+	result := database.DB.WithContext(ctx).
+		Where("user_id = ? AND organization_id = ?", claims.UserID, claims.OrgID).
+		Limit(1).
+		Find(&entry)
+	if result.Error != nil {
+		failMode := getBlacklistFailMode()
+		log.Warn().
+			Err(result.Error).
+			Str("fail_mode", failMode).
+			Uint("user_id", claims.UserID).
+			Uint("org_id", claims.OrgID).
+			Msg("SECURITY: Org token revocation check failed")
+		return failMode == "closed"
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+
+	// Cache the cutoff while it can still affect unexpired tokens
+	if ttl := time.Until(entry.RevokedAt.Add(GetAccessTokenExpirationTime())); ttl > 0 {
+		_ = cache.Set(ctx, cacheKey, []byte(strconv.FormatInt(entry.RevokedAt.UnixNano(), 10)), ttl)
+	}
+
+	return issuedBefore(issuedAt, entry.RevokedAt)
+}
+
+// issuedBefore reports whether a token issued at issuedAt predates a revocation at
+// revokedAt. The issue time is truncated to the second, so a token issued in the same
+// second as the revocation counts as issued before it.
+func issuedBefore(issuedAt, revokedAt time.Time) bool {
+	return !issuedAt.After(revokedAt)
+}
+
+// cleanupExpiredOrgRevocations removes revocation cutoffs older than the access token lifetime,
+// since every token they could reject has already expired
+func cleanupExpiredOrgRevocations() error {
+	result := database.DB.
+		Where("revoked_at < ?", time.Now().Add(-GetAccessTokenExpirationTime())).
+		Delete(&models.OrgTokenRevocation{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Info().Int64("count", result.RowsAffected).Msg("cleaned up expired org token revocations")
+	}
+
+	return nil
+}
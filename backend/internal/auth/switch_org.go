@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SwitchOrganization godoc
+// @Summary Switch active organization
+// @Description Reissue the access token with org_id and org_role claims for the given organization.
+// @Description Org-scoped routes can then resolve tenancy from the token. Send an empty org_slug to return to the personal context.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.SwitchOrgRequest true "Organization to switch to"
+// @Success 200 {object} models.AuthResponse "New access token issued"
+// @Failure 400 {object} models.ErrorResponse "Invalid request body"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Not a member of this organization"
+// @Failure 404 {object} models.ErrorResponse "Organization not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to generate token"
+// @Router /auth/switch-org [post]
+func SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	user, ok := GetUserFromContext(r.Context())
+	if !ok || user == nil {
+		writeUnauthorized(w, r, "User not found in context")
+		return
+	}
+
+	var req models.SwitchOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid JSON")
+		return
+	}
+
+	var membership *models.OrganizationMember
+	if slug := strings.TrimSpace(req.OrgSlug); slug != "" {
+		var org models.Organization
+		if err := database.DB.Where("slug = ?", slug).First(&org).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				writeNotFound(w, r, "Organization not found")
+				return
+			}
+			writeInternalError(w, r, "Failed to load organization")
+			return
+		}
+
+		var err error
+		membership, err = loadActiveMembership(org.ID, user.ID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				writeForbidden(w, r, "Not a member of this organization")
+				return
+			}
+			writeInternalError(w, r, "Failed to load membership")
+			return
+		}
+	}
+
+	token, err := GenerateOrgJWT(user, membership)
+	if err != nil {
+		writeInternalError(w, r, "Failed to generate token")
+		return
+	}
+
+	// Revoke the previous access token so only the new org context stays usable
+	if previous := extractRequestToken(r); previous != "" {
+		if claims, err := ValidateJWT(previous); err == nil && claims.ExpiresAt != nil {
+			_ = BlacklistToken(previous, claims.UserID, claims.ExpiresAt.Time, "switch_org")
+		}
+	}
+
+	SetAuthCookie(w, token)
+
+	writeJSON(w, http.StatusOK, models.AuthResponse{
+		User:         user.ToUserResponse(),
+		Token:        token,
+		ExpiresIn:    int64(GetAccessTokenExpirationTime().Seconds()),
+		Organization: activeOrganization(membership),
+	})
+}
+
+// carriedOrgMembership returns the user's current membership in the organization from the
+// request's previous (possibly expired) access token, so a refresh keeps the active org
+// with an up-to-date role. Returns nil if there is no org context or the user left the org.
+func carriedOrgMembership(r *http.Request, userID uint) *models.OrganizationMember {
+	tokenString := extractRequestToken(r)
+	if tokenString == "" {
+		return nil
+	}
+
+	claims, err := parseClaimsAllowExpired(tokenString)
+	if err != nil || !claims.HasOrganization() || claims.UserID != userID {
+		return nil
+	}
+
+	membership, err := loadActiveMembership(claims.OrgID, userID)
+	if err != nil {
+		return nil
+	}
+	return membership
+}
+
+// loadActiveMembership loads an active membership with its organization
+func loadActiveMembership(orgID, userID uint) (*models.OrganizationMember, error) {
+	var membership models.OrganizationMember
+	if err := database.DB.Preload("Organization").
+		Where("organization_id = ? AND user_id = ? AND status = ?", orgID, userID, models.MemberStatusActive).
+		First(&membership).Error; err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// activeOrganization converts a membership into the response's org context
+func activeOrganization(membership *models.OrganizationMember) *models.ActiveOrganization {
+	if membership == nil || membership.Organization == nil {
+		return nil
+	}
+	return &models.ActiveOrganization{
+		ID:   membership.OrganizationID,
+		Slug: membership.Organization.Slug,
+		Role: membership.Role,
+	}
+}
+
+// extractRequestToken returns the access token from the auth cookie or Authorization header
+func extractRequestToken(r *http.Request) string {
+	if tokenString, err := ExtractTokenFromCookie(r); err == nil {
+		return tokenString
+	}
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		tokenString, _ := ExtractTokenFromHeader(authHeader)
+		return tokenString
+	}
+	return ""
+}
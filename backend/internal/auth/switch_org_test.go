@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ============ Org-Scoped Token Tests ============
+
+func TestGenerateOrgJWT(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-key-for-testing")
+
+	user := &models.User{ID: 7, Email: "member@example.com", Role: models.RoleUser}
+	membership := &models.OrganizationMember{
+		OrganizationID: 3,
+		UserID:         7,
+		Role:           models.OrgRoleAdmin,
+		Organization:   &models.Organization{ID: 3, Slug: "acme"},
+	}
+
+	token, err := GenerateOrgJWT(user, membership)
+	if err != nil {
+		t.Fatalf("GenerateOrgJWT() error = %v", err)
+	}
+
+	claims, err := ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("ValidateJWT() error = %v", err)
+	}
+
+	if !claims.HasOrganization() {
+		t.Fatal("claims should carry organization context")
+	}
+	if claims.OrgID != 3 || claims.OrgSlug != "acme" || claims.OrgRole != string(models.OrgRoleAdmin) {
+		t.Errorf("org claims = (%d, %q, %q), want (3, \"acme\", \"admin\")", claims.OrgID, claims.OrgSlug, claims.OrgRole)
+	}
+}
+
+func TestGenerateJWT_NoOrgClaims(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-key-for-testing")
+
+	token, err := GenerateJWT(&models.User{ID: 1, Email: "test@example.com"})
+	if err != nil {
+		t.Fatalf("GenerateJWT() error = %v", err)
+	}
+
+	claims, err := ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("ValidateJWT() error = %v", err)
+	}
+	if claims.HasOrganization() {
+		t.Error("GenerateJWT() should not include organization claims")
+	}
+}
+
+func TestParseClaimsAllowExpired(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-key-for-testing")
+
+	claims := &Claims{
+		UserID:  1,
+		OrgID:   3,
+		OrgSlug: "acme",
+		OrgRole: "member",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-2 * time.Hour)),
+		},
+	}
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret-key-for-testing"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	if _, err := ValidateJWT(expired); err == nil {
+		t.Fatal("ValidateJWT() should reject expired token")
+	}
+
+	parsed, err := parseClaimsAllowExpired(expired)
+	if err != nil {
+		t.Fatalf("parseClaimsAllowExpired() error = %v", err)
+	}
+	if parsed.OrgID != 3 {
+		t.Errorf("parsed.OrgID = %d, want 3", parsed.OrgID)
+	}
+
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("wrong-secret"))
+	if _, err := parseClaimsAllowExpired(forged); err == nil {
+		t.Error("parseClaimsAllowExpired() should reject tokens with an invalid signature")
+	}
+}
+
+func TestIsOrgTokenRevoked_NoOrgContext(t *testing.T) {
+	if IsOrgTokenRevoked(nil) {
+		t.Error("nil claims should not be revoked")
+	}
+	if IsOrgTokenRevoked(&Claims{UserID: 1}) {
+		t.Error("claims without org context should not be revoked")
+	}
+}
+
+func TestIssuedBefore(t *testing.T) {
+	revokedAt := time.Date(2026, time.March, 1, 12, 0, 0, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "earlier second", issuedAt: revokedAt.Add(-time.Second).Truncate(time.Second), want: true},
+		{name: "same second as the revocation", issuedAt: revokedAt.Truncate(time.Second), want: true},
+		{name: "next second", issuedAt: revokedAt.Add(time.Second).Truncate(time.Second), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := issuedBefore(tt.issuedAt, revokedAt); got != tt.want {
+				t.Errorf("issuedBefore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// ============ SwitchOrganization Tests ============
+
+func TestSwitchOrganization_NoUser(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/switch-org", bytes.NewBufferString(`{"org_slug":"acme"}`))
+	rec := httptest.NewRecorder()
+
+	SwitchOrganization(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestSwitchOrganization_InvalidJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/switch-org", bytes.NewBufferString("invalid json"))
+	req = req.WithContext(SetUserContext(req.Context(), &models.User{ID: 1}))
+	rec := httptest.NewRecorder()
+
+	SwitchOrganization(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestSwitchOrganization_ClearOrgContext(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-key-for-testing")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/switch-org", bytes.NewBufferString(`{"org_slug":""}`))
+	req = req.WithContext(SetUserContext(req.Context(), &models.User{ID: 1, Email: "test@example.com"}))
+	rec := httptest.NewRecorder()
+
+	SwitchOrganization(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var response models.AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Organization != nil {
+		t.Errorf("expected no organization, got %+v", response.Organization)
+	}
+
+	claims, err := ValidateJWT(response.Token)
+	if err != nil {
+		t.Fatalf("ValidateJWT() error = %v", err)
+	}
+	if claims.HasOrganization() {
+		t.Error("token should not carry organization claims after clearing")
+	}
+}
+
+// ============ OptionalOrganization Token Claims Tests ============
+
+func TestOptionalOrganization_FromTokenClaims(t *testing.T) {
+	middleware := NewTenantMiddleware(nil)
+
+	user := &models.User{ID: 7}
+	claims := &Claims{UserID: 7, OrgID: 3, OrgSlug: "acme", OrgRole: string(models.OrgRoleAdmin)}
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "no slug uses active org", header: ""},
+		{name: "matching slug uses token", header: "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := SetClaimsContext(SetUserContext(context.Background(), user), claims)
+			req := httptest.NewRequest(http.MethodGet, "/resource", nil).WithContext(ctx)
+			if tt.header != "" {
+				req.Header.Set("X-Organization-Slug", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			// A nil db would panic if the middleware fell back to a lookup
+			handler := middleware.OptionalOrganization(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				org := GetOrganizationFromContext(r.Context())
+				membership := GetMembershipFromContext(r.Context())
+				if org == nil || org.ID != 3 || org.Slug != "acme" {
+					t.Errorf("organization = %+v, want ID 3 slug acme", org)
+				}
+				if membership == nil || membership.Role != models.OrgRoleAdmin {
+					t.Errorf("membership = %+v, want admin role", membership)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestOptionalOrganization_SlugFromRoute(t *testing.T) {
+	middleware := NewTenantMiddleware(nil)
+
+	ctx := SetClaimsContext(SetUserContext(context.Background(), &models.User{ID: 7}), &Claims{UserID: 7, OrgID: 3, OrgSlug: "acme", OrgRole: "member"})
+
+	// The route slug takes precedence over the header; a nil db would panic if the
+	// header's slug were looked up instead
+	r := chi.NewRouter()
+	r.Route("/orgs/{orgSlug}", func(r chi.Router) {
+		r.Use(middleware.OptionalOrganization)
+		r.Get("/resource", func(w http.ResponseWriter, r *http.Request) {
+			if org := GetOrganizationFromContext(r.Context()); org == nil || org.ID != 3 {
+				t.Errorf("organization = %+v, want ID 3 from the token", org)
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/orgs/acme/resource", nil).WithContext(ctx)
+	req.Header.Set("X-Organization-Slug", "other")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestOptionalOrganization_IgnoresOtherUsersClaims(t *testing.T) {
+	middleware := NewTenantMiddleware(nil)
+
+	ctx := SetClaimsContext(SetUserContext(context.Background(), &models.User{ID: 1}), &Claims{UserID: 2, OrgID: 3, OrgSlug: "acme", OrgRole: "owner"})
+	req := httptest.NewRequest(http.MethodGet, "/resource", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler := middleware.OptionalOrganization(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetOrganizationFromContext(r.Context()) != nil {
+			t.Error("claims for another user should not set organization context")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(w, req)
+}
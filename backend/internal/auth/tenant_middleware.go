@@ -101,11 +101,45 @@ func (m *TenantMiddleware) RequireOrganization(next http.Handler) http.Handler {
 		// Add organization and membership to context
 		ctx = context.WithValue(ctx, OrganizationContextKey, org)
 		ctx = context.WithValue(ctx, MembershipContextKey, membership)
+		recordOrganization(ctx, org.ID, org.Slug)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// MemberOrganization resolves an organization by slug together with the user's active
+// membership, using cache-first lookups. Returns an error if either cannot be found.
+func (m *TenantMiddleware) MemberOrganization(ctx context.Context, orgSlug string, userID uint) (*models.Organization, *models.OrganizationMember, error) {
+	// Try cache first for organization
+	org, _ := cache.GetOrganization(ctx, orgSlug)
+	if org == nil {
+		// Cache miss - query database
+		var dbOrg models.Organization
+		if err := m.db.WithContext(ctx).Where("slug = ?", orgSlug).First(&dbOrg).Error; err != nil {
+			return nil, nil, err
+		}
+		org = &dbOrg
+		// Cache the result
+		cache.SetOrganization(ctx, org)
+	}
+
+	// Try cache first for membership
+	membership, _ := cache.GetMembership(ctx, org.ID, userID)
+	if membership == nil || membership.Status != models.MemberStatusActive {
+		// Cache miss - query database
+		var dbMembership models.OrganizationMember
+		if err := m.db.WithContext(ctx).Where("organization_id = ? AND user_id = ? AND status = ?",
+			org.ID, userID, models.MemberStatusActive).First(&dbMembership).Error; err != nil {
+			return nil, nil, err
+		}
+		membership = &dbMembership
+		// Cache the result
+		cache.SetMembership(ctx, membership)
+	}
+
+	return org, membership, nil
+}
+
 // RequireOrgRole middleware requires a minimum role within the organization
 func (m *TenantMiddleware) RequireOrgRole(minRole models.OrganizationRole) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -128,7 +162,9 @@ func (m *TenantMiddleware) RequireOrgRole(minRole models.OrganizationRole) func(
 
 // OptionalOrganization middleware optionally extracts organization context
 // Use this when org context is optional (e.g., listing all user's organizations)
-// Uses cache-first lookup to reduce database queries
+// The active organization from the token's org claims is used when no slug is given
+// (or the slug matches), without any cache or database lookup. Otherwise uses
+// cache-first lookup to reduce database queries.
 func (m *TenantMiddleware) OptionalOrganization(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -145,56 +181,58 @@ func (m *TenantMiddleware) OptionalOrganization(next http.Handler) http.Handler
 			orgSlug = r.Header.Get("X-Organization-Slug")
 		}
 
-		if orgSlug == "" {
-			next.ServeHTTP(w, r)
+		// Resolve tenancy from the token when it carries the requested (or any) organization
+		if org, membership, ok := orgContextFromClaims(ctx, user.ID, orgSlug); ok {
+			ctx = context.WithValue(ctx, OrganizationContextKey, org)
+			ctx = context.WithValue(ctx, MembershipContextKey, membership)
+			recordOrganization(ctx, org.ID, org.Slug)
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		// Try cache first for organization
-		var org *models.Organization
-		cachedOrg, _ := cache.GetOrganization(ctx, orgSlug)
-		if cachedOrg != nil {
-			org = cachedOrg
-		} else {
-			// Cache miss - query database
-			var dbOrg models.Organization
-			if err := m.db.Where("slug = ?", orgSlug).First(&dbOrg).Error; err != nil {
-				// Continue without org context
-				next.ServeHTTP(w, r)
-				return
-			}
-			org = &dbOrg
-			// Cache the result
-			cache.SetOrganization(ctx, org)
+		if orgSlug == "" {
+			next.ServeHTTP(w, r)
+			return
 		}
 
-		// Try cache first for membership
-		var membership *models.OrganizationMember
-		cachedMembership, _ := cache.GetMembership(ctx, org.ID, user.ID)
-		if cachedMembership != nil && cachedMembership.Status == models.MemberStatusActive {
-			membership = cachedMembership
-		} else {
-			// Cache miss - query database
-			var dbMembership models.OrganizationMember
-			if err := m.db.Where("organization_id = ? AND user_id = ? AND status = ?",
-				org.ID, user.ID, models.MemberStatusActive).First(&dbMembership).Error; err != nil {
-				// Continue without org context
-				next.ServeHTTP(w, r)
-				return
-			}
-			membership = &dbMembership
-			// Cache the result
-			cache.SetMembership(ctx, membership)
+		org, membership, err := m.MemberOrganization(ctx, orgSlug, user.ID)
+		if err != nil {
+			// Continue without org context
+			next.ServeHTTP(w, r)
+			return
 		}
 
 		// Add organization and membership to context
 		ctx = context.WithValue(ctx, OrganizationContextKey, org)
 		ctx = context.WithValue(ctx, MembershipContextKey, membership)
+		recordOrganization(ctx, org.ID, org.Slug)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// orgContextFromClaims builds organization and membership context from the token's org claims.
+// The organization only carries ID and slug; handlers needing other fields must load it.
+// Returns false if the token has no org context, belongs to another user, or targets a different slug.
+func orgContextFromClaims(ctx context.Context, userID uint, orgSlug string) (*models.Organization, *models.OrganizationMember, bool) {
+	claims, ok := GetClaimsFromContext(ctx)
+	if !ok || claims == nil || !claims.HasOrganization() || claims.UserID != userID {
+		return nil, nil, false
+	}
+	if orgSlug != "" && orgSlug != claims.OrgSlug {
+		return nil, nil, false
+	}
+
+	org := &models.Organization{ID: claims.OrgID, Slug: claims.OrgSlug}
+	membership := &models.OrganizationMember{
+		OrganizationID: claims.OrgID,
+		UserID:         userID,
+		Role:           models.OrganizationRole(claims.OrgRole),
+		Status:         models.MemberStatusActive,
+	}
+	return org, membership, true
+}
+
 // GetOrganizationFromContext extracts the organization from the request context
 func GetOrganizationFromContext(ctx context.Context) *models.Organization {
 	org, ok := ctx.Value(OrganizationContextKey).(*models.Organization)
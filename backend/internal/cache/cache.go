@@ -63,6 +63,7 @@ var instance Cache
 
 // Initialize sets up the cache based on configuration
 func Initialize(config *Config) error {
+	counters = nil
 	if !config.Enabled {
 		log.Info().Msg("cache disabled, using no-op cache")
 		instance = NewNoOpCache()
@@ -90,6 +91,7 @@ func Initialize(config *Config) error {
 
 		log.Info().Str("url", config.RedisURL).Msg("Redis cache initialized")
 		instance = NewMetricsCache(redisCache)
+		counters = NewRedisCounters(redisCache)
 		return nil
 	}
 
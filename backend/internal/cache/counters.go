@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Counters stores atomic integer counters for hot-path metering. The Redis implementation
+// is shared by every instance; the memory implementation only by the current process.
+type Counters interface {
+	// Increment adds delta to a counter, creating it with the given TTL if it is missing
+	Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	// IncrementExisting adds delta to a counter only if it exists
+	IncrementExisting(ctx context.Context, key string, delta int64) (bool, error)
+	// Get returns a counter's value and whether it exists
+	Get(ctx context.Context, key string) (int64, bool, error)
+	// SetIfAbsent creates a counter with value unless it already exists
+	SetIfAbsent(ctx context.Context, key string, value int64, ttl time.Duration) (bool, error)
+	// Take returns a counter's value and deletes it atomically (0 if missing)
+	Take(ctx context.Context, key string) (int64, error)
+	// Keys returns the keys of all counters starting with prefix
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// Shared reports whether the counters are visible to other instances
+	Shared() bool
+}
+
+// counters holds the shared counter store, set when Redis is in use
+var counters Counters
+
+var (
+	localCounters     *MemoryCounters
+	localCountersOnce sync.Once
+)
+
+// GetCounters returns the counter store shared across instances (Redis), or the
+// in-process store when Redis is not configured or unavailable
+func GetCounters() Counters {
+	if counters != nil {
+		return counters
+	}
+	return LocalCounters()
+}
+
+// LocalCounters returns the in-process counter store, used as a fallback when Redis errors
+func LocalCounters() Counters {
+	localCountersOnce.Do(func() {
+		localCounters = NewMemoryCounters()
+	})
+	return localCounters
+}
+
+// ============ Redis ============
+
+// incrementScript increments a counter and sets its TTL only when it has none, so
+// increments never extend the lifetime of a counter
+var incrementScript = redis.NewScript(`
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call("PTTL", KEYS[1]) == -1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value
+`)
+
+// incrementExistingScript increments a counter only if it exists
+var incrementExistingScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("INCRBY", KEYS[1], ARGV[1])
+return 1
+`)
+
+// RedisCounters implements Counters using Redis
+type RedisCounters struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisCounters creates counters sharing the connection of a Redis cache
+func NewRedisCounters(c *RedisCache) *RedisCounters {
+	return &RedisCounters{client: c.client, keyPrefix: c.keyPrefix}
+}
+
+// prefixKey adds the configured prefix to a key
+func (c *RedisCounters) prefixKey(key string) string {
+	if c.keyPrefix == "" {
+		return key
+	}
+	return c.keyPrefix + ":" + key
+}
+
+// Increment adds delta to a counter in Redis
+func (c *RedisCounters) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	value, err := incrementScript.Run(ctx, c.client, []string{c.prefixKey(key)}, delta, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, &CacheError{Op: "incr", Key: key, Err: err}
+	}
+	return value, nil
+}
+
+// IncrementExisting adds delta to a counter in Redis if it exists
+func (c *RedisCounters) IncrementExisting(ctx context.Context, key string, delta int64) (bool, error) {
+	ok, err := incrementExistingScript.Run(ctx, c.client, []string{c.prefixKey(key)}, delta).Int64()
+	if err != nil {
+		return false, &CacheError{Op: "incr", Key: key, Err: err}
+	}
+	return ok == 1, nil
+}
+
+// Get returns a counter from Redis
+func (c *RedisCounters) Get(ctx context.Context, key string) (int64, bool, error) {
+	value, err := c.client.Get(ctx, c.prefixKey(key)).Int64()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, &CacheError{Op: "get", Key: key, Err: err}
+	}
+	return value, true, nil
+}
+
+// SetIfAbsent creates a counter in Redis unless it exists
+func (c *RedisCounters) SetIfAbsent(ctx context.Context, key string, value int64, ttl time.Duration) (bool, error) {
+	ok, err := c.client.SetNX(ctx, c.prefixKey(key), value, ttl).Result()
+	if err != nil {
+		return false, &CacheError{Op: "set", Key: key, Err: err}
+	}
+	return ok, nil
+}
+
+// Take reads and deletes a counter in Redis
+func (c *RedisCounters) Take(ctx context.Context, key string) (int64, error) {
+	value, err := c.client.GetDel(ctx, c.prefixKey(key)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, &CacheError{Op: "take", Key: key, Err: err}
+	}
+	return value, nil
+}
+
+// Keys scans Redis for counters starting with prefix
+func (c *RedisCounters) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	iter := c.client.Scan(ctx, 0, c.prefixKey(prefix)+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if c.keyPrefix != "" {
+			key = strings.TrimPrefix(key, c.keyPrefix+":")
+		}
+		keys = append(keys, key)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, &CacheError{Op: "scan", Key: prefix, Err: err}
+	}
+	return keys, nil
+}
+
+// Shared reports true since Redis counters are visible to every instance
+func (c *RedisCounters) Shared() bool {
+	return true
+}
+
+// ============ Memory ============
+
+type counterItem struct {
+	value      int64
+	expiration time.Time
+}
+
+// MemoryCounters implements Counters with an in-process map
+type MemoryCounters struct {
+	mu    sync.Mutex
+	items map[string]*counterItem
+}
+
+// NewMemoryCounters creates an empty in-process counter store
+func NewMemoryCounters() *MemoryCounters {
+	return &MemoryCounters{items: make(map[string]*counterItem)}
+}
+
+// item returns a live counter, dropping it if expired. Callers must hold mu.
+func (c *MemoryCounters) item(key string) (*counterItem, bool) {
+	item, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if !item.expiration.IsZero() && time.Now().After(item.expiration) {
+		delete(c.items, key)
+		return nil, false
+	}
+	return item, true
+}
+
+// Increment adds delta to an in-process counter
+func (c *MemoryCounters) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.item(key)
+	if !ok {
+		item = &counterItem{}
+		if ttl > 0 {
+			item.expiration = time.Now().Add(ttl)
+		}
+		c.items[key] = item
+	}
+	item.value += delta
+	return item.value, nil
+}
+
+// IncrementExisting adds delta to an in-process counter if it exists
+func (c *MemoryCounters) IncrementExisting(ctx context.Context, key string, delta int64) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.item(key)
+	if !ok {
+		return false, nil
+	}
+	item.value += delta
+	return true, nil
+}
+
+// Get returns an in-process counter
+func (c *MemoryCounters) Get(ctx context.Context, key string) (int64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.item(key)
+	if !ok {
+		return 0, false, nil
+	}
+	return item.value, true, nil
+}
+
+// SetIfAbsent creates an in-process counter unless it exists
+func (c *MemoryCounters) SetIfAbsent(ctx context.Context, key string, value int64, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.item(key); ok {
+		return false, nil
+	}
+	item := &counterItem{value: value}
+	if ttl > 0 {
+		item.expiration = time.Now().Add(ttl)
+	}
+	c.items[key] = item
+	return true, nil
+}
+
+// Take reads and deletes an in-process counter
+func (c *MemoryCounters) Take(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.item(key)
+	if !ok {
+		return 0, nil
+	}
+	delete(c.items, key)
+	return item.value, nil
+}
+
+// Keys returns the in-process counters starting with prefix
+func (c *MemoryCounters) Keys(ctx context.Context, prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []string
+	for key := range c.items {
+		if _, ok := c.item(key); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Shared reports false since in-process counters are private to this instance
+func (c *MemoryCounters) Shared() bool {
+	return false
+}
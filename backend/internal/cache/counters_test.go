@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ============ MemoryCounters Tests ============
+
+func TestMemoryCounters_Increment(t *testing.T) {
+	c := NewMemoryCounters()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Increment(ctx, "hits", 2, time.Minute)
+		}()
+	}
+	wg.Wait()
+
+	value, ok, err := c.Get(ctx, "hits")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v, want existing counter", ok, err)
+	}
+	if value != 100 {
+		t.Errorf("Get() = %d, want 100", value)
+	}
+}
+
+func TestMemoryCounters_IncrementExisting(t *testing.T) {
+	c := NewMemoryCounters()
+	ctx := context.Background()
+
+	if ok, _ := c.IncrementExisting(ctx, "total", 1); ok {
+		t.Error("IncrementExisting() created a missing counter")
+	}
+	if _, ok, _ := c.Get(ctx, "total"); ok {
+		t.Error("missing counter should stay missing")
+	}
+
+	c.SetIfAbsent(ctx, "total", 10, time.Minute)
+	if ok, _ := c.IncrementExisting(ctx, "total", 5); !ok {
+		t.Error("IncrementExisting() = false for an existing counter")
+	}
+	if value, _, _ := c.Get(ctx, "total"); value != 15 {
+		t.Errorf("Get() = %d, want 15", value)
+	}
+}
+
+func TestMemoryCounters_SetIfAbsent(t *testing.T) {
+	c := NewMemoryCounters()
+	ctx := context.Background()
+
+	if ok, _ := c.SetIfAbsent(ctx, "seed", 3, time.Minute); !ok {
+		t.Error("SetIfAbsent() = false for a missing counter")
+	}
+	if ok, _ := c.SetIfAbsent(ctx, "seed", 7, time.Minute); ok {
+		t.Error("SetIfAbsent() = true for an existing counter")
+	}
+	if value, _, _ := c.Get(ctx, "seed"); value != 3 {
+		t.Errorf("Get() = %d, want 3", value)
+	}
+}
+
+func TestMemoryCounters_Take(t *testing.T) {
+	c := NewMemoryCounters()
+	ctx := context.Background()
+
+	c.Increment(ctx, "pending", 4, 0)
+
+	value, err := c.Take(ctx, "pending")
+	if err != nil || value != 4 {
+		t.Errorf("Take() = %d, %v, want 4", value, err)
+	}
+	if value, _ := c.Take(ctx, "pending"); value != 0 {
+		t.Errorf("second Take() = %d, want 0", value)
+	}
+}
+
+func TestMemoryCounters_Expiry(t *testing.T) {
+	c := NewMemoryCounters()
+	ctx := context.Background()
+
+	c.Increment(ctx, "short", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("expired counter should be missing")
+	}
+	if value, _ := c.Increment(ctx, "short", 1, time.Minute); value != 1 {
+		t.Errorf("Increment() after expiry = %d, want 1", value)
+	}
+}
+
+func TestMemoryCounters_Keys(t *testing.T) {
+	c := NewMemoryCounters()
+	ctx := context.Background()
+
+	c.Increment(ctx, "usage:pending:a", 1, 0)
+	c.Increment(ctx, "usage:pending:b", 1, 0)
+	c.Increment(ctx, "usage:total:a", 1, 0)
+
+	keys, err := c.Keys(ctx, "usage:pending:")
+	if err != nil {
+		t.Fatalf("Keys() error = %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "usage:pending:a" || keys[1] != "usage:pending:b" {
+		t.Errorf("Keys() = %v, want pending keys only", keys)
+	}
+}
+
+// ============ Counter Store Selection Tests ============
+
+func TestGetCounters_FallsBackToLocal(t *testing.T) {
+	Initialize(&Config{Enabled: false})
+
+	if GetCounters() != LocalCounters() {
+		t.Error("GetCounters() should return the local store without Redis")
+	}
+	if GetCounters().Shared() {
+		t.Error("local counters should not be shared")
+	}
+}
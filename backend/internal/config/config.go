@@ -61,6 +61,12 @@ type Config struct {
 
 	// AWS S3 configuration (optional)
 	AWS AWSConfig
+
+	// Weighted usage costs (YAML only; the usage_costs table overrides them)
+	UsageCosts UsageCostsConfig
+
+	// AI model prices used to estimate the cost of AI token usage (YAML only)
+	AIPricing AIPricingConfig
 }
 
 type ServerConfig struct {
@@ -133,6 +139,34 @@ type AWSConfig struct {
 	S3Bucket        string
 }
 
+// UsageCostsConfig weights metered usage. Costs are charged in units against the plan
+// limits; anything without a cost counts as 1 unit.
+type UsageCostsConfig struct {
+	// EventTypes sets the cost of one event of a usage type, e.g. file_upload
+	EventTypes map[string]int64
+	// Routes sets the cost of requests by chi route pattern, taking precedence over EventTypes
+	Routes []UsageRouteCost
+}
+
+type UsageRouteCost struct {
+	Pattern   string
+	Method    string // empty matches every method
+	EventType string // empty means api_call
+	Cost      int64
+}
+
+// AIPricingConfig overrides and extends the built-in AI model prices
+type AIPricingConfig struct {
+	// Models maps a model name (or name prefix) to its price
+	Models map[string]AIModelPrice
+}
+
+// AIModelPrice is the price of a model's tokens in USD per million tokens
+type AIModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Try to load .env file (optional)
@@ -617,3 +617,50 @@ func TestLoad_CustomRateLimits(t *testing.T) {
 	assert.Equal(t, 120, cfg.RateLimit.IPRequestsPerMinute)
 	assert.Equal(t, 10, cfg.RateLimit.AuthRequestsPerMinute)
 }
+
+func TestLoadWithFile_UsageCosts(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	path := t.TempDir() + "/config.yaml"
+	data := `
+usage_costs:
+  event_types:
+    file_upload: 3
+  routes:
+    - pattern: /api/ai/chat
+      method: POST
+      cost: 10
+    - pattern: /api/files/upload
+      event_type: file_upload
+      cost: 5
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	cfg, err := LoadWithFile(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), cfg.UsageCosts.EventTypes["file_upload"])
+	require.Len(t, cfg.UsageCosts.Routes, 2)
+	assert.Equal(t, UsageRouteCost{Pattern: "/api/ai/chat", Method: "POST", Cost: 10}, cfg.UsageCosts.Routes[0])
+	assert.Equal(t, "file_upload", cfg.UsageCosts.Routes[1].EventType)
+}
+
+func TestLoadWithFile_AIPricing(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	path := t.TempDir() + "/config.yaml"
+	data := `
+ai_pricing:
+  models:
+    gemini-2.0-flash:
+      input_per_million: 0.15
+      output_per_million: 0.6
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	cfg, err := LoadWithFile(path)
+
+	require.NoError(t, err)
+	require.Len(t, cfg.AIPricing.Models, 1)
+	assert.Equal(t, AIModelPrice{InputPerMillion: 0.15, OutputPerMillion: 0.6}, cfg.AIPricing.Models["gemini-2.0-flash"])
+}
@@ -19,6 +19,8 @@ type ConfigFile struct {
 	CORS       *CORSConfigFile       `yaml:"cors,omitempty"`
 	FileUpload *FileUploadConfigFile `yaml:"file_upload,omitempty"`
 	AWS        *AWSConfigFile        `yaml:"aws,omitempty"`
+	UsageCosts *UsageCostsConfigFile `yaml:"usage_costs,omitempty"`
+	AIPricing  *AIPricingConfigFile  `yaml:"ai_pricing,omitempty"`
 }
 
 type ServerConfigFile struct {
@@ -89,6 +91,27 @@ type AWSConfigFile struct {
 	// Credentials should be set via environment variables for security
 }
 
+type UsageCostsConfigFile struct {
+	EventTypes map[string]int64     `yaml:"event_types,omitempty"`
+	Routes     []UsageRouteCostFile `yaml:"routes,omitempty"`
+}
+
+type UsageRouteCostFile struct {
+	Pattern   string `yaml:"pattern"`
+	Method    string `yaml:"method,omitempty"`
+	EventType string `yaml:"event_type,omitempty"`
+	Cost      int64  `yaml:"cost"`
+}
+
+type AIPricingConfigFile struct {
+	Models map[string]AIModelPriceFile `yaml:"models,omitempty"`
+}
+
+type AIModelPriceFile struct {
+	InputPerMillion  float64 `yaml:"input_per_million"`
+	OutputPerMillion float64 `yaml:"output_per_million"`
+}
+
 // LoadFromFile loads configuration from a YAML file.
 // Returns nil if the file doesn't exist (not an error).
 func LoadFromFile(path string) (*ConfigFile, error) {
@@ -272,6 +295,23 @@ func MergeWithConfig(config *Config, fileConfig *ConfigFile) {
 			config.AWS.S3Bucket = fc.S3Bucket
 		}
 	}
+
+	// Usage costs (file only)
+	if fc := fileConfig.UsageCosts; fc != nil {
+		config.UsageCosts.EventTypes = fc.EventTypes
+		config.UsageCosts.Routes = make([]UsageRouteCost, 0, len(fc.Routes))
+		for _, route := range fc.Routes {
+			config.UsageCosts.Routes = append(config.UsageCosts.Routes, UsageRouteCost(route))
+		}
+	}
+
+	// AI pricing (file only)
+	if fc := fileConfig.AIPricing; fc != nil {
+		config.AIPricing.Models = make(map[string]AIModelPrice, len(fc.Models))
+		for model, price := range fc.Models {
+			config.AIPricing.Models[model] = AIModelPrice(price)
+		}
+	}
 }
 
 // LoadWithFile loads configuration with support for an optional YAML config file.
@@ -0,0 +1,117 @@
+package database_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/testutil"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const migrationsPath = "../../migrations"
+
+// setupMigrationDB creates an empty scratch database on the test server and points
+// database.DB at it. The shared test database is built by GORM auto-migrate, so the SQL
+// migrations need a database of their own.
+func setupMigrationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	testutil.SkipIfNotIntegration(t)
+
+	admin := testutil.GetTestDB(t)
+	name := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE DATABASE " + name).Error; err != nil {
+		t.Fatalf("Failed to create scratch database: %v", err)
+	}
+
+	cfg := testutil.DefaultTestDBConfig()
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, name,
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to scratch database: %v", err)
+	}
+
+	oldDB := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = oldDB
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)")
+	})
+
+	return db
+}
+
+func TestMigrations_UpAndDown_Integration(t *testing.T) {
+	db := setupMigrationDB(t)
+
+	if err := database.RunMigrations(migrationsPath); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	version, dirty, err := database.GetMigrationVersion(migrationsPath)
+	if err != nil || dirty || version == 0 {
+		t.Fatalf("GetMigrationVersion() = %d, dirty=%v, error=%v", version, dirty, err)
+	}
+
+	t.Run("usage event partitions absorb the default partition", func(t *testing.T) {
+		// Past the partitions the migration creates ahead, so the event lands in the default partition
+		month := time.Date(time.Now().Year()+2, time.January, 1, 0, 0, 0, 0, time.UTC)
+		partition := "usage_events_p" + month.Format("200601")
+
+		err := db.Exec(`INSERT INTO usage_events (created_at, event_type, resource, billing_period_start, billing_period_end)
+			VALUES (?, 'api_call', '/api/test', ?, ?)`, month.Add(36*time.Hour), month, month.AddDate(0, 1, -1)).Error
+		if err != nil {
+			t.Fatalf("Failed to insert usage event: %v", err)
+		}
+		if got := usageEventPartition(t, db); got != "usage_events_default" {
+			t.Fatalf("event stored in %q, want usage_events_default", got)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := db.Exec("SELECT create_usage_events_partition(?::date)", month.Format("2006-01-02")).Error; err != nil {
+				t.Fatalf("create_usage_events_partition() run %d error = %v", i+1, err)
+			}
+		}
+		if got := usageEventPartition(t, db); got != partition {
+			t.Errorf("event stored in %q after creating the partition, want %q", got, partition)
+		}
+
+		// New events for the month go to the attached partition
+		err = db.Exec(`INSERT INTO usage_events (created_at, event_type, resource, billing_period_start, billing_period_end)
+			VALUES (?, 'api_call', '/api/test', ?, ?)`, month.Add(48*time.Hour), month, month.AddDate(0, 1, -1)).Error
+		if err != nil {
+			t.Fatalf("Failed to insert usage event into the new partition: %v", err)
+		}
+		var inDefault int64
+		db.Raw("SELECT COUNT(*) FROM usage_events_default").Scan(&inDefault)
+		if inDefault != 0 {
+			t.Errorf("default partition holds %d events, want 0", inDefault)
+		}
+	})
+
+	if err := database.MigrateDown(migrationsPath, int(version)); err != nil {
+		t.Fatalf("MigrateDown() error = %v", err)
+	}
+	if err := database.RunMigrations(migrationsPath); err != nil {
+		t.Fatalf("RunMigrations() after a full rollback error = %v", err)
+	}
+}
+
+// usageEventPartition returns the partition holding the first usage event
+func usageEventPartition(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	var partition string
+	if err := db.Raw("SELECT tableoid::regclass::text FROM usage_events ORDER BY id LIMIT 1").Scan(&partition).Error; err != nil {
+		t.Fatalf("Failed to locate usage event: %v", err)
+	}
+	return partition
+}
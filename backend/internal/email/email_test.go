@@ -250,6 +250,11 @@ func TestNewTemplateManager(t *testing.T) {
 		"two_factor_code",
 		"login_new_device",
 		"account_locked",
+		"org_deletion_scheduled",
+		"org_export_ready",
+		"dunning_notice",
+		"trial_ending",
+		"usage_alert",
 	}
 
 	for _, name := range expectedTemplates {
@@ -412,6 +417,123 @@ func TestTemplateManager_Render_AccountLocked(t *testing.T) {
 	}
 }
 
+func TestTemplateManager_Render_DunningNotice(t *testing.T) {
+	tm, err := NewTemplateManager()
+	if err != nil {
+		t.Fatalf("NewTemplateManager() error = %v", err)
+	}
+
+	data := map[string]interface{}{
+		"Name":          "Test User",
+		"Stage":         "reminder",
+		"OrgName":       "Acme",
+		"AmountDue":     "$29.00",
+		"GraceEndsAt":   "March 15, 2024",
+		"DaysRemaining": 3,
+		"BillingURL":    "https://example.com/org/acme/billing",
+	}
+
+	subject, html, _, err := tm.Render("dunning_notice", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if !strings.Contains(subject, "payment failed") {
+		t.Errorf("subject = %q, want payment failed notice", subject)
+	}
+	if !strings.Contains(html, "3 days") || !strings.Contains(html, "$29.00") {
+		t.Error("HTML body does not contain days remaining and amount due")
+	}
+
+	data["Stage"] = "downgraded"
+	subject, _, _, err = tm.Render("dunning_notice", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(subject, "Acme has been moved to the free plan") {
+		t.Errorf("subject = %q, want downgrade notice", subject)
+	}
+}
+
+func TestTemplateManager_Render_TrialEnding(t *testing.T) {
+	tm, err := NewTemplateManager()
+	if err != nil {
+		t.Fatalf("NewTemplateManager() error = %v", err)
+	}
+
+	data := map[string]interface{}{
+		"Name":             "Test User",
+		"TrialEndsAt":      "March 15, 2024",
+		"DaysRemaining":    3,
+		"HasPaymentMethod": false,
+		"BillingURL":       "https://example.com/billing",
+	}
+
+	subject, html, _, err := tm.Render("trial_ending", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if !strings.Contains(subject, "ends in 3 days") {
+		t.Errorf("subject = %q, want days remaining", subject)
+	}
+	if !strings.Contains(html, "Add Payment Method") {
+		t.Error("HTML body should ask for a payment method when none is on file")
+	}
+
+	data["HasPaymentMethod"] = true
+	_, html, _, err = tm.Render("trial_ending", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(html, "continue automatically") {
+		t.Error("HTML body should confirm automatic conversion when a card is on file")
+	}
+}
+
+func TestTemplateManager_Render_UsageAlert(t *testing.T) {
+	tm, err := NewTemplateManager()
+	if err != nil {
+		t.Fatalf("NewTemplateManager() error = %v", err)
+	}
+
+	data := map[string]interface{}{
+		"Name":           "Test User",
+		"UsageLabel":     "API calls",
+		"CurrentUsage":   "9,000",
+		"UsageLimit":     "10,000",
+		"PercentageUsed": 90,
+		"Exceeded":       false,
+		"PeriodEnd":      "March 31, 2024",
+		"BillingURL":     "https://example.com/billing",
+	}
+
+	subject, html, _, err := tm.Render("usage_alert", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if !strings.Contains(subject, "used 90% of the API calls limit") {
+		t.Errorf("subject = %q, want percentage used", subject)
+	}
+	if !strings.Contains(html, "9,000 of 10,000") {
+		t.Error("HTML body should show current usage against the limit")
+	}
+
+	data["Exceeded"] = true
+	data["OrgName"] = "Acme"
+	subject, html, _, err = tm.Render("usage_alert", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(subject, "Acme has reached the API calls limit") {
+		t.Errorf("subject = %q, want organization limit reached", subject)
+	}
+	if !strings.Contains(html, "The limit has been reached") {
+		t.Error("HTML body should warn that the limit was reached")
+	}
+}
+
 func TestTemplateManager_Render_NotFound(t *testing.T) {
 	tm, err := NewTemplateManager()
 	if err != nil {
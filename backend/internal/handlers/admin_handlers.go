@@ -133,6 +133,7 @@ func GetAdminStats(w http.ResponseWriter, r *http.Request) {
 // @Tags Admin
 // @Security BearerAuth
 // @Param user_id query int false "Filter by user ID"
+// @Param organization_id query int false "Filter by organization ID"
 // @Param target_type query string false "Filter by target type"
 // @Param action query string false "Filter by action"
 // @Param start_date query string false "Filter by start date (RFC3339)"
@@ -153,6 +154,12 @@ func GetAuditLogs(w http.ResponseWriter, r *http.Request) {
 			filter.UserID = &uid
 		}
 	}
+	if orgIDStr := r.URL.Query().Get("organization_id"); orgIDStr != "" {
+		if orgID, err := strconv.ParseUint(orgIDStr, 10, 32); err == nil {
+			oid := uint(orgID)
+			filter.OrganizationID = &oid
+		}
+	}
 	filter.TargetType = r.URL.Query().Get("target_type")
 	if targetIDStr := r.URL.Query().Get("target_id"); targetIDStr != "" {
 		if targetID, err := strconv.ParseUint(targetIDStr, 10, 32); err == nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"react-golang-starter/internal/ai"
+	"react-golang-starter/internal/services"
 )
 
 // AI operation timeouts
@@ -110,6 +111,7 @@ func AIChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAIUsage(r.Context(), "chat", resp.Model, resp.Usage)
 	WriteSuccess(w, "Chat response generated", resp)
 }
 
@@ -202,6 +204,7 @@ func AIChatStream(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if chunk.Done {
+			recordAIUsage(r.Context(), "chat_stream", ai.GetService().GetModel(), chunk.Usage)
 			fmt.Fprintf(w, "data: [DONE]\n\n")
 			flusher.Flush()
 			return
@@ -268,6 +271,8 @@ func AIAnalyzeImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAIUsage(r.Context(), "analyze_image", resp.Model, resp.Usage)
+
 	WriteSuccess(w, "Image analyzed successfully", resp)
 }
 
@@ -322,12 +327,18 @@ func AIEmbeddings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Embedding batches are charged per text
+	services.SetUsageQuantity(r.Context(), int64(len(req.Texts)))
+
 	// Get the embedding model name from service
 	model := ai.GetService().GetModel()
 	if svc, ok := ai.GetService().(interface{ GetEmbeddingModel() string }); ok {
 		model = svc.GetEmbeddingModel()
 	}
 
+	// The embeddings API reports no token counts, so the input is estimated
+	recordAIUsage(r.Context(), "embeddings", model, &ai.Usage{InputTokens: ai.EstimateTokens(req.Texts)})
+
 	WriteSuccess(w, "Embeddings generated successfully", map[string]interface{}{
 		"embeddings": embeddings,
 		"model":      model,
@@ -427,5 +438,19 @@ func AIChatAdvanced(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAIUsage(r.Context(), "chat_advanced", resp.Model, resp.Usage)
 	WriteSuccess(w, "Advanced chat response generated", resp)
 }
+
+// recordAIUsage reports the token usage of an AI call for metering against AI budgets
+func recordAIUsage(ctx context.Context, operation, model string, usage *ai.Usage) {
+	if usage == nil {
+		return
+	}
+	services.AddAITokenUsage(ctx, services.AITokenUsage{
+		Model:        model,
+		Operation:    operation,
+		InputTokens:  int64(usage.InputTokens),
+		OutputTokens: int64(usage.OutputTokens),
+	})
+}
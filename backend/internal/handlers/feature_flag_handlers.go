@@ -14,17 +14,31 @@ import (
 	"react-golang-starter/internal/database"
 	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/response"
+	"react-golang-starter/internal/services"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/lib/pq"
+	"gorm.io/gorm/clause"
 )
 
-// planHierarchy defines the order of subscription plans for comparison
-var planHierarchy = map[string]int{
-	"":           0, // No plan / default
-	"free":       1,
-	"pro":        2,
-	"enterprise": 3,
+// planCatalog returns the plan catalog that orders plans and defines their features
+func planCatalog() *services.PlanCatalog {
+	return services.GetEntitlementsService().Catalog()
+}
+
+// isValidMinPlan reports whether a flag's min_plan names a plan in the catalog
+func isValidMinPlan(plan string) bool {
+	return plan == "" || planCatalog().HasPlan(plan)
+}
+
+// invalidMinPlanMessage lists the plans a flag can require
+func invalidMinPlanMessage() string {
+	plans := planCatalog().Plans()
+	keys := make([]string, 0, len(plans))
+	for _, plan := range plans {
+		keys = append(keys, plan.Key)
+	}
+	return "Invalid min_plan. Valid values: " + strings.Join(keys, ", ")
 }
 
 // GetFeatureFlags returns all feature flags
@@ -87,8 +101,8 @@ func CreateFeatureFlag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate min_plan if provided
-	if req.MinPlan != "" && req.MinPlan != "free" && req.MinPlan != "pro" && req.MinPlan != "enterprise" {
-		WriteBadRequest(w, r, "Invalid min_plan. Valid values: free, pro, enterprise")
+	if !isValidMinPlan(req.MinPlan) {
+		WriteBadRequest(w, r, invalidMinPlanMessage())
 		return
 	}
 
@@ -170,8 +184,8 @@ func UpdateFeatureFlag(w http.ResponseWriter, r *http.Request) {
 		flag.AllowedRoles = pq.StringArray(*req.AllowedRoles)
 	}
 	if req.MinPlan != nil {
-		if *req.MinPlan != "" && *req.MinPlan != "free" && *req.MinPlan != "pro" && *req.MinPlan != "enterprise" {
-			WriteBadRequest(w, r, "Invalid min_plan. Valid values: free, pro, enterprise")
+		if !isValidMinPlan(*req.MinPlan) {
+			WriteBadRequest(w, r, invalidMinPlanMessage())
 			return
 		}
 		flag.MinPlan = *req.MinPlan
@@ -247,7 +261,7 @@ func GetFeatureFlagsForUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user's effective plan (highest of personal subscription and org memberships)
-	effectivePlan := resolveEffectivePlan(ctx, user.ID)
+	plan := resolveEffectivePlan(ctx, user.ID)
 
 	// Get all feature flags
 	var flags []models.FeatureFlag
@@ -266,10 +280,18 @@ func GetFeatureFlagsForUser(w http.ResponseWriter, r *http.Request) {
 		overrideMap[override.FeatureFlagID] = override.Enabled
 	}
 
+	// Get overrides from the user's organizations
+	orgOverrideMap, err := resolveOrgOverrides(ctx, user.ID)
+	if err != nil {
+		WriteInternalError(w, r, "Failed to fetch feature flags")
+		return
+	}
+
 	// Evaluate flags for user with plan gating
 	result := make(map[string]models.UserFeatureFlagDetail)
 	for _, flag := range flags {
-		detail := evaluateFlagForUser(flag, user, effectivePlan, overrideMap)
+		detail := evaluateFlagForUser(flag, user, plan.Plan, overrideMap, orgOverrideMap)
+		applyTrialState(&detail, flag, plan)
 		result[flag.Key] = detail
 	}
 
@@ -395,6 +417,163 @@ func DeleteUserFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// GetOrganizationFeatureFlagOverrides lists feature flag overrides for an organization
+// @Summary List organization feature flag overrides
+// @Tags Feature Flags
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {array} models.OrganizationFeatureFlagOverride
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/admin/organizations/{id}/feature-flags [get]
+func GetOrganizationFeatureFlagOverrides(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		WriteBadRequest(w, r, "Invalid organization ID")
+		return
+	}
+
+	overrides := []models.OrganizationFeatureFlagOverride{}
+	if err := database.DB.WithContext(r.Context()).
+		Table("organization_feature_flags").
+		Select("feature_flags.key, organization_feature_flags.enabled, organization_feature_flags.updated_at").
+		Joins("JOIN feature_flags ON feature_flags.id = organization_feature_flags.feature_flag_id").
+		Where("organization_feature_flags.organization_id = ?", orgID).
+		Order("feature_flags.key ASC").
+		Scan(&overrides).Error; err != nil {
+		WriteInternalError(w, r, "Failed to fetch overrides")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overrides)
+}
+
+// SetOrganizationFeatureFlagOverride sets a feature flag override for every member of an organization
+// @Summary Set organization feature flag override
+// @Tags Feature Flags
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param key path string true "Feature flag key"
+// @Param body body object{enabled=bool} true "Override value"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/admin/organizations/{id}/feature-flags/{key} [put]
+func SetOrganizationFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		WriteBadRequest(w, r, "Invalid organization ID")
+		return
+	}
+
+	key := chi.URLParam(r, "key")
+
+	// Find the feature flag
+	var flag models.FeatureFlag
+	if err := database.DB.Where("key = ?", key).First(&flag).Error; err != nil {
+		WriteNotFound(w, r, "Feature flag not found")
+		return
+	}
+
+	// Verify organization exists
+	var org models.Organization
+	if err := database.DB.First(&org, orgID).Error; err != nil {
+		WriteNotFound(w, r, "Organization not found")
+		return
+	}
+
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	override := models.OrganizationFeatureFlag{
+		OrganizationID: org.ID,
+		FeatureFlagID:  flag.ID,
+		Enabled:        req.Enabled,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	// Upsert the override in one statement so concurrent requests cannot insert duplicates
+	if err := database.DB.WithContext(r.Context()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "feature_flag_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&override).Error; err != nil {
+		WriteInternalError(w, r, "Failed to set override")
+		return
+	}
+
+	// Invalidate feature flags cache so members pick up the change
+	cache.InvalidateFeatureFlags(r.Context())
+
+	response := models.SuccessResponse{
+		Success: true,
+		Message: "Organization feature flag override set successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteOrganizationFeatureFlagOverride removes a feature flag override for an organization
+// @Summary Delete organization feature flag override
+// @Tags Feature Flags
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param key path string true "Feature flag key"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/admin/organizations/{id}/feature-flags/{key} [delete]
+func DeleteOrganizationFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		WriteBadRequest(w, r, "Invalid organization ID")
+		return
+	}
+
+	key := chi.URLParam(r, "key")
+
+	// Find the feature flag
+	var flag models.FeatureFlag
+	if err := database.DB.Where("key = ?", key).First(&flag).Error; err != nil {
+		WriteNotFound(w, r, "Feature flag not found")
+		return
+	}
+
+	result := database.DB.Where("organization_id = ? AND feature_flag_id = ?", orgID, flag.ID).Delete(&models.OrganizationFeatureFlag{})
+	if result.Error != nil {
+		WriteInternalError(w, r, "Failed to delete override")
+		return
+	}
+	if result.RowsAffected == 0 {
+		WriteNotFound(w, r, "Override not found")
+		return
+	}
+
+	// Invalidate feature flags cache so members pick up the change
+	cache.InvalidateFeatureFlags(r.Context())
+
+	response := models.SuccessResponse{
+		Success: true,
+		Message: "Organization feature flag override removed successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // Helper functions
 
 func toFeatureFlagResponse(flag models.FeatureFlag) models.FeatureFlagResponse {
@@ -431,15 +610,25 @@ func isFeatureEnabledForUser(flag models.FeatureFlag, userID uint, userRole stri
 	}
 
 	// Check if user's role is in allowed roles
-	if len(flag.AllowedRoles) > 0 {
-		for _, role := range flag.AllowedRoles {
-			if role == userRole {
-				return true
-			}
+	if isRoleAllowed(flag, userRole) {
+		return true
+	}
+
+	return isInRollout(flag, userID)
+}
+
+// isRoleAllowed reports whether the role is listed in the flag's allowed roles
+func isRoleAllowed(flag models.FeatureFlag, userRole string) bool {
+	for _, role := range flag.AllowedRoles {
+		if role == userRole {
+			return true
 		}
 	}
+	return false
+}
 
-	// Check rollout percentage
+// isInRollout reports whether the user falls within the flag's rollout percentage
+func isInRollout(flag models.FeatureFlag, userID uint) bool {
 	if flag.RolloutPercentage >= 100 {
 		return true
 	}
@@ -456,26 +645,73 @@ func isFeatureEnabledForUser(flag models.FeatureFlag, userID uint, userRole stri
 	return (hash % 100) < uint32(flag.RolloutPercentage)
 }
 
-// resolveEffectivePlan determines the user's highest plan tier from personal subscription and org memberships
-func resolveEffectivePlan(ctx context.Context, userID uint) string {
-	effectivePlan := "free"
-	maxLevel := planHierarchy["free"]
-
-	// Check user's personal subscription (active or trialing)
-	var subscription models.Subscription
-	if err := database.DB.WithContext(ctx).
-		Where("user_id = ? AND organization_id IS NULL", userID).
-		Where("status IN ?", []string{models.SubscriptionStatusActive, models.SubscriptionStatusTrialing}).
-		First(&subscription).Error; err == nil {
-		// User has an active personal subscription - treat as "pro" by default
-		// In a full implementation, you'd map stripe_price_id to a plan tier
-		userPlan := "pro"
-		if planHierarchy[userPlan] > maxLevel {
-			maxLevel = planHierarchy[userPlan]
-			effectivePlan = userPlan
+// resolveOrgOverrides returns organization-level flag overrides that apply to the user.
+// If the token carries an active organization only its overrides apply. Otherwise overrides
+// from all active memberships are merged by mergeOrgOverrides.
+func resolveOrgOverrides(ctx context.Context, userID uint) (map[uint]bool, error) {
+	query := database.DB.WithContext(ctx).Model(&models.OrganizationFeatureFlag{})
+	if claims, ok := auth.GetClaimsFromContext(ctx); ok && claims.HasOrganization() {
+		query = query.Where("organization_id = ?", claims.OrgID)
+	} else {
+		query = query.Where("organization_id IN (?)", database.DB.Model(&models.OrganizationMember{}).
+			Select("organization_id").
+			Where("user_id = ? AND status = ?", userID, models.MemberStatusActive))
+	}
+
+	var overrides []models.OrganizationFeatureFlag
+	if err := query.Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+
+	return mergeOrgOverrides(overrides), nil
+}
+
+// mergeOrgOverrides combines overrides from several organizations. A flag disabled by any
+// organization stays disabled, so an organization cannot switch a feature back on for
+// members of another organization that turned it off.
+func mergeOrgOverrides(overrides []models.OrganizationFeatureFlag) map[uint]bool {
+	orgOverrideMap := make(map[uint]bool)
+	for _, override := range overrides {
+		enabled, seen := orgOverrideMap[override.FeatureFlagID]
+		orgOverrideMap[override.FeatureFlagID] = override.Enabled && (!seen || enabled)
+	}
+	return orgOverrideMap
+}
+
+// effectivePlan is the user's highest plan tier. When that tier is only reached through a
+// free trial, PaidPlan is the tier the user keeps after the trial and TrialEndsAt its end.
+type effectivePlan struct {
+	Plan        string
+	PaidPlan    string
+	TrialEndsAt *string
+}
+
+// resolveEffectivePlan determines the user's highest plan tier from the entitlements of their
+// personal subscription and org memberships. Trials that have passed their end date no longer
+// count, even before Stripe reports the outcome.
+func resolveEffectivePlan(ctx context.Context, userID uint) effectivePlan {
+	svc := services.GetEntitlementsService()
+	catalog := svc.Catalog()
+	result := effectivePlan{Plan: "free", PaidPlan: "free"}
+
+	consider := func(ent *models.Entitlements) {
+		if !ent.Trial {
+			if catalog.Rank(ent.Plan) > catalog.Rank(result.PaidPlan) {
+				result.PaidPlan = ent.Plan
+			}
+		} else if catalog.Rank(ent.Plan) > catalog.Rank(result.Plan) {
+			result.TrialEndsAt = ent.TrialEndsAt
+		}
+		if catalog.Rank(ent.Plan) > catalog.Rank(result.Plan) {
+			result.Plan = ent.Plan
 		}
 	}
 
+	// Check user's personal subscription (active, trialing or in its dunning grace period)
+	if ent, err := svc.Entitlements(ctx, services.UserSubject(userID)); err == nil {
+		consider(ent)
+	}
+
 	// Check organization memberships for higher plan tiers
 	var memberships []models.OrganizationMember
 	database.DB.WithContext(ctx).
@@ -484,24 +720,43 @@ func resolveEffectivePlan(ctx context.Context, userID uint) string {
 		Find(&memberships)
 
 	for _, m := range memberships {
-		if m.Organization != nil {
-			orgPlan := string(m.Organization.Plan)
-			if planHierarchy[orgPlan] > maxLevel {
-				maxLevel = planHierarchy[orgPlan]
-				effectivePlan = orgPlan
-			}
+		if m.Organization == nil {
+			continue
+		}
+		if ent, err := svc.OrganizationEntitlements(ctx, m.Organization); err == nil {
+			consider(ent)
 		}
 	}
 
-	return effectivePlan
+	if catalog.Rank(result.PaidPlan) >= catalog.Rank(result.Plan) {
+		result.TrialEndsAt = nil
+	}
+	return result
+}
+
+// applyTrialState marks a flag that is only enabled because of a plan still in its free trial,
+// so the UI can warn that the feature goes away when the trial ends
+func applyTrialState(detail *models.UserFeatureFlagDetail, flag models.FeatureFlag, plan effectivePlan) {
+	catalog := planCatalog()
+	required := catalog.RequiredPlan(flag.MinPlan, flag.Key)
+	if !detail.Enabled || required == "" {
+		return
+	}
+	if !catalog.Grants(plan.PaidPlan, flag.MinPlan, flag.Key) && catalog.Grants(plan.Plan, flag.MinPlan, flag.Key) {
+		detail.Trial = true
+		detail.TrialEndsAt = plan.TrialEndsAt
+		detail.RequiredPlan = required
+	}
 }
 
-// evaluateFlagForUser evaluates a flag for a specific user with plan gating
+// evaluateFlagForUser evaluates a flag for a specific user with plan gating.
+// Precedence: user override, org override, allowed role, plan requirement, rollout.
 func evaluateFlagForUser(
 	flag models.FeatureFlag,
 	user *models.User,
 	effectivePlan string,
 	overrideMap map[uint]bool,
+	orgOverrideMap map[uint]bool,
 ) models.UserFeatureFlagDetail {
 	detail := models.UserFeatureFlagDetail{
 		Enabled:      false,
@@ -515,15 +770,31 @@ func evaluateFlagForUser(
 		return detail
 	}
 
-	// Check plan requirement
-	if flag.MinPlan != "" && planHierarchy[effectivePlan] < planHierarchy[flag.MinPlan] {
+	// Organization overrides apply to every member and also bypass the remaining checks
+	if override, hasOverride := orgOverrideMap[flag.ID]; hasOverride {
+		detail.Enabled = override
+		return detail
+	}
+
+	// If flag is disabled globally, nothing below can enable it
+	if !flag.Enabled {
+		return detail
+	}
+
+	// Allowed roles get the feature regardless of plan
+	if isRoleAllowed(flag, user.Role) {
+		detail.Enabled = true
+		return detail
+	}
+
+	// Check plan requirement; flags without a minimum plan follow the plans' entitlement features
+	if catalog := planCatalog(); !catalog.Grants(effectivePlan, flag.MinPlan, flag.Key) {
 		detail.GatedByPlan = true
-		detail.RequiredPlan = flag.MinPlan
-		detail.Enabled = false
+		detail.RequiredPlan = catalog.RequiredPlan(flag.MinPlan, flag.Key)
 		return detail
 	}
 
-	// Apply standard evaluation (enabled, roles, rollout)
-	detail.Enabled = isFeatureEnabledForUser(flag, user.ID, user.Role)
+	// Apply percentage rollout
+	detail.Enabled = isInRollout(flag, user.ID)
 	return detail
 }
@@ -373,17 +373,32 @@ func TestCreateFeatureFlag_ValidRolloutPercentageValues(t *testing.T) {
 
 func TestPlanHierarchy(t *testing.T) {
 	// Verify the plan hierarchy is correct
-	if planHierarchy[""] > planHierarchy["free"] {
+	catalog := planCatalog()
+	if catalog.Rank("") > catalog.Rank("free") {
 		t.Error("empty plan should be lower than free")
 	}
-	if planHierarchy["free"] >= planHierarchy["pro"] {
+	if catalog.Rank("free") >= catalog.Rank("pro") {
 		t.Error("free plan should be lower than pro")
 	}
-	if planHierarchy["pro"] >= planHierarchy["enterprise"] {
+	if catalog.Rank("pro") >= catalog.Rank("enterprise") {
 		t.Error("pro plan should be lower than enterprise")
 	}
 }
 
+func TestIsValidMinPlan(t *testing.T) {
+	for _, plan := range []string{"", "free", "pro", "enterprise"} {
+		if !isValidMinPlan(plan) {
+			t.Errorf("isValidMinPlan(%q) = false, want true", plan)
+		}
+	}
+	if isValidMinPlan("platinum") {
+		t.Error("isValidMinPlan(platinum) = true, want false for a plan outside the catalog")
+	}
+	if got := invalidMinPlanMessage(); got != "Invalid min_plan. Valid values: free, pro, enterprise" {
+		t.Errorf("invalidMinPlanMessage() = %q", got)
+	}
+}
+
 func TestEvaluateFlagForUser(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -391,6 +406,7 @@ func TestEvaluateFlagForUser(t *testing.T) {
 		user          *models.User
 		effectivePlan string
 		overrideMap   map[uint]bool
+		orgOverrides  map[uint]bool
 		wantEnabled   bool
 		wantGated     bool
 		wantRequired  string
@@ -522,11 +538,139 @@ func TestEvaluateFlagForUser(t *testing.T) {
 			wantGated:     false,
 			wantRequired:  "",
 		},
+		{
+			name: "user override beats org override",
+			flag: models.FeatureFlag{
+				ID:      8,
+				Key:     "org_feature",
+				Enabled: true,
+			},
+			user:          &models.User{ID: 1, Role: models.RoleUser},
+			effectivePlan: "free",
+			overrideMap:   map[uint]bool{8: false},
+			orgOverrides:  map[uint]bool{8: true},
+			wantEnabled:   false,
+			wantGated:     false,
+			wantRequired:  "",
+		},
+		{
+			name: "org override enables plan-gated disabled flag",
+			flag: models.FeatureFlag{
+				ID:                9,
+				Key:               "org_feature",
+				Enabled:           false,
+				RolloutPercentage: 0,
+				MinPlan:           "enterprise",
+			},
+			user:          &models.User{ID: 1, Role: models.RoleUser},
+			effectivePlan: "free",
+			overrideMap:   map[uint]bool{},
+			orgOverrides:  map[uint]bool{9: true},
+			wantEnabled:   true,
+			wantGated:     false,
+			wantRequired:  "",
+		},
+		{
+			name: "org override disables rolled out flag",
+			flag: models.FeatureFlag{
+				ID:                10,
+				Key:               "org_feature",
+				Enabled:           true,
+				RolloutPercentage: 100,
+			},
+			user:          &models.User{ID: 1, Role: models.RoleUser},
+			effectivePlan: "enterprise",
+			overrideMap:   map[uint]bool{},
+			orgOverrides:  map[uint]bool{10: false},
+			wantEnabled:   false,
+			wantGated:     false,
+			wantRequired:  "",
+		},
+		{
+			name: "allowed role bypasses plan requirement",
+			flag: models.FeatureFlag{
+				ID:                11,
+				Key:               "beta_feature",
+				Enabled:           true,
+				RolloutPercentage: 0,
+				AllowedRoles:      []string{models.RoleAdmin},
+				MinPlan:           "enterprise",
+			},
+			user:          &models.User{ID: 1, Role: models.RoleAdmin},
+			effectivePlan: "free",
+			overrideMap:   map[uint]bool{},
+			wantEnabled:   true,
+			wantGated:     false,
+			wantRequired:  "",
+		},
+		{
+			name: "min plan takes precedence over plan entitlement feature",
+			flag: models.FeatureFlag{
+				ID:                13,
+				Key:               "advanced_analytics",
+				Enabled:           true,
+				RolloutPercentage: 100,
+				MinPlan:           "enterprise",
+			},
+			user:          &models.User{ID: 1, Role: models.RoleUser},
+			effectivePlan: "pro",
+			overrideMap:   map[uint]bool{},
+			wantEnabled:   false,
+			wantGated:     true,
+			wantRequired:  "enterprise",
+		},
+		{
+			name: "plan entitlement feature gates flag without min plan",
+			flag: models.FeatureFlag{
+				ID:                14,
+				Key:               "advanced_analytics",
+				Enabled:           true,
+				RolloutPercentage: 100,
+			},
+			user:          &models.User{ID: 1, Role: models.RoleUser},
+			effectivePlan: "free",
+			overrideMap:   map[uint]bool{},
+			wantEnabled:   false,
+			wantGated:     true,
+			wantRequired:  "pro",
+		},
+		{
+			name: "plan entitlement feature enables flag without min plan",
+			flag: models.FeatureFlag{
+				ID:                15,
+				Key:               "advanced_analytics",
+				Enabled:           true,
+				RolloutPercentage: 100,
+			},
+			user:          &models.User{ID: 1, Role: models.RoleUser},
+			effectivePlan: "pro",
+			overrideMap:   map[uint]bool{},
+			wantEnabled:   true,
+			wantGated:     false,
+			wantRequired:  "",
+		},
+		{
+			name: "plan gate applies before rollout",
+			flag: models.FeatureFlag{
+				ID:                12,
+				Key:               "beta_feature",
+				Enabled:           true,
+				RolloutPercentage: 100,
+				AllowedRoles:      []string{models.RoleAdmin},
+				MinPlan:           "pro",
+			},
+			user:          &models.User{ID: 1, Role: models.RoleUser},
+			effectivePlan: "free",
+			overrideMap:   map[uint]bool{},
+			wantEnabled:   false,
+			wantGated:     true,
+			wantRequired:  "pro",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := evaluateFlagForUser(tt.flag, tt.user, tt.effectivePlan, tt.overrideMap)
+			result := evaluateFlagForUser(tt.flag, tt.user, tt.effectivePlan, tt.overrideMap, tt.orgOverrides)
 
 			if result.Enabled != tt.wantEnabled {
 				t.Errorf("evaluateFlagForUser().Enabled = %v, want %v", result.Enabled, tt.wantEnabled)
@@ -541,6 +685,109 @@ func TestEvaluateFlagForUser(t *testing.T) {
 	}
 }
 
+func TestApplyTrialState(t *testing.T) {
+	trialEnd := "2024-03-15T00:00:00Z"
+	flag := models.FeatureFlag{ID: 1, Key: "advanced_reports", Enabled: true, MinPlan: "pro"}
+
+	tests := []struct {
+		name      string
+		flag      models.FeatureFlag
+		enabled   bool
+		plan      effectivePlan
+		wantTrial bool
+	}{
+		{"unlocked by trial", flag, true, effectivePlan{Plan: "pro", PaidPlan: "free", TrialEndsAt: &trialEnd}, true},
+		{"paid plan covers flag", flag, true, effectivePlan{Plan: "enterprise", PaidPlan: "pro", TrialEndsAt: &trialEnd}, false},
+		{"flag not enabled", flag, false, effectivePlan{Plan: "pro", PaidPlan: "free", TrialEndsAt: &trialEnd}, false},
+		{"flag without plan requirement", models.FeatureFlag{ID: 2, Key: "beta", Enabled: true}, true, effectivePlan{Plan: "pro", PaidPlan: "free"}, false},
+		{"plan feature unlocked by trial", models.FeatureFlag{ID: 3, Key: "advanced_analytics", Enabled: true}, true, effectivePlan{Plan: "pro", PaidPlan: "free", TrialEndsAt: &trialEnd}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail := models.UserFeatureFlagDetail{Enabled: tt.enabled}
+			applyTrialState(&detail, tt.flag, tt.plan)
+
+			if detail.Trial != tt.wantTrial {
+				t.Errorf("Trial = %v, want %v", detail.Trial, tt.wantTrial)
+			}
+			if tt.wantTrial && (detail.TrialEndsAt == nil || detail.RequiredPlan != "pro") {
+				t.Errorf("detail = %+v, want trial end and required plan", detail)
+			}
+		})
+	}
+}
+
+func TestMergeOrgOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []models.OrganizationFeatureFlag
+		want      map[uint]bool
+	}{
+		{"no overrides", nil, map[uint]bool{}},
+		{"single organization", []models.OrganizationFeatureFlag{
+			{OrganizationID: 1, FeatureFlagID: 1, Enabled: true},
+			{OrganizationID: 1, FeatureFlagID: 2, Enabled: false},
+		}, map[uint]bool{1: true, 2: false}},
+		{"disable wins after enable", []models.OrganizationFeatureFlag{
+			{OrganizationID: 1, FeatureFlagID: 1, Enabled: true},
+			{OrganizationID: 2, FeatureFlagID: 1, Enabled: false},
+		}, map[uint]bool{1: false}},
+		{"disable wins before enable", []models.OrganizationFeatureFlag{
+			{OrganizationID: 1, FeatureFlagID: 1, Enabled: false},
+			{OrganizationID: 2, FeatureFlagID: 1, Enabled: true},
+		}, map[uint]bool{1: false}},
+		{"enabled everywhere", []models.OrganizationFeatureFlag{
+			{OrganizationID: 1, FeatureFlagID: 1, Enabled: true},
+			{OrganizationID: 2, FeatureFlagID: 1, Enabled: true},
+		}, map[uint]bool{1: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeOrgOverrides(tt.overrides)
+			if len(got) != len(tt.want) {
+				t.Fatalf("mergeOrgOverrides() = %v, want %v", got, tt.want)
+			}
+			for flagID, want := range tt.want {
+				if enabled, ok := got[flagID]; !ok || enabled != want {
+					t.Errorf("mergeOrgOverrides()[%d] = %v, want %v", flagID, enabled, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetOrganizationFeatureFlagOverride_InvalidOrgID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/organizations/abc/feature-flags/test_flag", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "abc")
+	rctx.URLParams.Add("key", "test_flag")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	SetOrganizationFeatureFlagOverride(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("SetOrganizationFeatureFlagOverride() status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDeleteOrganizationFeatureFlagOverride_InvalidOrgID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/admin/organizations/abc/feature-flags/test_flag", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "abc")
+	rctx.URLParams.Add("key", "test_flag")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	DeleteOrganizationFeatureFlagOverride(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("DeleteOrganizationFeatureFlagOverride() status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
 func TestToFeatureFlagResponse(t *testing.T) {
 	flag := models.FeatureFlag{
 		ID:                1,
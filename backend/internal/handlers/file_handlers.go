@@ -1,17 +1,21 @@
 package handlers
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"react-golang-starter/internal/auth"
 	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/sanitize"
 	"react-golang-starter/internal/services"
+	"react-golang-starter/internal/storage"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -60,18 +64,25 @@ func NewFileHandler(fileService *services.FileService) *FileHandler {
 
 // UploadFile handles file upload requests
 // @Summary Upload a file
-// @Description Upload a file to the server. Uses S3 if configured, otherwise stores in database.
+// @Description Upload a file to the server. The file is streamed to the configured storage backend and rejected as soon as it exceeds the maximum file size.
 // @Tags files
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "File to upload"
 // @Success 200 {object} models.SuccessResponse{data=models.FileResponse}
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /files/upload [post]
 func (fh *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form
-	err := r.ParseMultipartForm(32 << 20) // 32MB max memory
+	// Bound the request body; the file itself is limited while it streams to storage
+	maxFileSize := fh.fileService.MaxFileSize()
+	if maxFileSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxFileSize+maxMultipartOverhead)
+	}
+
+	// Read the multipart body as a stream instead of buffering the form
+	reader, err := r.MultipartReader()
 	if err != nil {
 		response := models.ErrorResponse{
 			Error:   "Bad Request",
@@ -83,36 +94,32 @@ func (fh *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	file, header, err := r.FormFile("file")
+	part, err := nextFilePart(reader, "file")
 	if err != nil {
-		response := models.ErrorResponse{
-			Error:   "Bad Request",
-			Message: "Failed to get file from form",
-			Code:    http.StatusBadRequest,
+		if isFileTooLarge(err) {
+			writeFileTooLarge(w, maxFileSize)
+			return
 		}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-	defer file.Close()
-
-	// Validate file size (optional - you can set your own limits)
-	if header.Size > 10<<20 { // 10MB limit
 		response := models.ErrorResponse{
 			Error:   "Bad Request",
-			Message: "File size exceeds 10MB limit",
+			Message: "Failed to get file from form",
 			Code:    http.StatusBadRequest,
 		}
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
+	defer part.Close()
 
 	// Validate file type using magic bytes (content sniffing)
-	// Read first 512 bytes to detect actual content type
-	buf := make([]byte, 512)
-	n, err := file.Read(buf)
+	// Peek at the first 512 bytes to detect actual content type without consuming them
+	file := bufio.NewReaderSize(part, 512)
+	head, err := file.Peek(512)
 	if err != nil && err != io.EOF {
+		if isFileTooLarge(err) {
+			writeFileTooLarge(w, maxFileSize)
+			return
+		}
 		response := models.ErrorResponse{
 			Error:   "Bad Request",
 			Message: "Failed to read file content",
@@ -124,18 +131,11 @@ func (fh *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Detect actual content type from file content (magic bytes)
-	detectedType := http.DetectContentType(buf[:n])
+	detectedType := http.DetectContentType(head)
 
-	// Reset file reader for subsequent operations
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		response := models.ErrorResponse{
-			Error:   "Internal Server Error",
-			Message: "Failed to process file",
-			Code:    http.StatusInternalServerError,
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
+	header := &multipart.FileHeader{
+		Filename: part.FileName(),
+		Header:   part.Header,
 	}
 
 	// Get claimed content type from header
@@ -159,13 +159,17 @@ func (fh *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	// Sanitize filename to prevent path traversal and other attacks
 	sanitizedFilename := sanitize.Filename(header.Filename)
 	if sanitizedFilename == "" || sanitizedFilename == "unnamed" {
-		sanitizedFilename = fmt.Sprintf("file_%d", header.Size)
+		sanitizedFilename = fmt.Sprintf("file_%d", time.Now().Unix())
 	}
 	header.Filename = sanitizedFilename
 
 	// Upload file using service
 	uploadedFile, err := fh.fileService.UploadFile(r.Context(), file, header)
 	if err != nil {
+		if isFileTooLarge(err) {
+			writeFileTooLarge(w, maxFileSize)
+			return
+		}
 		response := models.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: fmt.Sprintf("Failed to upload file: %v", err),
@@ -176,6 +180,8 @@ func (fh *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	services.AddStorageUsage(r.Context(), uploadedFile.FileSize)
+
 	response := models.SuccessResponse{
 		Success: true,
 		Message: "File uploaded successfully",
@@ -188,11 +194,15 @@ func (fh *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 
 // DownloadFile handles file download requests
 // @Summary Download a file
-// @Description Download a file by its ID. For S3 files, redirects to the S3 URL.
+// @Description Download a file by its ID. Supports Range requests and conditional requests with If-None-Match against the file's ETag. For S3 files, redirects to the S3 URL.
 // @Tags files
 // @Produce octet-stream
 // @Param id path int true "File ID"
+// @Param Range header string false "Byte range, e.g. bytes=0-1023"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {file} binary
+// @Success 206 {file} binary
+// @Success 304 "Not Modified"
 // @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
@@ -268,23 +278,125 @@ func (fh *FileHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set headers for file download
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file.FileName))
-	w.Header().Set("Content-Type", file.ContentType)
-	w.Header().Set("Content-Length", strconv.FormatInt(file.FileSize, 10))
+	defer content.Close()
 
-	// Write file content
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write(content); err != nil {
+	serveFileContent(w, r, file, content)
+}
+
+// DownloadSignedFile handles downloads through signed URLs
+// @Summary Download a file through a signed URL
+// @Description Download a locally stored file using the expiring signed URL returned by GET /files/{id}/url. No authentication is required.
+// @Tags files
+// @Produce octet-stream
+// @Param id path int true "File ID"
+// @Param expires query int true "Expiry as a Unix timestamp"
+// @Param signature query string true "URL signature"
+// @Success 200 {file} binary
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /files/signed/{id} [get]
+func (fh *FileHandler) DownloadSignedFile(w http.ResponseWriter, r *http.Request) {
+	fileIDStr := chi.URLParam(r, "id")
+	fileID, err := strconv.ParseUint(fileIDStr, 10, 32)
+	if err != nil {
 		response := models.ErrorResponse{
-			Error:   "Internal Server Error",
-			Message: "Failed to write file content",
-			Code:    http.StatusInternalServerError,
+			Error:   "Bad Request",
+			Message: "Invalid file ID",
+			Code:    http.StatusBadRequest,
 		}
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	query := r.URL.Query()
+	content, file, err := fh.fileService.DownloadSignedFile(r.Context(), uint(fileID), query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidSignature) || errors.Is(err, storage.ErrURLExpired) {
+			response := models.ErrorResponse{
+				Error:   "Forbidden",
+				Message: "Invalid or expired download link",
+				Code:    http.StatusForbidden,
+			}
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		response := models.ErrorResponse{
+			Error:   "Not Found",
+			Message: "File not found",
+			Code:    http.StatusNotFound,
+		}
+		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
+
+	defer content.Close()
+
+	serveFileContent(w, r, file, content)
+}
+
+// serveFileContent streams file content as a download. http.ServeContent answers Range
+// requests and conditional requests (If-None-Match, If-Modified-Since, If-Range) from the
+// ETag and modification time.
+func serveFileContent(w http.ResponseWriter, r *http.Request, file *models.File, content io.ReadSeeker) {
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file.FileName))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", fileETag(file))
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	modTime, _ := time.Parse(time.RFC3339, file.UpdatedAt)
+	http.ServeContent(w, r, file.FileName, modTime, content)
+}
+
+// fileETag returns a strong ETag from the file's checksum, or a weak one from its metadata
+// for files uploaded before checksums were recorded
+func fileETag(file *models.File) string {
+	if file.Checksum != "" {
+		return fmt.Sprintf("%q", file.Checksum)
+	}
+	return fmt.Sprintf("W/\"%d-%d-%s\"", file.ID, file.FileSize, file.UpdatedAt)
+}
+
+// maxMultipartOverhead is the room allowed for multipart boundaries and part headers on
+// top of the maximum file size
+const maxMultipartOverhead = 64 << 10
+
+// nextFilePart returns the next file part of a multipart body with the form field name
+func nextFilePart(reader *multipart.Reader, name string) (*multipart.Part, error) {
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == name && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// isFileTooLarge reports whether an upload failed because it exceeded the size limit
+func isFileTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.Is(err, storage.ErrFileTooLarge) || errors.As(err, &maxBytesErr)
+}
+
+// writeFileTooLarge writes the response for an upload over the size limit
+func writeFileTooLarge(w http.ResponseWriter, maxFileSize int64) {
+	response := models.ErrorResponse{
+		Error:   "Request Entity Too Large",
+		Message: fmt.Sprintf("File size exceeds %dMB limit", maxFileSize>>20),
+		Code:    http.StatusRequestEntityTooLarge,
+	}
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	json.NewEncoder(w).Encode(response)
 }
 
 // GetFileInfo handles requests for file information
@@ -1,7 +1,22 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
+	"react-golang-starter/internal/storage"
+
+	"github.com/go-chi/chi/v5"
 )
 
 func TestIsAllowedMimeType(t *testing.T) {
@@ -104,3 +119,135 @@ func TestAllowedMimeTypes_DoesNotContain_DangerousTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestDownloadSignedFile_RejectsInvalidLinks(t *testing.T) {
+	fileService, err := services.NewFileService(&storage.Config{
+		Driver:     storage.DriverLocal,
+		LocalRoot:  t.TempDir(),
+		SigningKey: []byte("test-signing-key"),
+		URLExpiry:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewFileService() error = %v", err)
+	}
+	handler := NewFileHandler(fileService)
+
+	tests := []struct {
+		name       string
+		id         string
+		query      string
+		wantStatus int
+	}{
+		{"invalid file ID", "abc", "expires=1&signature=x", http.StatusBadRequest},
+		{"missing signature", "1", "", http.StatusForbidden},
+		{"wrong signature", "1", fmt.Sprintf("expires=%d&signature=deadbeef", time.Now().Add(time.Minute).Unix()), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/files/signed/"+tt.id+"?"+tt.query, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rr := httptest.NewRecorder()
+
+			handler.DownloadSignedFile(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("DownloadSignedFile() status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServeFileContent(t *testing.T) {
+	file := &models.File{
+		ID:          1,
+		FileName:    "report.txt",
+		ContentType: "text/plain",
+		FileSize:    10,
+		Checksum:    "abc123",
+		UpdatedAt:   "2026-01-02T15:04:05Z",
+	}
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{"full download", nil, http.StatusOK, "0123456789"},
+		{"range", map[string]string{"Range": "bytes=2-5"}, http.StatusPartialContent, "2345"},
+		{"matching etag", map[string]string{"If-None-Match": `"abc123"`}, http.StatusNotModified, ""},
+		{"stale etag", map[string]string{"If-None-Match": `"old"`}, http.StatusOK, "0123456789"},
+		{"unsatisfiable range", map[string]string{"Range": "bytes=20-30"}, http.StatusRequestedRangeNotSatisfiable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/files/1/download", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+
+			serveFileContent(rr, req, file, strings.NewReader("0123456789"))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.wantBody)
+			}
+			if etag := rr.Header().Get("ETag"); rr.Code < 400 && etag != `"abc123"` {
+				t.Errorf("ETag = %q, want %q", etag, `"abc123"`)
+			}
+		})
+	}
+}
+
+func TestFileETag_WithoutChecksum(t *testing.T) {
+	file := &models.File{ID: 7, FileSize: 42, UpdatedAt: "2026-01-02T15:04:05Z"}
+
+	etag := fileETag(file)
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("fileETag() = %q, want a weak ETag for files without a checksum", etag)
+	}
+	if fileETag(&models.File{ID: 7, FileSize: 43, UpdatedAt: file.UpdatedAt}) == etag {
+		t.Error("fileETag() should change when the file changes")
+	}
+}
+
+func TestNextFilePart(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("description", "not a file")
+	part, _ := writer.CreateFormFile("file", "upload.txt")
+	part.Write([]byte("file content"))
+	writer.Close()
+
+	reader := multipart.NewReader(&body, writer.Boundary())
+	filePart, err := nextFilePart(reader, "file")
+	if err != nil {
+		t.Fatalf("nextFilePart() error = %v", err)
+	}
+	if filePart.FileName() != "upload.txt" {
+		t.Errorf("FileName() = %q, want %q", filePart.FileName(), "upload.txt")
+	}
+
+	if _, err := nextFilePart(reader, "file"); err != io.EOF {
+		t.Errorf("nextFilePart() without more parts error = %v, want io.EOF", err)
+	}
+}
+
+func TestIsFileTooLarge(t *testing.T) {
+	if !isFileTooLarge(fmt.Errorf("failed to upload file: %w", storage.ErrFileTooLarge)) {
+		t.Error("isFileTooLarge() should match storage.ErrFileTooLarge")
+	}
+	if !isFileTooLarge(fmt.Errorf("multipart: NextPart: %w", &http.MaxBytesError{Limit: 10})) {
+		t.Error("isFileTooLarge() should match http.MaxBytesError")
+	}
+	if isFileTooLarge(io.ErrUnexpectedEOF) {
+		t.Error("isFileTooLarge() should not match other errors")
+	}
+}
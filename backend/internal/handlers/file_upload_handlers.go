@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/sanitize"
+	"react-golang-starter/internal/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Resumable uploads implement the tus 1.0 protocol (https://tus.io/protocols/resumable-upload)
+// with the creation, expiration and termination extensions
+const (
+	tusVersion         = "1.0.0"
+	tusExtensions      = "creation,expiration,termination"
+	tusContentType     = "application/offset+octet-stream"
+	uploadFileIDHeader = "X-File-Id"
+)
+
+// TusOptions describes the server's resumable upload support
+// @Summary Resumable upload capabilities
+// @Description Returns the supported tus protocol version, extensions and maximum upload size
+// @Tags files
+// @Success 204 "No Content"
+// @Router /files/uploads [options]
+func (fh *FileHandler) TusOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	if maxFileSize := fh.fileService.MaxFileSize(); maxFileSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(maxFileSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateUpload starts a resumable upload
+// @Summary Create a resumable upload
+// @Description Starts a tus upload of Upload-Length bytes. The file name and type are read from the filename and filetype keys of Upload-Metadata. Content is sent with PATCH requests to the returned Location.
+// @Tags files
+// @Param Tus-Resumable header string true "tus protocol version (1.0.0)"
+// @Param Upload-Length header int true "Size of the file in bytes"
+// @Param Upload-Metadata header string false "Comma-separated key and base64 value pairs"
+// @Success 201 "Created"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /files/uploads [post]
+func (fh *FileHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		writeUploadError(w, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		writeUploadError(w, http.StatusBadRequest, "Bad Request", "Upload-Length header must be a non-negative integer")
+		return
+	}
+
+	metadata := r.Header.Get("Upload-Metadata")
+	values, err := parseUploadMetadata(metadata)
+	if err != nil {
+		writeUploadError(w, http.StatusBadRequest, "Bad Request", "Invalid Upload-Metadata header")
+		return
+	}
+
+	// Sanitize filename to prevent path traversal and other attacks
+	fileName := sanitize.Filename(values["filename"])
+	if fileName == "" || fileName == "unnamed" {
+		fileName = fmt.Sprintf("file_%d", time.Now().Unix())
+	}
+
+	upload, err := fh.fileService.CreateUpload(r.Context(), userID, length, fileName, values["filetype"], metadata)
+	if err != nil {
+		if isFileTooLarge(err) {
+			writeFileTooLarge(w, fh.fileService.MaxFileSize())
+			return
+		}
+		writeUploadError(w, http.StatusInternalServerError, "Internal Server Error", fmt.Sprintf("Failed to create upload: %v", err))
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+upload.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GetUploadOffset reports how much of a resumable upload has been received
+// @Summary Get resumable upload offset
+// @Description Returns the upload's offset and length in the Upload-Offset and Upload-Length headers. Once the upload is complete, X-File-Id holds the ID of the assembled file.
+// @Tags files
+// @Param Tus-Resumable header string true "tus protocol version (1.0.0)"
+// @Param id path string true "Upload ID"
+// @Success 200 "OK"
+// @Failure 404 "Not Found"
+// @Router /files/uploads/{id} [head]
+func (fh *FileHandler) GetUploadOffset(w http.ResponseWriter, r *http.Request) {
+	upload, ok := fh.findUpload(w, r)
+	if !ok {
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchUpload appends content to a resumable upload
+// @Summary Append to a resumable upload
+// @Description Appends the request body to the upload at Upload-Offset, which must match the upload's current offset. Content received before a dropped connection is kept. When the last byte arrives the upload is stored as a file, whose ID is returned in X-File-Id.
+// @Tags files
+// @Accept application/offset+octet-stream
+// @Param Tus-Resumable header string true "tus protocol version (1.0.0)"
+// @Param Upload-Offset header int true "Offset the content starts at"
+// @Param id path string true "Upload ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /files/uploads/{id} [patch]
+func (fh *FileHandler) PatchUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := fh.findUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != tusContentType {
+		writeUploadError(w, http.StatusUnsupportedMediaType, "Unsupported Media Type", "Content-Type must be "+tusContentType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeUploadError(w, http.StatusBadRequest, "Bad Request", "Upload-Offset header must be a non-negative integer")
+		return
+	}
+	if offset != upload.Offset {
+		writeUploadError(w, http.StatusConflict, "Conflict", fmt.Sprintf("Upload-Offset %d does not match the upload offset %d", offset, upload.Offset))
+		return
+	}
+
+	var body io.Reader = r.Body
+	if offset == 0 && upload.Length > 0 {
+		// Reject a disallowed file type early from the first content. The content may arrive
+		// in pieces of any size, so the assembled content is checked again on completion.
+		content := bufio.NewReaderSize(r.Body, 512)
+		head, err := content.Peek(int(min(upload.Length, 512)))
+		if err != nil && err != io.EOF {
+			writeUploadError(w, http.StatusBadRequest, "Bad Request", "Failed to read file content")
+			return
+		}
+		if detectedType := http.DetectContentType(head); !isAllowedMimeType(detectedType) {
+			if err := fh.fileService.DeleteUpload(r.Context(), upload); err != nil {
+				writeUploadError(w, http.StatusInternalServerError, "Internal Server Error", fmt.Sprintf("Failed to delete upload: %v", err))
+				return
+			}
+			writeUploadError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("File type '%s' is not allowed", detectedType))
+			return
+		}
+		body = content
+	}
+
+	assembled := upload.FileID != nil
+	err = fh.fileService.WriteUpload(r.Context(), upload, offset, body, isAllowedMimeType)
+	// Content stored before an error is kept, so the offset is reported either way
+	setUploadHeaders(w, upload)
+	if !assembled && upload.FileID != nil {
+		services.AddStorageUsage(r.Context(), upload.Length)
+	}
+	if err != nil {
+		var typeErr *services.UploadTypeError
+		switch {
+		case errors.As(err, &typeErr):
+			writeUploadError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("File type '%s' is not allowed", typeErr.ContentType))
+		case errors.Is(err, services.ErrUploadOffsetMismatch):
+			writeUploadError(w, http.StatusConflict, "Conflict", "Upload was modified by another request")
+		case errors.Is(err, services.ErrUploadAssembling):
+			writeUploadError(w, http.StatusLocked, "Locked", "Upload is being assembled by another request")
+		case isFileTooLarge(err):
+			writeUploadError(w, http.StatusRequestEntityTooLarge, "Request Entity Too Large", "Content exceeds the upload length")
+		default:
+			writeUploadError(w, http.StatusInternalServerError, "Internal Server Error", fmt.Sprintf("Failed to write upload: %v", err))
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUpload terminates a resumable upload
+// @Summary Terminate a resumable upload
+// @Description Removes an upload and the content received so far. A file assembled from a completed upload is kept.
+// @Tags files
+// @Param Tus-Resumable header string true "tus protocol version (1.0.0)"
+// @Param id path string true "Upload ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /files/uploads/{id} [delete]
+func (fh *FileHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := fh.findUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if err := fh.fileService.DeleteUpload(r.Context(), upload); err != nil {
+		writeUploadError(w, http.StatusInternalServerError, "Internal Server Error", fmt.Sprintf("Failed to delete upload: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findUpload checks the protocol version and loads the authenticated user's upload named in
+// the URL, writing the error response when it can't
+func (fh *FileHandler) findUpload(w http.ResponseWriter, r *http.Request) (*models.FileUpload, bool) {
+	if !checkTusResumable(w, r) {
+		return nil, false
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		writeUploadError(w, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return nil, false
+	}
+
+	upload, err := fh.fileService.GetUpload(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUploadNotFound) {
+			writeUploadError(w, http.StatusNotFound, "Not Found", "Upload not found")
+			return nil, false
+		}
+		writeUploadError(w, http.StatusInternalServerError, "Internal Server Error", fmt.Sprintf("Failed to retrieve upload: %v", err))
+		return nil, false
+	}
+
+	return upload, true
+}
+
+// checkTusResumable sets the Tus-Resumable response header and rejects requests for another
+// protocol version
+func checkTusResumable(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		writeUploadError(w, http.StatusPreconditionFailed, "Precondition Failed", "Unsupported tus protocol version, expected "+tusVersion)
+		return false
+	}
+	return true
+}
+
+// setUploadHeaders sets the headers describing an upload's progress
+func setUploadHeaders(w http.ResponseWriter, upload *models.FileUpload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if expiresAt, err := time.Parse(time.RFC3339, upload.ExpiresAt); err == nil {
+		w.Header().Set("Upload-Expires", expiresAt.UTC().Format(http.TimeFormat))
+	}
+	if upload.FileID != nil {
+		w.Header().Set(uploadFileIDHeader, strconv.FormatUint(uint64(*upload.FileID), 10))
+	}
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header: comma-separated pairs of a key and
+// an optional base64 value, separated by a space
+func parseUploadMetadata(header string) (map[string]string, error) {
+	values := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return values, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("empty metadata key")
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for metadata key %q: %w", key, err)
+		}
+		values[key] = string(value)
+	}
+
+	return values, nil
+}
+
+// writeUploadError writes an error response for a resumable upload request
+func writeUploadError(w http.ResponseWriter, statusCode int, errorText, message string) {
+	response := models.ErrorResponse{
+		Error:   errorText,
+		Message: message,
+		Code:    statusCode,
+	}
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
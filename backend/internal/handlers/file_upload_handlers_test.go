@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
+	"react-golang-starter/internal/storage"
+)
+
+func newTestUploadHandler(t *testing.T) *FileHandler {
+	t.Helper()
+	fileService, err := services.NewFileService(&storage.Config{
+		Driver:      storage.DriverLocal,
+		MaxFileSize: 10 << 20,
+		LocalRoot:   t.TempDir(),
+		SigningKey:  []byte("test-signing-key"),
+		URLExpiry:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewFileService() error = %v", err)
+	}
+	return NewFileHandler(fileService)
+}
+
+func TestParseUploadMetadata(t *testing.T) {
+	values, err := parseUploadMetadata("filename cmVwb3J0LnBkZg==, filetype YXBwbGljYXRpb24vcGRm,is_confidential")
+	if err != nil {
+		t.Fatalf("parseUploadMetadata() error = %v", err)
+	}
+	if values["filename"] != "report.pdf" {
+		t.Errorf("filename = %q, want %q", values["filename"], "report.pdf")
+	}
+	if values["filetype"] != "application/pdf" {
+		t.Errorf("filetype = %q, want %q", values["filetype"], "application/pdf")
+	}
+	if v, ok := values["is_confidential"]; !ok || v != "" {
+		t.Errorf("is_confidential = %q, %v, want an empty value", v, ok)
+	}
+
+	if values, err := parseUploadMetadata(""); err != nil || len(values) != 0 {
+		t.Errorf("parseUploadMetadata(\"\") = %v, %v, want no values", values, err)
+	}
+	for _, header := range []string{"filename not-base64!", ",filename cmVwb3J0LnBkZg=="} {
+		if _, err := parseUploadMetadata(header); err == nil {
+			t.Errorf("parseUploadMetadata(%q) should return error", header)
+		}
+	}
+}
+
+func TestTusOptions(t *testing.T) {
+	handler := newTestUploadHandler(t)
+	rr := httptest.NewRecorder()
+
+	handler.TusOptions(rr, httptest.NewRequest(http.MethodOptions, "/api/files/uploads", nil))
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("TusOptions() status = %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if got := rr.Header().Get("Tus-Version"); got != tusVersion {
+		t.Errorf("Tus-Version = %q, want %q", got, tusVersion)
+	}
+	if got := rr.Header().Get("Tus-Extension"); got != tusExtensions {
+		t.Errorf("Tus-Extension = %q, want %q", got, tusExtensions)
+	}
+	if got := rr.Header().Get("Tus-Max-Size"); got != "10485760" {
+		t.Errorf("Tus-Max-Size = %q, want %q", got, "10485760")
+	}
+}
+
+func TestCreateUpload_RejectsInvalidRequests(t *testing.T) {
+	handler := newTestUploadHandler(t)
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		userID     uint
+		wantStatus int
+	}{
+		{"missing Tus-Resumable", map[string]string{"Upload-Length": "10"}, 1, http.StatusPreconditionFailed},
+		{"unsupported version", map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "10"}, 1, http.StatusPreconditionFailed},
+		{"unauthenticated", map[string]string{"Tus-Resumable": tusVersion, "Upload-Length": "10"}, 0, http.StatusUnauthorized},
+		{"missing Upload-Length", map[string]string{"Tus-Resumable": tusVersion}, 1, http.StatusBadRequest},
+		{"negative Upload-Length", map[string]string{"Tus-Resumable": tusVersion, "Upload-Length": "-1"}, 1, http.StatusBadRequest},
+		{"invalid metadata", map[string]string{"Tus-Resumable": tusVersion, "Upload-Length": "10", "Upload-Metadata": "filename !!"}, 1, http.StatusBadRequest},
+		{"over the size limit", map[string]string{"Tus-Resumable": tusVersion, "Upload-Length": "10485761"}, 1, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/files/uploads", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if tt.userID != 0 {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, tt.userID))
+			}
+			rr := httptest.NewRecorder()
+
+			handler.CreateUpload(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("CreateUpload() status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if got := rr.Header().Get("Tus-Resumable"); got != tusVersion {
+				t.Errorf("Tus-Resumable = %q, want %q", got, tusVersion)
+			}
+			if tt.wantStatus == http.StatusPreconditionFailed && rr.Header().Get("Tus-Version") != tusVersion {
+				t.Error("412 response should list the supported versions in Tus-Version")
+			}
+		})
+	}
+}
+
+func TestSetUploadHeaders(t *testing.T) {
+	fileID := uint(7)
+	upload := &models.FileUpload{
+		Length:    100,
+		Offset:    100,
+		FileID:    &fileID,
+		ExpiresAt: "2026-10-19T12:00:00Z",
+	}
+	rr := httptest.NewRecorder()
+
+	setUploadHeaders(rr, upload)
+
+	if got := rr.Header().Get("Upload-Offset"); got != "100" {
+		t.Errorf("Upload-Offset = %q, want %q", got, "100")
+	}
+	if got := rr.Header().Get("Upload-Expires"); got != "Mon, 19 Oct 2026 12:00:00 GMT" {
+		t.Errorf("Upload-Expires = %q, want RFC 7231 date", got)
+	}
+	if got := rr.Header().Get(uploadFileIDHeader); got != "7" {
+		t.Errorf("%s = %q, want %q", uploadFileIDHeader, got, "7")
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"react-golang-starter/internal/audit"
 	"react-golang-starter/internal/auth"
 	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/pagination"
 	"react-golang-starter/internal/services"
 	"react-golang-starter/internal/stripe"
 )
@@ -28,6 +31,8 @@ func NewOrgHandler(orgService *services.OrgService) *OrgHandler {
 type CreateOrganizationRequest struct {
 	Name string `json:"name" validate:"required,min=2,max=100"`
 	Slug string `json:"slug" validate:"required,min=2,max=100"`
+	// Optional plan price to start a free trial on, without a card
+	TrialPriceID string `json:"trial_price_id,omitempty"`
 }
 
 // UpdateOrganizationRequest represents the request body for updating an organization
@@ -54,6 +59,26 @@ type OrganizationResponse struct {
 	Plan      models.OrganizationPlan `json:"plan"`
 	CreatedAt string                  `json:"created_at"`
 	Role      models.OrganizationRole `json:"role,omitempty"` // User's role in this org
+
+	// Set while the organization is pending deletion
+	DeletionScheduledAt *string `json:"deletion_scheduled_at,omitempty"`
+}
+
+// newOrganizationResponse builds the API representation of an organization
+func newOrganizationResponse(org *models.Organization, role models.OrganizationRole) OrganizationResponse {
+	response := OrganizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		Slug:      org.Slug,
+		Plan:      org.Plan,
+		CreatedAt: org.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Role:      role,
+	}
+	if org.DeletionScheduledAt != nil {
+		scheduledAt := org.DeletionScheduledAt.UTC().Format("2006-01-02T15:04:05Z")
+		response.DeletionScheduledAt = &scheduledAt
+	}
+	return response
 }
 
 // MemberResponse represents a member in API responses
@@ -103,14 +128,7 @@ func (h *OrgHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
 	// Build response
 	response := make([]OrganizationResponse, 0, len(orgsWithRoles))
 	for _, owr := range orgsWithRoles {
-		response = append(response, OrganizationResponse{
-			ID:        owr.Organization.ID,
-			Name:      owr.Organization.Name,
-			Slug:      owr.Organization.Slug,
-			Plan:      owr.Organization.Plan,
-			CreatedAt: owr.Organization.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			Role:      owr.Role,
-		})
+		response = append(response, newOrganizationResponse(&owr.Organization, owr.Role))
 	}
 
 	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: response})
@@ -140,6 +158,12 @@ func (h *OrgHandler) CreateOrganization(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	stripeConfig := stripe.LoadConfig()
+	if req.TrialPriceID != "" && (!stripe.IsAvailable() || stripe.CheckoutTrialDays(r.Context(), stripeConfig, req.TrialPriceID, user.ID, nil) <= 0) {
+		WriteBadRequest(w, r, "No free trial is available for this plan")
+		return
+	}
+
 	org, err := h.orgService.CreateOrganization(r.Context(), user.ID, req.Name, req.Slug)
 	if err != nil {
 		switch {
@@ -153,6 +177,22 @@ func (h *OrgHandler) CreateOrganization(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The organization was created for the trial, so it is removed again if the trial cannot start
+	if req.TrialPriceID != "" {
+		if _, err := stripe.StartOrganizationTrial(r.Context(), stripeConfig, org, user, req.TrialPriceID); err != nil {
+			log.Error().Err(err).Uint("org_id", org.ID).Str("price_id", req.TrialPriceID).Msg("failed to start organization trial")
+			if delErr := h.orgService.DeleteOrganization(r.Context(), org); delErr != nil {
+				log.Error().Err(delErr).Uint("org_id", org.ID).Msg("failed to remove organization after trial failure")
+			}
+			if errors.Is(err, stripe.ErrTrialNotAvailable) {
+				WriteBadRequest(w, r, "No free trial is available for this plan")
+			} else {
+				WriteInternalError(w, r, "Failed to start free trial")
+			}
+			return
+		}
+	}
+
 	response := OrganizationResponse{
 		ID:        org.ID,
 		Name:      org.Name,
@@ -185,14 +225,7 @@ func (h *OrgHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := OrganizationResponse{
-		ID:        org.ID,
-		Name:      org.Name,
-		Slug:      org.Slug,
-		Plan:      org.Plan,
-		CreatedAt: org.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		Role:      membership.Role,
-	}
+	response := newOrganizationResponse(org, membership.Role)
 
 	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: response})
 }
@@ -241,30 +274,75 @@ func (h *OrgHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request)
 	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: response})
 }
 
-// DeleteOrganization deletes an organization
+// DeleteOrganization schedules an organization for deletion
 // @Summary Delete organization
-// @Description Delete an organization (owner only)
+// @Description Schedule an organization for deletion after the grace period (owner only). A data export is generated and members are notified.
 // @Tags organizations
 // @Accept json
 // @Produce json
 // @Param orgSlug path string true "Organization slug"
-// @Success 200 {object} models.SuccessResponse
+// @Success 202 {object} models.SuccessResponse{data=OrganizationResponse}
 // @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /organizations/{orgSlug} [delete]
 func (h *OrgHandler) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
 	org := auth.GetOrganizationFromContext(r.Context())
+	user, ok := auth.GetUserFromContext(r.Context())
 
 	if org == nil {
 		WriteNotFound(w, r, "Organization not found")
 		return
 	}
+	if !ok || user == nil {
+		WriteUnauthorized(w, r, "Unauthorized")
+		return
+	}
 
-	if err := h.orgService.DeleteOrganization(r.Context(), org); err != nil {
+	if err := h.orgService.ScheduleDeletion(r.Context(), org, user.ID); err != nil {
+		if errors.Is(err, services.ErrOrgPendingDeletion) {
+			WriteConflict(w, r, "Organization is already scheduled for deletion")
+			return
+		}
 		WriteInternalError(w, r, "Failed to delete organization")
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: map[string]string{"message": "Organization deleted successfully"}})
+	WriteJSON(w, http.StatusAccepted, models.SuccessResponse{
+		Success: true,
+		Message: "Organization scheduled for deletion",
+		Data:    newOrganizationResponse(org, models.OrgRoleOwner),
+	})
+}
+
+// RestoreOrganization cancels a pending organization deletion
+// @Summary Restore organization
+// @Description Cancel a scheduled organization deletion during the grace period (owner only)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} models.SuccessResponse{data=OrganizationResponse}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/restore [post]
+func (h *OrgHandler) RestoreOrganization(w http.ResponseWriter, r *http.Request) {
+	org := auth.GetOrganizationFromContext(r.Context())
+
+	if org == nil {
+		WriteNotFound(w, r, "Organization not found")
+		return
+	}
+
+	if err := h.orgService.RestoreOrganization(r.Context(), org); err != nil {
+		if errors.Is(err, services.ErrOrgNotPendingDeletion) {
+			WriteConflict(w, r, "Organization is not scheduled for deletion")
+			return
+		}
+		WriteInternalError(w, r, "Failed to restore organization")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: newOrganizationResponse(org, models.OrgRoleOwner)})
 }
 
 // ListMembers returns all members of an organization
@@ -390,6 +468,7 @@ func (h *OrgHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} models.SuccessResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /organizations/{orgSlug}/members/{userId}/role [put]
 func (h *OrgHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
 	user, ok := auth.GetUserFromContext(r.Context())
@@ -429,6 +508,8 @@ func (h *OrgHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
 			WriteBadRequest(w, r, "Cannot change your own role")
 		case errors.Is(err, services.ErrMustHaveOwner):
 			WriteBadRequest(w, r, "Organization must have at least one owner")
+		case errors.Is(err, services.ErrMemberSuspended):
+			WriteConflict(w, r, "Member is suspended; reactivate them first")
 		default:
 			WriteInternalError(w, r, "Failed to update role")
 		}
@@ -481,6 +562,104 @@ func (h *OrgHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: map[string]string{"message": "Member removed successfully"}})
 }
 
+// SuspendMember suspends a member's access to the organization
+// @Summary Suspend member
+// @Description Suspend a member's access without removing them (admin+ only). Suspended members do not use a seat.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Param userId path int true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/members/{userId}/suspend [post]
+func (h *OrgHandler) SuspendMember(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	org := auth.GetOrganizationFromContext(r.Context())
+
+	if !ok || org == nil || user == nil {
+		WriteNotFound(w, r, "Organization not found")
+		return
+	}
+
+	targetUserID, err := strconv.ParseUint(r.PathValue("userId"), 10, 32)
+	if err != nil {
+		WriteBadRequest(w, r, "Invalid user ID")
+		return
+	}
+
+	err = h.orgService.SuspendMember(r.Context(), org.ID, uint(targetUserID), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotMember):
+			WriteNotFound(w, r, "Member not found")
+		case errors.Is(err, services.ErrCannotSuspendSelf):
+			WriteBadRequest(w, r, "Cannot suspend yourself")
+		case errors.Is(err, services.ErrCannotSuspendOwner):
+			WriteBadRequest(w, r, "Cannot suspend an owner; change their role first")
+		case errors.Is(err, services.ErrInsufficientRole):
+			WriteForbidden(w, r, "Only an owner can suspend an admin")
+		case errors.Is(err, services.ErrMemberAlreadySuspended):
+			WriteConflict(w, r, "Member is already suspended")
+		default:
+			WriteInternalError(w, r, "Failed to suspend member")
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: map[string]string{"message": "Member suspended successfully"}})
+}
+
+// ReactivateMember restores a suspended member's access
+// @Summary Reactivate member
+// @Description Restore a suspended member's access (admin+ only). Requires an available seat.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Param userId path int true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/members/{userId}/reactivate [post]
+func (h *OrgHandler) ReactivateMember(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	org := auth.GetOrganizationFromContext(r.Context())
+
+	if !ok || org == nil || user == nil {
+		WriteNotFound(w, r, "Organization not found")
+		return
+	}
+
+	targetUserID, err := strconv.ParseUint(r.PathValue("userId"), 10, 32)
+	if err != nil {
+		WriteBadRequest(w, r, "Invalid user ID")
+		return
+	}
+
+	err = h.orgService.ReactivateMember(r.Context(), org.ID, uint(targetUserID), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotMember):
+			WriteNotFound(w, r, "Member not found")
+		case errors.Is(err, services.ErrMemberNotSuspended):
+			WriteConflict(w, r, "Member is not suspended")
+		case errors.Is(err, services.ErrSeatLimitExceeded):
+			WriteForbidden(w, r, "Organization has reached its seat limit")
+		default:
+			WriteInternalError(w, r, "Failed to reactivate member")
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: map[string]string{"message": "Member reactivated successfully"}})
+}
+
 // ListInvitations returns pending invitations for an organization
 // @Summary List pending invitations
 // @Description Get all pending invitations for an organization (admin+ only)
@@ -658,6 +837,112 @@ func (h *OrgHandler) LeaveOrganization(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: map[string]string{"message": "Left organization successfully"}})
 }
 
+// ListAuditLogs returns the organization's audit trail
+// @Summary List organization audit logs
+// @Description Get cursor-paginated audit logs for an organization (admin+ only)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Param user_id query int false "Filter by actor user ID"
+// @Param target_type query string false "Filter by target type"
+// @Param target_id query int false "Filter by target ID"
+// @Param action query string false "Filter by action"
+// @Param start_date query string false "Filter by start date (RFC3339)"
+// @Param end_date query string false "Filter by end date (RFC3339)"
+// @Param cursor query string false "Pagination cursor from a previous response"
+// @Param direction query string false "Pagination direction (next or prev)" default(next)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.SuccessResponse{data=models.OrgAuditLogsResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/audit-logs [get]
+func (h *OrgHandler) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	org := auth.GetOrganizationFromContext(r.Context())
+
+	if org == nil {
+		WriteNotFound(w, r, "Organization not found")
+		return
+	}
+
+	params, err := pagination.ParseParams(r)
+	if err != nil {
+		WriteBadRequest(w, r, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	var filter models.AuditLogFilter
+	if userIDStr := q.Get("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			WriteBadRequest(w, r, "Invalid user ID")
+			return
+		}
+		uid := uint(userID)
+		filter.UserID = &uid
+	}
+	if targetIDStr := q.Get("target_id"); targetIDStr != "" {
+		targetID, err := strconv.ParseUint(targetIDStr, 10, 32)
+		if err != nil {
+			WriteBadRequest(w, r, "Invalid target ID")
+			return
+		}
+		tid := uint(targetID)
+		filter.TargetID = &tid
+	}
+	filter.TargetType = q.Get("target_type")
+	filter.Action = q.Get("action")
+	filter.StartDate = q.Get("start_date")
+	filter.EndDate = q.Get("end_date")
+
+	logs, hasMore, err := audit.GetOrgAuditLogs(org.ID, filter, params.ParsedCursor, params.Direction, params.Limit)
+	if err != nil {
+		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to fetch organization audit logs")
+		WriteInternalError(w, r, "Failed to fetch audit logs")
+		return
+	}
+
+	response := models.OrgAuditLogsResponse{
+		Logs:  make([]models.AuditLogResponse, len(logs)),
+		Count: len(logs),
+	}
+	for i := range logs {
+		response.Logs[i] = logs[i].ToAuditLogResponse()
+	}
+
+	// Newer entries exist whenever we paged forward, or paged back and hit the limit
+	if len(logs) > 0 {
+		first, last := logs[0], logs[len(logs)-1]
+		if params.Direction == "prev" {
+			response.HasMore = true
+			response.NextCursor = encodeAuditCursor(last)
+			if hasMore {
+				response.PrevCursor = encodeAuditCursor(first)
+			}
+		} else {
+			response.HasMore = hasMore
+			if hasMore {
+				response.NextCursor = encodeAuditCursor(last)
+			}
+			if params.ParsedCursor != nil {
+				response.PrevCursor = encodeAuditCursor(first)
+			}
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: response})
+}
+
+// encodeAuditCursor builds a pagination cursor from an audit log entry
+func encodeAuditCursor(entry models.AuditLog) string {
+	createdAt, err := time.Parse(time.RFC3339Nano, entry.CreatedAt)
+	if err != nil {
+		return ""
+	}
+	return pagination.EncodeCursor(entry.ID, createdAt)
+}
+
 // OrgBillingResponse represents organization billing information
 type OrgBillingResponse struct {
 	Plan             models.OrganizationPlan      `json:"plan"`
@@ -665,12 +950,15 @@ type OrgBillingResponse struct {
 	Subscription     *models.SubscriptionResponse `json:"subscription,omitempty"`
 	SeatLimit        int                          `json:"seat_limit"`
 	SeatCount        int64                        `json:"seat_count"`
+	SeatsPurchased   int64                        `json:"seats_purchased,omitempty"`
 	StripeCustomerID *string                      `json:"stripe_customer_id,omitempty"`
+	Entitlements     *models.Entitlements         `json:"entitlements,omitempty"`
 }
 
 // OrgCheckoutRequest represents the request body for org checkout
 type OrgCheckoutRequest struct {
-	PriceID string `json:"price_id" validate:"required"`
+	PriceID       string `json:"price_id" validate:"required"`
+	PromotionCode string `json:"promotion_code,omitempty"`
 }
 
 // GetOrganizationBilling returns the organization's billing information
@@ -707,11 +995,58 @@ func (h *OrgHandler) GetOrganizationBilling(w http.ResponseWriter, r *http.Reque
 		StripeCustomerID: org.StripeCustomerID,
 	}
 
+	// Seat limit and plan capabilities come from the entitlements catalog
+	ent, err := services.GetEntitlementsService().OrganizationEntitlements(r.Context(), org)
+	if err != nil {
+		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to resolve org entitlements")
+	} else {
+		response.SeatLimit = ent.Seats
+		response.Entitlements = ent
+	}
+
 	// Get subscription if exists
 	sub, err := h.orgService.GetOrganizationSubscription(r.Context(), org.ID)
 	if err == nil && sub != nil {
 		subResponse := sub.ToSubscriptionResponse()
 		response.Subscription = &subResponse
+		response.SeatsPurchased = sub.SeatQuantity
+
+		// Seats bought beyond the plan allowance raise the limit, matching CanAddMember
+		if response.SeatLimit > 0 && sub.IsActiveSubscription() && int(sub.SeatQuantity) > response.SeatLimit {
+			response.SeatLimit = int(sub.SeatQuantity)
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: response})
+}
+
+// GetOrganizationInvoices returns the organization's invoice history
+// @Summary List organization invoices
+// @Description Get the organization's invoices from the local mirror, newest first (admin+ only)
+// @Tags organizations
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param status query string false "Filter by status (open, paid, void, uncollectible)"
+// @Success 200 {object} models.SuccessResponse{data=models.InvoicesResponse}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/billing/invoices [get]
+func (h *OrgHandler) GetOrganizationInvoices(w http.ResponseWriter, r *http.Request) {
+	org := auth.GetOrganizationFromContext(r.Context())
+
+	if org == nil {
+		WriteNotFound(w, r, "Organization not found")
+		return
+	}
+
+	page, limit, status := stripe.ParseInvoiceListParams(r)
+	response, err := stripe.ListInvoices(r.Context(), stripe.InvoiceFilter{OrganizationID: org.ID, Status: status}, page, limit)
+	if err != nil {
+		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to list organization invoices")
+		WriteInternalError(w, r, "Failed to fetch invoices")
+		return
 	}
 
 	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: response})
@@ -755,6 +1090,10 @@ func (h *OrgHandler) CreateOrganizationCheckout(w http.ResponseWriter, r *http.R
 		WriteBadRequest(w, r, "Price ID is required")
 		return
 	}
+	if err := stripe.ValidatePlanPrice(req.PriceID); err != nil {
+		WriteBadRequest(w, r, "Price is not part of any plan")
+		return
+	}
 
 	// Get or create Stripe customer for org
 	var customerID string
@@ -780,7 +1119,19 @@ func (h *OrgHandler) CreateOrganizationCheckout(w http.ResponseWriter, r *http.R
 	stripeConfig := stripe.LoadConfig()
 
 	// Create checkout session
-	session, err := svc.CreateCheckoutSession(r.Context(), customerID, req.PriceID, stripeConfig.SuccessURL, stripeConfig.CancelURL)
+	opts := stripe.CheckoutOptions{
+		TrialDays: stripe.CheckoutTrialDays(r.Context(), stripeConfig, req.PriceID, user.ID, &org.ID),
+	}
+	if req.PromotionCode != "" {
+		discount, err := stripe.ResolveCheckoutDiscount(r.Context(), svc, req.PromotionCode, req.PriceID)
+		if err != nil {
+			writeOrgDiscountError(w, r, org.ID, err)
+			return
+		}
+		opts.Discount = discount
+	}
+
+	session, err := svc.CreateCheckoutSession(r.Context(), customerID, req.PriceID, stripeConfig.SuccessURL, stripeConfig.CancelURL, opts)
 	if err != nil {
 		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to create checkout session")
 		WriteInternalError(w, r, "Failed to create checkout session")
@@ -839,3 +1190,190 @@ func (h *OrgHandler) CreateOrganizationBillingPortal(w http.ResponseWriter, r *h
 		URL: session.URL,
 	}})
 }
+
+// PreviewOrganizationSubscriptionChange previews the prorated invoice for an org plan change
+// @Summary Preview organization plan change
+// @Description Returns the prorated upcoming invoice if the organization switched to the given price (owner only)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Param request body models.ChangeSubscriptionRequest true "Target price"
+// @Success 200 {object} models.SuccessResponse{data=models.SubscriptionChangePreview}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/billing/subscription/preview-change [post]
+func (h *OrgHandler) PreviewOrganizationSubscriptionChange(w http.ResponseWriter, r *http.Request) {
+	svc, sub, ok := h.loadOrganizationSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ChangeSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PriceID == "" {
+		WriteBadRequest(w, r, "Price ID is required")
+		return
+	}
+
+	preview, err := stripe.PreviewPlanChange(r.Context(), svc, sub, req.PriceID, req.ProrationDate)
+	if err != nil {
+		writeOrgSubscriptionError(w, r, sub, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: preview})
+}
+
+// ChangeOrganizationSubscription switches the organization's subscription to another price
+// @Summary Change organization plan
+// @Description Switches the organization subscription to the given price with proration (owner only)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Param request body models.ChangeSubscriptionRequest true "Target price and proration date from the preview"
+// @Success 200 {object} models.SuccessResponse{data=models.SubscriptionResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/billing/subscription/change [post]
+func (h *OrgHandler) ChangeOrganizationSubscription(w http.ResponseWriter, r *http.Request) {
+	svc, sub, ok := h.loadOrganizationSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ChangeSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PriceID == "" {
+		WriteBadRequest(w, r, "Price ID is required")
+		return
+	}
+
+	updated, err := stripe.ChangePlan(r.Context(), svc, sub, req.PriceID, req.ProrationDate)
+	if err != nil {
+		writeOrgSubscriptionError(w, r, sub, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: updated.ToSubscriptionResponse()})
+}
+
+// CancelOrganizationSubscription cancels the organization's subscription
+// @Summary Cancel organization subscription
+// @Description Cancels the organization subscription at period end, or immediately (owner only)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Param request body models.CancelSubscriptionRequest false "Cancellation options"
+// @Success 200 {object} models.SuccessResponse{data=models.SubscriptionResponse}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/billing/subscription/cancel [post]
+func (h *OrgHandler) CancelOrganizationSubscription(w http.ResponseWriter, r *http.Request) {
+	svc, sub, ok := h.loadOrganizationSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CancelSubscriptionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+	}
+
+	updated, err := stripe.CancelPlan(r.Context(), svc, sub, req.Immediately)
+	if err != nil {
+		writeOrgSubscriptionError(w, r, sub, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: updated.ToSubscriptionResponse()})
+}
+
+// ResumeOrganizationSubscription removes a scheduled cancellation from the organization's subscription
+// @Summary Resume organization subscription
+// @Description Keeps an organization subscription that was set to cancel at period end (owner only)
+// @Tags organizations
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} models.SuccessResponse{data=models.SubscriptionResponse}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /organizations/{orgSlug}/billing/subscription/resume [post]
+func (h *OrgHandler) ResumeOrganizationSubscription(w http.ResponseWriter, r *http.Request) {
+	svc, sub, ok := h.loadOrganizationSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := stripe.ResumePlan(r.Context(), svc, sub)
+	if err != nil {
+		writeOrgSubscriptionError(w, r, sub, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{Success: true, Data: updated.ToSubscriptionResponse()})
+}
+
+// loadOrganizationSubscription resolves the billing service and the organization's active
+// subscription, writing an error response if either is unavailable
+func (h *OrgHandler) loadOrganizationSubscription(w http.ResponseWriter, r *http.Request) (stripe.Service, *models.Subscription, bool) {
+	org := auth.GetOrganizationFromContext(r.Context())
+	if org == nil {
+		WriteNotFound(w, r, "Organization not found")
+		return nil, nil, false
+	}
+
+	if !stripe.IsAvailable() {
+		WriteError(w, r, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Billing is not configured")
+		return nil, nil, false
+	}
+
+	sub, err := h.orgService.GetOrganizationSubscription(r.Context(), org.ID)
+	if err != nil {
+		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to get organization subscription")
+		WriteInternalError(w, r, "Failed to load subscription")
+		return nil, nil, false
+	}
+	if sub == nil || sub.Status == models.SubscriptionStatusCanceled {
+		WriteNotFound(w, r, "No active subscription found for this organization")
+		return nil, nil, false
+	}
+
+	return stripe.GetService(), sub, true
+}
+
+func writeOrgSubscriptionError(w http.ResponseWriter, r *http.Request, sub *models.Subscription, err error) {
+	status, message := stripe.SubscriptionErrorStatus(err)
+	switch status {
+	case http.StatusBadRequest:
+		WriteBadRequest(w, r, message)
+	case http.StatusNotFound:
+		WriteNotFound(w, r, message)
+	case http.StatusConflict:
+		WriteConflict(w, r, message)
+	case http.StatusServiceUnavailable:
+		WriteError(w, r, status, "SERVICE_UNAVAILABLE", message)
+	default:
+		log.Error().Err(err).Str("subscription_id", sub.StripeSubscriptionID).Msg("failed to update organization subscription")
+		WriteInternalError(w, r, message)
+	}
+}
+
+func writeOrgDiscountError(w http.ResponseWriter, r *http.Request, orgID uint, err error) {
+	status, message := stripe.DiscountErrorStatus(err)
+	switch status {
+	case http.StatusBadRequest:
+		WriteBadRequest(w, r, message)
+	case http.StatusServiceUnavailable:
+		WriteError(w, r, status, "SERVICE_UNAVAILABLE", message)
+	default:
+		log.Error().Err(err).Uint("org_id", orgID).Msg("failed to resolve promotion code")
+		WriteInternalError(w, r, message)
+	}
+}
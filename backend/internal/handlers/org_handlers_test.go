@@ -154,6 +154,36 @@ func TestOrgHandler_DeleteOrganization_NotFound(t *testing.T) {
 	}
 }
 
+func TestOrgHandler_DeleteOrganization_Unauthorized(t *testing.T) {
+	handler := NewOrgHandler(nil)
+	org := &models.Organization{ID: 1, Slug: "test-org"}
+
+	req := httptest.NewRequest(http.MethodDelete, "/organizations/test-org", nil)
+	req = req.WithContext(setOrganizationInTestContext(req.Context(), org))
+	w := httptest.NewRecorder()
+
+	handler.DeleteOrganization(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("DeleteOrganization() without user in context status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+// ============ RestoreOrganization Tests ============
+
+func TestOrgHandler_RestoreOrganization_NotFound(t *testing.T) {
+	handler := NewOrgHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/organizations/test-org/restore", nil)
+	w := httptest.NewRecorder()
+
+	handler.RestoreOrganization(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("RestoreOrganization() without org in context status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
 // ============ ListMembers Tests ============
 
 func TestOrgHandler_ListMembers_NotFound(t *testing.T) {
@@ -262,6 +292,51 @@ func TestOrgHandler_RemoveMember_NotFound(t *testing.T) {
 	}
 }
 
+// ============ SuspendMember / ReactivateMember Tests ============
+
+func TestOrgHandler_SuspendMember_NotFound(t *testing.T) {
+	handler := NewOrgHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/organizations/test-org/members/1/suspend", nil)
+	w := httptest.NewRecorder()
+
+	handler.SuspendMember(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("SuspendMember() without org in context status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestOrgHandler_SuspendMember_InvalidUserID(t *testing.T) {
+	handler := NewOrgHandler(nil)
+	org := &models.Organization{ID: 1, Slug: "test-org"}
+
+	req := httptest.NewRequest(http.MethodPost, "/organizations/test-org/members/abc/suspend", nil)
+	req.SetPathValue("userId", "abc")
+	ctx := auth.SetUserContext(req.Context(), &models.User{ID: 1})
+	req = req.WithContext(setOrganizationInTestContext(ctx, org))
+	w := httptest.NewRecorder()
+
+	handler.SuspendMember(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("SuspendMember() with invalid user ID status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOrgHandler_ReactivateMember_NotFound(t *testing.T) {
+	handler := NewOrgHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/organizations/test-org/members/1/reactivate", nil)
+	w := httptest.NewRecorder()
+
+	handler.ReactivateMember(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ReactivateMember() without org in context status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
 // ============ ListInvitations Tests ============
 
 func TestOrgHandler_ListInvitations_NotFound(t *testing.T) {
@@ -292,6 +367,49 @@ func TestOrgHandler_CancelInvitation_NotFound(t *testing.T) {
 	}
 }
 
+// ============ ListAuditLogs Tests ============
+
+func TestOrgHandler_ListAuditLogs_NotFound(t *testing.T) {
+	handler := NewOrgHandler(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/organizations/test-org/audit-logs", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListAuditLogs(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ListAuditLogs() without org in context status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestOrgHandler_ListAuditLogs_InvalidParams(t *testing.T) {
+	handler := NewOrgHandler(nil)
+	org := &models.Organization{ID: 1, Slug: "test-org"}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"invalid cursor", "cursor=not-a-cursor"},
+		{"invalid user_id", "user_id=abc"},
+		{"invalid target_id", "target_id=-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/organizations/test-org/audit-logs?"+tt.query, nil)
+			req = req.WithContext(setOrganizationInTestContext(req.Context(), org))
+			w := httptest.NewRecorder()
+
+			handler.ListAuditLogs(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("ListAuditLogs(%s) status = %v, want %v", tt.query, w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
 // ============ AcceptInvitation Tests ============
 
 func TestOrgHandler_AcceptInvitation_Unauthorized(t *testing.T) {
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/response"
+	"react-golang-starter/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// QuotaHandler handles admin management of usage quota exemptions
+type QuotaHandler struct {
+	quotaService *services.QuotaService
+}
+
+// NewQuotaHandler creates a new quota handler
+func NewQuotaHandler(quotaService *services.QuotaService) *QuotaHandler {
+	return &QuotaHandler{quotaService: quotaService}
+}
+
+// ListExemptions returns all quota exemptions
+// @Summary List quota exemptions
+// @Description Lists users and organizations exempt from usage quota enforcement (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.QuotaExemptionsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/admin/quota-exemptions [get]
+func (h *QuotaHandler) ListExemptions(w http.ResponseWriter, r *http.Request) {
+	exemptions, err := h.quotaService.ListExemptions(r.Context())
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to list quota exemptions")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, models.QuotaExemptionsResponse{
+		Exemptions: exemptions,
+		Count:      len(exemptions),
+	})
+}
+
+// CreateExemption exempts a user or organization from quota enforcement
+// @Summary Create quota exemption
+// @Description Exempts a user or organization from usage quota enforcement; usage is still recorded (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.CreateQuotaExemptionRequest true "Exempt subject"
+// @Success 201 {object} models.QuotaExemption
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/admin/quota-exemptions [post]
+func (h *QuotaHandler) CreateExemption(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		response.Unauthorized(w, r, "unauthorized")
+		return
+	}
+
+	var req models.CreateQuotaExemptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, r, "invalid request body")
+		return
+	}
+
+	exemption, err := h.quotaService.CreateExemption(r.Context(), &req, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidQuotaExemption) {
+			response.BadRequest(w, r, err.Error())
+			return
+		}
+		response.HandleErrorWithMessage(w, r, err, "failed to create quota exemption")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, exemption)
+}
+
+// DeleteExemption removes a quota exemption
+// @Summary Delete quota exemption
+// @Description Removes a quota exemption so the subject's plan limits apply again (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Exemption ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/admin/quota-exemptions/{id} [delete]
+func (h *QuotaHandler) DeleteExemption(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		response.BadRequest(w, r, "invalid exemption ID")
+		return
+	}
+
+	if err := h.quotaService.DeleteExemption(r.Context(), uint(id)); err != nil {
+		if errors.Is(err, services.ErrQuotaExemptionNotFound) {
+			response.NotFound(w, r, "quota exemption not found")
+			return
+		}
+		response.HandleErrorWithMessage(w, r, err, "failed to delete quota exemption")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": "quota exemption removed",
+	})
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -52,13 +55,22 @@ func (h *UsageHandler) GetCurrentUsage(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, summary)
 }
 
-// GetUsageHistory returns usage history for past billing periods
+// GetUsageHistory returns usage history for past billing periods, or a usage series from
+// the usage rollups when granularity, group_by, from, to or format is given
 // @Summary Get usage history
-// @Description Returns usage metrics for past billing periods
+// @Description Returns usage metrics for past billing periods. With series parameters, returns usage per hour, day or month grouped by event type or resource, as JSON or CSV.
 // @Tags Usage
 // @Produce json
+// @Produce text/csv
 // @Param months query int false "Number of months to retrieve (default 6)"
+// @Param granularity query string false "Series granularity: hour, day (default) or month"
+// @Param group_by query string false "Series grouping: event_type (default) or resource"
+// @Param from query string false "Series start (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "Series end, exclusive (RFC3339 or YYYY-MM-DD, default now)"
+// @Param format query string false "json (default) or csv"
 // @Success 200 {array} models.UsageSummaryResponse
+// @Success 200 {object} models.UsageHistorySeriesResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /api/usage/history [get]
@@ -71,14 +83,12 @@ func (h *UsageHandler) GetUsageHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	months := 6 // default
-	if m := r.URL.Query().Get("months"); m != "" {
-		if parsed, err := strconv.Atoi(m); err == nil && parsed > 0 && parsed <= 24 {
-			months = parsed
-		}
+	if isUsageSeriesRequest(r) {
+		h.writeUsageSeries(w, r, &userID, nil)
+		return
 	}
 
-	history, err := h.usageService.GetUsageHistory(ctx, &userID, nil, months)
+	history, err := h.usageService.GetUsageHistory(ctx, &userID, nil, historyMonths(r))
 	if err != nil {
 		response.HandleErrorWithMessage(w, r, err, "failed to get usage history")
 		return
@@ -164,6 +174,475 @@ func (h *UsageHandler) AcknowledgeAlert(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// GetOrgUsage returns the organization's usage summary for the current billing period
+// @Summary Get organization usage summary
+// @Description Returns the organization's usage metrics for the current billing period (admin+)
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} models.UsageSummaryResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/organizations/{orgSlug}/usage [get]
+func (h *UsageHandler) GetOrgUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	org := auth.GetOrganizationFromContext(ctx)
+	if org == nil {
+		response.BadRequest(w, r, "organization context required")
+		return
+	}
+
+	summary, err := h.usageService.GetCurrentUsageSummary(ctx, nil, &org.ID)
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to get usage summary")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, summary)
+}
+
+// GetOrgUsageHistory returns the organization's usage for past billing periods, or a usage
+// series from the usage rollups when granularity, group_by, from, to or format is given
+// @Summary Get organization usage history
+// @Description Returns the organization's usage metrics for past billing periods (admin+). With series parameters, returns usage per hour, day or month grouped by event type or resource, as JSON or CSV.
+// @Tags Organizations
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param months query int false "Number of months to retrieve (default 6)"
+// @Param granularity query string false "Series granularity: hour, day (default) or month"
+// @Param group_by query string false "Series grouping: event_type (default) or resource"
+// @Param from query string false "Series start (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "Series end, exclusive (RFC3339 or YYYY-MM-DD, default now)"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} map[string]interface{}
+// @Success 200 {object} models.UsageHistorySeriesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/organizations/{orgSlug}/usage/history [get]
+func (h *UsageHandler) GetOrgUsageHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	org := auth.GetOrganizationFromContext(ctx)
+	if org == nil {
+		response.BadRequest(w, r, "organization context required")
+		return
+	}
+
+	if isUsageSeriesRequest(r) {
+		h.writeUsageSeries(w, r, nil, &org.ID)
+		return
+	}
+
+	history, err := h.usageService.GetUsageHistory(ctx, nil, &org.ID, historyMonths(r))
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to get usage history")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]interface{}{
+		"history": history,
+		"count":   len(history),
+	})
+}
+
+// GetOrgAlerts returns the organization's unacknowledged usage alerts
+// @Summary Get organization usage alerts
+// @Description Returns the organization's unacknowledged usage alerts (admin+)
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/organizations/{orgSlug}/usage/alerts [get]
+func (h *UsageHandler) GetOrgAlerts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	org := auth.GetOrganizationFromContext(ctx)
+	if org == nil {
+		response.BadRequest(w, r, "organization context required")
+		return
+	}
+
+	alerts, err := h.usageService.GetUnacknowledgedAlerts(ctx, nil, &org.ID)
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to get alerts")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]interface{}{
+		"alerts": alerts,
+		"count":  len(alerts),
+	})
+}
+
+// AcknowledgeOrgAlert marks an organization's usage alert as acknowledged
+// @Summary Acknowledge organization usage alert
+// @Description Marks one of the organization's usage alerts as acknowledged (admin+)
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Alert ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/organizations/{orgSlug}/usage/alerts/{id}/acknowledge [post]
+func (h *UsageHandler) AcknowledgeOrgAlert(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok || userID == 0 {
+		response.Unauthorized(w, r, "unauthorized")
+		return
+	}
+
+	org := auth.GetOrganizationFromContext(ctx)
+	if org == nil {
+		response.BadRequest(w, r, "organization context required")
+		return
+	}
+
+	alertID, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(w, r, "invalid alert ID")
+		return
+	}
+
+	if err := h.usageService.AcknowledgeOrgAlert(ctx, org.ID, uint(alertID), userID); err != nil {
+		response.NotFound(w, r, "alert not found")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": "alert acknowledged",
+	})
+}
+
+// GetAlertPreferences returns how the user's usage alerts are delivered
+// @Summary Get usage alert preferences
+// @Description Returns the email thresholds for the user's usage alerts
+// @Tags Usage
+// @Produce json
+// @Success 200 {object} models.UsageAlertPreferencesResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/usage/alerts/preferences [get]
+func (h *UsageHandler) GetAlertPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		response.Unauthorized(w, r, "unauthorized")
+		return
+	}
+
+	h.writeAlertPreferences(w, r, &userID, nil)
+}
+
+// UpdateAlertPreferences updates how the user's usage alerts are delivered
+// @Summary Update usage alert preferences
+// @Description Updates the email thresholds for the user's usage alerts. Webhooks are only available to organizations.
+// @Tags Usage
+// @Accept json
+// @Produce json
+// @Param body body models.UpdateUsageAlertPreferencesRequest true "Preferences to change"
+// @Success 200 {object} models.UsageAlertPreferencesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/usage/alerts/preferences [put]
+func (h *UsageHandler) UpdateAlertPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		response.Unauthorized(w, r, "unauthorized")
+		return
+	}
+
+	h.updateAlertPreferences(w, r, &userID, nil)
+}
+
+// GetOrgAlertPreferences returns how the organization's usage alerts are delivered
+// @Summary Get organization usage alert preferences
+// @Description Returns the email thresholds and webhook endpoint for the organization's usage alerts (admin+)
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} models.UsageAlertPreferencesResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/organizations/{orgSlug}/usage/alerts/preferences [get]
+func (h *UsageHandler) GetOrgAlertPreferences(w http.ResponseWriter, r *http.Request) {
+	org := auth.GetOrganizationFromContext(r.Context())
+	if org == nil {
+		response.BadRequest(w, r, "organization context required")
+		return
+	}
+
+	h.writeAlertPreferences(w, r, nil, &org.ID)
+}
+
+// UpdateOrgAlertPreferences updates how the organization's usage alerts are delivered
+// @Summary Update organization usage alert preferences
+// @Description Updates the email thresholds and webhook endpoint for the organization's usage alerts (admin+).
+// @Description The webhook signing secret is returned only when it is generated or rotated.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param body body models.UpdateUsageAlertPreferencesRequest true "Preferences to change"
+// @Success 200 {object} models.UsageAlertPreferencesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/organizations/{orgSlug}/usage/alerts/preferences [put]
+func (h *UsageHandler) UpdateOrgAlertPreferences(w http.ResponseWriter, r *http.Request) {
+	org := auth.GetOrganizationFromContext(r.Context())
+	if org == nil {
+		response.BadRequest(w, r, "organization context required")
+		return
+	}
+
+	h.updateAlertPreferences(w, r, nil, &org.ID)
+}
+
+// writeAlertPreferences writes the usage alert preferences of a user or organization
+func (h *UsageHandler) writeAlertPreferences(w http.ResponseWriter, r *http.Request, userID, orgID *uint) {
+	prefs, err := h.usageService.GetAlertPreferences(r.Context(), userID, orgID)
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to get usage alert preferences")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, prefs.ToResponse())
+}
+
+// updateAlertPreferences applies a preferences update for a user or organization
+func (h *UsageHandler) updateAlertPreferences(w http.ResponseWriter, r *http.Request, userID, orgID *uint) {
+	var req models.UpdateUsageAlertPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, r, "invalid request body")
+		return
+	}
+
+	prefs, err := h.usageService.UpdateAlertPreferences(r.Context(), userID, orgID, req)
+	if errors.Is(err, services.ErrInvalidUsageAlertPreferences) {
+		response.BadRequest(w, r, err.Error())
+		return
+	}
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to update usage alert preferences")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, prefs)
+}
+
+// GetAIUsage returns the user's AI token usage by model for the current billing period
+// @Summary Get AI token usage
+// @Description Returns the user's AI token usage and estimated cost by model for the current billing period
+// @Tags Usage
+// @Produce json
+// @Success 200 {object} models.AIUsageResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/usage/ai [get]
+func (h *UsageHandler) GetAIUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		response.Unauthorized(w, r, "unauthorized")
+		return
+	}
+
+	usage, err := h.usageService.GetAIUsage(r.Context(), &userID, nil)
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to get AI usage")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, usage)
+}
+
+// GetOrgAIUsage returns the organization's AI token usage by model and its AI budget
+// @Summary Get organization AI token usage
+// @Description Returns the organization's AI token usage and estimated cost by model for the current billing period, with its monthly AI token budget (admin+)
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} models.AIUsageResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/organizations/{orgSlug}/usage/ai [get]
+func (h *UsageHandler) GetOrgAIUsage(w http.ResponseWriter, r *http.Request) {
+	org := auth.GetOrganizationFromContext(r.Context())
+	if org == nil {
+		response.BadRequest(w, r, "organization context required")
+		return
+	}
+
+	usage, err := h.usageService.GetAIUsage(r.Context(), nil, &org.ID)
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to get AI usage")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, usage)
+}
+
+// UpdateOrgAIBudget sets the organization's monthly AI token budget
+// @Summary Update organization AI token budget
+// @Description Sets the organization's monthly AI token budget (admin+). The budget can only lower the plan's AI token limit; 0 removes it.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param body body models.UpdateAIBudgetRequest true "Monthly AI token budget"
+// @Success 200 {object} models.AIUsageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/organizations/{orgSlug}/usage/ai [put]
+func (h *UsageHandler) UpdateOrgAIBudget(w http.ResponseWriter, r *http.Request) {
+	org := auth.GetOrganizationFromContext(r.Context())
+	if org == nil {
+		response.BadRequest(w, r, "organization context required")
+		return
+	}
+
+	var req models.UpdateAIBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, r, "invalid request body")
+		return
+	}
+
+	err := h.usageService.SetOrgAIBudget(r.Context(), org, req.MonthlyTokenBudget)
+	if errors.Is(err, services.ErrInvalidAIBudget) {
+		response.BadRequest(w, r, err.Error())
+		return
+	}
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to update AI token budget")
+		return
+	}
+
+	usage, err := h.usageService.GetAIUsage(r.Context(), nil, &org.ID)
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to get AI usage")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, usage)
+}
+
+// historyMonths returns the number of months of usage history requested (default 6, max 24)
+func historyMonths(r *http.Request) int {
+	months := 6 // default
+	if m := r.URL.Query().Get("months"); m != "" {
+		if parsed, err := strconv.Atoi(m); err == nil && parsed > 0 && parsed <= 24 {
+			months = parsed
+		}
+	}
+	return months
+}
+
+// isUsageSeriesRequest reports whether a history request asks for a usage series
+func isUsageSeriesRequest(r *http.Request) bool {
+	query := r.URL.Query()
+	for _, param := range []string{"granularity", "group_by", "from", "to", "format"} {
+		if query.Has(param) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeUsageSeries writes the usage series of a user or organization as JSON or CSV
+func (h *UsageHandler) writeUsageSeries(w http.ResponseWriter, r *http.Request, userID, orgID *uint) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		response.BadRequest(w, r, "format must be json or csv")
+		return
+	}
+
+	from, err := parseHistoryTime(query.Get("from"))
+	if err != nil {
+		response.BadRequest(w, r, "from must be an RFC3339 timestamp or YYYY-MM-DD date")
+		return
+	}
+	to, err := parseHistoryTime(query.Get("to"))
+	if err != nil {
+		response.BadRequest(w, r, "to must be an RFC3339 timestamp or YYYY-MM-DD date")
+		return
+	}
+
+	series, err := h.usageService.GetUsageSeries(r.Context(), services.UsageHistoryQuery{
+		UserID:         userID,
+		OrganizationID: orgID,
+		Granularity:    query.Get("granularity"),
+		GroupBy:        query.Get("group_by"),
+		From:           from,
+		To:             to,
+	})
+	if errors.Is(err, services.ErrInvalidUsageHistoryQuery) {
+		response.BadRequest(w, r, err.Error())
+		return
+	}
+	if err != nil {
+		response.HandleErrorWithMessage(w, r, err, "failed to get usage history")
+		return
+	}
+
+	if format != "csv" {
+		response.JSON(w, http.StatusOK, series)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="usage-%s.csv"`, series.Granularity))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"bucket_start", series.GroupBy, "quantity", "event_count"})
+	for _, point := range series.Points {
+		_ = writer.Write([]string{
+			point.BucketStart,
+			point.Group,
+			strconv.FormatInt(point.Quantity, 10),
+			strconv.FormatInt(point.EventCount, 10),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Warn().Err(err).Msg("failed to write usage history CSV")
+	}
+}
+
+// parseHistoryTime parses an RFC3339 timestamp or a YYYY-MM-DD date (UTC); empty is zero
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
 // RecordUsage records a usage event
 // @Summary Record usage event
 // @Description Records a usage event for metering purposes
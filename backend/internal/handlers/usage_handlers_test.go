@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"react-golang-starter/internal/auth"
 	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
 )
 
 // ============ UsageHandler Creation Tests ============
@@ -68,6 +70,72 @@ func TestUsageHandler_GetUsageHistory_Unauthorized(t *testing.T) {
 	}
 }
 
+func TestUsageHandler_GetUsageHistory_InvalidSeriesParams(t *testing.T) {
+	handler := NewUsageHandler(services.NewUsageServiceWithRepo(nil, nil, nil, nil))
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown granularity", "?granularity=week"},
+		{"unknown grouping", "?group_by=ip_address"},
+		{"unknown format", "?format=xml"},
+		{"invalid from", "?from=yesterday"},
+		{"reversed range", "?granularity=day&from=2026-03-10&to=2026-03-01"},
+		{"hourly range too long", "?granularity=hour&from=2025-01-01&to=2026-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/usage/history"+tt.query, nil)
+			req = req.WithContext(auth.SetUserContext(req.Context(), &models.User{ID: 1, Email: "test@example.com"}))
+			w := httptest.NewRecorder()
+
+			handler.GetUsageHistory(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("GetUsageHistory(%q) status = %v, want %v", tt.query, w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestIsUsageSeriesRequest(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", false},
+		{"?months=12", false},
+		{"?granularity=hour", true},
+		{"?group_by=resource", true},
+		{"?format=csv", true},
+		{"?from=2026-01-01", true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/usage/history"+tt.query, nil)
+		if got := isUsageSeriesRequest(req); got != tt.want {
+			t.Errorf("isUsageSeriesRequest(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseHistoryTime(t *testing.T) {
+	if got, err := parseHistoryTime(""); err != nil || !got.IsZero() {
+		t.Errorf("parseHistoryTime(\"\") = %v, %v, want zero time", got, err)
+	}
+	if got, err := parseHistoryTime("2026-03-01"); err != nil || got.Day() != 1 || got.Location() != time.UTC {
+		t.Errorf("parseHistoryTime(date) = %v, %v", got, err)
+	}
+	if got, err := parseHistoryTime("2026-03-01T10:00:00+02:00"); err != nil || got.UTC().Hour() != 8 {
+		t.Errorf("parseHistoryTime(RFC3339) = %v, %v", got, err)
+	}
+	if _, err := parseHistoryTime("03/01/2026"); err == nil {
+		t.Error("parseHistoryTime() should reject other formats")
+	}
+}
+
 // ============ GetAlerts Tests ============
 
 func TestUsageHandler_GetAlerts_Unauthorized(t *testing.T) {
@@ -135,6 +203,149 @@ func TestUsageHandler_AcknowledgeAlert_InvalidAlertID(t *testing.T) {
 	}
 }
 
+// ============ Organization Usage Tests ============
+
+func TestUsageHandler_OrgEndpoints_RequireOrganization(t *testing.T) {
+	handler := NewUsageHandler(nil)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"GetOrgUsage", handler.GetOrgUsage},
+		{"GetOrgUsageHistory", handler.GetOrgUsageHistory},
+		{"GetOrgAlerts", handler.GetOrgAlerts},
+		{"AcknowledgeOrgAlert", handler.AcknowledgeOrgAlert},
+		{"GetOrgAlertPreferences", handler.GetOrgAlertPreferences},
+		{"UpdateOrgAlertPreferences", handler.UpdateOrgAlertPreferences},
+		{"GetOrgAIUsage", handler.GetOrgAIUsage},
+		{"UpdateOrgAIBudget", handler.UpdateOrgAIBudget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/organizations/acme/usage", nil)
+			req = req.WithContext(auth.SetUserContext(req.Context(), &models.User{ID: 1, Email: "test@example.com"}))
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s() without organization status = %v, want %v", tt.name, w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestUsageHandler_AcknowledgeOrgAlert_InvalidAlertID(t *testing.T) {
+	handler := NewUsageHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/organizations/acme/usage/alerts/abc/acknowledge", nil)
+	req.SetPathValue("id", "abc")
+	ctx := auth.SetUserContext(req.Context(), &models.User{ID: 1, Email: "test@example.com"})
+	ctx = setOrganizationInTestContext(ctx, &models.Organization{ID: 1, Slug: "acme"})
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler.AcknowledgeOrgAlert(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("AcknowledgeOrgAlert() with invalid alert ID status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUsageHandler_AlertPreferences_Unauthorized(t *testing.T) {
+	handler := NewUsageHandler(nil)
+
+	for name, fn := range map[string]http.HandlerFunc{
+		"GetAlertPreferences":    handler.GetAlertPreferences,
+		"UpdateAlertPreferences": handler.UpdateAlertPreferences,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/usage/alerts/preferences", nil)
+			w := httptest.NewRecorder()
+
+			fn(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s() without auth status = %v, want %v", name, w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestUsageHandler_UpdateOrgAlertPreferences_InvalidJSON(t *testing.T) {
+	handler := NewUsageHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/organizations/acme/usage/alerts/preferences", bytes.NewBufferString("{invalid"))
+	ctx := auth.SetUserContext(req.Context(), &models.User{ID: 1, Email: "test@example.com"})
+	ctx = setOrganizationInTestContext(ctx, &models.Organization{ID: 1, Slug: "acme"})
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler.UpdateOrgAlertPreferences(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("UpdateOrgAlertPreferences() with invalid JSON status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUsageHandler_GetAIUsage_Unauthorized(t *testing.T) {
+	handler := NewUsageHandler(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/usage/ai", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetAIUsage(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("GetAIUsage() without auth status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUsageHandler_UpdateOrgAIBudget_InvalidBody(t *testing.T) {
+	handler := NewUsageHandler(nil)
+
+	for name, body := range map[string]string{
+		"invalid JSON":    "{invalid",
+		"negative budget": `{"monthly_token_budget": -1}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/organizations/acme/usage/ai", bytes.NewBufferString(body))
+			ctx := auth.SetUserContext(req.Context(), &models.User{ID: 1, Email: "test@example.com"})
+			ctx = setOrganizationInTestContext(ctx, &models.Organization{ID: 1, Slug: "acme"})
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handler.UpdateOrgAIBudget(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("UpdateOrgAIBudget() with %s status = %v, want %v", name, w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestHistoryMonths(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 6},
+		{"?months=12", 12},
+		{"?months=0", 6},
+		{"?months=25", 6},
+		{"?months=abc", 6},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/usage/history"+tt.query, nil)
+		if got := historyMonths(req); got != tt.want {
+			t.Errorf("historyMonths(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
 // ============ RecordUsage Tests ============
 
 func TestUsageHandler_RecordUsage_Unauthorized(t *testing.T) {
@@ -615,7 +615,7 @@ func RequestDataExport(w http.ResponseWriter, r *http.Request) {
 	// Check if there's already a pending or processing export
 	var existingExport models.DataExport
 	err := database.DB.Where(
-		"user_id = ? AND status IN ?",
+		"user_id = ? AND organization_id IS NULL AND status IN ?",
 		userID,
 		[]string{models.ExportStatusPending, models.ExportStatusProcessing},
 	).First(&existingExport).Error
@@ -628,7 +628,7 @@ func RequestDataExport(w http.ResponseWriter, r *http.Request) {
 	twentyFourHoursAgo := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
 	var recentExport models.DataExport
 	err = database.DB.Where(
-		"user_id = ? AND requested_at > ?",
+		"user_id = ? AND organization_id IS NULL AND requested_at > ?",
 		userID,
 		twentyFourHoursAgo,
 	).First(&recentExport).Error
@@ -691,6 +691,7 @@ func RequestDataExport(w http.ResponseWriter, r *http.Request) {
 // @Summary Download data export
 // @Tags User Settings
 // @Security BearerAuth
+// @Param id query int false "Specific export ID (e.g. an organization export); defaults to the latest personal export"
 // @Success 200 {file} binary "ZIP file with user data"
 // @Failure 401 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
@@ -702,11 +703,20 @@ func DownloadDataExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query := database.DB.Where("user_id = ? AND status = ?", userID, models.ExportStatusCompleted)
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		exportID, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			WriteBadRequest(w, r, "Invalid export ID")
+			return
+		}
+		query = query.Where("id = ?", exportID)
+	} else {
+		query = query.Where("organization_id IS NULL")
+	}
+
 	var export models.DataExport
-	if err := database.DB.
-		Where("user_id = ? AND status = ?", userID, models.ExportStatusCompleted).
-		Order("completed_at DESC").
-		First(&export).Error; err != nil {
+	if err := query.Order("completed_at DESC").First(&export).Error; err != nil {
 		WriteNotFound(w, r, "No completed export found")
 		return
 	}
@@ -1040,7 +1050,7 @@ func GetDataExportStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var export models.DataExport
-	err := database.DB.Where("user_id = ?", userID).Order("requested_at DESC").First(&export).Error
+	err := database.DB.Where("user_id = ? AND organization_id IS NULL", userID).Order("requested_at DESC").First(&export).Error
 	if err != nil {
 		WriteNotFound(w, r, "No export request found")
 		return
@@ -86,6 +86,52 @@ func InitializeWithPool(config *Config, pool *pgxpool.Pool) error {
 	river.AddWorker(workers, &SendAccountLockedEmailWorker{})
 	river.AddWorker(workers, &ProcessStripeWebhookWorker{})
 	river.AddWorker(workers, &DataExportWorker{})
+	river.AddWorker(workers, &OrgDataExportWorker{})
+	river.AddWorker(workers, &SendOrgDeletionEmailWorker{})
+	river.AddWorker(workers, &ReportMeteredUsageWorker{})
+	river.AddWorker(workers, &ProcessDunningWorker{})
+	river.AddWorker(workers, &SendDunningEmailWorker{})
+	river.AddWorker(workers, &ExpireTrialsWorker{})
+	river.AddWorker(workers, &SendTrialEndingEmailWorker{})
+	river.AddWorker(workers, &SyncSeatQuantityWorker{})
+	river.AddWorker(workers, &FlushUsageCountersWorker{})
+	river.AddWorker(workers, &RollupUsageWorker{})
+	river.AddWorker(workers, &EnforceUsageRetentionWorker{})
+	river.AddWorker(workers, &SendUsageAlertEmailWorker{})
+	river.AddWorker(workers, &DeliverUsageAlertWebhookWorker{})
+	river.AddWorker(workers, &CleanupFileUploadsWorker{})
+	river.AddWorker(workers, &PurgeOrganizationsWorker{})
+	river.AddWorker(workers, &SweepStripeEventsWorker{})
+
+	// Schedule periodic jobs
+	var periodicJobs []*river.PeriodicJob
+	if config.UsageReportInterval > 0 {
+		periodicJobs = append(periodicJobs, meteredUsagePeriodicJob(config.UsageReportInterval))
+	}
+	if config.DunningInterval > 0 {
+		periodicJobs = append(periodicJobs, dunningPeriodicJob(config.DunningInterval))
+	}
+	if config.TrialExpiryInterval > 0 {
+		periodicJobs = append(periodicJobs, trialExpiryPeriodicJob(config.TrialExpiryInterval))
+	}
+	if config.UsageFlushInterval > 0 {
+		periodicJobs = append(periodicJobs, usageFlushPeriodicJob(config.UsageFlushInterval))
+	}
+	if config.UsageRollupInterval > 0 {
+		periodicJobs = append(periodicJobs, usageRollupPeriodicJob(config.UsageRollupInterval))
+	}
+	if config.UsageRetentionInterval > 0 {
+		periodicJobs = append(periodicJobs, usageRetentionPeriodicJob(config.UsageRetentionInterval))
+	}
+	if config.FileUploadCleanupInterval > 0 {
+		periodicJobs = append(periodicJobs, fileUploadCleanupPeriodicJob(config.FileUploadCleanupInterval))
+	}
+	if config.OrgDeletionPurgeInterval > 0 {
+		periodicJobs = append(periodicJobs, orgDeletionPurgePeriodicJob(config.OrgDeletionPurgeInterval))
+	}
+	if config.StripeEventSweepInterval > 0 {
+		periodicJobs = append(periodicJobs, stripeEventSweepPeriodicJob(config.StripeEventSweepInterval))
+	}
 
 	// Create River client
 	riverClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{
@@ -95,6 +141,7 @@ func InitializeWithPool(config *Config, pool *pgxpool.Pool) error {
 			"webhooks":         {MaxWorkers: 3},
 		},
 		Workers:              workers,
+		PeriodicJobs:         periodicJobs,
 		JobTimeout:           config.JobTimeout,
 		RescueStuckJobsAfter: config.RescueStuckJobsAfter,
 	})
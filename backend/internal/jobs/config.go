@@ -19,17 +19,47 @@ type Config struct {
 
 	// Maintenance
 	RescueStuckJobsAfter time.Duration
+
+	// Periodic jobs (0 disables)
+	UsageReportInterval    time.Duration
+	DunningInterval        time.Duration
+	TrialExpiryInterval    time.Duration
+	UsageFlushInterval     time.Duration
+	UsageRollupInterval    time.Duration
+	UsageRetentionInterval time.Duration
+
+	// Removal of resumable uploads abandoned past their expiry (0 disables)
+	FileUploadCleanupInterval time.Duration
+
+	// Permanent deletion of organizations past their deletion grace period (0 disables)
+	OrgDeletionPurgeInterval time.Duration
+
+	// Re-queuing of stored Stripe events left without a job (0 disables)
+	StripeEventSweepInterval time.Duration
+
+	// Debounce window for organization seat quantity syncs (below 1s syncs immediately)
+	SeatSyncDelay time.Duration
 }
 
 // DefaultConfig returns sensible default job configuration
 func DefaultConfig() *Config {
 	return &Config{
-		Enabled:              false, // Disabled by default
-		WorkerCount:          10,
-		MaxRetries:           3,
-		RetryBackoff:         5 * time.Second,
-		JobTimeout:           30 * time.Second,
-		RescueStuckJobsAfter: 1 * time.Hour,
+		Enabled:                   false, // Disabled by default
+		WorkerCount:               10,
+		MaxRetries:                3,
+		RetryBackoff:              5 * time.Second,
+		JobTimeout:                30 * time.Second,
+		RescueStuckJobsAfter:      1 * time.Hour,
+		UsageReportInterval:       1 * time.Hour,
+		DunningInterval:           1 * time.Hour,
+		TrialExpiryInterval:       1 * time.Hour,
+		UsageFlushInterval:        1 * time.Minute,
+		UsageRollupInterval:       15 * time.Minute,
+		UsageRetentionInterval:    24 * time.Hour,
+		FileUploadCleanupInterval: 1 * time.Hour,
+		OrgDeletionPurgeInterval:  1 * time.Hour,
+		StripeEventSweepInterval:  10 * time.Minute,
+		SeatSyncDelay:             30 * time.Second,
 	}
 }
 
@@ -59,5 +89,65 @@ func LoadConfig() *Config {
 		}
 	}
 
+	if interval := os.Getenv("JOBS_USAGE_REPORT_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.UsageReportInterval = d
+		}
+	}
+
+	if interval := os.Getenv("JOBS_DUNNING_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.DunningInterval = d
+		}
+	}
+
+	if interval := os.Getenv("JOBS_TRIAL_EXPIRY_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.TrialExpiryInterval = d
+		}
+	}
+
+	if interval := os.Getenv("JOBS_USAGE_FLUSH_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.UsageFlushInterval = d
+		}
+	}
+
+	if interval := os.Getenv("JOBS_USAGE_ROLLUP_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.UsageRollupInterval = d
+		}
+	}
+
+	if interval := os.Getenv("JOBS_USAGE_RETENTION_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.UsageRetentionInterval = d
+		}
+	}
+
+	if interval := os.Getenv("JOBS_FILE_UPLOAD_CLEANUP_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.FileUploadCleanupInterval = d
+		}
+	}
+
+	if interval := os.Getenv("JOBS_ORG_DELETION_PURGE_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.OrgDeletionPurgeInterval = d
+		}
+	}
+
+	if interval := os.Getenv("JOBS_STRIPE_EVENT_SWEEP_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			config.StripeEventSweepInterval = d
+		}
+	}
+
+	if delay := os.Getenv("JOBS_SEAT_SYNC_DELAY"); delay != "" {
+		if d, err := time.ParseDuration(delay); err == nil && d >= 0 {
+			config.SeatSyncDelay = d
+		}
+	}
+
 	return config
 }
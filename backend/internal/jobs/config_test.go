@@ -37,6 +37,10 @@ func TestDefaultConfig(t *testing.T) {
 	if config.RescueStuckJobsAfter != 1*time.Hour {
 		t.Errorf("RescueStuckJobsAfter = %v, want 1h", config.RescueStuckJobsAfter)
 	}
+
+	if config.UsageReportInterval != 1*time.Hour {
+		t.Errorf("UsageReportInterval = %v, want 1h", config.UsageReportInterval)
+	}
 }
 
 // ============ LoadConfig Tests ============
@@ -156,6 +160,215 @@ func TestLoadConfig_Timeout(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_UsageReportInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   time.Duration
+	}{
+		{"valid duration", "15m", 15 * time.Minute},
+		{"zero disables", "0", 0},
+		{"invalid uses default", "abc", 1 * time.Hour},
+		{"empty uses default", "", 1 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_USAGE_REPORT_INTERVAL", tt.envVal)
+			config := LoadConfig()
+			if config.UsageReportInterval != tt.want {
+				t.Errorf("UsageReportInterval = %v, want %v", config.UsageReportInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_UsageFlushInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   time.Duration
+	}{
+		{"valid duration", "30s", 30 * time.Second},
+		{"zero disables", "0", 0},
+		{"invalid uses default", "abc", 1 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_USAGE_FLUSH_INTERVAL", tt.envVal)
+			config := LoadConfig()
+			if config.UsageFlushInterval != tt.want {
+				t.Errorf("UsageFlushInterval = %v, want %v", config.UsageFlushInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_FileUploadCleanupInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   time.Duration
+	}{
+		{"valid duration", "30m", 30 * time.Minute},
+		{"zero disables", "0", 0},
+		{"invalid uses default", "abc", 1 * time.Hour},
+		{"negative uses default", "-1h", 1 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_FILE_UPLOAD_CLEANUP_INTERVAL", tt.envVal)
+			config := LoadConfig()
+			if config.FileUploadCleanupInterval != tt.want {
+				t.Errorf("FileUploadCleanupInterval = %v, want %v", config.FileUploadCleanupInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_OrgDeletionPurgeInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   time.Duration
+	}{
+		{"valid duration", "15m", 15 * time.Minute},
+		{"zero disables", "0", 0},
+		{"invalid uses default", "abc", 1 * time.Hour},
+		{"negative uses default", "-1h", 1 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_ORG_DELETION_PURGE_INTERVAL", tt.envVal)
+			config := LoadConfig()
+			if config.OrgDeletionPurgeInterval != tt.want {
+				t.Errorf("OrgDeletionPurgeInterval = %v, want %v", config.OrgDeletionPurgeInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_StripeEventSweepInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   time.Duration
+	}{
+		{"valid duration", "5m", 5 * time.Minute},
+		{"zero disables", "0", 0},
+		{"invalid uses default", "abc", 10 * time.Minute},
+		{"negative uses default", "-1h", 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_STRIPE_EVENT_SWEEP_INTERVAL", tt.envVal)
+			config := LoadConfig()
+			if config.StripeEventSweepInterval != tt.want {
+				t.Errorf("StripeEventSweepInterval = %v, want %v", config.StripeEventSweepInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_UsageMaintenanceIntervals(t *testing.T) {
+	tests := []struct {
+		name          string
+		rollup        string
+		retention     string
+		wantRollup    time.Duration
+		wantRetention time.Duration
+	}{
+		{"defaults", "", "", 15 * time.Minute, 24 * time.Hour},
+		{"valid durations", "5m", "12h", 5 * time.Minute, 12 * time.Hour},
+		{"zero disables", "0", "0", 0, 0},
+		{"invalid uses default", "abc", "-1h", 15 * time.Minute, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_USAGE_ROLLUP_INTERVAL", tt.rollup)
+			t.Setenv("JOBS_USAGE_RETENTION_INTERVAL", tt.retention)
+			config := LoadConfig()
+			if config.UsageRollupInterval != tt.wantRollup {
+				t.Errorf("UsageRollupInterval = %v, want %v", config.UsageRollupInterval, tt.wantRollup)
+			}
+			if config.UsageRetentionInterval != tt.wantRetention {
+				t.Errorf("UsageRetentionInterval = %v, want %v", config.UsageRetentionInterval, tt.wantRetention)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_DunningInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   time.Duration
+	}{
+		{"valid duration", "30m", 30 * time.Minute},
+		{"zero disables", "0", 0},
+		{"invalid uses default", "abc", 1 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_DUNNING_INTERVAL", tt.envVal)
+			config := LoadConfig()
+			if config.DunningInterval != tt.want {
+				t.Errorf("DunningInterval = %v, want %v", config.DunningInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_TrialExpiryInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   time.Duration
+	}{
+		{"valid duration", "15m", 15 * time.Minute},
+		{"zero disables", "0", 0},
+		{"negative uses default", "-1h", 1 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_TRIAL_EXPIRY_INTERVAL", tt.envVal)
+			config := LoadConfig()
+			if config.TrialExpiryInterval != tt.want {
+				t.Errorf("TrialExpiryInterval = %v, want %v", config.TrialExpiryInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_SeatSyncDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   time.Duration
+	}{
+		{"valid duration", "2m", 2 * time.Minute},
+		{"zero syncs immediately", "0", 0},
+		{"invalid uses default", "soon", 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JOBS_SEAT_SYNC_DELAY", tt.envVal)
+			config := LoadConfig()
+			if config.SeatSyncDelay != tt.want {
+				t.Errorf("SeatSyncDelay = %v, want %v", config.SeatSyncDelay, tt.want)
+			}
+		})
+	}
+}
+
 // ============ Config Struct Tests ============
 
 func TestConfig_Structure(t *testing.T) {
@@ -111,40 +111,12 @@ For questions about this export, please contact support.
 	zipWriter.Close()
 
 	// Store file - try S3 first, fall back to local filesystem
-	var filePath string
-	var storageType string
-
-	s3Storage, err := storage.NewS3Storage()
-	if err == nil && s3Storage.IsAvailable() {
-		// Upload to S3
-		s3Key := fmt.Sprintf("exports/%d/user_data_%d_%d.zip", args.UserID, args.ExportID, time.Now().Unix())
-		if err := s3Storage.UploadBytes(ctx, s3Key, zipBuf.Bytes(), "application/zip"); err != nil {
-			log.Warn().Err(err).Msg("S3 upload failed, falling back to local storage")
-			// Fall through to local storage
-		} else {
-			filePath = s3Key
-			storageType = "s3"
-			log.Info().Str("s3_key", s3Key).Msg("export uploaded to S3")
-		}
-	}
-
-	// Fall back to local filesystem if S3 upload didn't succeed
-	if storageType == "" {
-		exportsDir := getExportsDir()
-		if err := os.MkdirAll(exportsDir, 0755); err != nil {
-			updateExportError(args.ExportID, "File storage failed")
-			return fmt.Errorf("failed to create export directory: %w", err)
-		}
-
-		filename := fmt.Sprintf("user_data_%d_%d.zip", args.UserID, time.Now().Unix())
-		filePath = filepath.Join(exportsDir, filename)
-
-		if err := os.WriteFile(filePath, zipBuf.Bytes(), 0600); err != nil {
-			updateExportError(args.ExportID, "File write failed")
-			return fmt.Errorf("failed to write export file: %w", err)
-		}
-		storageType = "local"
-		log.Info().Str("file_path", filePath).Msg("export saved to local filesystem")
+	s3Key := fmt.Sprintf("exports/%d/user_data_%d_%d.zip", args.UserID, args.ExportID, time.Now().Unix())
+	filename := fmt.Sprintf("user_data_%d_%d.zip", args.UserID, time.Now().Unix())
+	filePath, storageType, err := storeExportArchive(ctx, s3Key, filename, zipBuf.Bytes())
+	if err != nil {
+		updateExportError(args.ExportID, "File storage failed")
+		return err
 	}
 
 	// Update export record
@@ -195,6 +167,33 @@ For questions about this export, please contact support.
 	return nil
 }
 
+// storeExportArchive saves an export archive, preferring S3 and falling back to the local exports directory.
+// It returns the stored path (S3 key or file path) and the storage type.
+func storeExportArchive(ctx context.Context, s3Key, filename string, data []byte) (string, string, error) {
+	s3Storage, err := storage.NewS3Storage()
+	if err == nil && s3Storage.IsAvailable() {
+		if err := s3Storage.UploadBytes(ctx, s3Key, data, "application/zip"); err != nil {
+			log.Warn().Err(err).Msg("S3 upload failed, falling back to local storage")
+		} else {
+			log.Info().Str("s3_key", s3Key).Msg("export uploaded to S3")
+			return s3Key, "s3", nil
+		}
+	}
+
+	exportsDir := getExportsDir()
+	if err := os.MkdirAll(exportsDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	filePath := filepath.Join(exportsDir, filename)
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write export file: %w", err)
+	}
+	log.Info().Str("file_path", filePath).Msg("export saved to local filesystem")
+
+	return filePath, "local", nil
+}
+
 // updateExportError updates the export record with an error status
 func updateExportError(exportID uint, errorMsg string) {
 	database.DB.Model(&models.DataExport{}).
@@ -57,6 +57,50 @@ func TestDataExportArgs_JSON(t *testing.T) {
 	}
 }
 
+// ============ OrgDataExportArgs Tests ============
+
+func TestOrgDataExportArgs_Kind(t *testing.T) {
+	args := OrgDataExportArgs{}
+	if args.Kind() != "generate_org_data_export" {
+		t.Errorf("Kind() = %q, want %q", args.Kind(), "generate_org_data_export")
+	}
+	if args.InsertOpts().Queue != river.QueueDefault {
+		t.Errorf("InsertOpts().Queue = %q, want default queue", args.InsertOpts().Queue)
+	}
+}
+
+func TestOrgDataExport_JSONStructure(t *testing.T) {
+	export := OrgDataExport{
+		ExportedAt:   "2026-01-01T00:00:00Z",
+		Organization: orgExportData{ID: 7, Name: "Acme", Slug: "acme", Plan: "pro"},
+		Members: []orgMemberExport{
+			{UserID: 1, Email: "owner@acme.test", Role: "owner", Status: "active"},
+		},
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	org, ok := result["organization"].(map[string]interface{})
+	if !ok || org["slug"] != "acme" {
+		t.Errorf("organization = %v, want slug acme", result["organization"])
+	}
+	if _, ok := result["invitations"]; ok {
+		t.Error("empty invitations should be omitted")
+	}
+	members, ok := result["members"].([]interface{})
+	if !ok || len(members) != 1 {
+		t.Errorf("members = %v, want 1 entry", result["members"])
+	}
+}
+
 // ============ formatBytes Tests ============
 
 func TestFormatBytes(t *testing.T) {
@@ -0,0 +1,179 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"react-golang-starter/internal/email"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// Dunning notice stages, in escalation order
+const (
+	DunningStagePaymentFailed = "payment_failed"
+	DunningStageReminder      = "reminder"
+	DunningStageFinalNotice   = "final_notice"
+	DunningStageDowngraded    = "downgraded"
+)
+
+// ============================================
+// Dunning Processing Worker
+// ============================================
+
+// ProcessDunningArgs contains the arguments for a dunning escalation run
+type ProcessDunningArgs struct{}
+
+// Kind returns the job type identifier
+func (ProcessDunningArgs) Kind() string {
+	return "process_dunning"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (ProcessDunningArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 5 * time.Minute, // Collapse overlapping runs
+		},
+	}
+}
+
+// DunningProcessor sends due payment reminders and downgrades subscriptions whose grace
+// period has ended. The stripe package registers its implementation at startup.
+type DunningProcessor interface {
+	ProcessDunning(ctx context.Context) error
+}
+
+var dunningProcessor DunningProcessor
+
+// SetDunningProcessor registers the processor used by ProcessDunningWorker
+func SetDunningProcessor(processor DunningProcessor) {
+	dunningProcessor = processor
+}
+
+// ProcessDunningWorker escalates open dunning cases on a schedule
+type ProcessDunningWorker struct {
+	river.WorkerDefaults[ProcessDunningArgs]
+}
+
+// Work runs a dunning pass
+func (w *ProcessDunningWorker) Work(ctx context.Context, job *river.Job[ProcessDunningArgs]) error {
+	if dunningProcessor == nil {
+		log.Debug().Msg("dunning not configured, skipping")
+		return nil
+	}
+
+	if err := dunningProcessor.ProcessDunning(ctx); err != nil {
+		return fmt.Errorf("dunning processing failed: %w", err)
+	}
+
+	return nil
+}
+
+// dunningPeriodicJob schedules dunning processing at the given interval
+func dunningPeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return ProcessDunningArgs{}, nil
+		},
+		nil,
+	)
+}
+
+// ============================================
+// Dunning Email Worker
+// ============================================
+
+// SendDunningEmailArgs contains the job arguments for a failed payment notice
+type SendDunningEmailArgs struct {
+	UserID        uint   `json:"user_id"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	Stage         string `json:"stage"`
+	OrgName       string `json:"org_name,omitempty"`
+	OrgSlug       string `json:"org_slug,omitempty"`
+	AmountDue     string `json:"amount_due"`
+	GraceEndsAt   string `json:"grace_ends_at"`
+	DaysRemaining int    `json:"days_remaining"`
+}
+
+// Kind returns the job type identifier
+func (SendDunningEmailArgs) Kind() string {
+	return "send_dunning_email"
+}
+
+// InsertOpts returns default insert options for this job type
+func (SendDunningEmailArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       "email",
+		MaxAttempts: 5,
+	}
+}
+
+// SendDunningEmailWorker processes failed payment notice jobs
+type SendDunningEmailWorker struct {
+	river.WorkerDefaults[SendDunningEmailArgs]
+}
+
+// Work executes the failed payment notice job
+func (w *SendDunningEmailWorker) Work(ctx context.Context, job *river.Job[SendDunningEmailArgs]) error {
+	args := job.Args
+
+	log.Info().
+		Uint("user_id", args.UserID).
+		Str("email", args.Email).
+		Str("stage", args.Stage).
+		Msg("sending dunning email")
+
+	billingURL := fmt.Sprintf("%s/billing", email.GetFrontendURL())
+	if args.OrgSlug != "" {
+		billingURL = fmt.Sprintf("%s/org/%s/billing", email.GetFrontendURL(), args.OrgSlug)
+	}
+
+	err := email.Send(ctx, email.SendParams{
+		To:           args.Email,
+		TemplateName: "dunning_notice",
+		Data: map[string]interface{}{
+			"Name":          args.Name,
+			"Stage":         args.Stage,
+			"OrgName":       args.OrgName,
+			"AmountDue":     args.AmountDue,
+			"GraceEndsAt":   args.GraceEndsAt,
+			"DaysRemaining": args.DaysRemaining,
+			"BillingURL":    billingURL,
+		},
+	})
+
+	if err != nil {
+		log.Error().Err(err).Str("email", args.Email).Msg("failed to send dunning email")
+		return fmt.Errorf("failed to send dunning email: %w", err)
+	}
+
+	log.Info().
+		Uint("user_id", args.UserID).
+		Str("stage", args.Stage).
+		Msg("dunning email sent successfully")
+
+	return nil
+}
+
+// EnqueueDunningEmails queues failed payment notices for a batch of recipients
+func EnqueueDunningEmails(ctx context.Context, notices []SendDunningEmailArgs) error {
+	if !IsAvailable() {
+		return fmt.Errorf("job system not available")
+	}
+	if len(notices) == 0 {
+		return nil
+	}
+
+	params := make([]river.InsertManyParams, len(notices))
+	for i, n := range notices {
+		params[i] = river.InsertManyParams{Args: n}
+	}
+	return InsertMany(ctx, params)
+}
@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeDunningProcessor struct {
+	calls int
+	err   error
+}
+
+func (f *fakeDunningProcessor) ProcessDunning(ctx context.Context) error {
+	f.calls++
+	return f.err
+}
+
+func TestProcessDunningArgs_Kind(t *testing.T) {
+	args := ProcessDunningArgs{}
+	if args.Kind() != "process_dunning" {
+		t.Errorf("Kind() = %q, want %q", args.Kind(), "process_dunning")
+	}
+}
+
+func TestSendDunningEmailArgs_InsertOpts(t *testing.T) {
+	opts := SendDunningEmailArgs{}.InsertOpts()
+
+	if opts.Queue != "email" {
+		t.Errorf("InsertOpts().Queue = %q, want %q", opts.Queue, "email")
+	}
+	if opts.MaxAttempts < 3 {
+		t.Errorf("InsertOpts().MaxAttempts = %d, want at least 3", opts.MaxAttempts)
+	}
+}
+
+func TestProcessDunningWorker_NoProcessor(t *testing.T) {
+	oldProcessor := dunningProcessor
+	dunningProcessor = nil
+	defer func() { dunningProcessor = oldProcessor }()
+
+	worker := &ProcessDunningWorker{}
+	if err := worker.Work(context.Background(), &river.Job[ProcessDunningArgs]{}); err != nil {
+		t.Errorf("Work() error = %v, want nil when dunning is not configured", err)
+	}
+}
+
+func TestProcessDunningWorker_DelegatesToProcessor(t *testing.T) {
+	oldProcessor := dunningProcessor
+	defer func() { dunningProcessor = oldProcessor }()
+
+	processor := &fakeDunningProcessor{}
+	SetDunningProcessor(processor)
+
+	worker := &ProcessDunningWorker{}
+	if err := worker.Work(context.Background(), &river.Job[ProcessDunningArgs]{}); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if processor.calls != 1 {
+		t.Errorf("processor called %d times, want 1", processor.calls)
+	}
+
+	processor.err = errors.New("database unavailable")
+	if err := worker.Work(context.Background(), &river.Job[ProcessDunningArgs]{}); err == nil {
+		t.Error("Work() should return error so the run is retried")
+	}
+}
+
+func TestEnqueueDunningEmails_JobsUnavailable(t *testing.T) {
+	oldInstance := instance
+	instance = nil
+	defer func() { instance = oldInstance }()
+
+	err := EnqueueDunningEmails(context.Background(), []SendDunningEmailArgs{{UserID: 1, Email: "a@example.com"}})
+	if err == nil {
+		t.Error("EnqueueDunningEmails() should return error when the job system is unavailable")
+	}
+}
+
+func TestDunningPeriodicJob(t *testing.T) {
+	if job := dunningPeriodicJob(time.Hour); job == nil {
+		t.Error("dunningPeriodicJob() returned nil")
+	}
+}
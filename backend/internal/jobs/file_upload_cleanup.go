@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// CleanupFileUploadsArgs contains the arguments for removing expired resumable uploads
+type CleanupFileUploadsArgs struct{}
+
+// Kind returns the job type identifier
+func (CleanupFileUploadsArgs) Kind() string {
+	return "cleanup_file_uploads"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (CleanupFileUploadsArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 5 * time.Minute, // Collapse overlapping runs
+		},
+	}
+}
+
+// FileUploadCleaner removes resumable uploads abandoned past their expiry.
+// The services package registers its implementation at startup since jobs cannot import it.
+type FileUploadCleaner interface {
+	CleanupExpiredUploads(ctx context.Context) (int, error)
+}
+
+var fileUploadCleaner FileUploadCleaner
+
+// SetFileUploadCleaner registers the cleaner used by CleanupFileUploadsWorker
+func SetFileUploadCleaner(cleaner FileUploadCleaner) {
+	fileUploadCleaner = cleaner
+}
+
+// CleanupFileUploadsWorker removes expired resumable uploads on a schedule
+type CleanupFileUploadsWorker struct {
+	river.WorkerDefaults[CleanupFileUploadsArgs]
+}
+
+// Work runs a cleanup. Uploads that fail to be removed are picked up by the next run.
+func (w *CleanupFileUploadsWorker) Work(ctx context.Context, job *river.Job[CleanupFileUploadsArgs]) error {
+	if fileUploadCleaner == nil {
+		log.Debug().Msg("resumable upload cleanup not configured, skipping")
+		return nil
+	}
+
+	start := time.Now()
+	removed, err := fileUploadCleaner.CleanupExpiredUploads(ctx)
+	if err != nil {
+		return fmt.Errorf("resumable upload cleanup failed: %w", err)
+	}
+
+	log.Debug().
+		Int("uploads", removed).
+		Dur("duration", time.Since(start)).
+		Msg("resumable upload cleanup completed")
+
+	return nil
+}
+
+// fileUploadCleanupPeriodicJob schedules expired upload cleanups at the given interval
+func fileUploadCleanupPeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return CleanupFileUploadsArgs{}, nil
+		},
+		nil,
+	)
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeFileUploadCleaner struct {
+	calls int
+	err   error
+}
+
+func (f *fakeFileUploadCleaner) CleanupExpiredUploads(ctx context.Context) (int, error) {
+	f.calls++
+	return 2, f.err
+}
+
+func TestCleanupFileUploadsArgs_Kind(t *testing.T) {
+	args := CleanupFileUploadsArgs{}
+	if args.Kind() != "cleanup_file_uploads" {
+		t.Errorf("Kind() = %q, want %q", args.Kind(), "cleanup_file_uploads")
+	}
+}
+
+func TestCleanupFileUploadsArgs_InsertOpts(t *testing.T) {
+	opts := CleanupFileUploadsArgs{}.InsertOpts()
+
+	if opts.Queue != river.QueueDefault {
+		t.Errorf("InsertOpts().Queue = %q, want %q", opts.Queue, river.QueueDefault)
+	}
+	if opts.UniqueOpts.ByPeriod <= 0 {
+		t.Error("InsertOpts().UniqueOpts.ByPeriod should be set to collapse overlapping runs")
+	}
+}
+
+func TestCleanupFileUploadsWorker_NoCleaner(t *testing.T) {
+	oldCleaner := fileUploadCleaner
+	fileUploadCleaner = nil
+	defer func() { fileUploadCleaner = oldCleaner }()
+
+	worker := &CleanupFileUploadsWorker{}
+	if err := worker.Work(context.Background(), &river.Job[CleanupFileUploadsArgs]{}); err != nil {
+		t.Errorf("Work() error = %v, want nil when cleanup is not configured", err)
+	}
+}
+
+func TestCleanupFileUploadsWorker_DelegatesToCleaner(t *testing.T) {
+	oldCleaner := fileUploadCleaner
+	defer func() { fileUploadCleaner = oldCleaner }()
+
+	cleaner := &fakeFileUploadCleaner{}
+	SetFileUploadCleaner(cleaner)
+
+	worker := &CleanupFileUploadsWorker{}
+	if err := worker.Work(context.Background(), &river.Job[CleanupFileUploadsArgs]{}); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if cleaner.calls != 1 {
+		t.Errorf("cleaner called %d times, want 1", cleaner.calls)
+	}
+
+	cleaner.err = errors.New("database unavailable")
+	if err := worker.Work(context.Background(), &river.Job[CleanupFileUploadsArgs]{}); err == nil {
+		t.Error("Work() should return error so the run is retried")
+	}
+}
+
+func TestFileUploadCleanupPeriodicJob(t *testing.T) {
+	if job := fileUploadCleanupPeriodicJob(time.Hour); job == nil {
+		t.Error("fileUploadCleanupPeriodicJob() returned nil")
+	}
+}
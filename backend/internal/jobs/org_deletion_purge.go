@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// PurgeOrganizationsArgs contains the arguments for permanently deleting organizations
+// whose deletion grace period has ended
+type PurgeOrganizationsArgs struct{}
+
+// Kind returns the job type identifier
+func (PurgeOrganizationsArgs) Kind() string {
+	return "purge_organizations"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (PurgeOrganizationsArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 5 * time.Minute, // Collapse overlapping runs
+		},
+	}
+}
+
+// OrganizationPurger permanently deletes organizations past their deletion grace period.
+// The services package registers its implementation at startup since jobs cannot import it.
+type OrganizationPurger interface {
+	PurgeDueOrganizations(ctx context.Context) (int, error)
+}
+
+var organizationPurger OrganizationPurger
+
+// SetOrganizationPurger registers the purger used by PurgeOrganizationsWorker
+func SetOrganizationPurger(purger OrganizationPurger) {
+	organizationPurger = purger
+}
+
+// PurgeOrganizationsWorker deletes organizations past their grace period on a schedule
+type PurgeOrganizationsWorker struct {
+	river.WorkerDefaults[PurgeOrganizationsArgs]
+}
+
+// Work runs a purge. Organizations that fail to be purged stay scheduled for the next run.
+func (w *PurgeOrganizationsWorker) Work(ctx context.Context, job *river.Job[PurgeOrganizationsArgs]) error {
+	if organizationPurger == nil {
+		log.Debug().Msg("organization deletion purge not configured, skipping")
+		return nil
+	}
+
+	start := time.Now()
+	purged, err := organizationPurger.PurgeDueOrganizations(ctx)
+	if err != nil {
+		return fmt.Errorf("organization deletion purge failed: %w", err)
+	}
+
+	log.Debug().
+		Int("organizations", purged).
+		Dur("duration", time.Since(start)).
+		Msg("organization deletion purge completed")
+
+	return nil
+}
+
+// orgDeletionPurgePeriodicJob schedules organization purges at the given interval
+func orgDeletionPurgePeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return PurgeOrganizationsArgs{}, nil
+		},
+		nil,
+	)
+}
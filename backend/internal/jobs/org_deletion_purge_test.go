@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeOrganizationPurger struct {
+	calls int
+	err   error
+}
+
+func (f *fakeOrganizationPurger) PurgeDueOrganizations(ctx context.Context) (int, error) {
+	f.calls++
+	return 1, f.err
+}
+
+func TestPurgeOrganizationsArgs_Kind(t *testing.T) {
+	args := PurgeOrganizationsArgs{}
+	if args.Kind() != "purge_organizations" {
+		t.Errorf("Kind() = %q, want %q", args.Kind(), "purge_organizations")
+	}
+}
+
+func TestPurgeOrganizationsArgs_InsertOpts(t *testing.T) {
+	opts := PurgeOrganizationsArgs{}.InsertOpts()
+
+	if opts.Queue != river.QueueDefault {
+		t.Errorf("InsertOpts().Queue = %q, want %q", opts.Queue, river.QueueDefault)
+	}
+	if opts.UniqueOpts.ByPeriod <= 0 {
+		t.Error("InsertOpts().UniqueOpts.ByPeriod should be set to collapse overlapping runs")
+	}
+}
+
+func TestPurgeOrganizationsWorker_NoPurger(t *testing.T) {
+	oldPurger := organizationPurger
+	organizationPurger = nil
+	defer func() { organizationPurger = oldPurger }()
+
+	worker := &PurgeOrganizationsWorker{}
+	if err := worker.Work(context.Background(), &river.Job[PurgeOrganizationsArgs]{}); err != nil {
+		t.Errorf("Work() error = %v, want nil when the purge is not configured", err)
+	}
+}
+
+func TestPurgeOrganizationsWorker_DelegatesToPurger(t *testing.T) {
+	oldPurger := organizationPurger
+	defer func() { organizationPurger = oldPurger }()
+
+	purger := &fakeOrganizationPurger{}
+	SetOrganizationPurger(purger)
+
+	worker := &PurgeOrganizationsWorker{}
+	if err := worker.Work(context.Background(), &river.Job[PurgeOrganizationsArgs]{}); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if purger.calls != 1 {
+		t.Errorf("purger called %d times, want 1", purger.calls)
+	}
+
+	purger.err = errors.New("database unavailable")
+	if err := worker.Work(context.Background(), &river.Job[PurgeOrganizationsArgs]{}); err == nil {
+		t.Error("Work() should return error so the run is retried")
+	}
+}
+
+func TestOrgDeletionPurgePeriodicJob(t *testing.T) {
+	if job := orgDeletionPurgePeriodicJob(time.Hour); job == nil {
+		t.Error("orgDeletionPurgePeriodicJob() returned nil")
+	}
+}
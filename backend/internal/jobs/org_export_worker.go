@@ -0,0 +1,313 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/email"
+	"react-golang-starter/internal/models"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// OrgDataExportArgs contains the arguments for an organization data export job
+type OrgDataExportArgs struct {
+	OrganizationID uint   `json:"organization_id"`
+	UserID         uint   `json:"user_id"` // Owner who requested the export
+	Email          string `json:"email"`
+	ExportID       uint   `json:"export_id"`
+
+	// ExpiresAt is when the export stops being downloadable. It is set past the
+	// organization's deletion date so owners can still fetch it after the purge.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Kind returns the job type identifier
+func (OrgDataExportArgs) Kind() string {
+	return "generate_org_data_export"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (OrgDataExportArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+	}
+}
+
+// OrgDataExportWorker processes organization data export jobs
+type OrgDataExportWorker struct {
+	river.WorkerDefaults[OrgDataExportArgs]
+}
+
+// Work processes an organization data export job
+func (w *OrgDataExportWorker) Work(ctx context.Context, job *river.Job[OrgDataExportArgs]) error {
+	args := job.Args
+
+	log.Info().
+		Uint("org_id", args.OrganizationID).
+		Uint("export_id", args.ExportID).
+		Msg("starting organization data export generation")
+
+	if err := database.DB.Model(&models.DataExport{}).
+		Where("id = ?", args.ExportID).
+		Update("status", models.ExportStatusProcessing).Error; err != nil {
+		return fmt.Errorf("failed to update export status: %w", err)
+	}
+
+	orgData, err := compileOrgData(ctx, args.OrganizationID)
+	if err != nil {
+		updateExportError(args.ExportID, fmt.Sprintf("Data compilation failed: %v", err))
+		return fmt.Errorf("failed to compile organization data: %w", err)
+	}
+
+	jsonData, err := json.MarshalIndent(orgData, "", "  ")
+	if err != nil {
+		updateExportError(args.ExportID, "JSON serialization failed")
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+
+	jsonFile, err := zipWriter.Create("organization_data.json")
+	if err != nil {
+		zipWriter.Close()
+		updateExportError(args.ExportID, "ZIP creation failed")
+		return fmt.Errorf("failed to create ZIP entry: %w", err)
+	}
+	if _, err := jsonFile.Write(jsonData); err != nil {
+		zipWriter.Close()
+		updateExportError(args.ExportID, "ZIP write failed")
+		return fmt.Errorf("failed to write to ZIP: %w", err)
+	}
+
+	readmeContent := fmt.Sprintf(`Data Export for Organization: %s (%s)
+Generated: %s
+
+This archive contains your organization's data as stored in our system.
+It was generated because the organization was scheduled for deletion.
+
+Files included:
+- organization_data.json: Organization details, members, invitations,
+  subscriptions and audit history in JSON format
+
+For questions about this export, please contact support.
+`, orgData.Organization.Name, orgData.Organization.Slug, time.Now().Format(time.RFC3339))
+
+	readmeFile, err := zipWriter.Create("README.txt")
+	if err == nil {
+		readmeFile.Write([]byte(readmeContent))
+	}
+
+	zipWriter.Close()
+
+	s3Key := fmt.Sprintf("exports/orgs/%d/org_data_%d_%d.zip", args.OrganizationID, args.ExportID, time.Now().Unix())
+	filename := fmt.Sprintf("org_data_%d_%d.zip", args.OrganizationID, time.Now().Unix())
+	filePath, storageType, err := storeExportArchive(ctx, s3Key, filename, zipBuf.Bytes())
+	if err != nil {
+		updateExportError(args.ExportID, "File storage failed")
+		return err
+	}
+
+	if err := database.DB.Model(&models.DataExport{}).
+		Where("id = ?", args.ExportID).
+		Updates(map[string]interface{}{
+			"status":       models.ExportStatusCompleted,
+			"download_url": fmt.Sprintf("/api/users/me/export/download?id=%d", args.ExportID),
+			"file_path":    filePath,
+			"storage_type": storageType,
+			"file_size":    int64(zipBuf.Len()),
+			"completed_at": time.Now().Format(time.RFC3339),
+			"expires_at":   args.ExpiresAt.Format(time.RFC3339),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update export record: %w", err)
+	}
+
+	if email.IsAvailable() && args.Email != "" {
+		err = email.Send(ctx, email.SendParams{
+			To:           args.Email,
+			TemplateName: "org_export_ready",
+			Data: map[string]interface{}{
+				"OrgName":      orgData.Organization.Name,
+				"DownloadLink": fmt.Sprintf("%s/settings/privacy", email.GetFrontendURL()),
+				"FileSize":     formatBytes(int64(zipBuf.Len())),
+			},
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to send organization export ready email")
+			// Don't fail the job - email is optional
+		}
+	}
+
+	log.Info().
+		Uint("org_id", args.OrganizationID).
+		Uint("export_id", args.ExportID).
+		Int("size_bytes", zipBuf.Len()).
+		Msg("organization data export completed successfully")
+
+	return nil
+}
+
+// EnqueueOrgDataExport queues an organization data export job that stays downloadable until expiresAt
+func EnqueueOrgDataExport(ctx context.Context, orgID, userID uint, email string, exportID uint, expiresAt time.Time) error {
+	if !IsAvailable() {
+		return fmt.Errorf("job system not available")
+	}
+
+	return Insert(ctx, OrgDataExportArgs{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Email:          email,
+		ExportID:       exportID,
+		ExpiresAt:      expiresAt,
+	}, nil)
+}
+
+// OrgDataExport contains all organization data for export before deletion
+type OrgDataExport struct {
+	ExportedAt    string                `json:"exported_at"`
+	Organization  orgExportData         `json:"organization"`
+	Members       []orgMemberExport     `json:"members,omitempty"`
+	Invitations   []orgInvitationExport `json:"invitations,omitempty"`
+	Subscriptions []orgSubscriptionData `json:"subscriptions,omitempty"`
+	AuditLogs     []orgAuditLogExport   `json:"audit_logs,omitempty"`
+}
+
+type orgExportData struct {
+	ID        uint            `json:"id"`
+	Name      string          `json:"name"`
+	Slug      string          `json:"slug"`
+	Plan      string          `json:"plan"`
+	Settings  json.RawMessage `json:"settings,omitempty"`
+	CreatedAt string          `json:"created_at"`
+}
+
+type orgMemberExport struct {
+	UserID   uint   `json:"user_id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Status   string `json:"status"`
+	JoinedAt string `json:"joined_at"`
+}
+
+type orgInvitationExport struct {
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	ExpiresAt  string `json:"expires_at"`
+	AcceptedAt string `json:"accepted_at,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type orgSubscriptionData struct {
+	Status             string `json:"status"`
+	StripePriceID      string `json:"stripe_price_id"`
+	CurrentPeriodStart string `json:"current_period_start"`
+	CurrentPeriodEnd   string `json:"current_period_end"`
+	CancelAtPeriodEnd  bool   `json:"cancel_at_period_end"`
+	CreatedAt          string `json:"created_at"`
+}
+
+type orgAuditLogExport struct {
+	UserID     *uint  `json:"user_id,omitempty"`
+	Action     string `json:"action"`
+	TargetType string `json:"target_type"`
+	TargetID   *uint  `json:"target_id,omitempty"`
+	Changes    string `json:"changes,omitempty"`
+	IPAddress  string `json:"ip_address,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// compileOrgData gathers all organization data for export
+func compileOrgData(ctx context.Context, orgID uint) (*OrgDataExport, error) {
+	data := &OrgDataExport{
+		ExportedAt: time.Now().Format(time.RFC3339),
+	}
+
+	var org models.Organization
+	if err := database.DB.WithContext(ctx).First(&org, orgID).Error; err != nil {
+		return nil, err
+	}
+	data.Organization = orgExportData{
+		ID:        org.ID,
+		Name:      org.Name,
+		Slug:      org.Slug,
+		Plan:      string(org.Plan),
+		Settings:  json.RawMessage(org.Settings),
+		CreatedAt: org.CreatedAt.Format(time.RFC3339),
+	}
+
+	// Get members with user details (no credentials)
+	var members []models.OrganizationMember
+	database.DB.WithContext(ctx).Where("organization_id = ?", orgID).Preload("User").Find(&members)
+	for _, m := range members {
+		export := orgMemberExport{
+			UserID:   m.UserID,
+			Role:     string(m.Role),
+			Status:   string(m.Status),
+			JoinedAt: m.CreatedAt.Format(time.RFC3339),
+		}
+		if m.User != nil {
+			export.Name = m.User.Name
+			export.Email = m.User.Email
+		}
+		data.Members = append(data.Members, export)
+	}
+
+	// Get invitations (no tokens)
+	var invitations []models.OrganizationInvitation
+	database.DB.WithContext(ctx).Where("organization_id = ?", orgID).Find(&invitations)
+	for _, inv := range invitations {
+		export := orgInvitationExport{
+			Email:     inv.Email,
+			Role:      string(inv.Role),
+			ExpiresAt: inv.ExpiresAt.Format(time.RFC3339),
+			CreatedAt: inv.CreatedAt.Format(time.RFC3339),
+		}
+		if inv.AcceptedAt != nil {
+			export.AcceptedAt = inv.AcceptedAt.Format(time.RFC3339)
+		}
+		data.Invitations = append(data.Invitations, export)
+	}
+
+	// Get subscriptions
+	var subscriptions []models.Subscription
+	database.DB.WithContext(ctx).Where("organization_id = ?", orgID).Find(&subscriptions)
+	for _, sub := range subscriptions {
+		data.Subscriptions = append(data.Subscriptions, orgSubscriptionData{
+			Status:             sub.Status,
+			StripePriceID:      sub.StripePriceID,
+			CurrentPeriodStart: sub.CurrentPeriodStart,
+			CurrentPeriodEnd:   sub.CurrentPeriodEnd,
+			CancelAtPeriodEnd:  sub.CancelAtPeriodEnd,
+			CreatedAt:          sub.CreatedAt,
+		})
+	}
+
+	// Get audit logs (last 1000)
+	var auditLogs []models.AuditLog
+	database.DB.WithContext(ctx).Where("organization_id = ?", orgID).
+		Order("created_at DESC").
+		Limit(1000).
+		Find(&auditLogs)
+	for _, l := range auditLogs {
+		data.AuditLogs = append(data.AuditLogs, orgAuditLogExport{
+			UserID:     l.UserID,
+			Action:     l.Action,
+			TargetType: l.TargetType,
+			TargetID:   l.TargetID,
+			Changes:    l.Changes,
+			IPAddress:  l.IPAddress,
+			CreatedAt:  l.CreatedAt,
+		})
+	}
+
+	return data, nil
+}
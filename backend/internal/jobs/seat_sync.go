@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// ============================================
+// Seat Quantity Sync Worker
+// ============================================
+
+// SyncSeatQuantityArgs contains the arguments for an organization seat quantity sync
+type SyncSeatQuantityArgs struct {
+	OrganizationID uint `json:"organization_id"`
+}
+
+// Kind returns the job type identifier
+func (SyncSeatQuantityArgs) Kind() string {
+	return "sync_seat_quantity"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (SyncSeatQuantityArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 5,
+	}
+}
+
+// SeatQuantitySyncer sets an organization's billed seat quantity to its active member count.
+// The services package registers its implementation at startup.
+type SeatQuantitySyncer interface {
+	SyncSeatQuantity(ctx context.Context, orgID uint) error
+}
+
+var seatQuantitySyncer SeatQuantitySyncer
+
+// SetSeatQuantitySyncer registers the syncer used by SyncSeatQuantityWorker
+func SetSeatQuantitySyncer(syncer SeatQuantitySyncer) {
+	seatQuantitySyncer = syncer
+}
+
+// SyncSeatQuantityWorker pushes an organization's member count to its subscription
+type SyncSeatQuantityWorker struct {
+	river.WorkerDefaults[SyncSeatQuantityArgs]
+}
+
+// Work runs a seat quantity sync for one organization
+func (w *SyncSeatQuantityWorker) Work(ctx context.Context, job *river.Job[SyncSeatQuantityArgs]) error {
+	if seatQuantitySyncer == nil {
+		log.Debug().Msg("seat quantity sync not configured, skipping")
+		return nil
+	}
+
+	if err := seatQuantitySyncer.SyncSeatQuantity(ctx, job.Args.OrganizationID); err != nil {
+		return fmt.Errorf("seat quantity sync failed: %w", err)
+	}
+
+	return nil
+}
+
+// seatSyncInsertOpts schedules a sync at the end of the current debounce window.
+// Every membership change inside the window maps to the same scheduled time, so
+// River's uniqueness check collapses them into a single job.
+func seatSyncInsertOpts(now time.Time, window time.Duration) *river.InsertOpts {
+	if window < time.Second {
+		return nil
+	}
+
+	return &river.InsertOpts{
+		ScheduledAt: now.Truncate(window).Add(window),
+		UniqueOpts: river.UniqueOpts{
+			ByArgs:   true,
+			ByPeriod: window,
+		},
+	}
+}
+
+// EnqueueSeatQuantitySync queues a debounced seat quantity sync for an organization
+func EnqueueSeatQuantitySync(ctx context.Context, orgID uint) error {
+	if !IsAvailable() {
+		return fmt.Errorf("job system not available")
+	}
+
+	return Insert(ctx, SyncSeatQuantityArgs{OrganizationID: orgID}, seatSyncInsertOpts(time.Now(), instance.config.SeatSyncDelay))
+}
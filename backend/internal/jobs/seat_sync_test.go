@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeSeatQuantitySyncer struct {
+	orgIDs []uint
+	err    error
+}
+
+func (f *fakeSeatQuantitySyncer) SyncSeatQuantity(ctx context.Context, orgID uint) error {
+	f.orgIDs = append(f.orgIDs, orgID)
+	return f.err
+}
+
+func TestSyncSeatQuantityArgs_Kind(t *testing.T) {
+	if kind := (SyncSeatQuantityArgs{}).Kind(); kind != "sync_seat_quantity" {
+		t.Errorf("Kind() = %q, want %q", kind, "sync_seat_quantity")
+	}
+}
+
+func TestSeatSyncInsertOpts_DebouncesWithinWindow(t *testing.T) {
+	window := 30 * time.Second
+	start := time.Date(2024, time.June, 1, 12, 0, 5, 0, time.UTC)
+
+	first := seatSyncInsertOpts(start, window)
+	second := seatSyncInsertOpts(start.Add(20*time.Second), window)
+	next := seatSyncInsertOpts(start.Add(40*time.Second), window)
+
+	if !first.ScheduledAt.Equal(start.Truncate(window).Add(window)) {
+		t.Errorf("ScheduledAt = %v, want end of the window", first.ScheduledAt)
+	}
+	if !first.ScheduledAt.Equal(second.ScheduledAt) {
+		t.Errorf("changes in the same window scheduled at %v and %v", first.ScheduledAt, second.ScheduledAt)
+	}
+	if !next.ScheduledAt.After(first.ScheduledAt) {
+		t.Error("a change in the next window should schedule a new sync")
+	}
+	if !first.UniqueOpts.ByArgs || first.UniqueOpts.ByPeriod != window {
+		t.Errorf("UniqueOpts = %+v, want unique by args within the window", first.UniqueOpts)
+	}
+}
+
+func TestSeatSyncInsertOpts_NoDelay(t *testing.T) {
+	if opts := seatSyncInsertOpts(time.Now(), 0); opts != nil {
+		t.Errorf("seatSyncInsertOpts() = %+v, want nil to sync immediately", opts)
+	}
+}
+
+func TestSyncSeatQuantityWorker_NoSyncer(t *testing.T) {
+	oldSyncer := seatQuantitySyncer
+	seatQuantitySyncer = nil
+	defer func() { seatQuantitySyncer = oldSyncer }()
+
+	worker := &SyncSeatQuantityWorker{}
+	if err := worker.Work(context.Background(), &river.Job[SyncSeatQuantityArgs]{}); err != nil {
+		t.Errorf("Work() error = %v, want nil when seat sync is not configured", err)
+	}
+}
+
+func TestSyncSeatQuantityWorker_DelegatesToSyncer(t *testing.T) {
+	oldSyncer := seatQuantitySyncer
+	defer func() { seatQuantitySyncer = oldSyncer }()
+
+	syncer := &fakeSeatQuantitySyncer{}
+	SetSeatQuantitySyncer(syncer)
+
+	worker := &SyncSeatQuantityWorker{}
+	job := &river.Job[SyncSeatQuantityArgs]{Args: SyncSeatQuantityArgs{OrganizationID: 42}}
+	if err := worker.Work(context.Background(), job); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if len(syncer.orgIDs) != 1 || syncer.orgIDs[0] != 42 {
+		t.Errorf("syncer called with %v, want [42]", syncer.orgIDs)
+	}
+
+	syncer.err = errors.New("stripe unavailable")
+	if err := worker.Work(context.Background(), job); err == nil {
+		t.Error("Work() should return error so the sync is retried")
+	}
+}
+
+func TestEnqueueSeatQuantitySync_JobsUnavailable(t *testing.T) {
+	oldInstance := instance
+	instance = nil
+	defer func() { instance = oldInstance }()
+
+	if err := EnqueueSeatQuantitySync(context.Background(), 1); err == nil {
+		t.Error("EnqueueSeatQuantitySync() should return error when the job system is unavailable")
+	}
+}
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// SweepStripeEventsArgs contains the arguments for re-queuing stored Stripe events that
+// were never handed to the job queue
+type SweepStripeEventsArgs struct{}
+
+// Kind returns the job type identifier
+func (SweepStripeEventsArgs) Kind() string {
+	return "sweep_stripe_events"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (SweepStripeEventsArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 5 * time.Minute, // Collapse overlapping runs
+		},
+	}
+}
+
+// StripeEventSweeper re-queues stored Stripe events left pending or retrying without a job,
+// for example after a crash between storing an event and queuing it.
+// The stripe package registers its implementation at startup since jobs cannot import it.
+type StripeEventSweeper interface {
+	ResumeStalledEvents(ctx context.Context) (int, error)
+}
+
+var stripeEventSweeper StripeEventSweeper
+
+// SetStripeEventSweeper registers the sweeper used by SweepStripeEventsWorker
+func SetStripeEventSweeper(sweeper StripeEventSweeper) {
+	stripeEventSweeper = sweeper
+}
+
+// SweepStripeEventsWorker re-queues stalled Stripe events on a schedule
+type SweepStripeEventsWorker struct {
+	river.WorkerDefaults[SweepStripeEventsArgs]
+}
+
+// Work runs a sweep. Re-queued events are deduplicated against jobs that still exist.
+func (w *SweepStripeEventsWorker) Work(ctx context.Context, job *river.Job[SweepStripeEventsArgs]) error {
+	if stripeEventSweeper == nil {
+		log.Debug().Msg("stripe event sweep not configured, skipping")
+		return nil
+	}
+
+	start := time.Now()
+	resumed, err := stripeEventSweeper.ResumeStalledEvents(ctx)
+	if err != nil {
+		return fmt.Errorf("stripe event sweep failed: %w", err)
+	}
+
+	log.Debug().
+		Int("events", resumed).
+		Dur("duration", time.Since(start)).
+		Msg("stripe event sweep completed")
+
+	return nil
+}
+
+// stripeEventSweepPeriodicJob schedules stalled Stripe event sweeps at the given interval
+func stripeEventSweepPeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return SweepStripeEventsArgs{}, nil
+		},
+		nil,
+	)
+}
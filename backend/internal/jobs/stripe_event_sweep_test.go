@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeStripeEventSweeper struct {
+	calls int
+	err   error
+}
+
+func (f *fakeStripeEventSweeper) ResumeStalledEvents(ctx context.Context) (int, error) {
+	f.calls++
+	return 1, f.err
+}
+
+func TestSweepStripeEventsArgs_Kind(t *testing.T) {
+	args := SweepStripeEventsArgs{}
+	if args.Kind() != "sweep_stripe_events" {
+		t.Errorf("Kind() = %q, want %q", args.Kind(), "sweep_stripe_events")
+	}
+}
+
+func TestSweepStripeEventsArgs_InsertOpts(t *testing.T) {
+	opts := SweepStripeEventsArgs{}.InsertOpts()
+
+	if opts.Queue != river.QueueDefault {
+		t.Errorf("InsertOpts().Queue = %q, want %q", opts.Queue, river.QueueDefault)
+	}
+	if opts.UniqueOpts.ByPeriod <= 0 {
+		t.Error("InsertOpts().UniqueOpts.ByPeriod should be set to collapse overlapping runs")
+	}
+}
+
+func TestSweepStripeEventsWorker_NoSweeper(t *testing.T) {
+	oldSweeper := stripeEventSweeper
+	stripeEventSweeper = nil
+	defer func() { stripeEventSweeper = oldSweeper }()
+
+	worker := &SweepStripeEventsWorker{}
+	if err := worker.Work(context.Background(), &river.Job[SweepStripeEventsArgs]{}); err != nil {
+		t.Errorf("Work() error = %v, want nil when the sweep is not configured", err)
+	}
+}
+
+func TestSweepStripeEventsWorker_DelegatesToSweeper(t *testing.T) {
+	oldSweeper := stripeEventSweeper
+	defer func() { stripeEventSweeper = oldSweeper }()
+
+	sweeper := &fakeStripeEventSweeper{}
+	SetStripeEventSweeper(sweeper)
+
+	worker := &SweepStripeEventsWorker{}
+	if err := worker.Work(context.Background(), &river.Job[SweepStripeEventsArgs]{}); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if sweeper.calls != 1 {
+		t.Errorf("sweeper called %d times, want 1", sweeper.calls)
+	}
+
+	sweeper.err = errors.New("database unavailable")
+	if err := worker.Work(context.Background(), &river.Job[SweepStripeEventsArgs]{}); err == nil {
+		t.Error("Work() should return error so the run is retried")
+	}
+}
+
+func TestStripeEventSweepPeriodicJob(t *testing.T) {
+	if job := stripeEventSweepPeriodicJob(10 * time.Minute); job == nil {
+		t.Error("stripeEventSweepPeriodicJob() returned nil")
+	}
+}
@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"react-golang-starter/internal/email"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// ============================================
+// Trial Expiry Worker
+// ============================================
+
+// ExpireTrialsArgs contains the arguments for a trial expiry run
+type ExpireTrialsArgs struct{}
+
+// Kind returns the job type identifier
+func (ExpireTrialsArgs) Kind() string {
+	return "expire_trials"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (ExpireTrialsArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 5 * time.Minute, // Collapse overlapping runs
+		},
+	}
+}
+
+// TrialExpirer downgrades subscriptions whose free trial ended without payment.
+// The stripe package registers its implementation at startup.
+type TrialExpirer interface {
+	ExpireTrials(ctx context.Context) error
+}
+
+var trialExpirer TrialExpirer
+
+// SetTrialExpirer registers the expirer used by ExpireTrialsWorker
+func SetTrialExpirer(expirer TrialExpirer) {
+	trialExpirer = expirer
+}
+
+// ExpireTrialsWorker enforces trial end dates on a schedule
+type ExpireTrialsWorker struct {
+	river.WorkerDefaults[ExpireTrialsArgs]
+}
+
+// Work runs a trial expiry pass
+func (w *ExpireTrialsWorker) Work(ctx context.Context, job *river.Job[ExpireTrialsArgs]) error {
+	if trialExpirer == nil {
+		log.Debug().Msg("trial expiry not configured, skipping")
+		return nil
+	}
+
+	if err := trialExpirer.ExpireTrials(ctx); err != nil {
+		return fmt.Errorf("trial expiry failed: %w", err)
+	}
+
+	return nil
+}
+
+// trialExpiryPeriodicJob schedules trial expiry at the given interval
+func trialExpiryPeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return ExpireTrialsArgs{}, nil
+		},
+		nil,
+	)
+}
+
+// ============================================
+// Trial Ending Email Worker
+// ============================================
+
+// SendTrialEndingEmailArgs contains the job arguments for a trial ending reminder
+type SendTrialEndingEmailArgs struct {
+	UserID           uint   `json:"user_id"`
+	Email            string `json:"email"`
+	Name             string `json:"name"`
+	OrgName          string `json:"org_name,omitempty"`
+	OrgSlug          string `json:"org_slug,omitempty"`
+	TrialEndsAt      string `json:"trial_ends_at"`
+	DaysRemaining    int    `json:"days_remaining"`
+	HasPaymentMethod bool   `json:"has_payment_method"`
+}
+
+// Kind returns the job type identifier
+func (SendTrialEndingEmailArgs) Kind() string {
+	return "send_trial_ending_email"
+}
+
+// InsertOpts returns default insert options for this job type
+func (SendTrialEndingEmailArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       "email",
+		MaxAttempts: 5,
+	}
+}
+
+// SendTrialEndingEmailWorker processes trial ending reminder jobs
+type SendTrialEndingEmailWorker struct {
+	river.WorkerDefaults[SendTrialEndingEmailArgs]
+}
+
+// Work executes the trial ending reminder job
+func (w *SendTrialEndingEmailWorker) Work(ctx context.Context, job *river.Job[SendTrialEndingEmailArgs]) error {
+	args := job.Args
+
+	log.Info().
+		Uint("user_id", args.UserID).
+		Str("email", args.Email).
+		Msg("sending trial ending email")
+
+	billingURL := fmt.Sprintf("%s/billing", email.GetFrontendURL())
+	if args.OrgSlug != "" {
+		billingURL = fmt.Sprintf("%s/org/%s/billing", email.GetFrontendURL(), args.OrgSlug)
+	}
+
+	err := email.Send(ctx, email.SendParams{
+		To:           args.Email,
+		TemplateName: "trial_ending",
+		Data: map[string]interface{}{
+			"Name":             args.Name,
+			"OrgName":          args.OrgName,
+			"TrialEndsAt":      args.TrialEndsAt,
+			"DaysRemaining":    args.DaysRemaining,
+			"HasPaymentMethod": args.HasPaymentMethod,
+			"BillingURL":       billingURL,
+		},
+	})
+
+	if err != nil {
+		log.Error().Err(err).Str("email", args.Email).Msg("failed to send trial ending email")
+		return fmt.Errorf("failed to send trial ending email: %w", err)
+	}
+
+	log.Info().Uint("user_id", args.UserID).Msg("trial ending email sent successfully")
+
+	return nil
+}
+
+// EnqueueTrialEndingEmails queues trial ending reminders for a batch of recipients
+func EnqueueTrialEndingEmails(ctx context.Context, reminders []SendTrialEndingEmailArgs) error {
+	if !IsAvailable() {
+		return fmt.Errorf("job system not available")
+	}
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	params := make([]river.InsertManyParams, len(reminders))
+	for i, r := range reminders {
+		params[i] = river.InsertManyParams{Args: r}
+	}
+	return InsertMany(ctx, params)
+}
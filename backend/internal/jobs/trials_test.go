@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeTrialExpirer struct {
+	calls int
+	err   error
+}
+
+func (f *fakeTrialExpirer) ExpireTrials(ctx context.Context) error {
+	f.calls++
+	return f.err
+}
+
+func TestExpireTrialsArgs_Kind(t *testing.T) {
+	if kind := (ExpireTrialsArgs{}).Kind(); kind != "expire_trials" {
+		t.Errorf("Kind() = %q, want %q", kind, "expire_trials")
+	}
+}
+
+func TestSendTrialEndingEmailArgs_InsertOpts(t *testing.T) {
+	opts := SendTrialEndingEmailArgs{}.InsertOpts()
+
+	if opts.Queue != "email" {
+		t.Errorf("InsertOpts().Queue = %q, want %q", opts.Queue, "email")
+	}
+}
+
+func TestExpireTrialsWorker_NoExpirer(t *testing.T) {
+	oldExpirer := trialExpirer
+	trialExpirer = nil
+	defer func() { trialExpirer = oldExpirer }()
+
+	worker := &ExpireTrialsWorker{}
+	if err := worker.Work(context.Background(), &river.Job[ExpireTrialsArgs]{}); err != nil {
+		t.Errorf("Work() error = %v, want nil when trial expiry is not configured", err)
+	}
+}
+
+func TestExpireTrialsWorker_DelegatesToExpirer(t *testing.T) {
+	oldExpirer := trialExpirer
+	defer func() { trialExpirer = oldExpirer }()
+
+	expirer := &fakeTrialExpirer{}
+	SetTrialExpirer(expirer)
+
+	worker := &ExpireTrialsWorker{}
+	if err := worker.Work(context.Background(), &river.Job[ExpireTrialsArgs]{}); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if expirer.calls != 1 {
+		t.Errorf("expirer called %d times, want 1", expirer.calls)
+	}
+
+	expirer.err = errors.New("database unavailable")
+	if err := worker.Work(context.Background(), &river.Job[ExpireTrialsArgs]{}); err == nil {
+		t.Error("Work() should return error so the run is retried")
+	}
+}
+
+func TestEnqueueTrialEndingEmails_JobsUnavailable(t *testing.T) {
+	oldInstance := instance
+	instance = nil
+	defer func() { instance = oldInstance }()
+
+	err := EnqueueTrialEndingEmails(context.Background(), []SendTrialEndingEmailArgs{{UserID: 1, Email: "a@example.com"}})
+	if err == nil {
+		t.Error("EnqueueTrialEndingEmails() should return error when the job system is unavailable")
+	}
+}
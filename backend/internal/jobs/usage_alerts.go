@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"react-golang-starter/internal/email"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// ============================================
+// Usage Alert Email Worker
+// ============================================
+
+// SendUsageAlertEmailArgs contains the job arguments for a usage alert email
+type SendUsageAlertEmailArgs struct {
+	AlertID        uint   `json:"alert_id"`
+	UserID         uint   `json:"user_id"`
+	Email          string `json:"email"`
+	Name           string `json:"name"`
+	OrgName        string `json:"org_name,omitempty"`
+	OrgSlug        string `json:"org_slug,omitempty"`
+	UsageLabel     string `json:"usage_label"`
+	CurrentUsage   string `json:"current_usage"`
+	UsageLimit     string `json:"usage_limit"`
+	PercentageUsed int    `json:"percentage_used"`
+	Exceeded       bool   `json:"exceeded"`
+	PeriodEnd      string `json:"period_end"`
+}
+
+// Kind returns the job type identifier
+func (SendUsageAlertEmailArgs) Kind() string {
+	return "send_usage_alert_email"
+}
+
+// InsertOpts returns default insert options for this job type
+func (SendUsageAlertEmailArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       "email",
+		MaxAttempts: 5,
+		UniqueOpts: river.UniqueOpts{
+			ByArgs: true, // One email per alert and recipient
+		},
+	}
+}
+
+// SendUsageAlertEmailWorker processes usage alert email jobs
+type SendUsageAlertEmailWorker struct {
+	river.WorkerDefaults[SendUsageAlertEmailArgs]
+}
+
+// Work executes the usage alert email job
+func (w *SendUsageAlertEmailWorker) Work(ctx context.Context, job *river.Job[SendUsageAlertEmailArgs]) error {
+	args := job.Args
+
+	log.Info().
+		Uint("alert_id", args.AlertID).
+		Uint("user_id", args.UserID).
+		Msg("sending usage alert email")
+
+	billingURL := fmt.Sprintf("%s/billing", email.GetFrontendURL())
+	if args.OrgSlug != "" {
+		billingURL = fmt.Sprintf("%s/org/%s/billing", email.GetFrontendURL(), args.OrgSlug)
+	}
+
+	err := email.Send(ctx, email.SendParams{
+		To:           args.Email,
+		TemplateName: "usage_alert",
+		Data: map[string]interface{}{
+			"Name":           args.Name,
+			"OrgName":        args.OrgName,
+			"UsageLabel":     args.UsageLabel,
+			"CurrentUsage":   args.CurrentUsage,
+			"UsageLimit":     args.UsageLimit,
+			"PercentageUsed": args.PercentageUsed,
+			"Exceeded":       args.Exceeded,
+			"PeriodEnd":      args.PeriodEnd,
+			"BillingURL":     billingURL,
+		},
+	})
+
+	if err != nil {
+		log.Error().Err(err).Str("email", args.Email).Msg("failed to send usage alert email")
+		return fmt.Errorf("failed to send usage alert email: %w", err)
+	}
+
+	log.Info().Uint("alert_id", args.AlertID).Uint("user_id", args.UserID).Msg("usage alert email sent successfully")
+
+	return nil
+}
+
+// EnqueueUsageAlertEmails queues usage alert emails for a batch of recipients
+func EnqueueUsageAlertEmails(ctx context.Context, alerts []SendUsageAlertEmailArgs) error {
+	if !IsAvailable() {
+		return fmt.Errorf("job system not available")
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	params := make([]river.InsertManyParams, len(alerts))
+	for i, a := range alerts {
+		params[i] = river.InsertManyParams{Args: a}
+	}
+	return InsertMany(ctx, params)
+}
+
+// ============================================
+// Usage Alert Webhook Worker
+// ============================================
+
+// DeliverUsageAlertWebhookArgs contains the job arguments for a usage alert webhook delivery.
+// The endpoint and signing secret are read when the job runs so they never sit in the queue.
+type DeliverUsageAlertWebhookArgs struct {
+	AlertID        uint `json:"alert_id"`
+	OrganizationID uint `json:"organization_id"`
+}
+
+// Kind returns the job type identifier
+func (DeliverUsageAlertWebhookArgs) Kind() string {
+	return "deliver_usage_alert_webhook"
+}
+
+// InsertOpts returns default insert options for this job type
+func (DeliverUsageAlertWebhookArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       "webhooks",
+		MaxAttempts: 8, // Retries with backoff cover a receiver being down for a few hours
+		UniqueOpts: river.UniqueOpts{
+			ByArgs: true, // One delivery per alert
+		},
+	}
+}
+
+// UsageAlertWebhookDeliverer signs and POSTs a usage alert to its organization's webhook.
+// The services package registers its implementation at startup since jobs cannot import it.
+type UsageAlertWebhookDeliverer interface {
+	DeliverUsageAlertWebhook(ctx context.Context, alertID, orgID uint) error
+}
+
+var usageAlertWebhookDeliverer UsageAlertWebhookDeliverer
+
+// SetUsageAlertWebhookDeliverer registers the deliverer used by DeliverUsageAlertWebhookWorker
+func SetUsageAlertWebhookDeliverer(deliverer UsageAlertWebhookDeliverer) {
+	usageAlertWebhookDeliverer = deliverer
+}
+
+// DeliverUsageAlertWebhookWorker delivers usage alerts to organization webhooks
+type DeliverUsageAlertWebhookWorker struct {
+	river.WorkerDefaults[DeliverUsageAlertWebhookArgs]
+}
+
+// Work executes the webhook delivery. Returning an error lets River retry with backoff.
+func (w *DeliverUsageAlertWebhookWorker) Work(ctx context.Context, job *river.Job[DeliverUsageAlertWebhookArgs]) error {
+	args := job.Args
+
+	if usageAlertWebhookDeliverer == nil {
+		return fmt.Errorf("usage alert webhook deliverer not registered")
+	}
+
+	if err := usageAlertWebhookDeliverer.DeliverUsageAlertWebhook(ctx, args.AlertID, args.OrganizationID); err != nil {
+		log.Warn().
+			Err(err).
+			Uint("alert_id", args.AlertID).
+			Uint("org_id", args.OrganizationID).
+			Msg("usage alert webhook delivery failed")
+		return fmt.Errorf("usage alert webhook delivery failed: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueUsageAlertWebhook queues delivery of a usage alert to an organization's webhook
+func EnqueueUsageAlertWebhook(ctx context.Context, alertID, orgID uint) error {
+	if !IsAvailable() {
+		return fmt.Errorf("job system not available")
+	}
+
+	return Insert(ctx, DeliverUsageAlertWebhookArgs{
+		AlertID:        alertID,
+		OrganizationID: orgID,
+	}, nil)
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeUsageAlertWebhookDeliverer struct {
+	alertID uint
+	orgID   uint
+	err     error
+}
+
+func (f *fakeUsageAlertWebhookDeliverer) DeliverUsageAlertWebhook(ctx context.Context, alertID, orgID uint) error {
+	f.alertID = alertID
+	f.orgID = orgID
+	return f.err
+}
+
+func TestUsageAlertArgs_InsertOpts(t *testing.T) {
+	if opts := (SendUsageAlertEmailArgs{}).InsertOpts(); opts.Queue != "email" {
+		t.Errorf("SendUsageAlertEmailArgs.InsertOpts().Queue = %q, want %q", opts.Queue, "email")
+	}
+
+	opts := DeliverUsageAlertWebhookArgs{}.InsertOpts()
+	if opts.Queue != "webhooks" {
+		t.Errorf("DeliverUsageAlertWebhookArgs.InsertOpts().Queue = %q, want %q", opts.Queue, "webhooks")
+	}
+	if !opts.UniqueOpts.ByArgs {
+		t.Error("DeliverUsageAlertWebhookArgs should be unique by args so an alert is delivered once")
+	}
+}
+
+func TestDeliverUsageAlertWebhookWorker_NoDeliverer(t *testing.T) {
+	oldDeliverer := usageAlertWebhookDeliverer
+	usageAlertWebhookDeliverer = nil
+	defer func() { usageAlertWebhookDeliverer = oldDeliverer }()
+
+	worker := &DeliverUsageAlertWebhookWorker{}
+	job := &river.Job[DeliverUsageAlertWebhookArgs]{Args: DeliverUsageAlertWebhookArgs{AlertID: 1, OrganizationID: 2}}
+	if err := worker.Work(context.Background(), job); err == nil {
+		t.Error("Work() should return error when no deliverer is registered")
+	}
+}
+
+func TestDeliverUsageAlertWebhookWorker_DelegatesToDeliverer(t *testing.T) {
+	oldDeliverer := usageAlertWebhookDeliverer
+	defer func() { usageAlertWebhookDeliverer = oldDeliverer }()
+
+	deliverer := &fakeUsageAlertWebhookDeliverer{}
+	SetUsageAlertWebhookDeliverer(deliverer)
+
+	worker := &DeliverUsageAlertWebhookWorker{}
+	job := &river.Job[DeliverUsageAlertWebhookArgs]{Args: DeliverUsageAlertWebhookArgs{AlertID: 7, OrganizationID: 3}}
+	if err := worker.Work(context.Background(), job); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if deliverer.alertID != 7 || deliverer.orgID != 3 {
+		t.Errorf("deliverer called with alert %d org %d, want alert 7 org 3", deliverer.alertID, deliverer.orgID)
+	}
+
+	deliverer.err = errors.New("receiver returned 503")
+	if err := worker.Work(context.Background(), job); err == nil {
+		t.Error("Work() should return error so the delivery is retried")
+	}
+}
+
+func TestEnqueueUsageAlerts_JobsUnavailable(t *testing.T) {
+	oldInstance := instance
+	instance = nil
+	defer func() { instance = oldInstance }()
+
+	if err := EnqueueUsageAlertEmails(context.Background(), []SendUsageAlertEmailArgs{{AlertID: 1, Email: "a@example.com"}}); err == nil {
+		t.Error("EnqueueUsageAlertEmails() should return error when the job system is unavailable")
+	}
+	if err := EnqueueUsageAlertWebhook(context.Background(), 1, 2); err == nil {
+		t.Error("EnqueueUsageAlertWebhook() should return error when the job system is unavailable")
+	}
+}
@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// FlushUsageCountersArgs contains the arguments for a usage counter flush
+type FlushUsageCountersArgs struct{}
+
+// Kind returns the job type identifier
+func (FlushUsageCountersArgs) Kind() string {
+	return "flush_usage_counters"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (FlushUsageCountersArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 30 * time.Second, // Collapse overlapping runs
+		},
+	}
+}
+
+// UsageCounterFlusher moves live usage counters into the usage period totals.
+// The services package registers its implementation at startup since jobs cannot import it.
+type UsageCounterFlusher interface {
+	FlushCounters(ctx context.Context) (int, error)
+}
+
+var usageCounterFlusher UsageCounterFlusher
+
+// SetUsageCounterFlusher registers the flusher used by FlushUsageCountersWorker
+func SetUsageCounterFlusher(flusher UsageCounterFlusher) {
+	usageCounterFlusher = flusher
+}
+
+// FlushUsageCountersWorker flushes the shared usage counters on a schedule
+type FlushUsageCountersWorker struct {
+	river.WorkerDefaults[FlushUsageCountersArgs]
+}
+
+// Work runs a flush. Counters that fail to flush are kept for the next run.
+func (w *FlushUsageCountersWorker) Work(ctx context.Context, job *river.Job[FlushUsageCountersArgs]) error {
+	if usageCounterFlusher == nil {
+		log.Debug().Msg("usage counter flushing not configured, skipping")
+		return nil
+	}
+
+	start := time.Now()
+	flushed, err := usageCounterFlusher.FlushCounters(ctx)
+	if err != nil {
+		return fmt.Errorf("usage counter flush failed: %w", err)
+	}
+
+	log.Debug().
+		Int("counters", flushed).
+		Dur("duration", time.Since(start)).
+		Msg("usage counter flush completed")
+
+	return nil
+}
+
+// usageFlushPeriodicJob schedules usage counter flushes at the given interval
+func usageFlushPeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return FlushUsageCountersArgs{}, nil
+		},
+		nil,
+	)
+}
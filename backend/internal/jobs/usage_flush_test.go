@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeUsageCounterFlusher struct {
+	calls int
+	err   error
+}
+
+func (f *fakeUsageCounterFlusher) FlushCounters(ctx context.Context) (int, error) {
+	f.calls++
+	return 3, f.err
+}
+
+func TestFlushUsageCountersArgs_Kind(t *testing.T) {
+	args := FlushUsageCountersArgs{}
+	if args.Kind() != "flush_usage_counters" {
+		t.Errorf("Kind() = %q, want %q", args.Kind(), "flush_usage_counters")
+	}
+}
+
+func TestFlushUsageCountersArgs_InsertOpts(t *testing.T) {
+	opts := FlushUsageCountersArgs{}.InsertOpts()
+
+	if opts.Queue != river.QueueDefault {
+		t.Errorf("InsertOpts().Queue = %q, want %q", opts.Queue, river.QueueDefault)
+	}
+	if opts.UniqueOpts.ByPeriod <= 0 {
+		t.Error("InsertOpts().UniqueOpts.ByPeriod should be set to collapse overlapping runs")
+	}
+}
+
+func TestFlushUsageCountersWorker_NoFlusher(t *testing.T) {
+	oldFlusher := usageCounterFlusher
+	usageCounterFlusher = nil
+	defer func() { usageCounterFlusher = oldFlusher }()
+
+	worker := &FlushUsageCountersWorker{}
+	if err := worker.Work(context.Background(), &river.Job[FlushUsageCountersArgs]{}); err != nil {
+		t.Errorf("Work() error = %v, want nil when flushing is not configured", err)
+	}
+}
+
+func TestFlushUsageCountersWorker_DelegatesToFlusher(t *testing.T) {
+	oldFlusher := usageCounterFlusher
+	defer func() { usageCounterFlusher = oldFlusher }()
+
+	flusher := &fakeUsageCounterFlusher{}
+	SetUsageCounterFlusher(flusher)
+
+	worker := &FlushUsageCountersWorker{}
+	if err := worker.Work(context.Background(), &river.Job[FlushUsageCountersArgs]{}); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if flusher.calls != 1 {
+		t.Errorf("flusher called %d times, want 1", flusher.calls)
+	}
+
+	flusher.err = errors.New("database unavailable")
+	if err := worker.Work(context.Background(), &river.Job[FlushUsageCountersArgs]{}); err == nil {
+		t.Error("Work() should return error so the run is retried")
+	}
+}
+
+func TestUsageFlushPeriodicJob(t *testing.T) {
+	if job := usageFlushPeriodicJob(time.Minute); job == nil {
+		t.Error("usageFlushPeriodicJob() returned nil")
+	}
+}
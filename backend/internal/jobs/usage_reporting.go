@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// ReportMeteredUsageArgs contains the arguments for a metered usage reporting run
+type ReportMeteredUsageArgs struct{}
+
+// Kind returns the job type identifier
+func (ReportMeteredUsageArgs) Kind() string {
+	return "report_metered_usage"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (ReportMeteredUsageArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 5 * time.Minute, // Collapse overlapping runs
+		},
+	}
+}
+
+// MeteredUsageReporter reports aggregated usage to the billing provider.
+// The stripe package registers its implementation at startup since jobs cannot import it.
+type MeteredUsageReporter interface {
+	ReportUsage(ctx context.Context) error
+}
+
+var meteredUsageReporter MeteredUsageReporter
+
+// SetMeteredUsageReporter registers the reporter used by ReportMeteredUsageWorker
+func SetMeteredUsageReporter(reporter MeteredUsageReporter) {
+	meteredUsageReporter = reporter
+}
+
+// ReportMeteredUsageWorker reports metered usage on a schedule
+type ReportMeteredUsageWorker struct {
+	river.WorkerDefaults[ReportMeteredUsageArgs]
+}
+
+// Work runs a reporting pass. Reports are idempotent, so a failed run is safely retried.
+func (w *ReportMeteredUsageWorker) Work(ctx context.Context, job *river.Job[ReportMeteredUsageArgs]) error {
+	if meteredUsageReporter == nil {
+		log.Debug().Msg("metered usage reporting not configured, skipping")
+		return nil
+	}
+
+	start := time.Now()
+	if err := meteredUsageReporter.ReportUsage(ctx); err != nil {
+		return fmt.Errorf("metered usage reporting failed: %w", err)
+	}
+
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Msg("metered usage reporting completed")
+
+	return nil
+}
+
+// meteredUsagePeriodicJob schedules metered usage reporting at the given interval
+func meteredUsagePeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return ReportMeteredUsageArgs{}, nil
+		},
+		nil,
+	)
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeMeteredUsageReporter struct {
+	calls int
+	err   error
+}
+
+func (f *fakeMeteredUsageReporter) ReportUsage(ctx context.Context) error {
+	f.calls++
+	return f.err
+}
+
+func TestReportMeteredUsageArgs_Kind(t *testing.T) {
+	args := ReportMeteredUsageArgs{}
+	if args.Kind() != "report_metered_usage" {
+		t.Errorf("Kind() = %q, want %q", args.Kind(), "report_metered_usage")
+	}
+}
+
+func TestReportMeteredUsageArgs_InsertOpts(t *testing.T) {
+	opts := ReportMeteredUsageArgs{}.InsertOpts()
+
+	if opts.Queue != river.QueueDefault {
+		t.Errorf("InsertOpts().Queue = %q, want %q", opts.Queue, river.QueueDefault)
+	}
+	if opts.UniqueOpts.ByPeriod <= 0 {
+		t.Error("InsertOpts().UniqueOpts.ByPeriod should be set to collapse overlapping runs")
+	}
+}
+
+func TestReportMeteredUsageWorker_NoReporter(t *testing.T) {
+	oldReporter := meteredUsageReporter
+	meteredUsageReporter = nil
+	defer func() { meteredUsageReporter = oldReporter }()
+
+	worker := &ReportMeteredUsageWorker{}
+	if err := worker.Work(context.Background(), &river.Job[ReportMeteredUsageArgs]{}); err != nil {
+		t.Errorf("Work() error = %v, want nil when reporting is not configured", err)
+	}
+}
+
+func TestReportMeteredUsageWorker_DelegatesToReporter(t *testing.T) {
+	oldReporter := meteredUsageReporter
+	defer func() { meteredUsageReporter = oldReporter }()
+
+	reporter := &fakeMeteredUsageReporter{}
+	SetMeteredUsageReporter(reporter)
+
+	worker := &ReportMeteredUsageWorker{}
+	if err := worker.Work(context.Background(), &river.Job[ReportMeteredUsageArgs]{}); err != nil {
+		t.Fatalf("Work() error = %v", err)
+	}
+	if reporter.calls != 1 {
+		t.Errorf("reporter called %d times, want 1", reporter.calls)
+	}
+
+	reporter.err = errors.New("stripe unavailable")
+	if err := worker.Work(context.Background(), &river.Job[ReportMeteredUsageArgs]{}); err == nil {
+		t.Error("Work() should return error so the run is retried")
+	}
+}
+
+func TestMeteredUsagePeriodicJob(t *testing.T) {
+	if job := meteredUsagePeriodicJob(time.Hour); job == nil {
+		t.Error("meteredUsagePeriodicJob() returned nil")
+	}
+}
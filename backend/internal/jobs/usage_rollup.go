@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+)
+
+// RollupUsageArgs contains the arguments for a usage rollup run
+type RollupUsageArgs struct{}
+
+// Kind returns the job type identifier
+func (RollupUsageArgs) Kind() string {
+	return "rollup_usage"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (RollupUsageArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 5 * time.Minute, // Collapse overlapping runs
+		},
+	}
+}
+
+// EnforceUsageRetentionArgs contains the arguments for a usage retention run
+type EnforceUsageRetentionArgs struct{}
+
+// Kind returns the job type identifier
+func (EnforceUsageRetentionArgs) Kind() string {
+	return "enforce_usage_retention"
+}
+
+// InsertOpts returns the default insert options for this job type
+func (EnforceUsageRetentionArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       river.QueueDefault,
+		MaxAttempts: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByPeriod: 1 * time.Hour, // One run at a time
+		},
+	}
+}
+
+// UsageMaintainer rebuilds usage rollups and removes usage detail past retention.
+// The services package registers its implementation at startup since jobs cannot import it.
+type UsageMaintainer interface {
+	RollupUsage(ctx context.Context) (int64, error)
+	EnforceUsageRetention(ctx context.Context) (int64, error)
+}
+
+var usageMaintainer UsageMaintainer
+
+// SetUsageMaintainer registers the maintainer used by the usage rollup and retention workers
+func SetUsageMaintainer(maintainer UsageMaintainer) {
+	usageMaintainer = maintainer
+}
+
+// RollupUsageWorker rebuilds the hourly and daily usage rollups on a schedule
+type RollupUsageWorker struct {
+	river.WorkerDefaults[RollupUsageArgs]
+}
+
+// Work runs a rollup. Runs rebuild recent buckets, so a failed run is repaired by the next.
+func (w *RollupUsageWorker) Work(ctx context.Context, job *river.Job[RollupUsageArgs]) error {
+	if usageMaintainer == nil {
+		log.Debug().Msg("usage rollups not configured, skipping")
+		return nil
+	}
+
+	start := time.Now()
+	rows, err := usageMaintainer.RollupUsage(ctx)
+	if err != nil {
+		return fmt.Errorf("usage rollup failed: %w", err)
+	}
+
+	log.Debug().
+		Int64("rows", rows).
+		Dur("duration", time.Since(start)).
+		Msg("usage rollup completed")
+
+	return nil
+}
+
+// EnforceUsageRetentionWorker maintains usage_events partitions and removes expired usage detail
+type EnforceUsageRetentionWorker struct {
+	river.WorkerDefaults[EnforceUsageRetentionArgs]
+}
+
+// Work runs the retention policy
+func (w *EnforceUsageRetentionWorker) Work(ctx context.Context, job *river.Job[EnforceUsageRetentionArgs]) error {
+	if usageMaintainer == nil {
+		log.Debug().Msg("usage retention not configured, skipping")
+		return nil
+	}
+
+	start := time.Now()
+	removed, err := usageMaintainer.EnforceUsageRetention(ctx)
+	if err != nil {
+		return fmt.Errorf("usage retention failed: %w", err)
+	}
+
+	log.Info().
+		Int64("removed", removed).
+		Dur("duration", time.Since(start)).
+		Msg("usage retention completed")
+
+	return nil
+}
+
+// usageRollupPeriodicJob schedules usage rollups at the given interval
+func usageRollupPeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return RollupUsageArgs{}, nil
+		},
+		nil,
+	)
+}
+
+// usageRetentionPeriodicJob schedules usage retention at the given interval
+func usageRetentionPeriodicJob(interval time.Duration) *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(interval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return EnforceUsageRetentionArgs{}, nil
+		},
+		nil,
+	)
+}
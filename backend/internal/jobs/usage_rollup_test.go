@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+type fakeUsageMaintainer struct {
+	rollups   int
+	retention int
+	err       error
+}
+
+func (f *fakeUsageMaintainer) RollupUsage(ctx context.Context) (int64, error) {
+	f.rollups++
+	return 4, f.err
+}
+
+func (f *fakeUsageMaintainer) EnforceUsageRetention(ctx context.Context) (int64, error) {
+	f.retention++
+	return 2, f.err
+}
+
+func TestUsageRollupArgs_Kind(t *testing.T) {
+	if kind := (RollupUsageArgs{}).Kind(); kind != "rollup_usage" {
+		t.Errorf("RollupUsageArgs.Kind() = %q, want %q", kind, "rollup_usage")
+	}
+	if kind := (EnforceUsageRetentionArgs{}).Kind(); kind != "enforce_usage_retention" {
+		t.Errorf("EnforceUsageRetentionArgs.Kind() = %q, want %q", kind, "enforce_usage_retention")
+	}
+}
+
+func TestUsageRollupArgs_InsertOpts(t *testing.T) {
+	for _, opts := range []river.InsertOpts{RollupUsageArgs{}.InsertOpts(), EnforceUsageRetentionArgs{}.InsertOpts()} {
+		if opts.Queue != river.QueueDefault {
+			t.Errorf("InsertOpts().Queue = %q, want %q", opts.Queue, river.QueueDefault)
+		}
+		if opts.UniqueOpts.ByPeriod <= 0 {
+			t.Error("InsertOpts().UniqueOpts.ByPeriod should be set to collapse overlapping runs")
+		}
+	}
+}
+
+func TestUsageMaintenanceWorkers_NoMaintainer(t *testing.T) {
+	oldMaintainer := usageMaintainer
+	usageMaintainer = nil
+	defer func() { usageMaintainer = oldMaintainer }()
+
+	if err := (&RollupUsageWorker{}).Work(context.Background(), &river.Job[RollupUsageArgs]{}); err != nil {
+		t.Errorf("RollupUsageWorker.Work() error = %v, want nil when not configured", err)
+	}
+	if err := (&EnforceUsageRetentionWorker{}).Work(context.Background(), &river.Job[EnforceUsageRetentionArgs]{}); err != nil {
+		t.Errorf("EnforceUsageRetentionWorker.Work() error = %v, want nil when not configured", err)
+	}
+}
+
+func TestUsageMaintenanceWorkers_DelegateToMaintainer(t *testing.T) {
+	oldMaintainer := usageMaintainer
+	defer func() { usageMaintainer = oldMaintainer }()
+
+	maintainer := &fakeUsageMaintainer{}
+	SetUsageMaintainer(maintainer)
+
+	if err := (&RollupUsageWorker{}).Work(context.Background(), &river.Job[RollupUsageArgs]{}); err != nil {
+		t.Fatalf("RollupUsageWorker.Work() error = %v", err)
+	}
+	if err := (&EnforceUsageRetentionWorker{}).Work(context.Background(), &river.Job[EnforceUsageRetentionArgs]{}); err != nil {
+		t.Fatalf("EnforceUsageRetentionWorker.Work() error = %v", err)
+	}
+	if maintainer.rollups != 1 || maintainer.retention != 1 {
+		t.Errorf("maintainer called %d/%d times, want 1/1", maintainer.rollups, maintainer.retention)
+	}
+
+	maintainer.err = errors.New("database unavailable")
+	if err := (&RollupUsageWorker{}).Work(context.Background(), &river.Job[RollupUsageArgs]{}); err == nil {
+		t.Error("RollupUsageWorker.Work() should return error so the run is retried")
+	}
+	if err := (&EnforceUsageRetentionWorker{}).Work(context.Background(), &river.Job[EnforceUsageRetentionArgs]{}); err == nil {
+		t.Error("EnforceUsageRetentionWorker.Work() should return error so the run is retried")
+	}
+}
+
+func TestUsageMaintenancePeriodicJobs(t *testing.T) {
+	if job := usageRollupPeriodicJob(15 * time.Minute); job == nil {
+		t.Error("usageRollupPeriodicJob() returned nil")
+	}
+	if job := usageRetentionPeriodicJob(24 * time.Hour); job == nil {
+		t.Error("usageRetentionPeriodicJob() returned nil")
+	}
+}
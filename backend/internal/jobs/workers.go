@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"react-golang-starter/internal/email"
 
@@ -145,9 +146,10 @@ func (w *SendPasswordResetEmailWorker) Work(ctx context.Context, job *river.Job[
 
 // ProcessStripeWebhookArgs contains Stripe webhook data
 type ProcessStripeWebhookArgs struct {
-	EventID   string          `json:"event_id"`
-	EventType string          `json:"event_type"`
-	Payload   json.RawMessage `json:"payload"`
+	EventID    string          `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload"`
+	ReplayedAt int64           `json:"replayed_at,omitempty"` // Set on manual replays so they bypass uniqueness
 }
 
 // Kind returns the job type identifier
@@ -166,12 +168,27 @@ func (ProcessStripeWebhookArgs) InsertOpts() river.InsertOpts {
 	}
 }
 
+// StripeEventHandler processes a Stripe webhook event. finalAttempt is set when a failure
+// will not be retried.
+// The stripe package registers its implementation at startup since jobs cannot import it.
+type StripeEventHandler interface {
+	HandleStripeEvent(ctx context.Context, eventID, eventType string, payload json.RawMessage, finalAttempt bool) error
+}
+
+var stripeEventHandler StripeEventHandler
+
+// SetStripeEventHandler registers the handler used by ProcessStripeWebhookWorker
+func SetStripeEventHandler(handler StripeEventHandler) {
+	stripeEventHandler = handler
+}
+
 // ProcessStripeWebhookWorker processes Stripe webhook events
 type ProcessStripeWebhookWorker struct {
 	river.WorkerDefaults[ProcessStripeWebhookArgs]
 }
 
-// Work executes the Stripe webhook processing job
+// Work executes the Stripe webhook processing job.
+// Returning an error lets River retry the event with backoff.
 func (w *ProcessStripeWebhookWorker) Work(ctx context.Context, job *river.Job[ProcessStripeWebhookArgs]) error {
 	args := job.Args
 
@@ -180,54 +197,12 @@ func (w *ProcessStripeWebhookWorker) Work(ctx context.Context, job *river.Job[Pr
 		Str("event_type", args.EventType).
 		Msg("processing Stripe webhook")
 
-	switch args.EventType {
-	case "checkout.session.completed":
-		return w.handleCheckoutCompleted(ctx, args.Payload)
-	case "customer.subscription.created":
-		return w.handleSubscriptionCreated(ctx, args.Payload)
-	case "customer.subscription.updated":
-		return w.handleSubscriptionUpdated(ctx, args.Payload)
-	case "customer.subscription.deleted":
-		return w.handleSubscriptionDeleted(ctx, args.Payload)
-	case "invoice.payment_succeeded":
-		return w.handlePaymentSucceeded(ctx, args.Payload)
-	case "invoice.payment_failed":
-		return w.handlePaymentFailed(ctx, args.Payload)
-	default:
-		log.Warn().Str("event_type", args.EventType).Msg("unhandled Stripe event type")
-		return nil // Don't retry for unknown events
+	if stripeEventHandler == nil {
+		return fmt.Errorf("stripe event handler not registered")
 	}
-}
-
-func (w *ProcessStripeWebhookWorker) handleCheckoutCompleted(ctx context.Context, payload json.RawMessage) error {
-	// Stripe service will implement the actual logic
-	log.Info().Msg("handling checkout.session.completed")
-	return nil
-}
-
-func (w *ProcessStripeWebhookWorker) handleSubscriptionCreated(ctx context.Context, payload json.RawMessage) error {
-	log.Info().Msg("handling customer.subscription.created")
-	return nil
-}
-
-func (w *ProcessStripeWebhookWorker) handleSubscriptionUpdated(ctx context.Context, payload json.RawMessage) error {
-	log.Info().Msg("handling customer.subscription.updated")
-	return nil
-}
-
-func (w *ProcessStripeWebhookWorker) handleSubscriptionDeleted(ctx context.Context, payload json.RawMessage) error {
-	log.Info().Msg("handling customer.subscription.deleted")
-	return nil
-}
-
-func (w *ProcessStripeWebhookWorker) handlePaymentSucceeded(ctx context.Context, payload json.RawMessage) error {
-	log.Info().Msg("handling invoice.payment_succeeded")
-	return nil
-}
 
-func (w *ProcessStripeWebhookWorker) handlePaymentFailed(ctx context.Context, payload json.RawMessage) error {
-	log.Info().Msg("handling invoice.payment_failed")
-	return nil
+	finalAttempt := job.Attempt >= job.MaxAttempts
+	return stripeEventHandler.HandleStripeEvent(ctx, args.EventID, args.EventType, args.Payload, finalAttempt)
 }
 
 // ============================================
@@ -275,6 +250,20 @@ func EnqueueStripeWebhook(ctx context.Context, eventID, eventType string, payloa
 	}, nil)
 }
 
+// ReplayStripeWebhook re-queues a stored Stripe webhook event for processing
+func ReplayStripeWebhook(ctx context.Context, eventID, eventType string, payload json.RawMessage) error {
+	if !IsAvailable() {
+		return fmt.Errorf("job system not available")
+	}
+
+	return Insert(ctx, ProcessStripeWebhookArgs{
+		EventID:    eventID,
+		EventType:  eventType,
+		Payload:    payload,
+		ReplayedAt: time.Now().UnixNano(),
+	}, nil)
+}
+
 // ============================================
 // Announcement Email Worker
 // ============================================
@@ -457,3 +446,90 @@ func EnqueueAccountLockoutNotification(ctx context.Context, userID uint, email,
 		FailedAttempts: failedAttempts,
 	}, nil)
 }
+
+// ============================================
+// Organization Deletion Email Worker
+// ============================================
+
+// SendOrgDeletionEmailArgs contains the job arguments for organization deletion notices
+type SendOrgDeletionEmailArgs struct {
+	OrganizationID uint   `json:"organization_id"`
+	OrgName        string `json:"org_name"`
+	OrgSlug        string `json:"org_slug"`
+	UserID         uint   `json:"user_id"`
+	Email          string `json:"email"`
+	Name           string `json:"name"`
+	ScheduledFor   string `json:"scheduled_for"`
+	CanRestore     bool   `json:"can_restore"`
+}
+
+// Kind returns the job type identifier
+func (SendOrgDeletionEmailArgs) Kind() string {
+	return "send_org_deletion_email"
+}
+
+// InsertOpts returns default insert options for this job type
+func (SendOrgDeletionEmailArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       "email",
+		MaxAttempts: 5,
+	}
+}
+
+// SendOrgDeletionEmailWorker processes organization deletion notice jobs
+type SendOrgDeletionEmailWorker struct {
+	river.WorkerDefaults[SendOrgDeletionEmailArgs]
+}
+
+// Work executes the organization deletion notice job
+func (w *SendOrgDeletionEmailWorker) Work(ctx context.Context, job *river.Job[SendOrgDeletionEmailArgs]) error {
+	args := job.Args
+
+	log.Info().
+		Uint("org_id", args.OrganizationID).
+		Uint("user_id", args.UserID).
+		Str("email", args.Email).
+		Msg("sending organization deletion email")
+
+	settingsURL := fmt.Sprintf("%s/org/%s/settings", email.GetFrontendURL(), args.OrgSlug)
+
+	err := email.Send(ctx, email.SendParams{
+		To:           args.Email,
+		TemplateName: "org_deletion_scheduled",
+		Data: map[string]interface{}{
+			"Name":         args.Name,
+			"OrgName":      args.OrgName,
+			"ScheduledFor": args.ScheduledFor,
+			"CanRestore":   args.CanRestore,
+			"SettingsURL":  settingsURL,
+		},
+	})
+
+	if err != nil {
+		log.Error().Err(err).Str("email", args.Email).Msg("failed to send organization deletion email")
+		return fmt.Errorf("failed to send organization deletion email: %w", err)
+	}
+
+	log.Info().
+		Uint("org_id", args.OrganizationID).
+		Uint("user_id", args.UserID).
+		Msg("organization deletion email sent successfully")
+
+	return nil
+}
+
+// EnqueueOrgDeletionEmails queues deletion notices for a batch of organization members
+func EnqueueOrgDeletionEmails(ctx context.Context, notices []SendOrgDeletionEmailArgs) error {
+	if !IsAvailable() {
+		return fmt.Errorf("job system not available")
+	}
+	if len(notices) == 0 {
+		return nil
+	}
+
+	params := make([]river.InsertManyParams, len(notices))
+	for i, n := range notices {
+		params[i] = river.InsertManyParams{Args: n}
+	}
+	return InsertMany(ctx, params)
+}
@@ -3,7 +3,11 @@ package jobs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
+
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
 )
 
 // ============ SendVerificationEmailArgs Tests ============
@@ -255,6 +259,22 @@ func TestJobKindsAreUnique(t *testing.T) {
 		SendAnnouncementEmailArgs{}.Kind(),
 		SendAccountLockedEmailArgs{}.Kind(),
 		DataExportArgs{}.Kind(),
+		OrgDataExportArgs{}.Kind(),
+		SendOrgDeletionEmailArgs{}.Kind(),
+		ReportMeteredUsageArgs{}.Kind(),
+		ProcessDunningArgs{}.Kind(),
+		SendDunningEmailArgs{}.Kind(),
+		ExpireTrialsArgs{}.Kind(),
+		SendTrialEndingEmailArgs{}.Kind(),
+		SyncSeatQuantityArgs{}.Kind(),
+		FlushUsageCountersArgs{}.Kind(),
+		RollupUsageArgs{}.Kind(),
+		EnforceUsageRetentionArgs{}.Kind(),
+		SendUsageAlertEmailArgs{}.Kind(),
+		DeliverUsageAlertWebhookArgs{}.Kind(),
+		CleanupFileUploadsArgs{}.Kind(),
+		PurgeOrganizationsArgs{}.Kind(),
+		SweepStripeEventsArgs{}.Kind(),
 	}
 
 	for _, kind := range jobKinds {
@@ -276,6 +296,7 @@ func TestEmailJobsHaveReasonableMaxAttempts(t *testing.T) {
 		{"password reset email", SendPasswordResetEmailArgs{}.InsertOpts().MaxAttempts},
 		{"announcement email", SendAnnouncementEmailArgs{}.InsertOpts().MaxAttempts},
 		{"account locked email", SendAccountLockedEmailArgs{}.InsertOpts().MaxAttempts},
+		{"org deletion email", SendOrgDeletionEmailArgs{}.InsertOpts().MaxAttempts},
 	}
 
 	for _, tt := range tests {
@@ -350,6 +371,80 @@ func TestEnqueueStripeWebhook_NilInstance(t *testing.T) {
 	}
 }
 
+func TestReplayStripeWebhook_NilInstance(t *testing.T) {
+	oldInstance := instance
+	instance = nil
+	defer func() { instance = oldInstance }()
+
+	err := ReplayStripeWebhook(context.Background(), "evt_123", "invoice.payment_failed", json.RawMessage(`{}`))
+
+	if err == nil {
+		t.Error("ReplayStripeWebhook() should return error when instance is nil")
+	}
+}
+
+// ============ ProcessStripeWebhookWorker Tests ============
+
+type fakeStripeEventHandler struct {
+	eventID      string
+	eventType    string
+	finalAttempt bool
+	err          error
+}
+
+func (f *fakeStripeEventHandler) HandleStripeEvent(ctx context.Context, eventID, eventType string, payload json.RawMessage, finalAttempt bool) error {
+	f.eventID = eventID
+	f.eventType = eventType
+	f.finalAttempt = finalAttempt
+	return f.err
+}
+
+func TestProcessStripeWebhookWorker_NoHandler(t *testing.T) {
+	oldHandler := stripeEventHandler
+	stripeEventHandler = nil
+	defer func() { stripeEventHandler = oldHandler }()
+
+	worker := &ProcessStripeWebhookWorker{}
+	job := &river.Job[ProcessStripeWebhookArgs]{Args: ProcessStripeWebhookArgs{EventID: "evt_123"}}
+
+	if err := worker.Work(context.Background(), job); err == nil {
+		t.Error("Work() should return error when no handler is registered so the job is retried")
+	}
+}
+
+func TestProcessStripeWebhookWorker_DelegatesToHandler(t *testing.T) {
+	oldHandler := stripeEventHandler
+	defer func() { stripeEventHandler = oldHandler }()
+
+	handler := &fakeStripeEventHandler{err: errors.New("transient failure")}
+	SetStripeEventHandler(handler)
+
+	worker := &ProcessStripeWebhookWorker{}
+	job := &river.Job[ProcessStripeWebhookArgs]{Args: ProcessStripeWebhookArgs{
+		EventID:   "evt_123",
+		EventType: "customer.subscription.updated",
+		Payload:   json.RawMessage(`{}`),
+	}}
+	job.JobRow = &rivertype.JobRow{Attempt: 1, MaxAttempts: 3}
+
+	err := worker.Work(context.Background(), job)
+	if err == nil || err.Error() != "transient failure" {
+		t.Errorf("Work() error = %v, want handler error", err)
+	}
+	if handler.eventID != "evt_123" || handler.eventType != "customer.subscription.updated" {
+		t.Errorf("handler received (%q, %q), want (evt_123, customer.subscription.updated)", handler.eventID, handler.eventType)
+	}
+	if handler.finalAttempt {
+		t.Error("first of three attempts should not be final")
+	}
+
+	job.Attempt = 3
+	_ = worker.Work(context.Background(), job)
+	if !handler.finalAttempt {
+		t.Error("last attempt should be final")
+	}
+}
+
 func TestEnqueueAnnouncementEmail_NilInstance(t *testing.T) {
 	oldInstance := instance
 	instance = nil
@@ -390,3 +485,25 @@ func TestEnqueueAccountLockoutNotification_NilInstance(t *testing.T) {
 		t.Errorf("EnqueueAccountLockoutNotification() error = %q, want 'job system not available'", err.Error())
 	}
 }
+
+func TestSendOrgDeletionEmailArgs_Kind(t *testing.T) {
+	args := SendOrgDeletionEmailArgs{}
+	if args.Kind() != "send_org_deletion_email" {
+		t.Errorf("Kind() = %q, want %q", args.Kind(), "send_org_deletion_email")
+	}
+	if args.InsertOpts().Queue != "email" {
+		t.Errorf("InsertOpts().Queue = %q, want %q", args.InsertOpts().Queue, "email")
+	}
+}
+
+func TestEnqueueOrgDeletionEmails_NilInstance(t *testing.T) {
+	oldInstance := instance
+	instance = nil
+	defer func() { instance = oldInstance }()
+
+	err := EnqueueOrgDeletionEmails(context.Background(), []SendOrgDeletionEmailArgs{{UserID: 1, Email: "test@example.com"}})
+
+	if err == nil {
+		t.Error("EnqueueOrgDeletionEmails() should return error when instance is nil")
+	}
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/contextkeys"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/response"
+	"react-golang-starter/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// QuotaMiddleware enforces the plan quota for usageType before the handler runs.
+// It must be mounted after AuthMiddleware; requests in an organization context (tenant
+// route or active org claim) count against the organization, others against the user. Quota headers are set on every
+// checked response:
+// - X-Quota-Limit: plan limit for the usage type
+// - X-Quota-Remaining: usage left before the limit
+// - X-Quota-Reset: Unix timestamp when the usage period resets
+// - X-Quota-Warning: set when usage is past the limit under soft or overage mode
+//
+// Each request is checked at its weight in the usage cost table; routes that cost
+// nothing are not checked. The admitted weight is reserved in the live usage total
+// until UsageMiddleware or MeterUsage records the request.
+func QuotaMiddleware(quotaService *services.QuotaService, usageType string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cost := services.GetUsageCostTable().Cost(usageType, r.Method, resolveRoutePattern(r))
+			enforceQuota(w, r, next, quotaService, usageType, cost)
+		})
+	}
+}
+
+// StorageQuotaMiddleware enforces the plan's storage quota on uploads before their content
+// is read, at the size the client declares: the tus Upload-Length header, else the
+// Content-Length of the request. Requests that declare no size are checked at one byte,
+// so a subject already at its ceiling is still turned away. Quota headers are set as by
+// QuotaMiddleware.
+func StorageQuotaMiddleware(quotaService *services.QuotaService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			size := r.ContentLength
+			if length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64); err == nil {
+				size = length
+			}
+			enforceQuota(w, r, next, quotaService, services.UsageTypeStorage, max(size, 1))
+		})
+	}
+}
+
+// enforceQuota checks quantity of usageType for the request's subject and serves the
+// request when it is admitted
+func enforceQuota(w http.ResponseWriter, r *http.Request, next http.Handler, quotaService *services.QuotaService, usageType string, quantity int64) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if quotaService == nil || !ok || userID == 0 || quantity <= 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	subject := services.UserSubject(userID)
+	if orgID := auth.ActiveOrganizationID(r.Context()); orgID != nil {
+		subject = services.OrgSubject(*orgID)
+	}
+
+	decision, err := quotaService.Check(r.Context(), subject, usageType, quantity)
+	if err != nil {
+		// Fail open: a billing lookup problem should not take the API down
+		log.Warn().Err(err).Str("usage_type", usageType).Msg("quota check failed, allowing request")
+		next.ServeHTTP(w, r)
+		return
+	}
+	if decision.Limit <= 0 || decision.Exempt {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	setQuotaHeaders(w, decision)
+	if !decision.Allowed {
+		writeQuotaExceeded(w, r, decision)
+		return
+	}
+	if decision.OverLimit {
+		w.Header().Set("X-Quota-Warning", fmt.Sprintf("%s limit of %d exceeded", usageType, decision.Limit))
+	}
+
+	// Recording the request's usage settles the reservation; without a usage meter
+	// nothing will, so it is released once the handler is done
+	if decision.Reservation != nil && !services.AttachQuotaReservation(r.Context(), decision.Reservation) {
+		defer decision.Reservation.Release(context.WithoutCancel(r.Context()))
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+// resolveRoutePattern returns the chi route pattern a request will be routed to. Route
+// middleware runs before routing completes, so the pattern is looked up from the root router.
+func resolveRoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return r.URL.Path
+	}
+
+	pattern := rctx.Routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+	if pattern == "" {
+		return r.URL.Path
+	}
+	if pattern != "/" {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+// setQuotaHeaders adds the quota state to the response headers
+func setQuotaHeaders(w http.ResponseWriter, decision *services.QuotaDecision) {
+	w.Header().Set("X-Quota-Limit", strconv.FormatInt(decision.Limit, 10))
+	w.Header().Set("X-Quota-Remaining", strconv.FormatInt(decision.Remaining(), 10))
+	w.Header().Set("X-Quota-Reset", strconv.FormatInt(decision.ResetAt.Unix(), 10))
+}
+
+// writeQuotaExceeded writes the structured QUOTA_EXCEEDED response
+func writeQuotaExceeded(w http.ResponseWriter, r *http.Request, decision *services.QuotaDecision) {
+	status := decision.StatusCode()
+	if status == http.StatusTooManyRequests {
+		retryAfter := int(time.Until(decision.ResetAt).Seconds())
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	message := fmt.Sprintf("Your %s plan allows %d %s per billing period.", decision.Plan, decision.Limit, decision.UsageType)
+	if decision.UpgradeAvailable {
+		message += " Upgrade your plan to continue."
+	} else {
+		message += " Usage resets at the start of the next period."
+	}
+
+	requestID, _ := r.Context().Value(contextkeys.RequestIDKey).(string)
+	response.JSON(w, status, models.QuotaExceededResponse{
+		Error:     response.ErrCodeQuotaExceeded,
+		Message:   message,
+		Code:      status,
+		RequestID: requestID,
+		UsageType: decision.UsageType,
+		Limit:     decision.Limit,
+		Current:   decision.Current,
+		ResetAt:   decision.ResetAt.UTC().Format(time.RFC3339),
+		Plan:      decision.Plan,
+		QuotaMode: decision.Mode,
+	})
+}
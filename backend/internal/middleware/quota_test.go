@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/response"
+	"react-golang-starter/internal/services"
+	"react-golang-starter/internal/testutil/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupQuotaTest puts every subject on a free plan allowing two API calls under policy
+func setupQuotaTest(t *testing.T, policy models.QuotaPolicy) http.Handler {
+	t.Helper()
+
+	catalog := services.DefaultPlanCatalog()
+	free := catalog.Plan("free")
+	free.Limits.APICalls = 2
+	free.Quota = policy
+	catalog.Set(free)
+
+	previous := services.GetEntitlementsService()
+	services.SetEntitlementsService(services.NewEntitlementsService(nil, catalog))
+	t.Cleanup(func() { services.SetEntitlementsService(previous) })
+
+	usageService := services.NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+	quotaService := services.NewQuotaService(nil, usageService)
+	return QuotaMiddleware(quotaService, services.UsageTypeAPICall)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Record admitted calls as UsageMiddleware would, without waiting for a goroutine
+		if userID, ok := auth.GetUserIDFromContext(r.Context()); ok {
+			usageService.RecordAPICall(r.Context(), &userID, nil, r.URL.Path, "", "")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func quotaRequest(userID uint) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+	if userID == 0 {
+		return req
+	}
+	ctx := auth.SetUserContext(req.Context(), &models.User{ID: userID, Email: "quota@example.com"})
+	return req.WithContext(ctx)
+}
+
+func TestQuotaMiddleware_RejectsOverLimit(t *testing.T) {
+	handler := setupQuotaTest(t, models.QuotaPolicy{Mode: models.QuotaModeHard})
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, quotaRequest(1))
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, quotaRequest(1))
+
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code, "free plan can upgrade")
+	assert.Equal(t, "2", rr.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, "0", rr.Header().Get("X-Quota-Remaining"))
+	assert.NotEmpty(t, rr.Header().Get("X-Quota-Reset"))
+
+	var body models.QuotaExceededResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, response.ErrCodeQuotaExceeded, body.Error)
+	assert.Equal(t, services.UsageTypeAPICall, body.UsageType)
+	assert.Equal(t, int64(2), body.Limit)
+	assert.Equal(t, int64(2), body.Current)
+	assert.Equal(t, "free", body.Plan)
+	assert.NotEmpty(t, body.ResetAt)
+}
+
+func TestQuotaMiddleware_OverageWarns(t *testing.T) {
+	handler := setupQuotaTest(t, models.QuotaPolicy{Mode: models.QuotaModeOverage})
+
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, quotaRequest(1))
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	assert.NotEmpty(t, rr.Header().Get("X-Quota-Warning"))
+}
+
+func TestQuotaMiddleware_SkipsUnauthenticated(t *testing.T) {
+	handler := setupQuotaTest(t, models.QuotaPolicy{Mode: models.QuotaModeHard})
+
+	for i := 0; i < 5; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, quotaRequest(0))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("X-Quota-Limit"))
+	}
+}
+
+func TestQuotaMiddleware_NilServiceDisablesEnforcement(t *testing.T) {
+	handler := QuotaMiddleware(nil, services.UsageTypeAPICall)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, quotaRequest(1))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestQuotaMiddleware_ChecksRouteCost(t *testing.T) {
+	setupQuotaTest(t, models.QuotaPolicy{Mode: models.QuotaModeHard})
+
+	costs := services.DefaultUsageCostTable()
+	require.NoError(t, costs.SetRouteCost("/api/ai/chat", "POST", "", 2))
+	require.NoError(t, costs.SetRouteCost("/api/ai/models/{id}", "", "", 0))
+	previous := services.GetUsageCostTable()
+	services.SetUsageCostTable(costs)
+	t.Cleanup(func() { services.SetUsageCostTable(previous) })
+
+	usageService := services.NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+	quotaService := services.NewQuotaService(nil, usageService)
+
+	r := chi.NewRouter()
+	r.Route("/api/ai", func(r chi.Router) {
+		r.Use(QuotaMiddleware(quotaService, services.UsageTypeAPICall))
+		r.Post("/chat", func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := auth.GetUserIDFromContext(r.Context())
+			usageService.RecordAPIRequest(r.Context(), &userID, nil, r.Method, "/api/ai/chat", 1, "", "")
+			w.WriteHeader(http.StatusOK)
+		})
+		r.Get("/models/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	chat := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/ai/chat", nil)
+		req = req.WithContext(auth.SetUserContext(req.Context(), &models.User{ID: 1, Email: "quota@example.com"}))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := chat()
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-Quota-Remaining"), "remaining is reported before the request")
+
+	rr = chat()
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code, "a second chat call would exceed the limit of 2 units")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ai/models/gemini", nil)
+	req = req.WithContext(auth.SetUserContext(req.Context(), &models.User{ID: 1, Email: "quota@example.com"}))
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "free routes are not checked")
+	assert.Empty(t, rr.Header().Get("X-Quota-Limit"))
+}
+
+func TestStorageQuotaMiddleware_ChecksDeclaredSize(t *testing.T) {
+	catalog := services.DefaultPlanCatalog()
+	free := catalog.Plan("free")
+	free.Limits.StorageBytes = 1000
+	free.Quota = models.QuotaPolicy{Mode: models.QuotaModeHard}
+	catalog.Set(free)
+
+	previous := services.GetEntitlementsService()
+	services.SetEntitlementsService(services.NewEntitlementsService(nil, catalog))
+	t.Cleanup(func() { services.SetEntitlementsService(previous) })
+
+	usageService := services.NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+	handler := StorageQuotaMiddleware(services.NewQuotaService(nil, usageService))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	upload := func(setSize func(r *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/files/uploads", nil)
+		setSize(req)
+		req = req.WithContext(auth.SetUserContext(req.Context(), &models.User{ID: 1, Email: "quota@example.com"}))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := upload(func(r *http.Request) { r.Header.Set("Upload-Length", "1001") })
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code, "Upload-Length past the quota is rejected")
+
+	rr = upload(func(r *http.Request) { r.ContentLength = 2000 })
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code, "Content-Length past the quota is rejected")
+
+	rr = upload(func(r *http.Request) { r.Header.Set("Upload-Length", "1000") })
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "1000", rr.Header().Get("X-Quota-Remaining"))
+}
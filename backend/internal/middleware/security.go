@@ -264,3 +264,19 @@ func MaxBodySize(maxBytes int64) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// MaxBodySizeExcept is MaxBodySize for all requests except those whose path starts with one
+// of the exempt prefixes, such as file uploads that enforce their own limit while streaming
+func MaxBodySizeExcept(maxBytes int64, exemptPrefixes ...string) func(http.Handler) http.Handler {
+	limit := MaxBodySize(maxBytes)
+	return func(next http.Handler) http.Handler {
+		limited := limit(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isExemptPath(r.URL.Path, exemptPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -483,3 +485,38 @@ func TestSecurityHeaders_PassesToNextHandler(t *testing.T) {
 		t.Errorf("SecurityHeaders() status = %d, want %d", rr.Code, http.StatusTeapot)
 	}
 }
+
+// ============ MaxBodySizeExcept Tests ============
+
+func TestMaxBodySizeExcept(t *testing.T) {
+	handler := MaxBodySizeExcept(8, "/api/files/upload")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"limited path", "/api/users/me", http.StatusRequestEntityTooLarge},
+		{"exempt path", "/api/files/upload", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("more than eight bytes"))
+			req.ContentLength = -1 // force the streaming check
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,46 +1,143 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/services"
 
 	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
 )
 
-// UsageMiddleware records API call usage for authenticated requests
+const (
+	// usageRecordTimeout bounds recording a request's usage after the response is sent
+	usageRecordTimeout = 10 * time.Second
+)
+
+// UsageMiddleware records API call usage for authenticated requests. It is mounted before
+// authentication, so it collects the user and organization through an auth.RequestIdentity
+// filled in by the auth and tenant middleware. Calls are attributed to the organization
+// resolved by TenantMiddleware, else the active organization of the token: the same
+// subject QuotaMiddleware enforces the quota against (see auth.ActiveOrganizationID).
+// Each call is weighted by the usage cost table for its method and route pattern,
+// times the item count a handler reports with services.SetUsageQuantity. AI token usage
+// reported with services.AddAITokenUsage is recorded per model, and bytes reported with
+// services.AddStorageUsage as storage, with the same attribution.
 func UsageMiddleware(usageService *services.UsageService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, identity := auth.WithRequestIdentity(r.Context())
+			ctx, meter := services.WithUsageMeter(ctx)
+
 			// Process request first
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(ctx))
 
 			// Only record usage for authenticated users
-			userID, ok := auth.GetUserIDFromContext(r.Context())
-			if !ok || userID == 0 {
+			userID := identity.UserID()
+			if usageService == nil || userID == 0 {
+				meter.ReleaseReservations(context.WithoutCancel(r.Context()))
 				return
 			}
 
 			// Get route pattern for consistent resource identification
-			routePattern := chi.RouteContext(r.Context()).RoutePattern()
+			routePattern := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				routePattern = rctx.RoutePattern()
+			}
 			if routePattern == "" {
 				routePattern = r.URL.Path
 			}
 
 			// Skip certain paths from usage tracking
 			if shouldSkipUsageTracking(routePattern) {
+				meter.ReleaseReservations(context.WithoutCancel(r.Context()))
 				return
 			}
 
 			// Get client info
 			ip := getClientIP(r)
 			ua := r.Header.Get("User-Agent")
+			items := meter.Quantity()
+			aiTokens := meter.AITokens()
+			storageBytes := meter.StorageBytes()
+
+			orgID, _ := identity.Organization()
 
-			// Record the API call asynchronously to not block the response
+			// Record the API call asynchronously to not block the response.
+			// Use background context since request context will be canceled when response is sent
 			go func() {
-				usageService.RecordAPICall(r.Context(), &userID, nil, routePattern, ip, ua)
+				bgCtx, cancel := context.WithTimeout(context.Background(), usageRecordTimeout)
+				defer cancel()
+
+				// Recording settles the quota reservations of the request; the rest are released
+				bgCtx = services.ContextWithUsageMeter(bgCtx, meter)
+				usageService.RecordAPIRequest(bgCtx, &userID, orgID, r.Method, routePattern, items, ip, ua)
+				for _, usage := range aiTokens {
+					usageService.RecordAITokens(bgCtx, &userID, orgID, usage, ip, ua)
+				}
+				if storageBytes > 0 {
+					usageService.RecordStorageUsage(bgCtx, &userID, orgID, storageBytes, routePattern)
+				}
+				meter.ReleaseReservations(bgCtx)
+			}()
+		})
+	}
+}
+
+// MeterUsage records usageType at its weight in the usage cost table for each successful
+// request by an authenticated user, for usage that QuotaMiddleware enforces but that is not
+// an API call. It must be mounted after AuthMiddleware; usage in an organization context
+// counts against the organization.
+func MeterUsage(usageService *services.UsageService, usageType string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := auth.GetUserIDFromContext(r.Context())
+			if usageService == nil || !ok || userID == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(wrapped, r)
+
+			// Handlers that never write a status respond 200. The quota reservation of a
+			// request that is not recorded is released by UsageMiddleware.
+			if status := wrapped.Status(); status != 0 && (status < 200 || status >= 300) {
+				return
+			}
+
+			units := services.GetUsageCostTable().Cost(usageType, r.Method, resolveRoutePattern(r))
+			if units <= 0 {
+				return
+			}
+			meter := services.GetUsageMeter(r.Context())
+
+			event := &models.UsageEvent{
+				UserID:         &userID,
+				OrganizationID: auth.ActiveOrganizationID(r.Context()),
+				EventType:      usageType,
+				Resource:       r.URL.Path,
+				Quantity:       units,
+				IPAddress:      getClientIP(r),
+				UserAgent:      r.Header.Get("User-Agent"),
+			}
+
+			go func() {
+				bgCtx, cancel := context.WithTimeout(context.Background(), usageRecordTimeout)
+				defer cancel()
+
+				if meter != nil {
+					bgCtx = services.ContextWithUsageMeter(bgCtx, meter)
+				}
+				if err := usageService.RecordEvent(bgCtx, event); err != nil {
+					log.Warn().Err(err).Str("usage_type", usageType).Msg("failed to record metered usage")
+				}
 			}()
 		})
 	}
@@ -52,6 +149,7 @@ func shouldSkipUsageTracking(path string) bool {
 		"/api/health",
 		"/api/webhooks",
 		"/api/usage", // Don't count usage API calls themselves
+		"/api/organizations/{orgSlug}/usage",
 		"/metrics",
 		"/debug",
 	}
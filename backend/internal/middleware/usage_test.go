@@ -4,6 +4,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
+	"react-golang-starter/internal/testutil/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // ============ shouldSkipUsageTracking Tests ============
@@ -339,3 +349,237 @@ func TestUsageMiddleware_SkippedPaths(t *testing.T) {
 		})
 	}
 }
+
+func TestUsageMiddleware_AttributesActiveOrganization(t *testing.T) {
+	tests := []struct {
+		name      string
+		claims    *auth.Claims
+		orgHeader string
+		wantOrgID *uint
+	}{
+		{
+			name: "personal request",
+		},
+		{
+			name:      "active org claim",
+			claims:    &auth.Claims{UserID: 7, OrgID: 3, OrgSlug: "acme", OrgRole: string(models.OrgRoleMember)},
+			wantOrgID: uintPtr(3),
+		},
+		{
+			// Only tenant routes resolve the header, where the quota is checked against the same org
+			name:      "org header outside tenant routes",
+			orgHeader: "acme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventRepo := mocks.NewMockUsageEventRepository()
+			usageService := services.NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+			defer usageService.Shutdown()
+
+			// Stands in for AuthMiddleware mounted below the usage middleware
+			authenticate := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx := auth.SetUserContext(r.Context(), &models.User{ID: 7, Email: "user@example.com"})
+					if tt.claims != nil {
+						ctx = auth.SetClaimsContext(ctx, tt.claims)
+					}
+					next.ServeHTTP(w, r.WithContext(ctx))
+				})
+			}
+
+			handler := UsageMiddleware(usageService)(authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/files", nil)
+			if tt.orgHeader != "" {
+				req.Header.Set("X-Organization-Slug", tt.orgHeader)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			require.Eventually(t, func() bool { return len(eventRepo.GetEvents()) == 1 }, time.Second, 10*time.Millisecond)
+			event := eventRepo.GetEvents()[0]
+			require.NotNil(t, event.UserID)
+			assert.Equal(t, uint(7), *event.UserID)
+			assert.Equal(t, tt.wantOrgID, event.OrganizationID)
+			assert.Equal(t, services.UsageTypeAPICall, event.EventType)
+		})
+	}
+}
+
+func TestUsageMiddleware_SkipsUnauthenticated(t *testing.T) {
+	eventRepo := mocks.NewMockUsageEventRepository()
+	usageService := services.NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+	defer usageService.Shutdown()
+
+	handler := UsageMiddleware(usageService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/files", nil))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, eventRepo.GetEvents())
+}
+
+func TestUsageMiddleware_RecordsWeightedUnits(t *testing.T) {
+	costs := services.DefaultUsageCostTable()
+	require.NoError(t, costs.SetRouteCost("/api/ai/embeddings", "POST", "", 2))
+	previous := services.GetUsageCostTable()
+	services.SetUsageCostTable(costs)
+	defer services.SetUsageCostTable(previous)
+
+	eventRepo := mocks.NewMockUsageEventRepository()
+	usageService := services.NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+	defer usageService.Shutdown()
+
+	// Stands in for AuthMiddleware mounted below the usage middleware
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := auth.SetUserContext(r.Context(), &models.User{ID: 7, Email: "user@example.com"})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	r := chi.NewRouter()
+	r.Use(UsageMiddleware(usageService))
+	r.Use(authenticate)
+	r.Post("/api/ai/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		services.SetUsageQuantity(r.Context(), 3)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/ai/embeddings", nil))
+
+	require.Eventually(t, func() bool { return len(eventRepo.GetEvents()) == 1 }, time.Second, 10*time.Millisecond)
+	event := eventRepo.GetEvents()[0]
+	assert.Equal(t, int64(6), event.Quantity, "3 texts at 2 units each")
+	assert.Equal(t, "/api/ai/embeddings", event.Resource)
+}
+
+func TestUsageMiddleware_RecordsAITokens(t *testing.T) {
+	eventRepo := mocks.NewMockUsageEventRepository()
+	usageService := services.NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+	defer usageService.Shutdown()
+
+	// Stands in for AuthMiddleware mounted below the usage middleware
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := auth.SetUserContext(r.Context(), &models.User{ID: 7, Email: "user@example.com"})
+			ctx = auth.SetClaimsContext(ctx, &auth.Claims{UserID: 7, OrgID: 3, OrgSlug: "acme", OrgRole: string(models.OrgRoleMember)})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	r := chi.NewRouter()
+	r.Use(UsageMiddleware(usageService))
+	r.Use(authenticate)
+	r.Post("/api/ai/chat", func(w http.ResponseWriter, r *http.Request) {
+		services.AddAITokenUsage(r.Context(), services.AITokenUsage{Model: "gemini-2.0-flash", Operation: "chat", InputTokens: 40, OutputTokens: 2})
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/ai/chat", nil))
+
+	require.Eventually(t, func() bool { return len(eventRepo.GetEvents()) == 2 }, time.Second, 10*time.Millisecond)
+	var aiEvent *models.UsageEvent
+	for _, event := range eventRepo.GetEvents() {
+		if event.EventType == services.UsageTypeAITokens {
+			aiEvent = &event
+		}
+	}
+	require.NotNil(t, aiEvent, "AI token usage should be recorded alongside the API call")
+	assert.Equal(t, int64(42), aiEvent.Quantity)
+	assert.Equal(t, "gemini-2.0-flash", aiEvent.Resource)
+	assert.Equal(t, uintPtr(3), aiEvent.OrganizationID)
+}
+
+func TestUsageMiddleware_RecordsStorage(t *testing.T) {
+	eventRepo := mocks.NewMockUsageEventRepository()
+	usageService := services.NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+	defer usageService.Shutdown()
+
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := auth.SetUserContext(r.Context(), &models.User{ID: 7, Email: "user@example.com"})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	r := chi.NewRouter()
+	r.Use(UsageMiddleware(usageService))
+	r.Use(authenticate)
+	r.Post("/api/files/upload", func(w http.ResponseWriter, r *http.Request) {
+		services.AddStorageUsage(r.Context(), 2048)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/files/upload", nil))
+
+	require.Eventually(t, func() bool { return len(eventRepo.GetEvents()) == 2 }, time.Second, 10*time.Millisecond)
+	var storageEvent *models.UsageEvent
+	for _, event := range eventRepo.GetEvents() {
+		if event.EventType == services.UsageTypeStorage {
+			storageEvent = &event
+		}
+	}
+	require.NotNil(t, storageEvent, "stored bytes should be recorded alongside the API call")
+	assert.Equal(t, int64(2048), storageEvent.Quantity)
+	assert.Equal(t, "bytes", storageEvent.Unit)
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+// ============ MeterUsage Tests ============
+
+func TestMeterUsage_RecordsSuccessfulRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantEvents int
+	}{
+		{"created", http.StatusCreated, 1},
+		{"rejected", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventRepo := mocks.NewMockUsageEventRepository()
+			usageService := services.NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+
+			handler := MeterUsage(usageService, services.UsageTypeFileUpload)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/files/upload", nil)
+			req = req.WithContext(auth.SetUserContext(req.Context(), &models.User{ID: 7, Email: "user@example.com"}))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.wantEvents == 0 {
+				time.Sleep(50 * time.Millisecond)
+				assert.Empty(t, eventRepo.GetEvents())
+				return
+			}
+			require.Eventually(t, func() bool { return len(eventRepo.GetEvents()) == 1 }, time.Second, 10*time.Millisecond)
+			assert.Equal(t, services.UsageTypeFileUpload, eventRepo.GetEvents()[0].EventType)
+		})
+	}
+}
+
+func TestMeterUsage_SkipsUnauthenticated(t *testing.T) {
+	eventRepo := mocks.NewMockUsageEventRepository()
+	usageService := services.NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+
+	handler := MeterUsage(usageService, services.UsageTypeFileUpload)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/files/upload", nil))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, eventRepo.GetEvents())
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/lib/pq"
@@ -72,6 +73,10 @@ type User struct {
 	// Stripe customer ID for billing (pointer to allow NULL for users without Stripe accounts)
 	StripeCustomerID *string `json:"-" gorm:"uniqueIndex"`
 
+	// When the user started their free trial, personally or for an organization they own.
+	// Kept on the user because an organization's subscriptions are deleted along with it.
+	TrialUsedAt *time.Time `json:"-"`
+
 	// OAuth provider (if user signed up via OAuth)
 	// example: google
 	OAuthProvider string `json:"oauth_provider,omitempty" gorm:"column:oauth_provider;type:varchar(50);index"`
@@ -127,6 +132,26 @@ func (TokenBlacklist) TableName() string {
 	return "token_blacklist"
 }
 
+// OrgTokenRevocation invalidates org-scoped access tokens issued before RevokedAt.
+// Written when a member's role changes or they leave an organization, since
+// org claims in already-issued tokens would otherwise stay valid until expiry.
+type OrgTokenRevocation struct {
+	// The unique ID of the revocation entry
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// User whose org-scoped tokens are revoked
+	UserID uint `json:"user_id" gorm:"not null;uniqueIndex:idx_org_token_revocation"`
+
+	// Organization the revoked tokens were scoped to
+	OrganizationID uint `json:"organization_id" gorm:"not null;uniqueIndex:idx_org_token_revocation"`
+
+	// Tokens issued before this time are rejected
+	RevokedAt time.Time `json:"revoked_at" gorm:"not null;index"`
+
+	// Reason for revocation (role_change, member_removed, org_deleted)
+	Reason string `json:"reason" gorm:"type:varchar(50)"`
+}
+
 // UserResponse represents the user data returned to the frontend (without sensitive fields)
 // swagger:model UserResponse
 type UserResponse struct {
@@ -217,6 +242,30 @@ type AuthResponse struct {
 	// Access token expiration time in seconds
 	// example: 900
 	ExpiresIn int64 `json:"expires_in,omitempty" example:"900"`
+
+	// Active organization carried in the access token (omitted for personal context)
+	Organization *ActiveOrganization `json:"organization,omitempty"`
+}
+
+// ActiveOrganization describes the organization context embedded in an access token
+// swagger:model ActiveOrganization
+type ActiveOrganization struct {
+	// example: 1
+	ID uint `json:"id" example:"1"`
+
+	// example: acme
+	Slug string `json:"slug" example:"acme"`
+
+	// example: admin
+	Role OrganizationRole `json:"role" example:"admin"`
+}
+
+// SwitchOrgRequest selects the active organization for subsequent access tokens
+// swagger:model SwitchOrgRequest
+type SwitchOrgRequest struct {
+	// Slug of the organization to switch to; empty clears the organization context
+	// example: acme
+	OrgSlug string `json:"org_slug" example:"acme"`
 }
 
 // RefreshTokenRequest represents a request to refresh the access token
@@ -423,12 +472,16 @@ type File struct {
 	// example: https://bucket-name.s3.amazonaws.com/uploads/file.pdf
 	Location string `json:"location"`
 
-	// Actual file content (only used for database storage)
+	// Inline file content, no longer written: database storage keeps content in file_chunks
 	Content []byte `json:"-" gorm:"type:bytea"`
 
-	// Storage type (s3 or database)
+	// Storage type (s3, local or database)
 	// example: s3
 	StorageType string `json:"storage_type" gorm:"default:database"`
+
+	// SHA-256 checksum of the content, hex encoded; empty for files uploaded before checksums
+	// example: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+	Checksum string `json:"checksum,omitempty" gorm:"size:64"`
 }
 
 // FileResponse represents the file data returned to the frontend
@@ -441,6 +494,7 @@ type FileResponse struct {
 	FileSize    int64  `json:"file_size"`
 	Location    string `json:"location"`
 	StorageType string `json:"storage_type"`
+	Checksum    string `json:"checksum,omitempty"`
 	CreatedAt   string `json:"created_at"`
 	UpdatedAt   string `json:"updated_at"`
 }
@@ -455,11 +509,63 @@ func (f *File) ToFileResponse() FileResponse {
 		FileSize:    f.FileSize,
 		Location:    f.Location,
 		StorageType: f.StorageType,
+		Checksum:    f.Checksum,
 		CreatedAt:   f.CreatedAt,
 		UpdatedAt:   f.UpdatedAt,
 	}
 }
 
+// FileChunk is a piece of a database-stored file's content starting at Offset. Content is
+// split into rows so files are written and read without rewriting or decompressing the whole.
+type FileChunk struct {
+	FileID uint   `json:"file_id" gorm:"primaryKey;autoIncrement:false"`
+	Offset int64  `json:"offset" gorm:"column:chunk_offset;primaryKey;autoIncrement:false"`
+	Data   []byte `json:"-" gorm:"type:bytea;not null"`
+}
+
+// FileUpload is a resumable upload in progress (tus protocol). Its content is stored in
+// file_upload_chunks until the upload completes and is assembled into a File.
+type FileUpload struct {
+	ID        string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+
+	// The user uploading the file
+	UserID uint `json:"user_id" gorm:"index;not null"`
+
+	// File name and type from the Upload-Metadata header
+	FileName    string `json:"file_name" gorm:"not null"`
+	ContentType string `json:"content_type"`
+
+	// Upload-Length and Upload-Offset: total size and the bytes received so far
+	Length int64 `json:"length" gorm:"not null"`
+	Offset int64 `json:"offset" gorm:"column:upload_offset;not null;default:0"`
+
+	// Raw Upload-Metadata header, returned as received
+	Metadata string `json:"-" gorm:"type:text"`
+
+	// The assembled file once the upload completes
+	FileID *uint `json:"file_id,omitempty"`
+	// Set while a request assembles the completed upload
+	AssemblingAt *string `json:"-"`
+
+	// Abandoned uploads are removed after they expire
+	ExpiresAt string `json:"expires_at" gorm:"not null;index"`
+}
+
+// IsComplete reports whether all of the upload's content has been received
+func (u *FileUpload) IsComplete() bool {
+	return u.Offset >= u.Length
+}
+
+// FileUploadChunk is a piece of a resumable upload's content starting at Offset
+type FileUploadChunk struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	UploadID string `json:"upload_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_file_upload_chunks_offset"`
+	Offset   int64  `json:"offset" gorm:"column:chunk_offset;not null;uniqueIndex:idx_file_upload_chunks_offset"`
+	Data     []byte `json:"-" gorm:"type:bytea;not null"`
+}
+
 // Role constants
 const (
 	RoleSuperAdmin = "super_admin" // System administrators (full access)
@@ -526,22 +632,63 @@ type Subscription struct {
 
 	// When the subscription was canceled (if applicable)
 	CanceledAt string `json:"canceled_at,omitempty"`
+
+	// When the free trial ends (null if the subscription never had a trial)
+	TrialEndsAt *string `json:"trial_ends_at,omitempty"`
+
+	// Seats billed on the subscription item (organization subscriptions, 0 if unknown)
+	SeatQuantity int64 `json:"seat_quantity,omitempty" gorm:"default:0"`
+
+	// Seats the customer set in the billing portal; the member count sync never bills fewer
+	MinSeatQuantity int64 `json:"min_seat_quantity,omitempty" gorm:"default:0"`
+
+	// Discount currently applied to the subscription, mirrored from Stripe
+	Discount SubscriptionDiscount `json:"-" gorm:"embedded;embeddedPrefix:discount_"`
+}
+
+// SubscriptionDiscount describes a coupon or promotion code applied to a subscription
+// swagger:model SubscriptionDiscount
+type SubscriptionDiscount struct {
+	// Stripe coupon ID (empty when no discount is applied)
+	CouponID string `json:"coupon_id" gorm:"type:varchar(255)"`
+
+	// Code the customer redeemed (promotion code or internal coupon code)
+	Code string `json:"code,omitempty" gorm:"type:varchar(100)"`
+
+	// Display name of the coupon
+	Name string `json:"name,omitempty" gorm:"type:varchar(100)"`
+
+	// Percentage discount, or fixed amount off in the smallest currency unit
+	PercentOff float64 `json:"percent_off,omitempty"`
+	AmountOff  int64   `json:"amount_off,omitempty"`
+	Currency   string  `json:"currency,omitempty" gorm:"type:varchar(3)"`
+
+	// How long the discount applies (once, repeating, forever)
+	Duration string `json:"duration,omitempty" gorm:"type:varchar(20)"`
+
+	// When the discount stops applying (null for forever)
+	EndsAt *string `json:"ends_at,omitempty"`
 }
 
 // SubscriptionResponse represents subscription data returned to the frontend
 // swagger:model SubscriptionResponse
 type SubscriptionResponse struct {
-	ID                 uint   `json:"id"`
-	UserID             uint   `json:"user_id"`
-	OrganizationID     *uint  `json:"organization_id,omitempty"`
-	Status             string `json:"status"`
-	StripePriceID      string `json:"stripe_price_id"`
-	CurrentPeriodStart string `json:"current_period_start"`
-	CurrentPeriodEnd   string `json:"current_period_end"`
-	CancelAtPeriodEnd  bool   `json:"cancel_at_period_end"`
-	CanceledAt         string `json:"canceled_at,omitempty"`
-	CreatedAt          string `json:"created_at"`
-	UpdatedAt          string `json:"updated_at"`
+	ID                 uint                  `json:"id"`
+	UserID             uint                  `json:"user_id"`
+	OrganizationID     *uint                 `json:"organization_id,omitempty"`
+	Status             string                `json:"status"`
+	StripePriceID      string                `json:"stripe_price_id"`
+	CurrentPeriodStart string                `json:"current_period_start"`
+	CurrentPeriodEnd   string                `json:"current_period_end"`
+	CancelAtPeriodEnd  bool                  `json:"cancel_at_period_end"`
+	CanceledAt         string                `json:"canceled_at,omitempty"`
+	IsTrialing         bool                  `json:"is_trialing"`
+	TrialEndsAt        *string               `json:"trial_ends_at,omitempty"`
+	TrialDaysRemaining int                   `json:"trial_days_remaining,omitempty"`
+	SeatQuantity       int64                 `json:"seat_quantity,omitempty"`
+	Discount           *SubscriptionDiscount `json:"discount,omitempty"`
+	CreatedAt          string                `json:"created_at"`
+	UpdatedAt          string                `json:"updated_at"`
 }
 
 // ToSubscriptionResponse converts a Subscription to SubscriptionResponse
@@ -556,16 +703,56 @@ func (s *Subscription) ToSubscriptionResponse() SubscriptionResponse {
 		CurrentPeriodEnd:   s.CurrentPeriodEnd,
 		CancelAtPeriodEnd:  s.CancelAtPeriodEnd,
 		CanceledAt:         s.CanceledAt,
+		IsTrialing:         s.IsTrialing(),
+		TrialEndsAt:        s.TrialEndsAt,
+		TrialDaysRemaining: s.TrialDaysRemaining(time.Now()),
+		SeatQuantity:       s.SeatQuantity,
+		Discount:           s.ActiveDiscount(),
 		CreatedAt:          s.CreatedAt,
 		UpdatedAt:          s.UpdatedAt,
 	}
 }
 
+// ActiveDiscount returns the applied discount, or nil if the subscription has none
+func (s *Subscription) ActiveDiscount() *SubscriptionDiscount {
+	if s.Discount.CouponID == "" {
+		return nil
+	}
+	discount := s.Discount
+	return &discount
+}
+
 // IsActiveSubscription returns true if the subscription is in an active state
 func (s *Subscription) IsActiveSubscription() bool {
 	return s.Status == SubscriptionStatusActive || s.Status == SubscriptionStatusTrialing
 }
 
+// IsTrialing returns true if the subscription is in its free trial
+func (s *Subscription) IsTrialing() bool {
+	return s.Status == SubscriptionStatusTrialing
+}
+
+// TrialExpired returns true if a trialing subscription is past its trial end date
+func (s *Subscription) TrialExpired(now time.Time) bool {
+	if !s.IsTrialing() || s.TrialEndsAt == nil {
+		return false
+	}
+	trialEnd, err := time.Parse(time.RFC3339, *s.TrialEndsAt)
+	return err == nil && !now.Before(trialEnd)
+}
+
+// TrialDaysRemaining returns the whole days left in the trial, rounded up (0 when not trialing)
+func (s *Subscription) TrialDaysRemaining(now time.Time) int {
+	if !s.IsTrialing() || s.TrialEndsAt == nil {
+		return 0
+	}
+	trialEnd, err := time.Parse(time.RFC3339, *s.TrialEndsAt)
+	if err != nil || !now.Before(trialEnd) {
+		return 0
+	}
+	return int(math.Ceil(trialEnd.Sub(now).Hours() / 24))
+}
+
 // BillingPlan represents an available subscription plan
 // swagger:model BillingPlan
 type BillingPlan struct {
@@ -577,12 +764,129 @@ type BillingPlan struct {
 	Currency    string   `json:"currency"` // e.g., "usd"
 	Interval    string   `json:"interval"` // e.g., "month", "year"
 	Features    []string `json:"features"`
+
+	// Entitlements of the plan this price subscribes to, when it is in the plan catalog
+	Plan      string       `json:"plan,omitempty"`
+	Limits    *UsageLimits `json:"limits,omitempty"`
+	SeatLimit *int         `json:"seat_limit,omitempty"` // 0 means unlimited
 }
 
 // CreateCheckoutRequest represents a request to create a checkout session
 // swagger:model CreateCheckoutRequest
 type CreateCheckoutRequest struct {
 	PriceID string `json:"price_id" binding:"required"`
+
+	// Optional promotion or coupon code to apply at checkout
+	PromotionCode string `json:"promotion_code,omitempty"`
+}
+
+// ValidatePromotionCodeRequest is a request to check a promotion code before checkout
+// swagger:model ValidatePromotionCodeRequest
+type ValidatePromotionCodeRequest struct {
+	Code    string `json:"code" binding:"required"`
+	PriceID string `json:"price_id,omitempty"`
+}
+
+// PromotionCodePreview describes the discount a code would apply at checkout.
+// Amounts are only set when a price ID was given.
+// swagger:model PromotionCodePreview
+type PromotionCodePreview struct {
+	Code             string  `json:"code"`
+	Name             string  `json:"name,omitempty"`
+	PercentOff       float64 `json:"percent_off,omitempty"`
+	AmountOff        int64   `json:"amount_off,omitempty"`
+	Currency         string  `json:"currency,omitempty"`
+	Duration         string  `json:"duration"`
+	DurationInMonths int64   `json:"duration_in_months,omitempty"`
+	PriceID          string  `json:"price_id,omitempty"`
+	OriginalAmount   int64   `json:"original_amount,omitempty"`
+	DiscountAmount   int64   `json:"discount_amount,omitempty"`
+	FinalAmount      int64   `json:"final_amount,omitempty"`
+}
+
+// Coupon discount durations
+const (
+	CouponDurationOnce      = "once"
+	CouponDurationRepeating = "repeating"
+	CouponDurationForever   = "forever"
+)
+
+// Coupon is an admin-created discount, such as a partner deal, redeemable by code at checkout.
+// Each coupon is backed by a Stripe coupon that is attached to the checkout session.
+// swagger:model Coupon
+type Coupon struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Code customers enter at checkout, stored uppercase
+	Code string `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"`
+
+	// Display name shown on invoices
+	Name string `json:"name" gorm:"type:varchar(100)"`
+
+	// Backing Stripe coupon ID
+	StripeCouponID string `json:"stripe_coupon_id" gorm:"type:varchar(255);not null;index"`
+
+	// Percentage discount, or fixed amount off in the smallest currency unit
+	PercentOff float64 `json:"percent_off,omitempty"`
+	AmountOff  int64   `json:"amount_off,omitempty"`
+	Currency   string  `json:"currency,omitempty" gorm:"type:varchar(3)"`
+
+	// How long the discount applies (once, repeating, forever)
+	Duration         string `json:"duration" gorm:"type:varchar(20);not null"`
+	DurationInMonths int64  `json:"duration_in_months,omitempty"`
+
+	// Redemption limit (0 means unlimited) and redemptions so far
+	MaxRedemptions int64 `json:"max_redemptions,omitempty"`
+	TimesRedeemed  int64 `json:"times_redeemed" gorm:"default:0"`
+
+	// Last moment the code can be redeemed (null means no expiry)
+	ExpiresAt *string `json:"expires_at,omitempty"`
+
+	// Inactive coupons can no longer be redeemed
+	Active bool `json:"active" gorm:"default:true;index"`
+
+	CreatedByUserID *uint  `json:"created_by_user_id,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+// IsRedeemable reports whether the coupon can be applied to a new checkout at now
+func (c *Coupon) IsRedeemable(now time.Time) bool {
+	if !c.Active {
+		return false
+	}
+	if c.MaxRedemptions > 0 && c.TimesRedeemed >= c.MaxRedemptions {
+		return false
+	}
+	if c.ExpiresAt != nil {
+		expiresAt, err := time.Parse(time.RFC3339, *c.ExpiresAt)
+		if err == nil && !now.Before(expiresAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// CouponsResponse represents the list of internal coupons
+// swagger:model CouponsResponse
+type CouponsResponse struct {
+	Coupons []Coupon `json:"coupons"`
+	Count   int      `json:"count"`
+}
+
+// CreateCouponRequest is the admin request to create an internal coupon.
+// Exactly one of PercentOff or AmountOff (with Currency) must be set.
+// swagger:model CreateCouponRequest
+type CreateCouponRequest struct {
+	Code             string  `json:"code"`
+	Name             string  `json:"name"`
+	PercentOff       float64 `json:"percent_off,omitempty"`
+	AmountOff        int64   `json:"amount_off,omitempty"`
+	Currency         string  `json:"currency,omitempty"`
+	Duration         string  `json:"duration"`
+	DurationInMonths int64   `json:"duration_in_months,omitempty"`
+	MaxRedemptions   int64   `json:"max_redemptions,omitempty"`
+	ExpiresAt        *string `json:"expires_at,omitempty"`
 }
 
 // CheckoutSessionResponse represents the response from creating a checkout session
@@ -598,12 +902,212 @@ type PortalSessionResponse struct {
 	URL string `json:"url"`
 }
 
+// ChangeSubscriptionRequest represents a request to preview or apply a plan change
+// swagger:model ChangeSubscriptionRequest
+type ChangeSubscriptionRequest struct {
+	PriceID string `json:"price_id" binding:"required"`
+	// Unix timestamp returned by the preview; pass it back so the charge matches the preview
+	ProrationDate int64 `json:"proration_date,omitempty"`
+}
+
+// CancelSubscriptionRequest represents a request to cancel a subscription
+// swagger:model CancelSubscriptionRequest
+type CancelSubscriptionRequest struct {
+	// Cancel now instead of at the end of the current billing period
+	Immediately bool `json:"immediately"`
+}
+
+// InvoiceLinePreview is a single line of a previewed invoice
+// swagger:model InvoiceLinePreview
+type InvoiceLinePreview struct {
+	Description string `json:"description"`
+	Amount      int64  `json:"amount"` // Amount in cents, negative for credits
+	Proration   bool   `json:"proration"`
+	PeriodStart string `json:"period_start"`
+	PeriodEnd   string `json:"period_end"`
+}
+
+// SubscriptionChangePreview describes the prorated invoice for a plan change
+// swagger:model SubscriptionChangePreview
+type SubscriptionChangePreview struct {
+	CurrentPriceID  string               `json:"current_price_id"`
+	NewPriceID      string               `json:"new_price_id"`
+	Currency        string               `json:"currency"`
+	ProrationDate   int64                `json:"proration_date"`
+	ProrationAmount int64                `json:"proration_amount"` // Net of proration lines, in cents
+	Subtotal        int64                `json:"subtotal"`
+	Total           int64                `json:"total"`
+	AmountDue       int64                `json:"amount_due"`
+	NextPaymentAt   string               `json:"next_payment_at,omitempty"`
+	Lines           []InvoiceLinePreview `json:"lines"`
+}
+
 // BillingConfigResponse represents the public billing configuration
 // swagger:model BillingConfigResponse
 type BillingConfigResponse struct {
 	PublishableKey string `json:"publishable_key"`
 }
 
+// Stripe webhook event processing status constants
+const (
+	StripeEventStatusPending   = "pending"
+	StripeEventStatusRetrying  = "retrying" // Failed, the job queue will try again
+	StripeEventStatusProcessed = "processed"
+	StripeEventStatusFailed    = "failed"  // Failed on the last attempt, can be replayed
+	StripeEventStatusSkipped   = "skipped" // Superseded by a newer event for the same object
+)
+
+// StripeEvent is a verified Stripe webhook event persisted for deduplication and replay
+// swagger:model StripeEvent
+type StripeEvent struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Stripe event ID (evt_...), unique so redelivered events are ignored
+	EventID string `json:"event_id" gorm:"type:varchar(255);uniqueIndex;not null"`
+
+	// Stripe event type (e.g. customer.subscription.updated)
+	EventType string `json:"event_type" gorm:"type:varchar(100);not null;index"`
+
+	// ID of the Stripe object the event describes (sub_..., in_..., cs_...)
+	ObjectID string `json:"object_id,omitempty" gorm:"type:varchar(255);index:idx_stripe_events_object"`
+
+	// Unix timestamp at which Stripe created the event, used to detect out-of-order delivery
+	StripeCreatedAt int64 `json:"stripe_created_at" gorm:"not null;index:idx_stripe_events_object"`
+
+	// Raw event data object
+	Payload string `json:"-" gorm:"type:jsonb;not null"`
+
+	// Processing status (pending, retrying, processed, failed, skipped)
+	Status string `json:"status" gorm:"type:varchar(20);default:'pending';index"`
+
+	// Number of processing attempts
+	Attempts int `json:"attempts" gorm:"default:0"`
+
+	// Error from the most recent failed attempt
+	LastError *string `json:"last_error,omitempty" gorm:"type:text"`
+
+	// When the event was successfully processed or skipped
+	ProcessedAt *string `json:"processed_at,omitempty"`
+
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// StripeEventsResponse represents a paginated list of stored Stripe events
+// swagger:model StripeEventsResponse
+type StripeEventsResponse struct {
+	Events     []StripeEvent `json:"events"`
+	Count      int           `json:"count"`
+	Total      int           `json:"total"`
+	Page       int           `json:"page"`
+	Limit      int           `json:"limit"`
+	TotalPages int           `json:"total_pages"`
+}
+
+// Invoice status constants (mirrors Stripe invoice statuses)
+const (
+	InvoiceStatusDraft         = "draft"
+	InvoiceStatusOpen          = "open"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusVoid          = "void"
+	InvoiceStatusUncollectible = "uncollectible"
+)
+
+// Invoice is a local mirror of a Stripe invoice, kept current by invoice.* webhook events
+// swagger:model Invoice
+type Invoice struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+
+	// Billed subject (user or organization)
+	UserID         *uint `json:"user_id,omitempty" gorm:"index"`
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
+	// Stripe identifiers
+	StripeInvoiceID      string  `json:"stripe_invoice_id" gorm:"type:varchar(255);uniqueIndex;not null"`
+	StripeCustomerID     string  `json:"stripe_customer_id" gorm:"type:varchar(255);not null;index"`
+	StripeSubscriptionID *string `json:"stripe_subscription_id,omitempty" gorm:"type:varchar(255)"`
+
+	// Human-readable invoice number (empty for drafts)
+	Number string `json:"number,omitempty" gorm:"type:varchar(100)"`
+
+	// Invoice status (draft, open, paid, void, uncollectible)
+	Status string `json:"status" gorm:"type:varchar(20);not null;index"`
+
+	// Amounts in the smallest currency unit
+	Currency        string `json:"currency" gorm:"type:varchar(10)"`
+	Subtotal        int64  `json:"subtotal"`
+	Total           int64  `json:"total"`
+	AmountDue       int64  `json:"amount_due"`
+	AmountPaid      int64  `json:"amount_paid"`
+	AmountRemaining int64  `json:"amount_remaining"`
+
+	// Service period the invoice covers
+	PeriodStart string `json:"period_start"`
+	PeriodEnd   string `json:"period_end"`
+
+	// Stripe-hosted invoice page and PDF download links
+	HostedInvoiceURL string `json:"hosted_invoice_url,omitempty" gorm:"type:text"`
+	InvoicePDF       string `json:"invoice_pdf,omitempty" gorm:"type:text"`
+
+	DueDate *string `json:"due_date,omitempty"`
+	PaidAt  *string `json:"paid_at,omitempty"`
+
+	// When Stripe created the invoice, used for ordering
+	IssuedAt string `json:"issued_at" gorm:"index"`
+}
+
+// InvoicesResponse represents a paginated list of invoices
+// swagger:model InvoicesResponse
+type InvoicesResponse struct {
+	Invoices   []Invoice `json:"invoices"`
+	Count      int       `json:"count"`
+	Total      int       `json:"total"`
+	Page       int       `json:"page"`
+	Limit      int       `json:"limit"`
+	TotalPages int       `json:"total_pages"`
+}
+
+// Dunning status constants
+const (
+	DunningStatusActive   = "active"
+	DunningStatusResolved = "resolved" // Payment recovered during the grace period
+	DunningStatusExpired  = "expired"  // Grace period ended and the plan was downgraded
+)
+
+// Dunning tracks a failed subscription payment through its grace period and reminders
+// swagger:model Dunning
+type Dunning struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+
+	// Local subscription and its billed subject
+	SubscriptionID uint  `json:"subscription_id" gorm:"not null;index"`
+	UserID         uint  `json:"user_id" gorm:"not null;index"`
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
+	// Stripe subscription and the most recent unpaid invoice
+	StripeSubscriptionID string `json:"stripe_subscription_id" gorm:"type:varchar(255);not null;index"`
+	StripeInvoiceID      string `json:"stripe_invoice_id" gorm:"type:varchar(255)"`
+	AmountDue            int64  `json:"amount_due"`
+	Currency             string `json:"currency" gorm:"type:varchar(10)"`
+
+	// Dunning status (active, resolved, expired)
+	Status string `json:"status" gorm:"type:varchar(20);not null;index"`
+
+	// When the grace period ends and the plan is downgraded
+	GraceEndsAt string `json:"grace_ends_at" gorm:"not null;index"`
+
+	// Reminder escalation state
+	RemindersSent  int     `json:"reminders_sent" gorm:"default:0"`
+	NextReminderAt *string `json:"next_reminder_at,omitempty" gorm:"index"`
+
+	// When the case was resolved or expired
+	ResolvedAt *string `json:"resolved_at,omitempty"`
+}
+
 // ============ OAuth Models ============
 
 // OAuth provider constants
@@ -712,15 +1216,25 @@ type OAuthConfig struct {
 
 // AuditAction constants
 const (
-	AuditActionCreate          = "create"
-	AuditActionUpdate          = "update"
-	AuditActionDelete          = "delete"
-	AuditActionLogin           = "login"
-	AuditActionLogout          = "logout"
-	AuditActionImpersonate     = "impersonate"
-	AuditActionStopImpersonate = "stop_impersonate"
-	AuditActionPasswordReset   = "password_reset"
-	AuditActionRoleChange      = "role_change"
+	AuditActionCreate           = "create"
+	AuditActionUpdate           = "update"
+	AuditActionDelete           = "delete"
+	AuditActionLogin            = "login"
+	AuditActionLogout           = "logout"
+	AuditActionImpersonate      = "impersonate"
+	AuditActionStopImpersonate  = "stop_impersonate"
+	AuditActionPasswordReset    = "password_reset"
+	AuditActionRoleChange       = "role_change"
+	AuditActionInvite           = "invite"
+	AuditActionInviteAccept     = "invite_accept"
+	AuditActionInviteRevoke     = "invite_revoke"
+	AuditActionMemberRemove     = "member_remove"
+	AuditActionMemberSuspend    = "member_suspend"
+	AuditActionMemberReactivate = "member_reactivate"
+	AuditActionPlanChange       = "plan_change"
+	AuditActionBillingUpdate    = "billing_update"
+	AuditActionDeleteSchedule   = "delete_schedule"
+	AuditActionRestore          = "restore"
 )
 
 // AuditTargetType constants
@@ -730,6 +1244,9 @@ const (
 	AuditTargetFile         = "file"
 	AuditTargetSettings     = "settings"
 	AuditTargetFeatureFlag  = "feature_flag"
+	AuditTargetOrganization = "organization"
+	AuditTargetOrgMember    = "organization_member"
+	AuditTargetInvitation   = "organization_invitation"
 )
 
 // AuditLog represents an audit log entry
@@ -744,6 +1261,9 @@ type AuditLog struct {
 	// User who performed the action (populated via join)
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 
+	// Organization the action belongs to (null for non-tenant actions)
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
 	// Type of resource affected
 	TargetType string `json:"target_type" gorm:"type:varchar(50);not null;index"`
 
@@ -772,31 +1292,33 @@ type AuditLog struct {
 // AuditLogResponse represents audit log data returned to the frontend
 // swagger:model AuditLogResponse
 type AuditLogResponse struct {
-	ID         uint        `json:"id"`
-	UserID     *uint       `json:"user_id,omitempty"`
-	UserName   string      `json:"user_name,omitempty"`
-	UserEmail  string      `json:"user_email,omitempty"`
-	TargetType string      `json:"target_type"`
-	TargetID   *uint       `json:"target_id,omitempty"`
-	Action     string      `json:"action"`
-	Changes    interface{} `json:"changes,omitempty"`
-	IPAddress  string      `json:"ip_address,omitempty"`
-	UserAgent  string      `json:"user_agent,omitempty"`
-	Metadata   interface{} `json:"metadata,omitempty"`
-	CreatedAt  string      `json:"created_at"`
+	ID             uint        `json:"id"`
+	UserID         *uint       `json:"user_id,omitempty"`
+	UserName       string      `json:"user_name,omitempty"`
+	UserEmail      string      `json:"user_email,omitempty"`
+	OrganizationID *uint       `json:"organization_id,omitempty"`
+	TargetType     string      `json:"target_type"`
+	TargetID       *uint       `json:"target_id,omitempty"`
+	Action         string      `json:"action"`
+	Changes        interface{} `json:"changes,omitempty"`
+	IPAddress      string      `json:"ip_address,omitempty"`
+	UserAgent      string      `json:"user_agent,omitempty"`
+	Metadata       interface{} `json:"metadata,omitempty"`
+	CreatedAt      string      `json:"created_at"`
 }
 
 // ToAuditLogResponse converts an AuditLog to AuditLogResponse
 func (a *AuditLog) ToAuditLogResponse() AuditLogResponse {
 	resp := AuditLogResponse{
-		ID:         a.ID,
-		UserID:     a.UserID,
-		TargetType: a.TargetType,
-		TargetID:   a.TargetID,
-		Action:     a.Action,
-		IPAddress:  a.IPAddress,
-		UserAgent:  a.UserAgent,
-		CreatedAt:  a.CreatedAt,
+		ID:             a.ID,
+		UserID:         a.UserID,
+		OrganizationID: a.OrganizationID,
+		TargetType:     a.TargetType,
+		TargetID:       a.TargetID,
+		Action:         a.Action,
+		IPAddress:      a.IPAddress,
+		UserAgent:      a.UserAgent,
+		CreatedAt:      a.CreatedAt,
 	}
 	if a.User != nil {
 		resp.UserName = a.User.Name
@@ -816,16 +1338,27 @@ type AuditLogsResponse struct {
 	TotalPages int                `json:"total_pages"`
 }
 
+// OrgAuditLogsResponse represents a cursor-paginated list of organization audit logs
+// swagger:model OrgAuditLogsResponse
+type OrgAuditLogsResponse struct {
+	Logs       []AuditLogResponse `json:"logs"`
+	Count      int                `json:"count"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	PrevCursor string             `json:"prev_cursor,omitempty"`
+	HasMore    bool               `json:"has_more"`
+}
+
 // AuditLogFilter represents filter options for audit log queries
 type AuditLogFilter struct {
-	UserID     *uint  `form:"user_id"`
-	TargetType string `form:"target_type"`
-	TargetID   *uint  `form:"target_id"`
-	Action     string `form:"action"`
-	StartDate  string `form:"start_date"`
-	EndDate    string `form:"end_date"`
-	Page       int    `form:"page"`
-	Limit      int    `form:"limit"`
+	UserID         *uint  `form:"user_id"`
+	OrganizationID *uint  `form:"organization_id"`
+	TargetType     string `form:"target_type"`
+	TargetID       *uint  `form:"target_id"`
+	Action         string `form:"action"`
+	StartDate      string `form:"start_date"`
+	EndDate        string `form:"end_date"`
+	Page           int    `form:"page"`
+	Limit          int    `form:"limit"`
 }
 
 // ============ Feature Flag Models ============
@@ -906,6 +1439,39 @@ type UserFeatureFlag struct {
 	UpdatedAt string `json:"updated_at"`
 }
 
+// OrganizationFeatureFlag represents an organization-wide feature flag override
+// swagger:model OrganizationFeatureFlag
+type OrganizationFeatureFlag struct {
+	// The unique ID
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Organization ID
+	OrganizationID uint `json:"organization_id" gorm:"not null;index;uniqueIndex:idx_organization_feature_flags_unique"`
+
+	// Feature flag ID
+	FeatureFlagID uint `json:"feature_flag_id" gorm:"not null;index;uniqueIndex:idx_organization_feature_flags_unique"`
+
+	// Override value for every member of the organization
+	Enabled bool `json:"enabled" gorm:"not null"`
+
+	// When the override was created
+	CreatedAt string `json:"created_at"`
+
+	// When the override was last updated
+	UpdatedAt string `json:"updated_at"`
+}
+
+// OrganizationFeatureFlagOverride represents an org override in admin API responses
+// swagger:model OrganizationFeatureFlagOverride
+type OrganizationFeatureFlagOverride struct {
+	// Feature flag key
+	Key string `json:"key"`
+	// Override value
+	Enabled bool `json:"enabled"`
+	// When the override was last updated
+	UpdatedAt string `json:"updated_at"`
+}
+
 // CreateFeatureFlagRequest represents a request to create a feature flag
 // swagger:model CreateFeatureFlagRequest
 type CreateFeatureFlagRequest struct {
@@ -943,8 +1509,12 @@ type UserFeatureFlagDetail struct {
 	Enabled bool `json:"enabled"`
 	// Whether the flag is disabled due to plan restrictions
 	GatedByPlan bool `json:"gated_by_plan"`
-	// The plan required to unlock this feature (if gated)
+	// The plan required to unlock this feature (if gated, or if only unlocked by a trial)
 	RequiredPlan string `json:"required_plan,omitempty"`
+	// Whether the flag is enabled only through a plan that is still in its free trial
+	Trial bool `json:"trial,omitempty"`
+	// When that free trial ends
+	TrialEndsAt *string `json:"trial_ends_at,omitempty"`
 }
 
 // ============ Admin Models ============
@@ -1163,6 +1733,48 @@ type UsagePeriod struct {
 
 	// When limits were last checked/updated
 	LastAggregatedAt *string `json:"last_aggregated_at,omitempty"`
+
+	// Storage integrated over time for metered billing, sampled by the usage reporter
+	StorageByteHours int64   `json:"storage_byte_hours" gorm:"default:0"`
+	StorageSampledAt *string `json:"storage_sampled_at,omitempty"`
+}
+
+// UsageRollup is usage aggregated per time bucket, owner, event type and resource.
+// Rollups are rebuilt from the raw usage events by the usage rollup job.
+type UsageRollup struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	BucketStart string `json:"bucket_start" gorm:"type:timestamptz;not null;index"`
+
+	// Who generated this usage
+	UserID         *uint `json:"user_id,omitempty" gorm:"index"`
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
+	EventType string `json:"event_type" gorm:"type:varchar(50);not null"`
+	Resource  string `json:"resource" gorm:"type:varchar(255);not null"`
+
+	// Summed quantity and number of raw events in the bucket
+	Quantity   int64 `json:"quantity" gorm:"not null;default:0"`
+	EventCount int64 `json:"event_count" gorm:"not null;default:0"`
+}
+
+// UsageHourlyRollup is usage aggregated per hour
+type UsageHourlyRollup struct {
+	UsageRollup
+}
+
+// TableName specifies the table name for GORM
+func (UsageHourlyRollup) TableName() string {
+	return "usage_rollups_hourly"
+}
+
+// UsageDailyRollup is usage aggregated per day (UTC), kept after raw events expire
+type UsageDailyRollup struct {
+	UsageRollup
+}
+
+// TableName specifies the table name for GORM
+func (UsageDailyRollup) TableName() string {
+	return "usage_rollups_daily"
 }
 
 // UsageAlert represents a notification when approaching or exceeding limits
@@ -1197,20 +1809,172 @@ type UsageAlert struct {
 	PeriodEnd   string `json:"period_end" gorm:"type:date;not null"`
 }
 
+// UsageAlertPreference controls how usage alerts for a user or an organization are
+// delivered besides the in-app notification. Owners without a row get the defaults
+// from DefaultUsageAlertPreference. Webhooks are only delivered for organizations.
+type UsageAlertPreference struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+
+	// Who the preferences belong to (exactly one is set)
+	UserID         *uint `json:"user_id,omitempty" gorm:"uniqueIndex"`
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"uniqueIndex"`
+
+	// Email delivery and the thresholds that trigger a notification
+	EmailEnabled bool `json:"email_enabled" gorm:"not null"`
+	Threshold80  bool `json:"threshold_80" gorm:"column:threshold_80;not null"`
+	Threshold90  bool `json:"threshold_90" gorm:"column:threshold_90;not null"`
+	Threshold100 bool `json:"threshold_100" gorm:"column:threshold_100;not null"`
+
+	// Webhook endpoint; payloads are signed with WebhookSecret (HMAC-SHA256)
+	WebhookEnabled bool   `json:"webhook_enabled" gorm:"not null"`
+	WebhookURL     string `json:"webhook_url,omitempty" gorm:"type:varchar(2048)"`
+	WebhookSecret  string `json:"-" gorm:"type:varchar(255)"`
+}
+
+// DefaultUsageAlertPreference returns the preferences used when none are stored:
+// email for every threshold and no webhook
+func DefaultUsageAlertPreference() UsageAlertPreference {
+	return UsageAlertPreference{
+		EmailEnabled: true,
+		Threshold80:  true,
+		Threshold90:  true,
+		Threshold100: true,
+	}
+}
+
+// NotifiesAt reports whether alerts at the given threshold (80, 90 or 100) are delivered
+func (p *UsageAlertPreference) NotifiesAt(threshold int) bool {
+	switch threshold {
+	case 80:
+		return p.Threshold80
+	case 90:
+		return p.Threshold90
+	case 100:
+		return p.Threshold100
+	default:
+		return false
+	}
+}
+
+// UsageAlertPreferencesResponse represents usage alert preferences returned to the frontend.
+// WebhookSecret is only set when a secret was generated by the request.
+// swagger:model UsageAlertPreferencesResponse
+type UsageAlertPreferencesResponse struct {
+	EmailEnabled   bool   `json:"email_enabled"`
+	Threshold80    bool   `json:"threshold_80"`
+	Threshold90    bool   `json:"threshold_90"`
+	Threshold100   bool   `json:"threshold_100"`
+	WebhookEnabled bool   `json:"webhook_enabled"`
+	WebhookURL     string `json:"webhook_url,omitempty"`
+	WebhookSecret  string `json:"webhook_secret,omitempty"`
+}
+
+// ToResponse converts UsageAlertPreference to UsageAlertPreferencesResponse
+func (p *UsageAlertPreference) ToResponse() UsageAlertPreferencesResponse {
+	return UsageAlertPreferencesResponse{
+		EmailEnabled:   p.EmailEnabled,
+		Threshold80:    p.Threshold80,
+		Threshold90:    p.Threshold90,
+		Threshold100:   p.Threshold100,
+		WebhookEnabled: p.WebhookEnabled,
+		WebhookURL:     p.WebhookURL,
+	}
+}
+
+// UpdateUsageAlertPreferencesRequest represents a request to update usage alert preferences.
+// Omitted fields keep their current value. Webhook fields are only accepted for organizations.
+// swagger:model UpdateUsageAlertPreferencesRequest
+type UpdateUsageAlertPreferencesRequest struct {
+	EmailEnabled        *bool   `json:"email_enabled,omitempty"`
+	Threshold80         *bool   `json:"threshold_80,omitempty"`
+	Threshold90         *bool   `json:"threshold_90,omitempty"`
+	Threshold100        *bool   `json:"threshold_100,omitempty"`
+	WebhookEnabled      *bool   `json:"webhook_enabled,omitempty"`
+	WebhookURL          *string `json:"webhook_url,omitempty"`
+	RotateWebhookSecret bool    `json:"rotate_webhook_secret,omitempty"`
+}
+
+// UsageAlertWebhookPayload is the JSON body POSTed to an organization's usage alert webhook
+type UsageAlertWebhookPayload struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	CreatedAt      string `json:"created_at"`
+	OrganizationID uint   `json:"organization_id"`
+	AlertID        uint   `json:"alert_id"`
+	AlertType      string `json:"alert_type"`
+	Threshold      int    `json:"threshold"`
+	UsageType      string `json:"usage_type"`
+	CurrentUsage   int64  `json:"current_usage"`
+	UsageLimit     int64  `json:"usage_limit"`
+	PercentageUsed int    `json:"percentage_used"`
+	PeriodStart    string `json:"period_start"`
+	PeriodEnd      string `json:"period_end"`
+}
+
+// Usage report status constants
+const (
+	UsageReportStatusReported = "reported"
+	UsageReportStatusFailed   = "failed"
+)
+
+// UsageReport records a metered usage quantity sent to Stripe.
+// Rows form the reconciliation ledger: summing reported quantities for a subject,
+// metric and period gives exactly what Stripe was told for that period.
+type UsageReport struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+
+	// Billed subject (user or organization)
+	UserID         *uint `json:"user_id,omitempty" gorm:"index"`
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
+	// Stripe customer the usage was reported for
+	StripeCustomerID string `json:"stripe_customer_id" gorm:"type:varchar(255);not null"`
+
+	// Metered metric (api_calls, storage_gb_hours, ai_tokens) and its Stripe meter event name
+	Metric         string `json:"metric" gorm:"type:varchar(50);not null;index"`
+	MeterEventName string `json:"meter_event_name" gorm:"type:varchar(100);not null"`
+
+	// Billing period the usage belongs to
+	PeriodStart string `json:"period_start" gorm:"type:date;not null;index"`
+	PeriodEnd   string `json:"period_end" gorm:"type:date;not null"`
+
+	// Quantity sent in this report and the running total reported for the period
+	Quantity           int64 `json:"quantity"`
+	CumulativeQuantity int64 `json:"cumulative_quantity"`
+
+	// Raw measurement the report was derived from (count, tokens or byte-hours) and when it was taken
+	MeasuredValue int64  `json:"measured_value"`
+	MeasuredAt    string `json:"measured_at"`
+
+	// Idempotency key / meter event identifier sent to Stripe
+	Identifier string `json:"identifier" gorm:"type:varchar(255);uniqueIndex;not null"`
+
+	// Report status (reported, failed) and failure details
+	Status     string  `json:"status" gorm:"type:varchar(20);not null;index"`
+	Error      *string `json:"error,omitempty" gorm:"type:text"`
+	ReportedAt *string `json:"reported_at,omitempty"`
+}
+
 // UsageTotals represents the aggregated usage counts
 type UsageTotals struct {
 	APICalls     int64 `json:"api_calls"`
 	StorageBytes int64 `json:"storage_bytes"`
 	ComputeMS    int64 `json:"compute_ms"`
 	FileUploads  int64 `json:"file_uploads"`
+	AITokens     int64 `json:"ai_tokens"`
 }
 
-// UsageLimits represents the limits for a billing period
+// UsageLimits represents the limits for a billing period (0 means unlimited)
 type UsageLimits struct {
-	APICalls     int64 `json:"api_calls"`
-	StorageBytes int64 `json:"storage_bytes"`
-	ComputeMS    int64 `json:"compute_ms"`
-	FileUploads  int64 `json:"file_uploads"`
+	APICalls     int64 `json:"api_calls" yaml:"api_calls"`
+	StorageBytes int64 `json:"storage_bytes" yaml:"storage_bytes"`
+	ComputeMS    int64 `json:"compute_ms" yaml:"compute_ms"`
+	FileUploads  int64 `json:"file_uploads" yaml:"file_uploads"`
+	AITokens     int64 `json:"ai_tokens" yaml:"ai_tokens"`
 }
 
 // UsagePercentages represents percentage of limits used
@@ -1219,6 +1983,7 @@ type UsagePercentages struct {
 	StorageBytes int `json:"storage_bytes"`
 	ComputeMS    int `json:"compute_ms"`
 	FileUploads  int `json:"file_uploads"`
+	AITokens     int `json:"ai_tokens"`
 }
 
 // UsageSummaryResponse represents usage summary returned to frontend
@@ -1232,6 +1997,236 @@ type UsageSummaryResponse struct {
 	Percentages    UsagePercentages `json:"percentages"`
 }
 
+// AIModelUsage is the AI token usage of one model in a billing period
+type AIModelUsage struct {
+	Model            string  `json:"model"`
+	Requests         int64   `json:"requests"`
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+}
+
+// AIUsageResponse represents AI token usage by model for the current billing period.
+// TokenLimit is the effective monthly limit: the plan limit, lowered by the organization's
+// budget when one is set (0 means unlimited).
+// swagger:model AIUsageResponse
+type AIUsageResponse struct {
+	PeriodStart      string         `json:"period_start"`
+	PeriodEnd        string         `json:"period_end"`
+	Models           []AIModelUsage `json:"models"`
+	TotalTokens      int64          `json:"total_tokens"`
+	EstimatedCostUSD float64        `json:"estimated_cost_usd"`
+	TokenLimit       int64          `json:"token_limit"`
+	Budget           int64          `json:"budget,omitempty"`
+}
+
+// UpdateAIBudgetRequest sets an organization's monthly AI token budget (0 removes it)
+// swagger:model UpdateAIBudgetRequest
+type UpdateAIBudgetRequest struct {
+	MonthlyTokenBudget int64 `json:"monthly_token_budget"`
+}
+
+// UsageHistoryPoint is the usage of one group in one time bucket
+type UsageHistoryPoint struct {
+	BucketStart string `json:"bucket_start"`
+	Group       string `json:"group"`
+	Quantity    int64  `json:"quantity"`
+	EventCount  int64  `json:"event_count"`
+}
+
+// UsageHistorySeriesResponse is usage history built from the usage rollups
+// swagger:model UsageHistorySeriesResponse
+type UsageHistorySeriesResponse struct {
+	Granularity string              `json:"granularity" example:"day"`
+	GroupBy     string              `json:"group_by" example:"event_type"`
+	From        string              `json:"from"`
+	To          string              `json:"to"`
+	Points      []UsageHistoryPoint `json:"points"`
+}
+
+// ============ Entitlement Models ============
+
+// PlanEntitlement is a plan catalog entry stored in the database. Rows override the
+// built-in and YAML plan definitions with the same plan key.
+// swagger:model PlanEntitlement
+type PlanEntitlement struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+
+	// Plan key (free, pro, enterprise or a custom plan) and display name
+	Plan string `json:"plan" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Name string `json:"name" gorm:"type:varchar(100)"`
+
+	// Position in the plan hierarchy; higher ranks include lower-ranked plans
+	Rank int `json:"rank" gorm:"default:0"`
+
+	// Stripe price IDs that subscribe to this plan
+	PriceIDs pq.StringArray `json:"price_ids" gorm:"type:text[]"`
+
+	// Seat count and usage limits (0 means unlimited)
+	Seats        int   `json:"seats" gorm:"default:0"`
+	APICalls     int64 `json:"api_calls" gorm:"default:0"`
+	StorageBytes int64 `json:"storage_bytes" gorm:"default:0"`
+	ComputeMS    int64 `json:"compute_ms" gorm:"default:0"`
+	FileUploads  int64 `json:"file_uploads" gorm:"default:0"`
+	AITokens     int64 `json:"ai_tokens" gorm:"default:0"`
+
+	// Boolean features included in the plan
+	Features pq.StringArray `json:"features" gorm:"type:text[]"`
+
+	// What happens when a usage limit is reached (hard, soft, overage; empty keeps the file default)
+	QuotaMode        string `json:"quota_mode,omitempty" gorm:"type:varchar(20)"`
+	SoftLimitPercent int    `json:"soft_limit_percent,omitempty" gorm:"default:0"`
+}
+
+// Quota enforcement modes
+const (
+	// QuotaModeHard rejects requests once a limit is reached
+	QuotaModeHard = "hard"
+	// QuotaModeSoft allows usage up to SoftLimitPercent of the limit with a warning, then rejects
+	QuotaModeSoft = "soft"
+	// QuotaModeOverage never rejects; usage beyond the limit is billed as overage
+	QuotaModeOverage = "overage"
+)
+
+// QuotaPolicy is how a plan enforces its usage limits
+type QuotaPolicy struct {
+	Mode             string `json:"mode" yaml:"mode"`
+	SoftLimitPercent int    `json:"soft_limit_percent,omitempty" yaml:"soft_limit_percent"`
+}
+
+// Ceiling returns the usage at which requests are rejected for a limit, or 0 if
+// they never are (unlimited, or overage billing)
+func (p QuotaPolicy) Ceiling(limit int64) int64 {
+	if limit <= 0 {
+		return 0
+	}
+	switch p.Mode {
+	case QuotaModeOverage:
+		return 0
+	case QuotaModeSoft:
+		percent := p.SoftLimitPercent
+		if percent < 100 {
+			percent = 100
+		}
+		return limit * int64(percent) / 100
+	default:
+		return limit
+	}
+}
+
+// QuotaExemption lifts usage quota enforcement for a user or organization.
+// Usage is still recorded; only rejections are skipped.
+// swagger:model QuotaExemption
+type QuotaExemption struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CreatedAt string `json:"created_at"`
+
+	// Exempt subject; exactly one is set
+	UserID         *uint `json:"user_id,omitempty" gorm:"index"`
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
+	// Why the exemption was granted and when it lapses (nil never expires)
+	Reason    string  `json:"reason" gorm:"type:text"`
+	ExpiresAt *string `json:"expires_at,omitempty"`
+
+	// Admin who granted the exemption
+	CreatedByUserID *uint `json:"created_by_user_id,omitempty"`
+}
+
+// IsActive reports whether the exemption applies at now
+func (e *QuotaExemption) IsActive(now time.Time) bool {
+	if e.ExpiresAt == nil || *e.ExpiresAt == "" {
+		return true
+	}
+	expiresAt, err := time.Parse(time.RFC3339, *e.ExpiresAt)
+	if err != nil {
+		return false
+	}
+	return now.Before(expiresAt)
+}
+
+// CreateQuotaExemptionRequest grants a quota exemption
+// swagger:model CreateQuotaExemptionRequest
+type CreateQuotaExemptionRequest struct {
+	UserID         *uint   `json:"user_id,omitempty"`
+	OrganizationID *uint   `json:"organization_id,omitempty"`
+	Reason         string  `json:"reason"`
+	ExpiresAt      *string `json:"expires_at,omitempty"`
+}
+
+// QuotaExemptionsResponse lists quota exemptions
+// swagger:model QuotaExemptionsResponse
+type QuotaExemptionsResponse struct {
+	Exemptions []QuotaExemption `json:"exemptions"`
+	Count      int              `json:"count"`
+}
+
+// UsageCost weights metered usage. A row with a route pattern sets the cost of requests to
+// that chi route; a row without one sets the cost of every event of its type. Rows
+// override the usage_costs section of config.yaml.
+// swagger:model UsageCost
+type UsageCost struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+
+	// Chi route pattern (e.g. /api/ai/chat) and HTTP method; empty matches everything
+	RoutePattern string `json:"route_pattern" gorm:"type:varchar(255);not null;default:'';uniqueIndex:idx_usage_costs_rule"`
+	Method       string `json:"method" gorm:"type:varchar(10);not null;default:'';uniqueIndex:idx_usage_costs_rule"`
+
+	// Usage type the cost applies to
+	EventType string `json:"event_type" gorm:"type:varchar(50);not null;default:'api_call';uniqueIndex:idx_usage_costs_rule"`
+
+	// Units charged against the plan limit (0 means the usage is not metered)
+	Cost        int64  `json:"cost" gorm:"not null;default:1"`
+	Description string `json:"description,omitempty" gorm:"type:text"`
+}
+
+// QuotaExceededResponse is returned with 402 or 429 when a usage quota blocks a request
+// swagger:model QuotaExceededResponse
+type QuotaExceededResponse struct {
+	Error     string `json:"error" example:"QUOTA_EXCEEDED"`
+	Message   string `json:"message"`
+	Code      int    `json:"code" example:"402"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Usage type that is exhausted (api_call, file_upload, ...)
+	UsageType string `json:"usage_type"`
+	Limit     int64  `json:"limit"`
+	Current   int64  `json:"current"`
+
+	// When the usage period resets (RFC3339)
+	ResetAt string `json:"reset_at"`
+
+	Plan      string `json:"plan"`
+	QuotaMode string `json:"quota_mode"`
+}
+
+// Entitlements is what a user or organization may use under its current plan
+// swagger:model Entitlements
+type Entitlements struct {
+	Plan        string      `json:"plan"`
+	Trial       bool        `json:"trial"`
+	TrialEndsAt *string     `json:"trial_ends_at,omitempty"`
+	Limits      UsageLimits `json:"limits"`
+	Seats       int         `json:"seats"` // 0 means unlimited
+	Features    []string    `json:"features"`
+	Quota       QuotaPolicy `json:"quota"`
+}
+
+// HasFeature reports whether the plan includes a boolean feature
+func (e *Entitlements) HasFeature(feature string) bool {
+	for _, f := range e.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
 // UsageEventRequest represents a request to record usage
 // swagger:model UsageEventRequest
 type UsageEventRequest struct {
@@ -160,6 +160,29 @@ func TestFile_ToFileResponse(t *testing.T) {
 	}
 }
 
+func TestFileUpload_IsComplete(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int64
+		offset int64
+		want   bool
+	}{
+		{"empty upload", 0, 0, true},
+		{"nothing received", 100, 0, false},
+		{"partially received", 100, 60, false},
+		{"fully received", 100, 100, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upload := &FileUpload{Length: tt.length, Offset: tt.offset}
+			if got := upload.IsComplete(); got != tt.want {
+				t.Errorf("IsComplete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // ============ Role Constants Tests ============
 
 func TestRoleConstants(t *testing.T) {
@@ -537,6 +560,88 @@ func TestSubscription_IsActiveSubscription(t *testing.T) {
 	}
 }
 
+func TestSubscription_TrialState(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	trialEnd := now.Add(36 * time.Hour).Format(time.RFC3339)
+	pastEnd := now.Add(-time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name        string
+		status      string
+		trialEndsAt *string
+		wantDays    int
+		wantExpired bool
+	}{
+		{"trialing", SubscriptionStatusTrialing, &trialEnd, 2, false},
+		{"trial past end", SubscriptionStatusTrialing, &pastEnd, 0, true},
+		{"converted to active", SubscriptionStatusActive, &pastEnd, 0, false},
+		{"trialing without end date", SubscriptionStatusTrialing, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &Subscription{Status: tt.status, TrialEndsAt: tt.trialEndsAt}
+			if got := sub.TrialDaysRemaining(now); got != tt.wantDays {
+				t.Errorf("TrialDaysRemaining() = %d, want %d", got, tt.wantDays)
+			}
+			if got := sub.TrialExpired(now); got != tt.wantExpired {
+				t.Errorf("TrialExpired() = %v, want %v", got, tt.wantExpired)
+			}
+		})
+	}
+
+	response := (&Subscription{Status: SubscriptionStatusTrialing, TrialEndsAt: &trialEnd}).ToSubscriptionResponse()
+	if !response.IsTrialing || response.TrialEndsAt == nil || *response.TrialEndsAt != trialEnd {
+		t.Errorf("ToSubscriptionResponse() trial state = %+v", response)
+	}
+}
+
+func TestSubscription_ActiveDiscount(t *testing.T) {
+	if got := (&Subscription{}).ToSubscriptionResponse().Discount; got != nil {
+		t.Errorf("Discount = %+v, want nil without a coupon", got)
+	}
+
+	sub := &Subscription{Discount: SubscriptionDiscount{CouponID: "co_partner", Code: "PARTNER20", PercentOff: 20}}
+	got := sub.ToSubscriptionResponse().Discount
+	if got == nil || got.Code != "PARTNER20" || got.PercentOff != 20 {
+		t.Errorf("Discount = %+v, want the applied coupon", got)
+	}
+
+	got.Code = "CHANGED"
+	if sub.Discount.Code != "PARTNER20" {
+		t.Error("ActiveDiscount() should return a copy")
+	}
+}
+
+// ============ Coupon Tests ============
+
+func TestCoupon_IsRedeemable(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour).Format(time.RFC3339)
+	past := now.Add(-time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name   string
+		coupon Coupon
+		want   bool
+	}{
+		{"active", Coupon{Active: true}, true},
+		{"inactive", Coupon{Active: false}, false},
+		{"under redemption limit", Coupon{Active: true, MaxRedemptions: 5, TimesRedeemed: 4}, true},
+		{"redemption limit reached", Coupon{Active: true, MaxRedemptions: 5, TimesRedeemed: 5}, false},
+		{"not yet expired", Coupon{Active: true, ExpiresAt: &future}, true},
+		{"expired", Coupon{Active: true, ExpiresAt: &past}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.coupon.IsRedeemable(now); got != tt.want {
+				t.Errorf("IsRedeemable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // ============ AuditLog Tests ============
 
 func TestAuditLog_ToAuditLogResponse(t *testing.T) {
@@ -1233,3 +1338,56 @@ func TestExportStatusConstants(t *testing.T) {
 		}
 	}
 }
+
+// ============ Quota Tests ============
+
+func TestQuotaPolicy_Ceiling(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy QuotaPolicy
+		limit  int64
+		want   int64
+	}{
+		{"hard", QuotaPolicy{Mode: QuotaModeHard}, 100, 100},
+		{"empty mode is hard", QuotaPolicy{}, 100, 100},
+		{"soft", QuotaPolicy{Mode: QuotaModeSoft, SoftLimitPercent: 120}, 100, 120},
+		{"soft below 100 percent", QuotaPolicy{Mode: QuotaModeSoft, SoftLimitPercent: 50}, 100, 100},
+		{"overage", QuotaPolicy{Mode: QuotaModeOverage}, 100, 0},
+		{"unlimited", QuotaPolicy{Mode: QuotaModeHard}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Ceiling(tt.limit); got != tt.want {
+				t.Errorf("Ceiling(%d) = %d, want %d", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaExemption_IsActive(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour).Format(time.RFC3339)
+	future := now.Add(time.Hour).Format(time.RFC3339)
+	invalid := "soon"
+
+	tests := []struct {
+		name      string
+		expiresAt *string
+		want      bool
+	}{
+		{"no expiry", nil, true},
+		{"expires later", &future, true},
+		{"expired", &past, false},
+		{"invalid expiry", &invalid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &QuotaExemption{ExpiresAt: tt.expiresAt}
+			if got := e.IsActive(now); got != tt.want {
+				t.Errorf("IsActive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
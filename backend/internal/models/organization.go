@@ -28,9 +28,10 @@ const (
 type MemberStatus string
 
 const (
-	MemberStatusActive   MemberStatus = "active"
-	MemberStatusInactive MemberStatus = "inactive"
-	MemberStatusPending  MemberStatus = "pending"
+	MemberStatusActive    MemberStatus = "active"
+	MemberStatusInactive  MemberStatus = "inactive"
+	MemberStatusPending   MemberStatus = "pending"
+	MemberStatusSuspended MemberStatus = "suspended" // Access revoked by an admin; does not use a seat
 )
 
 // Organization represents a tenant/organization in the multi-tenant system
@@ -49,6 +50,9 @@ type Organization struct {
 	StripeSubscriptionID *string          `gorm:"size:255" json:"stripe_subscription_id,omitempty"`
 	PlanFeatures         datatypes.JSON   `gorm:"type:jsonb;default:'{}'" json:"plan_features"`
 
+	// Monthly AI token budget set by admins; caps the plan's ai_tokens limit (0 = plan limit)
+	AITokenBudget int64 `gorm:"default:0" json:"ai_token_budget"`
+
 	// Settings stored as JSON
 	Settings datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"settings"`
 
@@ -56,6 +60,11 @@ type Organization struct {
 	CreatedByUserID uint  `gorm:"not null" json:"created_by_user_id"`
 	CreatedByUser   *User `gorm:"foreignKey:CreatedByUserID" json:"created_by_user,omitempty"`
 
+	// Scheduled deletion (owners can restore until DeletionScheduledAt passes)
+	DeletionRequestedAt       *time.Time `json:"deletion_requested_at,omitempty"`
+	DeletionRequestedByUserID *uint      `json:"deletion_requested_by_user_id,omitempty"`
+	DeletionScheduledAt       *time.Time `gorm:"index" json:"deletion_scheduled_at,omitempty"`
+
 	// Relations
 	Members     []OrganizationMember     `gorm:"foreignKey:OrganizationID" json:"members,omitempty"`
 	Invitations []OrganizationInvitation `gorm:"foreignKey:OrganizationID" json:"invitations,omitempty"`
@@ -66,6 +75,11 @@ func (Organization) TableName() string {
 	return "organizations"
 }
 
+// IsPendingDeletion returns true if the organization is scheduled for deletion
+func (o *Organization) IsPendingDeletion() bool {
+	return o.DeletionScheduledAt != nil
+}
+
 // OrganizationMember represents a user's membership in an organization
 type OrganizationMember struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
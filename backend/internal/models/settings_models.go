@@ -755,19 +755,20 @@ const (
 // DataExport represents a user data export request
 // swagger:model DataExport
 type DataExport struct {
-	ID           uint    `json:"id" gorm:"primaryKey"`
-	UserID       uint    `json:"user_id" gorm:"not null;index"`
-	Status       string  `json:"status" gorm:"type:varchar(50);default:'pending'"`
-	DownloadURL  *string `json:"download_url,omitempty" gorm:"type:varchar(500)"`
-	FilePath     *string `json:"-" gorm:"type:varchar(500)"`
-	StorageType  string  `json:"-" gorm:"type:varchar(20);default:'local'"` // "local" or "s3"
-	FileSize     int64   `json:"file_size,omitempty"`
-	RequestedAt  string  `json:"requested_at" gorm:"not null"`
-	CompletedAt  *string `json:"completed_at,omitempty"`
-	ExpiresAt    *string `json:"expires_at,omitempty"`
-	ErrorMessage *string `json:"error_message,omitempty" gorm:"type:text"`
-	CreatedAt    string  `json:"created_at"`
-	UpdatedAt    string  `json:"updated_at"`
+	ID             uint    `json:"id" gorm:"primaryKey"`
+	UserID         uint    `json:"user_id" gorm:"not null;index"`
+	OrganizationID *uint   `json:"organization_id,omitempty" gorm:"index"` // Set for organization exports
+	Status         string  `json:"status" gorm:"type:varchar(50);default:'pending'"`
+	DownloadURL    *string `json:"download_url,omitempty" gorm:"type:varchar(500)"`
+	FilePath       *string `json:"-" gorm:"type:varchar(500)"`
+	StorageType    string  `json:"-" gorm:"type:varchar(20);default:'local'"` // "local" or "s3"
+	FileSize       int64   `json:"file_size,omitempty"`
+	RequestedAt    string  `json:"requested_at" gorm:"not null"`
+	CompletedAt    *string `json:"completed_at,omitempty"`
+	ExpiresAt      *string `json:"expires_at,omitempty"`
+	ErrorMessage   *string `json:"error_message,omitempty" gorm:"type:text"`
+	CreatedAt      string  `json:"created_at"`
+	UpdatedAt      string  `json:"updated_at"`
 }
 
 // DataExportResponse represents the response for data export status
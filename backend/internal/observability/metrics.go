@@ -105,6 +105,29 @@ var (
 		[]string{"cache"},
 	)
 
+	// Usage metering metrics
+	UsageCounterFallbacksTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "usage_counter_fallbacks_total",
+			Help: "Usage increments kept in process memory because Redis was unavailable",
+		},
+	)
+
+	UsageDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "usage_dropped_total",
+			Help: "Usage that could not be recorded",
+		},
+		[]string{"usage_type", "reason"}, // reason: event_write_failed, counter_failed, flush_failed
+	)
+
+	UsageCountersFlushedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "usage_counters_flushed_total",
+			Help: "Usage counters flushed to usage periods",
+		},
+	)
+
 	// Job queue metrics
 	JobsProcessedTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -192,6 +215,21 @@ func RecordCacheMiss(cache string) {
 	CacheMissesTotal.WithLabelValues(cache).Inc()
 }
 
+// RecordUsageCounterFallback records an increment kept in memory instead of Redis
+func RecordUsageCounterFallback() {
+	UsageCounterFallbacksTotal.Inc()
+}
+
+// RecordUsageDropped records usage lost before it reached the database
+func RecordUsageDropped(usageType, reason string) {
+	UsageDroppedTotal.WithLabelValues(usageType, reason).Inc()
+}
+
+// RecordUsageCountersFlushed records counters flushed to usage periods
+func RecordUsageCountersFlushed(count int) {
+	UsageCountersFlushedTotal.Add(float64(count))
+}
+
 // RecordJobProcessed records a processed job
 func RecordJobProcessed(jobType, status string, duration float64) {
 	JobsProcessedTotal.WithLabelValues(jobType, status).Inc()
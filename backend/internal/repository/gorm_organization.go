@@ -69,6 +69,16 @@ func (r *GormOrganizationRepository) FindByStripeSubscriptionID(ctx context.Cont
 	return &org, nil
 }
 
+// FindDueForDeletion returns organizations whose scheduled deletion time has passed.
+func (r *GormOrganizationRepository) FindDueForDeletion(ctx context.Context, before time.Time) ([]models.Organization, error) {
+	var orgs []models.Organization
+	err := r.db.WithContext(ctx).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", before).
+		Order("deletion_scheduled_at ASC").
+		Find(&orgs).Error
+	return orgs, err
+}
+
 // CountBySlug returns the count of organizations with the given slug.
 func (r *GormOrganizationRepository) CountBySlug(ctx context.Context, slug string) (int64, error) {
 	var count int64
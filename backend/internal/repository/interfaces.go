@@ -82,6 +82,9 @@ type OrganizationRepository interface {
 	// FindByStripeSubscriptionID returns an organization by Stripe subscription ID.
 	FindByStripeSubscriptionID(ctx context.Context, subID string) (*models.Organization, error)
 
+	// FindDueForDeletion returns organizations whose scheduled deletion time has passed.
+	FindDueForDeletion(ctx context.Context, before time.Time) ([]models.Organization, error)
+
 	// CountBySlug returns the count of organizations with the given slug.
 	CountBySlug(ctx context.Context, slug string) (int64, error)
 
@@ -31,6 +31,7 @@ const (
 	ErrCodeTokenInvalid     = "TOKEN_INVALID"
 	ErrCodeEmailNotVerified = "EMAIL_NOT_VERIFIED"
 	ErrCodeAccountInactive  = "ACCOUNT_INACTIVE"
+	ErrCodeQuotaExceeded    = "QUOTA_EXCEEDED"
 )
 
 // JSON writes a JSON response with the given status code
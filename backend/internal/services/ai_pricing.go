@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"react-golang-starter/internal/config"
+)
+
+// AIModelPrice is the price of a model's tokens in USD per million tokens
+type AIModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// defaultAIModelPrices are the list prices of the Gemini models the AI service uses
+var defaultAIModelPrices = map[string]AIModelPrice{
+	"gemini-2.5-pro":        {InputPerMillion: 1.25, OutputPerMillion: 10.00},
+	"gemini-2.5-flash":      {InputPerMillion: 0.30, OutputPerMillion: 2.50},
+	"gemini-2.0-flash":      {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gemini-2.0-flash-lite": {InputPerMillion: 0.075, OutputPerMillion: 0.30},
+	"gemini-1.5-pro":        {InputPerMillion: 1.25, OutputPerMillion: 5.00},
+	"gemini-1.5-flash":      {InputPerMillion: 0.075, OutputPerMillion: 0.30},
+	"text-embedding-004":    {},
+}
+
+// AIPricingTable estimates the cost of AI token usage by model. Models are matched exactly,
+// else by the longest priced prefix (so gemini-2.0-flash-001 uses the gemini-2.0-flash price);
+// unknown models are estimated at zero cost.
+type AIPricingTable struct {
+	mu     sync.RWMutex
+	prices map[string]AIModelPrice
+}
+
+// DefaultAIPricingTable returns a table with the list prices of the Gemini models
+func DefaultAIPricingTable() *AIPricingTable {
+	table := &AIPricingTable{prices: make(map[string]AIModelPrice, len(defaultAIModelPrices))}
+	for model, price := range defaultAIModelPrices {
+		table.prices[model] = price
+	}
+	return table
+}
+
+// NewAIPricingTable builds a pricing table from the ai_pricing section of the config file,
+// which overrides and extends the default prices
+func NewAIPricingTable(cfg config.AIPricingConfig) (*AIPricingTable, error) {
+	table := DefaultAIPricingTable()
+	for model, price := range cfg.Models {
+		if err := table.SetPrice(model, AIModelPrice(price)); err != nil {
+			return DefaultAIPricingTable(), err
+		}
+	}
+	return table, nil
+}
+
+// SetPrice sets the price of a model
+func (t *AIPricingTable) SetPrice(model string, price AIModelPrice) error {
+	if model == "" {
+		return fmt.Errorf("ai price without a model")
+	}
+	if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+		return fmt.Errorf("ai price for %s must not be negative", model)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prices[strings.ToLower(model)] = price
+	return nil
+}
+
+// Price returns the price of a model and whether one is configured
+func (t *AIPricingTable) Price(model string) (AIModelPrice, bool) {
+	model = strings.ToLower(strings.TrimPrefix(model, "models/"))
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if price, ok := t.prices[model]; ok {
+		return price, true
+	}
+
+	best, found := "", false
+	for prefix := range t.prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, found = prefix, true
+		}
+	}
+	return t.prices[best], found
+}
+
+// EstimateCostMicros returns the estimated cost of token usage in millionths of a USD.
+// A price per million tokens in USD is the price of one token in micro-USD.
+func (t *AIPricingTable) EstimateCostMicros(model string, inputTokens, outputTokens int64) int64 {
+	price, ok := t.Price(model)
+	if !ok {
+		return 0
+	}
+	cost := float64(inputTokens)*price.InputPerMillion + float64(outputTokens)*price.OutputPerMillion
+	return int64(cost + 0.5)
+}
+
+var (
+	aiPricingTable   = DefaultAIPricingTable()
+	aiPricingTableMu sync.RWMutex
+)
+
+// GetAIPricingTable returns the table used to estimate the cost of AI token usage
+func GetAIPricingTable() *AIPricingTable {
+	aiPricingTableMu.RLock()
+	defer aiPricingTableMu.RUnlock()
+	return aiPricingTable
+}
+
+// SetAIPricingTable replaces the table used to estimate the cost of AI token usage
+func SetAIPricingTable(table *AIPricingTable) {
+	aiPricingTableMu.Lock()
+	defer aiPricingTableMu.Unlock()
+	aiPricingTable = table
+}
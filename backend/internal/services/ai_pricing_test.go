@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"react-golang-starter/internal/config"
+	"react-golang-starter/internal/testutil/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAIPricingTable_Price(t *testing.T) {
+	table := DefaultAIPricingTable()
+
+	price, ok := table.Price("gemini-2.0-flash")
+	require.True(t, ok)
+	assert.Equal(t, AIModelPrice{InputPerMillion: 0.10, OutputPerMillion: 0.40}, price)
+
+	// Versioned names use the longest priced prefix
+	price, ok = table.Price("models/gemini-2.0-flash-lite-001")
+	require.True(t, ok)
+	assert.Equal(t, 0.075, price.InputPerMillion)
+
+	_, ok = table.Price("unknown-model")
+	assert.False(t, ok)
+}
+
+func TestAIPricingTable_EstimateCostMicros(t *testing.T) {
+	table := DefaultAIPricingTable()
+
+	// 1000 input tokens at $0.10/M + 500 output tokens at $0.40/M = $0.0003
+	assert.Equal(t, int64(300), table.EstimateCostMicros("gemini-2.0-flash", 1000, 500))
+	assert.Zero(t, table.EstimateCostMicros("text-embedding-004", 1000, 0))
+	assert.Zero(t, table.EstimateCostMicros("unknown-model", 1000, 1000))
+
+	assert.Equal(t, 0.0003, microsToUSD(300))
+	assert.Equal(t, 1.5, microsToUSD(1500000))
+}
+
+func TestNewAIPricingTable(t *testing.T) {
+	table, err := NewAIPricingTable(config.AIPricingConfig{
+		Models: map[string]config.AIModelPrice{
+			"Gemini-2.0-Flash": {InputPerMillion: 0.2, OutputPerMillion: 0.8},
+			"custom-model":     {InputPerMillion: 1, OutputPerMillion: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	price, _ := table.Price("gemini-2.0-flash")
+	assert.Equal(t, 0.2, price.InputPerMillion, "config overrides the default price")
+	price, ok := table.Price("custom-model")
+	require.True(t, ok)
+	assert.Equal(t, 2.0, price.OutputPerMillion)
+
+	_, err = NewAIPricingTable(config.AIPricingConfig{
+		Models: map[string]config.AIModelPrice{"gemini-2.0-flash": {InputPerMillion: -1}},
+	})
+	assert.Error(t, err)
+}
+
+func TestUsageMeter_AITokens(t *testing.T) {
+	ctx, meter := WithUsageMeter(context.Background())
+
+	AddAITokenUsage(ctx, AITokenUsage{Model: "gemini-2.0-flash", Operation: "chat", InputTokens: 10, OutputTokens: 5})
+	AddAITokenUsage(ctx, AITokenUsage{Model: "gemini-2.0-flash", Operation: "chat"})
+	AddAITokenUsage(ctx, AITokenUsage{Model: "text-embedding-004", Operation: "embeddings", InputTokens: 7})
+
+	usage := meter.AITokens()
+	require.Len(t, usage, 2, "calls without tokens are dropped")
+	assert.Equal(t, int64(15), usage[0].TotalTokens())
+	assert.Equal(t, "text-embedding-004", usage[1].Model)
+
+	// Without a meter the usage is dropped
+	AddAITokenUsage(context.Background(), AITokenUsage{Model: "gemini-2.0-flash", InputTokens: 1})
+}
+
+func TestUsageService_RecordAITokens(t *testing.T) {
+	ctx := context.Background()
+	eventRepo := mocks.NewMockUsageEventRepository()
+	service := NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+	defer service.Shutdown()
+
+	orgID := uint(4)
+	userID := uint(1)
+	service.RecordAITokens(ctx, &userID, &orgID, AITokenUsage{Model: "gemini-2.0-flash", Operation: "chat", InputTokens: 1000, OutputTokens: 500}, "", "")
+	service.RecordAITokens(ctx, &userID, &orgID, AITokenUsage{Model: "gemini-2.0-flash"}, "", "")
+
+	events := eventRepo.GetEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, UsageTypeAITokens, events[0].EventType)
+	assert.Equal(t, "gemini-2.0-flash", events[0].Resource)
+	assert.Equal(t, int64(1500), events[0].Quantity)
+	assert.Equal(t, "tokens", events[0].Unit)
+
+	var metadata aiTokenMetadata
+	require.NoError(t, json.Unmarshal([]byte(events[0].Metadata), &metadata))
+	assert.Equal(t, aiTokenMetadata{Operation: "chat", InputTokens: 1000, OutputTokens: 500, CostMicros: 300}, metadata)
+
+	// AI tokens count against the organization's ai_tokens limit
+	current, err := service.CurrentUsage(ctx, nil, &orgID, UsageTypeAITokens)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), current)
+}
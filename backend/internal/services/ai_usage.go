@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"react-golang-starter/internal/cache"
+	"react-golang-starter/internal/models"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrInvalidAIBudget is returned when an AI token budget is negative
+var ErrInvalidAIBudget = errors.New("AI token budget must not be negative")
+
+// aiTokenMetadata is the metadata recorded with each ai_tokens usage event
+type aiTokenMetadata struct {
+	Operation    string `json:"operation,omitempty"`
+	InputTokens  int64  `json:"input_tokens"`
+	OutputTokens int64  `json:"output_tokens"`
+	CostMicros   int64  `json:"cost_micros"`
+}
+
+// RecordAITokens records the token usage of an AI model call. The event is counted in
+// tokens against the ai_tokens limit; its metadata keeps the input and output split and
+// the cost estimated from the AI pricing table.
+func (s *UsageService) RecordAITokens(ctx context.Context, userID *uint, orgID *uint, usage AITokenUsage, ipAddress string, userAgent string) {
+	if usage.TotalTokens() <= 0 {
+		return
+	}
+
+	metadata, _ := json.Marshal(aiTokenMetadata{
+		Operation:    usage.Operation,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		CostMicros:   GetAIPricingTable().EstimateCostMicros(usage.Model, usage.InputTokens, usage.OutputTokens),
+	})
+
+	event := &models.UsageEvent{
+		UserID:         userID,
+		OrganizationID: orgID,
+		EventType:      UsageTypeAITokens,
+		Resource:       usage.Model,
+		Quantity:       usage.TotalTokens(),
+		Unit:           "tokens",
+		Metadata:       string(metadata),
+		IPAddress:      ipAddress,
+		UserAgent:      userAgent,
+	}
+
+	if err := s.RecordEvent(ctx, event); err != nil {
+		log.Warn().Err(err).Str("model", usage.Model).Msg("failed to record AI token usage")
+	}
+}
+
+// GetAIUsage returns a user's or organization's AI token usage in the current billing period
+// by model, with its estimated cost and effective token limit. A user's usage excludes usage
+// in organization contexts, which counts against the organization.
+func (s *UsageService) GetAIUsage(ctx context.Context, userID *uint, orgID *uint) (*models.AIUsageResponse, error) {
+	periodStart, periodEnd := getCurrentBillingPeriod()
+
+	query := s.db.WithContext(ctx).
+		Model(&models.UsageEvent{}).
+		Select(`resource AS model,
+			COUNT(*) AS requests,
+			COALESCE(SUM((metadata->>'input_tokens')::bigint), 0) AS input_tokens,
+			COALESCE(SUM((metadata->>'output_tokens')::bigint), 0) AS output_tokens,
+			COALESCE(SUM(quantity), 0) AS total_tokens,
+			COALESCE(SUM((metadata->>'cost_micros')::bigint), 0) AS cost_micros`).
+		Where("event_type = ? AND billing_period_start = ?", UsageTypeAITokens, periodStart)
+
+	switch {
+	case userID != nil:
+		query = query.Where("user_id = ? AND organization_id IS NULL", *userID)
+	case orgID != nil:
+		query = query.Where("organization_id = ?", *orgID)
+	default:
+		return nil, fmt.Errorf("either user_id or organization_id must be provided")
+	}
+
+	var rows []struct {
+		Model        string
+		Requests     int64
+		InputTokens  int64
+		OutputTokens int64
+		TotalTokens  int64
+		CostMicros   int64
+	}
+	if err := query.Group("resource").Order("total_tokens DESC").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get AI usage: %w", err)
+	}
+
+	resp := &models.AIUsageResponse{
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Models:      make([]models.AIModelUsage, 0, len(rows)),
+		TokenLimit:  s.defaultLimits(ctx, userID, orgID).AITokens,
+	}
+
+	var costMicros int64
+	for _, row := range rows {
+		resp.Models = append(resp.Models, models.AIModelUsage{
+			Model:            row.Model,
+			Requests:         row.Requests,
+			InputTokens:      row.InputTokens,
+			OutputTokens:     row.OutputTokens,
+			TotalTokens:      row.TotalTokens,
+			EstimatedCostUSD: microsToUSD(row.CostMicros),
+		})
+		resp.TotalTokens += row.TotalTokens
+		costMicros += row.CostMicros
+	}
+	resp.EstimatedCostUSD = microsToUSD(costMicros)
+
+	if userID == nil && orgID != nil {
+		var org models.Organization
+		if err := s.db.WithContext(ctx).Select("id", "ai_token_budget").First(&org, *orgID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load organization: %w", err)
+		}
+		resp.Budget = org.AITokenBudget
+	}
+
+	return resp, nil
+}
+
+// SetOrgAIBudget sets an organization's monthly AI token budget; 0 removes it. The budget
+// lowers the plan's AI token limit and applies to the current billing period immediately.
+func (s *UsageService) SetOrgAIBudget(ctx context.Context, org *models.Organization, tokens int64) error {
+	if tokens < 0 {
+		return ErrInvalidAIBudget
+	}
+
+	err := s.db.WithContext(ctx).Model(&models.Organization{}).
+		Where("id = ?", org.ID).
+		Updates(map[string]interface{}{
+			"ai_token_budget": tokens,
+			"updated_at":      time.Now(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update AI token budget: %w", err)
+	}
+	org.AITokenBudget = tokens
+
+	InvalidateEntitlements(ctx, OrgSubject(org.ID))
+	if err := cache.InvalidateOrganization(ctx, org.Slug, org.ID); err != nil {
+		log.Warn().Err(err).Uint("org_id", org.ID).Msg("failed to invalidate organization cache")
+	}
+
+	// Refresh the limits recorded for the current period so usage summaries reflect the budget
+	periodStart, periodEnd := getCurrentBillingPeriod()
+	key, _ := newUsageCounterKey(nil, &org.ID, periodStart, periodEnd, "")
+	limitsJSON, err := json.Marshal(s.defaultLimits(ctx, nil, &org.ID))
+	if err != nil {
+		return fmt.Errorf("failed to marshal limits: %w", err)
+	}
+	err = usagePeriodQuery(s.db.WithContext(ctx).Model(&models.UsagePeriod{}), key).
+		Update("usage_limits", string(limitsJSON)).Error
+	if err != nil {
+		return fmt.Errorf("failed to update usage limits: %w", err)
+	}
+
+	log.Info().Uint("org_id", org.ID).Int64("ai_token_budget", tokens).Msg("updated organization AI token budget")
+	return nil
+}
+
+// microsToUSD converts millionths of a USD to USD, rounded to a hundredth of a cent
+func microsToUSD(micros int64) float64 {
+	return math.Round(float64(micros)/100) / 10000
+}
@@ -0,0 +1,584 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"react-golang-starter/internal/cache"
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+const (
+	// EntitlementsKeyPrefix is the cache key prefix for resolved entitlements
+	EntitlementsKeyPrefix = "entitlements:"
+
+	// entitlementsTTL bounds how stale cached entitlements can be if an invalidation is missed
+	entitlementsTTL = 5 * time.Minute
+
+	// defaultEntitlementsFile is the plan catalog loaded when ENTITLEMENTS_FILE is not set
+	defaultEntitlementsFile = "entitlements.yaml"
+)
+
+// PlanDefinition describes what a plan includes
+type PlanDefinition struct {
+	Key      string             `yaml:"key"`
+	Name     string             `yaml:"name"`
+	Rank     int                `yaml:"rank"`
+	PriceIDs []string           `yaml:"price_ids"`
+	Seats    int                `yaml:"seats"` // 0 means unlimited
+	Limits   models.UsageLimits `yaml:"limits"`
+	Features []string           `yaml:"features"`
+	Quota    models.QuotaPolicy `yaml:"quota"` // empty mode enforces hard limits
+}
+
+// validQuotaMode reports whether mode is a known quota mode; empty means hard
+func validQuotaMode(mode string) bool {
+	switch mode {
+	case "", models.QuotaModeHard, models.QuotaModeSoft, models.QuotaModeOverage:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasFeature reports whether the plan includes a boolean feature
+func (p PlanDefinition) HasFeature(feature string) bool {
+	for _, f := range p.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// planCatalogFile is the YAML plan catalog format
+type planCatalogFile struct {
+	Plans []PlanDefinition `yaml:"plans"`
+}
+
+// PlanCatalog is the set of plans and the Stripe prices that subscribe to them
+type PlanCatalog struct {
+	mu      sync.RWMutex
+	plans   map[string]PlanDefinition
+	byPrice map[string]string
+}
+
+// NewPlanCatalog creates a catalog from plan definitions
+func NewPlanCatalog(plans []PlanDefinition) *PlanCatalog {
+	c := &PlanCatalog{
+		plans:   make(map[string]PlanDefinition),
+		byPrice: make(map[string]string),
+	}
+	for _, plan := range plans {
+		c.Set(plan)
+	}
+	return c
+}
+
+// DefaultPlanCatalog returns the built-in free, pro and enterprise plans
+func DefaultPlanCatalog() *PlanCatalog {
+	return NewPlanCatalog([]PlanDefinition{
+		{
+			Key:    string(models.OrgPlanFree),
+			Name:   "Free",
+			Rank:   1,
+			Seats:  models.DefaultPlanFeatures(models.OrgPlanFree).SeatLimit,
+			Limits: DefaultUsageLimits,
+			Quota:  models.QuotaPolicy{Mode: models.QuotaModeHard},
+		},
+		{
+			Key:      string(models.OrgPlanPro),
+			Name:     "Pro",
+			Rank:     2,
+			PriceIDs: []string{"price_pro_monthly", "price_pro_yearly"},
+			Seats:    models.DefaultPlanFeatures(models.OrgPlanPro).SeatLimit,
+			Limits:   TierLimits["price_pro_monthly"],
+			Features: []string{"priority_support", "advanced_analytics"},
+			Quota:    models.QuotaPolicy{Mode: models.QuotaModeSoft, SoftLimitPercent: 120},
+		},
+		{
+			Key:      string(models.OrgPlanEnterprise),
+			Name:     "Enterprise",
+			Rank:     3,
+			PriceIDs: []string{"price_enterprise_monthly", "price_enterprise_yearly"},
+			Seats:    models.DefaultPlanFeatures(models.OrgPlanEnterprise).SeatLimit,
+			Limits:   TierLimits["price_enterprise_monthly"],
+			Features: []string{"priority_support", "advanced_analytics", "sso", "audit_log_export"},
+			Quota:    models.QuotaPolicy{Mode: models.QuotaModeOverage},
+		},
+	})
+}
+
+// LoadPlanCatalog loads the built-in plans overridden by a YAML catalog file.
+// Plans in the file replace the built-in plan with the same key. A missing file is not an error.
+func LoadPlanCatalog(path string) (*PlanCatalog, error) {
+	if path == "" {
+		path = os.Getenv("ENTITLEMENTS_FILE")
+	}
+	if path == "" {
+		path = defaultEntitlementsFile
+	}
+
+	catalog := DefaultPlanCatalog()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return catalog, nil
+		}
+		return catalog, err
+	}
+
+	var file planCatalogFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return catalog, fmt.Errorf("failed to parse plan catalog: %w", err)
+	}
+
+	for _, plan := range file.Plans {
+		if plan.Key == "" {
+			return catalog, fmt.Errorf("plan catalog entry without a key")
+		}
+		if !validQuotaMode(plan.Quota.Mode) {
+			return catalog, fmt.Errorf("plan %s: unknown quota mode %q", plan.Key, plan.Quota.Mode)
+		}
+		catalog.Set(plan)
+	}
+
+	log.Info().Str("path", path).Int("plans", len(file.Plans)).Msg("loaded plan catalog from YAML file")
+	return catalog, nil
+}
+
+// LoadFromDB applies the plan_entitlements rows, which take precedence over the YAML catalog
+func (c *PlanCatalog) LoadFromDB(ctx context.Context, db *gorm.DB) error {
+	var rows []models.PlanEntitlement
+	if err := db.WithContext(ctx).Order("rank ASC").Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load plan entitlements: %w", err)
+	}
+
+	for _, row := range rows {
+		// Rows without a quota mode keep the enforcement configured for the plan so far
+		var quota models.QuotaPolicy
+		if c.HasPlan(row.Plan) {
+			quota = c.Plan(row.Plan).Quota
+		}
+		if row.QuotaMode != "" {
+			quota = models.QuotaPolicy{Mode: row.QuotaMode, SoftLimitPercent: row.SoftLimitPercent}
+		}
+
+		c.Set(PlanDefinition{
+			Key:      row.Plan,
+			Name:     row.Name,
+			Rank:     row.Rank,
+			PriceIDs: row.PriceIDs,
+			Seats:    row.Seats,
+			Limits: models.UsageLimits{
+				APICalls:     row.APICalls,
+				StorageBytes: row.StorageBytes,
+				ComputeMS:    row.ComputeMS,
+				FileUploads:  row.FileUploads,
+				AITokens:     row.AITokens,
+			},
+			Features: row.Features,
+			Quota:    quota,
+		})
+	}
+	return nil
+}
+
+// Set adds or replaces a plan and its price mappings
+func (c *PlanCatalog) Set(plan PlanDefinition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.plans[plan.Key]; ok {
+		for _, priceID := range old.PriceIDs {
+			delete(c.byPrice, priceID)
+		}
+	}
+	c.plans[plan.Key] = plan
+	for _, priceID := range plan.PriceIDs {
+		if priceID != "" {
+			c.byPrice[priceID] = plan.Key
+		}
+	}
+}
+
+// AssignPrice maps a Stripe price ID to a plan, e.g. the price IDs from the Stripe config
+func (c *PlanCatalog) AssignPrice(planKey, priceID string) {
+	if priceID == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	plan, ok := c.plans[planKey]
+	if !ok {
+		return
+	}
+	if c.byPrice[priceID] == planKey {
+		return
+	}
+	plan.PriceIDs = append(plan.PriceIDs, priceID)
+	c.plans[planKey] = plan
+	c.byPrice[priceID] = planKey
+}
+
+// Plan returns a plan by key, falling back to the free plan for unknown keys
+func (c *PlanCatalog) Plan(key string) PlanDefinition {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if plan, ok := c.plans[key]; ok {
+		return plan
+	}
+	return c.plans[string(models.OrgPlanFree)]
+}
+
+// HasPlan reports whether a plan key is in the catalog
+func (c *PlanCatalog) HasPlan(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.plans[key]
+	return ok
+}
+
+// Plans returns all plans ordered by rank
+func (c *PlanCatalog) Plans() []PlanDefinition {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	plans := make([]PlanDefinition, 0, len(c.plans))
+	for _, plan := range c.plans {
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		if plans[i].Rank != plans[j].Rank {
+			return plans[i].Rank < plans[j].Rank
+		}
+		return plans[i].Key < plans[j].Key
+	})
+	return plans
+}
+
+// PlanForPrice returns the plan a Stripe price subscribes to
+func (c *PlanCatalog) PlanForPrice(priceID string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	plan, ok := c.byPrice[priceID]
+	return plan, ok
+}
+
+// LimitsForPrice returns the usage limits of the plan a price subscribes to,
+// or the free plan's limits for unknown prices
+func (c *PlanCatalog) LimitsForPrice(priceID string) models.UsageLimits {
+	plan, _ := c.PlanForPrice(priceID)
+	return c.Plan(plan).Limits
+}
+
+// Rank returns a plan's position in the hierarchy; unknown plans rank 0
+func (c *PlanCatalog) Rank(key string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.plans[key].Rank
+}
+
+// Grants reports whether a plan satisfies a flag's plan requirement. A minimum plan is
+// compared by rank. Without one, a feature that some plan includes is limited to the plans
+// that include it, and any other feature is ungated.
+func (c *PlanCatalog) Grants(plan, minPlan, feature string) bool {
+	if minPlan != "" {
+		return c.Rank(plan) >= c.Rank(minPlan)
+	}
+	if c.LowestPlanWithFeature(feature) == "" {
+		return true
+	}
+	return c.HasPlan(plan) && c.Plan(plan).HasFeature(feature)
+}
+
+// RequiredPlan returns the plan to offer for a flag that Grants refuses: the minimum plan
+// when set, otherwise the lowest plan that includes the feature
+func (c *PlanCatalog) RequiredPlan(minPlan, feature string) string {
+	if minPlan != "" {
+		return minPlan
+	}
+	return c.LowestPlanWithFeature(feature)
+}
+
+// LowestPlanWithFeature returns the lowest-ranked plan that includes a feature, or an empty
+// string if no plan does
+func (c *PlanCatalog) LowestPlanWithFeature(feature string) string {
+	if feature == "" {
+		return ""
+	}
+	for _, plan := range c.Plans() {
+		if plan.HasFeature(feature) {
+			return plan.Key
+		}
+	}
+	return ""
+}
+
+// EntitlementSubject identifies whose entitlements to resolve: an organization when
+// OrganizationID is set, otherwise the user's personal subscription
+type EntitlementSubject struct {
+	UserID         uint
+	OrganizationID *uint
+}
+
+// UserSubject returns the subject for a user's personal plan
+func UserSubject(userID uint) EntitlementSubject {
+	return EntitlementSubject{UserID: userID}
+}
+
+// OrgSubject returns the subject for an organization's plan
+func OrgSubject(orgID uint) EntitlementSubject {
+	return EntitlementSubject{OrganizationID: &orgID}
+}
+
+// SubscriptionSubject returns the subject a subscription is billed to
+func SubscriptionSubject(sub *models.Subscription) EntitlementSubject {
+	if sub.OrganizationID != nil && *sub.OrganizationID > 0 {
+		return OrgSubject(*sub.OrganizationID)
+	}
+	return UserSubject(sub.UserID)
+}
+
+// cacheKey returns the cache key for the subject's entitlements
+func (s EntitlementSubject) cacheKey() string {
+	if s.OrganizationID != nil {
+		return EntitlementsKeyPrefix + "org:" + strconv.FormatUint(uint64(*s.OrganizationID), 10)
+	}
+	return EntitlementsKeyPrefix + "user:" + strconv.FormatUint(uint64(s.UserID), 10)
+}
+
+// EntitlementsService resolves what users and organizations may use from their plan
+type EntitlementsService struct {
+	db      *gorm.DB
+	catalog *PlanCatalog
+	now     func() time.Time
+}
+
+// NewEntitlementsService creates an entitlements service for a plan catalog
+func NewEntitlementsService(db *gorm.DB, catalog *PlanCatalog) *EntitlementsService {
+	if catalog == nil {
+		catalog = DefaultPlanCatalog()
+	}
+	return &EntitlementsService{
+		db:      db,
+		catalog: catalog,
+		now:     time.Now,
+	}
+}
+
+var (
+	entitlementsService *EntitlementsService
+	defaultCatalog      = DefaultPlanCatalog()
+)
+
+// SetEntitlementsService sets the service used by usage limits, seat checks, feature flags and billing
+func SetEntitlementsService(svc *EntitlementsService) {
+	entitlementsService = svc
+}
+
+// GetEntitlementsService returns the configured entitlements service, or one backed by the
+// built-in plan catalog if none has been set
+func GetEntitlementsService() *EntitlementsService {
+	if entitlementsService != nil {
+		return entitlementsService
+	}
+	return NewEntitlementsService(database.DB, defaultCatalog)
+}
+
+// Catalog returns the plan catalog
+func (s *EntitlementsService) Catalog() *PlanCatalog {
+	return s.catalog
+}
+
+// Entitlements returns the plan limits, seats and features currently available to a subject
+func (s *EntitlementsService) Entitlements(ctx context.Context, subject EntitlementSubject) (*models.Entitlements, error) {
+	return s.cached(ctx, subject, func() (*models.Entitlements, error) {
+		if subject.OrganizationID == nil {
+			return s.resolveUser(ctx, subject.UserID)
+		}
+		if s.db == nil {
+			return s.build(string(models.OrgPlanFree), nil), nil
+		}
+		var org models.Organization
+		if err := s.db.WithContext(ctx).Select("id", "plan", "ai_token_budget").First(&org, *subject.OrganizationID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load organization: %w", err)
+		}
+		return s.resolveOrg(ctx, &org), nil
+	})
+}
+
+// OrganizationEntitlements returns the entitlements of an organization that is already loaded
+func (s *EntitlementsService) OrganizationEntitlements(ctx context.Context, org *models.Organization) (*models.Entitlements, error) {
+	return s.cached(ctx, OrgSubject(org.ID), func() (*models.Entitlements, error) {
+		return s.resolveOrg(ctx, org), nil
+	})
+}
+
+// cached returns the subject's cached entitlements, resolving and caching them on a miss
+func (s *EntitlementsService) cached(ctx context.Context, subject EntitlementSubject, resolve func() (*models.Entitlements, error)) (*models.Entitlements, error) {
+	key := subject.cacheKey()
+
+	var cached models.Entitlements
+	if cache.IsAvailable() {
+		if err := cache.GetJSON(ctx, key, &cached); err == nil && !s.trialEnded(cached) {
+			return &cached, nil
+		}
+	}
+
+	ent, err := resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	if cache.IsAvailable() {
+		if err := cache.SetJSON(ctx, key, ent, entitlementsTTL); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("failed to cache entitlements")
+		}
+	}
+	return ent, nil
+}
+
+// trialEnded reports whether cached entitlements came from a trial that has since ended
+func (s *EntitlementsService) trialEnded(ent models.Entitlements) bool {
+	if !ent.Trial || ent.TrialEndsAt == nil {
+		return false
+	}
+	sub := models.Subscription{Status: models.SubscriptionStatusTrialing, TrialEndsAt: ent.TrialEndsAt}
+	return sub.TrialExpired(s.now())
+}
+
+// resolveOrg determines an organization's entitlements: those of its plan, with the AI
+// token limit lowered to the organization's own AI budget when one is set
+func (s *EntitlementsService) resolveOrg(ctx context.Context, org *models.Organization) *models.Entitlements {
+	ent := s.resolveOrgPlan(ctx, org)
+	if budget := org.AITokenBudget; budget > 0 && (ent.Limits.AITokens <= 0 || budget < ent.Limits.AITokens) {
+		ent.Limits.AITokens = budget
+	}
+	return ent
+}
+
+// resolveOrgPlan determines the entitlements of an organization's plan. The organization plan
+// is kept in sync by the billing webhooks; only trials that ended without Stripe reporting the
+// outcome need to be checked here.
+func (s *EntitlementsService) resolveOrgPlan(ctx context.Context, org *models.Organization) *models.Entitlements {
+	if s.db == nil {
+		return s.build(string(org.Plan), nil)
+	}
+
+	var trial models.Subscription
+	err := s.db.WithContext(ctx).
+		Where("organization_id = ? AND status = ?", org.ID, models.SubscriptionStatusTrialing).
+		First(&trial).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn().Err(err).Uint("org_id", org.ID).Msg("failed to load organization trial")
+		}
+		return s.build(string(org.Plan), nil)
+	}
+	if trial.TrialExpired(s.now()) {
+		return s.build(string(models.OrgPlanFree), nil)
+	}
+	return s.build(string(org.Plan), &trial)
+}
+
+// resolveUser determines the entitlements of a user's personal subscription. Past-due
+// subscriptions keep their plan until the dunning grace period ends.
+func (s *EntitlementsService) resolveUser(ctx context.Context, userID uint) (*models.Entitlements, error) {
+	if s.db == nil {
+		return s.build(string(models.OrgPlanFree), nil), nil
+	}
+
+	var sub models.Subscription
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND organization_id IS NULL", userID).
+		Where("status IN ?", []string{models.SubscriptionStatusActive, models.SubscriptionStatusTrialing, models.SubscriptionStatusPastDue}).
+		Order("created_at DESC").
+		First(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.build(string(models.OrgPlanFree), nil), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscription: %w", err)
+	}
+	if sub.TrialExpired(s.now()) {
+		return s.build(string(models.OrgPlanFree), nil), nil
+	}
+	if sub.Status == models.SubscriptionStatusPastDue {
+		// Only the latest dunning counts; an earlier expired one may since have been paid
+		var latest models.Dunning
+		err := s.db.WithContext(ctx).
+			Where("subscription_id = ?", sub.ID).
+			Order("created_at DESC").
+			First(&latest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to load dunning: %w", err)
+		}
+		if err == nil && latest.Status == models.DunningStatusExpired {
+			return s.build(string(models.OrgPlanFree), nil), nil
+		}
+	}
+
+	// Paid prices missing from the catalog count as pro, matching the organization plan mapping
+	plan, ok := s.catalog.PlanForPrice(sub.StripePriceID)
+	if !ok {
+		plan = string(models.OrgPlanPro)
+	}
+
+	var trial *models.Subscription
+	if sub.IsTrialing() {
+		trial = &sub
+	}
+	return s.build(plan, trial), nil
+}
+
+// build creates entitlements from a catalog plan
+func (s *EntitlementsService) build(planKey string, trial *models.Subscription) *models.Entitlements {
+	plan := s.catalog.Plan(planKey)
+	features := make([]string, len(plan.Features))
+	copy(features, plan.Features)
+
+	ent := &models.Entitlements{
+		Plan:     plan.Key,
+		Limits:   plan.Limits,
+		Seats:    plan.Seats,
+		Features: features,
+		Quota:    plan.Quota,
+	}
+	if ent.Quota.Mode == "" {
+		ent.Quota.Mode = models.QuotaModeHard
+	}
+	if trial != nil {
+		ent.Trial = true
+		ent.TrialEndsAt = trial.TrialEndsAt
+	}
+	return ent
+}
+
+// InvalidateEntitlements drops a subject's cached entitlements after its plan changes
+func InvalidateEntitlements(ctx context.Context, subject EntitlementSubject) {
+	if !cache.IsAvailable() {
+		return
+	}
+	if err := cache.Delete(ctx, subject.cacheKey()); err != nil {
+		log.Warn().Err(err).Str("key", subject.cacheKey()).Msg("failed to invalidate entitlements")
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/testutil"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// testEntitlementsSetup creates an entitlements service on a test transaction
+func testEntitlementsSetup(t *testing.T) (*EntitlementsService, *gorm.DB, func()) {
+	t.Helper()
+	testutil.SkipIfNotIntegration(t)
+
+	db := testutil.SetupTestDB(t)
+	tt := testutil.NewTestTransaction(t, db)
+	return NewEntitlementsService(tt.DB, DefaultPlanCatalog()), tt.DB, tt.Rollback
+}
+
+func TestEntitlementsService_User_Integration(t *testing.T) {
+	svc, db, cleanup := testEntitlementsSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := createTestUserForUsage(t, db, "entitlements@example.com")
+
+	ent, err := svc.Entitlements(ctx, UserSubject(user.ID))
+	if err != nil {
+		t.Fatalf("Entitlements() error = %v", err)
+	}
+	if ent.Plan != "free" {
+		t.Errorf("plan without subscription = %q, want free", ent.Plan)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	sub := &models.Subscription{
+		UserID:               user.ID,
+		StripeSubscriptionID: "sub_entitlements",
+		StripePriceID:        "price_enterprise_yearly",
+		Status:               models.SubscriptionStatusActive,
+		CurrentPeriodStart:   now,
+		CurrentPeriodEnd:     now,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+	if err := db.Create(sub).Error; err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	ent, err = svc.Entitlements(ctx, UserSubject(user.ID))
+	if err != nil {
+		t.Fatalf("Entitlements() error = %v", err)
+	}
+	if ent.Plan != "enterprise" || ent.Limits != TierLimits["price_enterprise_yearly"] {
+		t.Errorf("entitlements = %+v, want enterprise limits", ent)
+	}
+
+	// A trial past its end date no longer counts
+	pastEnd := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	db.Model(sub).Updates(map[string]any{"status": models.SubscriptionStatusTrialing, "trial_ends_at": pastEnd})
+
+	ent, err = svc.Entitlements(ctx, UserSubject(user.ID))
+	if err != nil {
+		t.Fatalf("Entitlements() error = %v", err)
+	}
+	if ent.Plan != "free" || ent.Trial {
+		t.Errorf("entitlements after trial = %+v, want free", ent)
+	}
+}
+
+func TestEntitlementsService_UserDunning_Integration(t *testing.T) {
+	svc, db, cleanup := testEntitlementsSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := createTestUserForUsage(t, db, "entitlements-dunning@example.com")
+	now := time.Now()
+	sub := &models.Subscription{
+		UserID:               user.ID,
+		StripeSubscriptionID: "sub_entitlements_dunning",
+		StripePriceID:        "price_enterprise_yearly",
+		Status:               models.SubscriptionStatusPastDue,
+		CurrentPeriodStart:   now.Format(time.RFC3339),
+		CurrentPeriodEnd:     now.Format(time.RFC3339),
+		CreatedAt:            now.Format(time.RFC3339),
+		UpdatedAt:            now.Format(time.RFC3339),
+	}
+	if err := db.Create(sub).Error; err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	addDunning := func(status string, createdAt time.Time) {
+		t.Helper()
+		dunning := &models.Dunning{
+			SubscriptionID:       sub.ID,
+			UserID:               user.ID,
+			StripeSubscriptionID: sub.StripeSubscriptionID,
+			Status:               status,
+			GraceEndsAt:          createdAt.Add(7 * 24 * time.Hour).Format(time.RFC3339),
+			CreatedAt:            createdAt.Format(time.RFC3339),
+			UpdatedAt:            createdAt.Format(time.RFC3339),
+		}
+		if err := db.Create(dunning).Error; err != nil {
+			t.Fatalf("failed to create dunning: %v", err)
+		}
+	}
+
+	// An expired dunning from an earlier missed payment does not end a newer grace period
+	addDunning(models.DunningStatusExpired, now.Add(-60*24*time.Hour))
+	addDunning(models.DunningStatusActive, now.Add(-24*time.Hour))
+
+	ent, err := svc.Entitlements(ctx, UserSubject(user.ID))
+	if err != nil {
+		t.Fatalf("Entitlements() error = %v", err)
+	}
+	if ent.Plan != "enterprise" {
+		t.Errorf("plan during grace period = %q, want enterprise", ent.Plan)
+	}
+
+	addDunning(models.DunningStatusExpired, now)
+
+	ent, err = svc.Entitlements(ctx, UserSubject(user.ID))
+	if err != nil {
+		t.Fatalf("Entitlements() error = %v", err)
+	}
+	if ent.Plan != "free" {
+		t.Errorf("plan after grace period = %q, want free", ent.Plan)
+	}
+}
+
+func TestEntitlementsService_Organization_Integration(t *testing.T) {
+	svc, db, cleanup := testEntitlementsSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := createTestUserForUsage(t, db, "org-entitlements@example.com")
+	org := &models.Organization{Name: "Entitled", Slug: "entitled", Plan: models.OrgPlanPro, CreatedByUserID: user.ID}
+	if err := db.Create(org).Error; err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+
+	trialEnd := time.Now().Add(48 * time.Hour).Format(time.RFC3339)
+	now := time.Now().Format(time.RFC3339)
+	if err := db.Create(&models.Subscription{
+		UserID:               user.ID,
+		OrganizationID:       &org.ID,
+		StripeSubscriptionID: "sub_org_entitlements",
+		StripePriceID:        "price_pro_monthly",
+		Status:               models.SubscriptionStatusTrialing,
+		TrialEndsAt:          &trialEnd,
+		CurrentPeriodStart:   now,
+		CurrentPeriodEnd:     now,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}).Error; err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	ent, err := svc.Entitlements(ctx, OrgSubject(org.ID))
+	if err != nil {
+		t.Fatalf("Entitlements() error = %v", err)
+	}
+	if ent.Plan != "pro" || ent.Seats != 25 || !ent.Trial || ent.TrialEndsAt == nil {
+		t.Errorf("entitlements = %+v, want pro trial with 25 seats", ent)
+	}
+}
+
+func TestPlanCatalog_LoadFromDB_Integration(t *testing.T) {
+	_, db, cleanup := testEntitlementsSetup(t)
+	defer cleanup()
+
+	now := time.Now().Format(time.RFC3339)
+	if err := db.Create(&models.PlanEntitlement{
+		Plan:      "pro",
+		Name:      "Pro",
+		Rank:      2,
+		PriceIDs:  pq.StringArray{"price_pro_db"},
+		Seats:     40,
+		APICalls:  500000,
+		Features:  pq.StringArray{"priority_support", "custom_domains"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}).Error; err != nil {
+		t.Fatalf("failed to create plan entitlement: %v", err)
+	}
+
+	catalog := DefaultPlanCatalog()
+	if err := catalog.LoadFromDB(context.Background(), db); err != nil {
+		t.Fatalf("LoadFromDB() error = %v", err)
+	}
+
+	pro := catalog.Plan("pro")
+	if pro.Seats != 40 || pro.Limits.APICalls != 500000 || !pro.HasFeature("custom_domains") {
+		t.Errorf("pro plan = %+v, want database override", pro)
+	}
+	if plan, ok := catalog.PlanForPrice("price_pro_db"); !ok || plan != "pro" {
+		t.Errorf("PlanForPrice(price_pro_db) = %q, %v", plan, ok)
+	}
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============ PlanCatalog Tests ============
+
+func TestDefaultPlanCatalog_MatchesBuiltInLimits(t *testing.T) {
+	catalog := DefaultPlanCatalog()
+
+	assert.Equal(t, DefaultUsageLimits, catalog.Plan("free").Limits)
+	assert.Equal(t, TierLimits["price_pro_yearly"], catalog.LimitsForPrice("price_pro_yearly"))
+	assert.Equal(t, TierLimits["price_enterprise_monthly"], catalog.LimitsForPrice("price_enterprise_monthly"))
+	assert.Equal(t, DefaultUsageLimits, catalog.LimitsForPrice("price_unknown"))
+
+	for _, plan := range []models.OrganizationPlan{models.OrgPlanFree, models.OrgPlanPro, models.OrgPlanEnterprise} {
+		assert.Equal(t, models.DefaultPlanFeatures(plan).SeatLimit, catalog.Plan(string(plan)).Seats, "seats for %s", plan)
+	}
+}
+
+func TestPlanCatalog_PlansOrderedByRank(t *testing.T) {
+	plans := DefaultPlanCatalog().Plans()
+
+	require.Len(t, plans, 3)
+	assert.Equal(t, "free", plans[0].Key)
+	assert.Equal(t, "pro", plans[1].Key)
+	assert.Equal(t, "enterprise", plans[2].Key)
+}
+
+func TestPlanCatalog_UnknownPlanFallsBackToFree(t *testing.T) {
+	catalog := DefaultPlanCatalog()
+
+	assert.Equal(t, "free", catalog.Plan("platinum").Key)
+	assert.False(t, catalog.HasPlan("platinum"))
+	assert.Equal(t, 0, catalog.Rank("platinum"))
+	assert.Equal(t, 0, catalog.Rank(""))
+}
+
+func TestPlanCatalog_AssignPrice(t *testing.T) {
+	catalog := DefaultPlanCatalog()
+
+	catalog.AssignPrice("enterprise", "price_configured_enterprise")
+	catalog.AssignPrice("enterprise", "price_configured_enterprise")
+	catalog.AssignPrice("platinum", "price_ignored")
+	catalog.AssignPrice("pro", "")
+
+	plan, ok := catalog.PlanForPrice("price_configured_enterprise")
+	assert.True(t, ok)
+	assert.Equal(t, "enterprise", plan)
+	assert.Len(t, catalog.Plan("enterprise").PriceIDs, 3)
+
+	_, ok = catalog.PlanForPrice("price_ignored")
+	assert.False(t, ok)
+}
+
+func TestPlanCatalog_SetReplacesPriceMappings(t *testing.T) {
+	catalog := DefaultPlanCatalog()
+
+	catalog.Set(PlanDefinition{Key: "pro", Rank: 2, PriceIDs: []string{"price_pro_v2"}})
+
+	_, ok := catalog.PlanForPrice("price_pro_monthly")
+	assert.False(t, ok, "replaced plan should drop its old prices")
+	plan, ok := catalog.PlanForPrice("price_pro_v2")
+	assert.True(t, ok)
+	assert.Equal(t, "pro", plan)
+}
+
+func TestPlanCatalog_Grants(t *testing.T) {
+	catalog := DefaultPlanCatalog()
+
+	tests := []struct {
+		name    string
+		plan    string
+		minPlan string
+		feature string
+		want    bool
+	}{
+		{"no requirement", "free", "", "beta", true},
+		{"higher plan", "enterprise", "pro", "beta", true},
+		{"same plan", "pro", "pro", "beta", true},
+		{"lower plan", "free", "pro", "beta", false},
+		{"unknown plan", "", "free", "beta", false},
+		{"min plan wins over plan feature", "pro", "enterprise", "advanced_analytics", false},
+		{"feature not included", "pro", "enterprise", "sso", false},
+		{"plan feature without min plan", "pro", "", "advanced_analytics", true},
+		{"plan feature missing without min plan", "free", "", "advanced_analytics", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, catalog.Grants(tt.plan, tt.minPlan, tt.feature))
+		})
+	}
+}
+
+func TestPlanCatalog_RequiredPlan(t *testing.T) {
+	catalog := DefaultPlanCatalog()
+
+	assert.Equal(t, "enterprise", catalog.RequiredPlan("enterprise", "advanced_analytics"))
+	assert.Equal(t, "pro", catalog.RequiredPlan("", "advanced_analytics"))
+	assert.Equal(t, "enterprise", catalog.RequiredPlan("", "sso"))
+	assert.Empty(t, catalog.RequiredPlan("", "beta"))
+}
+
+func TestLoadPlanCatalog(t *testing.T) {
+	t.Run("missing file uses built-in plans", func(t *testing.T) {
+		catalog, err := LoadPlanCatalog(filepath.Join(t.TempDir(), "missing.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, DefaultPlanCatalog().Plans(), catalog.Plans())
+	})
+
+	t.Run("file overrides and adds plans", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "entitlements.yaml")
+		data := `
+plans:
+  - key: pro
+    name: Pro
+    rank: 2
+    price_ids: [price_pro_custom]
+    seats: 50
+    limits:
+      api_calls: 250000
+      storage_bytes: 21474836480
+      compute_ms: 72000000
+      file_uploads: 2500
+    features: [priority_support]
+  - key: team
+    name: Team
+    rank: 4
+    seats: 0
+    limits:
+      api_calls: 0
+`
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+		catalog, err := LoadPlanCatalog(path)
+		require.NoError(t, err)
+
+		pro := catalog.Plan("pro")
+		assert.Equal(t, 50, pro.Seats)
+		assert.Equal(t, int64(250000), pro.Limits.APICalls)
+		assert.Equal(t, []string{"priority_support"}, pro.Features)
+		assert.Equal(t, pro.Limits, catalog.LimitsForPrice("price_pro_custom"))
+		assert.Equal(t, DefaultUsageLimits, catalog.LimitsForPrice("price_pro_monthly"))
+
+		assert.True(t, catalog.HasPlan("team"))
+		assert.Greater(t, catalog.Rank("team"), catalog.Rank("enterprise"))
+		assert.Equal(t, "free", catalog.Plan("free").Key, "plans missing from the file are kept")
+	})
+
+	t.Run("entry without key is rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "entitlements.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("plans:\n  - name: Nameless\n"), 0o600))
+
+		_, err := LoadPlanCatalog(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("quota policy", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "entitlements.yaml")
+		data := "plans:\n  - key: pro\n    rank: 2\n    quota:\n      mode: overage\n"
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+		catalog, err := LoadPlanCatalog(path)
+		require.NoError(t, err)
+		assert.Equal(t, models.QuotaModeOverage, catalog.Plan("pro").Quota.Mode)
+	})
+
+	t.Run("unknown quota mode is rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "entitlements.yaml")
+		data := "plans:\n  - key: pro\n    quota:\n      mode: lenient\n"
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+		_, err := LoadPlanCatalog(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "entitlements.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("plans: [unclosed"), 0o600))
+
+		_, err := LoadPlanCatalog(path)
+		assert.Error(t, err)
+	})
+}
+
+// ============ EntitlementsService Tests ============
+
+func TestEntitlementSubject_CacheKey(t *testing.T) {
+	assert.Equal(t, "entitlements:user:7", UserSubject(7).cacheKey())
+	assert.Equal(t, "entitlements:org:3", OrgSubject(3).cacheKey())
+
+	orgID := uint(3)
+	assert.Equal(t, OrgSubject(3), SubscriptionSubject(&models.Subscription{UserID: 7, OrganizationID: &orgID}))
+	assert.Equal(t, UserSubject(7), SubscriptionSubject(&models.Subscription{UserID: 7}))
+}
+
+func TestEntitlementsService_OrganizationEntitlements_WithoutDB(t *testing.T) {
+	svc := NewEntitlementsService(nil, nil)
+
+	ent, err := svc.OrganizationEntitlements(context.Background(), &models.Organization{ID: 1, Plan: models.OrgPlanPro})
+	require.NoError(t, err)
+	assert.Equal(t, "pro", ent.Plan)
+	assert.Equal(t, 25, ent.Seats)
+	assert.Equal(t, TierLimits["price_pro_monthly"], ent.Limits)
+	assert.True(t, ent.HasFeature("priority_support"))
+	assert.False(t, ent.Trial)
+}
+
+func TestEntitlementsService_OrganizationEntitlements_AIBudget(t *testing.T) {
+	svc := NewEntitlementsService(nil, nil)
+	planLimit := TierLimits["price_pro_monthly"].AITokens
+
+	tests := []struct {
+		name   string
+		budget int64
+		want   int64
+	}{
+		{"no budget uses the plan limit", 0, planLimit},
+		{"budget lowers the plan limit", 50000, 50000},
+		{"budget cannot raise the plan limit", planLimit * 2, planLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			org := &models.Organization{ID: 1, Plan: models.OrgPlanPro, AITokenBudget: tt.budget}
+			ent, err := svc.OrganizationEntitlements(context.Background(), org)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ent.Limits.AITokens)
+			assert.Equal(t, TierLimits["price_pro_monthly"].APICalls, ent.Limits.APICalls)
+		})
+	}
+}
+
+func TestEntitlementsService_TrialEnded(t *testing.T) {
+	svc := NewEntitlementsService(nil, nil)
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	past := now.Add(-time.Hour).Format(time.RFC3339)
+	future := now.Add(time.Hour).Format(time.RFC3339)
+
+	assert.True(t, svc.trialEnded(models.Entitlements{Trial: true, TrialEndsAt: &past}))
+	assert.False(t, svc.trialEnded(models.Entitlements{Trial: true, TrialEndsAt: &future}))
+	assert.False(t, svc.trialEnded(models.Entitlements{TrialEndsAt: &past}))
+}
+
+func TestEntitlementsService_BuildQuotaPolicy(t *testing.T) {
+	svc := NewEntitlementsService(nil, nil)
+
+	assert.Equal(t, models.QuotaModeHard, svc.build("free", nil).Quota.Mode)
+	assert.Equal(t, models.QuotaPolicy{Mode: models.QuotaModeSoft, SoftLimitPercent: 120}, svc.build("pro", nil).Quota)
+	assert.Equal(t, models.QuotaModeOverage, svc.build("enterprise", nil).Quota.Mode)
+
+	svc.Catalog().Set(PlanDefinition{Key: "team", Rank: 4})
+	assert.Equal(t, models.QuotaModeHard, svc.build("team", nil).Quota.Mode, "plans without a policy are enforced hard")
+}
+
+func TestEntitlementsService_BuildCopiesFeatures(t *testing.T) {
+	svc := NewEntitlementsService(nil, nil)
+
+	ent := svc.build("enterprise", nil)
+	ent.Features[0] = "changed"
+
+	assert.Equal(t, "priority_support", svc.Catalog().Plan("enterprise").Features[0])
+}
@@ -2,8 +2,11 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"react-golang-starter/internal/auth"
 	"react-golang-starter/internal/database"
@@ -11,6 +14,7 @@ import (
 	"react-golang-starter/internal/storage"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
@@ -22,44 +26,98 @@ type FileService struct {
 	db            *gorm.DB
 	s3Storage     *storage.S3Storage
 	dbStorage     *storage.DatabaseStorage
+	localStorage  *storage.LocalStorage
 	activeStorage storage.FileStorage
+	maxFileSize   int64
+	uploadExpiry  time.Duration
 }
 
-// NewFileService creates a new file service instance
-func NewFileService() (*FileService, error) {
+// NewFileService creates a new file service instance. Every backend whose configuration is
+// present is initialized, so existing files are always served from the backend that stored
+// them; the storage driver only selects the backend new files are uploaded to.
+func NewFileService(cfg *storage.Config) (*FileService, error) {
+	if cfg == nil {
+		cfg = storage.DefaultConfig()
+	}
+
 	// Initialize S3 storage (will return nil if not configured)
 	s3Storage, _ := storage.NewS3Storage()
 
 	// Initialize database storage (always available if DB is connected)
 	dbStorage := storage.NewDatabaseStorage()
 
-	var activeStorage storage.FileStorage
+	// Initialize local storage when a root and signing key are configured
+	var localStorage *storage.LocalStorage
+	var localErr error
+	if cfg.LocalRoot != "" && len(cfg.SigningKey) > 0 {
+		localStorage, localErr = storage.NewLocalStorage(cfg)
+		if localErr != nil && cfg.Driver != storage.DriverLocal {
+			log.Warn().Err(localErr).Str("root", cfg.LocalRoot).Msg("local file storage unavailable, locally stored files cannot be served")
+		}
+	}
 
-	// Prefer S3 if available, otherwise use database
-	if s3Storage != nil && s3Storage.IsAvailable() {
-		activeStorage = s3Storage
-	} else {
-		activeStorage = dbStorage
+	fs := &FileService{
+		db:           database.DB,
+		s3Storage:    s3Storage,
+		dbStorage:    dbStorage,
+		localStorage: localStorage,
+		maxFileSize:  cfg.MaxFileSize,
+		uploadExpiry: cfg.UploadExpiry,
 	}
 
-	return &FileService{
-		db:            database.DB,
-		s3Storage:     s3Storage,
-		dbStorage:     dbStorage,
-		activeStorage: activeStorage,
-	}, nil
+	switch cfg.Driver {
+	case storage.DriverAuto, "":
+		// Prefer S3 if available, otherwise use database
+		if s3Storage != nil && s3Storage.IsAvailable() {
+			fs.activeStorage = s3Storage
+		} else {
+			fs.activeStorage = dbStorage
+		}
+	case storage.DriverS3:
+		if s3Storage == nil || !s3Storage.IsAvailable() {
+			return nil, fmt.Errorf("file storage driver %q selected but S3 is not configured", cfg.Driver)
+		}
+		fs.activeStorage = s3Storage
+	case storage.DriverLocal:
+		if localErr != nil {
+			return nil, fmt.Errorf("failed to initialize local storage: %w", localErr)
+		}
+		if localStorage == nil {
+			return nil, fmt.Errorf("file storage driver %q selected but local storage is not configured", cfg.Driver)
+		}
+		fs.activeStorage = localStorage
+	case storage.DriverDatabase:
+		fs.activeStorage = dbStorage
+	default:
+		return nil, fmt.Errorf("unknown file storage driver: %s", cfg.Driver)
+	}
+
+	return fs, nil
 }
 
-// UploadFile uploads a file using the active storage backend
-func (fs *FileService) UploadFile(ctx context.Context, file multipart.File, header *multipart.FileHeader) (*models.File, error) {
+// UploadFile streams a file to the active storage backend for the user in the context.
+// The upload fails with storage.ErrFileTooLarge as soon as it exceeds the maximum file size,
+// and the content's checksum is computed on the way through.
+func (fs *FileService) UploadFile(ctx context.Context, r io.Reader, header *multipart.FileHeader) (*models.File, error) {
+	// Set UserID from context if available
+	userID, _ := auth.GetUserIDFromContext(ctx)
+	return fs.uploadFile(ctx, r, header, userID)
+}
+
+// uploadFile streams a file owned by userID (0 for none) to the active storage backend
+func (fs *FileService) uploadFile(ctx context.Context, r io.Reader, header *multipart.FileHeader, userID uint) (*models.File, error) {
+	if fs.maxFileSize > 0 {
+		r = storage.LimitReader(r, fs.maxFileSize)
+	}
+	hash := sha256.New()
+
 	// Upload using active storage
-	fileModel, err := fs.activeStorage.UploadFile(ctx, file, header)
+	fileModel, err := fs.activeStorage.UploadFile(ctx, io.TeeReader(r, hash), header)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
-
-	// Set UserID from context if available
-	if userID, ok := auth.GetUserIDFromContext(ctx); ok {
+	fileModel.Checksum = hex.EncodeToString(hash.Sum(nil))
+	if userID != 0 {
 		fileModel.UserID = userID
 	}
 
@@ -75,24 +133,30 @@ func (fs *FileService) UploadFile(ctx context.Context, file multipart.File, head
 			}
 			return nil, fmt.Errorf("failed to save file metadata to database: %w", err)
 		}
+	} else if fileModel.ID != 0 {
+		// Backends that save the metadata themselves don't know the owner or checksum
+		updates := map[string]interface{}{"checksum": fileModel.Checksum}
+		if userID != 0 {
+			updates["user_id"] = userID
+		}
+		if err := fs.db.Model(fileModel).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to save file metadata: %w", err)
+		}
 	}
 
 	return fileModel, nil
 }
 
-// DownloadFile downloads a file using the appropriate storage backend
-func (fs *FileService) DownloadFile(ctx context.Context, fileID uint) ([]byte, *models.File, error) {
+// DownloadFile opens a file for streaming using the appropriate storage backend. The caller
+// must close the returned reader.
+func (fs *FileService) DownloadFile(ctx context.Context, fileID uint) (io.ReadSeekCloser, *models.File, error) {
 	// Get file metadata from database
-	var file models.File
-	if err := fs.db.First(&file, fileID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil, fmt.Errorf("file not found")
-		}
-		return nil, nil, fmt.Errorf("failed to retrieve file metadata: %w", err)
+	file, err := fs.GetFileByID(fileID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var content []byte
-	var err error
+	var content io.ReadSeekCloser
 
 	switch file.StorageType {
 	case "s3":
@@ -100,24 +164,32 @@ func (fs *FileService) DownloadFile(ctx context.Context, fileID uint) ([]byte, *
 			return nil, nil, fmt.Errorf("S3 storage not available for file retrieval")
 		}
 		// For S3, return the URL instead of downloading content
-		return nil, &file, fmt.Errorf("use GetFileURL for S3 files")
+		return nil, file, fmt.Errorf("use GetFileURL for S3 files")
 	case "database":
 		content, err = fs.dbStorage.DownloadFile(ctx, fileID)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to download file from database: %w", err)
 		}
+	case "local":
+		if fs.localStorage == nil {
+			return nil, nil, fmt.Errorf("local storage not available for file retrieval")
+		}
+		content, err = fs.localStorage.DownloadFile(ctx, fileID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to download file from local storage: %w", err)
+		}
 	default:
 		return nil, nil, fmt.Errorf("unknown storage type: %s", file.StorageType)
 	}
 
-	return content, &file, nil
+	return content, file, nil
 }
 
 // DeleteFile deletes a file from both storage and database
 func (fs *FileService) DeleteFile(ctx context.Context, fileID uint) error {
 	// Get file metadata from database
 	var file models.File
-	if err := fs.db.First(&file, fileID).Error; err != nil {
+	if err := fs.db.Omit("content").First(&file, fileID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return fmt.Errorf("file not found")
 		}
@@ -136,6 +208,13 @@ func (fs *FileService) DeleteFile(ctx context.Context, fileID uint) error {
 		if err := fs.dbStorage.DeleteFile(ctx, fileID); err != nil {
 			return fmt.Errorf("failed to delete file from database: %w", err)
 		}
+	case "local":
+		if fs.localStorage == nil {
+			return fmt.Errorf("local storage not available")
+		}
+		if err := fs.localStorage.DeleteFile(ctx, fileID); err != nil {
+			return fmt.Errorf("failed to delete file from local storage: %w", err)
+		}
 	default:
 		return fmt.Errorf("unknown storage type: %s", file.StorageType)
 	}
@@ -147,7 +226,7 @@ func (fs *FileService) DeleteFile(ctx context.Context, fileID uint) error {
 func (fs *FileService) GetFileURL(ctx context.Context, fileID uint) (string, error) {
 	// Get file metadata from database
 	var file models.File
-	if err := fs.db.First(&file, fileID).Error; err != nil {
+	if err := fs.db.Omit("content").First(&file, fileID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return "", fmt.Errorf("file not found")
 		}
@@ -162,15 +241,32 @@ func (fs *FileService) GetFileURL(ctx context.Context, fileID uint) (string, err
 		return "", fmt.Errorf("S3 storage not available")
 	case "database":
 		return fs.dbStorage.GetFileURL(ctx, fileID)
+	case "local":
+		if fs.localStorage != nil {
+			return fs.localStorage.GetFileURL(ctx, fileID)
+		}
+		return "", fmt.Errorf("local storage not available")
 	default:
 		return "", fmt.Errorf("unknown storage type: %s", file.StorageType)
 	}
 }
 
+// DownloadSignedFile downloads a locally stored file through a signed URL, verifying the
+// URL's expiry and signature instead of the caller's identity
+func (fs *FileService) DownloadSignedFile(ctx context.Context, fileID uint, expires, signature string) (io.ReadSeekCloser, *models.File, error) {
+	if fs.localStorage == nil {
+		return nil, nil, fmt.Errorf("local storage not available")
+	}
+	if err := fs.localStorage.VerifySignedURL(fileID, expires, signature); err != nil {
+		return nil, nil, err
+	}
+	return fs.DownloadFile(ctx, fileID)
+}
+
 // GetFileByID retrieves file metadata by ID
 func (fs *FileService) GetFileByID(fileID uint) (*models.File, error) {
 	var file models.File
-	if err := fs.db.First(&file, fileID).Error; err != nil {
+	if err := fs.db.Omit("content").First(&file, fileID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("file not found")
 		}
@@ -203,7 +299,7 @@ func (fs *FileService) GetFileByIDForUser(fileID uint, userID uint, isAdmin bool
 // ListFiles retrieves a list of files with pagination
 func (fs *FileService) ListFiles(limit, offset int) ([]models.File, error) {
 	var files []models.File
-	if err := fs.db.Limit(limit).Offset(offset).Find(&files).Error; err != nil {
+	if err := fs.db.Omit("content").Limit(limit).Offset(offset).Find(&files).Error; err != nil {
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
 	return files, nil
@@ -213,7 +309,7 @@ func (fs *FileService) ListFiles(limit, offset int) ([]models.File, error) {
 // Admins can see all files by passing isAdmin=true
 func (fs *FileService) ListFilesForUser(userID uint, isAdmin bool, limit, offset int) ([]models.File, error) {
 	var files []models.File
-	query := fs.db.Limit(limit).Offset(offset)
+	query := fs.db.Omit("content").Limit(limit).Offset(offset)
 
 	// Admins can see all files
 	if !isAdmin {
@@ -226,10 +322,18 @@ func (fs *FileService) ListFilesForUser(userID uint, isAdmin bool, limit, offset
 	return files, nil
 }
 
+// MaxFileSize returns the maximum size of an uploaded file in bytes, or 0 for no limit
+func (fs *FileService) MaxFileSize() int64 {
+	return fs.maxFileSize
+}
+
 // GetStorageType returns the currently active storage type
 func (fs *FileService) GetStorageType() string {
 	if fs.activeStorage == fs.s3Storage {
 		return "s3"
 	}
+	if fs.localStorage != nil && fs.activeStorage == fs.localStorage {
+		return "local"
+	}
 	return "database"
 }
@@ -2,9 +2,17 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
 	"testing"
 	"time"
 
+	"react-golang-starter/internal/auth"
 	"react-golang-starter/internal/database"
 	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/storage"
@@ -60,7 +68,6 @@ func createTestFile(t *testing.T, db *gorm.DB, userID uint, fileName string, con
 		FileName:    fileName,
 		ContentType: "text/plain",
 		FileSize:    int64(len(content)),
-		Content:     content,
 		StorageType: "database",
 		Location:    "database://files",
 		CreatedAt:   time.Now().Format(time.RFC3339),
@@ -69,6 +76,11 @@ func createTestFile(t *testing.T, db *gorm.DB, userID uint, fileName string, con
 	if err := db.Create(file).Error; err != nil {
 		t.Fatalf("Failed to create test file: %v", err)
 	}
+	if len(content) > 0 {
+		if err := db.Create(&models.FileChunk{FileID: file.ID, Data: content}).Error; err != nil {
+			t.Fatalf("Failed to create test file content: %v", err)
+		}
+	}
 	return file
 }
 
@@ -325,10 +337,16 @@ func TestFileService_DownloadFile_Integration(t *testing.T) {
 		expectedContent := []byte("hello download test")
 		file := createTestFile(t, db, user.ID, "download.txt", expectedContent)
 
-		content, metadata, err := svc.DownloadFile(context.Background(), file.ID)
+		reader, metadata, err := svc.DownloadFile(context.Background(), file.ID)
 		if err != nil {
 			t.Fatalf("DownloadFile failed: %v", err)
 		}
+		defer reader.Close()
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to read downloaded file: %v", err)
+		}
 
 		if string(content) != string(expectedContent) {
 			t.Errorf("Expected content %q, got: %q", expectedContent, content)
@@ -401,3 +419,60 @@ func TestFileService_ErrAccessDenied_Integration(t *testing.T) {
 	})
 	_ = db // Suppress unused variable
 }
+
+func TestFileService_UploadFile_Integration(t *testing.T) {
+	svc, db, cleanup := testFileServiceSetup(t)
+	defer cleanup()
+
+	newHeader := func(name string) *multipart.FileHeader {
+		return &multipart.FileHeader{
+			Filename: name,
+			Header:   textproto.MIMEHeader{"Content-Type": {"text/plain"}},
+		}
+	}
+
+	t.Run("streams file and records owner and checksum", func(t *testing.T) {
+		user := createTestUserForFiles(t, db, "upload@example.com")
+		ctx := context.WithValue(context.Background(), auth.UserIDContextKey, user.ID)
+		content := "streamed upload content"
+
+		file, err := svc.UploadFile(ctx, strings.NewReader(content), newHeader("stream.txt"))
+		if err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+
+		sum := sha256.Sum256([]byte(content))
+		var stored models.File
+		if err := db.First(&stored, file.ID).Error; err != nil {
+			t.Fatalf("Failed to load uploaded file: %v", err)
+		}
+		if stored.Checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("Expected checksum %x, got: %s", sum, stored.Checksum)
+		}
+		if stored.UserID != user.ID {
+			t.Errorf("Expected UserID %d, got: %d", user.ID, stored.UserID)
+		}
+		if stored.FileSize != int64(len(content)) {
+			t.Errorf("Expected FileSize %d, got: %d", len(content), stored.FileSize)
+		}
+	})
+
+	t.Run("rejects file over the size limit", func(t *testing.T) {
+		svc.maxFileSize = 8
+		defer func() { svc.maxFileSize = 0 }()
+
+		var before int64
+		db.Model(&models.File{}).Count(&before)
+
+		_, err := svc.UploadFile(context.Background(), strings.NewReader("more than eight bytes"), newHeader("large.txt"))
+		if !errors.Is(err, storage.ErrFileTooLarge) {
+			t.Errorf("Expected ErrFileTooLarge, got: %v", err)
+		}
+
+		var after int64
+		db.Model(&models.File{}).Count(&after)
+		if after != before {
+			t.Errorf("Expected no file row for a rejected upload, got %d new", after-before)
+		}
+	})
+}
@@ -2,6 +2,9 @@ package services
 
 import (
 	"testing"
+	"time"
+
+	"react-golang-starter/internal/storage"
 )
 
 // ============ File Service Error Tests ============
@@ -71,3 +74,84 @@ func TestFileService_GetStorageType_ReturnsDatabase(t *testing.T) {
 		t.Errorf("GetStorageType() = %q, want 'database'", storageType)
 	}
 }
+
+// ============ FileService Driver Selection Tests ============
+
+func TestNewFileService_LocalDriver(t *testing.T) {
+	fs, err := NewFileService(&storage.Config{
+		Driver:     storage.DriverLocal,
+		LocalRoot:  t.TempDir(),
+		SigningKey: []byte("test-signing-key"),
+		URLExpiry:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewFileService() error = %v", err)
+	}
+	if fs.localStorage == nil {
+		t.Fatal("localStorage should be initialized for the local driver")
+	}
+	if storageType := fs.GetStorageType(); storageType != "local" {
+		t.Errorf("GetStorageType() = %q, want 'local'", storageType)
+	}
+}
+
+func TestNewFileService_ServesLocalFilesUnderOtherDrivers(t *testing.T) {
+	fs, err := NewFileService(&storage.Config{
+		Driver:     storage.DriverDatabase,
+		LocalRoot:  t.TempDir(),
+		SigningKey: []byte("test-signing-key"),
+		URLExpiry:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewFileService() error = %v", err)
+	}
+	if fs.localStorage == nil {
+		t.Error("localStorage should be initialized when configured, so existing local files stay reachable")
+	}
+	if storageType := fs.GetStorageType(); storageType != "database" {
+		t.Errorf("GetStorageType() = %q, want 'database'", storageType)
+	}
+
+	// Without a signing key local storage is not configured, which only matters for the local driver
+	fs, err = NewFileService(&storage.Config{Driver: storage.DriverDatabase, LocalRoot: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewFileService() error = %v", err)
+	}
+	if fs.localStorage != nil {
+		t.Error("localStorage should not be initialized without a signing key")
+	}
+}
+
+func TestNewFileService_InvalidDriver(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_S3_BUCKET", "")
+
+	tests := []struct {
+		name string
+		cfg  *storage.Config
+	}{
+		{"unknown driver", &storage.Config{Driver: "ftp"}},
+		{"s3 without configuration", &storage.Config{Driver: storage.DriverS3}},
+		{"local without signing key", &storage.Config{Driver: storage.DriverLocal, LocalRoot: t.TempDir()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFileService(tt.cfg); err == nil {
+				t.Error("NewFileService() should return error")
+			}
+		})
+	}
+}
+
+func TestNewFileService_DatabaseDriver(t *testing.T) {
+	fs, err := NewFileService(&storage.Config{Driver: storage.DriverDatabase})
+	if err != nil {
+		t.Fatalf("NewFileService() error = %v", err)
+	}
+	if storageType := fs.GetStorageType(); storageType != "database" {
+		t.Errorf("GetStorageType() = %q, want 'database'", storageType)
+	}
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"time"
+
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Resumable upload errors
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match")
+	ErrInvalidUploadLength  = errors.New("upload length must not be negative")
+	ErrUploadAssembling     = errors.New("upload is being assembled by another request")
+)
+
+// UploadTypeError is returned when the assembled content of an upload is of a type that is
+// not allowed. The upload is removed.
+type UploadTypeError struct {
+	ContentType string
+}
+
+func (e *UploadTypeError) Error() string {
+	return fmt.Sprintf("file type '%s' is not allowed", e.ContentType)
+}
+
+// uploadChunkSize is the size of the pieces resumable upload content is stored in. Each piece
+// is committed as it arrives, so a dropped connection loses at most one piece.
+const uploadChunkSize = 1 << 20
+
+// DefaultUploadExpiry is how long a resumable upload is kept without receiving content
+const DefaultUploadExpiry = 24 * time.Hour
+
+// uploadAssemblyTimeout is how long an assembly claim holds; a claim older than this was
+// left by a request that died and may be taken over
+const uploadAssemblyTimeout = 10 * time.Minute
+
+// CreateUpload starts a resumable upload of length bytes for a user. metadata is the raw
+// Upload-Metadata header, kept to return it as received. An empty upload completes at once.
+func (fs *FileService) CreateUpload(ctx context.Context, userID uint, length int64, fileName, contentType, metadata string) (*models.FileUpload, error) {
+	if length < 0 {
+		return nil, ErrInvalidUploadLength
+	}
+	if fs.maxFileSize > 0 && length > fs.maxFileSize {
+		return nil, storage.ErrFileTooLarge
+	}
+
+	now := time.Now().UTC()
+	upload := &models.FileUpload{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Length:      length,
+		Metadata:    metadata,
+		ExpiresAt:   now.Add(fs.getUploadExpiry()).Format(time.RFC3339),
+		CreatedAt:   now.Format(time.RFC3339),
+		UpdatedAt:   now.Format(time.RFC3339),
+	}
+
+	if err := fs.db.WithContext(ctx).Create(upload).Error; err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	// There is no content to check the type of
+	if upload.IsComplete() {
+		if err := fs.completeUpload(ctx, upload, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return upload, nil
+}
+
+// GetUpload returns a user's resumable upload. Expired uploads are not found.
+func (fs *FileService) GetUpload(ctx context.Context, uploadID string, userID uint) (*models.FileUpload, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return nil, ErrUploadNotFound
+	}
+
+	var upload models.FileUpload
+	err := fs.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND expires_at > ?", uploadID, userID, time.Now().UTC().Format(time.RFC3339)).
+		First(&upload).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve upload: %w", err)
+	}
+
+	return &upload, nil
+}
+
+// WriteUpload appends the content read from r to an upload, starting at offset, which must
+// be the upload's current offset. Content is stored in pieces as it arrives, so after a
+// dropped connection the client resumes from the stored offset. Content past the upload's
+// length fails with storage.ErrFileTooLarge. When the last byte arrives the upload is
+// assembled into a File with the active storage backend, unless allowType (nil allows any)
+// rejects the type sniffed from the start of the content: then the upload is removed and
+// an *UploadTypeError returned.
+func (fs *FileService) WriteUpload(ctx context.Context, upload *models.FileUpload, offset int64, r io.Reader, allowType func(contentType string) bool) error {
+	if offset != upload.Offset {
+		return ErrUploadOffsetMismatch
+	}
+	if upload.IsComplete() {
+		// Retry an assembly that failed after the last byte arrived
+		if upload.FileID == nil {
+			return fs.completeUpload(ctx, upload, allowType)
+		}
+		return nil
+	}
+
+	r = storage.LimitReader(r, upload.Length-upload.Offset)
+	buf := make([]byte, uploadChunkSize)
+	var readErr error
+	for !upload.IsComplete() {
+		var n int
+		n, readErr = io.ReadFull(r, buf)
+		if n > 0 {
+			if err := fs.appendUploadChunk(ctx, upload, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			readErr = nil
+			break
+		}
+		if readErr != nil {
+			readErr = fmt.Errorf("failed to read upload content: %w", readErr)
+			break
+		}
+	}
+
+	// Content that filled the upload is assembled even when the client sent more
+	if upload.IsComplete() {
+		if err := fs.completeUpload(ctx, upload, allowType); err != nil {
+			return err
+		}
+	}
+	return readErr
+}
+
+// appendUploadChunk stores a piece of content at the upload's offset and advances the offset.
+// The offset check makes concurrent writes to the same upload fail instead of interleaving.
+func (fs *FileService) appendUploadChunk(ctx context.Context, upload *models.FileUpload, data []byte) error {
+	now := time.Now().UTC()
+	expiresAt := now.Add(fs.getUploadExpiry()).Format(time.RFC3339)
+
+	err := fs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.FileUpload{}).
+			Where("id = ? AND upload_offset = ?", upload.ID, upload.Offset).
+			Updates(map[string]interface{}{
+				"upload_offset": gorm.Expr("upload_offset + ?", len(data)),
+				"expires_at":    expiresAt,
+				"updated_at":    now.Format(time.RFC3339),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update upload offset: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrUploadOffsetMismatch
+		}
+
+		chunk := &models.FileUploadChunk{
+			UploadID: upload.ID,
+			Offset:   upload.Offset,
+			Data:     data,
+		}
+		if err := tx.Create(chunk).Error; err != nil {
+			return fmt.Errorf("failed to store upload content: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	upload.Offset += int64(len(data))
+	upload.ExpiresAt = expiresAt
+	return nil
+}
+
+// completeUpload assembles an upload's content into a File owned by the uploader, then
+// removes the content. The assembly is claimed first, so concurrent requests completing the
+// same upload fail with ErrUploadAssembling instead of assembling it twice.
+func (fs *FileService) completeUpload(ctx context.Context, upload *models.FileUpload, allowType func(contentType string) bool) error {
+	if err := fs.claimUploadAssembly(ctx, upload); err != nil {
+		return err
+	}
+
+	content := bufio.NewReaderSize(&uploadChunkReader{db: fs.db.WithContext(ctx), uploadID: upload.ID}, 512)
+	if allowType != nil {
+		// Validate file type using magic bytes of the assembled content, as for regular uploads
+		head, err := content.Peek(int(min(upload.Length, 512)))
+		if err != nil && err != io.EOF {
+			fs.releaseUploadAssembly(ctx, upload)
+			return fmt.Errorf("failed to read upload content: %w", err)
+		}
+		if detectedType := http.DetectContentType(head); !allowType(detectedType) {
+			if err := fs.DeleteUpload(ctx, upload); err != nil {
+				return err
+			}
+			return &UploadTypeError{ContentType: detectedType}
+		}
+	}
+
+	file, err := fs.assembleUpload(ctx, upload, content)
+	if err != nil {
+		fs.releaseUploadAssembly(ctx, upload)
+		return err
+	}
+
+	upload.FileID = &file.ID
+	log.Info().Str("upload_id", upload.ID).Uint("file_id", file.ID).Int64("size", file.FileSize).Msg("resumable upload completed")
+	return nil
+}
+
+// assembleUpload stores an upload's content as a File, links it to the upload and removes
+// the content
+func (fs *FileService) assembleUpload(ctx context.Context, upload *models.FileUpload, content io.Reader) (*models.File, error) {
+	header := &multipart.FileHeader{
+		Filename: upload.FileName,
+		Header:   textproto.MIMEHeader{"Content-Type": {upload.ContentType}},
+	}
+
+	file, err := fs.uploadFile(ctx, content, header, upload.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	if file.FileSize != upload.Length {
+		if err := fs.DeleteFile(ctx, file.ID); err != nil {
+			log.Warn().Err(err).Uint("file_id", file.ID).Msg("failed to remove incomplete assembled upload")
+		}
+		return nil, fmt.Errorf("assembled upload is %d bytes, expected %d", file.FileSize, upload.Length)
+	}
+
+	err = fs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.FileUpload{}).Where("id = ?", upload.ID).Updates(map[string]interface{}{
+			"file_id":    file.ID,
+			"updated_at": time.Now().UTC().Format(time.RFC3339),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("upload_id = ?", upload.ID).Delete(&models.FileUploadChunk{}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete upload: %w", err)
+	}
+	return file, nil
+}
+
+// claimUploadAssembly marks an unassembled upload as being assembled. It fails with
+// ErrUploadAssembling while another request holds a claim younger than uploadAssemblyTimeout.
+func (fs *FileService) claimUploadAssembly(ctx context.Context, upload *models.FileUpload) error {
+	now := time.Now().UTC()
+	result := fs.db.WithContext(ctx).Model(&models.FileUpload{}).
+		Where("id = ? AND file_id IS NULL AND (assembling_at IS NULL OR assembling_at <= ?)",
+			upload.ID, now.Add(-uploadAssemblyTimeout).Format(time.RFC3339)).
+		Update("assembling_at", now.Format(time.RFC3339))
+	if result.Error != nil {
+		return fmt.Errorf("failed to claim upload assembly: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUploadAssembling
+	}
+	return nil
+}
+
+// releaseUploadAssembly drops the assembly claim of an upload whose assembly failed, so a
+// retry can assemble it
+func (fs *FileService) releaseUploadAssembly(ctx context.Context, upload *models.FileUpload) {
+	err := fs.db.WithContext(context.WithoutCancel(ctx)).Model(&models.FileUpload{}).
+		Where("id = ?", upload.ID).
+		Update("assembling_at", nil).Error
+	if err != nil {
+		log.Warn().Err(err).Str("upload_id", upload.ID).Msg("failed to release upload assembly claim")
+	}
+}
+
+// DeleteUpload removes a resumable upload and its content. A file assembled from a
+// completed upload is kept.
+func (fs *FileService) DeleteUpload(ctx context.Context, upload *models.FileUpload) error {
+	err := fs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("upload_id = ?", upload.ID).Delete(&models.FileUploadChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.FileUpload{}, "id = ?", upload.ID).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
+
+// CleanupExpiredUploads removes resumable uploads that received no content before they
+// expired, with their content, and returns how many were removed
+func (fs *FileService) CleanupExpiredUploads(ctx context.Context) (int, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	var removed int64
+
+	err := fs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&models.FileUpload{}).Select("id").Where("expires_at <= ?", now)
+		if err := tx.Where("upload_id IN (?)", expired).Delete(&models.FileUploadChunk{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("expires_at <= ?", now).Delete(&models.FileUpload{})
+		removed = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up expired uploads: %w", err)
+	}
+
+	if removed > 0 {
+		log.Info().Int64("uploads", removed).Msg("removed expired resumable uploads")
+	}
+	return int(removed), nil
+}
+
+// StartUploadCleanup removes expired resumable uploads at the given interval until ctx is
+// done. It stands in for the cleanup job when the job queue is not running.
+func (fs *FileService) StartUploadCleanup(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("resumable upload cleanup stopped")
+				return
+			case <-ticker.C:
+				if _, err := fs.CleanupExpiredUploads(ctx); err != nil {
+					log.Error().Err(err).Msg("resumable upload cleanup failed")
+				}
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", interval).Msg("resumable upload cleanup started")
+}
+
+// getUploadExpiry returns how long an upload is kept without receiving content
+func (fs *FileService) getUploadExpiry() time.Duration {
+	if fs.uploadExpiry > 0 {
+		return fs.uploadExpiry
+	}
+	return DefaultUploadExpiry
+}
+
+// uploadChunkReader reads a resumable upload's content chunk by chunk, in offset order
+type uploadChunkReader struct {
+	db       *gorm.DB
+	uploadID string
+	offset   int64
+	buf      []byte
+}
+
+func (u *uploadChunkReader) Read(p []byte) (int, error) {
+	if len(u.buf) == 0 {
+		var chunk models.FileUploadChunk
+		err := u.db.Where("upload_id = ? AND chunk_offset = ?", u.uploadID, u.offset).Take(&chunk).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read upload content: %w", err)
+		}
+		if len(chunk.Data) == 0 {
+			return 0, io.EOF
+		}
+		u.buf = chunk.Data
+		u.offset += int64(len(chunk.Data))
+	}
+
+	n := copy(p, u.buf)
+	u.buf = u.buf[n:]
+	return n, nil
+}
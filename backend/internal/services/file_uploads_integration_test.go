@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/storage"
+)
+
+func TestFileService_ResumableUpload_Integration(t *testing.T) {
+	svc, db, cleanup := testFileServiceSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := createTestUserForFiles(t, db, "resumable@example.com")
+	content := []byte(strings.Repeat("resumable upload content\n", 100_000)) // > 2 chunks
+
+	upload, err := svc.CreateUpload(ctx, user.ID, int64(len(content)), "big.txt", "text/plain", "filename YmlnLnR4dA==")
+	if err != nil {
+		t.Fatalf("CreateUpload failed: %v", err)
+	}
+
+	t.Run("write resumes from the stored offset", func(t *testing.T) {
+		// A dropped connection after part of the content keeps what arrived
+		half := int64(len(content) / 2)
+		err := svc.WriteUpload(ctx, upload, 0, io.MultiReader(bytes.NewReader(content[:half]), failingUploadReader{}), nil)
+		if err == nil {
+			t.Fatal("Expected error from a dropped connection")
+		}
+
+		stored, err := svc.GetUpload(ctx, upload.ID, user.ID)
+		if err != nil {
+			t.Fatalf("GetUpload failed: %v", err)
+		}
+		if stored.Offset != half {
+			t.Fatalf("Expected offset %d after dropped connection, got: %d", half, stored.Offset)
+		}
+
+		if err := svc.WriteUpload(ctx, stored, 0, bytes.NewReader(content), nil); !errors.Is(err, ErrUploadOffsetMismatch) {
+			t.Errorf("Expected ErrUploadOffsetMismatch for a stale offset, got: %v", err)
+		}
+
+		if err := svc.WriteUpload(ctx, stored, half, bytes.NewReader(content[half:]), nil); err != nil {
+			t.Fatalf("WriteUpload failed: %v", err)
+		}
+		if stored.FileID == nil {
+			t.Fatal("Expected completed upload to be assembled into a file")
+		}
+
+		reader, file, err := svc.DownloadFile(ctx, *stored.FileID)
+		if err != nil {
+			t.Fatalf("DownloadFile failed: %v", err)
+		}
+		defer reader.Close()
+		downloaded, _ := io.ReadAll(reader)
+		if !bytes.Equal(downloaded, content) {
+			t.Errorf("Assembled file differs from the uploaded content (%d bytes, want %d)", len(downloaded), len(content))
+		}
+		if file.UserID != user.ID {
+			t.Errorf("Expected assembled file owned by %d, got: %d", user.ID, file.UserID)
+		}
+
+		var chunks int64
+		db.Model(&models.FileUploadChunk{}).Where("upload_id = ?", upload.ID).Count(&chunks)
+		if chunks != 0 {
+			t.Errorf("Expected upload content removed after assembly, got %d chunks", chunks)
+		}
+	})
+
+	t.Run("checks the type of the assembled content", func(t *testing.T) {
+		// The content is checked however it arrived, not only its first piece
+		exe := append([]byte("MZ"), make([]byte, 600)...)
+		onlyText := func(contentType string) bool { return strings.HasPrefix(contentType, "text/plain") }
+
+		typed, err := svc.CreateUpload(ctx, user.ID, int64(len(exe)), "tool.txt", "text/plain", "")
+		if err != nil {
+			t.Fatalf("CreateUpload failed: %v", err)
+		}
+		err = svc.WriteUpload(ctx, typed, 0, bytes.NewReader(exe), onlyText)
+		var typeErr *UploadTypeError
+		if !errors.As(err, &typeErr) {
+			t.Fatalf("Expected UploadTypeError, got: %v", err)
+		}
+		if _, err := svc.GetUpload(ctx, typed.ID, user.ID); !errors.Is(err, ErrUploadNotFound) {
+			t.Errorf("Expected rejected upload removed, got: %v", err)
+		}
+	})
+
+	t.Run("completes an upload once", func(t *testing.T) {
+		claimed, err := svc.CreateUpload(ctx, user.ID, 4, "claimed.txt", "text/plain", "")
+		if err != nil {
+			t.Fatalf("CreateUpload failed: %v", err)
+		}
+		// Another request is assembling the upload
+		db.Model(&models.FileUpload{}).Where("id = ?", claimed.ID).
+			Update("assembling_at", time.Now().UTC().Format(time.RFC3339))
+
+		if err := svc.WriteUpload(ctx, claimed, 0, strings.NewReader("text"), nil); !errors.Is(err, ErrUploadAssembling) {
+			t.Fatalf("Expected ErrUploadAssembling, got: %v", err)
+		}
+		if claimed.FileID != nil {
+			t.Error("Expected no file assembled while another request holds the claim")
+		}
+	})
+
+	t.Run("other users cannot access the upload", func(t *testing.T) {
+		other := createTestUserForFiles(t, db, "resumable-other@example.com")
+		if _, err := svc.GetUpload(ctx, upload.ID, other.ID); !errors.Is(err, ErrUploadNotFound) {
+			t.Errorf("Expected ErrUploadNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("rejects uploads over the size limit", func(t *testing.T) {
+		svc.maxFileSize = 8
+		defer func() { svc.maxFileSize = 0 }()
+
+		if _, err := svc.CreateUpload(ctx, user.ID, 9, "large.txt", "text/plain", ""); !errors.Is(err, storage.ErrFileTooLarge) {
+			t.Errorf("Expected ErrFileTooLarge, got: %v", err)
+		}
+	})
+
+	t.Run("cleanup removes expired uploads", func(t *testing.T) {
+		abandoned, err := svc.CreateUpload(ctx, user.ID, 10, "abandoned.txt", "text/plain", "")
+		if err != nil {
+			t.Fatalf("CreateUpload failed: %v", err)
+		}
+		if err := svc.WriteUpload(ctx, abandoned, 0, strings.NewReader("half"), nil); err != nil {
+			t.Fatalf("WriteUpload failed: %v", err)
+		}
+		db.Model(&models.FileUpload{}).Where("id = ?", abandoned.ID).
+			Update("expires_at", time.Now().Add(-time.Minute).UTC().Format(time.RFC3339))
+
+		if _, err := svc.GetUpload(ctx, abandoned.ID, user.ID); !errors.Is(err, ErrUploadNotFound) {
+			t.Errorf("Expected expired upload not found, got: %v", err)
+		}
+
+		removed, err := svc.CleanupExpiredUploads(ctx)
+		if err != nil {
+			t.Fatalf("CleanupExpiredUploads failed: %v", err)
+		}
+		if removed != 1 {
+			t.Errorf("Expected 1 removed upload, got: %d", removed)
+		}
+
+		var chunks int64
+		db.Model(&models.FileUploadChunk{}).Where("upload_id = ?", abandoned.ID).Count(&chunks)
+		if chunks != 0 {
+			t.Errorf("Expected expired upload content removed, got %d chunks", chunks)
+		}
+	})
+}
+
+// failingUploadReader fails like a dropped connection
+type failingUploadReader struct{}
+
+func (failingUploadReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
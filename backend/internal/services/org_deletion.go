@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"react-golang-starter/internal/cache"
+	"react-golang-starter/internal/jobs"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/websocket"
+
+	"github.com/rs/zerolog/log"
+	stripego "github.com/stripe/stripe-go/v76"
+)
+
+// Sentinel errors for organization deletion
+var (
+	ErrOrgPendingDeletion    = errors.New("organization is already scheduled for deletion")
+	ErrOrgNotPendingDeletion = errors.New("organization is not scheduled for deletion")
+)
+
+// orgExportRetentionMargin is how long an organization export stays downloadable after
+// the organization is purged
+const orgExportRetentionMargin = 7 * 24 * time.Hour
+
+// OrgDeletionConfig controls the organization deletion grace period
+type OrgDeletionConfig struct {
+	// GracePeriod is how long owners have to restore an organization (default: 30 days)
+	GracePeriod time.Duration
+}
+
+// DefaultOrgDeletionConfig returns the default deletion configuration
+func DefaultOrgDeletionConfig() *OrgDeletionConfig {
+	return &OrgDeletionConfig{
+		GracePeriod: 30 * 24 * time.Hour,
+	}
+}
+
+// ExportExpiry returns when an export requested at requestedAt expires: the end of the
+// grace period plus a margin, so the data outlives the organization
+func (c *OrgDeletionConfig) ExportExpiry(requestedAt time.Time) time.Time {
+	return requestedAt.Add(c.GracePeriod + orgExportRetentionMargin)
+}
+
+// LoadOrgDeletionConfig loads deletion configuration from environment variables
+func LoadOrgDeletionConfig() *OrgDeletionConfig {
+	config := DefaultOrgDeletionConfig()
+
+	// ORG_DELETION_GRACE_DAYS (default: 30)
+	if daysStr := os.Getenv("ORG_DELETION_GRACE_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days >= 0 {
+			config.GracePeriod = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	return config
+}
+
+// SubscriptionCanceler cancels and resumes billing subscriptions. Implemented by stripe.Service.
+type SubscriptionCanceler interface {
+	CancelSubscription(ctx context.Context, subscriptionID string, cancelAtPeriodEnd bool) (*stripego.Subscription, error)
+	ResumeSubscription(ctx context.Context, subscriptionID string) (*stripego.Subscription, error)
+}
+
+// SetDeletionConfig overrides the organization deletion configuration
+func (s *OrgService) SetDeletionConfig(config *OrgDeletionConfig) {
+	if config != nil {
+		s.deletionConfig = config
+	}
+}
+
+// SetSubscriptionCanceler sets the billing provider used to cancel subscriptions when
+// deletion is scheduled and to resume them on restore
+func (s *OrgService) SetSubscriptionCanceler(canceler SubscriptionCanceler) {
+	s.subscriptionCanceler = canceler
+}
+
+// ScheduleDeletion marks an organization for deletion after the grace period.
+// The subscription is set to cancel at the end of its billing period, an export of the
+// organization's data is generated and all members are notified.
+func (s *OrgService) ScheduleDeletion(ctx context.Context, org *models.Organization, requestedByUserID uint) error {
+	if org.IsPendingDeletion() {
+		return ErrOrgPendingDeletion
+	}
+
+	// Stop renewals before marking the org so a Stripe failure leaves nothing to undo
+	if s.hasSubscription(org) {
+		_, err := s.subscriptionCanceler.CancelSubscription(ctx, *org.StripeSubscriptionID, true)
+		if err != nil && !isStripeResourceMissing(err) {
+			return fmt.Errorf("failed to cancel subscription: %w", err)
+		}
+	}
+
+	now := time.Now()
+	scheduledAt := now.Add(s.deletionConfig.GracePeriod)
+
+	org.DeletionRequestedAt = &now
+	org.DeletionRequestedByUserID = &requestedByUserID
+	org.DeletionScheduledAt = &scheduledAt
+
+	if err := s.orgRepo.Update(ctx, org); err != nil {
+		org.DeletionRequestedAt = nil
+		org.DeletionRequestedByUserID = nil
+		org.DeletionScheduledAt = nil
+		s.resumeSubscription(ctx, org)
+		return err
+	}
+	s.logAuditAs(ctx, org.ID, &requestedByUserID, models.AuditTargetOrganization, &org.ID, models.AuditActionDeleteSchedule, map[string]interface{}{
+		"scheduled_at": scheduledAt.Format(time.RFC3339),
+	})
+
+	_ = cache.InvalidateOrganization(ctx, org.Slug, org.ID)
+
+	s.broadcastToOrgMembers(ctx, org.ID, websocket.MessageTypeOrgUpdate, websocket.OrgUpdatePayload{
+		OrgSlug: org.Slug,
+		Event:   "deletion_scheduled",
+	})
+
+	s.requestOrgExport(ctx, org, requestedByUserID)
+	s.notifyDeletionScheduled(ctx, org)
+
+	return nil
+}
+
+// RestoreOrganization cancels a pending deletion and resumes the subscription
+func (s *OrgService) RestoreOrganization(ctx context.Context, org *models.Organization) error {
+	if !org.IsPendingDeletion() {
+		return ErrOrgNotPendingDeletion
+	}
+
+	// A subscription that already ended during the grace period is gone; restore the org anyway
+	if s.hasSubscription(org) {
+		_, err := s.subscriptionCanceler.ResumeSubscription(ctx, *org.StripeSubscriptionID)
+		if err != nil && !isStripeResourceMissing(err) {
+			return fmt.Errorf("failed to resume subscription: %w", err)
+		}
+	}
+
+	previous := *org
+	org.DeletionRequestedAt = nil
+	org.DeletionRequestedByUserID = nil
+	org.DeletionScheduledAt = nil
+
+	if err := s.orgRepo.Update(ctx, org); err != nil {
+		*org = previous
+		if s.hasSubscription(org) {
+			if _, cancelErr := s.subscriptionCanceler.CancelSubscription(ctx, *org.StripeSubscriptionID, true); cancelErr != nil {
+				log.Error().Err(cancelErr).Uint("org_id", org.ID).Msg("failed to re-cancel subscription after restore failed")
+			}
+		}
+		return err
+	}
+	s.logAudit(ctx, org.ID, models.AuditTargetOrganization, &org.ID, models.AuditActionRestore, nil)
+
+	_ = cache.InvalidateOrganization(ctx, org.Slug, org.ID)
+
+	s.broadcastToOrgMembers(ctx, org.ID, websocket.MessageTypeOrgUpdate, websocket.OrgUpdatePayload{
+		OrgSlug: org.Slug,
+		Event:   "restored",
+	})
+
+	return nil
+}
+
+// PurgeOrganization permanently deletes the organization. The subscription normally ended
+// at period end during the grace period; one still active is canceled immediately.
+func (s *OrgService) PurgeOrganization(ctx context.Context, org *models.Organization) error {
+	if s.hasSubscription(org) {
+		_, err := s.subscriptionCanceler.CancelSubscription(ctx, *org.StripeSubscriptionID, false)
+		if err != nil && !isStripeResourceMissing(err) {
+			return fmt.Errorf("failed to cancel subscription: %w", err)
+		}
+	}
+
+	return s.DeleteOrganization(ctx, org)
+}
+
+// PurgeDueOrganizations permanently deletes organizations whose grace period has ended.
+// Returns the number of organizations purged.
+func (s *OrgService) PurgeDueOrganizations(ctx context.Context) (int, error) {
+	orgs, err := s.orgRepo.FindDueForDeletion(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range orgs {
+		org := &orgs[i]
+		if err := s.PurgeOrganization(ctx, org); err != nil {
+			// Leave it scheduled so the next run retries
+			log.Error().Err(err).Uint("org_id", org.ID).Str("slug", org.Slug).Msg("failed to purge organization")
+			continue
+		}
+		purged++
+		log.Info().Uint("org_id", org.ID).Str("slug", org.Slug).Msg("organization purged after deletion grace period")
+	}
+
+	return purged, nil
+}
+
+// StartDeletionPurge purges organizations whose deletion grace period has ended at the given
+// interval until ctx is done. It stands in for the purge job when the job queue is not running.
+func (s *OrgService) StartDeletionPurge(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("organization deletion purge shutting down")
+				return
+			case <-ticker.C:
+				if _, err := s.PurgeDueOrganizations(ctx); err != nil {
+					log.Error().Err(err).Msg("organization deletion purge failed")
+				}
+			}
+		}
+	}()
+
+	log.Info().
+		Dur("interval", interval).
+		Dur("grace_period", s.deletionConfig.GracePeriod).
+		Msg("organization deletion purge started")
+}
+
+// hasSubscription reports whether the organization has a subscription the canceler can act on
+func (s *OrgService) hasSubscription(org *models.Organization) bool {
+	return s.subscriptionCanceler != nil && org.StripeSubscriptionID != nil && *org.StripeSubscriptionID != ""
+}
+
+// resumeSubscription undoes a cancel-at-period-end after scheduling deletion failed
+func (s *OrgService) resumeSubscription(ctx context.Context, org *models.Organization) {
+	if !s.hasSubscription(org) {
+		return
+	}
+	if _, err := s.subscriptionCanceler.ResumeSubscription(ctx, *org.StripeSubscriptionID); err != nil && !isStripeResourceMissing(err) {
+		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to resume subscription after scheduling deletion failed")
+	}
+}
+
+// requestOrgExport creates an export record for the requesting owner and queues the export job
+func (s *OrgService) requestOrgExport(ctx context.Context, org *models.Organization, userID uint) {
+	if s.db == nil {
+		return
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		log.Warn().Err(err).Uint("org_id", org.ID).Msg("failed to load requester for organization export")
+		return
+	}
+
+	requestedAt := time.Now()
+	now := requestedAt.Format(time.RFC3339)
+	export := models.DataExport{
+		UserID:         userID,
+		OrganizationID: &org.ID,
+		Status:         models.ExportStatusPending,
+		RequestedAt:    now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.db.WithContext(ctx).Create(&export).Error; err != nil {
+		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to create organization export record")
+		return
+	}
+
+	if err := jobs.EnqueueOrgDataExport(ctx, org.ID, userID, user.Email, export.ID, s.deletionConfig.ExportExpiry(requestedAt)); err != nil {
+		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to queue organization export job")
+		s.db.WithContext(ctx).Model(&export).Updates(map[string]interface{}{
+			"status":        models.ExportStatusFailed,
+			"error_message": err.Error(),
+		})
+	}
+}
+
+// notifyDeletionScheduled emails every member about the pending deletion
+func (s *OrgService) notifyDeletionScheduled(ctx context.Context, org *models.Organization) {
+	if !jobs.IsAvailable() {
+		return
+	}
+
+	members, err := s.memberRepo.FindByOrgID(ctx, org.ID)
+	if err != nil {
+		log.Warn().Err(err).Uint("org_id", org.ID).Msg("failed to load members for deletion notice")
+		return
+	}
+
+	scheduledFor := org.DeletionScheduledAt.Format("January 2, 2006")
+	notices := make([]jobs.SendOrgDeletionEmailArgs, 0, len(members))
+	for _, m := range members {
+		if m.User == nil || m.Status != models.MemberStatusActive {
+			continue
+		}
+		notices = append(notices, jobs.SendOrgDeletionEmailArgs{
+			OrganizationID: org.ID,
+			OrgName:        org.Name,
+			OrgSlug:        org.Slug,
+			UserID:         m.UserID,
+			Email:          m.User.Email,
+			Name:           m.User.Name,
+			ScheduledFor:   scheduledFor,
+			CanRestore:     m.Role == models.OrgRoleOwner,
+		})
+	}
+
+	if err := jobs.EnqueueOrgDeletionEmails(ctx, notices); err != nil {
+		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to queue organization deletion emails")
+	}
+}
+
+// isStripeResourceMissing reports whether a Stripe error means the object no longer exists
+func isStripeResourceMissing(err error) bool {
+	var stripeErr *stripego.Error
+	return errors.As(err, &stripeErr) && stripeErr.Code == stripego.ErrorCodeResourceMissing
+}
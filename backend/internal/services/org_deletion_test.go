@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stripego "github.com/stripe/stripe-go/v76"
+)
+
+// fakeSubscriptionCanceler records cancellation and resume calls for assertions
+type fakeSubscriptionCanceler struct {
+	calls       []string
+	atPeriodEnd []bool
+	resumes     []string
+	err         error
+}
+
+func (f *fakeSubscriptionCanceler) CancelSubscription(ctx context.Context, subscriptionID string, cancelAtPeriodEnd bool) (*stripego.Subscription, error) {
+	f.calls = append(f.calls, subscriptionID)
+	f.atPeriodEnd = append(f.atPeriodEnd, cancelAtPeriodEnd)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if cancelAtPeriodEnd {
+		return &stripego.Subscription{ID: subscriptionID, Status: stripego.SubscriptionStatusActive, CancelAtPeriodEnd: true}, nil
+	}
+	return &stripego.Subscription{ID: subscriptionID, Status: stripego.SubscriptionStatusCanceled}, nil
+}
+
+func (f *fakeSubscriptionCanceler) ResumeSubscription(ctx context.Context, subscriptionID string) (*stripego.Subscription, error) {
+	f.resumes = append(f.resumes, subscriptionID)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &stripego.Subscription{ID: subscriptionID, Status: stripego.SubscriptionStatusActive}, nil
+}
+
+// ============ Deletion Config Tests ============
+
+func TestLoadOrgDeletionConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		graceDays string
+		wantGrace time.Duration
+	}{
+		{
+			name:      "defaults",
+			wantGrace: 30 * 24 * time.Hour,
+		},
+		{
+			name:      "custom values",
+			graceDays: "7",
+			wantGrace: 7 * 24 * time.Hour,
+		},
+		{
+			name:      "invalid values fall back to defaults",
+			graceDays: "soon",
+			wantGrace: 30 * 24 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ORG_DELETION_GRACE_DAYS", tt.graceDays)
+
+			config := LoadOrgDeletionConfig()
+
+			assert.Equal(t, tt.wantGrace, config.GracePeriod)
+		})
+	}
+}
+
+func TestOrgDeletionConfig_ExportExpiry(t *testing.T) {
+	config := &OrgDeletionConfig{GracePeriod: 14 * 24 * time.Hour}
+	requestedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	expiry := config.ExportExpiry(requestedAt)
+
+	assert.True(t, expiry.After(requestedAt.Add(config.GracePeriod)), "exports outlive the grace period")
+	assert.Equal(t, requestedAt.Add(config.GracePeriod+orgExportRetentionMargin), expiry)
+}
+
+// ============ ScheduleDeletion / RestoreOrganization Tests ============
+
+func TestOrgService_ScheduleDeletion(t *testing.T) {
+	svc, orgRepo, _, _, _, _ := newTestOrgService()
+	svc.SetDeletionConfig(&OrgDeletionConfig{GracePeriod: 48 * time.Hour})
+
+	org := &models.Organization{ID: 1, Name: "Test", Slug: "test"}
+	orgRepo.AddOrganization(org)
+
+	before := time.Now()
+	require.NoError(t, svc.ScheduleDeletion(context.Background(), org, 42))
+
+	require.True(t, org.IsPendingDeletion())
+	require.NotNil(t, org.DeletionRequestedByUserID)
+	assert.Equal(t, uint(42), *org.DeletionRequestedByUserID)
+	assert.WithinDuration(t, before.Add(48*time.Hour), *org.DeletionScheduledAt, time.Minute)
+
+	stored, err := orgRepo.FindByID(context.Background(), 1)
+	require.NoError(t, err)
+	assert.True(t, stored.IsPendingDeletion(), "schedule should be persisted")
+
+	// Scheduling twice is rejected
+	err = svc.ScheduleDeletion(context.Background(), org, 42)
+	assert.ErrorIs(t, err, ErrOrgPendingDeletion)
+}
+
+func TestOrgService_ScheduleDeletion_UpdateError(t *testing.T) {
+	svc, orgRepo, _, _, _, _ := newTestOrgService()
+	org := &models.Organization{ID: 1, Name: "Test", Slug: "test"}
+	orgRepo.AddOrganization(org)
+	orgRepo.UpdateErr = errors.New("db down")
+
+	err := svc.ScheduleDeletion(context.Background(), org, 1)
+
+	require.Error(t, err)
+	assert.False(t, org.IsPendingDeletion(), "failed schedule should not leave the org marked")
+}
+
+func TestOrgService_RestoreOrganization(t *testing.T) {
+	svc, orgRepo, _, _, _, _ := newTestOrgService()
+
+	org := &models.Organization{ID: 1, Name: "Test", Slug: "test"}
+	orgRepo.AddOrganization(org)
+
+	// Not pending yet
+	err := svc.RestoreOrganization(context.Background(), org)
+	assert.ErrorIs(t, err, ErrOrgNotPendingDeletion)
+
+	require.NoError(t, svc.ScheduleDeletion(context.Background(), org, 1))
+	require.NoError(t, svc.RestoreOrganization(context.Background(), org))
+
+	assert.False(t, org.IsPendingDeletion())
+	assert.Nil(t, org.DeletionRequestedAt)
+	assert.Nil(t, org.DeletionRequestedByUserID)
+
+	stored, err := orgRepo.FindByID(context.Background(), 1)
+	require.NoError(t, err)
+	assert.False(t, stored.IsPendingDeletion(), "restore should be persisted")
+}
+
+func TestOrgService_ScheduleAndRestore_Subscription(t *testing.T) {
+	svc, orgRepo, _, _, _, _ := newTestOrgService()
+	canceler := &fakeSubscriptionCanceler{}
+	svc.SetSubscriptionCanceler(canceler)
+
+	subID := "sub_123"
+	org := &models.Organization{ID: 1, Name: "Test", Slug: "test", StripeSubscriptionID: &subID}
+	orgRepo.AddOrganization(org)
+
+	require.NoError(t, svc.ScheduleDeletion(context.Background(), org, 1))
+	assert.Equal(t, []string{"sub_123"}, canceler.calls)
+	assert.Equal(t, []bool{true}, canceler.atPeriodEnd, "scheduling should cancel at period end, not immediately")
+	assert.Empty(t, canceler.resumes)
+
+	require.NoError(t, svc.RestoreOrganization(context.Background(), org))
+	assert.Equal(t, []string{"sub_123"}, canceler.resumes, "restore should resume the subscription")
+	assert.False(t, org.IsPendingDeletion())
+}
+
+func TestOrgService_ScheduleDeletion_CancelError(t *testing.T) {
+	svc, orgRepo, _, _, _, _ := newTestOrgService()
+	svc.SetSubscriptionCanceler(&fakeSubscriptionCanceler{err: errors.New("stripe unavailable")})
+
+	subID := "sub_123"
+	org := &models.Organization{ID: 1, Name: "Test", Slug: "test", StripeSubscriptionID: &subID}
+	orgRepo.AddOrganization(org)
+
+	err := svc.ScheduleDeletion(context.Background(), org, 1)
+
+	require.Error(t, err)
+	assert.False(t, org.IsPendingDeletion(), "org should not be scheduled while billing continues")
+}
+
+func TestOrgService_ScheduleDeletion_UpdateErrorResumesSubscription(t *testing.T) {
+	svc, orgRepo, _, _, _, _ := newTestOrgService()
+	canceler := &fakeSubscriptionCanceler{}
+	svc.SetSubscriptionCanceler(canceler)
+
+	subID := "sub_123"
+	org := &models.Organization{ID: 1, Name: "Test", Slug: "test", StripeSubscriptionID: &subID}
+	orgRepo.AddOrganization(org)
+	orgRepo.UpdateErr = errors.New("db down")
+
+	require.Error(t, svc.ScheduleDeletion(context.Background(), org, 1))
+	assert.Equal(t, []string{"sub_123"}, canceler.resumes, "cancellation should be undone when the schedule is not saved")
+}
+
+// ============ Purge Tests ============
+
+func TestOrgService_PurgeDueOrganizations_CancelFailureKeepsOrg(t *testing.T) {
+	svc, orgRepo, _, _, _, _ := newTestOrgService()
+	canceler := &fakeSubscriptionCanceler{err: errors.New("stripe unavailable")}
+	svc.SetSubscriptionCanceler(canceler)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+	dueSub := "sub_due"
+	laterSub := "sub_later"
+
+	orgRepo.AddOrganization(&models.Organization{ID: 1, Slug: "due", StripeSubscriptionID: &dueSub, DeletionScheduledAt: &past})
+	orgRepo.AddOrganization(&models.Organization{ID: 2, Slug: "later", StripeSubscriptionID: &laterSub, DeletionScheduledAt: &future})
+	orgRepo.AddOrganization(&models.Organization{ID: 3, Slug: "active"})
+
+	purged, err := svc.PurgeDueOrganizations(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, purged)
+	assert.Equal(t, []string{"sub_due"}, canceler.calls, "only due organizations should be canceled")
+
+	_, err = orgRepo.FindByID(context.Background(), 1)
+	assert.NoError(t, err, "org should remain scheduled for retry when cancellation fails")
+}
+
+func TestOrgService_PurgeDueOrganizations_FindError(t *testing.T) {
+	svc, orgRepo, _, _, _, _ := newTestOrgService()
+	orgRepo.FindDueForDeletionErr = errors.New("db down")
+
+	_, err := svc.PurgeDueOrganizations(context.Background())
+
+	assert.Error(t, err)
+}
+
+func TestIsStripeResourceMissing(t *testing.T) {
+	assert.True(t, isStripeResourceMissing(&stripego.Error{Code: stripego.ErrorCodeResourceMissing}))
+	assert.False(t, isStripeResourceMissing(&stripego.Error{Code: stripego.ErrorCodeCardDeclined}))
+	assert.False(t, isStripeResourceMissing(errors.New("network error")))
+}
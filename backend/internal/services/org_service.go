@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"react-golang-starter/internal/audit"
+	"react-golang-starter/internal/auth"
 	"react-golang-starter/internal/cache"
 	"react-golang-starter/internal/database"
 	"react-golang-starter/internal/models"
@@ -49,6 +51,10 @@ type OrgService struct {
 	invitationRepo repository.OrganizationInvitationRepository
 	subRepo        repository.SubscriptionRepository
 	userRepo       repository.UserRepository
+
+	deletionConfig       *OrgDeletionConfig
+	subscriptionCanceler SubscriptionCanceler
+	seatQuantityUpdater  SeatQuantityUpdater
 }
 
 // NewOrgService creates a new organization service using the global DB.
@@ -61,6 +67,7 @@ func NewOrgService(db *gorm.DB) *OrgService {
 		invitationRepo: repository.NewGormOrganizationInvitationRepository(db),
 		subRepo:        repository.NewGormSubscriptionRepository(db),
 		userRepo:       repository.NewGormUserRepository(db),
+		deletionConfig: DefaultOrgDeletionConfig(),
 	}
 }
 
@@ -81,6 +88,7 @@ func NewOrgServiceWithRepo(
 		invitationRepo: invitationRepo,
 		subRepo:        subRepo,
 		userRepo:       userRepo,
+		deletionConfig: DefaultOrgDeletionConfig(),
 	}
 }
 
@@ -116,6 +124,37 @@ func (s *OrgService) broadcastToOrgMembers(ctx context.Context, orgID uint, msgT
 	log.Debug().Uint("org_id", orgID).Int("member_count", len(userIDs)).Str("msg_type", string(msgType)).Msg("broadcasted to org members")
 }
 
+// revokeOrgTokens invalidates access tokens carrying this organization in their claims.
+// Failures are logged; the tokens still expire with the access token lifetime.
+func (s *OrgService) revokeOrgTokens(ctx context.Context, orgID, userID uint, reason string) {
+	if err := auth.RevokeOrgTokens(ctx, userID, orgID, reason); err != nil {
+		log.Error().Err(err).Uint("org_id", orgID).Uint("user_id", userID).Msg("failed to revoke org-scoped tokens")
+	}
+}
+
+// logAudit records an organization-scoped audit entry.
+// The actor is the authenticated user in ctx; actions without one (webhooks, jobs) are logged as system actions.
+func (s *OrgService) logAudit(ctx context.Context, orgID uint, targetType string, targetID *uint, action string, changes interface{}) {
+	var actorID *uint
+	if user, ok := auth.GetUserFromContext(ctx); ok && user != nil {
+		id := user.ID
+		actorID = &id
+	}
+	s.logAuditAs(ctx, orgID, actorID, targetType, targetID, action, changes)
+}
+
+// logAuditAs records an organization-scoped audit entry for an explicit actor
+func (s *OrgService) logAuditAs(ctx context.Context, orgID uint, actorID *uint, targetType string, targetID *uint, action string, changes interface{}) {
+	audit.LogOrgEntry(ctx, audit.OrgEntry{
+		OrganizationID: orgID,
+		ActorID:        actorID,
+		TargetType:     targetType,
+		TargetID:       targetID,
+		Action:         action,
+		Changes:        changes,
+	})
+}
+
 // CreateOrganization creates a new organization with the user as owner
 func (s *OrgService) CreateOrganization(ctx context.Context, userID uint, name, slug string) (*models.Organization, error) {
 	// Validate slug
@@ -168,6 +207,11 @@ func (s *OrgService) CreateOrganization(ctx context.Context, userID uint, name,
 		return nil, err
 	}
 
+	s.logAuditAs(ctx, org.ID, &userID, models.AuditTargetOrganization, &org.ID, models.AuditActionCreate, map[string]interface{}{
+		"name": org.Name,
+		"slug": org.Slug,
+	})
+
 	return &org, nil
 }
 
@@ -250,10 +294,15 @@ func (s *OrgService) GetUserMembership(ctx context.Context, orgID, userID uint)
 
 // UpdateOrganization updates organization details
 func (s *OrgService) UpdateOrganization(ctx context.Context, org *models.Organization, name string) error {
+	oldName := org.Name
 	org.Name = strings.TrimSpace(name)
 	if err := s.db.WithContext(ctx).Save(org).Error; err != nil {
 		return err
 	}
+	s.logAudit(ctx, org.ID, models.AuditTargetOrganization, &org.ID, models.AuditActionUpdate, map[string]interface{}{
+		"old_name": oldName,
+		"new_name": org.Name,
+	})
 	// Invalidate org cache after successful update
 	_ = cache.InvalidateOrganization(ctx, org.Slug, org.ID)
 
@@ -275,6 +324,9 @@ func (s *OrgService) DeleteOrganization(ctx context.Context, org *models.Organiz
 		Event:   "deleted",
 	})
 
+	// Load members before deleting so their org-scoped tokens can be revoked
+	members, _ := s.memberRepo.FindByOrgID(ctx, org.ID)
+
 	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Delete invitations
 		if err := tx.Where("organization_id = ?", org.ID).Delete(&models.OrganizationInvitation{}).Error; err != nil {
@@ -292,9 +344,15 @@ func (s *OrgService) DeleteOrganization(ctx context.Context, org *models.Organiz
 	if err != nil {
 		return err
 	}
+	s.logAudit(ctx, org.ID, models.AuditTargetOrganization, &org.ID, models.AuditActionDelete, map[string]interface{}{
+		"slug": org.Slug,
+	})
 	// Invalidate caches after successful deletion
 	_ = cache.InvalidateOrganization(ctx, org.Slug, org.ID)
 	_ = cache.InvalidateOrgMemberships(ctx, org.ID)
+	for _, m := range members {
+		s.revokeOrgTokens(ctx, org.ID, m.UserID, "org_deleted")
+	}
 	return nil
 }
 
@@ -303,7 +361,7 @@ func (s *OrgService) GetMembers(ctx context.Context, orgID uint) ([]models.Organ
 	return s.memberRepo.FindByOrgID(ctx, orgID)
 }
 
-// UpdateMemberRole updates a member's role
+// UpdateMemberRole updates a member's role. Suspended members must be reactivated first.
 func (s *OrgService) UpdateMemberRole(ctx context.Context, orgID, userID, actorUserID uint, newRole models.OrganizationRole) error {
 	if userID == actorUserID {
 		return ErrCannotChangeOwnRole
@@ -317,6 +375,9 @@ func (s *OrgService) UpdateMemberRole(ctx context.Context, orgID, userID, actorU
 		}
 		return err
 	}
+	if member.Status == models.MemberStatusSuspended {
+		return ErrMemberSuspended
+	}
 
 	// If demoting from owner, ensure there's another owner
 	if member.Role == models.OrgRoleOwner && newRole != models.OrgRoleOwner {
@@ -329,13 +390,19 @@ func (s *OrgService) UpdateMemberRole(ctx context.Context, orgID, userID, actorU
 		}
 	}
 
+	oldRole := member.Role
 	member.Role = newRole
 	if err := s.memberRepo.Update(ctx, member); err != nil {
 		return err
 	}
+	s.logAuditAs(ctx, orgID, &actorUserID, models.AuditTargetOrgMember, &userID, models.AuditActionRoleChange, map[string]interface{}{
+		"old_role": oldRole,
+		"new_role": newRole,
+	})
 
 	// Invalidate membership cache after role update
 	_ = cache.InvalidateMembership(ctx, orgID, userID)
+	s.revokeOrgTokens(ctx, orgID, userID, "role_change")
 
 	// Broadcast member update to all org members
 	org, err := s.orgRepo.FindByID(ctx, orgID)
@@ -376,9 +443,13 @@ func (s *OrgService) RemoveMember(ctx context.Context, orgID, userID uint) error
 	if err := s.memberRepo.Delete(ctx, member); err != nil {
 		return err
 	}
+	s.logAudit(ctx, orgID, models.AuditTargetOrgMember, &userID, models.AuditActionMemberRemove, map[string]interface{}{
+		"role": member.Role,
+	})
 
 	// Invalidate membership cache after removal
 	_ = cache.InvalidateMembership(ctx, orgID, userID)
+	s.revokeOrgTokens(ctx, orgID, userID, "member_removed")
 
 	// Broadcast member removal to all org members (including the removed user)
 	org, err := s.orgRepo.FindByID(ctx, orgID)
@@ -398,6 +469,7 @@ func (s *OrgService) RemoveMember(ctx context.Context, orgID, userID uint) error
 			})
 		}
 	}
+	s.requestSeatQuantitySync(ctx, orgID)
 
 	return nil
 }
@@ -454,6 +526,10 @@ func (s *OrgService) CreateInvitation(ctx context.Context, orgID, inviterID uint
 	if err := s.invitationRepo.Create(ctx, invitation); err != nil {
 		return nil, err
 	}
+	s.logAuditAs(ctx, orgID, &inviterID, models.AuditTargetInvitation, &invitation.ID, models.AuditActionInvite, map[string]interface{}{
+		"email": email,
+		"role":  role,
+	})
 
 	// Broadcast invitation sent to all org members
 	org, err := s.orgRepo.FindByID(ctx, orgID)
@@ -537,6 +613,9 @@ func (s *OrgService) AcceptInvitation(ctx context.Context, token string, userID
 	if err != nil {
 		return nil, err
 	}
+	s.logAuditAs(ctx, invitation.OrganizationID, &userID, models.AuditTargetInvitation, &invitation.ID, models.AuditActionInviteAccept, map[string]interface{}{
+		"role": invitation.Role,
+	})
 
 	// Broadcast new member added to all org members
 	if invitation.Organization.Slug != "" {
@@ -547,6 +626,7 @@ func (s *OrgService) AcceptInvitation(ctx context.Context, token string, userID
 			Role:    string(invitation.Role),
 		})
 	}
+	s.requestSeatQuantitySync(ctx, invitation.OrganizationID)
 
 	return &member, nil
 }
@@ -565,6 +645,7 @@ func (s *OrgService) CancelInvitation(ctx context.Context, invitationID, orgID u
 	if rowsAffected == 0 {
 		return ErrInvitationNotFound
 	}
+	s.logAudit(ctx, orgID, models.AuditTargetInvitation, &invitationID, models.AuditActionInviteRevoke, nil)
 
 	// Broadcast invitation revoked to all org members
 	org, err := s.orgRepo.FindByID(ctx, orgID)
@@ -610,12 +691,27 @@ func (s *OrgService) GetOrganizationByStripeCustomerID(ctx context.Context, cust
 
 // UpdateOrganizationPlan updates the organization's plan and Stripe subscription info
 func (s *OrgService) UpdateOrganizationPlan(ctx context.Context, orgID uint, plan models.OrganizationPlan, stripeSubID *string) error {
-	return s.orgRepo.UpdatePlan(ctx, orgID, plan, stripeSubID)
+	if err := s.orgRepo.UpdatePlan(ctx, orgID, plan, stripeSubID); err != nil {
+		return err
+	}
+	InvalidateEntitlements(ctx, OrgSubject(orgID))
+	changes := map[string]interface{}{"plan": plan}
+	if stripeSubID != nil {
+		changes["stripe_subscription_id"] = *stripeSubID
+	}
+	s.logAudit(ctx, orgID, models.AuditTargetOrganization, &orgID, models.AuditActionPlanChange, changes)
+	return nil
 }
 
 // SetOrganizationStripeCustomer sets the Stripe customer ID for an organization
 func (s *OrgService) SetOrganizationStripeCustomer(ctx context.Context, orgID uint, customerID string) error {
-	return s.orgRepo.UpdateStripeCustomer(ctx, orgID, customerID)
+	if err := s.orgRepo.UpdateStripeCustomer(ctx, orgID, customerID); err != nil {
+		return err
+	}
+	s.logAudit(ctx, orgID, models.AuditTargetOrganization, &orgID, models.AuditActionBillingUpdate, map[string]interface{}{
+		"stripe_customer_id": customerID,
+	})
+	return nil
 }
 
 // GetMemberCount returns the number of active members in an organization
@@ -631,12 +727,26 @@ func (s *OrgService) CanAddMember(ctx context.Context, orgID uint) (bool, error)
 		return false, err
 	}
 
-	seatLimit := org.GetSeatLimit()
+	ent, err := GetEntitlementsService().OrganizationEntitlements(ctx, org)
+	if err != nil {
+		return false, err
+	}
+
+	seatLimit := ent.Seats
 	if seatLimit == 0 {
 		// Unlimited seats (enterprise)
 		return true, nil
 	}
 
+	// Seats purchased beyond the plan allowance raise the limit
+	sub, err := s.GetOrganizationSubscription(ctx, orgID)
+	if err != nil {
+		return false, err
+	}
+	if sub != nil && sub.IsActiveSubscription() && int(sub.SeatQuantity) > seatLimit {
+		seatLimit = int(sub.SeatQuantity)
+	}
+
 	memberCount, err := s.GetMemberCount(ctx, orgID)
 	if err != nil {
 		return false, err
@@ -665,12 +775,33 @@ func (s *OrgService) GetOrganizationSubscription(ctx context.Context, orgID uint
 
 // CreateOrganizationSubscription creates a subscription for an organization
 func (s *OrgService) CreateOrganizationSubscription(ctx context.Context, sub *models.Subscription) error {
-	return s.subRepo.Create(ctx, sub)
+	if err := s.subRepo.Create(ctx, sub); err != nil {
+		return err
+	}
+	s.logSubscriptionAudit(ctx, sub, models.AuditActionCreate)
+	return nil
 }
 
 // UpdateOrganizationSubscription updates an organization's subscription
 func (s *OrgService) UpdateOrganizationSubscription(ctx context.Context, sub *models.Subscription) error {
-	return s.subRepo.Update(ctx, sub)
+	if err := s.subRepo.Update(ctx, sub); err != nil {
+		return err
+	}
+	s.logSubscriptionAudit(ctx, sub, models.AuditActionUpdate)
+	return nil
+}
+
+// logSubscriptionAudit records a subscription change against the owning organization
+func (s *OrgService) logSubscriptionAudit(ctx context.Context, sub *models.Subscription, action string) {
+	if !sub.IsOrganizationSubscription() {
+		return
+	}
+	s.logAudit(ctx, *sub.OrganizationID, models.AuditTargetSubscription, &sub.ID, action, map[string]interface{}{
+		"stripe_subscription_id": sub.StripeSubscriptionID,
+		"stripe_price_id":        sub.StripePriceID,
+		"status":                 sub.Status,
+		"cancel_at_period_end":   sub.CancelAtPeriodEnd,
+	})
 }
 
 // GetOrganizationByStripeSubscriptionID retrieves an organization by its Stripe subscription ID
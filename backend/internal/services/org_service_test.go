@@ -771,6 +771,18 @@ func TestOrgService_UpdateMemberRole(t *testing.T) {
 			},
 			wantErr: ErrMustHaveOwner,
 		},
+		{
+			name:        "suspended member must be reactivated first",
+			orgID:       1,
+			userID:      2,
+			actorUserID: 1,
+			newRole:     models.OrgRoleAdmin,
+			setupMembers: []models.OrganizationMember{
+				{ID: 1, OrganizationID: 1, UserID: 1, Role: models.OrgRoleOwner},
+				{ID: 2, OrganizationID: 1, UserID: 2, Role: models.OrgRoleMember, Status: models.MemberStatusSuspended},
+			},
+			wantErr: ErrMemberSuspended,
+		},
 		{
 			name:        "success update role",
 			orgID:       1,
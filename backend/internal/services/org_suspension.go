@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"react-golang-starter/internal/cache"
+	"react-golang-starter/internal/jobs"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/websocket"
+
+	"github.com/rs/zerolog/log"
+	stripego "github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+)
+
+// Sentinel errors for member suspension
+var (
+	ErrCannotSuspendSelf      = errors.New("cannot suspend yourself")
+	ErrCannotSuspendOwner     = errors.New("cannot suspend an organization owner")
+	ErrMemberAlreadySuspended = errors.New("member is already suspended")
+	ErrMemberNotSuspended     = errors.New("member is not suspended")
+	ErrMemberSuspended        = errors.New("member is suspended")
+)
+
+// SeatQuantityUpdater updates the billed seat quantity on a subscription. Implemented by stripe.Service.
+type SeatQuantityUpdater interface {
+	UpdateSubscriptionQuantity(ctx context.Context, subscriptionID string, quantity int64) (*stripego.Subscription, error)
+}
+
+// SetSeatQuantityUpdater sets the billing provider used to keep seat quantities in sync
+func (s *OrgService) SetSeatQuantityUpdater(updater SeatQuantityUpdater) {
+	s.seatQuantityUpdater = updater
+}
+
+// SuspendMember revokes a member's access without removing them from the organization.
+// The actor must outrank the member. Suspended members do not count toward the seat limit.
+func (s *OrgService) SuspendMember(ctx context.Context, orgID, userID, actorUserID uint) error {
+	if userID == actorUserID {
+		return ErrCannotSuspendSelf
+	}
+
+	member, err := s.memberRepo.FindByOrgIDAndUserID(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotMember
+		}
+		return err
+	}
+
+	// Owners must be demoted first so an organization can't lose every active owner
+	if member.Role == models.OrgRoleOwner {
+		return ErrCannotSuspendOwner
+	}
+
+	// Admins cannot suspend each other; only an owner can suspend an admin
+	actor, err := s.memberRepo.FindByOrgIDAndUserID(ctx, orgID, actorUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInsufficientRole
+		}
+		return err
+	}
+	if actor.Role == member.Role || !actor.Role.IsHigherOrEqualTo(member.Role) {
+		return ErrInsufficientRole
+	}
+
+	if member.Status == models.MemberStatusSuspended {
+		return ErrMemberAlreadySuspended
+	}
+
+	previousStatus := member.Status
+	member.Status = models.MemberStatusSuspended
+	if err := s.memberRepo.Update(ctx, member); err != nil {
+		member.Status = previousStatus
+		return err
+	}
+	s.logAuditAs(ctx, orgID, &actorUserID, models.AuditTargetOrgMember, &userID, models.AuditActionMemberSuspend, map[string]interface{}{
+		"previous_status": previousStatus,
+	})
+
+	// Drop cached membership and org-scoped tokens so access ends immediately
+	_ = cache.InvalidateMembership(ctx, orgID, userID)
+	s.revokeOrgTokens(ctx, orgID, userID, "member_suspended")
+
+	s.notifyMemberStatusChange(ctx, orgID, userID, "suspended")
+	s.requestSeatQuantitySync(ctx, orgID)
+
+	return nil
+}
+
+// ReactivateMember restores a suspended member's access if a seat is available
+func (s *OrgService) ReactivateMember(ctx context.Context, orgID, userID, actorUserID uint) error {
+	member, err := s.memberRepo.FindByOrgIDAndUserID(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotMember
+		}
+		return err
+	}
+
+	if member.Status != models.MemberStatusSuspended {
+		return ErrMemberNotSuspended
+	}
+
+	canAdd, err := s.CanAddMember(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if !canAdd {
+		return ErrSeatLimitExceeded
+	}
+
+	member.Status = models.MemberStatusActive
+	if err := s.memberRepo.Update(ctx, member); err != nil {
+		member.Status = models.MemberStatusSuspended
+		return err
+	}
+	s.logAuditAs(ctx, orgID, &actorUserID, models.AuditTargetOrgMember, &userID, models.AuditActionMemberReactivate, nil)
+
+	_ = cache.InvalidateMembership(ctx, orgID, userID)
+
+	s.notifyMemberStatusChange(ctx, orgID, userID, "reactivated")
+	s.requestSeatQuantitySync(ctx, orgID)
+
+	return nil
+}
+
+// notifyMemberStatusChange broadcasts a member status event to the org and the affected user
+func (s *OrgService) notifyMemberStatusChange(ctx context.Context, orgID, userID uint, event string) {
+	org, err := s.orgRepo.FindByID(ctx, orgID)
+	if err != nil {
+		return
+	}
+
+	payload := websocket.MemberUpdatePayload{
+		OrgSlug: org.Slug,
+		Event:   event,
+		UserID:  userID,
+	}
+	s.broadcastToOrgMembers(ctx, orgID, websocket.MessageTypeMemberUpdate, payload)
+	if s.hub != nil {
+		s.hub.SendToUser(userID, websocket.MessageTypeMemberUpdate, payload)
+	}
+}
+
+// requestSeatQuantitySync schedules a seat quantity sync after a membership change.
+// With the job system running the sync is debounced so bulk changes make a single
+// Stripe call; otherwise it runs inline and failures are logged for the next change to retry.
+func (s *OrgService) requestSeatQuantitySync(ctx context.Context, orgID uint) {
+	if s.seatQuantityUpdater == nil {
+		return
+	}
+
+	if jobs.IsAvailable() {
+		err := jobs.EnqueueSeatQuantitySync(ctx, orgID)
+		if err == nil {
+			return
+		}
+		log.Warn().Err(err).Uint("org_id", orgID).Msg("failed to enqueue seat quantity sync, syncing inline")
+	}
+
+	if err := s.SyncSeatQuantity(ctx, orgID); err != nil {
+		log.Error().Err(err).Uint("org_id", orgID).Msg("failed to sync seat quantity")
+	}
+}
+
+// SyncSeatQuantity sets the organization subscription's billed quantity to the number of
+// active members, prorating the change, and records the purchased seats locally. Seats the
+// customer set in the billing portal are kept as a minimum.
+func (s *OrgService) SyncSeatQuantity(ctx context.Context, orgID uint) error {
+	if s.seatQuantityUpdater == nil {
+		return nil
+	}
+
+	org, err := s.orgRepo.FindByID(ctx, orgID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // Organization deleted since the sync was requested
+		}
+		return err
+	}
+	if !org.HasSubscription() {
+		return nil
+	}
+
+	count, err := s.memberRepo.CountActiveByOrgID(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to count active members: %w", err)
+	}
+	if count < 1 {
+		count = 1
+	}
+
+	sub, err := s.GetOrganizationSubscription(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if sub != nil && sub.MinSeatQuantity > count {
+		count = sub.MinSeatQuantity
+	}
+	if sub != nil && sub.SeatQuantity == count {
+		return nil
+	}
+
+	if _, err := s.seatQuantityUpdater.UpdateSubscriptionQuantity(ctx, *org.StripeSubscriptionID, count); err != nil {
+		return fmt.Errorf("failed to update subscription seat quantity: %w", err)
+	}
+
+	if sub != nil {
+		sub.SeatQuantity = count
+		sub.UpdatedAt = time.Now().Format(time.RFC3339)
+		if err := s.subRepo.Update(ctx, sub); err != nil {
+			log.Error().Err(err).Uint("org_id", orgID).Msg("failed to record subscription seat quantity")
+		}
+	}
+
+	log.Info().Uint("org_id", orgID).Int64("seats", count).Msg("subscription seat quantity updated")
+	return nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"react-golang-starter/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stripego "github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+)
+
+// fakeSeatQuantityUpdater records seat quantity updates for assertions
+type fakeSeatQuantityUpdater struct {
+	quantities []int64
+	err        error
+}
+
+func (f *fakeSeatQuantityUpdater) UpdateSubscriptionQuantity(ctx context.Context, subscriptionID string, quantity int64) (*stripego.Subscription, error) {
+	f.quantities = append(f.quantities, quantity)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &stripego.Subscription{ID: subscriptionID}, nil
+}
+
+func setupSuspensionOrg(t *testing.T, plan models.OrganizationPlan) (*OrgService, *fakeSeatQuantityUpdater) {
+	t.Helper()
+
+	svc, orgRepo, memberRepo, _, _, _ := newTestOrgService()
+	updater := &fakeSeatQuantityUpdater{}
+	svc.SetSeatQuantityUpdater(updater)
+
+	subID := "sub_seats"
+	orgRepo.AddOrganization(&models.Organization{ID: 1, Slug: "acme", Plan: plan, StripeSubscriptionID: &subID})
+	memberRepo.AddMember(models.OrganizationMember{OrganizationID: 1, UserID: 1, Role: models.OrgRoleOwner, Status: models.MemberStatusActive})
+	memberRepo.AddMember(models.OrganizationMember{OrganizationID: 1, UserID: 2, Role: models.OrgRoleMember, Status: models.MemberStatusActive})
+	memberRepo.AddMember(models.OrganizationMember{OrganizationID: 1, UserID: 3, Role: models.OrgRoleAdmin, Status: models.MemberStatusActive})
+
+	return svc, updater
+}
+
+func TestOrgService_SuspendMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  uint
+		actorID uint
+		wantErr error
+	}{
+		{name: "suspends member", userID: 2, actorID: 3},
+		{name: "cannot suspend self", userID: 3, actorID: 3, wantErr: ErrCannotSuspendSelf},
+		{name: "cannot suspend owner", userID: 1, actorID: 3, wantErr: ErrCannotSuspendOwner},
+		{name: "member cannot suspend admin", userID: 3, actorID: 2, wantErr: ErrInsufficientRole},
+		{name: "owner suspends admin", userID: 3, actorID: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, updater := setupSuspensionOrg(t, models.OrgPlanPro)
+
+			err := svc.SuspendMember(context.Background(), 1, tt.userID, tt.actorID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, updater.quantities, "seats should not sync on failure")
+				return
+			}
+			require.NoError(t, err)
+
+			member, err := svc.GetUserMembership(context.Background(), 1, tt.userID)
+			require.NoError(t, err)
+			assert.Equal(t, models.MemberStatusSuspended, member.Status)
+
+			count, err := svc.GetMemberCount(context.Background(), 1)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), count, "suspended members should not use a seat")
+			assert.Equal(t, []int64{2}, updater.quantities)
+
+			err = svc.SuspendMember(context.Background(), 1, tt.userID, tt.actorID)
+			assert.ErrorIs(t, err, ErrMemberAlreadySuspended)
+		})
+	}
+}
+
+func TestOrgService_SuspendMember_NotMember(t *testing.T) {
+	svc, _, memberRepo, _, _, _ := newTestOrgService()
+	memberRepo.FindByOrgIDAndUserIDErr = gorm.ErrRecordNotFound
+
+	err := svc.SuspendMember(context.Background(), 1, 2, 1)
+
+	assert.ErrorIs(t, err, ErrNotMember)
+}
+
+func TestOrgService_SuspendMember_AdminCannotSuspendAdmin(t *testing.T) {
+	svc, orgRepo, memberRepo, _, _, _ := newTestOrgService()
+	orgRepo.AddOrganization(&models.Organization{ID: 1, Slug: "acme", Plan: models.OrgPlanPro})
+	memberRepo.AddMember(models.OrganizationMember{OrganizationID: 1, UserID: 1, Role: models.OrgRoleAdmin, Status: models.MemberStatusActive})
+	memberRepo.AddMember(models.OrganizationMember{OrganizationID: 1, UserID: 2, Role: models.OrgRoleAdmin, Status: models.MemberStatusActive})
+
+	err := svc.SuspendMember(context.Background(), 1, 2, 1)
+
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+	member, err := svc.GetUserMembership(context.Background(), 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, models.MemberStatusActive, member.Status)
+}
+
+func TestOrgService_ReactivateMember(t *testing.T) {
+	svc, updater := setupSuspensionOrg(t, models.OrgPlanPro)
+
+	err := svc.ReactivateMember(context.Background(), 1, 2, 1)
+	assert.ErrorIs(t, err, ErrMemberNotSuspended)
+
+	require.NoError(t, svc.SuspendMember(context.Background(), 1, 2, 1))
+	require.NoError(t, svc.ReactivateMember(context.Background(), 1, 2, 1))
+
+	member, err := svc.GetUserMembership(context.Background(), 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, models.MemberStatusActive, member.Status)
+	assert.Equal(t, []int64{2, 3}, updater.quantities)
+}
+
+func TestOrgService_ReactivateMember_SeatLimit(t *testing.T) {
+	svc, orgRepo, memberRepo, _, _, _ := newTestOrgService()
+
+	// Free plan allows 5 seats
+	orgRepo.AddOrganization(&models.Organization{ID: 1, Slug: "acme", Plan: models.OrgPlanFree})
+	for i := uint(1); i <= 5; i++ {
+		memberRepo.AddMember(models.OrganizationMember{OrganizationID: 1, UserID: i, Role: models.OrgRoleMember, Status: models.MemberStatusActive})
+	}
+	memberRepo.AddMember(models.OrganizationMember{OrganizationID: 1, UserID: 6, Role: models.OrgRoleMember, Status: models.MemberStatusSuspended})
+
+	err := svc.ReactivateMember(context.Background(), 1, 6, 1)
+
+	assert.ErrorIs(t, err, ErrSeatLimitExceeded)
+}
+
+func TestOrgService_SuspendMember_SeatSyncFailureDoesNotFail(t *testing.T) {
+	svc, updater := setupSuspensionOrg(t, models.OrgPlanPro)
+	updater.err = errors.New("stripe unavailable")
+
+	err := svc.SuspendMember(context.Background(), 1, 2, 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, updater.quantities, 1)
+}
+
+// setupSeatSyncOrg creates a pro organization with a subscription and active members
+func setupSeatSyncOrg(t *testing.T, members int, purchased, minimum int64) (*OrgService, *fakeSeatQuantityUpdater, *models.Subscription) {
+	t.Helper()
+
+	svc, orgRepo, memberRepo, _, subRepo, _ := newTestOrgService()
+	updater := &fakeSeatQuantityUpdater{}
+	svc.SetSeatQuantityUpdater(updater)
+
+	orgID := uint(1)
+	subID := "sub_seats"
+	orgRepo.AddOrganization(&models.Organization{ID: orgID, Slug: "acme", Plan: models.OrgPlanPro, StripeSubscriptionID: &subID})
+	for i := 1; i <= members; i++ {
+		memberRepo.AddMember(models.OrganizationMember{OrganizationID: orgID, UserID: uint(i), Role: models.OrgRoleMember, Status: models.MemberStatusActive})
+	}
+	subRepo.AddSubscription(models.Subscription{
+		OrganizationID:       &orgID,
+		StripeSubscriptionID: subID,
+		Status:               models.SubscriptionStatusActive,
+		SeatQuantity:         purchased,
+		MinSeatQuantity:      minimum,
+	})
+
+	sub, err := subRepo.FindByOrgID(context.Background(), orgID)
+	require.NoError(t, err)
+	return svc, updater, sub
+}
+
+func TestOrgService_SyncSeatQuantity(t *testing.T) {
+	tests := []struct {
+		name          string
+		members       int
+		purchased     int64
+		minimum       int64
+		wantUpdates   []int64
+		wantPurchased int64
+	}{
+		{name: "grows with members", members: 3, purchased: 1, wantUpdates: []int64{3}, wantPurchased: 3},
+		{name: "shrinks with members", members: 2, purchased: 4, wantUpdates: []int64{2}, wantPurchased: 2},
+		{name: "shrinks with members beyond the plan allowance", members: 26, purchased: 30, wantUpdates: []int64{26}, wantPurchased: 26},
+		{name: "unchanged quantity skips stripe", members: 3, purchased: 3, wantPurchased: 3},
+		{name: "keeps seats set in the billing portal", members: 3, purchased: 30, minimum: 30, wantPurchased: 30},
+		{name: "shrinks down to the portal minimum", members: 3, purchased: 12, minimum: 5, wantUpdates: []int64{5}, wantPurchased: 5},
+		{name: "grows past the portal minimum", members: 6, purchased: 5, minimum: 5, wantUpdates: []int64{6}, wantPurchased: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, updater, sub := setupSeatSyncOrg(t, tt.members, tt.purchased, tt.minimum)
+
+			require.NoError(t, svc.SyncSeatQuantity(context.Background(), 1))
+
+			assert.Equal(t, tt.wantUpdates, updater.quantities)
+			assert.Equal(t, tt.wantPurchased, sub.SeatQuantity)
+		})
+	}
+}
+
+func TestOrgService_SyncSeatQuantity_ReturnsUpdateError(t *testing.T) {
+	svc, updater, sub := setupSeatSyncOrg(t, 3, 1, 0)
+	updater.err = errors.New("stripe unavailable")
+
+	err := svc.SyncSeatQuantity(context.Background(), 1)
+
+	assert.Error(t, err, "the job should retry failed updates")
+	assert.Equal(t, int64(1), sub.SeatQuantity)
+}
+
+func TestOrgService_CanAddMember_PurchasedSeats(t *testing.T) {
+	// Pro allows 25 seats; the organization bought 30
+	svc, _, sub := setupSeatSyncOrg(t, 27, 30, 30)
+
+	canAdd, err := svc.CanAddMember(context.Background(), 1)
+	require.NoError(t, err)
+	assert.True(t, canAdd, "purchased seats above the plan allowance should be usable")
+
+	sub.Status = models.SubscriptionStatusCanceled
+	canAdd, err = svc.CanAddMember(context.Background(), 1)
+	require.NoError(t, err)
+	assert.False(t, canAdd, "a canceled subscription falls back to the plan allowance")
+}
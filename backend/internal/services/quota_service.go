@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"react-golang-starter/internal/models"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// quotaExemptionsRefresh bounds how long an exemption change takes to apply everywhere
+	quotaExemptionsRefresh = time.Minute
+)
+
+var (
+	ErrQuotaExemptionNotFound = errors.New("quota exemption not found")
+	ErrInvalidQuotaExemption  = errors.New("exactly one of user_id or organization_id is required")
+)
+
+// QuotaDecision is the outcome of a quota check
+type QuotaDecision struct {
+	Allowed   bool
+	UsageType string
+	Plan      string
+	Mode      string
+
+	// Usage before the checked request, the plan limit and the usage at which
+	// requests are rejected (0 when they never are)
+	Current int64
+	Limit   int64
+	Ceiling int64
+
+	// When the usage period resets
+	ResetAt time.Time
+
+	// OverLimit is set when admitted usage goes past the plan limit (soft or overage mode)
+	OverLimit bool
+	// Exempt is set when an admin exemption skipped enforcement
+	Exempt bool
+	// UpgradeAvailable is set when a higher plan exists to lift the limit
+	UpgradeAvailable bool
+
+	// Reservation holds the admitted quantity in the live usage total until the request's
+	// usage is recorded; nil when nothing was reserved
+	Reservation *QuotaReservation
+}
+
+// StatusCode returns the HTTP status for a rejected request: 402 when upgrading the
+// plan would lift the limit, otherwise 429 until the period resets
+func (d *QuotaDecision) StatusCode() int {
+	if d.UpgradeAvailable {
+		return http.StatusPaymentRequired
+	}
+	return http.StatusTooManyRequests
+}
+
+// Remaining returns how much usage is left before the plan limit, never negative
+func (d *QuotaDecision) Remaining() int64 {
+	if d.Current >= d.Limit {
+		return 0
+	}
+	return d.Limit - d.Current
+}
+
+// QuotaService enforces plan usage limits before requests are handled. It reads the live
+// usage counters of the usage service, so checks are cheap, see usage admitted by every
+// instance and do not wait for usage to be flushed to the usage periods.
+type QuotaService struct {
+	db    *gorm.DB
+	usage *UsageService
+	now   func() time.Time
+
+	exemptMu           sync.RWMutex
+	exemptions         map[string]bool
+	exemptionsLoadedAt time.Time
+}
+
+// NewQuotaService creates a quota service reading live usage from usageService
+func NewQuotaService(db *gorm.DB, usageService *UsageService) *QuotaService {
+	return &QuotaService{
+		db:    db,
+		usage: usageService,
+		now:   time.Now,
+	}
+}
+
+// QuotaEnforcementEnabled reports whether quota enforcement is switched on (USAGE_QUOTA_ENFORCEMENT, default true)
+func QuotaEnforcementEnabled() bool {
+	val := os.Getenv("USAGE_QUOTA_ENFORCEMENT")
+	if val == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(val)
+	return err != nil || enabled
+}
+
+// Check decides whether a subject may use quantity more of usageType under its plan.
+// When the plan has a ceiling, an admitted quantity is reserved in the live usage total
+// at once, so concurrent requests cannot overshoot it; a rejected one is rolled back.
+// The caller hands decision.Reservation to the request's usage meter (or releases it),
+// and recording the usage settles it instead of counting it again.
+func (s *QuotaService) Check(ctx context.Context, subject EntitlementSubject, usageType string, quantity int64) (*QuotaDecision, error) {
+	ent, err := GetEntitlementsService().Entitlements(ctx, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve entitlements: %w", err)
+	}
+
+	catalog := GetEntitlementsService().Catalog()
+	decision := &QuotaDecision{
+		Allowed:          true,
+		UsageType:        usageType,
+		Plan:             ent.Plan,
+		Mode:             ent.Quota.Mode,
+		Limit:            usageLimit(ent.Limits, usageType),
+		ResetAt:          nextPeriodStart(s.now()),
+		UpgradeAvailable: hasHigherPlan(catalog, ent.Plan),
+	}
+	if decision.Limit <= 0 {
+		return decision, nil
+	}
+	decision.Ceiling = ent.Quota.Ceiling(decision.Limit)
+
+	if s.IsExempt(ctx, subject) {
+		decision.Exempt = true
+		return decision, nil
+	}
+
+	var current int64
+	if decision.Ceiling > 0 {
+		current, decision.Reservation, err = s.reserveUsage(ctx, subject, usageType, quantity)
+	} else {
+		current, err = s.currentUsage(ctx, subject, usageType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usage: %w", err)
+	}
+
+	decision.Current = current
+	if decision.Ceiling > 0 && current+quantity > decision.Ceiling {
+		decision.Reservation.Release(ctx)
+		decision.Reservation = nil
+		decision.Allowed = false
+		return decision, nil
+	}
+	decision.OverLimit = current+quantity > decision.Limit
+	return decision, nil
+}
+
+// currentUsage returns the subject's live usage for the current period
+func (s *QuotaService) currentUsage(ctx context.Context, subject EntitlementSubject, usageType string) (int64, error) {
+	if s.usage == nil {
+		return 0, nil
+	}
+
+	userID, orgID := subject.usageOwner()
+	return s.usage.CurrentUsage(ctx, userID, orgID, usageType)
+}
+
+// reserveUsage reserves quantity in the subject's live usage and returns the usage before it
+func (s *QuotaService) reserveUsage(ctx context.Context, subject EntitlementSubject, usageType string, quantity int64) (int64, *QuotaReservation, error) {
+	if s.usage == nil {
+		return 0, nil, nil
+	}
+
+	userID, orgID := subject.usageOwner()
+	return s.usage.reserveUsage(ctx, userID, orgID, usageType, quantity)
+}
+
+// usageOwner returns the user or organization the subject's usage is counted against
+func (s EntitlementSubject) usageOwner() (userID *uint, orgID *uint) {
+	if s.OrganizationID != nil {
+		return nil, s.OrganizationID
+	}
+	id := s.UserID
+	return &id, nil
+}
+
+// ============ Exemptions ============
+
+// IsExempt reports whether an admin exempted the subject from quota enforcement
+func (s *QuotaService) IsExempt(ctx context.Context, subject EntitlementSubject) bool {
+	s.exemptMu.RLock()
+	fresh := s.exemptions != nil && s.now().Sub(s.exemptionsLoadedAt) < quotaExemptionsRefresh
+	exempt := s.exemptions[subject.cacheKey()]
+	s.exemptMu.RUnlock()
+	if fresh {
+		return exempt
+	}
+
+	if err := s.loadExemptions(ctx); err != nil {
+		log.Warn().Err(err).Msg("failed to load quota exemptions")
+	}
+
+	s.exemptMu.RLock()
+	defer s.exemptMu.RUnlock()
+	return s.exemptions[subject.cacheKey()]
+}
+
+// loadExemptions caches the subjects with an active exemption
+func (s *QuotaService) loadExemptions(ctx context.Context) error {
+	exempt := make(map[string]bool)
+
+	var err error
+	if s.db != nil {
+		var rows []models.QuotaExemption
+		err = s.db.WithContext(ctx).Find(&rows).Error
+		now := s.now()
+		for i := range rows {
+			if rows[i].IsActive(now) {
+				exempt[exemptionSubject(&rows[i]).cacheKey()] = true
+			}
+		}
+	}
+
+	s.exemptMu.Lock()
+	defer s.exemptMu.Unlock()
+	if err == nil || s.exemptions == nil {
+		s.exemptions = exempt
+	}
+	s.exemptionsLoadedAt = s.now()
+	return err
+}
+
+// invalidateExemptions makes the next check reload exemptions
+func (s *QuotaService) invalidateExemptions() {
+	s.exemptMu.Lock()
+	defer s.exemptMu.Unlock()
+	s.exemptions = nil
+}
+
+// ListExemptions returns all quota exemptions, newest first
+func (s *QuotaService) ListExemptions(ctx context.Context) ([]models.QuotaExemption, error) {
+	var rows []models.QuotaExemption
+	if err := s.db.WithContext(ctx).Order("created_at DESC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list quota exemptions: %w", err)
+	}
+	return rows, nil
+}
+
+// CreateExemption exempts a user or organization from quota enforcement
+func (s *QuotaService) CreateExemption(ctx context.Context, req *models.CreateQuotaExemptionRequest, createdBy uint) (*models.QuotaExemption, error) {
+	if (req.UserID == nil) == (req.OrganizationID == nil) {
+		return nil, ErrInvalidQuotaExemption
+	}
+	if req.ExpiresAt != nil && *req.ExpiresAt != "" {
+		if _, err := time.Parse(time.RFC3339, *req.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("expires_at must be an RFC3339 timestamp: %w", ErrInvalidQuotaExemption)
+		}
+	}
+
+	exemption := &models.QuotaExemption{
+		UserID:          req.UserID,
+		OrganizationID:  req.OrganizationID,
+		Reason:          strings.TrimSpace(req.Reason),
+		ExpiresAt:       req.ExpiresAt,
+		CreatedByUserID: &createdBy,
+		CreatedAt:       s.now().Format(time.RFC3339),
+	}
+	if err := s.db.WithContext(ctx).Create(exemption).Error; err != nil {
+		return nil, fmt.Errorf("failed to create quota exemption: %w", err)
+	}
+	s.invalidateExemptions()
+
+	log.Info().
+		Uint("exemption_id", exemption.ID).
+		Uint("created_by", createdBy).
+		Msg("quota exemption granted")
+	return exemption, nil
+}
+
+// DeleteExemption removes a quota exemption
+func (s *QuotaService) DeleteExemption(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.QuotaExemption{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete quota exemption: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrQuotaExemptionNotFound
+	}
+	s.invalidateExemptions()
+	return nil
+}
+
+// exemptionSubject returns the subject an exemption applies to
+func exemptionSubject(e *models.QuotaExemption) EntitlementSubject {
+	if e.OrganizationID != nil {
+		return OrgSubject(*e.OrganizationID)
+	}
+	if e.UserID != nil {
+		return UserSubject(*e.UserID)
+	}
+	return EntitlementSubject{}
+}
+
+// ============ Helpers ============
+
+// usageLimit returns the plan limit for a usage type (0 means unlimited)
+func usageLimit(limits models.UsageLimits, usageType string) int64 {
+	switch usageType {
+	case UsageTypeAPICall:
+		return limits.APICalls
+	case UsageTypeStorage:
+		return limits.StorageBytes
+	case UsageTypeCompute:
+		return limits.ComputeMS
+	case UsageTypeFileUpload:
+		return limits.FileUploads
+	case UsageTypeAITokens:
+		return limits.AITokens
+	default:
+		return 0
+	}
+}
+
+// usageTotal returns the recorded total for a usage type
+func usageTotal(totals models.UsageTotals, usageType string) int64 {
+	switch usageType {
+	case UsageTypeAPICall:
+		return totals.APICalls
+	case UsageTypeStorage:
+		return totals.StorageBytes
+	case UsageTypeCompute:
+		return totals.ComputeMS
+	case UsageTypeFileUpload:
+		return totals.FileUploads
+	case UsageTypeAITokens:
+		return totals.AITokens
+	default:
+		return 0
+	}
+}
+
+// hasHigherPlan reports whether the catalog has a plan ranked above plan
+func hasHigherPlan(catalog *PlanCatalog, plan string) bool {
+	rank := catalog.Rank(plan)
+	for _, p := range catalog.Plans() {
+		if p.Rank > rank {
+			return true
+		}
+	}
+	return false
+}
+
+// nextPeriodStart returns when the usage period containing now ends
+func nextPeriodStart(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, 1, 0)
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/testutil/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testQuotaService returns a quota service without a database whose subjects are all on
+// the free plan with the given API call limit and quota policy
+func testQuotaService(t *testing.T, limit int64, policy models.QuotaPolicy) *QuotaService {
+	t.Helper()
+
+	catalog := DefaultPlanCatalog()
+	free := catalog.Plan("free")
+	free.Limits.APICalls = limit
+	free.Quota = policy
+	catalog.Set(free)
+
+	previous := GetEntitlementsService()
+	SetEntitlementsService(NewEntitlementsService(nil, catalog))
+	t.Cleanup(func() { SetEntitlementsService(previous) })
+
+	usage := NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+	svc := NewQuotaService(nil, usage)
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+// admit checks a request for subject and records it as an API call when allowed, settling
+// the quota reservation through the request's usage meter as the middleware does
+func admit(t *testing.T, svc *QuotaService, subject EntitlementSubject) *QuotaDecision {
+	t.Helper()
+
+	ctx, meter := WithUsageMeter(context.Background())
+	decision, err := svc.Check(ctx, subject, UsageTypeAPICall, 1)
+	require.NoError(t, err)
+	if decision.Allowed {
+		AttachQuotaReservation(ctx, decision.Reservation)
+		userID, orgID := subject.usageOwner()
+		svc.usage.RecordAPICall(ctx, userID, orgID, "/api/test", "", "")
+		meter.ReleaseReservations(ctx)
+	}
+	return decision
+}
+
+// ============ Check Tests ============
+
+func TestQuotaService_Check_HardLimit(t *testing.T) {
+	svc := testQuotaService(t, 2, models.QuotaPolicy{Mode: models.QuotaModeHard})
+
+	for i := 0; i < 2; i++ {
+		decision := admit(t, svc, UserSubject(1))
+		assert.True(t, decision.Allowed, "request %d should be admitted", i+1)
+		assert.False(t, decision.OverLimit)
+	}
+
+	decision := admit(t, svc, UserSubject(1))
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, int64(2), decision.Current)
+	assert.Equal(t, int64(2), decision.Limit)
+	assert.Equal(t, int64(0), decision.Remaining())
+	assert.Equal(t, "free", decision.Plan)
+	assert.Equal(t, time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC), decision.ResetAt)
+
+	other := admit(t, svc, UserSubject(2))
+	assert.True(t, other.Allowed, "usage is counted per subject")
+}
+
+func TestQuotaService_Check_SoftLimit(t *testing.T) {
+	svc := testQuotaService(t, 10, models.QuotaPolicy{Mode: models.QuotaModeSoft, SoftLimitPercent: 120})
+
+	var decision *QuotaDecision
+	for i := 0; i < 12; i++ {
+		decision = admit(t, svc, UserSubject(1))
+		require.True(t, decision.Allowed, "request %d should be admitted", i+1)
+	}
+	assert.True(t, decision.OverLimit, "usage past the limit is flagged")
+	assert.Equal(t, int64(12), decision.Ceiling)
+
+	decision = admit(t, svc, UserSubject(1))
+	assert.False(t, decision.Allowed, "requests past the soft ceiling are rejected")
+}
+
+func TestQuotaService_Check_Overage(t *testing.T) {
+	svc := testQuotaService(t, 1, models.QuotaPolicy{Mode: models.QuotaModeOverage})
+
+	for i := 0; i < 5; i++ {
+		decision := admit(t, svc, OrgSubject(3))
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, i > 0, decision.OverLimit)
+	}
+}
+
+func TestQuotaService_Check_ReservesAdmittedUsage(t *testing.T) {
+	svc := testQuotaService(t, 2, models.QuotaPolicy{Mode: models.QuotaModeHard})
+	ctx := context.Background()
+
+	// Requests in flight count before their usage is recorded
+	first, err := svc.Check(ctx, UserSubject(1), UsageTypeAPICall, 1)
+	require.NoError(t, err)
+	second, err := svc.Check(ctx, UserSubject(1), UsageTypeAPICall, 1)
+	require.NoError(t, err)
+	assert.True(t, first.Allowed)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, int64(1), second.Current)
+
+	rejected, err := svc.Check(ctx, UserSubject(1), UsageTypeAPICall, 1)
+	require.NoError(t, err)
+	assert.False(t, rejected.Allowed)
+	assert.Nil(t, rejected.Reservation, "a rejected request reserves nothing")
+
+	// A request that is never recorded gives its reservation back
+	second.Reservation.Release(ctx)
+	decision, err := svc.Check(ctx, UserSubject(1), UsageTypeAPICall, 1)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	// Recording a reserved request does not count it again
+	recordCtx, meter := WithUsageMeter(ctx)
+	AttachQuotaReservation(recordCtx, first.Reservation)
+	userID := uint(1)
+	svc.usage.RecordAPICall(recordCtx, &userID, nil, "/api/test", "", "")
+	meter.ReleaseReservations(recordCtx)
+
+	current, err := svc.usage.CurrentUsage(ctx, &userID, nil, UsageTypeAPICall)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), current)
+}
+
+func TestQuotaService_Check_Unlimited(t *testing.T) {
+	svc := testQuotaService(t, 0, models.QuotaPolicy{Mode: models.QuotaModeHard})
+
+	decision, err := svc.Check(context.Background(), UserSubject(1), UsageTypeAPICall, 1000)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(0), decision.Limit)
+}
+
+func TestQuotaService_Check_Exempt(t *testing.T) {
+	svc := testQuotaService(t, 1, models.QuotaPolicy{Mode: models.QuotaModeHard})
+	svc.exemptions = map[string]bool{UserSubject(1).cacheKey(): true}
+	svc.exemptionsLoadedAt = svc.now()
+
+	for i := 0; i < 3; i++ {
+		decision := admit(t, svc, UserSubject(1))
+		assert.True(t, decision.Allowed)
+		assert.True(t, decision.Exempt)
+	}
+
+	decision, err := svc.Check(context.Background(), UserSubject(2), UsageTypeAPICall, 2)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed, "exemptions apply only to their subject")
+}
+
+func TestQuotaService_Check_CountsCurrentPeriodOnly(t *testing.T) {
+	svc := testQuotaService(t, 1, models.QuotaPolicy{Mode: models.QuotaModeHard})
+	userID := uint(1)
+
+	err := svc.usage.RecordEvent(context.Background(), &models.UsageEvent{
+		UserID:             &userID,
+		EventType:          UsageTypeAPICall,
+		BillingPeriodStart: "2000-01-01",
+		BillingPeriodEnd:   "2000-01-31",
+	})
+	require.NoError(t, err)
+
+	decision := admit(t, svc, UserSubject(1))
+	assert.True(t, decision.Allowed, "usage from a previous period does not count")
+}
+
+func TestQuotaService_Check_OrgUsageIsSeparate(t *testing.T) {
+	svc := testQuotaService(t, 1, models.QuotaPolicy{Mode: models.QuotaModeHard})
+	userID, orgID := uint(1), uint(3)
+
+	// A member's usage in the organization counts against the organization only
+	svc.usage.RecordAPICall(context.Background(), &userID, &orgID, "/api/test", "", "")
+
+	assert.False(t, admit(t, svc, OrgSubject(3)).Allowed)
+	assert.True(t, admit(t, svc, UserSubject(1)).Allowed)
+}
+
+// ============ Exemption Tests ============
+
+func TestQuotaService_CreateExemption_Validation(t *testing.T) {
+	svc := NewQuotaService(nil, nil)
+	userID, orgID := uint(1), uint(2)
+	badExpiry := "next week"
+
+	tests := []struct {
+		name string
+		req  models.CreateQuotaExemptionRequest
+	}{
+		{"no subject", models.CreateQuotaExemptionRequest{}},
+		{"both subjects", models.CreateQuotaExemptionRequest{UserID: &userID, OrganizationID: &orgID}},
+		{"invalid expiry", models.CreateQuotaExemptionRequest{UserID: &userID, ExpiresAt: &badExpiry}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateExemption(context.Background(), &tt.req, 9)
+			assert.ErrorIs(t, err, ErrInvalidQuotaExemption)
+		})
+	}
+}
+
+func TestExemptionSubject(t *testing.T) {
+	userID, orgID := uint(1), uint(2)
+
+	assert.Equal(t, UserSubject(1), exemptionSubject(&models.QuotaExemption{UserID: &userID}))
+	assert.Equal(t, OrgSubject(2), exemptionSubject(&models.QuotaExemption{OrganizationID: &orgID}))
+}
+
+// ============ Helper Tests ============
+
+func TestQuotaDecision_StatusCode(t *testing.T) {
+	assert.Equal(t, http.StatusPaymentRequired, (&QuotaDecision{UpgradeAvailable: true}).StatusCode())
+	assert.Equal(t, http.StatusTooManyRequests, (&QuotaDecision{}).StatusCode())
+}
+
+func TestHasHigherPlan(t *testing.T) {
+	catalog := DefaultPlanCatalog()
+
+	assert.True(t, hasHigherPlan(catalog, "free"))
+	assert.True(t, hasHigherPlan(catalog, "pro"))
+	assert.False(t, hasHigherPlan(catalog, "enterprise"))
+}
+
+func TestUsageLimitAndTotal(t *testing.T) {
+	limits := models.UsageLimits{APICalls: 1, StorageBytes: 2, ComputeMS: 3, FileUploads: 4}
+	totals := models.UsageTotals{APICalls: 5, StorageBytes: 6, ComputeMS: 7, FileUploads: 8}
+
+	assert.Equal(t, int64(4), usageLimit(limits, UsageTypeFileUpload))
+	assert.Equal(t, int64(0), usageLimit(limits, "unknown"))
+	assert.Equal(t, int64(6), usageTotal(totals, UsageTypeStorage))
+	assert.Equal(t, int64(0), usageTotal(totals, "unknown"))
+}
+
+func TestNextPeriodStart(t *testing.T) {
+	assert.Equal(t,
+		time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		nextPeriodStart(time.Date(2024, time.December, 31, 23, 59, 0, 0, time.UTC)))
+}
@@ -0,0 +1,486 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"react-golang-starter/internal/jobs"
+	"react-golang-starter/internal/models"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Usage alert webhook request headers. The signature has the form t=<unix>,v1=<hex>, where
+// v1 is the HMAC-SHA256 of "<t>.<body>" keyed with the organization's webhook secret.
+const (
+	UsageAlertWebhookEvent           = "usage.alert"
+	UsageAlertWebhookSignatureHeader = "X-Webhook-Signature"
+	UsageAlertWebhookEventHeader     = "X-Webhook-Event"
+	UsageAlertWebhookIDHeader        = "X-Webhook-ID"
+)
+
+// usageAlertWebhookTimeout bounds a single delivery attempt; retries are left to the job queue
+const usageAlertWebhookTimeout = 10 * time.Second
+
+// ErrInvalidUsageAlertPreferences is returned when usage alert preferences fail validation
+var ErrInvalidUsageAlertPreferences = errors.New("invalid usage alert preferences")
+
+// errWebhookAddressBlocked is returned when a webhook would connect to a non-public address
+var errWebhookAddressBlocked = errors.New("webhook address is not publicly routable")
+
+// usageAlertWebhookClient sends usage alert webhooks; a variable so tests can replace it.
+// Webhook URLs are set by organization admins, so the client checks every address it
+// dials, after DNS resolution, and refuses internal ones; redirects are not followed.
+var usageAlertWebhookClient = &http.Client{
+	Timeout: usageAlertWebhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: webhookDialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// cgnatRange is the shared address space of carrier-grade NAT (RFC 6598)
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// GetAlertPreferences returns the usage alert preferences of a user or organization,
+// or the defaults when none have been saved
+func (s *UsageService) GetAlertPreferences(ctx context.Context, userID *uint, orgID *uint) (*models.UsageAlertPreference, error) {
+	if userID == nil && orgID == nil {
+		return nil, fmt.Errorf("either user_id or organization_id must be provided")
+	}
+
+	prefs := models.DefaultUsageAlertPreference()
+	prefs.UserID, prefs.OrganizationID = userID, orgID
+	if s.db == nil {
+		return &prefs, nil
+	}
+
+	var stored models.UsageAlertPreference
+	err := alertPreferenceQuery(s.db.WithContext(ctx), userID, orgID).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &prefs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage alert preferences: %w", err)
+	}
+	return &stored, nil
+}
+
+// UpdateAlertPreferences applies req to the usage alert preferences of a user or organization.
+// A webhook secret is generated when a webhook URL is first set or a rotation is requested;
+// it is only returned in this response.
+func (s *UsageService) UpdateAlertPreferences(ctx context.Context, userID *uint, orgID *uint, req models.UpdateUsageAlertPreferencesRequest) (*models.UsageAlertPreferencesResponse, error) {
+	prefs, err := s.GetAlertPreferences(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	newSecret, err := applyAlertPreferences(prefs, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	prefs.UpdatedAt = now
+	if prefs.ID == 0 {
+		prefs.CreatedAt = now
+		err = s.db.WithContext(ctx).Create(prefs).Error
+	} else {
+		err = s.db.WithContext(ctx).Save(prefs).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save usage alert preferences: %w", err)
+	}
+
+	resp := prefs.ToResponse()
+	resp.WebhookSecret = newSecret
+	return &resp, nil
+}
+
+// applyAlertPreferences validates req and applies it to prefs, returning the webhook
+// secret when a new one was generated
+func applyAlertPreferences(prefs *models.UsageAlertPreference, req models.UpdateUsageAlertPreferencesRequest) (string, error) {
+	if prefs.OrganizationID == nil && (req.WebhookURL != nil || req.WebhookEnabled != nil || req.RotateWebhookSecret) {
+		return "", fmt.Errorf("%w: webhooks can only be configured for organizations", ErrInvalidUsageAlertPreferences)
+	}
+
+	if req.EmailEnabled != nil {
+		prefs.EmailEnabled = *req.EmailEnabled
+	}
+	if req.Threshold80 != nil {
+		prefs.Threshold80 = *req.Threshold80
+	}
+	if req.Threshold90 != nil {
+		prefs.Threshold90 = *req.Threshold90
+	}
+	if req.Threshold100 != nil {
+		prefs.Threshold100 = *req.Threshold100
+	}
+
+	if req.WebhookURL != nil {
+		if *req.WebhookURL != "" {
+			if err := validateWebhookURL(*req.WebhookURL); err != nil {
+				return "", err
+			}
+		}
+		prefs.WebhookURL = *req.WebhookURL
+	}
+	if req.WebhookEnabled != nil {
+		prefs.WebhookEnabled = *req.WebhookEnabled
+	}
+	if prefs.WebhookURL == "" {
+		if req.WebhookEnabled != nil && *req.WebhookEnabled {
+			return "", fmt.Errorf("%w: webhook_url is required to enable the webhook", ErrInvalidUsageAlertPreferences)
+		}
+		prefs.WebhookEnabled = false
+		return "", nil
+	}
+
+	if prefs.WebhookSecret != "" && !req.RotateWebhookSecret {
+		return "", nil
+	}
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	prefs.WebhookSecret = secret
+	return secret, nil
+}
+
+// validateWebhookURL requires an absolute https URL whose host is not an internal address.
+// In development, plain http is also allowed for local receivers. Host names are checked
+// again when the webhook is sent, against the addresses they resolve to.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: webhook_url must be an absolute URL", ErrInvalidUsageAlertPreferences)
+	}
+
+	host := u.Hostname()
+	ip := net.ParseIP(host)
+	local := host == "localhost" || (ip != nil && ip.IsLoopback())
+	if local && webhookAllowsLocalReceivers() {
+		if u.Scheme == "https" || u.Scheme == "http" {
+			return nil
+		}
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: webhook_url must use https", ErrInvalidUsageAlertPreferences)
+	}
+	if local || (ip != nil && !isPublicIP(ip)) {
+		return fmt.Errorf("%w: webhook_url must not point to an internal address", ErrInvalidUsageAlertPreferences)
+	}
+	return nil
+}
+
+// webhookDialControl refuses webhook connections to addresses that are not publicly
+// routable. It runs for every connection, after DNS resolution, so a host name cannot be
+// pointed at an internal address once the URL was accepted.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errWebhookAddressBlocked
+	}
+	if isPublicIP(ip) || (ip.IsLoopback() && webhookAllowsLocalReceivers()) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errWebhookAddressBlocked, ip)
+}
+
+// isPublicIP reports whether ip is publicly routable: not private, loopback, link-local
+// (e.g. cloud metadata endpoints), shared NAT, multicast or unspecified
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsPrivate() &&
+		!ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!cgnatRange.Contains(ip)
+}
+
+// webhookAllowsLocalReceivers reports whether webhooks may be sent to loopback receivers,
+// which is only the case in development
+func webhookAllowsLocalReceivers() bool {
+	env := strings.ToLower(os.Getenv("GO_ENV"))
+	if env == "" {
+		env = strings.ToLower(os.Getenv("APP_ENV"))
+	}
+	return env == "development" || env == "dev"
+}
+
+// generateWebhookSecret returns a random signing secret
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// alertPreferenceQuery scopes a query to the preferences of a user or organization
+func alertPreferenceQuery(db *gorm.DB, userID *uint, orgID *uint) *gorm.DB {
+	if userID != nil {
+		return db.Where("user_id = ?", *userID)
+	}
+	return db.Where("organization_id = ? AND user_id IS NULL", *orgID)
+}
+
+// notifyUsageAlert queues the email and webhook deliveries of a newly created alert
+// according to its owner's preferences. Delivery needs the job queue; without it only
+// the in-app notification is sent.
+func (s *UsageService) notifyUsageAlert(ctx context.Context, alert *models.UsageAlert) {
+	if !jobs.IsAvailable() {
+		return
+	}
+
+	prefs, err := s.GetAlertPreferences(ctx, alert.UserID, alert.OrganizationID)
+	if err != nil {
+		log.Warn().Err(err).Uint("alert_id", alert.ID).Msg("failed to load usage alert preferences")
+		return
+	}
+	if !prefs.NotifiesAt(usageAlertThreshold(alert.AlertType)) {
+		return
+	}
+
+	if prefs.EmailEnabled {
+		emails, err := s.usageAlertEmails(ctx, alert)
+		if err != nil {
+			log.Warn().Err(err).Uint("alert_id", alert.ID).Msg("failed to load usage alert recipients")
+		} else if err := jobs.EnqueueUsageAlertEmails(ctx, emails); err != nil {
+			log.Error().Err(err).Uint("alert_id", alert.ID).Msg("failed to queue usage alert emails")
+		}
+	}
+
+	if prefs.WebhookEnabled && prefs.WebhookURL != "" && alert.OrganizationID != nil {
+		if err := jobs.EnqueueUsageAlertWebhook(ctx, alert.ID, *alert.OrganizationID); err != nil {
+			log.Error().Err(err).Uint("alert_id", alert.ID).Msg("failed to queue usage alert webhook")
+		}
+	}
+}
+
+// usageAlertEmails builds the alert email for a user, or for every active owner and
+// admin of an organization
+func (s *UsageService) usageAlertEmails(ctx context.Context, alert *models.UsageAlert) ([]jobs.SendUsageAlertEmailArgs, error) {
+	base := jobs.SendUsageAlertEmailArgs{
+		AlertID:        alert.ID,
+		UsageLabel:     usageTypeLabel(alert.UsageType),
+		CurrentUsage:   formatUsageAmount(alert.UsageType, alert.CurrentUsage),
+		UsageLimit:     formatUsageAmount(alert.UsageType, alert.UsageLimit),
+		PercentageUsed: alert.PercentageUsed,
+		Exceeded:       alert.AlertType == "exceeded",
+		PeriodEnd:      formatPeriodDate(alert.PeriodEnd),
+	}
+
+	if alert.UserID != nil {
+		var user models.User
+		if err := s.db.WithContext(ctx).First(&user, *alert.UserID).Error; err != nil {
+			return nil, err
+		}
+		base.UserID, base.Email, base.Name = user.ID, user.Email, user.Name
+		return []jobs.SendUsageAlertEmailArgs{base}, nil
+	}
+
+	var org models.Organization
+	if err := s.db.WithContext(ctx).First(&org, *alert.OrganizationID).Error; err != nil {
+		return nil, err
+	}
+	base.OrgName, base.OrgSlug = org.Name, org.Slug
+
+	var members []models.OrganizationMember
+	if err := s.db.WithContext(ctx).Preload("User").
+		Where("organization_id = ? AND status = ? AND role IN ?", org.ID, models.MemberStatusActive,
+			[]models.OrganizationRole{models.OrgRoleOwner, models.OrgRoleAdmin}).
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	emails := make([]jobs.SendUsageAlertEmailArgs, 0, len(members))
+	for _, m := range members {
+		if m.User == nil {
+			continue
+		}
+		email := base
+		email.UserID, email.Email, email.Name = m.User.ID, m.User.Email, m.User.Name
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
+
+// DeliverUsageAlertWebhook POSTs a signed usage alert to its organization's webhook.
+// Alerts whose webhook has since been disabled are skipped; any non-2xx response is an
+// error so the job queue retries the delivery.
+func (s *UsageService) DeliverUsageAlertWebhook(ctx context.Context, alertID, orgID uint) error {
+	var alert models.UsageAlert
+	if err := s.db.WithContext(ctx).Where("id = ? AND organization_id = ?", alertID, orgID).First(&alert).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Debug().Uint("alert_id", alertID).Msg("usage alert no longer exists, skipping webhook")
+			return nil
+		}
+		return fmt.Errorf("failed to get usage alert: %w", err)
+	}
+
+	prefs, err := s.GetAlertPreferences(ctx, nil, &orgID)
+	if err != nil {
+		return err
+	}
+	if !prefs.WebhookEnabled || prefs.WebhookURL == "" || prefs.WebhookSecret == "" {
+		log.Debug().Uint("org_id", orgID).Msg("usage alert webhook disabled, skipping")
+		return nil
+	}
+
+	payload := models.UsageAlertWebhookPayload{
+		ID:             fmt.Sprintf("usage_alert_%d", alert.ID),
+		Type:           UsageAlertWebhookEvent,
+		CreatedAt:      alert.CreatedAt,
+		OrganizationID: orgID,
+		AlertID:        alert.ID,
+		AlertType:      alert.AlertType,
+		Threshold:      usageAlertThreshold(alert.AlertType),
+		UsageType:      alert.UsageType,
+		CurrentUsage:   alert.CurrentUsage,
+		UsageLimit:     alert.UsageLimit,
+		PercentageUsed: alert.PercentageUsed,
+		PeriodStart:    alert.PeriodStart,
+		PeriodEnd:      alert.PeriodEnd,
+	}
+
+	return postSignedWebhook(ctx, prefs.WebhookURL, prefs.WebhookSecret, payload.ID, payload)
+}
+
+// postSignedWebhook sends payload as JSON to endpoint with an HMAC signature header
+func postSignedWebhook(ctx context.Context, endpoint, secret, deliveryID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(UsageAlertWebhookEventHeader, UsageAlertWebhookEvent)
+	req.Header.Set(UsageAlertWebhookIDHeader, deliveryID)
+	req.Header.Set(UsageAlertWebhookSignatureHeader, SignWebhookPayload(secret, time.Now().Unix(), body))
+
+	resp, err := usageAlertWebhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the signature header value for body sent at timestamp.
+// Receivers recompute the HMAC over "<t>.<body>" and compare it to v1, rejecting
+// stale timestamps to prevent replays.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// usageAlertThreshold returns the percentage threshold an alert type was raised at
+func usageAlertThreshold(alertType string) int {
+	switch alertType {
+	case "warning_80":
+		return 80
+	case "warning_90":
+		return 90
+	case "exceeded":
+		return 100
+	default:
+		return 0
+	}
+}
+
+// usageTypeLabel returns a human readable name for a usage type
+func usageTypeLabel(usageType string) string {
+	switch usageType {
+	case UsageTypeAPICall:
+		return "API calls"
+	case UsageTypeStorage:
+		return "storage"
+	case UsageTypeCompute:
+		return "compute time"
+	case UsageTypeFileUpload:
+		return "file uploads"
+	case UsageTypeAITokens:
+		return "AI tokens"
+	default:
+		return usageType
+	}
+}
+
+// formatUsageAmount formats a usage quantity for display in its unit
+func formatUsageAmount(usageType string, amount int64) string {
+	switch usageType {
+	case UsageTypeStorage:
+		return formatBytes(amount)
+	case UsageTypeCompute:
+		return formatThousands(amount/1000) + " s"
+	default:
+		return formatThousands(amount)
+	}
+}
+
+// formatThousands formats n with comma thousands separators
+func formatThousands(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return sign + digits
+}
+
+// formatPeriodDate formats a YYYY-MM-DD billing period date for emails
+func formatPeriodDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, date); err != nil {
+			return date
+		}
+	}
+	return t.Format("January 2, 2006")
+}
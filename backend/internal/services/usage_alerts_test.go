@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"react-golang-starter/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyAlertPreferences(t *testing.T) {
+	boolPtr := func(b bool) *bool { return &b }
+	strPtr := func(s string) *string { return &s }
+	orgID := uint(1)
+	userID := uint(2)
+
+	t.Run("updates thresholds", func(t *testing.T) {
+		prefs := models.DefaultUsageAlertPreference()
+		prefs.UserID = &userID
+
+		secret, err := applyAlertPreferences(&prefs, models.UpdateUsageAlertPreferencesRequest{
+			Threshold80:  boolPtr(false),
+			EmailEnabled: boolPtr(true),
+		})
+		require.NoError(t, err)
+		assert.Empty(t, secret)
+		assert.False(t, prefs.NotifiesAt(80))
+		assert.True(t, prefs.NotifiesAt(90))
+		assert.True(t, prefs.NotifiesAt(100))
+	})
+
+	t.Run("webhooks are organization only", func(t *testing.T) {
+		prefs := models.DefaultUsageAlertPreference()
+		prefs.UserID = &userID
+
+		_, err := applyAlertPreferences(&prefs, models.UpdateUsageAlertPreferencesRequest{
+			WebhookURL: strPtr("https://example.com/hooks"),
+		})
+		assert.True(t, errors.Is(err, ErrInvalidUsageAlertPreferences), "error = %v", err)
+	})
+
+	t.Run("setting a webhook generates a secret once", func(t *testing.T) {
+		prefs := models.DefaultUsageAlertPreference()
+		prefs.OrganizationID = &orgID
+
+		secret, err := applyAlertPreferences(&prefs, models.UpdateUsageAlertPreferencesRequest{
+			WebhookURL:     strPtr("https://example.com/hooks"),
+			WebhookEnabled: boolPtr(true),
+		})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(secret, "whsec_"))
+		assert.Equal(t, secret, prefs.WebhookSecret)
+		assert.True(t, prefs.WebhookEnabled)
+
+		again, err := applyAlertPreferences(&prefs, models.UpdateUsageAlertPreferencesRequest{Threshold90: boolPtr(false)})
+		require.NoError(t, err)
+		assert.Empty(t, again, "secret is only returned when generated")
+		assert.Equal(t, secret, prefs.WebhookSecret)
+
+		rotated, err := applyAlertPreferences(&prefs, models.UpdateUsageAlertPreferencesRequest{RotateWebhookSecret: true})
+		require.NoError(t, err)
+		assert.NotEmpty(t, rotated)
+		assert.NotEqual(t, secret, rotated)
+	})
+
+	t.Run("enabling requires a url", func(t *testing.T) {
+		prefs := models.DefaultUsageAlertPreference()
+		prefs.OrganizationID = &orgID
+
+		_, err := applyAlertPreferences(&prefs, models.UpdateUsageAlertPreferencesRequest{WebhookEnabled: boolPtr(true)})
+		assert.True(t, errors.Is(err, ErrInvalidUsageAlertPreferences), "error = %v", err)
+	})
+
+	t.Run("clearing the url disables the webhook", func(t *testing.T) {
+		prefs := models.DefaultUsageAlertPreference()
+		prefs.OrganizationID = &orgID
+		prefs.WebhookURL, prefs.WebhookSecret, prefs.WebhookEnabled = "https://example.com/hooks", "whsec_old", true
+
+		_, err := applyAlertPreferences(&prefs, models.UpdateUsageAlertPreferencesRequest{WebhookURL: strPtr("")})
+		require.NoError(t, err)
+		assert.False(t, prefs.WebhookEnabled)
+	})
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		env   string
+		valid bool
+	}{
+		{"https://hooks.example.com/usage", "production", true},
+		{"http://localhost:8080/hook", "development", true},
+		{"http://127.0.0.1/hook", "development", true},
+		{"http://localhost:8080/hook", "production", false},
+		{"https://127.0.0.1/hook", "production", false},
+		{"https://10.0.0.5/hook", "production", false},
+		{"https://169.254.169.254/latest/meta-data", "development", false},
+		{"https://[::1]/hook", "production", false},
+		{"http://hooks.example.com/usage", "production", false},
+		{"ftp://hooks.example.com", "production", false},
+		{"/relative/path", "production", false},
+		{"not a url", "production", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env+" "+tt.url, func(t *testing.T) {
+			t.Setenv("GO_ENV", tt.env)
+			err := validateWebhookURL(tt.url)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidUsageAlertPreferences), "error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"type":"usage.alert"}`)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte(`1700000000.{"type":"usage.alert"}`))
+	want := fmt.Sprintf("t=1700000000,v1=%s", hex.EncodeToString(mac.Sum(nil)))
+
+	assert.Equal(t, want, SignWebhookPayload("whsec_test", 1700000000, body))
+	assert.NotEqual(t, want, SignWebhookPayload("whsec_other", 1700000000, body))
+}
+
+func TestPostSignedWebhook(t *testing.T) {
+	// The test server listens on loopback, which only development allows
+	t.Setenv("GO_ENV", "development")
+
+	var gotHeaders http.Header
+	var gotBody []byte
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	payload := models.UsageAlertWebhookPayload{ID: "usage_alert_7", Type: UsageAlertWebhookEvent, AlertID: 7, Threshold: 90}
+	require.NoError(t, postSignedWebhook(context.Background(), server.URL, "whsec_test", payload.ID, payload))
+
+	assert.Equal(t, UsageAlertWebhookEvent, gotHeaders.Get(UsageAlertWebhookEventHeader))
+	assert.Equal(t, "usage_alert_7", gotHeaders.Get(UsageAlertWebhookIDHeader))
+
+	var sent models.UsageAlertWebhookPayload
+	require.NoError(t, json.Unmarshal(gotBody, &sent))
+	assert.Equal(t, payload, sent)
+
+	// The signature verifies against the body that was sent
+	signature := gotHeaders.Get(UsageAlertWebhookSignatureHeader)
+	var timestamp int64
+	_, err := fmt.Sscanf(signature, "t=%d,", &timestamp)
+	require.NoError(t, err)
+	assert.Equal(t, SignWebhookPayload("whsec_test", timestamp, gotBody), signature)
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, postSignedWebhook(context.Background(), server.URL, "whsec_test", payload.ID, payload),
+		"non-2xx responses are retried")
+}
+
+func TestPostSignedWebhook_RefusesInternalAddresses(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/internal", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	payload := models.UsageAlertWebhookPayload{ID: "usage_alert_7", Type: UsageAlertWebhookEvent}
+
+	t.Setenv("GO_ENV", "production")
+	err := postSignedWebhook(context.Background(), server.URL, "whsec_test", payload.ID, payload)
+	assert.ErrorIs(t, err, errWebhookAddressBlocked, "loopback is refused outside development")
+	assert.Zero(t, hits)
+
+	t.Setenv("GO_ENV", "development")
+	err = postSignedWebhook(context.Background(), server.URL+"/redirect", "whsec_test", payload.ID, payload)
+	assert.Error(t, err, "redirects are not followed")
+	assert.Equal(t, 1, hits)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, addr := range []string{"10.1.2.3", "172.16.0.1", "192.168.1.1", "127.0.0.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:10.0.0.1"} {
+		assert.False(t, isPublicIP(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		assert.True(t, isPublicIP(net.ParseIP(addr)), addr)
+	}
+}
+
+func TestUsageAlertFormatting(t *testing.T) {
+	assert.Equal(t, 80, usageAlertThreshold("warning_80"))
+	assert.Equal(t, 100, usageAlertThreshold("exceeded"))
+	assert.Zero(t, usageAlertThreshold("unknown"))
+
+	assert.Equal(t, "0", formatThousands(0))
+	assert.Equal(t, "999", formatThousands(999))
+	assert.Equal(t, "1,000", formatThousands(1000))
+	assert.Equal(t, "-12,345,678", formatThousands(-12345678))
+
+	assert.Equal(t, "9,000", formatUsageAmount(UsageTypeAPICall, 9000))
+	assert.Equal(t, "1.0 GB", formatUsageAmount(UsageTypeStorage, 1<<30))
+	assert.Equal(t, "March 31, 2026", formatPeriodDate("2026-03-31"))
+}
+
+func TestUsageService_GetAlertPreferences_Defaults(t *testing.T) {
+	svc := NewUsageServiceWithRepo(nil, nil, nil, nil)
+	orgID := uint(3)
+
+	prefs, err := svc.GetAlertPreferences(context.Background(), nil, &orgID)
+	require.NoError(t, err)
+	assert.True(t, prefs.EmailEnabled)
+	assert.True(t, prefs.NotifiesAt(80) && prefs.NotifiesAt(90) && prefs.NotifiesAt(100))
+	assert.False(t, prefs.WebhookEnabled)
+	assert.Equal(t, &orgID, prefs.OrganizationID)
+
+	_, err = svc.GetAlertPreferences(context.Background(), nil, nil)
+	assert.Error(t, err)
+}
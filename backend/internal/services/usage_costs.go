@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"react-golang-starter/internal/config"
+	"react-golang-starter/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// usageCostRule is the cost of requests to a route pattern
+type usageCostRule struct {
+	pattern   string
+	method    string
+	eventType string
+	cost      int64
+}
+
+// matches reports whether the rule applies to a request to routePattern
+func (r usageCostRule) matches(eventType, method, routePattern string) bool {
+	if r.eventType != eventType {
+		return false
+	}
+	if r.method != "" && !strings.EqualFold(r.method, method) {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(r.pattern, "*"); ok {
+		return strings.HasPrefix(routePattern, prefix)
+	}
+	return r.pattern == routePattern
+}
+
+// moreSpecific reports whether r should win over other when both match: exact patterns
+// beat wildcards, longer patterns beat shorter ones and method rules beat any-method rules
+func (r usageCostRule) moreSpecific(other usageCostRule) bool {
+	rWild, otherWild := strings.HasSuffix(r.pattern, "*"), strings.HasSuffix(other.pattern, "*")
+	if rWild != otherWild {
+		return !rWild
+	}
+	if len(r.pattern) != len(other.pattern) {
+		return len(r.pattern) > len(other.pattern)
+	}
+	return r.method != "" && other.method == ""
+}
+
+// UsageCostTable weights metered usage by chi route pattern and event type. Plan limits are
+// expressed in the resulting units; usage without a configured cost counts as 1 unit.
+type UsageCostTable struct {
+	mu         sync.RWMutex
+	eventTypes map[string]int64
+	rules      []usageCostRule
+}
+
+// DefaultUsageCostTable returns a table charging 1 unit for everything
+func DefaultUsageCostTable() *UsageCostTable {
+	return &UsageCostTable{eventTypes: make(map[string]int64)}
+}
+
+// NewUsageCostTable builds a cost table from the usage_costs section of the config file
+func NewUsageCostTable(cfg config.UsageCostsConfig) (*UsageCostTable, error) {
+	table := DefaultUsageCostTable()
+	for eventType, cost := range cfg.EventTypes {
+		if err := table.SetEventTypeCost(eventType, cost); err != nil {
+			return DefaultUsageCostTable(), err
+		}
+	}
+	for _, route := range cfg.Routes {
+		if err := table.SetRouteCost(route.Pattern, route.Method, route.EventType, route.Cost); err != nil {
+			return DefaultUsageCostTable(), err
+		}
+	}
+	return table, nil
+}
+
+// LoadFromDB applies the usage_costs rows, which take precedence over the config file
+func (t *UsageCostTable) LoadFromDB(ctx context.Context, db *gorm.DB) error {
+	var rows []models.UsageCost
+	if err := db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load usage costs: %w", err)
+	}
+
+	for _, row := range rows {
+		var err error
+		if row.RoutePattern == "" {
+			err = t.SetEventTypeCost(row.EventType, row.Cost)
+		} else {
+			err = t.SetRouteCost(row.RoutePattern, row.Method, row.EventType, row.Cost)
+		}
+		if err != nil {
+			return fmt.Errorf("usage cost %d: %w", row.ID, err)
+		}
+	}
+	return nil
+}
+
+// SetEventTypeCost sets the cost of every event of a usage type
+func (t *UsageCostTable) SetEventTypeCost(eventType string, cost int64) error {
+	if eventType == "" {
+		return fmt.Errorf("usage cost without an event type")
+	}
+	if cost < 0 {
+		return fmt.Errorf("usage cost for %s must not be negative", eventType)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.eventTypes[eventType] = cost
+	return nil
+}
+
+// SetRouteCost sets the cost of requests to a chi route pattern, replacing an existing
+// rule for the same pattern, method and event type. A pattern ending in * matches every
+// route below it; an empty event type means API calls.
+func (t *UsageCostTable) SetRouteCost(pattern, method, eventType string, cost int64) error {
+	if pattern == "" {
+		return fmt.Errorf("usage cost without a route pattern")
+	}
+	if cost < 0 {
+		return fmt.Errorf("usage cost for %s must not be negative", pattern)
+	}
+	if eventType == "" {
+		eventType = UsageTypeAPICall
+	}
+
+	rule := usageCostRule{pattern: pattern, method: strings.ToUpper(method), eventType: eventType, cost: cost}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, existing := range t.rules {
+		if existing.pattern == rule.pattern && existing.method == rule.method && existing.eventType == rule.eventType {
+			t.rules[i] = rule
+			return nil
+		}
+	}
+	t.rules = append(t.rules, rule)
+	return nil
+}
+
+// Cost returns the units charged for one event of eventType caused by a request to
+// routePattern: the most specific matching route rule, else the event type cost, else 1
+func (t *UsageCostTable) Cost(eventType, method, routePattern string) int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var best *usageCostRule
+	for i := range t.rules {
+		rule := t.rules[i]
+		if routePattern == "" || !rule.matches(eventType, method, routePattern) {
+			continue
+		}
+		if best == nil || rule.moreSpecific(*best) {
+			best = &t.rules[i]
+		}
+	}
+	if best != nil {
+		return best.cost
+	}
+
+	if cost, ok := t.eventTypes[eventType]; ok {
+		return cost
+	}
+	return 1
+}
+
+var (
+	usageCostTable   = DefaultUsageCostTable()
+	usageCostTableMu sync.RWMutex
+)
+
+// GetUsageCostTable returns the cost table used to weight metered usage
+func GetUsageCostTable() *UsageCostTable {
+	usageCostTableMu.RLock()
+	defer usageCostTableMu.RUnlock()
+	return usageCostTable
+}
+
+// SetUsageCostTable replaces the cost table used to weight metered usage
+func SetUsageCostTable(table *UsageCostTable) {
+	usageCostTableMu.Lock()
+	defer usageCostTableMu.Unlock()
+	usageCostTable = table
+}
+
+// ============ Request Quantity ============
+
+type usageMeterKey struct{}
+
+// UsageMeter collects how many billable items a request processed, e.g. the texts in an
+// embedding batch, the AI tokens it used and the bytes it stored. The request's route cost is charged once per item.
+type UsageMeter struct {
+	mu           sync.Mutex
+	quantity     int64
+	aiTokens     []AITokenUsage
+	storageBytes int64
+	reservations []*QuotaReservation
+}
+
+// WithUsageMeter returns a context in which handlers can report the request's quantity
+func WithUsageMeter(ctx context.Context) (context.Context, *UsageMeter) {
+	meter := &UsageMeter{quantity: 1}
+	return ContextWithUsageMeter(ctx, meter), meter
+}
+
+// GetUsageMeter returns the usage meter of a request, nil when none is installed
+func GetUsageMeter(ctx context.Context) *UsageMeter {
+	meter, _ := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	return meter
+}
+
+// ContextWithUsageMeter returns a context carrying an existing meter, e.g. to record a
+// request's usage after the request context is done
+func ContextWithUsageMeter(ctx context.Context, meter *UsageMeter) context.Context {
+	return context.WithValue(ctx, usageMeterKey{}, meter)
+}
+
+// Quantity returns the number of billable items, 1 unless a handler reported otherwise
+func (m *UsageMeter) Quantity() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.quantity
+}
+
+// SetUsageQuantity reports how many billable items the request processed. It is a no-op
+// when no usage meter is installed (e.g. usage metering is disabled).
+func SetUsageQuantity(ctx context.Context, quantity int64) {
+	meter, ok := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	if !ok || quantity < 0 {
+		return
+	}
+
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	meter.quantity = quantity
+}
+
+// AITokenUsage is the token usage of one AI model call
+type AITokenUsage struct {
+	Model        string
+	Operation    string // e.g. chat, chat_stream, embeddings
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// TotalTokens returns the tokens charged against AI budgets
+func (u AITokenUsage) TotalTokens() int64 {
+	return u.InputTokens + u.OutputTokens
+}
+
+// AITokens returns the AI token usage reported during the request
+func (m *UsageMeter) AITokens() []AITokenUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]AITokenUsage(nil), m.aiTokens...)
+}
+
+// AddAITokenUsage reports the token usage of an AI model call made by the request. It is a
+// no-op when no usage meter is installed.
+func AddAITokenUsage(ctx context.Context, usage AITokenUsage) {
+	meter, ok := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	if !ok || usage.InputTokens < 0 || usage.OutputTokens < 0 || usage.TotalTokens() == 0 {
+		return
+	}
+
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	meter.aiTokens = append(meter.aiTokens, usage)
+}
+
+// StorageBytes returns the bytes stored during the request
+func (m *UsageMeter) StorageBytes() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.storageBytes
+}
+
+// AddStorageUsage reports bytes the request stored, e.g. an uploaded file. It is a no-op
+// when no usage meter is installed.
+func AddStorageUsage(ctx context.Context, bytes int64) {
+	meter, ok := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	if !ok || bytes <= 0 {
+		return
+	}
+
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	meter.storageBytes += bytes
+}
+
+// AttachQuotaReservation hands a quota reservation to the request's meter, so recording the
+// request's usage settles it. It returns false when no usage meter is installed.
+func AttachQuotaReservation(ctx context.Context, reservation *QuotaReservation) bool {
+	meter, ok := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	if !ok || reservation == nil {
+		return false
+	}
+
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	meter.reservations = append(meter.reservations, reservation)
+	return true
+}
+
+// ReleaseReservations releases the quota reservations that recording the request's usage
+// did not settle, e.g. because the request failed
+func (m *UsageMeter) ReleaseReservations(ctx context.Context) {
+	m.mu.Lock()
+	reservations := m.reservations
+	m.reservations = nil
+	m.mu.Unlock()
+
+	for _, reservation := range reservations {
+		reservation.Release(ctx)
+	}
+}
+
+// usageReservation returns the request's reservation for a usage type, if any
+func usageReservation(ctx context.Context, usageType string) *QuotaReservation {
+	meter, ok := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	if !ok {
+		return nil
+	}
+
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	for _, reservation := range meter.reservations {
+		if reservation.key.usageType == usageType {
+			return reservation
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"react-golang-starter/internal/config"
+	"react-golang-starter/internal/testutil/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testUsageCostTable(t *testing.T) *UsageCostTable {
+	t.Helper()
+
+	table, err := NewUsageCostTable(config.UsageCostsConfig{
+		EventTypes: map[string]int64{UsageTypeFileUpload: 2},
+		Routes: []config.UsageRouteCost{
+			{Pattern: "/api/ai/*", Cost: 5},
+			{Pattern: "/api/ai/chat", Cost: 10},
+			{Pattern: "/api/ai/chat", Method: "post", Cost: 12},
+			{Pattern: "/api/health", Cost: 0},
+			{Pattern: "/api/files/upload", EventType: UsageTypeFileUpload, Cost: 4},
+		},
+	})
+	require.NoError(t, err)
+	return table
+}
+
+func TestUsageCostTable_Cost(t *testing.T) {
+	table := testUsageCostTable(t)
+
+	tests := []struct {
+		name      string
+		eventType string
+		method    string
+		pattern   string
+		want      int64
+	}{
+		{"method rule wins", UsageTypeAPICall, "POST", "/api/ai/chat", 12},
+		{"any-method rule", UsageTypeAPICall, "GET", "/api/ai/chat", 10},
+		{"wildcard rule", UsageTypeAPICall, "POST", "/api/ai/embeddings", 5},
+		{"free route", UsageTypeAPICall, "GET", "/api/health", 0},
+		{"unconfigured route", UsageTypeAPICall, "GET", "/api/users/me", 1},
+		{"route rule for event type", UsageTypeFileUpload, "POST", "/api/files/upload", 4},
+		{"event type weight", UsageTypeFileUpload, "POST", "/api/other/upload", 2},
+		{"route rules are per event type", UsageTypeAPICall, "POST", "/api/files/upload", 1},
+		{"no route", UsageTypeFileUpload, "", "", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, table.Cost(tt.eventType, tt.method, tt.pattern))
+		})
+	}
+}
+
+func TestUsageCostTable_SetRouteCostReplacesRule(t *testing.T) {
+	table := testUsageCostTable(t)
+
+	require.NoError(t, table.SetRouteCost("/api/ai/chat", "POST", "", 30))
+
+	assert.Equal(t, int64(30), table.Cost(UsageTypeAPICall, "POST", "/api/ai/chat"))
+	assert.Equal(t, int64(10), table.Cost(UsageTypeAPICall, "GET", "/api/ai/chat"))
+}
+
+func TestNewUsageCostTable_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.UsageCostsConfig
+	}{
+		{"negative route cost", config.UsageCostsConfig{Routes: []config.UsageRouteCost{{Pattern: "/api/x", Cost: -1}}}},
+		{"missing pattern", config.UsageCostsConfig{Routes: []config.UsageRouteCost{{Cost: 2}}}},
+		{"negative event type cost", config.UsageCostsConfig{EventTypes: map[string]int64{UsageTypeAPICall: -2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := NewUsageCostTable(tt.cfg)
+			assert.Error(t, err)
+			assert.Equal(t, int64(1), table.Cost(UsageTypeAPICall, "GET", "/api/x"))
+		})
+	}
+}
+
+func TestUsageMeter(t *testing.T) {
+	ctx, meter := WithUsageMeter(context.Background())
+	assert.Equal(t, int64(1), meter.Quantity())
+
+	SetUsageQuantity(ctx, 8)
+	assert.Equal(t, int64(8), meter.Quantity())
+
+	// Without a meter the quantity is dropped
+	SetUsageQuantity(context.Background(), 3)
+}
+
+func TestUsageService_RecordAPIRequest_Weighted(t *testing.T) {
+	previous := GetUsageCostTable()
+	SetUsageCostTable(testUsageCostTable(t))
+	defer SetUsageCostTable(previous)
+
+	ctx := context.Background()
+	eventRepo := mocks.NewMockUsageEventRepository()
+	service := NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+	defer service.Shutdown()
+
+	userID := uint(1)
+	service.RecordAPIRequest(ctx, &userID, nil, "POST", "/api/ai/embeddings", 4, "", "")
+	service.RecordAPIRequest(ctx, &userID, nil, "GET", "/api/health", 1, "", "")
+
+	events := eventRepo.GetEvents()
+	require.Len(t, events, 1, "free routes should not be recorded")
+	assert.Equal(t, int64(20), events[0].Quantity)
+	assert.Equal(t, "/api/ai/embeddings", events[0].Resource)
+
+	current, err := service.CurrentUsage(ctx, &userID, nil, UsageTypeAPICall)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), current)
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"react-golang-starter/internal/cache"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/observability"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// usagePendingPrefix prefixes counters of usage not yet flushed to usage periods
+	usagePendingPrefix = "usage:pending:"
+	// usageTotalPrefix prefixes live period totals used by limit checks
+	usageTotalPrefix = "usage:total:"
+
+	// usageTotalTTL bounds how long a live total may drift from the recorded totals
+	// (e.g. after a failed flush) before it is reseeded from the database
+	usageTotalTTL = 5 * time.Minute
+
+	// usageFlushTimeout bounds a single flush of the usage counters
+	usageFlushTimeout = 30 * time.Second
+
+	// DefaultUsageFlushInterval is how often in-process usage counters are flushed
+	DefaultUsageFlushInterval = time.Minute
+)
+
+// totaledUsageTypes are the usage types aggregated into usage period totals
+var totaledUsageTypes = []string{UsageTypeAPICall, UsageTypeStorage, UsageTypeCompute, UsageTypeFileUpload, UsageTypeAITokens}
+
+// usageCounterKey identifies the counters of one subject, billing period and usage type
+type usageCounterKey struct {
+	subject     string // "user:<id>" or "org:<id>"
+	periodStart string
+	periodEnd   string
+	usageType   string
+}
+
+// newUsageCounterKey returns the counter key for usage by a user or organization. Usage in
+// an organization context counts against the organization only.
+func newUsageCounterKey(userID *uint, orgID *uint, periodStart, periodEnd, usageType string) (usageCounterKey, bool) {
+	key := usageCounterKey{periodStart: periodStart, periodEnd: periodEnd, usageType: usageType}
+	switch {
+	case orgID != nil:
+		key.subject = "org:" + strconv.FormatUint(uint64(*orgID), 10)
+	case userID != nil:
+		key.subject = "user:" + strconv.FormatUint(uint64(*userID), 10)
+	default:
+		return key, false
+	}
+	return key, true
+}
+
+// parseUsageCounterKey parses a pending counter key
+func parseUsageCounterKey(raw string) (usageCounterKey, bool) {
+	parts := strings.Split(strings.TrimPrefix(raw, usagePendingPrefix), ":")
+	if len(parts) != 5 || (parts[0] != "user" && parts[0] != "org") {
+		return usageCounterKey{}, false
+	}
+	if _, err := strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return usageCounterKey{}, false
+	}
+	return usageCounterKey{
+		subject:     parts[0] + ":" + parts[1],
+		periodStart: parts[2],
+		periodEnd:   parts[3],
+		usageType:   parts[4],
+	}, true
+}
+
+func (k usageCounterKey) String() string {
+	return k.subject + ":" + k.periodStart + ":" + k.periodEnd + ":" + k.usageType
+}
+
+// pending returns the key of the usage counted since the last flush
+func (k usageCounterKey) pending() string {
+	return usagePendingPrefix + k.String()
+}
+
+// total returns the key of the live period total
+func (k usageCounterKey) total() string {
+	return usageTotalPrefix + k.String()
+}
+
+// period returns the key without its usage type, identifying the usage period
+func (k usageCounterKey) period() usageCounterKey {
+	k.usageType = ""
+	return k
+}
+
+// owner returns the user or organization the usage period belongs to
+func (k usageCounterKey) owner() (userID *uint, orgID *uint) {
+	kind, rawID, _ := strings.Cut(k.subject, ":")
+	id64, _ := strconv.ParseUint(rawID, 10, 64)
+	id := uint(id64)
+	if kind == "org" {
+		return nil, &id
+	}
+	return &id, nil
+}
+
+// countUsage adds an event to the live counters, falling back to process memory when
+// the shared counters are unavailable
+func (s *UsageService) countUsage(ctx context.Context, event *models.UsageEvent) {
+	if !isTotaledUsageType(event.EventType) {
+		return
+	}
+	key, ok := newUsageCounterKey(event.UserID, event.OrganizationID, event.BillingPeriodStart, event.BillingPeriodEnd, event.EventType)
+	if !ok {
+		return
+	}
+
+	store := s.counters
+	if _, err := store.Increment(ctx, key.pending(), event.Quantity, 0); err != nil {
+		log.Warn().Err(err).Str("usage_type", event.EventType).Msg("usage counters unavailable, counting in memory")
+		observability.RecordUsageCounterFallback()
+		store = s.local
+		if _, err := store.Increment(ctx, key.pending(), event.Quantity, 0); err != nil {
+			observability.RecordUsageDropped(event.EventType, "counter_failed")
+			return
+		}
+	}
+
+	// Usage a quota check reserved is already in the total; only the difference is added
+	delta := event.Quantity
+	if reservation := usageReservation(ctx, event.EventType); reservation != nil {
+		if reservation.key == key {
+			delta -= reservation.take()
+		} else {
+			reservation.Release(ctx)
+		}
+	}
+	if delta == 0 {
+		return
+	}
+
+	// Totals are seeded on the first limit check; until then there is nothing to update
+	if _, err := store.IncrementExisting(ctx, key.total(), delta); err != nil {
+		log.Debug().Err(err).Str("usage_type", event.EventType).Msg("failed to update live usage total")
+	}
+}
+
+// QuotaReservation is usage a quota check added to a live total before the request ran, so
+// concurrent checks see it. Recording the request's usage settles the reservation; Release
+// returns whatever was not recorded.
+type QuotaReservation struct {
+	store cache.Counters
+	key   usageCounterKey
+
+	mu       sync.Mutex
+	reserved int64
+}
+
+// take returns the unsettled reservation and clears it
+func (r *QuotaReservation) take() int64 {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reserved := r.reserved
+	r.reserved = 0
+	return reserved
+}
+
+// Release removes the unsettled reservation from the live total
+func (r *QuotaReservation) Release(ctx context.Context) {
+	reserved := r.take()
+	if reserved == 0 {
+		return
+	}
+	if _, err := r.store.IncrementExisting(ctx, r.key.total(), -reserved); err != nil {
+		log.Debug().Err(err).Str("usage_type", r.key.usageType).Msg("failed to release usage reservation")
+	}
+}
+
+// reserveUsage adds quantity to the live total of a user's or organization's usage and
+// returns the total before it. The increment is atomic, so concurrent reservations each
+// see the others.
+func (s *UsageService) reserveUsage(ctx context.Context, userID *uint, orgID *uint, usageType string, quantity int64) (int64, *QuotaReservation, error) {
+	// Seed the total so the reservation lands on the recorded usage
+	if _, err := s.CurrentUsage(ctx, userID, orgID, usageType); err != nil {
+		return 0, nil, err
+	}
+
+	periodStart, periodEnd := getCurrentBillingPeriod()
+	key, _ := newUsageCounterKey(userID, orgID, periodStart, periodEnd, usageType)
+
+	store := s.counters
+	total, err := store.Increment(ctx, key.total(), quantity, usageTotalTTL)
+	if err != nil {
+		log.Warn().Err(err).Str("usage_type", usageType).Msg("usage counters unavailable, reserving in memory")
+		store = s.local
+		if total, err = store.Increment(ctx, key.total(), quantity, usageTotalTTL); err != nil {
+			return 0, nil, fmt.Errorf("failed to reserve usage: %w", err)
+		}
+	}
+	return total - quantity, &QuotaReservation{store: store, key: key, reserved: quantity}, nil
+}
+
+// CurrentUsage returns a user's or organization's usage of usageType in the current billing
+// period, including usage not yet flushed. It reads a live counter and only queries the
+// database when the counter has to be seeded.
+func (s *UsageService) CurrentUsage(ctx context.Context, userID *uint, orgID *uint, usageType string) (int64, error) {
+	periodStart, periodEnd := getCurrentBillingPeriod()
+	key, ok := newUsageCounterKey(userID, orgID, periodStart, periodEnd, usageType)
+	if !ok {
+		return 0, fmt.Errorf("either user_id or organization_id must be provided")
+	}
+
+	store := s.counters
+	value, found, err := store.Get(ctx, key.total())
+	if err != nil {
+		log.Warn().Err(err).Str("usage_type", usageType).Msg("usage counters unavailable, reading from memory")
+		store = s.local
+		value, found, _ = store.Get(ctx, key.total())
+	}
+	if found {
+		return value, nil
+	}
+
+	recorded, err := s.recordedUsage(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	total := recorded + s.pendingUsage(ctx, key)
+
+	// Another check may have seeded the total meanwhile; prefer its value
+	if created, err := store.SetIfAbsent(ctx, key.total(), total, usageTotalTTL); err == nil && !created {
+		if value, found, err := store.Get(ctx, key.total()); err == nil && found {
+			return value, nil
+		}
+	}
+	return total, nil
+}
+
+// recordedUsage returns the flushed usage total of a counter's usage period
+func (s *UsageService) recordedUsage(ctx context.Context, key usageCounterKey) (int64, error) {
+	if s.db == nil {
+		return 0, nil
+	}
+
+	var period models.UsagePeriod
+	err := usagePeriodQuery(s.db.WithContext(ctx), key).First(&period).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get usage period: %w", err)
+	}
+
+	var totals models.UsageTotals
+	if period.UsageTotals != "" && period.UsageTotals != "{}" {
+		if err := json.Unmarshal([]byte(period.UsageTotals), &totals); err != nil {
+			log.Warn().Err(err).Msg("failed to parse usage totals")
+		}
+	}
+	return usageTotal(totals, key.usageType), nil
+}
+
+// pendingUsage returns the usage counted but not yet flushed for a counter
+func (s *UsageService) pendingUsage(ctx context.Context, key usageCounterKey) int64 {
+	var pending int64
+	if value, _, err := s.counters.Get(ctx, key.pending()); err == nil {
+		pending += value
+	}
+	if s.local != s.counters {
+		if value, _, err := s.local.Get(ctx, key.pending()); err == nil {
+			pending += value
+		}
+	}
+	return pending
+}
+
+// ============ Flushing ============
+
+// FlushCounters moves usage counted in the shared counters into the usage period totals
+// and returns the number of counters flushed
+func (s *UsageService) FlushCounters(ctx context.Context) (int, error) {
+	return s.flushStore(ctx, s.counters)
+}
+
+// flushCounters flushes the in-process counters, and the shared ones when includeShared is set
+func (s *UsageService) flushCounters(ctx context.Context, includeShared bool) (int, error) {
+	var flushed int
+	var errs []error
+	if s.local != s.counters {
+		n, err := s.flushStore(ctx, s.local)
+		flushed += n
+		errs = append(errs, err)
+	}
+	if includeShared || s.local == s.counters {
+		n, err := s.flushStore(ctx, s.counters)
+		flushed += n
+		errs = append(errs, err)
+	}
+	return flushed, errors.Join(errs...)
+}
+
+// StartCounterFlush flushes usage counters every interval until ctx is done. Counters in
+// process memory are only visible here; shared counters are flushed too when includeShared
+// is set, e.g. when the job queue that normally flushes them is disabled.
+func (s *UsageService) StartCounterFlush(ctx context.Context, interval time.Duration, includeShared bool) {
+	if interval <= 0 {
+		interval = DefaultUsageFlushInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("usage counter flush stopped")
+				return
+			case <-ticker.C:
+				flushCtx, cancel := context.WithTimeout(ctx, usageFlushTimeout)
+				if _, err := s.flushCounters(flushCtx, includeShared); err != nil {
+					log.Error().Err(err).Msg("usage counter flush failed")
+				}
+				cancel()
+			}
+		}
+	}()
+
+	log.Info().
+		Dur("interval", interval).
+		Bool("include_shared", includeShared).
+		Msg("usage counter flush started")
+}
+
+// flushStore takes every pending counter from store and adds it to its usage period.
+// Counters whose period cannot be updated are put back for the next flush.
+func (s *UsageService) flushStore(ctx context.Context, store cache.Counters) (int, error) {
+	// Without a database (unit tests) counters stay in place
+	if s.db == nil {
+		return 0, nil
+	}
+
+	keys, err := store.Keys(ctx, usagePendingPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list usage counters: %w", err)
+	}
+
+	periods := make(map[usageCounterKey]map[string]int64)
+	for _, raw := range keys {
+		key, ok := parseUsageCounterKey(raw)
+		if !ok {
+			log.Warn().Str("key", raw).Msg("skipping malformed usage counter")
+			continue
+		}
+		delta, err := store.Take(ctx, raw)
+		if err != nil {
+			log.Warn().Err(err).Str("key", raw).Msg("failed to take usage counter")
+			continue
+		}
+		if delta == 0 {
+			continue
+		}
+		if periods[key.period()] == nil {
+			periods[key.period()] = make(map[string]int64)
+		}
+		periods[key.period()][key.usageType] += delta
+	}
+
+	var flushed int
+	var errs []error
+	for period, deltas := range periods {
+		if err := s.applyUsageDeltas(ctx, period, deltas); err != nil {
+			errs = append(errs, err)
+			s.restoreCounters(ctx, store, period, deltas)
+			continue
+		}
+		flushed += len(deltas)
+		s.checkFlushedLimits(ctx, period)
+	}
+
+	observability.RecordUsageCountersFlushed(flushed)
+	return flushed, errors.Join(errs...)
+}
+
+// applyUsageDeltas adds flushed usage to a usage period, creating the period if needed.
+// The period row is locked so concurrent flushes from other instances cannot lose updates.
+func (s *UsageService) applyUsageDeltas(ctx context.Context, key usageCounterKey, deltas map[string]int64) error {
+	userID, orgID := key.owner()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().Format(time.RFC3339)
+
+		var period models.UsagePeriod
+		err := usagePeriodQuery(tx.Clauses(clause.Locking{Strength: "UPDATE"}), key).First(&period).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			limitsJSON, _ := json.Marshal(s.defaultLimits(ctx, userID, orgID))
+			period = models.UsagePeriod{
+				UserID:         userID,
+				OrganizationID: orgID,
+				PeriodStart:    key.periodStart,
+				PeriodEnd:      key.periodEnd,
+				UsageTotals:    "{}",
+				UsageLimits:    string(limitsJSON),
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+			if err := tx.Create(&period).Error; err != nil {
+				return fmt.Errorf("failed to create usage period: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to get usage period: %w", err)
+		}
+
+		var totals models.UsageTotals
+		if period.UsageTotals != "" && period.UsageTotals != "{}" {
+			if err := json.Unmarshal([]byte(period.UsageTotals), &totals); err != nil {
+				log.Warn().Err(err).Msg("failed to parse usage totals")
+			}
+		}
+		for usageType, delta := range deltas {
+			addUsageTotal(&totals, usageType, delta)
+		}
+
+		totalsJSON, err := json.Marshal(totals)
+		if err != nil {
+			return fmt.Errorf("failed to marshal usage totals: %w", err)
+		}
+
+		if err := tx.Model(&period).Updates(map[string]interface{}{
+			"usage_totals":       string(totalsJSON),
+			"last_aggregated_at": now,
+			"updated_at":         now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update usage period: %w", err)
+		}
+		return nil
+	})
+}
+
+// checkFlushedLimits raises usage alerts for the owner of a flushed usage period. Most
+// usage is recorded by middleware rather than the record endpoint, so this is where
+// alerts for API calls and organizations are created.
+func (s *UsageService) checkFlushedLimits(ctx context.Context, period usageCounterKey) {
+	periodStart, _ := getCurrentBillingPeriod()
+	if period.periodStart != periodStart {
+		return
+	}
+
+	userID, orgID := period.owner()
+	if _, err := s.CheckLimits(ctx, userID, orgID); err != nil {
+		log.Warn().Err(err).Str("subject", period.subject).Msg("usage limit check after flush failed")
+	}
+}
+
+// restoreCounters puts usage that failed to flush back into its counters. Usage that
+// cannot be put back is lost and counted as dropped.
+func (s *UsageService) restoreCounters(ctx context.Context, store cache.Counters, period usageCounterKey, deltas map[string]int64) {
+	for usageType, delta := range deltas {
+		key := period
+		key.usageType = usageType
+		if _, err := store.Increment(ctx, key.pending(), delta, 0); err != nil {
+			log.Error().
+				Err(err).
+				Str("key", key.pending()).
+				Int64("quantity", delta).
+				Msg("failed to restore usage counter, usage dropped")
+			observability.RecordUsageDropped(usageType, "flush_failed")
+		}
+	}
+}
+
+// usagePeriodQuery scopes a query to the usage period of a counter
+func usagePeriodQuery(db *gorm.DB, key usageCounterKey) *gorm.DB {
+	query := db.Where("period_start = ? AND period_end = ?", key.periodStart, key.periodEnd)
+	userID, orgID := key.owner()
+	if orgID != nil {
+		return query.Where("organization_id = ? AND user_id IS NULL", *orgID)
+	}
+	return query.Where("user_id = ?", *userID)
+}
+
+// isTotaledUsageType reports whether usage of a type is aggregated into period totals
+func isTotaledUsageType(usageType string) bool {
+	for _, t := range totaledUsageTypes {
+		if t == usageType {
+			return true
+		}
+	}
+	return false
+}
+
+// addUsageTotal adds delta to the total for a usage type
+func addUsageTotal(totals *models.UsageTotals, usageType string, delta int64) {
+	switch usageType {
+	case UsageTypeAPICall:
+		totals.APICalls += delta
+	case UsageTypeStorage:
+		totals.StorageBytes += delta
+	case UsageTypeCompute:
+		totals.ComputeMS += delta
+	case UsageTypeFileUpload:
+		totals.FileUploads += delta
+	case UsageTypeAITokens:
+		totals.AITokens += delta
+	}
+}
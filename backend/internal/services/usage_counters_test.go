@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/cache"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/testutil/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingCounters is a counter store whose every operation fails, like Redis when it is down
+type failingCounters struct{}
+
+var errCountersDown = errors.New("connection refused")
+
+func (failingCounters) Increment(context.Context, string, int64, time.Duration) (int64, error) {
+	return 0, errCountersDown
+}
+func (failingCounters) IncrementExisting(context.Context, string, int64) (bool, error) {
+	return false, errCountersDown
+}
+func (failingCounters) Get(context.Context, string) (int64, bool, error) {
+	return 0, false, errCountersDown
+}
+func (failingCounters) SetIfAbsent(context.Context, string, int64, time.Duration) (bool, error) {
+	return false, errCountersDown
+}
+func (failingCounters) Take(context.Context, string) (int64, error) { return 0, errCountersDown }
+func (failingCounters) Keys(context.Context, string) ([]string, error) {
+	return nil, errCountersDown
+}
+func (failingCounters) Shared() bool { return true }
+
+// ============ Counter Key Tests ============
+
+func TestUsageCounterKey_RoundTrip(t *testing.T) {
+	orgID := uint(42)
+	key, ok := newUsageCounterKey(nil, &orgID, "2024-06-01", "2024-06-30", UsageTypeStorage)
+	require.True(t, ok)
+
+	parsed, ok := parseUsageCounterKey(key.pending())
+	require.True(t, ok)
+	assert.Equal(t, key, parsed)
+
+	userID, owner := parsed.owner()
+	assert.Nil(t, userID)
+	require.NotNil(t, owner)
+	assert.Equal(t, orgID, *owner)
+}
+
+func TestNewUsageCounterKey_OrgTakesPrecedence(t *testing.T) {
+	userID, orgID := uint(1), uint(2)
+
+	key, ok := newUsageCounterKey(&userID, &orgID, "2024-06-01", "2024-06-30", UsageTypeAPICall)
+	require.True(t, ok)
+	assert.Equal(t, "org:2", key.subject)
+
+	_, ok = newUsageCounterKey(nil, nil, "2024-06-01", "2024-06-30", UsageTypeAPICall)
+	assert.False(t, ok)
+}
+
+func TestParseUsageCounterKey_Malformed(t *testing.T) {
+	for _, raw := range []string{
+		"usage:pending:",
+		"usage:pending:team:1:2024-06-01:2024-06-30:api_call",
+		"usage:pending:user:abc:2024-06-01:2024-06-30:api_call",
+		"usage:pending:user:1:2024-06-01:api_call",
+	} {
+		_, ok := parseUsageCounterKey(raw)
+		assert.False(t, ok, raw)
+	}
+}
+
+// ============ Counting Tests ============
+
+func TestUsageService_CurrentUsage_CountsRecordedEvents(t *testing.T) {
+	ctx := context.Background()
+	service := NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+	userID := uint(1)
+
+	current, err := service.CurrentUsage(ctx, &userID, nil, UsageTypeAPICall)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), current)
+
+	for i := 0; i < 3; i++ {
+		service.RecordAPICall(ctx, &userID, nil, "/api/users", "", "")
+	}
+	service.RecordStorageUsage(ctx, &userID, nil, 512, "notes.txt")
+
+	current, err = service.CurrentUsage(ctx, &userID, nil, UsageTypeAPICall)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), current, "live total follows recorded usage after it is seeded")
+
+	storage, err := service.CurrentUsage(ctx, &userID, nil, UsageTypeStorage)
+	require.NoError(t, err)
+	assert.Equal(t, int64(512), storage, "an unseeded total includes pending usage")
+}
+
+func TestUsageService_CurrentUsage_RequiresSubject(t *testing.T) {
+	service := NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+
+	_, err := service.CurrentUsage(context.Background(), nil, nil, UsageTypeAPICall)
+	assert.Error(t, err)
+}
+
+func TestUsageService_RecordEvent_CountsWhenEventWriteFails(t *testing.T) {
+	ctx := context.Background()
+	eventRepo := mocks.NewMockUsageEventRepository()
+	eventRepo.CreateErr = errors.New("database unavailable")
+	service := NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
+	userID := uint(1)
+
+	err := service.RecordEvent(ctx, &models.UsageEvent{UserID: &userID, EventType: UsageTypeAPICall})
+	assert.Error(t, err)
+
+	current, err := service.CurrentUsage(ctx, &userID, nil, UsageTypeAPICall)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), current, "usage is counted even when the event row is lost")
+}
+
+func TestUsageService_FallsBackToLocalCounters(t *testing.T) {
+	ctx := context.Background()
+	service := NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+	service.counters = failingCounters{}
+	local := cache.NewMemoryCounters()
+	service.local = local
+	userID := uint(1)
+
+	service.RecordAPICall(ctx, &userID, nil, "/api/users", "", "")
+	service.RecordAPICall(ctx, &userID, nil, "/api/users", "", "")
+
+	keys, err := local.Keys(ctx, usagePendingPrefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1, "usage is kept in memory while the shared counters are down")
+
+	current, err := service.CurrentUsage(ctx, &userID, nil, UsageTypeAPICall)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), current)
+}
+
+func TestUsageService_IgnoresUntotaledUsageTypes(t *testing.T) {
+	ctx := context.Background()
+	service := NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+	userID := uint(1)
+
+	service.RecordEvent(ctx, &models.UsageEvent{UserID: &userID, EventType: "custom_metric", Quantity: 5})
+
+	keys, err := service.counters.Keys(ctx, usagePendingPrefix)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+// ============ Flush Tests ============
+
+func TestUsageService_FlushCounters_NoDatabase(t *testing.T) {
+	ctx := context.Background()
+	service := NewUsageServiceWithRepo(nil, mocks.NewMockUsageEventRepository(), nil, nil)
+	userID := uint(1)
+
+	service.RecordAPICall(ctx, &userID, nil, "/api/users", "", "")
+
+	flushed, err := service.FlushCounters(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, flushed)
+
+	keys, _ := service.counters.Keys(ctx, usagePendingPrefix)
+	assert.Len(t, keys, 1, "counters stay in place until a database is available")
+}
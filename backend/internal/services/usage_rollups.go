@@ -0,0 +1,462 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"react-golang-starter/internal/models"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// usageRollupLookback is how far back each rollup run rebuilds buckets, covering usage
+	// events written late (e.g. counters flushed after the hour ended)
+	usageRollupLookback = 2 * time.Hour
+
+	// usageMaintenanceTimeout bounds a single rollup or retention run
+	usageMaintenanceTimeout = 5 * time.Minute
+
+	// usagePartitionsAhead is how many months of usage_events partitions are kept ready
+	usagePartitionsAhead = 3
+	// usagePartitionPrefix names the monthly usage_events partitions (usage_events_pYYYYMM)
+	usagePartitionPrefix = "usage_events_p"
+)
+
+// Usage history granularities and groupings
+const (
+	UsageGranularityHour  = "hour"
+	UsageGranularityDay   = "day"
+	UsageGranularityMonth = "month"
+
+	UsageGroupByEventType = "event_type"
+	UsageGroupByResource  = "resource"
+)
+
+// ErrInvalidUsageHistoryQuery is returned for an unsupported granularity, grouping or range
+var ErrInvalidUsageHistoryQuery = errors.New("invalid usage history query")
+
+// usageGranularityRanges bounds the range of a history query per granularity
+var usageGranularityRanges = map[string]struct {
+	defaultRange time.Duration
+	maxRange     time.Duration
+}{
+	UsageGranularityHour:  {defaultRange: 24 * time.Hour, maxRange: 31 * 24 * time.Hour},
+	UsageGranularityDay:   {defaultRange: 30 * 24 * time.Hour, maxRange: 2 * 366 * 24 * time.Hour},
+	UsageGranularityMonth: {defaultRange: 183 * 24 * time.Hour, maxRange: 10 * 366 * 24 * time.Hour},
+}
+
+// UsageRetention holds how long usage detail is kept. Daily rollups are kept indefinitely.
+type UsageRetention struct {
+	// Raw usage events (USAGE_EVENT_RETENTION_DAYS, default 90, 0 keeps them)
+	Events time.Duration
+	// Hourly rollups (USAGE_HOURLY_ROLLUP_RETENTION_DAYS, default 400, 0 keeps them)
+	HourlyRollups time.Duration
+}
+
+// LoadUsageRetention reads the usage retention policy from the environment
+func LoadUsageRetention() UsageRetention {
+	return UsageRetention{
+		Events:        retentionDaysEnv("USAGE_EVENT_RETENTION_DAYS", 90),
+		HourlyRollups: retentionDaysEnv("USAGE_HOURLY_ROLLUP_RETENTION_DAYS", 400),
+	}
+}
+
+// retentionDaysEnv reads a retention period in days, falling back on invalid values
+func retentionDaysEnv(key string, fallback int) time.Duration {
+	days := fallback
+	if val := os.Getenv(key); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ============ Rollups ============
+
+// RollupUsage rebuilds the hourly and daily usage rollups from the raw usage events
+// written since the last run, and returns the number of rollup rows written.
+// Runs are idempotent: recent buckets are deleted and recomputed.
+func (s *UsageService) RollupUsage(ctx context.Context) (int64, error) {
+	if s.db == nil {
+		return 0, nil
+	}
+
+	start, ok, err := s.rollupStart(ctx)
+	if err != nil || !ok {
+		return 0, err
+	}
+	dayStart := start.Truncate(24 * time.Hour)
+
+	var written int64
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM usage_rollups_hourly WHERE bucket_start >= ?", start).Error; err != nil {
+			return fmt.Errorf("failed to clear hourly rollups: %w", err)
+		}
+		hourly := tx.Exec(`
+			INSERT INTO usage_rollups_hourly (bucket_start, user_id, organization_id, event_type, resource, quantity, event_count)
+			SELECT date_trunc('hour', created_at::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+			       user_id, organization_id, event_type, resource, SUM(quantity), COUNT(*)
+			FROM usage_events
+			WHERE created_at::timestamptz >= ?
+			GROUP BY 1, 2, 3, 4, 5`, start)
+		if hourly.Error != nil {
+			return fmt.Errorf("failed to build hourly rollups: %w", hourly.Error)
+		}
+
+		if err := tx.Exec("DELETE FROM usage_rollups_daily WHERE bucket_start >= ?", dayStart).Error; err != nil {
+			return fmt.Errorf("failed to clear daily rollups: %w", err)
+		}
+		daily := tx.Exec(`
+			INSERT INTO usage_rollups_daily (bucket_start, user_id, organization_id, event_type, resource, quantity, event_count)
+			SELECT date_trunc('day', bucket_start AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+			       user_id, organization_id, event_type, resource, SUM(quantity), SUM(event_count)
+			FROM usage_rollups_hourly
+			WHERE bucket_start >= ?
+			GROUP BY 1, 2, 3, 4, 5`, dayStart)
+		if daily.Error != nil {
+			return fmt.Errorf("failed to build daily rollups: %w", daily.Error)
+		}
+
+		written = hourly.RowsAffected + daily.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Debug().
+		Time("from", start).
+		Int64("rows", written).
+		Msg("usage rollups rebuilt")
+	return written, nil
+}
+
+// rollupStart returns the hour from which rollups are rebuilt: the latest rolled up hour
+// or the lookback window, whichever is earlier, or the oldest event on the first run.
+// ok is false when there is nothing to roll up.
+func (s *UsageService) rollupStart(ctx context.Context) (time.Time, bool, error) {
+	latest, err := s.latestRollupHour(ctx)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	if !latest.Valid {
+		var oldest sql.NullTime
+		if err := s.db.WithContext(ctx).Raw("SELECT MIN(created_at::timestamptz) FROM usage_events").Row().Scan(&oldest); err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to find oldest usage event: %w", err)
+		}
+		if !oldest.Valid {
+			return time.Time{}, false, nil
+		}
+		return oldest.Time.UTC().Truncate(time.Hour), true, nil
+	}
+
+	start := time.Now().UTC().Add(-usageRollupLookback)
+	if latest.Time.Before(start) {
+		start = latest.Time
+	}
+	return start.UTC().Truncate(time.Hour), true, nil
+}
+
+// latestRollupHour returns the most recent hourly rollup bucket
+func (s *UsageService) latestRollupHour(ctx context.Context) (sql.NullTime, error) {
+	var latest sql.NullTime
+	if err := s.db.WithContext(ctx).Raw("SELECT MAX(bucket_start) FROM usage_rollups_hourly").Row().Scan(&latest); err != nil {
+		return latest, fmt.Errorf("failed to find latest usage rollup: %w", err)
+	}
+	return latest, nil
+}
+
+// ============ Retention ============
+
+// EnforceUsageRetention creates upcoming usage_events partitions and removes raw events
+// and hourly rollups past the retention policy, returning the number of rows removed.
+// Nothing newer than the latest rollup is removed, so expired detail is always rolled up first.
+func (s *UsageService) EnforceUsageRetention(ctx context.Context) (int64, error) {
+	if s.db == nil {
+		return 0, nil
+	}
+
+	now := time.Now().UTC()
+	partitioned, err := s.usageEventsPartitioned(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if partitioned {
+		s.ensureUsagePartitions(ctx, now)
+	}
+
+	latest, err := s.latestRollupHour(ctx)
+	if err != nil || !latest.Valid {
+		// Without rollups no raw events can be removed safely
+		return 0, err
+	}
+
+	var removed int64
+	if s.retention.Events > 0 {
+		cutoff := retentionCutoff(now, s.retention.Events, latest.Time)
+		if partitioned {
+			if err := s.dropUsagePartitions(ctx, cutoff); err != nil {
+				return removed, err
+			}
+		}
+		result := s.db.WithContext(ctx).Exec("DELETE FROM usage_events WHERE created_at::timestamptz < ?", cutoff)
+		if result.Error != nil {
+			return removed, fmt.Errorf("failed to remove expired usage events: %w", result.Error)
+		}
+		removed += result.RowsAffected
+	}
+
+	if s.retention.HourlyRollups > 0 {
+		// Daily rollups are rebuilt from the hourly ones from the start of the latest day
+		cutoff := retentionCutoff(now, s.retention.HourlyRollups, latest.Time.UTC().Truncate(24*time.Hour))
+		result := s.db.WithContext(ctx).Exec("DELETE FROM usage_rollups_hourly WHERE bucket_start < ?", cutoff)
+		if result.Error != nil {
+			return removed, fmt.Errorf("failed to remove expired hourly rollups: %w", result.Error)
+		}
+		removed += result.RowsAffected
+	}
+
+	log.Info().
+		Int64("rows", removed).
+		Bool("partitioned", partitioned).
+		Msg("usage retention enforced")
+	return removed, nil
+}
+
+// retentionCutoff returns the time before which data may be removed, never after limit
+func retentionCutoff(now time.Time, retention time.Duration, limit time.Time) time.Time {
+	cutoff := now.Add(-retention)
+	if limit.Before(cutoff) {
+		return limit
+	}
+	return cutoff
+}
+
+// usageEventsPartitioned reports whether usage_events is partitioned by month. Schemas
+// created without the SQL migrations (e.g. GORM auto-migrate in tests) are not.
+func (s *UsageService) usageEventsPartitioned(ctx context.Context) (bool, error) {
+	var partitioned bool
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT EXISTS (
+			SELECT 1 FROM pg_partitioned_table p
+			JOIN pg_class c ON c.oid = p.partrelid
+			WHERE c.relname = 'usage_events'
+		)`).Row().Scan(&partitioned)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect usage_events partitioning: %w", err)
+	}
+	return partitioned, nil
+}
+
+// ensureUsagePartitions creates the partitions for the current and upcoming months. Events
+// of a month that already landed in the default partition are moved into its new partition.
+// A failure is logged as an error since events for the month then pile up in the default
+// partition until a later run succeeds.
+func (s *UsageService) ensureUsagePartitions(ctx context.Context, now time.Time) {
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= usagePartitionsAhead; i++ {
+		monthStart := month.AddDate(0, i, 0).Format("2006-01-02")
+		if err := s.db.WithContext(ctx).Exec("SELECT create_usage_events_partition(?::date)", monthStart).Error; err != nil {
+			log.Error().Err(err).Str("month", monthStart).Msg("failed to create usage events partition")
+		}
+	}
+}
+
+// dropUsagePartitions drops the monthly partitions that end before cutoff
+func (s *UsageService) dropUsagePartitions(ctx context.Context, cutoff time.Time) error {
+	var partitions []string
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = 'usage_events'`).Scan(&partitions).Error
+	if err != nil {
+		return fmt.Errorf("failed to list usage events partitions: %w", err)
+	}
+
+	for _, name := range partitions {
+		monthStart, ok := usagePartitionMonth(name)
+		if !ok || monthStart.AddDate(0, 1, 0).After(cutoff) {
+			continue
+		}
+		// The name is safe to interpolate: it matched the partition naming scheme
+		if err := s.db.WithContext(ctx).Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", name)).Error; err != nil {
+			return fmt.Errorf("failed to drop usage events partition %s: %w", name, err)
+		}
+		log.Info().Str("partition", name).Msg("dropped expired usage events partition")
+	}
+	return nil
+}
+
+// usagePartitionMonth returns the month held by a partition named usage_events_pYYYYMM
+func usagePartitionMonth(name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, usagePartitionPrefix)
+	if !ok || len(suffix) != 6 {
+		return time.Time{}, false
+	}
+	month, err := time.Parse("200601", suffix)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+// StartUsageMaintenance rebuilds usage rollups and enforces usage retention on a schedule
+// until ctx is done, for deployments without the job queue. An interval of 0 disables a task.
+func (s *UsageService) StartUsageMaintenance(ctx context.Context, rollupInterval, retentionInterval time.Duration) {
+	run := func(name string, interval time.Duration, task func(context.Context) (int64, error)) {
+		if interval <= 0 {
+			return
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					taskCtx, cancel := context.WithTimeout(ctx, usageMaintenanceTimeout)
+					if _, err := task(taskCtx); err != nil {
+						log.Error().Err(err).Str("task", name).Msg("usage maintenance failed")
+					}
+					cancel()
+				}
+			}
+		}()
+	}
+
+	run("rollup", rollupInterval, s.RollupUsage)
+	run("retention", retentionInterval, s.EnforceUsageRetention)
+
+	log.Info().
+		Dur("rollup_interval", rollupInterval).
+		Dur("retention_interval", retentionInterval).
+		Msg("usage maintenance started")
+}
+
+// ============ History ============
+
+// UsageHistoryQuery selects usage history from the rollups
+type UsageHistoryQuery struct {
+	UserID         *uint
+	OrganizationID *uint
+
+	// Granularity is hour, day or month; GroupBy is event_type or resource
+	Granularity string
+	GroupBy     string
+
+	// From is inclusive and To exclusive; zero values select the default range ending now
+	From time.Time
+	To   time.Time
+}
+
+// normalize applies defaults and validates the query
+func (q *UsageHistoryQuery) normalize(now time.Time) error {
+	if q.Granularity == "" {
+		q.Granularity = UsageGranularityDay
+	}
+	ranges, ok := usageGranularityRanges[q.Granularity]
+	if !ok {
+		return fmt.Errorf("%w: granularity must be hour, day or month", ErrInvalidUsageHistoryQuery)
+	}
+
+	if q.GroupBy == "" {
+		q.GroupBy = UsageGroupByEventType
+	}
+	if q.GroupBy != UsageGroupByEventType && q.GroupBy != UsageGroupByResource {
+		return fmt.Errorf("%w: group_by must be event_type or resource", ErrInvalidUsageHistoryQuery)
+	}
+
+	if q.To.IsZero() {
+		q.To = now
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-ranges.defaultRange)
+	}
+	q.From, q.To = q.From.UTC(), q.To.UTC()
+
+	if !q.From.Before(q.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidUsageHistoryQuery)
+	}
+	if q.To.Sub(q.From) > ranges.maxRange {
+		return fmt.Errorf("%w: range too long for %s granularity", ErrInvalidUsageHistoryQuery, q.Granularity)
+	}
+	return nil
+}
+
+// GetUsageSeries returns usage per time bucket and group from the rollups. Hourly history
+// reads the hourly rollups; daily and monthly history read the daily rollups, so they
+// cover periods whose raw events have expired. Usage of the current hour appears once
+// the rollup job has run.
+func (s *UsageService) GetUsageSeries(ctx context.Context, q UsageHistoryQuery) (*models.UsageHistorySeriesResponse, error) {
+	if err := q.normalize(time.Now()); err != nil {
+		return nil, err
+	}
+
+	table := "usage_rollups_daily"
+	if q.Granularity == UsageGranularityHour {
+		table = "usage_rollups_hourly"
+	}
+
+	db := s.db.WithContext(ctx).Table(table)
+	switch {
+	case q.OrganizationID != nil:
+		db = db.Where("organization_id = ?", *q.OrganizationID)
+	case q.UserID != nil:
+		// Usage in an organization context belongs to the organization
+		db = db.Where("user_id = ? AND organization_id IS NULL", *q.UserID)
+	default:
+		return nil, fmt.Errorf("either user_id or organization_id must be provided")
+	}
+
+	// Granularity and grouping were validated against fixed values above
+	var rows []struct {
+		Bucket     time.Time
+		Grp        string
+		Quantity   int64
+		EventCount int64
+	}
+	err := db.
+		Select(fmt.Sprintf(
+			"date_trunc('%s', bucket_start AT TIME ZONE 'UTC') AS bucket, %s AS grp, SUM(quantity) AS quantity, SUM(event_count) AS event_count",
+			q.Granularity, q.GroupBy,
+		)).
+		Where("bucket_start >= ? AND bucket_start < ?", q.From, q.To).
+		Group("bucket, grp").
+		Order("bucket, grp").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage series: %w", err)
+	}
+
+	series := &models.UsageHistorySeriesResponse{
+		Granularity: q.Granularity,
+		GroupBy:     q.GroupBy,
+		From:        q.From.Format(time.RFC3339),
+		To:          q.To.Format(time.RFC3339),
+		Points:      make([]models.UsageHistoryPoint, 0, len(rows)),
+	}
+	for _, row := range rows {
+		series.Points = append(series.Points, models.UsageHistoryPoint{
+			// date_trunc on a UTC timestamp returns a UTC wall time without a zone
+			BucketStart: time.Date(row.Bucket.Year(), row.Bucket.Month(), row.Bucket.Day(),
+				row.Bucket.Hour(), 0, 0, 0, time.UTC).Format(time.RFC3339),
+			Group:      row.Grp,
+			Quantity:   row.Quantity,
+			EventCount: row.EventCount,
+		})
+	}
+	return series, nil
+}
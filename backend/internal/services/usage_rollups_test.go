@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageHistoryQuery_Normalize(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 30, 0, 0, time.UTC)
+
+	t.Run("defaults", func(t *testing.T) {
+		q := UsageHistoryQuery{}
+		require.NoError(t, q.normalize(now))
+
+		assert.Equal(t, UsageGranularityDay, q.Granularity)
+		assert.Equal(t, UsageGroupByEventType, q.GroupBy)
+		assert.Equal(t, now, q.To)
+		assert.Equal(t, now.Add(-30*24*time.Hour), q.From)
+	})
+
+	t.Run("hourly default range", func(t *testing.T) {
+		q := UsageHistoryQuery{Granularity: UsageGranularityHour, GroupBy: UsageGroupByResource}
+		require.NoError(t, q.normalize(now))
+
+		assert.Equal(t, now.Add(-24*time.Hour), q.From)
+		assert.Equal(t, UsageGroupByResource, q.GroupBy)
+	})
+
+	t.Run("converts to UTC", func(t *testing.T) {
+		zone := time.FixedZone("UTC+2", 2*60*60)
+		q := UsageHistoryQuery{From: time.Date(2026, 3, 1, 2, 0, 0, 0, zone), To: time.Date(2026, 3, 2, 2, 0, 0, 0, zone)}
+		require.NoError(t, q.normalize(now))
+
+		assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), q.From)
+		assert.Equal(t, time.UTC, q.To.Location())
+	})
+
+	invalid := []struct {
+		name string
+		q    UsageHistoryQuery
+	}{
+		{"unknown granularity", UsageHistoryQuery{Granularity: "week"}},
+		{"unknown grouping", UsageHistoryQuery{GroupBy: "user_agent"}},
+		{"empty range", UsageHistoryQuery{From: now, To: now}},
+		{"reversed range", UsageHistoryQuery{From: now, To: now.Add(-time.Hour)}},
+		{"hourly range too long", UsageHistoryQuery{Granularity: UsageGranularityHour, From: now.AddDate(0, -2, 0), To: now}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.q.normalize(now)
+			assert.True(t, errors.Is(err, ErrInvalidUsageHistoryQuery), "normalize() error = %v", err)
+		})
+	}
+}
+
+func TestUsagePartitionMonth(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   time.Time
+		wantOK bool
+	}{
+		{"usage_events_p202603", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"usage_events_default", time.Time{}, false},
+		{"usage_events_p2026", time.Time{}, false},
+		{"usage_events_p202613", time.Time{}, false},
+		{"other_p202603", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := usagePartitionMonth(tt.name)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.True(t, tt.want.Equal(got), "usagePartitionMonth() = %v, want %v", got, tt.want)
+		})
+	}
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	rolledUp := now.Add(-time.Hour)
+	assert.Equal(t, now.AddDate(0, 0, -90), retentionCutoff(now, 90*24*time.Hour, rolledUp))
+
+	// Events not yet rolled up are kept even past retention
+	stale := now.AddDate(0, 0, -120)
+	assert.Equal(t, stale, retentionCutoff(now, 90*24*time.Hour, stale))
+}
+
+func TestLoadUsageRetention(t *testing.T) {
+	t.Setenv("USAGE_EVENT_RETENTION_DAYS", "30")
+	t.Setenv("USAGE_HOURLY_ROLLUP_RETENTION_DAYS", "invalid")
+
+	retention := LoadUsageRetention()
+	assert.Equal(t, 30*24*time.Hour, retention.Events)
+	assert.Equal(t, 400*24*time.Hour, retention.HourlyRollups)
+
+	t.Setenv("USAGE_EVENT_RETENTION_DAYS", "0")
+	assert.Zero(t, LoadUsageRetention().Events, "0 keeps events forever")
+}
+
+func TestUsageService_MaintenanceWithoutDB(t *testing.T) {
+	svc := NewUsageServiceWithRepo(nil, nil, nil, nil)
+
+	rows, err := svc.RollupUsage(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, rows)
+
+	removed, err := svc.EnforceUsageRetention(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, removed)
+}
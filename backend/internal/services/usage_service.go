@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"time"
 
+	"react-golang-starter/internal/cache"
 	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/observability"
 	"react-golang-starter/internal/repository"
 	"react-golang-starter/internal/websocket"
 
@@ -14,82 +16,62 @@ import (
 	"gorm.io/gorm"
 )
 
-// UsageService handles usage metering operations
+// UsageService handles usage metering operations. Usage is counted in atomic per-subject,
+// per-period counters (Redis when available, else process memory) for hot-path limit
+// checks, and the counters are periodically flushed to the usage period totals.
 type UsageService struct {
 	db         *gorm.DB
 	hub        *websocket.Hub
-	workQueue  chan *models.UsageEvent
-	workerDone chan struct{}
+	counters   cache.Counters // shared across instances when Redis is in use
+	local      cache.Counters // in-process fallback when the shared counters fail
 	eventRepo  repository.UsageEventRepository
 	periodRepo repository.UsagePeriodRepository
 	alertRepo  repository.UsageAlertRepository
+	retention  UsageRetention
 }
 
-const (
-	// maxQueueSize limits buffered usage events to prevent unbounded memory growth
-	maxQueueSize = 1000
-	// numWorkers is the number of concurrent workers processing usage events
-	numWorkers = 3
-)
-
-// NewUsageService creates a new usage service with bounded worker pool
+// NewUsageService creates a new usage service counting usage in the cache counter store
 func NewUsageService(db *gorm.DB) *UsageService {
-	s := &UsageService{
+	return &UsageService{
 		db:         db,
-		workQueue:  make(chan *models.UsageEvent, maxQueueSize),
-		workerDone: make(chan struct{}),
+		counters:   cache.GetCounters(),
+		local:      cache.LocalCounters(),
 		eventRepo:  repository.NewGormUsageEventRepository(db),
 		periodRepo: repository.NewGormUsagePeriodRepository(db),
 		alertRepo:  repository.NewGormUsageAlertRepository(db),
+		retention:  LoadUsageRetention(),
 	}
-
-	// Start worker pool for async usage processing
-	for i := 0; i < numWorkers; i++ {
-		go s.worker(i)
-	}
-
-	return s
 }
 
 // NewUsageServiceWithRepo creates a usage service with injected repositories for testing.
+// It counts usage in its own in-memory counters.
 func NewUsageServiceWithRepo(
 	db *gorm.DB,
 	eventRepo repository.UsageEventRepository,
 	periodRepo repository.UsagePeriodRepository,
 	alertRepo repository.UsageAlertRepository,
 ) *UsageService {
-	s := &UsageService{
+	counters := cache.NewMemoryCounters()
+	return &UsageService{
 		db:         db,
-		workQueue:  make(chan *models.UsageEvent, maxQueueSize),
-		workerDone: make(chan struct{}),
+		counters:   counters,
+		local:      counters,
 		eventRepo:  eventRepo,
 		periodRepo: periodRepo,
 		alertRepo:  alertRepo,
+		retention:  LoadUsageRetention(),
 	}
-
-	// Start worker pool for async usage processing
-	for i := 0; i < numWorkers; i++ {
-		go s.worker(i)
-	}
-
-	return s
 }
 
-// worker processes usage events from the work queue
-func (s *UsageService) worker(id int) {
-	log.Debug().Int("worker_id", id).Msg("usage worker started")
-	for event := range s.workQueue {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		s.updatePeriodTotals(ctx, event)
-		cancel()
-	}
-	log.Debug().Int("worker_id", id).Msg("usage worker stopped")
-}
-
-// Shutdown gracefully stops the worker pool
+// Shutdown flushes pending usage counters so usage counted in process memory is not lost
 func (s *UsageService) Shutdown() {
-	log.Info().Msg("shutting down usage service workers")
-	close(s.workQueue)
+	log.Info().Msg("flushing usage counters before shutdown")
+
+	ctx, cancel := context.WithTimeout(context.Background(), usageFlushTimeout)
+	defer cancel()
+	if _, err := s.flushCounters(ctx, true); err != nil {
+		log.Error().Err(err).Msg("failed to flush usage counters on shutdown")
+	}
 }
 
 // SetHub sets the WebSocket hub for broadcasting alerts
@@ -170,6 +152,7 @@ const (
 	UsageTypeStorage    = "storage"
 	UsageTypeCompute    = "compute"
 	UsageTypeFileUpload = "file_upload"
+	UsageTypeAITokens   = "ai_tokens"
 )
 
 // Default limits for free tier
@@ -178,10 +161,11 @@ var DefaultUsageLimits = models.UsageLimits{
 	StorageBytes: 1073741824, // 1 GB
 	ComputeMS:    3600000,    // 1 hour of compute
 	FileUploads:  100,        // 100 files per month
+	AITokens:     100000,     // 100k AI tokens per month
 }
 
-// TierLimits maps Stripe price IDs to usage limits
-// Price IDs should match your Stripe dashboard configuration
+// TierLimits maps Stripe price IDs to usage limits. These seed the built-in plan catalog;
+// use the entitlements catalog (YAML or plan_entitlements table) to change plan limits.
 var TierLimits = map[string]models.UsageLimits{
 	"": DefaultUsageLimits, // Free tier (no subscription)
 	// Pro tier - 10x free limits
@@ -190,12 +174,14 @@ var TierLimits = map[string]models.UsageLimits{
 		StorageBytes: 10737418240, // 10 GB
 		ComputeMS:    36000000,    // 10 hours of compute
 		FileUploads:  1000,        // 1000 files per month
+		AITokens:     2000000,     // 2M AI tokens per month
 	},
 	"price_pro_yearly": {
 		APICalls:     100000,
 		StorageBytes: 10737418240,
 		ComputeMS:    36000000,
 		FileUploads:  1000,
+		AITokens:     2000000,
 	},
 	// Enterprise tier - 100x free limits
 	"price_enterprise_monthly": {
@@ -203,21 +189,40 @@ var TierLimits = map[string]models.UsageLimits{
 		StorageBytes: 107374182400, // 100 GB
 		ComputeMS:    360000000,    // 100 hours of compute
 		FileUploads:  10000,        // 10k files per month
+		AITokens:     20000000,     // 20M AI tokens per month
 	},
 	"price_enterprise_yearly": {
 		APICalls:     1000000,
 		StorageBytes: 107374182400,
 		ComputeMS:    360000000,
 		FileUploads:  10000,
+		AITokens:     20000000,
 	},
 }
 
-// GetLimitsForPriceID returns usage limits for a Stripe price ID
+// GetLimitsForPriceID returns usage limits for a Stripe price ID from the plan catalog
 func GetLimitsForPriceID(priceID string) models.UsageLimits {
-	if limits, ok := TierLimits[priceID]; ok {
-		return limits
+	return GetEntitlementsService().Catalog().LimitsForPrice(priceID)
+}
+
+// defaultLimits returns the plan limits for a usage period that has none recorded yet
+func (s *UsageService) defaultLimits(ctx context.Context, userID *uint, orgID *uint) models.UsageLimits {
+	var subject EntitlementSubject
+	switch {
+	case orgID != nil:
+		subject = OrgSubject(*orgID)
+	case userID != nil:
+		subject = UserSubject(*userID)
+	default:
+		return DefaultUsageLimits
 	}
-	return DefaultUsageLimits
+
+	ent, err := GetEntitlementsService().Entitlements(ctx, subject)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to resolve entitlements, using default usage limits")
+		return DefaultUsageLimits
+	}
+	return ent.Limits
 }
 
 // UpdateUserLimits updates usage limits for a user based on their subscription tier
@@ -282,32 +287,37 @@ func (s *UsageService) RecordEvent(ctx context.Context, event *models.UsageEvent
 	// Set timestamp
 	event.CreatedAt = time.Now().Format(time.RFC3339)
 
+	// Count first so totals and limit checks stay accurate even if the event row is lost
+	s.countUsage(ctx, event)
+
 	if err := s.eventRepo.Create(ctx, event); err != nil {
+		observability.RecordUsageDropped(event.EventType, "event_write_failed")
 		return fmt.Errorf("failed to record usage event: %w", err)
 	}
 
-	// Queue async update of aggregated totals (bounded worker pool)
-	select {
-	case s.workQueue <- event:
-		// Event queued successfully
-	default:
-		// Queue full - log warning but don't block the request
-		log.Warn().
-			Str("event_type", event.EventType).
-			Msg("usage event queue full, event will be aggregated on next period calculation")
-	}
-
 	return nil
 }
 
 // RecordAPICall is a convenience method for recording API calls
 func (s *UsageService) RecordAPICall(ctx context.Context, userID *uint, orgID *uint, resource string, ipAddress string, userAgent string) {
+	s.RecordAPIRequest(ctx, userID, orgID, "", resource, 1, ipAddress, userAgent)
+}
+
+// RecordAPIRequest records an API call weighted by the usage cost table: the cost of
+// the method and route pattern, once per item the request processed. Requests whose
+// route costs nothing are not recorded.
+func (s *UsageService) RecordAPIRequest(ctx context.Context, userID *uint, orgID *uint, method, routePattern string, items int64, ipAddress string, userAgent string) {
+	units := GetUsageCostTable().Cost(UsageTypeAPICall, method, routePattern) * items
+	if units <= 0 {
+		return
+	}
+
 	event := &models.UsageEvent{
 		UserID:         userID,
 		OrganizationID: orgID,
 		EventType:      UsageTypeAPICall,
-		Resource:       resource,
-		Quantity:       1,
+		Resource:       routePattern,
+		Quantity:       units,
 		Unit:           "count",
 		IPAddress:      ipAddress,
 		UserAgent:      userAgent,
@@ -382,15 +392,27 @@ func (s *UsageService) GetCurrentUsageSummary(ctx context.Context, userID *uint,
 		}
 	}
 
+	// Add usage counted since the last flush
+	subjectUserID, subjectOrgID := userID, orgID
+	if userID != nil {
+		subjectOrgID = nil
+	}
+	if key, ok := newUsageCounterKey(subjectUserID, subjectOrgID, periodStart, periodEnd, ""); ok {
+		for _, usageType := range totaledUsageTypes {
+			key.usageType = usageType
+			addUsageTotal(&totals, usageType, s.pendingUsage(ctx, key))
+		}
+	}
+
 	// Parse or use default limits
 	var limits models.UsageLimits
 	if period.UsageLimits != "" && period.UsageLimits != "{}" {
 		if err := json.Unmarshal([]byte(period.UsageLimits), &limits); err != nil {
 			log.Warn().Err(err).Msg("failed to parse usage limits")
-			limits = DefaultUsageLimits
+			limits = s.defaultLimits(ctx, userID, orgID)
 		}
 	} else {
-		limits = DefaultUsageLimits
+		limits = s.defaultLimits(ctx, userID, orgID)
 	}
 
 	// Calculate percentages
@@ -414,6 +436,9 @@ func (s *UsageService) GetCurrentUsageSummary(ctx context.Context, userID *uint,
 	if limits.FileUploads > 0 {
 		response.Percentages.FileUploads = int(float64(totals.FileUploads) / float64(limits.FileUploads) * 100)
 	}
+	if limits.AITokens > 0 {
+		response.Percentages.AITokens = int(float64(totals.AITokens) / float64(limits.AITokens) * 100)
+	}
 
 	return response, nil
 }
@@ -457,15 +482,25 @@ func (s *UsageService) CheckLimits(ctx context.Context, userID *uint, orgID *uin
 				}
 
 				// Use ON CONFLICT to avoid duplicates - check if it's a new alert
-				result := s.db.WithContext(ctx).
-					Where("user_id = ? AND alert_type = ? AND usage_type = ? AND period_start = ?",
-						userID, alertType, usageType, summary.PeriodStart).
+				query := s.db.WithContext(ctx)
+				if userID != nil {
+					query = query.Where("user_id = ?", *userID)
+				} else {
+					query = query.Where("organization_id = ? AND user_id IS NULL", *orgID)
+				}
+				result := query.
+					Where("alert_type = ? AND usage_type = ? AND period_start = ?", alertType, usageType, summary.PeriodStart).
 					FirstOrCreate(alert)
 
-				// If a new alert was created, broadcast via WebSocket
-				if result.RowsAffected > 0 && s.hub != nil && userID != nil {
+				if result.Error != nil || result.RowsAffected == 0 {
+					continue
+				}
+
+				// A new alert was created: broadcast via WebSocket and queue email/webhook delivery
+				if s.hub != nil && userID != nil {
 					s.broadcastUsageAlert(*userID, alertType, usageType, current, limit, percentage, summary.Limits)
 				}
+				s.notifyUsageAlert(ctx, alert)
 			}
 		}
 	}
@@ -474,6 +509,7 @@ func (s *UsageService) CheckLimits(ctx context.Context, userID *uint, orgID *uin
 	checkUsageType(UsageTypeStorage, summary.Totals.StorageBytes, summary.Limits.StorageBytes, summary.Percentages.StorageBytes)
 	checkUsageType(UsageTypeCompute, summary.Totals.ComputeMS, summary.Limits.ComputeMS, summary.Percentages.ComputeMS)
 	checkUsageType(UsageTypeFileUpload, summary.Totals.FileUploads, summary.Limits.FileUploads, summary.Percentages.FileUploads)
+	checkUsageType(UsageTypeAITokens, summary.Totals.AITokens, summary.Limits.AITokens, summary.Percentages.AITokens)
 
 	return limitsExceeded, nil
 }
@@ -513,84 +549,24 @@ func (s *UsageService) AcknowledgeAlert(ctx context.Context, alertID uint, ackno
 	return nil
 }
 
-// updatePeriodTotals updates the aggregated totals for a usage period
-func (s *UsageService) updatePeriodTotals(ctx context.Context, event *models.UsageEvent) {
-	// Skip if db is not available (unit tests with mock repositories)
-	if s.db == nil {
-		return
-	}
-
-	// Get or create period record
-	var period models.UsagePeriod
-	query := s.db.WithContext(ctx).
-		Where("period_start = ? AND period_end = ?", event.BillingPeriodStart, event.BillingPeriodEnd)
-
-	if event.UserID != nil {
-		query = query.Where("user_id = ?", *event.UserID)
-	} else if event.OrganizationID != nil {
-		query = query.Where("organization_id = ?", *event.OrganizationID)
-	} else {
-		return
-	}
-
-	err := query.First(&period).Error
-	if err == gorm.ErrRecordNotFound {
-		// Create new period
-		limitsJSON, _ := json.Marshal(DefaultUsageLimits)
-		period = models.UsagePeriod{
-			UserID:         event.UserID,
-			OrganizationID: event.OrganizationID,
-			PeriodStart:    event.BillingPeriodStart,
-			PeriodEnd:      event.BillingPeriodEnd,
-			UsageTotals:    "{}",
-			UsageLimits:    string(limitsJSON),
-			CreatedAt:      time.Now().Format(time.RFC3339),
-			UpdatedAt:      time.Now().Format(time.RFC3339),
-		}
-		if err := s.db.WithContext(ctx).Create(&period).Error; err != nil {
-			log.Warn().Err(err).Msg("failed to create usage period")
-			return
-		}
-	} else if err != nil {
-		log.Warn().Err(err).Msg("failed to get usage period")
-		return
-	}
-
-	// Parse current totals
-	var totals models.UsageTotals
-	if period.UsageTotals != "" && period.UsageTotals != "{}" {
-		if err := json.Unmarshal([]byte(period.UsageTotals), &totals); err != nil {
-			log.Warn().Err(err).Msg("failed to parse usage totals")
-		}
-	}
-
-	// Update totals based on event type
-	switch event.EventType {
-	case UsageTypeAPICall:
-		totals.APICalls += event.Quantity
-	case UsageTypeStorage:
-		totals.StorageBytes += event.Quantity
-	case UsageTypeCompute:
-		totals.ComputeMS += event.Quantity
-	case UsageTypeFileUpload:
-		totals.FileUploads += event.Quantity
+// AcknowledgeOrgAlert marks an organization's alert as acknowledged
+func (s *UsageService) AcknowledgeOrgAlert(ctx context.Context, orgID uint, alertID uint, acknowledgedBy uint) error {
+	result := s.db.WithContext(ctx).Model(&models.UsageAlert{}).
+		Where("id = ? AND organization_id = ?", alertID, orgID).
+		Updates(map[string]interface{}{
+			"acknowledged":    true,
+			"acknowledged_at": time.Now().Format(time.RFC3339),
+			"acknowledged_by": acknowledgedBy,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to acknowledge alert: %w", result.Error)
 	}
 
-	// Save updated totals
-	totalsJSON, err := json.Marshal(totals)
-	if err != nil {
-		log.Warn().Err(err).Msg("failed to marshal usage totals")
-		return
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("alert not found: %d", alertID)
 	}
 
-	now := time.Now().Format(time.RFC3339)
-	if err := s.db.WithContext(ctx).Model(&period).Updates(map[string]interface{}{
-		"usage_totals":       string(totalsJSON),
-		"last_aggregated_at": now,
-		"updated_at":         now,
-	}).Error; err != nil {
-		log.Warn().Err(err).Msg("failed to update usage period")
-	}
+	return nil
 }
 
 // getCurrentBillingPeriod returns the start and end dates of the current billing period
@@ -668,6 +644,9 @@ func (s *UsageService) GetUsageHistory(ctx context.Context, userID *uint, orgID
 		if limits.FileUploads > 0 {
 			summary.Percentages.FileUploads = int(float64(totals.FileUploads) / float64(limits.FileUploads) * 100)
 		}
+		if limits.AITokens > 0 {
+			summary.Percentages.AITokens = int(float64(totals.AITokens) / float64(limits.AITokens) * 100)
+		}
 
 		history = append(history, summary)
 	}
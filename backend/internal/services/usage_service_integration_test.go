@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"react-golang-starter/internal/cache"
 	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/testutil"
 
@@ -20,6 +21,9 @@ func testUsageSetup(t *testing.T) (*UsageService, *gorm.DB, func()) {
 	db := testutil.SetupTestDB(t)
 	tt := testutil.NewTestTransaction(t, db)
 	svc := NewUsageService(tt.DB)
+	// Keep counters private to the test so flushes only see its own usage
+	svc.counters = cache.NewMemoryCounters()
+	svc.local = svc.counters
 
 	return svc, tt.DB, func() {
 		svc.Shutdown()
@@ -590,6 +594,200 @@ func TestUsageService_Organization_Integration(t *testing.T) {
 			t.Error("Expected period dates to be set")
 		}
 	})
+
+	t.Run("member usage in org context counts against the organization", func(t *testing.T) {
+		user := createTestUserForUsage(t, db, "orgmember-usage@example.com")
+
+		org := &models.Organization{
+			Name:            "Test Org 3",
+			Slug:            "test-org-usage-3",
+			Plan:            models.OrgPlanFree,
+			CreatedByUserID: user.ID,
+		}
+		db.Create(org)
+
+		event := &models.UsageEvent{
+			UserID:         &user.ID,
+			OrganizationID: &org.ID,
+			EventType:      UsageTypeAPICall,
+			Resource:       "/api/files",
+			Quantity:       1,
+		}
+		event.BillingPeriodStart, event.BillingPeriodEnd = getCurrentBillingPeriod()
+		svc.countUsage(context.Background(), event)
+		if _, err := svc.FlushCounters(context.Background()); err != nil {
+			t.Fatalf("FlushCounters failed: %v", err)
+		}
+
+		orgSummary, err := svc.GetCurrentUsageSummary(context.Background(), nil, &org.ID)
+		if err != nil {
+			t.Fatalf("GetCurrentUsageSummary for org failed: %v", err)
+		}
+		if orgSummary.Totals.APICalls != 1 {
+			t.Errorf("Expected org API calls 1, got %d", orgSummary.Totals.APICalls)
+		}
+
+		userSummary, err := svc.GetCurrentUsageSummary(context.Background(), &user.ID, nil)
+		if err != nil {
+			t.Fatalf("GetCurrentUsageSummary for user failed: %v", err)
+		}
+		if userSummary.Totals.APICalls != 0 {
+			t.Errorf("Expected personal API calls 0, got %d", userSummary.Totals.APICalls)
+		}
+	})
+
+	t.Run("acknowledges only the organization's alerts", func(t *testing.T) {
+		user := createTestUserForUsage(t, db, "orgalerts-usage@example.com")
+
+		org := &models.Organization{
+			Name:            "Test Org 4",
+			Slug:            "test-org-usage-4",
+			Plan:            models.OrgPlanFree,
+			CreatedByUserID: user.ID,
+		}
+		db.Create(org)
+
+		alert := &models.UsageAlert{
+			OrganizationID: &org.ID,
+			AlertType:      "warning_80",
+			UsageType:      UsageTypeAPICall,
+			PeriodStart:    "2024-01-01",
+			PeriodEnd:      "2024-01-31",
+			CreatedAt:      time.Now().Format(time.RFC3339),
+		}
+		db.Create(alert)
+
+		if err := svc.AcknowledgeOrgAlert(context.Background(), org.ID+1000, alert.ID, user.ID); err == nil {
+			t.Error("Expected error acknowledging another organization's alert")
+		}
+		if err := svc.AcknowledgeOrgAlert(context.Background(), org.ID, alert.ID, user.ID); err != nil {
+			t.Fatalf("AcknowledgeOrgAlert failed: %v", err)
+		}
+	})
+}
+
+func TestUsageService_FlushCounters_Integration(t *testing.T) {
+	svc, db, cleanup := testUsageSetup(t)
+	defer cleanup()
+
+	t.Run("moves pending usage into the period totals once", func(t *testing.T) {
+		user := createTestUserForUsage(t, db, "flush@example.com")
+
+		svc.RecordAPICall(context.Background(), &user.ID, nil, "/api/users", "", "")
+		svc.RecordAPICall(context.Background(), &user.ID, nil, "/api/users", "", "")
+
+		// Pending usage is visible before the flush
+		summary, err := svc.GetCurrentUsageSummary(context.Background(), &user.ID, nil)
+		if err != nil {
+			t.Fatalf("GetCurrentUsageSummary failed: %v", err)
+		}
+		if summary.Totals.APICalls != 2 {
+			t.Errorf("Expected 2 API calls before flush, got %d", summary.Totals.APICalls)
+		}
+
+		flushed, err := svc.FlushCounters(context.Background())
+		if err != nil {
+			t.Fatalf("FlushCounters failed: %v", err)
+		}
+		if flushed != 1 {
+			t.Errorf("Expected 1 counter flushed, got %d", flushed)
+		}
+
+		// A second flush has nothing left to add
+		if flushed, _ := svc.FlushCounters(context.Background()); flushed != 0 {
+			t.Errorf("Expected nothing to flush, got %d", flushed)
+		}
+
+		summary, err = svc.GetCurrentUsageSummary(context.Background(), &user.ID, nil)
+		if err != nil {
+			t.Fatalf("GetCurrentUsageSummary failed: %v", err)
+		}
+		if summary.Totals.APICalls != 2 {
+			t.Errorf("Expected 2 API calls after flush, got %d", summary.Totals.APICalls)
+		}
+	})
+}
+
+func TestUsageService_Rollups_Integration(t *testing.T) {
+	svc, db, cleanup := testUsageSetup(t)
+	defer cleanup()
+
+	user := createTestUserForUsage(t, db, "rollup@example.com")
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-3 * time.Hour)
+	events := []models.UsageEvent{
+		{EventType: UsageTypeAPICall, Resource: "/api/users", Quantity: 1, CreatedAt: hour.Format(time.RFC3339)},
+		{EventType: UsageTypeAPICall, Resource: "/api/ai/chat", Quantity: 10, CreatedAt: hour.Add(10 * time.Minute).Format(time.RFC3339)},
+		{EventType: UsageTypeFileUpload, Resource: "/api/files/upload", Quantity: 1, CreatedAt: hour.Add(time.Hour).Format(time.RFC3339)},
+	}
+	for i := range events {
+		events[i].UserID = &user.ID
+		events[i].BillingPeriodStart, events[i].BillingPeriodEnd = getCurrentBillingPeriod()
+		if err := db.Create(&events[i]).Error; err != nil {
+			t.Fatalf("Failed to create usage event: %v", err)
+		}
+	}
+
+	t.Run("rollups are idempotent", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := svc.RollupUsage(context.Background()); err != nil {
+				t.Fatalf("RollupUsage failed: %v", err)
+			}
+		}
+
+		var hourly int64
+		db.Model(&models.UsageHourlyRollup{}).Where("user_id = ?", user.ID).Count(&hourly)
+		if hourly != 3 {
+			t.Errorf("Expected 3 hourly rollups, got %d", hourly)
+		}
+	})
+
+	t.Run("hourly series grouped by event type", func(t *testing.T) {
+		series, err := svc.GetUsageSeries(context.Background(), UsageHistoryQuery{
+			UserID:      &user.ID,
+			Granularity: UsageGranularityHour,
+		})
+		if err != nil {
+			t.Fatalf("GetUsageSeries failed: %v", err)
+		}
+		if len(series.Points) != 2 {
+			t.Fatalf("Expected 2 points, got %+v", series.Points)
+		}
+		first := series.Points[0]
+		if first.BucketStart != hour.Format(time.RFC3339) || first.Group != UsageTypeAPICall || first.Quantity != 11 || first.EventCount != 2 {
+			t.Errorf("Unexpected first point %+v", first)
+		}
+	})
+
+	t.Run("monthly series grouped by resource", func(t *testing.T) {
+		series, err := svc.GetUsageSeries(context.Background(), UsageHistoryQuery{
+			UserID:      &user.ID,
+			Granularity: UsageGranularityMonth,
+			GroupBy:     UsageGroupByResource,
+		})
+		if err != nil {
+			t.Fatalf("GetUsageSeries failed: %v", err)
+		}
+
+		var total int64
+		for _, point := range series.Points {
+			total += point.Quantity
+		}
+		if total != 12 {
+			t.Errorf("Expected 12 units across resources, got %d", total)
+		}
+	})
+
+	t.Run("retention keeps recent events", func(t *testing.T) {
+		if _, err := svc.EnforceUsageRetention(context.Background()); err != nil {
+			t.Fatalf("EnforceUsageRetention failed: %v", err)
+		}
+
+		var remaining int64
+		db.Model(&models.UsageEvent{}).Where("user_id = ?", user.ID).Count(&remaining)
+		if remaining != 3 {
+			t.Errorf("Expected 3 recent events kept, got %d", remaining)
+		}
+	})
 }
 
 func TestUsageService_Shutdown_Integration(t *testing.T) {
@@ -607,3 +805,42 @@ func TestUsageService_Shutdown_Integration(t *testing.T) {
 	// After shutdown, service should handle gracefully (no panic)
 	// Note: We don't test recording after shutdown as that would block
 }
+
+func TestUsageService_AlertPreferences_Integration(t *testing.T) {
+	svc, db, cleanup := testUsageSetup(t)
+	defer cleanup()
+
+	user := createTestUserForUsage(t, db, "alertprefs@example.com")
+	disabled := false
+
+	resp, err := svc.UpdateAlertPreferences(context.Background(), &user.ID, nil, models.UpdateUsageAlertPreferencesRequest{
+		Threshold80: &disabled,
+	})
+	if err != nil {
+		t.Fatalf("UpdateAlertPreferences failed: %v", err)
+	}
+	if resp.Threshold80 || !resp.Threshold90 || !resp.EmailEnabled {
+		t.Errorf("Unexpected preferences after update: %+v", resp)
+	}
+
+	// A second update changes the stored row rather than adding one
+	if _, err := svc.UpdateAlertPreferences(context.Background(), &user.ID, nil, models.UpdateUsageAlertPreferencesRequest{
+		EmailEnabled: &disabled,
+	}); err != nil {
+		t.Fatalf("UpdateAlertPreferences failed: %v", err)
+	}
+
+	prefs, err := svc.GetAlertPreferences(context.Background(), &user.ID, nil)
+	if err != nil {
+		t.Fatalf("GetAlertPreferences failed: %v", err)
+	}
+	if prefs.EmailEnabled || prefs.Threshold80 || !prefs.Threshold100 {
+		t.Errorf("Unexpected stored preferences: %+v", prefs)
+	}
+
+	var count int64
+	db.Model(&models.UsageAlertPreference{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 preferences row, got %d", count)
+	}
+}
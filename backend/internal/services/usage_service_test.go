@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"react-golang-starter/internal/cache"
 	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/testutil/mocks"
 
@@ -359,22 +360,15 @@ func TestTierLimitsEnterpriseGreaterThanPro(t *testing.T) {
 	}
 }
 
-// ============ Service Worker Pool Constants Tests ============
+// ============ Usage Counter Constants Tests ============
 
-func TestWorkerPoolConstants(t *testing.T) {
-	// Verify constants are reasonable values
-	if maxQueueSize < 100 {
-		t.Errorf("maxQueueSize = %d, should be at least 100", maxQueueSize)
+func TestUsageCounterConstants(t *testing.T) {
+	// Live totals must be reseeded well within a billing period
+	if usageTotalTTL < time.Minute || usageTotalTTL > time.Hour {
+		t.Errorf("usageTotalTTL = %v, should be between 1m and 1h", usageTotalTTL)
 	}
-	if maxQueueSize > 10000 {
-		t.Errorf("maxQueueSize = %d, should not exceed 10000 to prevent memory issues", maxQueueSize)
-	}
-
-	if numWorkers < 1 {
-		t.Errorf("numWorkers = %d, should be at least 1", numWorkers)
-	}
-	if numWorkers > 10 {
-		t.Errorf("numWorkers = %d, should not exceed 10 to prevent resource exhaustion", numWorkers)
+	if DefaultUsageFlushInterval <= 0 || DefaultUsageFlushInterval >= usageTotalTTL {
+		t.Errorf("DefaultUsageFlushInterval = %v, should be positive and below usageTotalTTL", DefaultUsageFlushInterval)
 	}
 }
 
@@ -920,14 +914,16 @@ func TestNewUsageServiceWithRepo(t *testing.T) {
 	assert.NotNil(t, service.alertRepo)
 }
 
-func TestNewUsageServiceWithRepo_WorkerPoolStarts(t *testing.T) {
+func TestNewUsageServiceWithRepo_PrivateCounters(t *testing.T) {
 	eventRepo := mocks.NewMockUsageEventRepository()
 	service := NewUsageServiceWithRepo(nil, eventRepo, nil, nil)
 
-	// Service should have work queue initialized
-	require.NotNil(t, service.workQueue)
+	// Test services count in their own memory so tests cannot see each other's usage
+	require.NotNil(t, service.counters)
+	assert.False(t, service.counters.Shared())
+	assert.NotSame(t, cache.LocalCounters(), service.counters)
 
-	// Clean shutdown
+	// Shutdown without a database leaves counters in place
 	service.Shutdown()
 }
 
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Storage drivers selectable with FILE_STORAGE_DRIVER
+const (
+	DriverAuto     = "auto"     // S3 when configured, else database
+	DriverS3       = "s3"       // AWS S3
+	DriverLocal    = "local"    // Local filesystem
+	DriverDatabase = "database" // PostgreSQL bytea
+)
+
+// Config holds file storage configuration
+type Config struct {
+	Driver      string
+	MaxFileSize int64 // Maximum upload size in bytes
+
+	// Resumable uploads are removed when no content arrives for this long
+	UploadExpiry time.Duration
+
+	// Local filesystem settings
+	LocalRoot  string        // Directory files are stored under
+	SigningKey []byte        // HMAC key for signed download URLs
+	URLExpiry  time.Duration // Lifetime of signed download URLs
+}
+
+// DefaultConfig returns the default file storage configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Driver:       DriverAuto,
+		MaxFileSize:  10 << 20, // 10MB
+		UploadExpiry: 24 * time.Hour,
+		LocalRoot:    "./uploads",
+		URLExpiry:    15 * time.Minute,
+	}
+}
+
+// LoadConfig loads file storage configuration from environment variables
+func LoadConfig() *Config {
+	config := DefaultConfig()
+
+	if val := os.Getenv("FILE_STORAGE_DRIVER"); val != "" {
+		config.Driver = strings.ToLower(val)
+	}
+	if val := os.Getenv("MAX_FILE_SIZE_MB"); val != "" {
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil && n > 0 {
+			config.MaxFileSize = n << 20
+		}
+	}
+	if val := os.Getenv("FILE_UPLOAD_EXPIRY_HOURS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.UploadExpiry = time.Duration(n) * time.Hour
+		}
+	}
+	if val := os.Getenv("FILE_STORAGE_LOCAL_ROOT"); val != "" {
+		config.LocalRoot = val
+	}
+	if val := os.Getenv("FILE_STORAGE_URL_EXPIRY_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.URLExpiry = time.Duration(n) * time.Second
+		}
+	}
+
+	// Signed URLs need a stable key across restarts and instances; without a dedicated key,
+	// derive one from the JWT secret so the two are never interchangeable
+	if val := os.Getenv("FILE_STORAGE_SIGNING_KEY"); val != "" {
+		config.SigningKey = []byte(val)
+	} else if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("file-storage-signed-urls"))
+		config.SigningKey = mac.Sum(nil)
+	}
+
+	return config
+}
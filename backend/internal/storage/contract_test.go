@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/testutil"
+)
+
+// ============ FileStorage Contract Tests ============
+
+// memoryFile is an in-memory multipart.File
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+func newTestUpload(name, contentType string, content []byte) (multipart.File, *multipart.FileHeader) {
+	header := &multipart.FileHeader{
+		Filename: name,
+		Header:   textproto.MIMEHeader{"Content-Type": {contentType}},
+		Size:     int64(len(content)),
+	}
+	return memoryFile{bytes.NewReader(content)}, header
+}
+
+// testFileStorageContract checks the behavior every FileStorage backend that stores its own
+// metadata must provide
+func testFileStorageContract(t *testing.T, s FileStorage) {
+	t.Helper()
+	ctx := context.Background()
+	content := []byte("contract test file content\n")
+
+	if !s.IsAvailable() {
+		t.Fatal("IsAvailable() = false, want true")
+	}
+
+	file, header := newTestUpload("report.txt", "text/plain", content)
+	uploaded, err := s.UploadFile(ctx, file, header)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if uploaded.ID == 0 {
+		t.Fatal("UploadFile() should save the file metadata")
+	}
+	if uploaded.FileName != "report.txt" {
+		t.Errorf("FileName = %q, want %q", uploaded.FileName, "report.txt")
+	}
+	if uploaded.ContentType != "text/plain" {
+		t.Errorf("ContentType = %q, want %q", uploaded.ContentType, "text/plain")
+	}
+	if uploaded.FileSize != int64(len(content)) {
+		t.Errorf("FileSize = %d, want %d", uploaded.FileSize, len(content))
+	}
+	if uploaded.Location == "" {
+		t.Error("Location should not be empty")
+	}
+
+	reader, err := s.DownloadFile(ctx, uploaded.ID)
+	if err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	downloaded, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("reading downloaded file error = %v", err)
+	}
+	if !bytes.Equal(downloaded, content) {
+		t.Errorf("DownloadFile() = %q, want %q", downloaded, content)
+	}
+
+	// Range requests seek within the file
+	if size, err := reader.Seek(0, io.SeekEnd); err != nil || size != int64(len(content)) {
+		t.Errorf("Seek(0, io.SeekEnd) = %d, %v, want %d", size, err, len(content))
+	}
+	if _, err := reader.Seek(9, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	part := make([]byte, 4)
+	if _, err := io.ReadFull(reader, part); err != nil || string(part) != "test" {
+		t.Errorf("read after Seek() = %q, %v, want %q", part, err, "test")
+	}
+	if err := reader.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	tooLarge, tooLargeHeader := newTestUpload("large.txt", "text/plain", content)
+	if _, err := s.UploadFile(ctx, LimitReader(tooLarge, 4), tooLargeHeader); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("UploadFile() over the size limit error = %v, want ErrFileTooLarge", err)
+	}
+
+	url, err := s.GetFileURL(ctx, uploaded.ID)
+	if err != nil {
+		t.Fatalf("GetFileURL() error = %v", err)
+	}
+	if url == "" {
+		t.Error("GetFileURL() should not be empty")
+	}
+
+	if err := s.DeleteFile(ctx, uploaded.ID); err != nil {
+		t.Fatalf("DeleteFile() error = %v", err)
+	}
+	if _, err := s.DownloadFile(ctx, uploaded.ID); err == nil {
+		t.Error("DownloadFile() after DeleteFile() should return error")
+	}
+	if err := s.DeleteFile(ctx, uploaded.ID); err == nil {
+		t.Error("DeleteFile() of a deleted file should return error")
+	}
+	if _, err := s.DownloadFile(ctx, 0); err == nil {
+		t.Error("DownloadFile() of a missing file should return error")
+	}
+}
+
+func setupContractDB(t *testing.T) {
+	t.Helper()
+	testutil.SkipIfNotIntegration(t)
+
+	db := testutil.SetupTestDB(t)
+	tt := testutil.NewTestTransaction(t, db)
+
+	oldDB := database.DB
+	database.DB = tt.DB
+	t.Cleanup(func() {
+		database.DB = oldDB
+		tt.Rollback()
+	})
+}
+
+func TestDatabaseStorage_Contract_Integration(t *testing.T) {
+	setupContractDB(t)
+
+	testFileStorageContract(t, NewDatabaseStorage())
+}
+
+func TestDatabaseStorage_ChunkedContent_Integration(t *testing.T) {
+	setupContractDB(t)
+	ctx := context.Background()
+	s := NewDatabaseStorage()
+
+	// Two and a half chunks, with each byte identifying its position
+	content := make([]byte, 2*databaseChunkSize+databaseChunkSize/2)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	file, header := newTestUpload("large.bin", "application/octet-stream", content)
+	uploaded, err := s.UploadFile(ctx, file, header)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	var chunks int64
+	database.DB.Model(&models.FileChunk{}).Where("file_id = ?", uploaded.ID).Count(&chunks)
+	if chunks != 3 {
+		t.Errorf("UploadFile() stored %d chunks, want 3", chunks)
+	}
+
+	reader, err := s.DownloadFile(ctx, uploaded.ID)
+	if err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	defer reader.Close()
+
+	// A read across a chunk boundary continues in the next chunk
+	start := int64(databaseChunkSize - 2)
+	if _, err := reader.Seek(start, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	part := make([]byte, 4)
+	if _, err := io.ReadFull(reader, part); err != nil || !bytes.Equal(part, content[start:start+4]) {
+		t.Errorf("read across chunks = %v, %v, want %v", part, err, content[start:start+4])
+	}
+
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	downloaded, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(downloaded, content) {
+		t.Errorf("DownloadFile() returned %d bytes, %v, want the %d uploaded", len(downloaded), err, len(content))
+	}
+}
+
+func TestLocalStorage_Contract_Integration(t *testing.T) {
+	setupContractDB(t)
+
+	s, err := NewLocalStorage(&Config{
+		LocalRoot:  t.TempDir(),
+		SigningKey: []byte("test-signing-key"),
+		URLExpiry:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+
+	testFileStorageContract(t, s)
+}
@@ -29,46 +29,68 @@ func (d *DatabaseStorage) IsAvailable() bool {
 	return d.db != nil
 }
 
-// UploadFile uploads a file to database storage
-func (d *DatabaseStorage) UploadFile(ctx context.Context, file multipart.File, header *multipart.FileHeader) (*models.File, error) {
+// databaseChunkSize is the size of the file_chunks rows file content is stored in
+const databaseChunkSize = 1 << 20
+
+// UploadFile uploads a file to database storage. Content is inserted as file_chunks rows
+// inside a transaction, so the whole file is never held in memory, each byte is written
+// once, and a failed upload leaves no row behind.
+func (d *DatabaseStorage) UploadFile(ctx context.Context, r io.Reader, header *multipart.FileHeader) (*models.File, error) {
 	if !d.IsAvailable() {
 		return nil, fmt.Errorf("database storage not available")
 	}
 
-	// Read file content
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file content: %w", err)
-	}
-
-	// Create file record with content
+	// Create file record, starting with empty content
 	fileModel := &models.File{
 		FileName:    header.Filename,
 		ContentType: header.Header.Get("Content-Type"),
-		FileSize:    int64(len(fileContent)),
 		Location:    fmt.Sprintf("db_file_%d", time.Now().UnixNano()), // Unique identifier for database storage
-		Content:     fileContent,
 		StorageType: "database",
 		CreatedAt:   time.Now().Format(time.RFC3339),
 		UpdatedAt:   time.Now().Format(time.RFC3339),
 	}
 
-	// Save to database
-	if err := d.db.Create(fileModel).Error; err != nil {
-		return nil, fmt.Errorf("failed to save file to database: %w", err)
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(fileModel).Error; err != nil {
+			return fmt.Errorf("failed to save file to database: %w", err)
+		}
+
+		buf := make([]byte, databaseChunkSize)
+		for {
+			n, readErr := io.ReadFull(r, buf)
+			if n > 0 {
+				chunk := &models.FileChunk{FileID: fileModel.ID, Offset: fileModel.FileSize, Data: buf[:n]}
+				if err := tx.Create(chunk).Error; err != nil {
+					return fmt.Errorf("failed to save file to database: %w", err)
+				}
+				fileModel.FileSize += int64(n)
+			}
+			if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+				break
+			}
+			if readErr != nil {
+				return fmt.Errorf("failed to read file content: %w", readErr)
+			}
+		}
+
+		return tx.Exec("UPDATE files SET file_size = ? WHERE id = ?", fileModel.FileSize, fileModel.ID).Error
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return fileModel, nil
 }
 
-// DownloadFile downloads a file from database storage
-func (d *DatabaseStorage) DownloadFile(ctx context.Context, fileID uint) ([]byte, error) {
+// DownloadFile opens a file in database storage. Content is read a chunk row at a time as
+// the returned reader is read.
+func (d *DatabaseStorage) DownloadFile(ctx context.Context, fileID uint) (io.ReadSeekCloser, error) {
 	if !d.IsAvailable() {
 		return nil, fmt.Errorf("database storage not available")
 	}
 
 	var file models.File
-	if err := d.db.First(&file, fileID).Error; err != nil {
+	if err := d.db.WithContext(ctx).Omit("content").First(&file, fileID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("file not found")
 		}
@@ -79,7 +101,7 @@ func (d *DatabaseStorage) DownloadFile(ctx context.Context, fileID uint) ([]byte
 		return nil, fmt.Errorf("file is not stored in database")
 	}
 
-	return file.Content, nil
+	return &chunkReader{db: d.db.WithContext(ctx), fileID: fileID, size: file.FileSize}, nil
 }
 
 // DeleteFile deletes a file from database storage
@@ -89,7 +111,7 @@ func (d *DatabaseStorage) DeleteFile(ctx context.Context, fileID uint) error {
 	}
 
 	var file models.File
-	if err := d.db.First(&file, fileID).Error; err != nil {
+	if err := d.db.WithContext(ctx).Omit("content").First(&file, fileID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return fmt.Errorf("file not found")
 		}
@@ -112,3 +134,61 @@ func (d *DatabaseStorage) GetFileURL(ctx context.Context, fileID uint) (string,
 	// For database storage, return an API endpoint URL
 	return fmt.Sprintf("/api/files/%d/download", fileID), nil
 }
+
+// chunkReader reads the content of a database-stored file a chunk row at a time, seeking
+// without queries
+type chunkReader struct {
+	db        *gorm.DB
+	fileID    uint
+	size      int64
+	offset    int64
+	buf       []byte
+	bufOffset int64
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if c.offset >= c.size {
+		return 0, io.EOF
+	}
+
+	if c.offset < c.bufOffset || c.offset >= c.bufOffset+int64(len(c.buf)) {
+		// The chunk holding the offset is the last one starting at or before it
+		var chunk models.FileChunk
+		err := c.db.Where("file_id = ? AND chunk_offset <= ?", c.fileID, c.offset).
+			Order("chunk_offset DESC").
+			Take(&chunk).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return 0, fmt.Errorf("failed to read file from database: %w", err)
+		}
+		if err == gorm.ErrRecordNotFound || c.offset >= chunk.Offset+int64(len(chunk.Data)) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		c.buf, c.bufOffset = chunk.Data, chunk.Offset
+	}
+
+	n := copy(p, c.buf[c.offset-c.bufOffset:])
+	c.offset += int64(n)
+	return n, nil
+}
+
+func (c *chunkReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.offset
+	case io.SeekEnd:
+		offset += c.size
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position: %d", offset)
+	}
+	c.offset = offset
+	return offset, nil
+}
+
+func (c *chunkReader) Close() error {
+	c.buf = nil
+	return nil
+}
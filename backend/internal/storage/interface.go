@@ -2,17 +2,23 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"io"
 	"mime/multipart"
 	"react-golang-starter/internal/models"
 )
 
+// ErrFileTooLarge is returned when an upload exceeds the maximum file size
+var ErrFileTooLarge = errors.New("file exceeds the maximum file size")
+
 // FileStorage defines the interface for file storage operations
 type FileStorage interface {
-	// UploadFile uploads a file to the storage backend
-	UploadFile(ctx context.Context, file multipart.File, header *multipart.FileHeader) (*models.File, error)
+	// UploadFile streams a file to the storage backend. The header provides the file name
+	// and content type; its size is not trusted.
+	UploadFile(ctx context.Context, r io.Reader, header *multipart.FileHeader) (*models.File, error)
 
-	// DownloadFile retrieves a file from the storage backend
-	DownloadFile(ctx context.Context, fileID uint) ([]byte, error)
+	// DownloadFile opens a file in the storage backend for streaming. The caller must close it.
+	DownloadFile(ctx context.Context, fileID uint) (io.ReadSeekCloser, error)
 
 	// DeleteFile removes a file from the storage backend
 	DeleteFile(ctx context.Context, fileID uint) error
@@ -23,3 +29,30 @@ type FileStorage interface {
 	// IsAvailable checks if the storage backend is available
 	IsAvailable() bool
 }
+
+// LimitReader returns a reader that reads from r and fails with ErrFileTooLarge once more
+// than n bytes have been read, so uploads are rejected while streaming instead of after
+func LimitReader(r io.Reader, n int64) io.Reader {
+	return &limitedReader{r: r, remaining: n}
+}
+
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrFileTooLarge
+	}
+	// Read one byte past the limit to tell a file of exactly n bytes from a larger one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrFileTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Signed URL errors
+var (
+	ErrInvalidSignature = errors.New("invalid file URL signature")
+	ErrURLExpired       = errors.New("file URL has expired")
+)
+
+// localTempDir holds partially written files under the storage root, so the final rename
+// stays on the same filesystem and is atomic
+const localTempDir = ".tmp"
+
+// LocalStorage implements FileStorage on the local filesystem. Files are stored under a root
+// directory in a two-level sharded layout (ab/cd/<uuid>.ext) and written atomically through a
+// temp file and rename. Metadata is kept in the files table, and downloads are served through
+// expiring HMAC-signed URLs.
+type LocalStorage struct {
+	db         *gorm.DB
+	root       string
+	signingKey []byte
+	urlExpiry  time.Duration
+}
+
+// NewLocalStorage creates a new local filesystem storage instance, creating the root directory
+func NewLocalStorage(cfg *Config) (*LocalStorage, error) {
+	if cfg.LocalRoot == "" {
+		return nil, fmt.Errorf("local storage root not configured")
+	}
+	if len(cfg.SigningKey) == 0 {
+		return nil, fmt.Errorf("local storage signing key not configured")
+	}
+
+	root, err := filepath.Abs(cfg.LocalRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve local storage root: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, localTempDir), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create local storage root: %w", err)
+	}
+
+	return &LocalStorage{
+		db:         database.DB,
+		root:       root,
+		signingKey: cfg.SigningKey,
+		urlExpiry:  cfg.URLExpiry,
+	}, nil
+}
+
+// IsAvailable checks if local storage is available
+func (l *LocalStorage) IsAvailable() bool {
+	return l.db != nil && l.root != ""
+}
+
+// UploadFile writes a file to disk and saves its metadata
+func (l *LocalStorage) UploadFile(ctx context.Context, r io.Reader, header *multipart.FileHeader) (*models.File, error) {
+	if !l.IsAvailable() {
+		return nil, fmt.Errorf("local storage not available")
+	}
+
+	key := newLocalKey(header.Filename)
+	size, err := l.writeFile(key, r)
+	if err != nil {
+		return nil, err
+	}
+
+	fileModel := &models.File{
+		FileName:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		FileSize:    size,
+		Location:    key,
+		StorageType: "local",
+		CreatedAt:   time.Now().Format(time.RFC3339),
+		UpdatedAt:   time.Now().Format(time.RFC3339),
+	}
+
+	if err := l.db.WithContext(ctx).Create(fileModel).Error; err != nil {
+		if cleanupErr := l.DeleteFileWithKey(ctx, key); cleanupErr != nil {
+			return nil, fmt.Errorf("failed to save file metadata: %w (cleanup failed: %v)", err, cleanupErr)
+		}
+		return nil, fmt.Errorf("failed to save file metadata: %w", err)
+	}
+
+	return fileModel, nil
+}
+
+// DownloadFile opens a file on disk
+func (l *LocalStorage) DownloadFile(ctx context.Context, fileID uint) (io.ReadSeekCloser, error) {
+	if !l.IsAvailable() {
+		return nil, fmt.Errorf("local storage not available")
+	}
+
+	file, err := l.findFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := l.path(file.Location)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("file not found")
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return f, nil
+}
+
+// DeleteFile removes a file from disk and deletes its metadata
+func (l *LocalStorage) DeleteFile(ctx context.Context, fileID uint) error {
+	if !l.IsAvailable() {
+		return fmt.Errorf("local storage not available")
+	}
+
+	file, err := l.findFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	if err := l.DeleteFileWithKey(ctx, file.Location); err != nil {
+		return err
+	}
+	if err := l.db.WithContext(ctx).Delete(file).Error; err != nil {
+		return fmt.Errorf("failed to delete file metadata: %w", err)
+	}
+
+	return nil
+}
+
+// GetFileURL returns a signed download URL for the file that expires after the configured expiry
+func (l *LocalStorage) GetFileURL(ctx context.Context, fileID uint) (string, error) {
+	if !l.IsAvailable() {
+		return "", fmt.Errorf("local storage not available")
+	}
+
+	return l.SignedURL(fileID, time.Now().Add(l.urlExpiry)), nil
+}
+
+// SignedURL returns a download URL for the file that is valid until expires
+func (l *LocalStorage) SignedURL(fileID uint, expires time.Time) string {
+	unix := expires.Unix()
+	return fmt.Sprintf("/api/files/signed/%d?expires=%d&signature=%s", fileID, unix, l.sign(fileID, unix))
+}
+
+// VerifySignedURL checks the expires and signature query parameters of a signed download URL
+func (l *LocalStorage) VerifySignedURL(fileID uint, expires, signature string) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || signature == "" {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(fileID, unix))) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > unix {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+// DeleteFileWithKey removes a file from disk using its key. Removing a missing file succeeds.
+func (l *LocalStorage) DeleteFileWithKey(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file from disk: %w", err)
+	}
+	return nil
+}
+
+// sign returns the hex HMAC-SHA256 of a file ID and expiry
+func (l *LocalStorage) sign(fileID uint, expires int64) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	fmt.Fprintf(mac, "%d:%d", fileID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// findFile loads the metadata of a locally stored file
+func (l *LocalStorage) findFile(ctx context.Context, fileID uint) (*models.File, error) {
+	var file models.File
+	if err := l.db.WithContext(ctx).Omit("content").First(&file, fileID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("file not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve file metadata: %w", err)
+	}
+
+	if file.StorageType != "local" {
+		return nil, fmt.Errorf("file is not stored on the local filesystem")
+	}
+
+	return &file, nil
+}
+
+// writeFile atomically writes r to key, returning the number of bytes written. Readers never
+// see a partial file: content goes to a temp file that is synced and renamed into place.
+func (l *LocalStorage) writeFile(key string, r io.Reader) (int64, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(l.root, localTempDir), "upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	size, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("failed to move file into place: %w", err)
+	}
+
+	return size, nil
+}
+
+// path resolves a storage key to a path under the root, rejecting keys that escape it
+func (l *LocalStorage) path(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(filepath.FromSlash(key)) || strings.HasPrefix(key, localTempDir+"/") {
+		return "", fmt.Errorf("invalid storage key")
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// newLocalKey returns a unique sharded storage key keeping the file's extension
+func newLocalKey(fileName string) string {
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	ext := strings.ToLower(filepath.Ext(filepath.Base(fileName)))
+	if len(ext) > 16 || strings.ContainsAny(ext, `/\`) {
+		ext = ""
+	}
+	return fmt.Sprintf("%s/%s/%s%s", id[0:2], id[2:4], id, ext)
+}
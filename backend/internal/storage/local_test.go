@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+	s, err := NewLocalStorage(&Config{
+		LocalRoot:  t.TempDir(),
+		SigningKey: []byte("test-signing-key"),
+		URLExpiry:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	return s
+}
+
+// ============ LocalStorage Construction Tests ============
+
+func TestNewLocalStorage_MissingConfig(t *testing.T) {
+	if _, err := NewLocalStorage(&Config{SigningKey: []byte("key")}); err == nil {
+		t.Error("NewLocalStorage() should return error without a root")
+	}
+	if _, err := NewLocalStorage(&Config{LocalRoot: t.TempDir()}); err == nil {
+		t.Error("NewLocalStorage() should return error without a signing key")
+	}
+}
+
+func TestNewLocalStorage_CreatesRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "nested", "uploads")
+
+	if _, err := NewLocalStorage(&Config{LocalRoot: root, SigningKey: []byte("key")}); err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(root, localTempDir)); err != nil || !info.IsDir() {
+		t.Errorf("NewLocalStorage() should create %s under the root", localTempDir)
+	}
+}
+
+func TestLocalStorage_MethodsReturnErrorWhenNotAvailable(t *testing.T) {
+	s := &LocalStorage{root: t.TempDir()}
+	ctx := context.Background()
+
+	if s.IsAvailable() {
+		t.Error("IsAvailable() should return false when db is nil")
+	}
+	if _, err := s.UploadFile(ctx, nil, nil); err == nil {
+		t.Error("UploadFile() should return error when not available")
+	}
+	if _, err := s.DownloadFile(ctx, 1); err == nil {
+		t.Error("DownloadFile() should return error when not available")
+	}
+	if err := s.DeleteFile(ctx, 1); err == nil {
+		t.Error("DeleteFile() should return error when not available")
+	}
+	if _, err := s.GetFileURL(ctx, 1); err == nil {
+		t.Error("GetFileURL() should return error when not available")
+	}
+}
+
+// ============ LocalStorage Layout Tests ============
+
+func TestNewLocalKey(t *testing.T) {
+	pattern := regexp.MustCompile(`^([0-9a-f]{2})/([0-9a-f]{2})/([0-9a-f]{32})(\.[a-z0-9]*)?$`)
+
+	tests := []struct {
+		fileName string
+		ext      string
+	}{
+		{"report.PDF", ".pdf"},
+		{"archive.tar.gz", ".gz"},
+		{"noextension", ""},
+		{"../../etc/passwd.txt", ".txt"},
+		{"file." + strings.Repeat("x", 32), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			key := newLocalKey(tt.fileName)
+			m := pattern.FindStringSubmatch(key)
+			if m == nil {
+				t.Fatalf("newLocalKey(%q) = %q, want sharded key", tt.fileName, key)
+			}
+			if m[1] != m[3][0:2] || m[2] != m[3][2:4] {
+				t.Errorf("newLocalKey(%q) = %q, shards should be the ID's first bytes", tt.fileName, key)
+			}
+			if m[4] != tt.ext {
+				t.Errorf("newLocalKey(%q) extension = %q, want %q", tt.fileName, m[4], tt.ext)
+			}
+		})
+	}
+
+	if newLocalKey("a.txt") == newLocalKey("a.txt") {
+		t.Error("newLocalKey() should return unique keys")
+	}
+}
+
+func TestLocalStorage_Path_RejectsUnsafeKeys(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	for _, key := range []string{"", "../outside.txt", "ab/../../outside.txt", "/etc/passwd", localTempDir + "/upload-1"} {
+		if _, err := s.path(key); err == nil {
+			t.Errorf("path(%q) should return error", key)
+		}
+	}
+
+	path, err := s.path("ab/cd/abcd.txt")
+	if err != nil {
+		t.Fatalf("path() error = %v", err)
+	}
+	if want := filepath.Join(s.root, "ab", "cd", "abcd.txt"); path != want {
+		t.Errorf("path() = %q, want %q", path, want)
+	}
+}
+
+func TestLocalStorage_WriteFile(t *testing.T) {
+	s := newTestLocalStorage(t)
+	content := []byte("hello local storage")
+	key := newLocalKey("hello.txt")
+
+	size, err := s.writeFile(key, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("writeFile() error = %v", err)
+	}
+	if size != int64(len(content)) {
+		t.Errorf("writeFile() size = %d, want %d", size, len(content))
+	}
+
+	path, _ := s.path(key)
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("stored content = %q, want %q", got, content)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(s.root, localTempDir))
+	if len(entries) != 0 {
+		t.Errorf("writeFile() left %d temp files behind", len(entries))
+	}
+}
+
+func TestLocalStorage_WriteFile_FailedWriteLeavesNothing(t *testing.T) {
+	s := newTestLocalStorage(t)
+	key := newLocalKey("broken.txt")
+
+	if _, err := s.writeFile(key, &failingReader{}); err == nil {
+		t.Fatal("writeFile() should return error when the reader fails")
+	}
+
+	path, _ := s.path(key)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("writeFile() should not create the file when the write fails")
+	}
+	entries, _ := os.ReadDir(filepath.Join(s.root, localTempDir))
+	if len(entries) != 0 {
+		t.Errorf("writeFile() left %d temp files behind", len(entries))
+	}
+}
+
+type failingReader struct{ read bool }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if !r.read {
+		r.read = true
+		return copy(p, "partial"), nil
+	}
+	return 0, errors.New("connection reset")
+}
+
+func TestLocalStorage_DeleteFileWithKey(t *testing.T) {
+	s := newTestLocalStorage(t)
+	key := newLocalKey("delete.txt")
+
+	if _, err := s.writeFile(key, strings.NewReader("bye")); err != nil {
+		t.Fatalf("writeFile() error = %v", err)
+	}
+	if err := s.DeleteFileWithKey(context.Background(), key); err != nil {
+		t.Fatalf("DeleteFileWithKey() error = %v", err)
+	}
+	path, _ := s.path(key)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("DeleteFileWithKey() should remove the file")
+	}
+	if err := s.DeleteFileWithKey(context.Background(), key); err != nil {
+		t.Errorf("DeleteFileWithKey() of a missing file error = %v", err)
+	}
+}
+
+// ============ LocalStorage Signed URL Tests ============
+
+func TestLocalStorage_SignedURL(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	signed, err := url.Parse(s.SignedURL(42, time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatalf("SignedURL() returned an invalid URL: %v", err)
+	}
+	if signed.Path != "/api/files/signed/42" {
+		t.Errorf("SignedURL() path = %q, want %q", signed.Path, "/api/files/signed/42")
+	}
+
+	expires := signed.Query().Get("expires")
+	signature := signed.Query().Get("signature")
+
+	if err := s.VerifySignedURL(42, expires, signature); err != nil {
+		t.Errorf("VerifySignedURL() error = %v", err)
+	}
+	if err := s.VerifySignedURL(43, expires, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifySignedURL() for another file error = %v, want ErrInvalidSignature", err)
+	}
+	if err := s.VerifySignedURL(42, expires+"0", signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifySignedURL() with a changed expiry error = %v, want ErrInvalidSignature", err)
+	}
+	if err := s.VerifySignedURL(42, expires, ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifySignedURL() without a signature error = %v, want ErrInvalidSignature", err)
+	}
+	if err := s.VerifySignedURL(42, "soon", signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifySignedURL() with an invalid expiry error = %v, want ErrInvalidSignature", err)
+	}
+
+	other := &LocalStorage{signingKey: []byte("another-key")}
+	if err := other.VerifySignedURL(42, expires, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifySignedURL() with another key error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestLocalStorage_SignedURL_Expired(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	signed, _ := url.Parse(s.SignedURL(7, time.Now().Add(-time.Second)))
+	err := s.VerifySignedURL(7, signed.Query().Get("expires"), signed.Query().Get("signature"))
+	if !errors.Is(err, ErrURLExpired) {
+		t.Errorf("VerifySignedURL() error = %v, want ErrURLExpired", err)
+	}
+}
+
+// ============ Config Tests ============
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("FILE_STORAGE_DRIVER", "Local")
+	t.Setenv("FILE_STORAGE_LOCAL_ROOT", "/var/lib/app/files")
+	t.Setenv("FILE_STORAGE_URL_EXPIRY_SECONDS", "60")
+	t.Setenv("FILE_STORAGE_SIGNING_KEY", "")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+
+	cfg := LoadConfig()
+	if cfg.Driver != DriverLocal {
+		t.Errorf("Driver = %q, want %q", cfg.Driver, DriverLocal)
+	}
+	if cfg.LocalRoot != "/var/lib/app/files" {
+		t.Errorf("LocalRoot = %q, want %q", cfg.LocalRoot, "/var/lib/app/files")
+	}
+	if cfg.URLExpiry != time.Minute {
+		t.Errorf("URLExpiry = %v, want %v", cfg.URLExpiry, time.Minute)
+	}
+	if len(cfg.SigningKey) == 0 || string(cfg.SigningKey) == "jwt-secret" {
+		t.Error("SigningKey should be derived from, not equal to, JWT_SECRET")
+	}
+
+	t.Setenv("FILE_STORAGE_SIGNING_KEY", "dedicated-key")
+	if got := string(LoadConfig().SigningKey); got != "dedicated-key" {
+		t.Errorf("SigningKey = %q, want %q", got, "dedicated-key")
+	}
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	t.Setenv("FILE_STORAGE_DRIVER", "")
+	t.Setenv("FILE_STORAGE_LOCAL_ROOT", "")
+	t.Setenv("FILE_STORAGE_URL_EXPIRY_SECONDS", "-5")
+
+	cfg := LoadConfig()
+	if cfg.Driver != DriverAuto {
+		t.Errorf("Driver = %q, want %q", cfg.Driver, DriverAuto)
+	}
+	if cfg.URLExpiry != DefaultConfig().URLExpiry {
+		t.Errorf("URLExpiry = %v, want default %v", cfg.URLExpiry, DefaultConfig().URLExpiry)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 )
 
@@ -62,18 +63,16 @@ func (s *S3Storage) IsAvailable() bool {
 	return s.client != nil
 }
 
-// UploadFile uploads a file to S3
-func (s *S3Storage) UploadFile(ctx context.Context, file multipart.File, header *multipart.FileHeader) (*models.File, error) {
+// s3PartSize is the size of the parts files are streamed to S3 in; S3 requires parts of
+// at least 5 MiB except the last
+const s3PartSize = 5 << 20
+
+// UploadFile streams a file to S3
+func (s *S3Storage) UploadFile(ctx context.Context, r io.Reader, header *multipart.FileHeader) (*models.File, error) {
 	if !s.IsAvailable() {
 		return nil, fmt.Errorf("S3 storage not available")
 	}
 
-	// Read file content
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file content: %w", err)
-	}
-
 	// Generate unique filename
 	fileExt := filepath.Ext(header.Filename)
 	fileName := strings.TrimSuffix(header.Filename, fileExt)
@@ -81,23 +80,16 @@ func (s *S3Storage) UploadFile(ctx context.Context, file multipart.File, header
 	s3Key := fmt.Sprintf("uploads/%s/%s_%s%s", time.Now().Format("2006-01-02"), fileName, uniqueID, fileExt)
 
 	// Upload to S3
-	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:        aws.String(s.bucketName),
-		Key:           aws.String(s3Key),
-		Body:          bytes.NewReader(fileContent),
-		ContentType:   aws.String(header.Header.Get("Content-Type")),
-		ContentLength: aws.Int64(int64(len(fileContent))),
-		ACL:           "private", // Set to private for security
-	})
+	size, err := s.putObject(ctx, s3Key, r, header.Header.Get("Content-Type"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload file to S3: %w", err)
+		return nil, err
 	}
 
 	// Create file record
 	fileModel := &models.File{
 		FileName:    header.Filename,
 		ContentType: header.Header.Get("Content-Type"),
-		FileSize:    int64(len(fileContent)),
+		FileSize:    size,
 		Location:    s3Key,
 		StorageType: "s3",
 		CreatedAt:   time.Now().Format(time.RFC3339),
@@ -107,8 +99,94 @@ func (s *S3Storage) UploadFile(ctx context.Context, file multipart.File, header
 	return fileModel, nil
 }
 
+// putObject streams r to S3 and returns the number of bytes uploaded. Content that fits in
+// one part is uploaded in a single request; larger content is uploaded with a multipart
+// upload, holding one part in memory at a time.
+func (s *S3Storage) putObject(ctx context.Context, s3Key string, r io.Reader, contentType string) (int64, error) {
+	buf := make([]byte, s3PartSize)
+	n, readErr := io.ReadFull(r, buf)
+	if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+		_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(s.bucketName),
+			Key:           aws.String(s3Key),
+			Body:          bytes.NewReader(buf[:n]),
+			ContentType:   aws.String(contentType),
+			ContentLength: aws.Int64(int64(n)),
+			ACL:           "private", // Set to private for security
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to upload file to S3: %w", err)
+		}
+		return int64(n), nil
+	}
+	if readErr != nil {
+		return 0, fmt.Errorf("failed to read file content: %w", readErr)
+	}
+
+	upload, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucketName),
+		Key:         aws.String(s3Key),
+		ContentType: aws.String(contentType),
+		ACL:         "private",
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start S3 multipart upload: %w", err)
+	}
+
+	// Abort on failure so S3 doesn't keep the uploaded parts
+	abort := func(err error) (int64, error) {
+		_, abortErr := s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucketName),
+			Key:      aws.String(s3Key),
+			UploadId: upload.UploadId,
+		})
+		if abortErr != nil {
+			return 0, fmt.Errorf("%w (abort failed: %v)", err, abortErr)
+		}
+		return 0, err
+	}
+
+	var parts []types.CompletedPart
+	var size int64
+	for partNumber := int32(1); ; partNumber++ {
+		part, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(s.bucketName),
+			Key:           aws.String(s3Key),
+			UploadId:      upload.UploadId,
+			PartNumber:    aws.Int32(partNumber),
+			Body:          bytes.NewReader(buf[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		})
+		if err != nil {
+			return abort(fmt.Errorf("failed to upload file part to S3: %w", err))
+		}
+		parts = append(parts, types.CompletedPart{ETag: part.ETag, PartNumber: aws.Int32(partNumber)})
+		size += int64(n)
+
+		n, readErr = io.ReadFull(r, buf)
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil && readErr != io.ErrUnexpectedEOF {
+			return abort(fmt.Errorf("failed to read file content: %w", readErr))
+		}
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucketName),
+		Key:             aws.String(s3Key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("failed to complete S3 multipart upload: %w", err))
+	}
+
+	return size, nil
+}
+
 // DownloadFile downloads a file from S3
-func (s *S3Storage) DownloadFile(ctx context.Context, fileID uint) ([]byte, error) {
+func (s *S3Storage) DownloadFile(ctx context.Context, fileID uint) (io.ReadSeekCloser, error) {
 	if !s.IsAvailable() {
 		return nil, fmt.Errorf("S3 storage not available")
 	}
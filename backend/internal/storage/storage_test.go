@@ -2,6 +2,9 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -213,6 +216,10 @@ func TestDatabaseStorage_ImplementsFileStorageInterface(t *testing.T) {
 	var _ FileStorage = (*DatabaseStorage)(nil)
 }
 
+func TestLocalStorage_ImplementsFileStorageInterface(t *testing.T) {
+	var _ FileStorage = (*LocalStorage)(nil)
+}
+
 // ============ S3Storage Structure Tests ============
 
 func TestS3Storage_Structure(t *testing.T) {
@@ -268,3 +275,41 @@ func TestDatabaseStorage_ErrorMessages(t *testing.T) {
 		t.Error("DownloadFile error should mention database storage not available")
 	}
 }
+
+// ============ LimitReader Tests ============
+
+func TestLimitReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		limit   int64
+		wantErr bool
+	}{
+		{"under limit", "hello", 10, false},
+		{"exactly at limit", "hello", 5, false},
+		{"over limit", "hello!", 5, true},
+		{"empty", "", 0, false},
+		{"over zero limit", "x", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(LimitReader(strings.NewReader(tt.content), tt.limit))
+			if tt.wantErr {
+				if !errors.Is(err, ErrFileTooLarge) {
+					t.Errorf("ReadAll() error = %v, want ErrFileTooLarge", err)
+				}
+				if int64(len(got)) > tt.limit {
+					t.Errorf("ReadAll() returned %d bytes, more than the %d byte limit", len(got), tt.limit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != tt.content {
+				t.Errorf("ReadAll() = %q, want %q", got, tt.content)
+			}
+		})
+	}
+}
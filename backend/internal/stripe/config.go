@@ -2,7 +2,9 @@ package stripe
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds Stripe configuration
@@ -16,20 +18,39 @@ type Config struct {
 	Enabled           bool
 	PremiumPriceID    string // Stripe Price ID for premium subscription (Pro tier)
 	EnterprisePriceID string // Stripe Price ID for enterprise subscription
+
+	// APIBaseURL overrides the Stripe API endpoint, e.g. to use the local fake server
+	APIBaseURL string
+
+	// Billing meter event names for metered usage (empty disables reporting that metric)
+	MeterAPICalls       string
+	MeterStorageGBHours string
+	MeterAITokens       string
+
+	// Dunning: how long a past-due subscription keeps its plan, and when reminders go out
+	// (offsets from the first failed payment)
+	DunningGracePeriod time.Duration
+	DunningReminders   []time.Duration
+
+	// Free trial length in days for each plan; 0 disables the trial for that plan
+	PremiumTrialDays    int64
+	EnterpriseTrialDays int64
 }
 
 // DefaultConfig returns the default Stripe configuration
 func DefaultConfig() *Config {
 	return &Config{
-		SecretKey:         "",
-		PublishableKey:    "",
-		WebhookSecret:     "",
-		SuccessURL:        "http://localhost:5173/billing/success",
-		CancelURL:         "http://localhost:5173/billing/cancel",
-		PortalReturnURL:   "http://localhost:5173/billing",
-		Enabled:           false,
-		PremiumPriceID:    "",
-		EnterprisePriceID: "",
+		SecretKey:          "",
+		PublishableKey:     "",
+		WebhookSecret:      "",
+		SuccessURL:         "http://localhost:5173/billing/success",
+		CancelURL:          "http://localhost:5173/billing/cancel",
+		PortalReturnURL:    "http://localhost:5173/billing",
+		Enabled:            false,
+		PremiumPriceID:     "",
+		EnterprisePriceID:  "",
+		DunningGracePeriod: 14 * 24 * time.Hour,
+		DunningReminders:   []time.Duration{3 * 24 * time.Hour, 7 * 24 * time.Hour, 12 * 24 * time.Hour},
 	}
 }
 
@@ -61,6 +82,38 @@ func LoadConfig() *Config {
 	if val := os.Getenv("STRIPE_ENTERPRISE_PRICE_ID"); val != "" {
 		config.EnterprisePriceID = val
 	}
+	if val := os.Getenv("STRIPE_API_BASE_URL"); val != "" {
+		config.APIBaseURL = val
+	}
+	if val := os.Getenv("STRIPE_METER_API_CALLS"); val != "" {
+		config.MeterAPICalls = val
+	}
+	if val := os.Getenv("STRIPE_METER_STORAGE_GB_HOURS"); val != "" {
+		config.MeterStorageGBHours = val
+	}
+	if val := os.Getenv("STRIPE_METER_AI_TOKENS"); val != "" {
+		config.MeterAITokens = val
+	}
+
+	if val := os.Getenv("STRIPE_PREMIUM_TRIAL_DAYS"); val != "" {
+		if days, err := strconv.ParseInt(val, 10, 64); err == nil && days >= 0 {
+			config.PremiumTrialDays = days
+		}
+	}
+	if val := os.Getenv("STRIPE_ENTERPRISE_TRIAL_DAYS"); val != "" {
+		if days, err := strconv.ParseInt(val, 10, 64); err == nil && days >= 0 {
+			config.EnterpriseTrialDays = days
+		}
+	}
+
+	if val := os.Getenv("STRIPE_DUNNING_GRACE_DAYS"); val != "" {
+		if days, err := strconv.Atoi(val); err == nil && days >= 0 {
+			config.DunningGracePeriod = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	if val := os.Getenv("STRIPE_DUNNING_REMINDER_DAYS"); val != "" {
+		config.DunningReminders = parseDayOffsets(val)
+	}
 
 	// Enable Stripe if secret key is provided
 	if val := os.Getenv("STRIPE_ENABLED"); val != "" {
@@ -72,6 +125,38 @@ func LoadConfig() *Config {
 	return config
 }
 
+// parseDayOffsets parses a comma-separated list of day counts, skipping invalid entries
+func parseDayOffsets(val string) []time.Duration {
+	var offsets []time.Duration
+	for _, part := range strings.Split(val, ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || days <= 0 {
+			continue
+		}
+		offsets = append(offsets, time.Duration(days)*24*time.Hour)
+	}
+	return offsets
+}
+
+// HasMeters returns true if any metered usage metric is configured
+func (c *Config) HasMeters() bool {
+	return c.MeterAPICalls != "" || c.MeterStorageGBHours != "" || c.MeterAITokens != ""
+}
+
+// TrialDays returns the free trial length configured for a price, or 0 if it has no trial
+func (c *Config) TrialDays(priceID string) int64 {
+	switch {
+	case priceID == "":
+		return 0
+	case priceID == c.PremiumPriceID:
+		return c.PremiumTrialDays
+	case priceID == c.EnterprisePriceID:
+		return c.EnterpriseTrialDays
+	default:
+		return 0
+	}
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if !c.Enabled {
@@ -0,0 +1,571 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+)
+
+// couponCodePattern restricts internal coupon codes to what customers can type reliably
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,50}$`)
+
+// Discount is a promotion or coupon code resolved for checkout. Internal coupons apply
+// their Stripe coupon directly; codes created in Stripe apply the promotion code.
+type Discount struct {
+	Code             string
+	CouponID         string
+	PromotionCodeID  string
+	Name             string
+	PercentOff       float64
+	AmountOff        int64
+	Currency         string
+	Duration         string
+	DurationInMonths int64
+
+	// Products the discount is restricted to (empty means any)
+	products []string
+}
+
+// sessionParams returns the checkout session discount for d
+func (d *Discount) sessionParams() *stripe.CheckoutSessionDiscountParams {
+	if d.PromotionCodeID != "" {
+		return &stripe.CheckoutSessionDiscountParams{PromotionCode: stripe.String(d.PromotionCodeID)}
+	}
+	return &stripe.CheckoutSessionDiscountParams{Coupon: stripe.String(d.CouponID)}
+}
+
+// appliesTo returns ErrPromotionCodeNotApplicable if d cannot be used with p
+func (d *Discount) appliesTo(p *stripe.Price) error {
+	if len(d.products) > 0 && (p.Product == nil || !slices.Contains(d.products, p.Product.ID)) {
+		return ErrPromotionCodeNotApplicable
+	}
+	if d.AmountOff > 0 && !strings.EqualFold(d.Currency, string(p.Currency)) {
+		return ErrPromotionCodeNotApplicable
+	}
+	return nil
+}
+
+// discountAmount returns how much d takes off an amount, never more than the amount
+func (d *Discount) discountAmount(amount int64) int64 {
+	off := d.AmountOff
+	if d.PercentOff > 0 {
+		off = int64(math.Round(float64(amount) * d.PercentOff / 100))
+	}
+	return min(off, amount)
+}
+
+func (d *Discount) preview() *models.PromotionCodePreview {
+	return &models.PromotionCodePreview{
+		Code:             d.Code,
+		Name:             d.Name,
+		PercentOff:       d.PercentOff,
+		AmountOff:        d.AmountOff,
+		Currency:         d.Currency,
+		Duration:         d.Duration,
+		DurationInMonths: d.DurationInMonths,
+	}
+}
+
+func discountFromCoupon(c *models.Coupon) *Discount {
+	return &Discount{
+		Code:             c.Code,
+		CouponID:         c.StripeCouponID,
+		Name:             c.Name,
+		PercentOff:       c.PercentOff,
+		AmountOff:        c.AmountOff,
+		Currency:         c.Currency,
+		Duration:         c.Duration,
+		DurationInMonths: c.DurationInMonths,
+	}
+}
+
+func discountFromPromotionCode(pc *stripe.PromotionCode) *Discount {
+	d := &Discount{
+		Code:             pc.Code,
+		CouponID:         pc.Coupon.ID,
+		PromotionCodeID:  pc.ID,
+		Name:             pc.Coupon.Name,
+		PercentOff:       pc.Coupon.PercentOff,
+		AmountOff:        pc.Coupon.AmountOff,
+		Currency:         string(pc.Coupon.Currency),
+		Duration:         string(pc.Coupon.Duration),
+		DurationInMonths: pc.Coupon.DurationInMonths,
+	}
+	if pc.Coupon.AppliesTo != nil {
+		d.products = pc.Coupon.AppliesTo.Products
+	}
+	return d
+}
+
+// normalizeCouponCode trims and uppercases a code as entered by a customer
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ResolveDiscount looks up a code among internal coupons first, then Stripe promotion codes
+func ResolveDiscount(ctx context.Context, svc Service, code string) (*Discount, error) {
+	code = normalizeCouponCode(code)
+	if code == "" {
+		return nil, ErrInvalidPromotionCode
+	}
+
+	if database.DB != nil {
+		var coupon models.Coupon
+		err := database.DB.WithContext(ctx).Where("code = ?", code).First(&coupon).Error
+		if err == nil {
+			if !coupon.IsRedeemable(time.Now()) {
+				return nil, ErrInvalidPromotionCode
+			}
+			return discountFromCoupon(&coupon), nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	pc, err := svc.FindPromotionCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	expired := pc.ExpiresAt > 0 && time.Now().Unix() >= pc.ExpiresAt
+	if !pc.Active || expired || pc.Coupon == nil || !pc.Coupon.Valid {
+		return nil, ErrInvalidPromotionCode
+	}
+	return discountFromPromotionCode(pc), nil
+}
+
+// resolveDiscountForPrice resolves code and checks it can be used with priceID
+func resolveDiscountForPrice(ctx context.Context, svc Service, code, priceID string) (*Discount, *stripe.Price, error) {
+	discount, err := ResolveDiscount(ctx, svc, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if priceID == "" {
+		return discount, nil, nil
+	}
+
+	p, err := svc.GetPrice(ctx, priceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := discount.appliesTo(p); err != nil {
+		return nil, nil, err
+	}
+	return discount, p, nil
+}
+
+// ResolveCheckoutDiscount resolves the code a customer entered for a checkout of priceID
+func ResolveCheckoutDiscount(ctx context.Context, svc Service, code, priceID string) (*Discount, error) {
+	discount, _, err := resolveDiscountForPrice(ctx, svc, code, priceID)
+	return discount, err
+}
+
+// PreviewDiscount describes the discount code would apply, including the discounted
+// first payment for priceID when one is given
+func PreviewDiscount(ctx context.Context, svc Service, code, priceID string) (*models.PromotionCodePreview, error) {
+	discount, p, err := resolveDiscountForPrice(ctx, svc, code, priceID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := discount.preview()
+	if p != nil {
+		preview.PriceID = priceID
+		preview.OriginalAmount = p.UnitAmount
+		preview.DiscountAmount = discount.discountAmount(p.UnitAmount)
+		preview.FinalAmount = p.UnitAmount - preview.DiscountAmount
+		if preview.Currency == "" {
+			preview.Currency = string(p.Currency)
+		}
+	}
+	return preview, nil
+}
+
+// DiscountErrorStatus maps promotion code errors to an HTTP status and message
+func DiscountErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrInvalidPromotionCode):
+		return http.StatusBadRequest, "Promotion code is invalid or expired"
+	case errors.Is(err, ErrPromotionCodeNotApplicable):
+		return http.StatusBadRequest, "Promotion code does not apply to this plan"
+	case errors.Is(err, ErrDisabled):
+		return http.StatusServiceUnavailable, "Billing is not configured"
+	default:
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.HTTPStatusCode == http.StatusNotFound {
+			return http.StatusBadRequest, "Invalid price ID"
+		}
+		return http.StatusInternalServerError, "Failed to validate promotion code"
+	}
+}
+
+// subscriptionDiscount mirrors the discount on a Stripe subscription. Codes from Stripe
+// promotion codes are only present when expanded, so internal coupons fill in their own.
+func subscriptionDiscount(ctx context.Context, sub *stripe.Subscription) models.SubscriptionDiscount {
+	if sub.Discount == nil || sub.Discount.Coupon == nil {
+		return models.SubscriptionDiscount{}
+	}
+
+	c := sub.Discount.Coupon
+	discount := models.SubscriptionDiscount{
+		CouponID:   c.ID,
+		Name:       c.Name,
+		PercentOff: c.PercentOff,
+		AmountOff:  c.AmountOff,
+		Currency:   string(c.Currency),
+		Duration:   string(c.Duration),
+	}
+	if sub.Discount.PromotionCode != nil {
+		discount.Code = sub.Discount.PromotionCode.Code
+	}
+	if discount.Code == "" && database.DB != nil {
+		var coupon models.Coupon
+		if err := database.DB.WithContext(ctx).Where("stripe_coupon_id = ?", c.ID).First(&coupon).Error; err == nil {
+			discount.Code = coupon.Code
+		}
+	}
+	if sub.Discount.End > 0 {
+		endsAt := time.Unix(sub.Discount.End, 0).Format(time.RFC3339)
+		discount.EndsAt = &endsAt
+	}
+	return discount
+}
+
+// recordCouponRedemption counts a new subscription against an internal coupon's limit
+func recordCouponRedemption(ctx context.Context, couponID string) {
+	if couponID == "" {
+		return
+	}
+	if err := database.DB.WithContext(ctx).Model(&models.Coupon{}).
+		Where("stripe_coupon_id = ?", couponID).
+		UpdateColumn("times_redeemed", gorm.Expr("times_redeemed + 1")).Error; err != nil {
+		log.Error().Err(err).Str("coupon_id", couponID).Msg("failed to record coupon redemption")
+	}
+}
+
+// validateCouponRequest normalizes the code and checks the discount terms
+func validateCouponRequest(req *models.CreateCouponRequest, now time.Time) string {
+	req.Code = normalizeCouponCode(req.Code)
+	req.Currency = strings.ToLower(strings.TrimSpace(req.Currency))
+
+	if !couponCodePattern.MatchString(req.Code) {
+		return "Code must be 3-50 letters, digits, dashes or underscores"
+	}
+	switch {
+	case req.PercentOff > 0 && req.AmountOff > 0:
+		return "Set either percent_off or amount_off, not both"
+	case req.PercentOff > 100:
+		return "percent_off cannot exceed 100"
+	case req.PercentOff < 0 || req.AmountOff < 0:
+		return "Discount must be positive"
+	case req.PercentOff == 0 && req.AmountOff == 0:
+		return "percent_off or amount_off is required"
+	case req.AmountOff > 0 && len(req.Currency) != 3:
+		return "currency is required with amount_off"
+	}
+
+	switch req.Duration {
+	case models.CouponDurationOnce, models.CouponDurationForever:
+		req.DurationInMonths = 0
+	case models.CouponDurationRepeating:
+		if req.DurationInMonths <= 0 {
+			return "duration_in_months is required for repeating coupons"
+		}
+	default:
+		return "duration must be once, repeating or forever"
+	}
+
+	if req.MaxRedemptions < 0 {
+		return "max_redemptions cannot be negative"
+	}
+	if req.ExpiresAt != nil {
+		expiresAt, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+		if err != nil {
+			return "expires_at must be an RFC3339 timestamp"
+		}
+		if !expiresAt.After(now) {
+			return "expires_at must be in the future"
+		}
+	}
+	return ""
+}
+
+// couponParams builds the Stripe coupon backing an internal coupon
+func couponParams(req *models.CreateCouponRequest) *stripe.CouponParams {
+	params := &stripe.CouponParams{
+		Duration: stripe.String(req.Duration),
+	}
+	if req.Name != "" {
+		params.Name = stripe.String(req.Name)
+	}
+	if req.PercentOff > 0 {
+		params.PercentOff = stripe.Float64(req.PercentOff)
+	} else {
+		params.AmountOff = stripe.Int64(req.AmountOff)
+		params.Currency = stripe.String(req.Currency)
+	}
+	if req.DurationInMonths > 0 {
+		params.DurationInMonths = stripe.Int64(req.DurationInMonths)
+	}
+	if req.MaxRedemptions > 0 {
+		params.MaxRedemptions = stripe.Int64(req.MaxRedemptions)
+	}
+	if req.ExpiresAt != nil {
+		if expiresAt, err := time.Parse(time.RFC3339, *req.ExpiresAt); err == nil {
+			params.RedeemBy = stripe.Int64(expiresAt.Unix())
+		}
+	}
+	params.AddMetadata("code", req.Code)
+	return params
+}
+
+// createCoupon creates an internal coupon backed by a Stripe coupon
+func createCoupon(ctx context.Context, svc Service, req *models.CreateCouponRequest, createdBy *uint) (*models.Coupon, error) {
+	var existing int64
+	if err := database.DB.WithContext(ctx).Model(&models.Coupon{}).Where("code = ?", req.Code).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrCouponCodeExists
+	}
+
+	stripeCoupon, err := svc.CreateCoupon(ctx, couponParams(req))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	coupon := &models.Coupon{
+		Code:             req.Code,
+		Name:             req.Name,
+		StripeCouponID:   stripeCoupon.ID,
+		PercentOff:       req.PercentOff,
+		AmountOff:        req.AmountOff,
+		Currency:         req.Currency,
+		Duration:         req.Duration,
+		DurationInMonths: req.DurationInMonths,
+		MaxRedemptions:   req.MaxRedemptions,
+		ExpiresAt:        req.ExpiresAt,
+		Active:           true,
+		CreatedByUserID:  createdBy,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := database.DB.WithContext(ctx).Create(coupon).Error; err != nil {
+		// Don't leave an orphaned coupon in Stripe
+		if delErr := svc.DeleteCoupon(ctx, stripeCoupon.ID); delErr != nil {
+			log.Error().Err(delErr).Str("coupon_id", stripeCoupon.ID).Msg("failed to delete orphaned stripe coupon")
+		}
+		return nil, err
+	}
+
+	log.Info().Str("code", coupon.Code).Str("coupon_id", coupon.StripeCouponID).Msg("coupon created")
+	return coupon, nil
+}
+
+// deactivateCoupon stops a coupon from being redeemed. Subscriptions that already use it
+// keep their discount.
+func deactivateCoupon(ctx context.Context, svc Service, id uint) error {
+	var coupon models.Coupon
+	if err := database.DB.WithContext(ctx).First(&coupon, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCouponNotFound
+		}
+		return err
+	}
+
+	if err := database.DB.WithContext(ctx).Model(&coupon).Updates(map[string]any{
+		"active":     false,
+		"updated_at": time.Now().Format(time.RFC3339),
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := svc.DeleteCoupon(ctx, coupon.StripeCouponID); err != nil {
+		log.Error().Err(err).Str("coupon_id", coupon.StripeCouponID).Msg("failed to delete stripe coupon")
+	}
+
+	log.Info().Str("code", coupon.Code).Msg("coupon deactivated")
+	return nil
+}
+
+// ValidatePromotionCode previews the discount a code would apply at checkout
+// @Summary Validate a promotion code
+// @Description Checks a promotion or coupon code and previews the discounted price when a price ID is given
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param request body models.ValidatePromotionCodeRequest true "Code and optional price"
+// @Success 200 {object} models.PromotionCodePreview
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/billing/promotion-codes/validate [post]
+func ValidatePromotionCode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !IsAvailable() {
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Billing is not configured")
+			return
+		}
+
+		var req models.ValidatePromotionCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "Code is required")
+			return
+		}
+
+		preview, err := PreviewDiscount(r.Context(), GetService(), req.Code, req.PriceID)
+		if err != nil {
+			writeDiscountError(w, err)
+			return
+		}
+
+		writeJSONResponse(w, preview)
+	}
+}
+
+// ListCoupons returns all internal coupons
+// @Summary List coupons
+// @Description Lists admin-created coupons, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.CouponsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/admin/coupons [get]
+func ListCoupons() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		coupons := []models.Coupon{}
+		if err := database.DB.WithContext(r.Context()).Order("created_at DESC").Find(&coupons).Error; err != nil {
+			log.Error().Err(err).Msg("failed to fetch coupons")
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to fetch coupons")
+			return
+		}
+
+		writeJSONResponse(w, models.CouponsResponse{Coupons: coupons, Count: len(coupons)})
+	}
+}
+
+// CreateCoupon creates an internal coupon
+// @Summary Create a coupon
+// @Description Creates a coupon, such as a partner discount, that customers redeem by code at checkout
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateCouponRequest true "Coupon terms"
+// @Success 201 {object} models.Coupon
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /api/admin/coupons [post]
+func CreateCoupon() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !IsAvailable() {
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Billing is not configured")
+			return
+		}
+
+		var req models.CreateCouponRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if message := validateCouponRequest(&req, time.Now()); message != "" {
+			writeErrorResponse(w, http.StatusBadRequest, message)
+			return
+		}
+
+		var createdBy *uint
+		if userID, ok := auth.GetUserIDFromContext(r.Context()); ok {
+			createdBy = &userID
+		}
+
+		coupon, err := createCoupon(r.Context(), GetService(), &req, createdBy)
+		if err != nil {
+			if errors.Is(err, ErrCouponCodeExists) {
+				writeErrorResponse(w, http.StatusConflict, "A coupon with this code already exists")
+				return
+			}
+			var stripeErr *stripe.Error
+			if errors.As(err, &stripeErr) && stripeErr.HTTPStatusCode == http.StatusBadRequest {
+				writeErrorResponse(w, http.StatusBadRequest, stripeErr.Msg)
+				return
+			}
+			log.Error().Err(err).Str("code", req.Code).Msg("failed to create coupon")
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to create coupon")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(coupon); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// DeactivateCoupon stops a coupon from being redeemed
+// @Summary Deactivate a coupon
+// @Description Stops new redemptions of a coupon; existing subscriptions keep their discount
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/admin/coupons/{id} [delete]
+func DeactivateCoupon() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid coupon ID")
+			return
+		}
+
+		if err := deactivateCoupon(r.Context(), GetService(), uint(id)); err != nil {
+			if errors.Is(err, ErrCouponNotFound) {
+				writeErrorResponse(w, http.StatusNotFound, "Coupon not found")
+				return
+			}
+			log.Error().Err(err).Uint64("coupon_id", id).Msg("failed to deactivate coupon")
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to deactivate coupon")
+			return
+		}
+
+		writeJSONResponse(w, models.SuccessResponse{Success: true, Message: "Coupon deactivated"})
+	}
+}
+
+func writeDiscountError(w http.ResponseWriter, err error) {
+	status, message := DiscountErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Error().Err(err).Msg("failed to resolve promotion code")
+	}
+	writeErrorResponse(w, status, message)
+}
@@ -0,0 +1,349 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	stripe "github.com/stripe/stripe-go/v76"
+)
+
+// fakeDiscountService serves promotion codes, prices and coupons from memory
+type fakeDiscountService struct {
+	noOpService
+	promotionCodes map[string]*stripe.PromotionCode
+	prices         map[string]*stripe.Price
+	created        []*stripe.CouponParams
+	deleted        []string
+}
+
+func (f *fakeDiscountService) IsAvailable() bool {
+	return true
+}
+
+func (f *fakeDiscountService) FindPromotionCode(ctx context.Context, code string) (*stripe.PromotionCode, error) {
+	if pc, ok := f.promotionCodes[code]; ok {
+		return pc, nil
+	}
+	return nil, ErrInvalidPromotionCode
+}
+
+func (f *fakeDiscountService) GetPrice(ctx context.Context, priceID string) (*stripe.Price, error) {
+	if p, ok := f.prices[priceID]; ok {
+		return p, nil
+	}
+	return nil, &stripe.Error{HTTPStatusCode: http.StatusNotFound, Msg: "No such price"}
+}
+
+func (f *fakeDiscountService) CreateCoupon(ctx context.Context, params *stripe.CouponParams) (*stripe.Coupon, error) {
+	f.created = append(f.created, params)
+	return &stripe.Coupon{ID: "co_created"}, nil
+}
+
+func (f *fakeDiscountService) DeleteCoupon(ctx context.Context, couponID string) error {
+	f.deleted = append(f.deleted, couponID)
+	return nil
+}
+
+func newFakeDiscountService() *fakeDiscountService {
+	return &fakeDiscountService{
+		promotionCodes: map[string]*stripe.PromotionCode{
+			"SPRING25": {
+				ID:     "promo_spring",
+				Code:   "SPRING25",
+				Active: true,
+				Coupon: &stripe.Coupon{ID: "co_spring", Name: "Spring sale", PercentOff: 25, Duration: stripe.CouponDurationOnce, Valid: true},
+			},
+			"PROONLY": {
+				ID:     "promo_pro_only",
+				Code:   "PROONLY",
+				Active: true,
+				Coupon: &stripe.Coupon{
+					ID: "co_pro_only", AmountOff: 500, Currency: stripe.CurrencyUSD, Duration: stripe.CouponDurationForever, Valid: true,
+					AppliesTo: &stripe.CouponAppliesTo{Products: []string{"prod_pro"}},
+				},
+			},
+			"EXPIRED": {
+				ID:        "promo_expired",
+				Code:      "EXPIRED",
+				Active:    true,
+				ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+				Coupon:    &stripe.Coupon{ID: "co_expired", PercentOff: 10, Valid: true},
+			},
+		},
+		prices: map[string]*stripe.Price{
+			"price_pro":   {ID: "price_pro", UnitAmount: 2000, Currency: stripe.CurrencyUSD, Product: &stripe.Product{ID: "prod_pro"}},
+			"price_basic": {ID: "price_basic", UnitAmount: 900, Currency: stripe.CurrencyUSD, Product: &stripe.Product{ID: "prod_basic"}},
+		},
+	}
+}
+
+// withoutDatabase runs unit tests against Stripe promotion codes only
+func withoutDatabase(t *testing.T) {
+	t.Helper()
+	oldDB := database.DB
+	database.DB = nil
+	t.Cleanup(func() { database.DB = oldDB })
+}
+
+// ============ Unit Tests ============
+
+func TestResolveDiscount_PromotionCode(t *testing.T) {
+	withoutDatabase(t)
+	svc := newFakeDiscountService()
+
+	discount, err := ResolveDiscount(context.Background(), svc, "  spring25 ")
+	if err != nil {
+		t.Fatalf("ResolveDiscount() error = %v", err)
+	}
+	if discount.PromotionCodeID != "promo_spring" || discount.PercentOff != 25 {
+		t.Errorf("discount = %+v, want the spring promotion", discount)
+	}
+	if params := discount.sessionParams(); params.PromotionCode == nil || *params.PromotionCode != "promo_spring" || params.Coupon != nil {
+		t.Errorf("sessionParams() = %+v, want the promotion code", params)
+	}
+
+	for _, code := range []string{"", "UNKNOWN", "EXPIRED"} {
+		if _, err := ResolveDiscount(context.Background(), svc, code); !errors.Is(err, ErrInvalidPromotionCode) {
+			t.Errorf("ResolveDiscount(%q) error = %v, want %v", code, err, ErrInvalidPromotionCode)
+		}
+	}
+}
+
+func TestPreviewDiscount(t *testing.T) {
+	withoutDatabase(t)
+	svc := newFakeDiscountService()
+	ctx := context.Background()
+
+	preview, err := PreviewDiscount(ctx, svc, "SPRING25", "price_pro")
+	if err != nil {
+		t.Fatalf("PreviewDiscount() error = %v", err)
+	}
+	if preview.OriginalAmount != 2000 || preview.DiscountAmount != 500 || preview.FinalAmount != 1500 || preview.Currency != "usd" {
+		t.Errorf("preview = %+v, want 25%% off 2000", preview)
+	}
+
+	preview, err = PreviewDiscount(ctx, svc, "PROONLY", "")
+	if err != nil {
+		t.Fatalf("PreviewDiscount() without price error = %v", err)
+	}
+	if preview.AmountOff != 500 || preview.FinalAmount != 0 {
+		t.Errorf("preview = %+v, want terms only", preview)
+	}
+
+	if _, err := PreviewDiscount(ctx, svc, "PROONLY", "price_basic"); !errors.Is(err, ErrPromotionCodeNotApplicable) {
+		t.Errorf("PreviewDiscount() error = %v, want %v for another product", err, ErrPromotionCodeNotApplicable)
+	}
+	if _, err := PreviewDiscount(ctx, svc, "SPRING25", "price_missing"); err == nil {
+		t.Error("PreviewDiscount() should fail for an unknown price")
+	}
+}
+
+func TestDiscount_DiscountAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount Discount
+		amount   int64
+		want     int64
+	}{
+		{"percent off", Discount{PercentOff: 20}, 1999, 400},
+		{"full percent", Discount{PercentOff: 100}, 1500, 1500},
+		{"amount off", Discount{AmountOff: 300}, 1500, 300},
+		{"amount off capped at price", Discount{AmountOff: 3000}, 1500, 1500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.discount.discountAmount(tt.amount); got != tt.want {
+				t.Errorf("discountAmount(%d) = %d, want %d", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscount_AppliesToCurrency(t *testing.T) {
+	eur := &stripe.Price{Currency: stripe.CurrencyEUR}
+
+	if err := (&Discount{AmountOff: 500, Currency: "usd"}).appliesTo(eur); !errors.Is(err, ErrPromotionCodeNotApplicable) {
+		t.Errorf("appliesTo() error = %v, want currency mismatch", err)
+	}
+	if err := (&Discount{PercentOff: 10}).appliesTo(eur); err != nil {
+		t.Errorf("appliesTo() error = %v, percentages apply in any currency", err)
+	}
+}
+
+func TestDiscountErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{ErrInvalidPromotionCode, http.StatusBadRequest},
+		{ErrPromotionCodeNotApplicable, http.StatusBadRequest},
+		{ErrDisabled, http.StatusServiceUnavailable},
+		{&stripe.Error{HTTPStatusCode: http.StatusNotFound}, http.StatusBadRequest},
+		{errors.New("network down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got, _ := DiscountErrorStatus(tt.err); got != tt.want {
+			t.Errorf("DiscountErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestValidateCouponRequest(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	future := now.Add(24 * time.Hour).Format(time.RFC3339)
+	past := now.Add(-24 * time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		req     models.CreateCouponRequest
+		wantErr bool
+	}{
+		{"percent forever", models.CreateCouponRequest{Code: "partner20", PercentOff: 20, Duration: "forever"}, false},
+		{"amount once", models.CreateCouponRequest{Code: "TEN-OFF", AmountOff: 1000, Currency: "USD", Duration: "once", ExpiresAt: &future}, false},
+		{"repeating", models.CreateCouponRequest{Code: "QUARTER", PercentOff: 15, Duration: "repeating", DurationInMonths: 3}, false},
+		{"code too short", models.CreateCouponRequest{Code: "AB", PercentOff: 20, Duration: "once"}, true},
+		{"code with spaces", models.CreateCouponRequest{Code: "TWO WORDS", PercentOff: 20, Duration: "once"}, true},
+		{"no discount", models.CreateCouponRequest{Code: "NOTHING", Duration: "once"}, true},
+		{"both discounts", models.CreateCouponRequest{Code: "BOTH", PercentOff: 10, AmountOff: 100, Currency: "usd", Duration: "once"}, true},
+		{"percent over 100", models.CreateCouponRequest{Code: "TOOMUCH", PercentOff: 120, Duration: "once"}, true},
+		{"amount without currency", models.CreateCouponRequest{Code: "NOCURR", AmountOff: 100, Duration: "once"}, true},
+		{"repeating without months", models.CreateCouponRequest{Code: "REPEAT", PercentOff: 10, Duration: "repeating"}, true},
+		{"unknown duration", models.CreateCouponRequest{Code: "WEEKLY", PercentOff: 10, Duration: "weekly"}, true},
+		{"expired", models.CreateCouponRequest{Code: "OLD", PercentOff: 10, Duration: "once", ExpiresAt: &past}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			message := validateCouponRequest(&req, now)
+			if (message != "") != tt.wantErr {
+				t.Errorf("validateCouponRequest() = %q, wantErr %v", message, tt.wantErr)
+			}
+		})
+	}
+
+	req := models.CreateCouponRequest{Code: " partner20 ", AmountOff: 100, Currency: "USD", Duration: "once", DurationInMonths: 6}
+	validateCouponRequest(&req, now)
+	if req.Code != "PARTNER20" || req.Currency != "usd" || req.DurationInMonths != 0 {
+		t.Errorf("normalized request = %+v", req)
+	}
+}
+
+func TestCouponParams(t *testing.T) {
+	expiresAt := "2030-01-01T00:00:00Z"
+	params := couponParams(&models.CreateCouponRequest{
+		Code:             "PARTNER20",
+		Name:             "Partner discount",
+		PercentOff:       20,
+		Duration:         "repeating",
+		DurationInMonths: 6,
+		MaxRedemptions:   100,
+		ExpiresAt:        &expiresAt,
+	})
+
+	if *params.PercentOff != 20 || params.AmountOff != nil {
+		t.Errorf("discount params = %v/%v, want percent only", params.PercentOff, params.AmountOff)
+	}
+	if *params.DurationInMonths != 6 || *params.MaxRedemptions != 100 {
+		t.Errorf("limits = %d months, %d redemptions", *params.DurationInMonths, *params.MaxRedemptions)
+	}
+	if *params.RedeemBy != time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("RedeemBy = %d, want the expiry", *params.RedeemBy)
+	}
+	if params.Metadata["code"] != "PARTNER20" {
+		t.Errorf("Metadata = %v, want the code", params.Metadata)
+	}
+}
+
+func TestSubscriptionDiscount(t *testing.T) {
+	withoutDatabase(t)
+
+	if got := subscriptionDiscount(context.Background(), &stripe.Subscription{}); got.CouponID != "" {
+		t.Errorf("subscriptionDiscount() = %+v, want empty without a discount", got)
+	}
+
+	end := time.Date(2024, time.September, 1, 0, 0, 0, 0, time.UTC)
+	got := subscriptionDiscount(context.Background(), &stripe.Subscription{
+		Discount: &stripe.Discount{
+			Coupon:        &stripe.Coupon{ID: "co_spring", Name: "Spring sale", PercentOff: 25, Duration: stripe.CouponDurationRepeating},
+			PromotionCode: &stripe.PromotionCode{ID: "promo_spring", Code: "SPRING25"},
+			End:           end.Unix(),
+		},
+	})
+	if got.CouponID != "co_spring" || got.Code != "SPRING25" || got.PercentOff != 25 || got.Duration != "repeating" {
+		t.Errorf("subscriptionDiscount() = %+v", got)
+	}
+	if got.EndsAt == nil || *got.EndsAt != time.Unix(end.Unix(), 0).Format(time.RFC3339) {
+		t.Errorf("EndsAt = %v, want the discount end", got.EndsAt)
+	}
+}
+
+// ============ Integration Tests ============
+
+func TestInternalCoupon_Integration(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc := newFakeDiscountService()
+	req := &models.CreateCouponRequest{Code: "PARTNER20", Name: "Partner", PercentOff: 20, Duration: "forever", MaxRedemptions: 1}
+
+	coupon, err := createCoupon(ctx, svc, req, nil)
+	if err != nil {
+		t.Fatalf("createCoupon() error = %v", err)
+	}
+	if coupon.StripeCouponID != "co_created" || len(svc.created) != 1 {
+		t.Fatalf("coupon = %+v, want it backed by a Stripe coupon", coupon)
+	}
+	if _, err := createCoupon(ctx, svc, req, nil); !errors.Is(err, ErrCouponCodeExists) {
+		t.Errorf("createCoupon() duplicate error = %v, want %v", err, ErrCouponCodeExists)
+	}
+
+	discount, err := ResolveCheckoutDiscount(ctx, svc, "partner20", "price_pro")
+	if err != nil {
+		t.Fatalf("ResolveCheckoutDiscount() error = %v", err)
+	}
+	if params := discount.sessionParams(); params.Coupon == nil || *params.Coupon != "co_created" {
+		t.Errorf("sessionParams() = %+v, want the internal coupon", params)
+	}
+
+	// The subscription created from checkout records the discount and uses up the coupon
+	user := createTestUserForWebhook(t, "coupon@example.com", "cus_coupon")
+	sub := fakeStripeSubscription("sub_coupon", "price_pro", stripe.SubscriptionStatusActive, false)
+	sub.Customer = &stripe.Customer{ID: "cus_coupon"}
+	sub.Discount = &stripe.Discount{Coupon: &stripe.Coupon{ID: "co_created", PercentOff: 20, Duration: stripe.CouponDurationForever}}
+	if err := handleSubscriptionCreated(ctx, sub); err != nil {
+		t.Fatalf("handleSubscriptionCreated() error = %v", err)
+	}
+
+	var stored models.Subscription
+	database.DB.Where("stripe_subscription_id = ?", "sub_coupon").First(&stored)
+	if d := stored.ToSubscriptionResponse().Discount; d == nil || d.Code != "PARTNER20" {
+		t.Errorf("stored discount = %+v, want PARTNER20", d)
+	}
+	if stored.UserID != user.ID {
+		t.Errorf("subscription user = %d, want %d", stored.UserID, user.ID)
+	}
+
+	if _, err := ResolveDiscount(ctx, svc, "PARTNER20"); !errors.Is(err, ErrInvalidPromotionCode) {
+		t.Errorf("ResolveDiscount() error = %v, want the redemption limit enforced", err)
+	}
+
+	if err := deactivateCoupon(ctx, svc, coupon.ID); err != nil {
+		t.Fatalf("deactivateCoupon() error = %v", err)
+	}
+	if len(svc.deleted) != 1 || svc.deleted[0] != "co_created" {
+		t.Errorf("deleted Stripe coupons = %v", svc.deleted)
+	}
+	if err := deactivateCoupon(ctx, svc, coupon.ID+100); !errors.Is(err, ErrCouponNotFound) {
+		t.Errorf("deactivateCoupon() error = %v, want %v", err, ErrCouponNotFound)
+	}
+}
@@ -0,0 +1,364 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/jobs"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
+)
+
+// Grace period and reminder schedule applied to new dunning cases
+var (
+	dunningGracePeriod = DefaultConfig().DunningGracePeriod
+	dunningReminders   = DefaultConfig().DunningReminders
+)
+
+// ConfigureDunning sets the grace period and reminder schedule used for failed payments
+func ConfigureDunning(config *Config) {
+	dunningGracePeriod = config.DunningGracePeriod
+	dunningReminders = config.DunningReminders
+}
+
+// startDunning opens a dunning case for a subscription whose payment failed. Further failures
+// while a case is open (Stripe retries) update the invoice but keep the original grace period.
+func startDunning(ctx context.Context, subscription *models.Subscription, invoice *stripe.Invoice) (*models.Dunning, error) {
+	now := time.Now()
+
+	var existing models.Dunning
+	err := database.DB.WithContext(ctx).
+		Where("subscription_id = ? AND status = ?", subscription.ID, models.DunningStatusActive).
+		First(&existing).Error
+	if err == nil {
+		if err := database.DB.WithContext(ctx).Model(&existing).Updates(map[string]any{
+			"stripe_invoice_id": invoice.ID,
+			"amount_due":        invoice.AmountDue,
+			"updated_at":        now.Format(time.RFC3339),
+		}).Error; err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	graceEnds := now.Add(dunningGracePeriod)
+	dunning := models.Dunning{
+		SubscriptionID:       subscription.ID,
+		UserID:               subscription.UserID,
+		OrganizationID:       subscription.OrganizationID,
+		StripeSubscriptionID: subscription.StripeSubscriptionID,
+		StripeInvoiceID:      invoice.ID,
+		AmountDue:            invoice.AmountDue,
+		Currency:             string(invoice.Currency),
+		Status:               models.DunningStatusActive,
+		GraceEndsAt:          graceEnds.Format(time.RFC3339),
+		NextReminderAt:       nextReminderAt(now, 0, graceEnds),
+		CreatedAt:            now.Format(time.RFC3339),
+		UpdatedAt:            now.Format(time.RFC3339),
+	}
+	if err := database.DB.WithContext(ctx).Create(&dunning).Error; err != nil {
+		return nil, err
+	}
+
+	log.Warn().
+		Str("subscription_id", subscription.StripeSubscriptionID).
+		Str("grace_ends_at", dunning.GraceEndsAt).
+		Msg("dunning started")
+
+	enqueueDunningEmails(ctx, &dunning, jobs.DunningStagePaymentFailed, now)
+	return &dunning, nil
+}
+
+// resolveDunning closes the open dunning case for a subscription after a successful payment
+func resolveDunning(ctx context.Context, stripeSubscriptionID string) error {
+	var dunning models.Dunning
+	err := database.DB.WithContext(ctx).
+		Where("stripe_subscription_id = ? AND status = ?", stripeSubscriptionID, models.DunningStatusActive).
+		First(&dunning).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := closeDunning(ctx, database.DB, &dunning, models.DunningStatusResolved, time.Now()); err != nil {
+		return err
+	}
+
+	log.Info().Str("subscription_id", stripeSubscriptionID).Msg("dunning resolved by successful payment")
+
+	message := "Payment received. Thank you!"
+	if dunning.OrganizationID != nil {
+		broadcastOrgSubscriptionEvent(ctx, *dunning.OrganizationID, "payment_recovered", models.SubscriptionStatusActive, "", "", false, "", message)
+	} else {
+		broadcastSubscriptionEvent(dunning.UserID, "payment_recovered", models.SubscriptionStatusActive, "premium", "", false, "", message)
+	}
+	return nil
+}
+
+// hasExpiredDunning reports whether the subscription was downgraded for non-payment and has
+// not been paid since, so a past-due update must not restore the paid plan
+func hasExpiredDunning(ctx context.Context, subscriptionID uint) bool {
+	var latest models.Dunning
+	if err := database.DB.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("created_at DESC").
+		First(&latest).Error; err != nil {
+		return false
+	}
+	return latest.Status == models.DunningStatusExpired
+}
+
+// handlePaymentSucceeded ends dunning for a subscription invoice that was paid
+func handlePaymentSucceeded(ctx context.Context, invoice *stripe.Invoice) error {
+	if invoice.Subscription == nil || invoice.Subscription.ID == "" {
+		return nil
+	}
+	return resolveDunning(ctx, invoice.Subscription.ID)
+}
+
+func closeDunning(ctx context.Context, db *gorm.DB, dunning *models.Dunning, status string, now time.Time) error {
+	resolvedAt := now.Format(time.RFC3339)
+	return db.WithContext(ctx).Model(dunning).Updates(map[string]any{
+		"status":           status,
+		"resolved_at":      resolvedAt,
+		"next_reminder_at": nil,
+		"updated_at":       resolvedAt,
+	}).Error
+}
+
+// nextReminderAt returns when the reminder after `sent` reminders is due, or nil if
+// the schedule is exhausted or the next reminder would fall after the grace period
+func nextReminderAt(start time.Time, sent int, graceEnds time.Time) *string {
+	if sent >= len(dunningReminders) {
+		return nil
+	}
+	at := start.Add(dunningReminders[sent])
+	if !at.Before(graceEnds) {
+		return nil
+	}
+	formatted := at.Format(time.RFC3339)
+	return &formatted
+}
+
+// DunningProcessor escalates open dunning cases: it sends due reminders and downgrades
+// subscriptions whose grace period has ended
+type DunningProcessor struct {
+	db           *gorm.DB
+	orgService   *services.OrgService
+	usageService *services.UsageService
+	now          func() time.Time
+}
+
+// NewDunningProcessor creates the processor to register with jobs.SetDunningProcessor
+func NewDunningProcessor(db *gorm.DB) *DunningProcessor {
+	return &DunningProcessor{
+		db:           db,
+		orgService:   services.NewOrgService(db),
+		usageService: services.NewUsageService(db),
+		now:          time.Now,
+	}
+}
+
+// ProcessDunning handles every open case that has a reminder due or an expired grace period
+func (p *DunningProcessor) ProcessDunning(ctx context.Context) error {
+	now := p.now()
+	nowStr := now.Format(time.RFC3339)
+
+	var due []models.Dunning
+	if err := p.db.WithContext(ctx).
+		Where("status = ? AND (grace_ends_at <= ? OR next_reminder_at <= ?)", models.DunningStatusActive, nowStr, nowStr).
+		Find(&due).Error; err != nil {
+		return fmt.Errorf("failed to load dunning cases: %w", err)
+	}
+
+	failed := 0
+	for i := range due {
+		dunning := &due[i]
+		graceEnds, err := time.Parse(time.RFC3339, dunning.GraceEndsAt)
+		if err != nil {
+			graceEnds = now
+		}
+
+		if !now.Before(graceEnds) {
+			err = p.expire(ctx, dunning, now)
+		} else {
+			err = p.remind(ctx, dunning, graceEnds, now)
+		}
+		if err != nil {
+			failed++
+			log.Error().
+				Err(err).
+				Uint("dunning_id", dunning.ID).
+				Str("subscription_id", dunning.StripeSubscriptionID).
+				Msg("failed to process dunning case")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d dunning cases failed", failed)
+	}
+	return nil
+}
+
+// remind sends the next reminder, escalating to a final notice when no more are scheduled
+func (p *DunningProcessor) remind(ctx context.Context, dunning *models.Dunning, graceEnds, now time.Time) error {
+	start, err := time.Parse(time.RFC3339, dunning.CreatedAt)
+	if err != nil {
+		start = now
+	}
+
+	sent := dunning.RemindersSent + 1
+	next := nextReminderAt(start, sent, graceEnds)
+
+	if err := p.db.WithContext(ctx).Model(dunning).Updates(map[string]any{
+		"reminders_sent":   sent,
+		"next_reminder_at": next,
+		"updated_at":       now.Format(time.RFC3339),
+	}).Error; err != nil {
+		return err
+	}
+
+	stage := jobs.DunningStageReminder
+	if next == nil {
+		stage = jobs.DunningStageFinalNotice
+	}
+	enqueueDunningEmails(ctx, dunning, stage, now)
+
+	message := fmt.Sprintf("Payment is still outstanding. Update your payment method within %s to keep your plan.", formatDaysRemaining(graceEnds, now))
+	if dunning.OrganizationID != nil {
+		broadcastOrgSubscriptionEvent(ctx, *dunning.OrganizationID, "payment_reminder", models.SubscriptionStatusPastDue, "", "", false, dunning.GraceEndsAt, message)
+	} else {
+		broadcastSubscriptionEvent(dunning.UserID, "payment_reminder", models.SubscriptionStatusPastDue, "premium", "", false, dunning.GraceEndsAt, message)
+	}
+	return nil
+}
+
+// expire downgrades the subscriber to the free plan once the grace period has ended
+func (p *DunningProcessor) expire(ctx context.Context, dunning *models.Dunning, now time.Time) error {
+	if dunning.OrganizationID != nil {
+		if err := p.orgService.UpdateOrganizationPlan(ctx, *dunning.OrganizationID, models.OrgPlanFree, nil); err != nil {
+			return fmt.Errorf("failed to downgrade organization plan: %w", err)
+		}
+	} else {
+		if err := p.usageService.UpdateUserLimits(ctx, dunning.UserID, ""); err != nil {
+			return fmt.Errorf("failed to downgrade usage limits: %w", err)
+		}
+		syncUserRole(ctx, dunning.UserID, stripe.SubscriptionStatusUnpaid)
+	}
+
+	if err := closeDunning(ctx, p.db, dunning, models.DunningStatusExpired, now); err != nil {
+		return err
+	}
+	if dunning.OrganizationID == nil {
+		services.InvalidateEntitlements(ctx, services.UserSubject(dunning.UserID))
+	}
+
+	log.Warn().
+		Str("subscription_id", dunning.StripeSubscriptionID).
+		Msg("dunning grace period ended, subscription downgraded")
+
+	enqueueDunningEmails(ctx, dunning, jobs.DunningStageDowngraded, now)
+
+	message := "Payment was not received, so your plan has been downgraded to free. Pay the outstanding invoice to restore it."
+	if dunning.OrganizationID != nil {
+		broadcastOrgSubscriptionEvent(ctx, *dunning.OrganizationID, "downgraded", models.SubscriptionStatusPastDue, string(models.OrgPlanFree), "", false, "", message)
+	} else {
+		broadcastSubscriptionEvent(dunning.UserID, "downgraded", models.SubscriptionStatusPastDue, "free", "", false, "", message)
+	}
+	return nil
+}
+
+// enqueueDunningEmails queues a notice for the billing contact, or for every owner of an organization
+func enqueueDunningEmails(ctx context.Context, dunning *models.Dunning, stage string, now time.Time) {
+	recipients, orgName, orgSlug, err := billingRecipients(ctx, dunning.UserID, dunning.OrganizationID)
+	if err != nil {
+		log.Error().Err(err).Uint("dunning_id", dunning.ID).Msg("failed to load dunning recipients")
+		return
+	}
+
+	graceEnds, _ := time.Parse(time.RFC3339, dunning.GraceEndsAt)
+
+	notices := make([]jobs.SendDunningEmailArgs, 0, len(recipients))
+	for _, user := range recipients {
+		notices = append(notices, jobs.SendDunningEmailArgs{
+			UserID:        user.ID,
+			Email:         user.Email,
+			Name:          user.Name,
+			Stage:         stage,
+			OrgName:       orgName,
+			OrgSlug:       orgSlug,
+			AmountDue:     formatAmount(dunning.AmountDue, dunning.Currency),
+			GraceEndsAt:   graceEnds.Format("January 2, 2006"),
+			DaysRemaining: daysUntil(graceEnds, now),
+		})
+	}
+
+	if err := jobs.EnqueueDunningEmails(ctx, notices); err != nil {
+		log.Warn().Err(err).Uint("dunning_id", dunning.ID).Str("stage", stage).Msg("failed to enqueue dunning emails")
+	}
+}
+
+// billingRecipients returns who receives billing notices for a subscription: the user, or every
+// active owner of the organization along with its name and slug
+func billingRecipients(ctx context.Context, userID uint, orgID *uint) ([]models.User, string, string, error) {
+	if orgID == nil {
+		var user models.User
+		if err := database.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
+			return nil, "", "", err
+		}
+		return []models.User{user}, "", "", nil
+	}
+
+	var org models.Organization
+	if err := database.DB.WithContext(ctx).First(&org, *orgID).Error; err != nil {
+		return nil, "", "", err
+	}
+
+	var owners []models.User
+	if err := database.DB.WithContext(ctx).
+		Joins("JOIN organization_members ON organization_members.user_id = users.id").
+		Where("organization_members.organization_id = ? AND organization_members.role = ? AND organization_members.status = ?",
+			org.ID, models.OrgRoleOwner, models.MemberStatusActive).
+		Find(&owners).Error; err != nil {
+		return nil, "", "", err
+	}
+	return owners, org.Name, org.Slug, nil
+}
+
+// formatAmount renders an amount in the smallest currency unit for display
+func formatAmount(amount int64, currency string) string {
+	if strings.EqualFold(currency, "usd") || currency == "" {
+		return fmt.Sprintf("$%.2f", float64(amount)/100)
+	}
+	return fmt.Sprintf("%.2f %s", float64(amount)/100, strings.ToUpper(currency))
+}
+
+// daysUntil returns the whole days left until t, rounded up
+func daysUntil(t, now time.Time) int {
+	days := int(math.Ceil(t.Sub(now).Hours() / 24))
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+func formatDaysRemaining(graceEnds, now time.Time) string {
+	if days := daysUntil(graceEnds, now); days > 1 {
+		return fmt.Sprintf("%d days", days)
+	}
+	return "1 day"
+}
@@ -0,0 +1,211 @@
+package stripe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	stripe "github.com/stripe/stripe-go/v76"
+)
+
+// withDunningSchedule overrides the dunning policy for the duration of a test
+func withDunningSchedule(t *testing.T, grace time.Duration, reminders ...time.Duration) {
+	t.Helper()
+	oldGrace, oldReminders := dunningGracePeriod, dunningReminders
+	ConfigureDunning(&Config{DunningGracePeriod: grace, DunningReminders: reminders})
+	t.Cleanup(func() {
+		dunningGracePeriod, dunningReminders = oldGrace, oldReminders
+	})
+}
+
+// ============ Unit Tests ============
+
+func TestNextReminderAt(t *testing.T) {
+	withDunningSchedule(t, 10*24*time.Hour, 2*24*time.Hour, 5*24*time.Hour, 12*24*time.Hour)
+
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	graceEnds := start.Add(10 * 24 * time.Hour)
+
+	first := nextReminderAt(start, 0, graceEnds)
+	if first == nil || *first != "2024-03-03T00:00:00Z" {
+		t.Errorf("first reminder = %v, want 2024-03-03", first)
+	}
+	if second := nextReminderAt(start, 1, graceEnds); second == nil || *second != "2024-03-06T00:00:00Z" {
+		t.Errorf("second reminder = %v, want 2024-03-06", second)
+	}
+	// The third reminder falls after the grace period ends
+	if third := nextReminderAt(start, 2, graceEnds); third != nil {
+		t.Errorf("third reminder = %v, want nil", *third)
+	}
+	if past := nextReminderAt(start, 3, graceEnds); past != nil {
+		t.Errorf("reminder past schedule = %v, want nil", *past)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		want     string
+	}{
+		{2900, "usd", "$29.00"},
+		{1999, "eur", "19.99 EUR"},
+		{0, "", "$0.00"},
+	}
+
+	for _, tt := range tests {
+		if got := formatAmount(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("formatAmount(%d, %q) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestDaysUntil(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := daysUntil(now.Add(36*time.Hour), now); got != 2 {
+		t.Errorf("daysUntil(36h) = %d, want 2", got)
+	}
+	if got := daysUntil(now.Add(-time.Hour), now); got != 0 {
+		t.Errorf("daysUntil(past) = %d, want 0", got)
+	}
+	if got := formatDaysRemaining(now.Add(time.Hour), now); got != "1 day" {
+		t.Errorf("formatDaysRemaining(1h) = %q, want 1 day", got)
+	}
+}
+
+// ============ Integration Tests ============
+
+func TestPaymentFailed_StartsDunning(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+	withDunningSchedule(t, 14*24*time.Hour, 3*24*time.Hour)
+
+	ctx := context.Background()
+	user := createTestUserForWebhook(t, "dunning@example.com", "cus_dunning")
+	sub := createTestSubscription(t, user.ID, "sub_dunning", models.SubscriptionStatusActive)
+
+	invoice := &stripe.Invoice{
+		ID:           "in_failed_1",
+		Customer:     &stripe.Customer{ID: "cus_dunning"},
+		Subscription: &stripe.Subscription{ID: "sub_dunning"},
+		AmountDue:    2900,
+		Currency:     stripe.CurrencyUSD,
+	}
+	if err := handlePaymentFailed(ctx, invoice); err != nil {
+		t.Fatalf("handlePaymentFailed() error = %v", err)
+	}
+
+	var dunning models.Dunning
+	if err := database.DB.Where("subscription_id = ?", sub.ID).First(&dunning).Error; err != nil {
+		t.Fatalf("expected a dunning case: %v", err)
+	}
+	if dunning.Status != models.DunningStatusActive || dunning.NextReminderAt == nil {
+		t.Errorf("dunning = %+v", dunning)
+	}
+
+	// A retried payment failure keeps the original grace period
+	invoice.ID = "in_failed_2"
+	if err := handlePaymentFailed(ctx, invoice); err != nil {
+		t.Fatalf("handlePaymentFailed() retry error = %v", err)
+	}
+	var count int64
+	database.DB.Model(&models.Dunning{}).Where("subscription_id = ?", sub.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("expected a single dunning case, got %d", count)
+	}
+	var updated models.Dunning
+	database.DB.First(&updated, dunning.ID)
+	if updated.StripeInvoiceID != "in_failed_2" || updated.GraceEndsAt != dunning.GraceEndsAt {
+		t.Errorf("retry should update the invoice only, got %+v", updated)
+	}
+
+	// A successful payment ends dunning
+	if err := handlePaymentSucceeded(ctx, &stripe.Invoice{ID: "in_failed_2", Subscription: &stripe.Subscription{ID: "sub_dunning"}}); err != nil {
+		t.Fatalf("handlePaymentSucceeded() error = %v", err)
+	}
+	database.DB.First(&updated, dunning.ID)
+	if updated.Status != models.DunningStatusResolved || updated.ResolvedAt == nil {
+		t.Errorf("status = %q, want %q", updated.Status, models.DunningStatusResolved)
+	}
+}
+
+func TestDunningProcessor_RemindsThenDowngrades(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+	withDunningSchedule(t, 7*24*time.Hour, 2*24*time.Hour, 5*24*time.Hour)
+
+	ctx := context.Background()
+	org := createTestOrganization(t, "dunning-org", "cus_dunning_org")
+	database.DB.Model(org).Update("plan", models.OrgPlanPro)
+	sub := createTestOrgSubscription(t, org.ID, org.CreatedByUserID, "sub_dunning_org", models.SubscriptionStatusActive)
+
+	if err := handlePaymentFailed(ctx, &stripe.Invoice{
+		ID:           "in_org_failed",
+		Customer:     &stripe.Customer{ID: "cus_dunning_org"},
+		Subscription: &stripe.Subscription{ID: "sub_dunning_org"},
+		AmountDue:    9900,
+	}); err != nil {
+		t.Fatalf("handlePaymentFailed() error = %v", err)
+	}
+
+	processor := NewDunningProcessor(database.DB)
+	start := time.Now()
+	run := func(at time.Time) models.Dunning {
+		t.Helper()
+		processor.now = func() time.Time { return at }
+		if err := processor.ProcessDunning(ctx); err != nil {
+			t.Fatalf("ProcessDunning() error = %v", err)
+		}
+		var dunning models.Dunning
+		database.DB.Where("subscription_id = ?", sub.ID).First(&dunning)
+		return dunning
+	}
+
+	// Nothing is due yet
+	if d := run(start.Add(time.Hour)); d.RemindersSent != 0 {
+		t.Errorf("reminders sent = %d, want 0", d.RemindersSent)
+	}
+
+	// First and second reminders
+	if d := run(start.Add(2*24*time.Hour + time.Hour)); d.RemindersSent != 1 || d.NextReminderAt == nil {
+		t.Errorf("after first reminder: %+v", d)
+	}
+	if d := run(start.Add(5*24*time.Hour + time.Hour)); d.RemindersSent != 2 || d.NextReminderAt != nil {
+		t.Errorf("after final reminder: %+v", d)
+	}
+
+	// The plan is kept during the grace period
+	var stored models.Organization
+	database.DB.First(&stored, org.ID)
+	if stored.Plan != models.OrgPlanPro {
+		t.Errorf("plan during grace = %q, want %q", stored.Plan, models.OrgPlanPro)
+	}
+
+	// Grace period ends: downgrade
+	if d := run(start.Add(7*24*time.Hour + time.Hour)); d.Status != models.DunningStatusExpired {
+		t.Errorf("status = %q, want %q", d.Status, models.DunningStatusExpired)
+	}
+	database.DB.First(&stored, org.ID)
+	if stored.Plan != models.OrgPlanFree {
+		t.Errorf("plan after grace = %q, want %q", stored.Plan, models.OrgPlanFree)
+	}
+
+	// A later past-due update does not restore the paid plan
+	if err := handleSubscriptionUpdated(ctx, &stripe.Subscription{
+		ID:     "sub_dunning_org",
+		Status: stripe.SubscriptionStatusPastDue,
+		Items: &stripe.SubscriptionItemList{
+			Data: []*stripe.SubscriptionItem{{Price: &stripe.Price{ID: "price_org_test_123"}}},
+		},
+	}); err != nil {
+		t.Fatalf("handleSubscriptionUpdated() error = %v", err)
+	}
+	database.DB.First(&stored, org.ID)
+	if stored.Plan != models.OrgPlanFree {
+		t.Errorf("plan after past_due update = %q, want %q", stored.Plan, models.OrgPlanFree)
+	}
+}
@@ -16,6 +16,17 @@ var (
 	// Subscription errors
 	ErrSubscriptionNotFound = errors.New("stripe: subscription not found")
 	ErrNoActiveSubscription = errors.New("stripe: no active subscription")
+	ErrNoSubscriptionItems  = errors.New("stripe: subscription has no items")
+	ErrSamePrice            = errors.New("stripe: subscription is already on this price")
+	ErrUnknownPrice         = errors.New("stripe: price is not part of any plan")
+	ErrNotScheduledToCancel = errors.New("stripe: subscription is not scheduled for cancellation")
+	ErrTrialNotAvailable    = errors.New("stripe: no free trial available for this plan")
+
+	// Discount errors
+	ErrInvalidPromotionCode       = errors.New("stripe: promotion code is invalid or expired")
+	ErrPromotionCodeNotApplicable = errors.New("stripe: promotion code does not apply to this price")
+	ErrCouponCodeExists           = errors.New("stripe: coupon code already exists")
+	ErrCouponNotFound             = errors.New("stripe: coupon not found")
 
 	// Webhook errors
 	ErrInvalidSignature = errors.New("stripe: invalid webhook signature")
@@ -0,0 +1,459 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/jobs"
+	"react-golang-starter/internal/models"
+)
+
+// stripeEventStallAfter is how long a stored event may stay pending or retrying without
+// progress before it is considered stalled and queued again
+const stripeEventStallAfter = 15 * time.Minute
+
+// stalledEventStatuses are the statuses of events still waiting to be processed
+var stalledEventStatuses = []string{models.StripeEventStatusPending, models.StripeEventStatusRetrying}
+
+// eventHandler processes stored Stripe events on behalf of the job worker
+type eventHandler struct{}
+
+// NewEventHandler returns the handler to register with jobs.SetStripeEventHandler
+func NewEventHandler() jobs.StripeEventHandler {
+	return &eventHandler{}
+}
+
+// NewEventSweeper returns the sweeper to register with jobs.SetStripeEventSweeper
+func NewEventSweeper() jobs.StripeEventSweeper {
+	return &eventHandler{}
+}
+
+// HandleStripeEvent processes an event queued by the webhook endpoint or a replay
+func (h *eventHandler) HandleStripeEvent(ctx context.Context, eventID, eventType string, payload json.RawMessage, finalAttempt bool) error {
+	var record models.StripeEvent
+	if err := database.DB.WithContext(ctx).Where("event_id = ?", eventID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Queued without being persisted (e.g. enqueued before the store existed)
+			return dispatchEvent(ctx, eventType, payload)
+		}
+		return fmt.Errorf("failed to load stripe event: %w", err)
+	}
+
+	return processStoredEvent(ctx, &record, finalAttempt)
+}
+
+// ResumeStalledEvents queues stored events that have been pending or retrying for longer
+// than stripeEventStallAfter, such as events stored just before a crash and never queued.
+// Returns the number of events queued again.
+func (h *eventHandler) ResumeStalledEvents(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-stripeEventStallAfter)
+
+	var stalled []models.StripeEvent
+	if err := database.DB.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", stalledEventStatuses, cutoff).
+		Order("stripe_created_at ASC").
+		Limit(500).
+		Find(&stalled).Error; err != nil {
+		return 0, fmt.Errorf("failed to load stalled stripe events: %w", err)
+	}
+
+	resumed := 0
+	for i := range stalled {
+		ok, err := resumeEvent(ctx, &stalled[i], cutoff)
+		if err != nil {
+			log.Error().Err(err).Str("event_id", stalled[i].EventID).Msg("failed to resume stalled stripe event")
+			continue
+		}
+		if ok {
+			resumed++
+		}
+	}
+	return resumed, nil
+}
+
+// resumeStalledEvent queues a redelivered event again if its stored copy has stalled, so a
+// redelivery recovers an event that was stored but never processed
+func resumeStalledEvent(ctx context.Context, eventID string) (bool, error) {
+	var record models.StripeEvent
+	if err := database.DB.WithContext(ctx).Where("event_id = ?", eventID).First(&record).Error; err != nil {
+		return false, err
+	}
+	return resumeEvent(ctx, &record, time.Now().Add(-stripeEventStallAfter))
+}
+
+// resumeEvent claims a stalled event by touching it, so concurrent sweeps and redeliveries
+// resume it once, then queues it. Without a job queue it is processed inline. Returns false
+// if the event was no longer stalled.
+func resumeEvent(ctx context.Context, record *models.StripeEvent, cutoff time.Time) (bool, error) {
+	result := database.DB.WithContext(ctx).Model(&models.StripeEvent{}).
+		Where("id = ? AND status IN ? AND updated_at < ?", record.ID, stalledEventStatuses, cutoff).
+		Update("updated_at", time.Now().Format(time.RFC3339))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	log.Warn().Str("event_id", record.EventID).Str("status", record.Status).Msg("resuming stalled stripe event")
+	if err := jobs.EnqueueStripeWebhook(ctx, record.EventID, record.EventType, json.RawMessage(record.Payload)); err != nil {
+		if err := processStoredEvent(ctx, record, true); err != nil {
+			// Failure is recorded on the stored event and can be replayed by an admin
+			log.Error().Err(err).Str("event_id", record.EventID).Msg("failed to process stalled stripe event")
+		}
+	}
+	return true, nil
+}
+
+// recordEvent persists a verified event. It returns false if the event ID was already stored.
+func recordEvent(ctx context.Context, event *stripe.Event) (*models.StripeEvent, bool, error) {
+	now := time.Now().Format(time.RFC3339)
+	record := models.StripeEvent{
+		EventID:         event.ID,
+		EventType:       string(event.Type),
+		ObjectID:        eventObjectID(event),
+		StripeCreatedAt: event.Created,
+		Payload:         string(event.Data.Raw),
+		Status:          models.StripeEventStatusPending,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if len(event.Data.Raw) == 0 {
+		record.Payload = "{}"
+	}
+
+	result := database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).
+		Create(&record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+
+	return &record, result.RowsAffected > 0, nil
+}
+
+// eventObjectID extracts the ID of the object an event describes
+func eventObjectID(event *stripe.Event) string {
+	if event.Data == nil || event.Data.Object == nil {
+		return ""
+	}
+	id, _ := event.Data.Object["id"].(string)
+	return id
+}
+
+// snapshotEventTypes are events whose handlers only store the latest state of their object.
+// An older delivery of one of these is skipped once a newer event for the object has been
+// applied. Every other event triggers an action (dunning, downgrades, reminders) and is
+// always dispatched, whatever order it arrives in.
+var snapshotEventTypes = map[string]bool{
+	"customer.subscription.updated": true,
+	"invoice.created":               true,
+	"invoice.finalized":             true,
+	"invoice.updated":               true,
+	"invoice.voided":                true,
+	"invoice.marked_uncollectible":  true,
+}
+
+// processStoredEvent applies a stored event and records the outcome. A failure is only
+// recorded as failed, and offered for replay, on the final attempt; until then the event
+// is retrying.
+func processStoredEvent(ctx context.Context, record *models.StripeEvent, finalAttempt bool) error {
+	stale, err := isStaleEvent(ctx, record)
+	if err != nil {
+		return fmt.Errorf("failed to check event ordering: %w", err)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	updates := map[string]any{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"updated_at": now,
+	}
+
+	if stale {
+		log.Info().
+			Str("event_id", record.EventID).
+			Str("object_id", record.ObjectID).
+			Msg("skipping stripe event superseded by a newer event")
+		updates["status"] = models.StripeEventStatusSkipped
+		updates["processed_at"] = now
+		updates["last_error"] = nil
+		return database.DB.WithContext(ctx).Model(record).Updates(updates).Error
+	}
+
+	processErr := dispatchEvent(ctx, record.EventType, json.RawMessage(record.Payload))
+	if processErr != nil {
+		updates["status"] = models.StripeEventStatusRetrying
+		if finalAttempt {
+			updates["status"] = models.StripeEventStatusFailed
+		}
+		updates["last_error"] = processErr.Error()
+	} else {
+		updates["status"] = models.StripeEventStatusProcessed
+		updates["processed_at"] = now
+		updates["last_error"] = nil
+	}
+
+	if err := database.DB.WithContext(ctx).Model(record).Updates(updates).Error; err != nil {
+		log.Error().Err(err).Str("event_id", record.EventID).Msg("failed to update stripe event status")
+	}
+
+	return processErr
+}
+
+// isStaleEvent reports whether a snapshot event is older than an event already applied to
+// the same object. Stripe does not guarantee delivery order, so an older snapshot must not
+// overwrite a newer one.
+func isStaleEvent(ctx context.Context, record *models.StripeEvent) (bool, error) {
+	if record.ObjectID == "" || !snapshotEventTypes[record.EventType] {
+		return false, nil
+	}
+
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&models.StripeEvent{}).
+		Where("object_id = ? AND event_id <> ? AND status = ? AND stripe_created_at > ?",
+			record.ObjectID, record.EventID, models.StripeEventStatusProcessed, record.StripeCreatedAt).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// dispatchEvent routes an event payload to its handler
+func dispatchEvent(ctx context.Context, eventType string, payload json.RawMessage) error {
+	switch eventType {
+	case "checkout.session.completed":
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(payload, &session); err != nil {
+			return fmt.Errorf("failed to unmarshal checkout session: %w", err)
+		}
+		return handleCheckoutSessionCompleted(ctx, &session)
+
+	case "customer.subscription.created":
+		var sub stripe.Subscription
+		if err := json.Unmarshal(payload, &sub); err != nil {
+			return fmt.Errorf("failed to unmarshal subscription: %w", err)
+		}
+		return handleSubscriptionCreated(ctx, &sub)
+
+	case "customer.subscription.updated":
+		var sub stripe.Subscription
+		if err := json.Unmarshal(payload, &sub); err != nil {
+			return fmt.Errorf("failed to unmarshal subscription: %w", err)
+		}
+		return handleSubscriptionUpdated(ctx, &sub)
+
+	case "customer.subscription.deleted":
+		var sub stripe.Subscription
+		if err := json.Unmarshal(payload, &sub); err != nil {
+			return fmt.Errorf("failed to unmarshal subscription: %w", err)
+		}
+		return handleSubscriptionDeleted(ctx, &sub)
+
+	case "customer.subscription.trial_will_end":
+		var sub stripe.Subscription
+		if err := json.Unmarshal(payload, &sub); err != nil {
+			return fmt.Errorf("failed to unmarshal subscription: %w", err)
+		}
+		return handleTrialWillEnd(ctx, &sub)
+
+	case "invoice.payment_failed":
+		var invoice stripe.Invoice
+		if err := json.Unmarshal(payload, &invoice); err != nil {
+			return fmt.Errorf("failed to unmarshal invoice: %w", err)
+		}
+		if err := handleInvoiceEvent(ctx, &invoice); err != nil {
+			return err
+		}
+		return handlePaymentFailed(ctx, &invoice)
+
+	case "invoice.paid", "invoice.payment_succeeded":
+		var invoice stripe.Invoice
+		if err := json.Unmarshal(payload, &invoice); err != nil {
+			return fmt.Errorf("failed to unmarshal invoice: %w", err)
+		}
+		if err := handleInvoiceEvent(ctx, &invoice); err != nil {
+			return err
+		}
+		return handlePaymentSucceeded(ctx, &invoice)
+
+	case "invoice.created", "invoice.finalized", "invoice.updated",
+		"invoice.voided", "invoice.marked_uncollectible":
+		var invoice stripe.Invoice
+		if err := json.Unmarshal(payload, &invoice); err != nil {
+			return fmt.Errorf("failed to unmarshal invoice: %w", err)
+		}
+		return handleInvoiceEvent(ctx, &invoice)
+
+	case "invoice.deleted":
+		var invoice stripe.Invoice
+		if err := json.Unmarshal(payload, &invoice); err != nil {
+			return fmt.Errorf("failed to unmarshal invoice: %w", err)
+		}
+		return handleInvoiceDeleted(ctx, &invoice)
+
+	default:
+		log.Debug().Str("event_type", eventType).Msg("unhandled webhook event type")
+		return nil
+	}
+}
+
+// ListFailedEvents returns stored Stripe events whose processing failed
+// @Summary List failed Stripe webhook events
+// @Description Returns stored webhook events that failed processing, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param type query string false "Filter by event type"
+// @Success 200 {object} models.StripeEventsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/admin/stripe/events/failed [get]
+func ListFailedEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		query := database.DB.WithContext(r.Context()).Model(&models.StripeEvent{}).
+			Where("status = ?", models.StripeEventStatusFailed)
+		if eventType := r.URL.Query().Get("type"); eventType != "" {
+			query = query.Where("event_type = ?", eventType)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			log.Error().Err(err).Msg("failed to count failed stripe events")
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to fetch events")
+			return
+		}
+
+		events := []models.StripeEvent{}
+		if err := query.Order("stripe_created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&events).Error; err != nil {
+			log.Error().Err(err).Msg("failed to fetch failed stripe events")
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to fetch events")
+			return
+		}
+
+		response := models.StripeEventsResponse{
+			Events:     events,
+			Count:      len(events),
+			Total:      int(total),
+			Page:       page,
+			Limit:      limit,
+			TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ReplayEvent re-queues a failed or stalled Stripe event for processing
+// @Summary Replay a failed Stripe webhook event
+// @Description Resets a failed event, or one stalled in pending or retrying, to pending and processes it again through the job queue
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param eventId path string true "Stripe event ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/admin/stripe/events/{eventId}/replay [post]
+func ReplayEvent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		eventID := chi.URLParam(r, "eventId")
+		if eventID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "Event ID is required")
+			return
+		}
+
+		var record models.StripeEvent
+		if err := database.DB.WithContext(r.Context()).Where("event_id = ?", eventID).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				writeErrorResponse(w, http.StatusNotFound, "Event not found")
+				return
+			}
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to fetch event")
+			return
+		}
+
+		// Claim the event so concurrent replays cannot queue it twice
+		result := database.DB.WithContext(r.Context()).Model(&models.StripeEvent{}).
+			Where("id = ? AND (status = ? OR (status IN ? AND updated_at < ?))", record.ID, models.StripeEventStatusFailed,
+				stalledEventStatuses, time.Now().Add(-stripeEventStallAfter)).
+			Updates(map[string]any{
+				"status":     models.StripeEventStatusPending,
+				"updated_at": time.Now().Format(time.RFC3339),
+			})
+		if result.Error != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to reset event")
+			return
+		}
+		if result.RowsAffected == 0 {
+			writeErrorResponse(w, http.StatusConflict, "Only failed or stalled events can be replayed")
+			return
+		}
+
+		message := "Event queued for replay"
+		if err := jobs.ReplayStripeWebhook(r.Context(), record.EventID, record.EventType, json.RawMessage(record.Payload)); err != nil {
+			// No job queue available; process inline so the replay still happens
+			if err := processStoredEvent(r.Context(), &record, true); err != nil {
+				log.Error().Err(err).Str("event_id", record.EventID).Msg("stripe event replay failed")
+				writeErrorResponse(w, http.StatusInternalServerError, "Replay failed: "+err.Error())
+				return
+			}
+			message = "Event replayed"
+		}
+
+		log.Info().Str("event_id", record.EventID).Msg("stripe event replay requested")
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.SuccessResponse{
+			Success: true,
+			Message: message,
+		}); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// writeErrorResponse writes a JSON error response in the shape used by the billing handlers
+func writeErrorResponse(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error:   http.StatusText(status),
+		Message: message,
+		Code:    status,
+	}); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,375 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	stripe "github.com/stripe/stripe-go/v76"
+)
+
+// ============ Unit Tests ============
+
+func TestEventObjectID(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *stripe.Event
+		want  string
+	}{
+		{
+			name:  "nil data",
+			event: &stripe.Event{},
+			want:  "",
+		},
+		{
+			name:  "object with id",
+			event: &stripe.Event{Data: &stripe.EventData{Object: map[string]interface{}{"id": "sub_123"}}},
+			want:  "sub_123",
+		},
+		{
+			name:  "object without id",
+			event: &stripe.Event{Data: &stripe.EventData{Object: map[string]interface{}{}}},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eventObjectID(tt.event); got != tt.want {
+				t.Errorf("eventObjectID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatchEvent_UnhandledType(t *testing.T) {
+	if err := dispatchEvent(context.Background(), "ping", json.RawMessage(`{}`)); err != nil {
+		t.Errorf("dispatchEvent() error = %v, want nil for unhandled event", err)
+	}
+}
+
+func TestDispatchEvent_InvalidPayload(t *testing.T) {
+	if err := dispatchEvent(context.Background(), "customer.subscription.updated", json.RawMessage(`not json`)); err == nil {
+		t.Error("dispatchEvent() should return error for an invalid payload")
+	}
+}
+
+// ============ Integration Tests ============
+
+func newTestStripeEvent(id, eventType, objectID string, created int64) *stripe.Event {
+	raw := json.RawMessage(`{"id":"` + objectID + `"}`)
+	return &stripe.Event{
+		ID:      id,
+		Type:    stripe.EventType(eventType),
+		Created: created,
+		Data: &stripe.EventData{
+			Object: map[string]interface{}{"id": objectID},
+			Raw:    raw,
+		},
+	}
+}
+
+func TestRecordEvent_Deduplicates(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	event := newTestStripeEvent("evt_dedupe_1", "customer.subscription.updated", "sub_dedupe", time.Now().Unix())
+
+	record, created, err := recordEvent(ctx, event)
+	if err != nil {
+		t.Fatalf("recordEvent() error = %v", err)
+	}
+	if !created {
+		t.Fatal("recordEvent() should create the first delivery")
+	}
+	if record.ObjectID != "sub_dedupe" || record.Status != models.StripeEventStatusPending {
+		t.Errorf("recordEvent() stored object=%q status=%q", record.ObjectID, record.Status)
+	}
+
+	_, created, err = recordEvent(ctx, event)
+	if err != nil {
+		t.Fatalf("recordEvent() redelivery error = %v", err)
+	}
+	if created {
+		t.Error("recordEvent() should ignore a redelivered event")
+	}
+
+	var count int64
+	database.DB.Model(&models.StripeEvent{}).Where("event_id = ?", "evt_dedupe_1").Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 stored event, got %d", count)
+	}
+}
+
+func TestProcessStoredEvent_SkipsStaleEvent(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	newer, _, err := recordEvent(ctx, newTestStripeEvent("evt_newer", "invoice.updated", "in_order", now))
+	if err != nil {
+		t.Fatalf("recordEvent() error = %v", err)
+	}
+	if err := processStoredEvent(ctx, newer, true); err != nil {
+		t.Fatalf("processStoredEvent() error = %v", err)
+	}
+
+	older, _, err := recordEvent(ctx, newTestStripeEvent("evt_older", "invoice.updated", "in_order", now-60))
+	if err != nil {
+		t.Fatalf("recordEvent() error = %v", err)
+	}
+	if err := processStoredEvent(ctx, older, true); err != nil {
+		t.Fatalf("processStoredEvent() error = %v", err)
+	}
+
+	var stored models.StripeEvent
+	database.DB.Where("event_id = ?", "evt_older").First(&stored)
+	if stored.Status != models.StripeEventStatusSkipped {
+		t.Errorf("older event status = %q, want %q", stored.Status, models.StripeEventStatusSkipped)
+	}
+
+	database.DB.Where("event_id = ?", "evt_newer").First(&stored)
+	if stored.Status != models.StripeEventStatusProcessed {
+		t.Errorf("newer event status = %q, want %q", stored.Status, models.StripeEventStatusProcessed)
+	}
+}
+
+func TestProcessStoredEvent_AlwaysDispatchesActionEvents(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+	user := createTestUserForWebhook(t, "event-order@example.com", "cus_event_order")
+	sub := createTestSubscription(t, user.ID, "sub_event_order", models.SubscriptionStatusActive)
+
+	invoice := &stripe.Invoice{
+		ID:           "in_event_order",
+		Customer:     &stripe.Customer{ID: "cus_event_order"},
+		Subscription: &stripe.Subscription{ID: "sub_event_order"},
+		Status:       stripe.InvoiceStatusOpen,
+		AmountDue:    2900,
+		Currency:     stripe.CurrencyUSD,
+	}
+	payload, err := json.Marshal(invoice)
+	if err != nil {
+		t.Fatalf("failed to marshal invoice: %v", err)
+	}
+	event := func(id, eventType string, created int64) *stripe.Event {
+		e := newTestStripeEvent(id, eventType, invoice.ID, created)
+		e.Data.Raw = payload
+		return e
+	}
+
+	// invoice.updated is delivered and applied before the earlier payment failure
+	updated, _, err := recordEvent(ctx, event("evt_order_updated", "invoice.updated", now))
+	if err != nil {
+		t.Fatalf("recordEvent() error = %v", err)
+	}
+	if err := processStoredEvent(ctx, updated, true); err != nil {
+		t.Fatalf("processStoredEvent() error = %v", err)
+	}
+
+	failed, _, err := recordEvent(ctx, event("evt_order_failed", "invoice.payment_failed", now-60))
+	if err != nil {
+		t.Fatalf("recordEvent() error = %v", err)
+	}
+	if err := processStoredEvent(ctx, failed, true); err != nil {
+		t.Fatalf("processStoredEvent() error = %v", err)
+	}
+
+	var stored models.StripeEvent
+	database.DB.Where("event_id = ?", "evt_order_failed").First(&stored)
+	if stored.Status != models.StripeEventStatusProcessed {
+		t.Errorf("payment_failed status = %q, want %q", stored.Status, models.StripeEventStatusProcessed)
+	}
+
+	var dunning models.Dunning
+	if err := database.DB.Where("subscription_id = ?", sub.ID).First(&dunning).Error; err != nil {
+		t.Errorf("expected a dunning case for the out-of-order payment failure: %v", err)
+	}
+}
+
+func TestProcessStoredEvent_RecordsFailure(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Updating an unknown subscription fails so the event can be retried
+	record, _, err := recordEvent(ctx, newTestStripeEvent("evt_fail", "customer.subscription.updated", "sub_missing", time.Now().Unix()))
+	if err != nil {
+		t.Fatalf("recordEvent() error = %v", err)
+	}
+	if err := processStoredEvent(ctx, record, false); err == nil {
+		t.Fatal("processStoredEvent() should return error for an unknown subscription")
+	}
+
+	// Attempts the job queue will retry do not fail the event yet
+	var stored models.StripeEvent
+	database.DB.Where("event_id = ?", "evt_fail").First(&stored)
+	if stored.Status != models.StripeEventStatusRetrying {
+		t.Errorf("status = %q, want %q", stored.Status, models.StripeEventStatusRetrying)
+	}
+
+	if err := processStoredEvent(ctx, record, true); err == nil {
+		t.Fatal("processStoredEvent() should return error for an unknown subscription")
+	}
+
+	database.DB.Where("event_id = ?", "evt_fail").First(&stored)
+	if stored.Status != models.StripeEventStatusFailed {
+		t.Errorf("status = %q, want %q", stored.Status, models.StripeEventStatusFailed)
+	}
+	if stored.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", stored.Attempts)
+	}
+	if stored.LastError == nil {
+		t.Error("last_error should be set")
+	}
+
+	// The failed event appears in the admin list
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/stripe/events/failed", nil)
+	rr := httptest.NewRecorder()
+	ListFailedEvents()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ListFailedEvents() status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	var resp models.StripeEventsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Events) != 1 || resp.Events[0].EventID != "evt_fail" {
+		t.Errorf("ListFailedEvents() = %+v, want the failed event", resp)
+	}
+}
+
+func TestResumeStalledEvents_Integration(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	// Stored before a crash and never queued
+	stalled, _, err := recordEvent(ctx, newTestStripeEvent("evt_stalled", "ping", "obj_stalled", now))
+	if err != nil {
+		t.Fatalf("recordEvent() error = %v", err)
+	}
+	database.DB.Model(stalled).Update("updated_at", time.Now().Add(-time.Hour).Format(time.RFC3339))
+
+	// Recently stored, so its job may still be waiting in the queue
+	if _, _, err := recordEvent(ctx, newTestStripeEvent("evt_recent", "ping", "obj_recent", now)); err != nil {
+		t.Fatalf("recordEvent() error = %v", err)
+	}
+
+	resumed, err := NewEventSweeper().ResumeStalledEvents(ctx)
+	if err != nil {
+		t.Fatalf("ResumeStalledEvents() error = %v", err)
+	}
+	if resumed != 1 {
+		t.Errorf("ResumeStalledEvents() = %d, want 1", resumed)
+	}
+
+	// Without a job queue the stalled event is processed inline
+	var stored models.StripeEvent
+	database.DB.Where("event_id = ?", "evt_stalled").First(&stored)
+	if stored.Status != models.StripeEventStatusProcessed {
+		t.Errorf("stalled event status = %q, want %q", stored.Status, models.StripeEventStatusProcessed)
+	}
+	database.DB.Where("event_id = ?", "evt_recent").First(&stored)
+	if stored.Status != models.StripeEventStatusPending {
+		t.Errorf("recent event status = %q, want %q", stored.Status, models.StripeEventStatusPending)
+	}
+
+	// A redelivery of a recent event is not resumed while it may still be queued
+	if ok, err := resumeStalledEvent(ctx, "evt_recent"); err != nil || ok {
+		t.Errorf("resumeStalledEvent() = %v, %v, want false for a recent event", ok, err)
+	}
+}
+
+func TestReplayEvent_Integration(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	replay := func(eventID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/stripe/events/"+eventID+"/replay", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("eventId", eventID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		ReplayEvent()(rr, req)
+		return rr
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		if rr := replay("evt_unknown"); rr.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("processed event cannot be replayed", func(t *testing.T) {
+		record, _, _ := recordEvent(ctx, newTestStripeEvent("evt_done", "ping", "obj_done", time.Now().Unix()))
+		_ = processStoredEvent(ctx, record, true)
+
+		if rr := replay("evt_done"); rr.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", rr.Code, http.StatusConflict)
+		}
+	})
+
+	t.Run("stalled pending event can be replayed", func(t *testing.T) {
+		record, _, _ := recordEvent(ctx, newTestStripeEvent("evt_stalled_replay", "ping", "obj_stalled_replay", time.Now().Unix()))
+		if rr := replay("evt_stalled_replay"); rr.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d while the event may still be queued", rr.Code, http.StatusConflict)
+		}
+
+		database.DB.Model(record).Update("updated_at", time.Now().Add(-time.Hour).Format(time.RFC3339))
+		if rr := replay("evt_stalled_replay"); rr.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+	})
+
+	t.Run("failed event succeeds on replay", func(t *testing.T) {
+		user := createTestUserForWebhook(t, "replay@example.com", "cus_replay")
+
+		record, _, _ := recordEvent(ctx, newTestStripeEvent("evt_replay", "customer.subscription.updated", "sub_replay", time.Now().Unix()))
+		record.Payload = `{"id":"sub_replay","status":"active","current_period_start":1700000000,"current_period_end":1702592000}`
+		database.DB.Model(record).Update("payload", record.Payload)
+
+		// Fails first because the subscription does not exist yet
+		if err := processStoredEvent(ctx, record, true); err == nil {
+			t.Fatal("expected first processing attempt to fail")
+		}
+
+		createTestSubscription(t, user.ID, "sub_replay", models.SubscriptionStatusPastDue)
+
+		rr := replay("evt_replay")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+
+		var stored models.StripeEvent
+		database.DB.Where("event_id = ?", "evt_replay").First(&stored)
+		if stored.Status != models.StripeEventStatusProcessed {
+			t.Errorf("status = %q, want %q", stored.Status, models.StripeEventStatusProcessed)
+		}
+
+		var sub models.Subscription
+		database.DB.Where("stripe_subscription_id = ?", "sub_replay").First(&sub)
+		if sub.Status != models.SubscriptionStatusActive {
+			t.Errorf("subscription status = %q, want %q", sub.Status, models.SubscriptionStatusActive)
+		}
+	})
+}
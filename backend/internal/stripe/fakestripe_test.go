@@ -0,0 +1,322 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/testutil"
+
+	stripe "github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+// newFakeBackedService returns the real Stripe service talking to a fake Stripe server
+func newFakeBackedService(t *testing.T) (*stripeService, *testutil.FakeStripeServer) {
+	t.Helper()
+	fake := testutil.StartFakeStripe(t)
+	svc := &stripeService{config: &Config{
+		Enabled:        true,
+		SecretKey:      testutil.FakeStripeSecretKey,
+		PublishableKey: "pk_test_fake",
+		WebhookSecret:  testutil.FakeStripeWebhookSecret,
+	}}
+	return svc, fake
+}
+
+// ============ Unit Tests ============
+
+func TestFakeStripe_CheckoutToSubscription(t *testing.T) {
+	svc, fake := newFakeBackedService(t)
+	ctx := context.Background()
+
+	customerID, err := svc.CreateCustomer(ctx, &models.User{ID: 42, Email: "fake@example.com", Name: "Fake"})
+	if err != nil {
+		t.Fatalf("CreateCustomer() error = %v", err)
+	}
+	if c := fake.Customer(customerID); c == nil || c.Email != "fake@example.com" || c.Metadata["user_id"] != "42" {
+		t.Fatalf("customer = %+v, want email and user_id metadata", c)
+	}
+
+	session, err := svc.CreateCheckoutSession(ctx, customerID, "price_pro_monthly", "http://app/success", "http://app/cancel", CheckoutOptions{
+		Discount: &Discount{CouponID: "co_launch"},
+	})
+	if err != nil {
+		t.Fatalf("CreateCheckoutSession() error = %v", err)
+	}
+	if !strings.HasPrefix(session.URL, fake.URL()) {
+		t.Errorf("session URL = %q, want it hosted by the fake", session.URL)
+	}
+	if cs := fake.CheckoutSession(session.ID); cs.SuccessURL != "http://app/success?session_id="+session.ID || cs.CouponID != "co_launch" {
+		t.Errorf("checkout session = %+v", cs)
+	}
+
+	subID, err := fake.CompleteCheckout(session.ID)
+	if err != nil {
+		t.Fatalf("CompleteCheckout() error = %v", err)
+	}
+
+	sub, err := svc.GetSubscription(ctx, subID)
+	if err != nil {
+		t.Fatalf("GetSubscription() error = %v", err)
+	}
+	if sub.Status != stripe.SubscriptionStatusActive || sub.Customer.ID != customerID || sub.Items.Data[0].Price.ID != "price_pro_monthly" {
+		t.Errorf("subscription = %+v", sub)
+	}
+	if sub.Discount == nil || sub.Discount.Coupon.ID != "co_launch" {
+		t.Errorf("subscription discount = %+v, want the checkout coupon", sub.Discount)
+	}
+
+	var types []string
+	for _, payload := range fake.PendingEvents() {
+		var event stripe.Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			t.Fatalf("invalid event payload: %v", err)
+		}
+		types = append(types, string(event.Type))
+	}
+	want := []string{"customer.created", "checkout.session.completed", "customer.subscription.created", "invoice.finalized", "invoice.paid"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", types, want)
+	}
+}
+
+func TestFakeStripe_TrialCheckout(t *testing.T) {
+	svc, fake := newFakeBackedService(t)
+	ctx := context.Background()
+
+	customerID, _ := svc.CreateCustomer(ctx, &models.User{ID: 1, Email: "trial@example.com"})
+	session, err := svc.CreateCheckoutSession(ctx, customerID, "price_pro_monthly", "http://app/success", "http://app/cancel", CheckoutOptions{TrialDays: 14})
+	if err != nil {
+		t.Fatalf("CreateCheckoutSession() error = %v", err)
+	}
+	subID, _ := fake.CompleteCheckout(session.ID)
+
+	sub := fake.Subscription(subID)
+	if sub.Status != string(stripe.SubscriptionStatusTrialing) || sub.TrialEnd == 0 {
+		t.Errorf("subscription = %+v, want a trial", sub)
+	}
+}
+
+func TestFakeStripe_SubscriptionManagement(t *testing.T) {
+	svc, fake := newFakeBackedService(t)
+	ctx := context.Background()
+
+	customerID, _ := svc.CreateCustomer(ctx, &models.User{ID: 7, Email: "manage@example.com"})
+	session, _ := svc.CreateCheckoutSession(ctx, customerID, "price_pro_monthly", "http://app/success", "http://app/cancel", CheckoutOptions{})
+	subID, _ := fake.CompleteCheckout(session.ID)
+
+	if _, err := svc.UpdateSubscriptionQuantity(ctx, subID, 5); err != nil {
+		t.Fatalf("UpdateSubscriptionQuantity() error = %v", err)
+	}
+	if got := fake.Subscription(subID).Quantity; got != 5 {
+		t.Errorf("quantity = %d, want 5", got)
+	}
+
+	current := fake.Subscription(subID)
+	midPeriod := current.CurrentPeriodStart + (current.CurrentPeriodEnd-current.CurrentPeriodStart)/2
+	preview, err := svc.PreviewSubscriptionChange(ctx, subID, "price_enterprise_monthly", midPeriod)
+	if err != nil {
+		t.Fatalf("PreviewSubscriptionChange() error = %v", err)
+	}
+	changePreview := buildChangePreview("price_pro_monthly", "price_enterprise_monthly", midPeriod, preview)
+	if len(changePreview.Lines) != 3 || changePreview.ProrationAmount <= 0 {
+		t.Errorf("preview = %+v, want credit, charge and next period lines", changePreview)
+	}
+
+	if _, err := svc.ChangeSubscriptionPrice(ctx, subID, "price_enterprise_monthly", midPeriod); err != nil {
+		t.Fatalf("ChangeSubscriptionPrice() error = %v", err)
+	}
+	if got := fake.Subscription(subID).PriceID; got != "price_enterprise_monthly" {
+		t.Errorf("price = %q, want enterprise", got)
+	}
+
+	sub, err := svc.CancelSubscription(ctx, subID, true)
+	if err != nil || !sub.CancelAtPeriodEnd {
+		t.Fatalf("CancelSubscription(at period end) = %+v, %v", sub, err)
+	}
+	if sub, err = svc.ResumeSubscription(ctx, subID); err != nil || sub.CancelAtPeriodEnd {
+		t.Fatalf("ResumeSubscription() = %+v, %v", sub, err)
+	}
+	if sub, err = svc.CancelSubscription(ctx, subID, false); err != nil || sub.Status != stripe.SubscriptionStatusCanceled {
+		t.Fatalf("CancelSubscription(now) = %+v, %v", sub, err)
+	}
+
+	if _, err := svc.GetSubscription(ctx, "sub_missing"); err == nil {
+		t.Error("GetSubscription() should fail for an unknown subscription")
+	} else if stripeErr, ok := err.(*stripe.Error); !ok || stripeErr.HTTPStatusCode != http.StatusNotFound {
+		t.Errorf("GetSubscription() error = %v, want a Stripe 404", err)
+	}
+}
+
+func TestFakeStripe_PricesAndPortal(t *testing.T) {
+	svc, _ := newFakeBackedService(t)
+	ctx := context.Background()
+
+	prices, err := svc.GetPrices(ctx)
+	if err != nil || len(prices) != 4 {
+		t.Fatalf("GetPrices() = %d prices, %v", len(prices), err)
+	}
+	price, err := svc.GetPrice(ctx, "price_enterprise_yearly")
+	if err != nil {
+		t.Fatalf("GetPrice() error = %v", err)
+	}
+	if price.UnitAmount != 99000 || price.Product == nil || price.Product.ID != "prod_enterprise" || price.Recurring.Interval != "year" {
+		t.Errorf("price = %+v", price)
+	}
+
+	customerID, _ := svc.CreateCustomer(ctx, &models.User{ID: 3, Email: "portal@example.com"})
+	portal, err := svc.CreatePortalSession(ctx, customerID, "http://app/billing")
+	if err != nil || portal.URL == "" {
+		t.Fatalf("CreatePortalSession() = %+v, %v", portal, err)
+	}
+
+	if err := svc.ReportMeterEvent(ctx, "api_calls", customerID, 10, time.Now().Unix(), "usage-1"); err != nil {
+		t.Errorf("ReportMeterEvent() error = %v", err)
+	}
+}
+
+func TestFakeStripe_SignedWebhookDelivery(t *testing.T) {
+	svc, fake := newFakeBackedService(t)
+	ctx := context.Background()
+
+	var received []stripe.Event
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		event, err := webhook.ConstructEvent(payload, r.Header.Get("Stripe-Signature"), testutil.FakeStripeWebhookSecret)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, event)
+	}))
+	defer endpoint.Close()
+
+	if _, err := svc.CreateCustomer(ctx, &models.User{ID: 9, Email: "hooks@example.com"}); err != nil {
+		t.Fatalf("CreateCustomer() error = %v", err)
+	}
+
+	// A wrong secret is rejected and the event stays queued for redelivery
+	fake.WebhookURL = endpoint.URL
+	fake.WebhookSecret = "whsec_wrong"
+	if err := fake.DeliverWebhooks(ctx); err == nil {
+		t.Fatal("DeliverWebhooks() should fail when the signature does not verify")
+	}
+	if len(fake.PendingEvents()) != 1 {
+		t.Fatalf("pending events = %d, want 1", len(fake.PendingEvents()))
+	}
+
+	fake.WebhookSecret = testutil.FakeStripeWebhookSecret
+	if err := fake.DeliverWebhooks(ctx); err != nil {
+		t.Fatalf("DeliverWebhooks() error = %v", err)
+	}
+	if len(received) != 1 || received[0].Type != "customer.created" {
+		t.Errorf("received = %+v, want customer.created", received)
+	}
+	if len(fake.PendingEvents()) != 0 || len(fake.DeliveredEvents()) != 1 {
+		t.Errorf("pending = %d, delivered = %d", len(fake.PendingEvents()), len(fake.DeliveredEvents()))
+	}
+}
+
+func TestHandleWebhook_RejectsFakeEventWithWrongSecret(t *testing.T) {
+	oldDB := database.DB
+	database.DB = nil
+	defer func() { database.DB = oldDB }()
+
+	handler := HandleWebhook(&Config{WebhookSecret: "whsec_app"})
+	payload := []byte(`{"id":"evt_1","object":"event","type":"customer.created","api_version":"` + stripe.APIVersion + `","data":{"object":{}}}`)
+
+	req, err := testutil.SignedWebhookRequest(context.Background(), "/api/webhooks/stripe", payload, testutil.FakeStripeWebhookSecret)
+	if err != nil {
+		t.Fatalf("SignedWebhookRequest() error = %v", err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	req, _ = testutil.SignedWebhookRequest(context.Background(), "/api/webhooks/stripe", payload, "whsec_app")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d for a correctly signed event", rr.Code, http.StatusOK)
+	}
+}
+
+// ============ Integration Tests ============
+
+func TestFakeStripe_BillingFlow_Integration(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	svc, fake := newFakeBackedService(t)
+	endpoint := httptest.NewServer(HandleWebhook(svc.config))
+	defer endpoint.Close()
+	fake.WebhookURL = endpoint.URL
+
+	ctx := context.Background()
+	user := &models.User{Email: "flow@example.com", Name: "Flow", Password: "hashed", Role: models.RoleUser, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	customerID, err := svc.GetOrCreateCustomer(ctx, user)
+	if err != nil {
+		t.Fatalf("GetOrCreateCustomer() error = %v", err)
+	}
+	session, err := svc.CreateCheckoutSession(ctx, customerID, "price_pro_monthly", "http://app/success", "http://app/cancel", CheckoutOptions{})
+	if err != nil {
+		t.Fatalf("CreateCheckoutSession() error = %v", err)
+	}
+	subID, err := fake.CompleteCheckout(session.ID)
+	if err != nil {
+		t.Fatalf("CompleteCheckout() error = %v", err)
+	}
+	if err := fake.DeliverWebhooks(ctx); err != nil {
+		t.Fatalf("DeliverWebhooks() error = %v", err)
+	}
+
+	var sub models.Subscription
+	if err := database.DB.Where("stripe_subscription_id = ?", subID).First(&sub).Error; err != nil {
+		t.Fatalf("subscription not synced: %v", err)
+	}
+	if sub.UserID != user.ID || sub.Status != models.SubscriptionStatusActive || sub.StripePriceID != "price_pro_monthly" {
+		t.Errorf("subscription = %+v", sub)
+	}
+	var invoices int64
+	database.DB.Model(&models.Invoice{}).Where("stripe_customer_id = ?", customerID).Count(&invoices)
+	if invoices != 1 {
+		t.Errorf("mirrored invoices = %d, want 1", invoices)
+	}
+
+	// A failed renewal puts the subscription past due
+	if err := fake.RenewSubscription(subID, false); err != nil {
+		t.Fatalf("RenewSubscription() error = %v", err)
+	}
+	if err := fake.DeliverWebhooks(ctx); err != nil {
+		t.Fatalf("DeliverWebhooks() error = %v", err)
+	}
+	database.DB.First(&sub, sub.ID)
+	if sub.Status != models.SubscriptionStatusPastDue {
+		t.Errorf("status after failed renewal = %q, want past_due", sub.Status)
+	}
+
+	if _, err := svc.CancelSubscription(ctx, subID, false); err != nil {
+		t.Fatalf("CancelSubscription() error = %v", err)
+	}
+	if err := fake.DeliverWebhooks(ctx); err != nil {
+		t.Fatalf("DeliverWebhooks() error = %v", err)
+	}
+	database.DB.First(&sub, sub.ID)
+	if sub.Status != models.SubscriptionStatusCanceled {
+		t.Errorf("status after cancel = %q, want canceled", sub.Status)
+	}
+}
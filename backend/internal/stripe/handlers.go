@@ -9,6 +9,7 @@ import (
 	"react-golang-starter/internal/auth"
 	"react-golang-starter/internal/database"
 	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
 )
 
 // GetBillingConfig returns the public Stripe configuration
@@ -35,7 +36,7 @@ func GetBillingConfig() http.HandlerFunc {
 
 // GetPlans returns available subscription plans
 // @Summary Get subscription plans
-// @Description Returns all available subscription plans with pricing
+// @Description Returns all available subscription plans with pricing and the limits, seats and features of each plan
 // @Tags billing
 // @Produce json
 // @Success 200 {array} models.BillingPlan
@@ -74,6 +75,7 @@ func GetPlans() http.HandlerFunc {
 		}
 
 		// Convert Stripe prices to BillingPlan format
+		catalog := services.GetEntitlementsService().Catalog()
 		var plans []models.BillingPlan
 		for _, p := range prices {
 			plan := models.BillingPlan{
@@ -92,6 +94,7 @@ func GetPlans() http.HandlerFunc {
 				plan.Description = p.Product.Description
 			}
 
+			applyPlanEntitlements(&plan, catalog)
 			plans = append(plans, plan)
 		}
 
@@ -102,6 +105,22 @@ func GetPlans() http.HandlerFunc {
 	}
 }
 
+// applyPlanEntitlements adds the limits, seats and features of the catalog plan a price subscribes to
+func applyPlanEntitlements(plan *models.BillingPlan, catalog *services.PlanCatalog) {
+	key, ok := catalog.PlanForPrice(plan.PriceID)
+	if !ok {
+		return
+	}
+
+	def := catalog.Plan(key)
+	limits := def.Limits
+	seats := def.Seats
+	plan.Plan = def.Key
+	plan.Limits = &limits
+	plan.SeatLimit = &seats
+	plan.Features = append([]string(nil), def.Features...)
+}
+
 // CreateCheckoutSession creates a new checkout session for subscription
 // @Summary Create checkout session
 // @Description Creates a Stripe checkout session for subscription purchase
@@ -175,6 +194,19 @@ func CreateCheckoutSession(config *Config) http.HandlerFunc {
 			return
 		}
 
+		if err := ValidatePlanPrice(req.PriceID); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			if err := json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Price is not part of any plan",
+				Code:    http.StatusBadRequest,
+			}); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+
 		// Get user from database
 		var user models.User
 		if err := database.DB.First(&user, userID).Error; err != nil {
@@ -206,8 +238,20 @@ func CreateCheckoutSession(config *Config) http.HandlerFunc {
 			return
 		}
 
-		// Create checkout session
-		session, err := svc.CreateCheckoutSession(r.Context(), customerID, req.PriceID, config.SuccessURL, config.CancelURL)
+		// Create checkout session, with the plan's free trial for first-time subscribers
+		opts := CheckoutOptions{
+			TrialDays: CheckoutTrialDays(r.Context(), config, req.PriceID, user.ID, nil),
+		}
+		if req.PromotionCode != "" {
+			discount, err := ResolveCheckoutDiscount(r.Context(), svc, req.PromotionCode, req.PriceID)
+			if err != nil {
+				writeDiscountError(w, err)
+				return
+			}
+			opts.Discount = discount
+		}
+
+		session, err := svc.CreateCheckoutSession(r.Context(), customerID, req.PriceID, config.SuccessURL, config.CancelURL, opts)
 		if err != nil {
 			log.Error().Err(err).Uint("user_id", user.ID).Msg("failed to create checkout session")
 			w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,199 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm/clause"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+)
+
+// InvoiceFilter selects the invoices of a single billing subject
+type InvoiceFilter struct {
+	UserID         uint
+	OrganizationID uint
+	Status         string
+}
+
+// handleInvoiceEvent mirrors an invoice from any invoice.* event into the local table
+func handleInvoiceEvent(ctx context.Context, invoice *stripe.Invoice) error {
+	if invoice.Customer == nil || invoice.Customer.ID == "" {
+		log.Warn().Str("invoice_id", invoice.ID).Msg("invoice has no customer, not mirroring")
+		return nil
+	}
+
+	owner, err := findCustomerOwner(ctx, invoice.Customer.ID)
+	if err != nil {
+		// Invoices for customers created outside this app are not shown to anyone
+		log.Debug().Str("invoice_id", invoice.ID).Str("customer_id", invoice.Customer.ID).Msg("no owner for invoice customer")
+		return nil
+	}
+
+	record := invoiceFromStripe(invoice)
+	if owner.Org != nil {
+		record.OrganizationID = &owner.Org.ID
+	} else {
+		record.UserID = &owner.User.ID
+	}
+
+	return database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "stripe_invoice_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"user_id", "organization_id", "stripe_subscription_id", "number", "status",
+				"currency", "subtotal", "total", "amount_due", "amount_paid", "amount_remaining",
+				"period_start", "period_end", "hosted_invoice_url", "invoice_pdf",
+				"due_date", "paid_at", "issued_at", "updated_at",
+			}),
+		}).
+		Create(record).Error
+}
+
+// handleInvoiceDeleted removes a deleted draft invoice from the mirror
+func handleInvoiceDeleted(ctx context.Context, invoice *stripe.Invoice) error {
+	return database.DB.WithContext(ctx).
+		Where("stripe_invoice_id = ?", invoice.ID).
+		Delete(&models.Invoice{}).Error
+}
+
+// invoiceFromStripe converts a Stripe invoice to its mirrored form
+func invoiceFromStripe(invoice *stripe.Invoice) *models.Invoice {
+	now := time.Now().Format(time.RFC3339)
+	record := &models.Invoice{
+		StripeInvoiceID:  invoice.ID,
+		StripeCustomerID: invoice.Customer.ID,
+		Number:           invoice.Number,
+		Status:           string(invoice.Status),
+		Currency:         string(invoice.Currency),
+		Subtotal:         invoice.Subtotal,
+		Total:            invoice.Total,
+		AmountDue:        invoice.AmountDue,
+		AmountPaid:       invoice.AmountPaid,
+		AmountRemaining:  invoice.AmountRemaining,
+		PeriodStart:      time.Unix(invoice.PeriodStart, 0).Format(time.RFC3339),
+		PeriodEnd:        time.Unix(invoice.PeriodEnd, 0).Format(time.RFC3339),
+		HostedInvoiceURL: invoice.HostedInvoiceURL,
+		InvoicePDF:       invoice.InvoicePDF,
+		IssuedAt:         time.Unix(invoice.Created, 0).Format(time.RFC3339),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if record.Status == "" {
+		record.Status = models.InvoiceStatusDraft
+	}
+
+	// Subscription invoices bill the period on their lines, not the invoice's own
+	// period (which covers the usage collected before it was created)
+	if invoice.Lines != nil && len(invoice.Lines.Data) > 0 && invoice.Lines.Data[0].Period != nil {
+		record.PeriodStart = time.Unix(invoice.Lines.Data[0].Period.Start, 0).Format(time.RFC3339)
+		record.PeriodEnd = time.Unix(invoice.Lines.Data[0].Period.End, 0).Format(time.RFC3339)
+	}
+
+	if invoice.Subscription != nil && invoice.Subscription.ID != "" {
+		record.StripeSubscriptionID = &invoice.Subscription.ID
+	}
+	if invoice.DueDate > 0 {
+		dueDate := time.Unix(invoice.DueDate, 0).Format(time.RFC3339)
+		record.DueDate = &dueDate
+	}
+	if invoice.StatusTransitions != nil && invoice.StatusTransitions.PaidAt > 0 {
+		paidAt := time.Unix(invoice.StatusTransitions.PaidAt, 0).Format(time.RFC3339)
+		record.PaidAt = &paidAt
+	}
+
+	return record
+}
+
+// ListInvoices returns a page of mirrored invoices for a user or organization, newest first
+func ListInvoices(ctx context.Context, filter InvoiceFilter, page, limit int) (*models.InvoicesResponse, error) {
+	if database.DB == nil {
+		return nil, errors.New("database not available")
+	}
+
+	query := database.DB.WithContext(ctx).Model(&models.Invoice{})
+	if filter.OrganizationID > 0 {
+		query = query.Where("organization_id = ?", filter.OrganizationID)
+	} else {
+		query = query.Where("user_id = ? AND organization_id IS NULL", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	} else {
+		// Drafts are not final and are not shown to customers
+		query = query.Where("status <> ?", models.InvoiceStatusDraft)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	invoices := []models.Invoice{}
+	if err := query.Order("issued_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.InvoicesResponse{
+		Invoices:   invoices,
+		Count:      len(invoices),
+		Total:      int(total),
+		Page:       page,
+		Limit:      limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+	}, nil
+}
+
+// ParseInvoiceListParams reads page, limit and status query parameters
+func ParseInvoiceListParams(r *http.Request) (page, limit int, status string) {
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit, r.URL.Query().Get("status")
+}
+
+// GetInvoices returns the current user's invoice history
+// @Summary List invoices
+// @Description Returns the current user's invoices from the local mirror, newest first
+// @Tags billing
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param status query string false "Filter by status (open, paid, void, uncollectible)"
+// @Success 200 {object} models.InvoicesResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/billing/invoices [get]
+func GetInvoices() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := auth.GetUserIDFromContext(r.Context())
+		if !ok {
+			writeErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+			return
+		}
+
+		page, limit, status := ParseInvoiceListParams(r)
+		response, err := ListInvoices(r.Context(), InvoiceFilter{UserID: userID, Status: status}, page, limit)
+		if err != nil {
+			log.Error().Err(err).Uint("user_id", userID).Msg("failed to list invoices")
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to fetch invoices")
+			return
+		}
+
+		writeJSONResponse(w, response)
+	}
+}
@@ -0,0 +1,156 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	stripe "github.com/stripe/stripe-go/v76"
+)
+
+func newTestInvoice(id, customerID string, status stripe.InvoiceStatus, created int64) *stripe.Invoice {
+	return &stripe.Invoice{
+		ID:               id,
+		Customer:         &stripe.Customer{ID: customerID},
+		Subscription:     &stripe.Subscription{ID: "sub_" + customerID},
+		Number:           "INV-" + id,
+		Status:           status,
+		Currency:         stripe.CurrencyUSD,
+		Subtotal:         1000,
+		Total:            1000,
+		AmountDue:        1000,
+		Created:          created,
+		HostedInvoiceURL: "https://invoice.stripe.com/i/" + id,
+		InvoicePDF:       "https://pay.stripe.com/invoice/" + id + "/pdf",
+	}
+}
+
+// ============ Unit Tests ============
+
+func TestInvoiceFromStripe(t *testing.T) {
+	invoice := newTestInvoice("in_1", "cus_1", stripe.InvoiceStatusPaid, 1700000000)
+	invoice.AmountPaid = 1000
+	invoice.StatusTransitions = &stripe.InvoiceStatusTransitions{PaidAt: 1700000100}
+	invoice.Lines = &stripe.InvoiceLineItemList{
+		Data: []*stripe.InvoiceLineItem{{Period: &stripe.Period{Start: 1700000000, End: 1702592000}}},
+	}
+
+	record := invoiceFromStripe(invoice)
+
+	if record.Status != models.InvoiceStatusPaid || record.AmountPaid != 1000 {
+		t.Errorf("record = %+v", record)
+	}
+	if record.PaidAt == nil {
+		t.Error("PaidAt should be set from status transitions")
+	}
+	if record.PeriodEnd != time.Unix(1702592000, 0).Format(time.RFC3339) {
+		t.Errorf("PeriodEnd = %q, want the line item period", record.PeriodEnd)
+	}
+	if record.StripeSubscriptionID == nil || *record.StripeSubscriptionID != "sub_cus_1" {
+		t.Errorf("StripeSubscriptionID = %v", record.StripeSubscriptionID)
+	}
+}
+
+func TestParseInvoiceListParams(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/billing/invoices?page=0&limit=500&status=paid", nil)
+	page, limit, status := ParseInvoiceListParams(req)
+
+	if page != 1 || limit != 20 || status != "paid" {
+		t.Errorf("ParseInvoiceListParams() = %d, %d, %q", page, limit, status)
+	}
+}
+
+func TestGetInvoices_Unauthorized(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/billing/invoices", nil)
+	rr := httptest.NewRecorder()
+	GetInvoices()(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+// ============ Integration Tests ============
+
+func TestInvoiceMirror_Integration(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := createTestUserForWebhook(t, "invoices@example.com", "cus_invoices")
+	now := time.Now().Unix()
+
+	// Draft created, then finalized and paid
+	draft := newTestInvoice("in_mirror_1", "cus_invoices", stripe.InvoiceStatusDraft, now-3600)
+	if err := dispatchEventObject(ctx, "invoice.created", draft); err != nil {
+		t.Fatalf("invoice.created error = %v", err)
+	}
+	paid := newTestInvoice("in_mirror_1", "cus_invoices", stripe.InvoiceStatusPaid, now-3600)
+	paid.AmountPaid = 1000
+	if err := dispatchEventObject(ctx, "invoice.paid", paid); err != nil {
+		t.Fatalf("invoice.paid error = %v", err)
+	}
+
+	// A second, newer open invoice and a draft that is later deleted
+	if err := dispatchEventObject(ctx, "invoice.finalized", newTestInvoice("in_mirror_2", "cus_invoices", stripe.InvoiceStatusOpen, now)); err != nil {
+		t.Fatalf("invoice.finalized error = %v", err)
+	}
+	if err := dispatchEventObject(ctx, "invoice.created", newTestInvoice("in_mirror_3", "cus_invoices", stripe.InvoiceStatusDraft, now)); err != nil {
+		t.Fatalf("invoice.created error = %v", err)
+	}
+	if err := dispatchEventObject(ctx, "invoice.deleted", &stripe.Invoice{ID: "in_mirror_3"}); err != nil {
+		t.Fatalf("invoice.deleted error = %v", err)
+	}
+
+	var count int64
+	database.DB.Model(&models.Invoice{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected 2 mirrored invoices, got %d", count)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/billing/invoices?limit=1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, user.ID))
+	rr := httptest.NewRecorder()
+	GetInvoices()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var resp models.InvoicesResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 2 || resp.TotalPages != 2 || len(resp.Invoices) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if resp.Invoices[0].StripeInvoiceID != "in_mirror_2" {
+		t.Errorf("first invoice = %q, want the newest (in_mirror_2)", resp.Invoices[0].StripeInvoiceID)
+	}
+	if resp.Invoices[0].InvoicePDF == "" || resp.Invoices[0].HostedInvoiceURL == "" {
+		t.Error("invoice links should be included")
+	}
+
+	filtered, err := ListInvoices(ctx, InvoiceFilter{UserID: user.ID, Status: models.InvoiceStatusPaid}, 1, 20)
+	if err != nil {
+		t.Fatalf("ListInvoices() error = %v", err)
+	}
+	if filtered.Total != 1 || filtered.Invoices[0].AmountPaid != 1000 {
+		t.Errorf("paid filter = %+v", filtered)
+	}
+}
+
+// dispatchEventObject marshals a Stripe object and routes it as a webhook event payload
+func dispatchEventObject(ctx context.Context, eventType string, object any) error {
+	payload, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	return dispatchEvent(ctx, eventType, payload)
+}
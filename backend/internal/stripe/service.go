@@ -8,10 +8,14 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/billing/meterevent"
 	"github.com/stripe/stripe-go/v76/billingportal/session"
 	checkoutsession "github.com/stripe/stripe-go/v76/checkout/session"
+	"github.com/stripe/stripe-go/v76/coupon"
 	"github.com/stripe/stripe-go/v76/customer"
+	"github.com/stripe/stripe-go/v76/invoice"
 	"github.com/stripe/stripe-go/v76/price"
+	"github.com/stripe/stripe-go/v76/promotioncode"
 	"github.com/stripe/stripe-go/v76/subscription"
 
 	"react-golang-starter/internal/database"
@@ -25,23 +29,46 @@ type Service interface {
 	GetOrCreateCustomer(ctx context.Context, user *models.User) (string, error)
 
 	// Checkout operations
-	CreateCheckoutSession(ctx context.Context, customerID, priceID, successURL, cancelURL string) (*stripe.CheckoutSession, error)
+	CreateCheckoutSession(ctx context.Context, customerID, priceID, successURL, cancelURL string, opts CheckoutOptions) (*stripe.CheckoutSession, error)
 
 	// Portal operations
 	CreatePortalSession(ctx context.Context, customerID, returnURL string) (*stripe.BillingPortalSession, error)
 
 	// Subscription operations
 	GetSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error)
+	CreateTrialSubscription(ctx context.Context, customerID, priceID string, trialDays int64) (*stripe.Subscription, error)
 	CancelSubscription(ctx context.Context, subscriptionID string, cancelAtPeriodEnd bool) (*stripe.Subscription, error)
+	UpdateSubscriptionQuantity(ctx context.Context, subscriptionID string, quantity int64) (*stripe.Subscription, error)
+	PreviewSubscriptionChange(ctx context.Context, subscriptionID, newPriceID string, prorationDate int64) (*stripe.Invoice, error)
+	ChangeSubscriptionPrice(ctx context.Context, subscriptionID, newPriceID string, prorationDate int64) (*stripe.Subscription, error)
+	ResumeSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error)
 
 	// Price/Plan operations
 	GetPrices(ctx context.Context) ([]*stripe.Price, error)
+	GetPrice(ctx context.Context, priceID string) (*stripe.Price, error)
+
+	// Discount operations
+	FindPromotionCode(ctx context.Context, code string) (*stripe.PromotionCode, error)
+	CreateCoupon(ctx context.Context, params *stripe.CouponParams) (*stripe.Coupon, error)
+	DeleteCoupon(ctx context.Context, couponID string) error
+
+	// Metered billing operations
+	ReportMeterEvent(ctx context.Context, eventName, customerID string, value, timestamp int64, identifier string) error
 
 	// Configuration
 	GetPublishableKey() string
 	IsAvailable() bool
 }
 
+// CheckoutOptions are the optional settings for a checkout session
+type CheckoutOptions struct {
+	// Free trial length; 0 starts billing immediately
+	TrialDays int64
+
+	// Discount to apply, resolved from a promotion or coupon code
+	Discount *Discount
+}
+
 // stripeService implements the Service interface
 type stripeService struct {
 	config *Config
@@ -71,6 +98,12 @@ func Initialize(config *Config) error {
 
 		// Set the Stripe API key globally
 		stripe.Key = config.SecretKey
+		if config.APIBaseURL != "" {
+			stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+				URL: stripe.String(config.APIBaseURL),
+			}))
+			log.Warn().Str("url", config.APIBaseURL).Msg("stripe API base URL overridden")
+		}
 
 		instance = &stripeService{
 			config: config,
@@ -137,8 +170,10 @@ func (s *stripeService) GetOrCreateCustomer(ctx context.Context, user *models.Us
 	return customerID, nil
 }
 
-// CreateCheckoutSession creates a new Stripe checkout session
-func (s *stripeService) CreateCheckoutSession(ctx context.Context, customerID, priceID, successURL, cancelURL string) (*stripe.CheckoutSession, error) {
+// CreateCheckoutSession creates a new Stripe checkout session.
+// With a trial the subscription starts free and no card is required. A resolved
+// discount is applied to the session.
+func (s *stripeService) CreateCheckoutSession(ctx context.Context, customerID, priceID, successURL, cancelURL string, opts CheckoutOptions) (*stripe.CheckoutSession, error) {
 	params := &stripe.CheckoutSessionParams{
 		Customer: stripe.String(customerID),
 		Mode:     stripe.String(string(stripe.CheckoutSessionModeSubscription)),
@@ -152,6 +187,23 @@ func (s *stripeService) CreateCheckoutSession(ctx context.Context, customerID, p
 		CancelURL:  stripe.String(cancelURL),
 	}
 
+	if opts.TrialDays > 0 {
+		params.PaymentMethodCollection = stripe.String(string(stripe.CheckoutSessionPaymentMethodCollectionIfRequired))
+		params.SubscriptionData = &stripe.CheckoutSessionSubscriptionDataParams{
+			TrialPeriodDays: stripe.Int64(opts.TrialDays),
+			TrialSettings: &stripe.CheckoutSessionSubscriptionDataTrialSettingsParams{
+				EndBehavior: &stripe.CheckoutSessionSubscriptionDataTrialSettingsEndBehaviorParams{
+					// Without a card the subscription is canceled, which downgrades to free
+					MissingPaymentMethod: stripe.String(string(stripe.SubscriptionTrialSettingsEndBehaviorMissingPaymentMethodCancel)),
+				},
+			},
+		}
+	}
+
+	if opts.Discount != nil {
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{opts.Discount.sessionParams()}
+	}
+
 	return checkoutsession.New(params)
 }
 
@@ -170,6 +222,28 @@ func (s *stripeService) GetSubscription(ctx context.Context, subscriptionID stri
 	return subscription.Get(subscriptionID, nil)
 }
 
+// CreateTrialSubscription starts a trialing subscription without collecting a payment method.
+// If no card is added before the trial ends, Stripe cancels the subscription.
+func (s *stripeService) CreateTrialSubscription(ctx context.Context, customerID, priceID string, trialDays int64) (*stripe.Subscription, error) {
+	params := &stripe.SubscriptionParams{
+		Customer: stripe.String(customerID),
+		Items: []*stripe.SubscriptionItemsParams{
+			{Price: stripe.String(priceID)},
+		},
+		TrialPeriodDays: stripe.Int64(trialDays),
+		TrialSettings: &stripe.SubscriptionTrialSettingsParams{
+			EndBehavior: &stripe.SubscriptionTrialSettingsEndBehaviorParams{
+				MissingPaymentMethod: stripe.String(string(stripe.SubscriptionTrialSettingsEndBehaviorMissingPaymentMethodCancel)),
+			},
+		},
+		PaymentSettings: &stripe.SubscriptionPaymentSettingsParams{
+			SaveDefaultPaymentMethod: stripe.String(string(stripe.SubscriptionPaymentSettingsSaveDefaultPaymentMethodOnSubscription)),
+		},
+	}
+
+	return subscription.New(params)
+}
+
 // CancelSubscription cancels a subscription
 func (s *stripeService) CancelSubscription(ctx context.Context, subscriptionID string, cancelAtPeriodEnd bool) (*stripe.Subscription, error) {
 	if cancelAtPeriodEnd {
@@ -182,6 +256,112 @@ func (s *stripeService) CancelSubscription(ctx context.Context, subscriptionID s
 	return subscription.Cancel(subscriptionID, nil)
 }
 
+// SeatSyncMetadataKey is the subscription metadata key holding the last synced seat quantity
+const SeatSyncMetadataKey = "seat_sync_quantity"
+
+// UpdateSubscriptionQuantity sets the seat quantity on a single-item subscription and records
+// it in the subscription's metadata, so webhooks can tell synced quantities from ones set in
+// the billing portal. Changes are prorated on the next invoice.
+func (s *stripeService) UpdateSubscriptionQuantity(ctx context.Context, subscriptionID string, quantity int64) (*stripe.Subscription, error) {
+	sub, err := subscription.Get(subscriptionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Items == nil || len(sub.Items.Data) == 0 {
+		return nil, ErrNoSubscriptionItems
+	}
+
+	params := &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{
+			{
+				ID:       stripe.String(sub.Items.Data[0].ID),
+				Quantity: stripe.Int64(quantity),
+			},
+		},
+		ProrationBehavior: stripe.String("create_prorations"),
+	}
+	params.AddMetadata(SeatSyncMetadataKey, strconv.FormatInt(quantity, 10))
+	return subscription.Update(subscriptionID, params)
+}
+
+// PreviewSubscriptionChange returns the upcoming invoice as it would look if the
+// subscription switched to newPriceID at prorationDate
+func (s *stripeService) PreviewSubscriptionChange(ctx context.Context, subscriptionID, newPriceID string, prorationDate int64) (*stripe.Invoice, error) {
+	sub, err := subscription.Get(subscriptionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Items == nil || len(sub.Items.Data) == 0 {
+		return nil, ErrNoSubscriptionItems
+	}
+
+	params := &stripe.InvoiceUpcomingParams{
+		Customer:     stripe.String(sub.Customer.ID),
+		Subscription: stripe.String(subscriptionID),
+		SubscriptionItems: []*stripe.SubscriptionItemsParams{
+			{
+				ID:    stripe.String(sub.Items.Data[0].ID),
+				Price: stripe.String(newPriceID),
+			},
+		},
+		SubscriptionProrationBehavior: stripe.String("create_prorations"),
+		SubscriptionProrationDate:     stripe.Int64(prorationDate),
+	}
+	return invoice.Upcoming(params)
+}
+
+// ChangeSubscriptionPrice switches a single-item subscription to a new price.
+// Passing the proration date from a preview makes the charge match what was shown.
+func (s *stripeService) ChangeSubscriptionPrice(ctx context.Context, subscriptionID, newPriceID string, prorationDate int64) (*stripe.Subscription, error) {
+	sub, err := subscription.Get(subscriptionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Items == nil || len(sub.Items.Data) == 0 {
+		return nil, ErrNoSubscriptionItems
+	}
+
+	params := &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{
+			{
+				ID:    stripe.String(sub.Items.Data[0].ID),
+				Price: stripe.String(newPriceID),
+			},
+		},
+		ProrationBehavior: stripe.String("create_prorations"),
+	}
+	if prorationDate > 0 {
+		params.ProrationDate = stripe.Int64(prorationDate)
+	}
+	return subscription.Update(subscriptionID, params)
+}
+
+// ResumeSubscription removes a scheduled cancellation so the subscription renews
+func (s *stripeService) ResumeSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error) {
+	params := &stripe.SubscriptionParams{
+		CancelAtPeriodEnd: stripe.Bool(false),
+	}
+	return subscription.Update(subscriptionID, params)
+}
+
+// ReportMeterEvent sends a billing meter event for a customer.
+// The identifier doubles as the idempotency key so retries never double-bill.
+func (s *stripeService) ReportMeterEvent(ctx context.Context, eventName, customerID string, value, timestamp int64, identifier string) error {
+	params := &stripe.BillingMeterEventParams{
+		EventName:  stripe.String(eventName),
+		Identifier: stripe.String(identifier),
+		Payload: map[string]string{
+			"stripe_customer_id": customerID,
+			"value":              strconv.FormatInt(value, 10),
+		},
+		Timestamp: stripe.Int64(timestamp),
+	}
+	params.SetIdempotencyKey(identifier)
+
+	_, err := meterevent.New(params)
+	return err
+}
+
 // GetPrices retrieves all active prices
 func (s *stripeService) GetPrices(ctx context.Context) ([]*stripe.Price, error) {
 	params := &stripe.PriceListParams{
@@ -202,6 +382,43 @@ func (s *stripeService) GetPrices(ctx context.Context) ([]*stripe.Price, error)
 	return prices, nil
 }
 
+// GetPrice retrieves a single price with its product
+func (s *stripeService) GetPrice(ctx context.Context, priceID string) (*stripe.Price, error) {
+	params := &stripe.PriceParams{}
+	params.AddExpand("product")
+	return price.Get(priceID, params)
+}
+
+// FindPromotionCode returns the active promotion code matching code.
+// Stripe matches codes case-insensitively.
+func (s *stripeService) FindPromotionCode(ctx context.Context, code string) (*stripe.PromotionCode, error) {
+	params := &stripe.PromotionCodeListParams{
+		Active: stripe.Bool(true),
+		Code:   stripe.String(code),
+	}
+	params.Limit = stripe.Int64(1)
+
+	iter := promotioncode.List(params)
+	if iter.Next() {
+		return iter.PromotionCode(), nil
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrInvalidPromotionCode
+}
+
+// CreateCoupon creates a Stripe coupon
+func (s *stripeService) CreateCoupon(ctx context.Context, params *stripe.CouponParams) (*stripe.Coupon, error) {
+	return coupon.New(params)
+}
+
+// DeleteCoupon deletes a Stripe coupon. Subscriptions already using it keep their discount.
+func (s *stripeService) DeleteCoupon(ctx context.Context, couponID string) error {
+	_, err := coupon.Del(couponID, nil)
+	return err
+}
+
 // GetPublishableKey returns the Stripe publishable key
 func (s *stripeService) GetPublishableKey() string {
 	return s.config.PublishableKey
@@ -223,7 +440,7 @@ func (n *noOpService) GetOrCreateCustomer(ctx context.Context, user *models.User
 	return "", ErrDisabled
 }
 
-func (n *noOpService) CreateCheckoutSession(ctx context.Context, customerID, priceID, successURL, cancelURL string) (*stripe.CheckoutSession, error) {
+func (n *noOpService) CreateCheckoutSession(ctx context.Context, customerID, priceID, successURL, cancelURL string, opts CheckoutOptions) (*stripe.CheckoutSession, error) {
 	return nil, ErrDisabled
 }
 
@@ -235,14 +452,54 @@ func (n *noOpService) GetSubscription(ctx context.Context, subscriptionID string
 	return nil, ErrDisabled
 }
 
+func (n *noOpService) CreateTrialSubscription(ctx context.Context, customerID, priceID string, trialDays int64) (*stripe.Subscription, error) {
+	return nil, ErrDisabled
+}
+
 func (n *noOpService) CancelSubscription(ctx context.Context, subscriptionID string, cancelAtPeriodEnd bool) (*stripe.Subscription, error) {
 	return nil, ErrDisabled
 }
 
+func (n *noOpService) UpdateSubscriptionQuantity(ctx context.Context, subscriptionID string, quantity int64) (*stripe.Subscription, error) {
+	return nil, ErrDisabled
+}
+
+func (n *noOpService) PreviewSubscriptionChange(ctx context.Context, subscriptionID, newPriceID string, prorationDate int64) (*stripe.Invoice, error) {
+	return nil, ErrDisabled
+}
+
+func (n *noOpService) ChangeSubscriptionPrice(ctx context.Context, subscriptionID, newPriceID string, prorationDate int64) (*stripe.Subscription, error) {
+	return nil, ErrDisabled
+}
+
+func (n *noOpService) ResumeSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error) {
+	return nil, ErrDisabled
+}
+
+func (n *noOpService) ReportMeterEvent(ctx context.Context, eventName, customerID string, value, timestamp int64, identifier string) error {
+	return ErrDisabled
+}
+
 func (n *noOpService) GetPrices(ctx context.Context) ([]*stripe.Price, error) {
 	return nil, ErrDisabled
 }
 
+func (n *noOpService) GetPrice(ctx context.Context, priceID string) (*stripe.Price, error) {
+	return nil, ErrDisabled
+}
+
+func (n *noOpService) FindPromotionCode(ctx context.Context, code string) (*stripe.PromotionCode, error) {
+	return nil, ErrDisabled
+}
+
+func (n *noOpService) CreateCoupon(ctx context.Context, params *stripe.CouponParams) (*stripe.Coupon, error) {
+	return nil, ErrDisabled
+}
+
+func (n *noOpService) DeleteCoupon(ctx context.Context, couponID string) error {
+	return ErrDisabled
+}
+
 func (n *noOpService) GetPublishableKey() string {
 	return ""
 }
@@ -5,6 +5,10 @@ import (
 	"os"
 	"sync"
 	"testing"
+	"time"
+
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
 )
 
 // ============ Config Tests ============
@@ -146,6 +150,50 @@ func TestLoadConfig_WebhookSecret(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_Dunning(t *testing.T) {
+	t.Setenv("STRIPE_DUNNING_GRACE_DAYS", "10")
+	t.Setenv("STRIPE_DUNNING_REMINDER_DAYS", "2, 5,bad,0,8")
+
+	config := LoadConfig()
+
+	if config.DunningGracePeriod != 10*24*time.Hour {
+		t.Errorf("LoadConfig().DunningGracePeriod = %v, want 240h", config.DunningGracePeriod)
+	}
+	want := []time.Duration{2 * 24 * time.Hour, 5 * 24 * time.Hour, 8 * 24 * time.Hour}
+	if len(config.DunningReminders) != len(want) {
+		t.Fatalf("LoadConfig().DunningReminders = %v, want %v", config.DunningReminders, want)
+	}
+	for i := range want {
+		if config.DunningReminders[i] != want[i] {
+			t.Errorf("DunningReminders[%d] = %v, want %v", i, config.DunningReminders[i], want[i])
+		}
+	}
+}
+
+func TestConfig_TrialDays(t *testing.T) {
+	t.Setenv("STRIPE_PREMIUM_PRICE_ID", "price_premium")
+	t.Setenv("STRIPE_ENTERPRISE_PRICE_ID", "price_enterprise")
+	t.Setenv("STRIPE_PREMIUM_TRIAL_DAYS", "14")
+	t.Setenv("STRIPE_ENTERPRISE_TRIAL_DAYS", "-3")
+
+	config := LoadConfig()
+
+	tests := []struct {
+		priceID string
+		want    int64
+	}{
+		{"price_premium", 14},
+		{"price_enterprise", 0},
+		{"price_other", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := config.TrialDays(tt.priceID); got != tt.want {
+			t.Errorf("TrialDays(%q) = %d, want %d", tt.priceID, got, tt.want)
+		}
+	}
+}
+
 // ============ Config Validation Tests ============
 
 func TestConfig_Validate_Disabled(t *testing.T) {
@@ -306,12 +354,21 @@ func TestNoOpService_GetOrCreateCustomer(t *testing.T) {
 func TestNoOpService_CreateCheckoutSession(t *testing.T) {
 	svc := &noOpService{}
 
-	_, err := svc.CreateCheckoutSession(context.Background(), "", "", "", "")
+	_, err := svc.CreateCheckoutSession(context.Background(), "", "", "", "", CheckoutOptions{})
 	if err != ErrDisabled {
 		t.Errorf("noOpService.CreateCheckoutSession() error = %v, want %v", err, ErrDisabled)
 	}
 }
 
+func TestNoOpService_CreateTrialSubscription(t *testing.T) {
+	svc := &noOpService{}
+
+	_, err := svc.CreateTrialSubscription(context.Background(), "cus_123", "price_123", 14)
+	if err != ErrDisabled {
+		t.Errorf("noOpService.CreateTrialSubscription() error = %v, want %v", err, ErrDisabled)
+	}
+}
+
 func TestNoOpService_CreatePortalSession(t *testing.T) {
 	svc := &noOpService{}
 
@@ -339,6 +396,51 @@ func TestNoOpService_CancelSubscription(t *testing.T) {
 	}
 }
 
+func TestNoOpService_UpdateSubscriptionQuantity(t *testing.T) {
+	svc := &noOpService{}
+
+	_, err := svc.UpdateSubscriptionQuantity(context.Background(), "sub_123", 3)
+	if err != ErrDisabled {
+		t.Errorf("noOpService.UpdateSubscriptionQuantity() error = %v, want %v", err, ErrDisabled)
+	}
+}
+
+func TestNoOpService_PreviewSubscriptionChange(t *testing.T) {
+	svc := &noOpService{}
+
+	_, err := svc.PreviewSubscriptionChange(context.Background(), "sub_123", "price_456", 1700000000)
+	if err != ErrDisabled {
+		t.Errorf("noOpService.PreviewSubscriptionChange() error = %v, want %v", err, ErrDisabled)
+	}
+}
+
+func TestNoOpService_ChangeSubscriptionPrice(t *testing.T) {
+	svc := &noOpService{}
+
+	_, err := svc.ChangeSubscriptionPrice(context.Background(), "sub_123", "price_456", 0)
+	if err != ErrDisabled {
+		t.Errorf("noOpService.ChangeSubscriptionPrice() error = %v, want %v", err, ErrDisabled)
+	}
+}
+
+func TestNoOpService_ResumeSubscription(t *testing.T) {
+	svc := &noOpService{}
+
+	_, err := svc.ResumeSubscription(context.Background(), "sub_123")
+	if err != ErrDisabled {
+		t.Errorf("noOpService.ResumeSubscription() error = %v, want %v", err, ErrDisabled)
+	}
+}
+
+func TestNoOpService_ReportMeterEvent(t *testing.T) {
+	svc := &noOpService{}
+
+	err := svc.ReportMeterEvent(context.Background(), "api_calls", "cus_123", 10, 1700000000, "usage_user_1_api_calls_2024-01-01_10")
+	if err != ErrDisabled {
+		t.Errorf("noOpService.ReportMeterEvent() error = %v, want %v", err, ErrDisabled)
+	}
+}
+
 func TestNoOpService_GetPrices(t *testing.T) {
 	svc := &noOpService{}
 
@@ -494,3 +596,37 @@ func TestStripeService_IsAvailable(t *testing.T) {
 		})
 	}
 }
+
+// ============ Plan Entitlements Tests ============
+
+func TestApplyPlanEntitlements(t *testing.T) {
+	catalog := services.DefaultPlanCatalog()
+
+	plan := models.BillingPlan{ID: "price_pro_monthly", PriceID: "price_pro_monthly"}
+	applyPlanEntitlements(&plan, catalog)
+
+	if plan.Plan != "pro" {
+		t.Errorf("Plan = %q, want pro", plan.Plan)
+	}
+	if plan.Limits == nil || *plan.Limits != services.TierLimits["price_pro_monthly"] {
+		t.Errorf("Limits = %+v, want pro limits", plan.Limits)
+	}
+	if plan.SeatLimit == nil || *plan.SeatLimit != 25 {
+		t.Errorf("SeatLimit = %v, want 25", plan.SeatLimit)
+	}
+	if len(plan.Features) == 0 {
+		t.Error("Features should list the plan's entitlement features")
+	}
+
+	unknown := models.BillingPlan{ID: "price_other", PriceID: "price_other"}
+	applyPlanEntitlements(&unknown, catalog)
+	if unknown.Plan != "" || unknown.Limits != nil || unknown.SeatLimit != nil {
+		t.Errorf("price outside the catalog should be left unchanged, got %+v", unknown)
+	}
+}
+
+func TestGetPlanFromPriceID_UsesPlanCatalog(t *testing.T) {
+	if got := getPlanFromPriceID("price_enterprise_yearly"); got != models.OrgPlanEnterprise {
+		t.Errorf("getPlanFromPriceID(price_enterprise_yearly) = %s, want %s", got, models.OrgPlanEnterprise)
+	}
+}
@@ -0,0 +1,389 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+
+	"react-golang-starter/internal/auth"
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
+)
+
+// findUserSubscription returns the user's personal (non-organization) subscription
+func findUserSubscription(ctx context.Context, userID uint) (*models.Subscription, error) {
+	var sub models.Subscription
+	err := database.DB.WithContext(ctx).
+		Where("user_id = ? AND organization_id IS NULL AND status <> ?", userID, models.SubscriptionStatusCanceled).
+		Order("created_at DESC").
+		First(&sub).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoActiveSubscription
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// PreviewPlanChange returns the prorated invoice the subscription would receive when
+// switching to priceID. A zero prorationDate means now.
+func PreviewPlanChange(ctx context.Context, svc Service, sub *models.Subscription, priceID string, prorationDate int64) (*models.SubscriptionChangePreview, error) {
+	if err := validatePlanChange(sub, priceID); err != nil {
+		return nil, err
+	}
+	if prorationDate <= 0 {
+		prorationDate = time.Now().Unix()
+	}
+
+	invoice, err := svc.PreviewSubscriptionChange(ctx, sub.StripeSubscriptionID, priceID, prorationDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildChangePreview(sub.StripePriceID, priceID, prorationDate, invoice), nil
+}
+
+// ChangePlan switches the subscription to priceID and applies the result locally
+// without waiting for the customer.subscription.updated webhook
+func ChangePlan(ctx context.Context, svc Service, sub *models.Subscription, priceID string, prorationDate int64) (*models.Subscription, error) {
+	if err := validatePlanChange(sub, priceID); err != nil {
+		return nil, err
+	}
+
+	updated, err := svc.ChangeSubscriptionPrice(ctx, sub.StripeSubscriptionID, priceID, prorationDate)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("subscription_id", sub.StripeSubscriptionID).
+		Str("from_price", sub.StripePriceID).
+		Str("to_price", priceID).
+		Msg("subscription plan changed")
+
+	return applySubscriptionChange(ctx, sub, updated, handleSubscriptionUpdated)
+}
+
+// CancelPlan cancels the subscription at the end of the current period, or immediately
+func CancelPlan(ctx context.Context, svc Service, sub *models.Subscription, immediately bool) (*models.Subscription, error) {
+	if sub.Status == models.SubscriptionStatusCanceled {
+		return nil, ErrNoActiveSubscription
+	}
+
+	updated, err := svc.CancelSubscription(ctx, sub.StripeSubscriptionID, !immediately)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("subscription_id", sub.StripeSubscriptionID).
+		Bool("immediately", immediately).
+		Msg("subscription cancellation requested")
+
+	if immediately {
+		return applySubscriptionChange(ctx, sub, updated, handleSubscriptionDeleted)
+	}
+	return applySubscriptionChange(ctx, sub, updated, handleSubscriptionUpdated)
+}
+
+// ResumePlan removes a scheduled cancellation from the subscription
+func ResumePlan(ctx context.Context, svc Service, sub *models.Subscription) (*models.Subscription, error) {
+	if sub.Status == models.SubscriptionStatusCanceled {
+		return nil, ErrNoActiveSubscription
+	}
+	if !sub.CancelAtPeriodEnd {
+		return nil, ErrNotScheduledToCancel
+	}
+
+	updated, err := svc.ResumeSubscription(ctx, sub.StripeSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("subscription_id", sub.StripeSubscriptionID).Msg("subscription resumed")
+
+	return applySubscriptionChange(ctx, sub, updated, handleSubscriptionUpdated)
+}
+
+func validatePlanChange(sub *models.Subscription, priceID string) error {
+	if sub.Status == models.SubscriptionStatusCanceled {
+		return ErrNoActiveSubscription
+	}
+	if sub.StripePriceID == priceID {
+		return ErrSamePrice
+	}
+	return ValidatePlanPrice(priceID)
+}
+
+// ValidatePlanPrice rejects prices that do not subscribe to a plan in the catalog, so
+// clients cannot subscribe to arbitrary Stripe prices on the account
+func ValidatePlanPrice(priceID string) error {
+	if _, ok := services.GetEntitlementsService().Catalog().PlanForPrice(priceID); !ok {
+		return ErrUnknownPrice
+	}
+	return nil
+}
+
+// applySubscriptionChange runs the webhook handler for the returned Stripe subscription so
+// local state, role, limits and broadcasts update immediately. The webhook that follows is
+// applied again idempotently.
+func applySubscriptionChange(ctx context.Context, sub *models.Subscription, updated *stripe.Subscription, apply func(context.Context, *stripe.Subscription) error) (*models.Subscription, error) {
+	if err := apply(ctx, updated); err != nil {
+		return nil, err
+	}
+
+	var refreshed models.Subscription
+	if err := database.DB.WithContext(ctx).First(&refreshed, sub.ID).Error; err != nil {
+		return nil, err
+	}
+	return &refreshed, nil
+}
+
+// buildChangePreview converts Stripe's upcoming invoice into the API preview
+func buildChangePreview(currentPriceID, newPriceID string, prorationDate int64, invoice *stripe.Invoice) *models.SubscriptionChangePreview {
+	preview := &models.SubscriptionChangePreview{
+		CurrentPriceID: currentPriceID,
+		NewPriceID:     newPriceID,
+		Currency:       string(invoice.Currency),
+		ProrationDate:  prorationDate,
+		Subtotal:       invoice.Subtotal,
+		Total:          invoice.Total,
+		AmountDue:      invoice.AmountDue,
+		Lines:          []models.InvoiceLinePreview{},
+	}
+	if invoice.NextPaymentAttempt > 0 {
+		preview.NextPaymentAt = time.Unix(invoice.NextPaymentAttempt, 0).Format(time.RFC3339)
+	}
+
+	if invoice.Lines == nil {
+		return preview
+	}
+	for _, line := range invoice.Lines.Data {
+		item := models.InvoiceLinePreview{
+			Description: line.Description,
+			Amount:      line.Amount,
+			Proration:   line.Proration,
+		}
+		if line.Period != nil {
+			item.PeriodStart = time.Unix(line.Period.Start, 0).Format(time.RFC3339)
+			item.PeriodEnd = time.Unix(line.Period.End, 0).Format(time.RFC3339)
+		}
+		if line.Proration {
+			preview.ProrationAmount += line.Amount
+		}
+		preview.Lines = append(preview.Lines, item)
+	}
+
+	return preview
+}
+
+// SubscriptionErrorStatus maps plan management errors to an HTTP status and message
+func SubscriptionErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrNoActiveSubscription):
+		return http.StatusNotFound, "No active subscription found"
+	case errors.Is(err, ErrSamePrice):
+		return http.StatusBadRequest, "Subscription is already on this plan"
+	case errors.Is(err, ErrUnknownPrice):
+		return http.StatusBadRequest, "Price is not part of any plan"
+	case errors.Is(err, ErrNotScheduledToCancel):
+		return http.StatusConflict, "Subscription is not scheduled for cancellation"
+	case errors.Is(err, ErrDisabled):
+		return http.StatusServiceUnavailable, "Billing is not configured"
+	default:
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.HTTPStatusCode == http.StatusBadRequest {
+			return http.StatusBadRequest, stripeErr.Msg
+		}
+		return http.StatusInternalServerError, "Failed to update subscription"
+	}
+}
+
+// PreviewSubscriptionChange returns the prorated invoice for switching plans
+// @Summary Preview a subscription plan change
+// @Description Returns the prorated upcoming invoice if the current user's subscription switched to the given price
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param request body models.ChangeSubscriptionRequest true "Target price"
+// @Success 200 {object} models.SubscriptionChangePreview
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/billing/subscription/preview-change [post]
+func PreviewSubscriptionChange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, sub, ok := loadUserSubscriptionForUpdate(w, r)
+		if !ok {
+			return
+		}
+
+		var req models.ChangeSubscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PriceID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "Price ID is required")
+			return
+		}
+
+		preview, err := PreviewPlanChange(r.Context(), svc, sub, req.PriceID, req.ProrationDate)
+		if err != nil {
+			writeSubscriptionError(w, sub, err)
+			return
+		}
+
+		writeJSONResponse(w, preview)
+	}
+}
+
+// ChangeSubscription switches the current user's subscription to another price
+// @Summary Change subscription plan
+// @Description Switches the current user's subscription to the given price with proration
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param request body models.ChangeSubscriptionRequest true "Target price and proration date from the preview"
+// @Success 200 {object} models.SubscriptionResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/billing/subscription/change [post]
+func ChangeSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, sub, ok := loadUserSubscriptionForUpdate(w, r)
+		if !ok {
+			return
+		}
+
+		var req models.ChangeSubscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PriceID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "Price ID is required")
+			return
+		}
+
+		updated, err := ChangePlan(r.Context(), svc, sub, req.PriceID, req.ProrationDate)
+		if err != nil {
+			writeSubscriptionError(w, sub, err)
+			return
+		}
+
+		writeJSONResponse(w, updated.ToSubscriptionResponse())
+	}
+}
+
+// CancelSubscription cancels the current user's subscription
+// @Summary Cancel subscription
+// @Description Cancels the current user's subscription at the end of the billing period, or immediately
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param request body models.CancelSubscriptionRequest false "Cancellation options"
+// @Success 200 {object} models.SubscriptionResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/billing/subscription/cancel [post]
+func CancelSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, sub, ok := loadUserSubscriptionForUpdate(w, r)
+		if !ok {
+			return
+		}
+
+		// The body is optional; an empty body cancels at period end
+		var req models.CancelSubscriptionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+
+		updated, err := CancelPlan(r.Context(), svc, sub, req.Immediately)
+		if err != nil {
+			writeSubscriptionError(w, sub, err)
+			return
+		}
+
+		writeJSONResponse(w, updated.ToSubscriptionResponse())
+	}
+}
+
+// ResumeSubscription removes a scheduled cancellation from the current user's subscription
+// @Summary Resume subscription
+// @Description Keeps a subscription that was set to cancel at the end of the billing period
+// @Tags billing
+// @Produce json
+// @Success 200 {object} models.SubscriptionResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/billing/subscription/resume [post]
+func ResumeSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, sub, ok := loadUserSubscriptionForUpdate(w, r)
+		if !ok {
+			return
+		}
+
+		updated, err := ResumePlan(r.Context(), svc, sub)
+		if err != nil {
+			writeSubscriptionError(w, sub, err)
+			return
+		}
+
+		writeJSONResponse(w, updated.ToSubscriptionResponse())
+	}
+}
+
+// loadUserSubscriptionForUpdate resolves the billing service and the authenticated user's
+// subscription, writing an error response if either is unavailable
+func loadUserSubscriptionForUpdate(w http.ResponseWriter, r *http.Request) (Service, *models.Subscription, bool) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		writeErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return nil, nil, false
+	}
+
+	if !IsAvailable() {
+		writeErrorResponse(w, http.StatusServiceUnavailable, "Billing is not configured")
+		return nil, nil, false
+	}
+
+	sub, err := findUserSubscription(r.Context(), userID)
+	if err != nil {
+		status, message := SubscriptionErrorStatus(err)
+		writeErrorResponse(w, status, message)
+		return nil, nil, false
+	}
+
+	return GetService(), sub, true
+}
+
+func writeSubscriptionError(w http.ResponseWriter, sub *models.Subscription, err error) {
+	status, message := SubscriptionErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Error().Err(err).Str("subscription_id", sub.StripeSubscriptionID).Msg("failed to update subscription")
+	}
+	writeErrorResponse(w, status, message)
+}
+
+func writeJSONResponse(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,243 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	stripe "github.com/stripe/stripe-go/v76"
+)
+
+// fakeSubscriptionService returns canned Stripe objects for plan management calls
+type fakeSubscriptionService struct {
+	noOpService
+	canceledImmediately bool
+}
+
+func (f *fakeSubscriptionService) IsAvailable() bool {
+	return true
+}
+
+func (f *fakeSubscriptionService) ChangeSubscriptionPrice(ctx context.Context, subscriptionID, newPriceID string, prorationDate int64) (*stripe.Subscription, error) {
+	return fakeStripeSubscription(subscriptionID, newPriceID, stripe.SubscriptionStatusActive, false), nil
+}
+
+func (f *fakeSubscriptionService) CancelSubscription(ctx context.Context, subscriptionID string, cancelAtPeriodEnd bool) (*stripe.Subscription, error) {
+	if !cancelAtPeriodEnd {
+		f.canceledImmediately = true
+		return fakeStripeSubscription(subscriptionID, "price_test_123", stripe.SubscriptionStatusCanceled, false), nil
+	}
+	return fakeStripeSubscription(subscriptionID, "price_test_123", stripe.SubscriptionStatusActive, true), nil
+}
+
+func (f *fakeSubscriptionService) ResumeSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error) {
+	return fakeStripeSubscription(subscriptionID, "price_test_123", stripe.SubscriptionStatusActive, false), nil
+}
+
+func fakeStripeSubscription(id, priceID string, status stripe.SubscriptionStatus, cancelAtPeriodEnd bool) *stripe.Subscription {
+	now := time.Now()
+	return &stripe.Subscription{
+		ID:                 id,
+		Status:             status,
+		CancelAtPeriodEnd:  cancelAtPeriodEnd,
+		CurrentPeriodStart: now.Unix(),
+		CurrentPeriodEnd:   now.Add(30 * 24 * time.Hour).Unix(),
+		Items: &stripe.SubscriptionItemList{
+			Data: []*stripe.SubscriptionItem{{Price: &stripe.Price{ID: priceID}}},
+		},
+	}
+}
+
+// ============ Unit Tests ============
+
+func TestValidatePlanChange(t *testing.T) {
+	active := &models.Subscription{StripePriceID: "price_basic", Status: models.SubscriptionStatusActive}
+	canceled := &models.Subscription{StripePriceID: "price_basic", Status: models.SubscriptionStatusCanceled}
+
+	if err := validatePlanChange(active, "price_pro_monthly"); err != nil {
+		t.Errorf("validatePlanChange() error = %v, want nil", err)
+	}
+	if err := validatePlanChange(active, "price_basic"); !errors.Is(err, ErrSamePrice) {
+		t.Errorf("validatePlanChange() error = %v, want %v", err, ErrSamePrice)
+	}
+	if err := validatePlanChange(canceled, "price_pro_monthly"); !errors.Is(err, ErrNoActiveSubscription) {
+		t.Errorf("validatePlanChange() error = %v, want %v", err, ErrNoActiveSubscription)
+	}
+	if err := validatePlanChange(active, "price_unlisted"); !errors.Is(err, ErrUnknownPrice) {
+		t.Errorf("validatePlanChange() error = %v, want %v", err, ErrUnknownPrice)
+	}
+}
+
+func TestPreviewPlanChange_RejectsUnknownPrice(t *testing.T) {
+	sub := &models.Subscription{StripePriceID: "price_pro_monthly", Status: models.SubscriptionStatusActive}
+
+	if _, err := PreviewPlanChange(context.Background(), &fakeSubscriptionService{}, sub, "price_unlisted", 0); !errors.Is(err, ErrUnknownPrice) {
+		t.Errorf("PreviewPlanChange() error = %v, want %v", err, ErrUnknownPrice)
+	}
+}
+
+func TestBuildChangePreview(t *testing.T) {
+	invoice := &stripe.Invoice{
+		Currency:           stripe.CurrencyUSD,
+		Subtotal:           2500,
+		Total:              2500,
+		AmountDue:          2500,
+		NextPaymentAttempt: 1700000000,
+		Lines: &stripe.InvoiceLineItemList{
+			Data: []*stripe.InvoiceLineItem{
+				{Description: "Unused time on Basic", Amount: -500, Proration: true, Period: &stripe.Period{Start: 1699000000, End: 1700000000}},
+				{Description: "Remaining time on Pro", Amount: 1000, Proration: true},
+				{Description: "Pro", Amount: 2000},
+			},
+		},
+	}
+
+	preview := buildChangePreview("price_basic", "price_pro", 1699500000, invoice)
+
+	if preview.ProrationAmount != 500 {
+		t.Errorf("ProrationAmount = %d, want 500", preview.ProrationAmount)
+	}
+	if preview.Currency != "usd" || preview.AmountDue != 2500 {
+		t.Errorf("preview = %+v", preview)
+	}
+	if len(preview.Lines) != 3 {
+		t.Fatalf("len(Lines) = %d, want 3", len(preview.Lines))
+	}
+	if preview.Lines[0].PeriodStart == "" || preview.Lines[1].PeriodStart != "" {
+		t.Errorf("line periods not mapped correctly: %+v", preview.Lines)
+	}
+	if preview.NextPaymentAt == "" {
+		t.Error("NextPaymentAt should be set")
+	}
+}
+
+func TestSubscriptionErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{ErrNoActiveSubscription, http.StatusNotFound},
+		{ErrSamePrice, http.StatusBadRequest},
+		{ErrUnknownPrice, http.StatusBadRequest},
+		{ErrNotScheduledToCancel, http.StatusConflict},
+		{ErrDisabled, http.StatusServiceUnavailable},
+		{&stripe.Error{HTTPStatusCode: http.StatusBadRequest, Msg: "No such price"}, http.StatusBadRequest},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got, _ := SubscriptionErrorStatus(tt.err); got != tt.want {
+			t.Errorf("SubscriptionErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSubscriptionHandlers_Unauthorized(t *testing.T) {
+	handlers := map[string]http.HandlerFunc{
+		"preview-change": PreviewSubscriptionChange(),
+		"change":         ChangeSubscription(),
+		"cancel":         CancelSubscription(),
+		"resume":         ResumeSubscription(),
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/billing/subscription/"+name, strings.NewReader(`{}`))
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", rr.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+// ============ Integration Tests ============
+
+func TestChangePlan_Integration(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := createTestUserForWebhook(t, "change@example.com", "cus_change")
+	sub := createTestSubscription(t, user.ID, "sub_change", models.SubscriptionStatusActive)
+
+	updated, err := ChangePlan(ctx, &fakeSubscriptionService{}, sub, "price_pro_monthly", 0)
+	if err != nil {
+		t.Fatalf("ChangePlan() error = %v", err)
+	}
+	if updated.StripePriceID != "price_pro_monthly" {
+		t.Errorf("StripePriceID = %q, want price_pro_monthly", updated.StripePriceID)
+	}
+
+	if _, err := ChangePlan(ctx, &fakeSubscriptionService{}, updated, "price_pro_monthly", 0); !errors.Is(err, ErrSamePrice) {
+		t.Errorf("ChangePlan() to the same price error = %v, want %v", err, ErrSamePrice)
+	}
+}
+
+func TestCancelAndResumePlan_Integration(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc := &fakeSubscriptionService{}
+
+	t.Run("cancel at period end then resume", func(t *testing.T) {
+		user := createTestUserForWebhook(t, "cancel@example.com", "cus_cancel")
+		sub := createTestSubscription(t, user.ID, "sub_cancel", models.SubscriptionStatusActive)
+
+		if _, err := ResumePlan(ctx, svc, sub); !errors.Is(err, ErrNotScheduledToCancel) {
+			t.Errorf("ResumePlan() error = %v, want %v", err, ErrNotScheduledToCancel)
+		}
+
+		canceling, err := CancelPlan(ctx, svc, sub, false)
+		if err != nil {
+			t.Fatalf("CancelPlan() error = %v", err)
+		}
+		if !canceling.CancelAtPeriodEnd || canceling.Status != models.SubscriptionStatusActive {
+			t.Errorf("after cancel: cancel_at_period_end=%v status=%q", canceling.CancelAtPeriodEnd, canceling.Status)
+		}
+
+		resumed, err := ResumePlan(ctx, svc, canceling)
+		if err != nil {
+			t.Fatalf("ResumePlan() error = %v", err)
+		}
+		if resumed.CancelAtPeriodEnd {
+			t.Error("resumed subscription should not cancel at period end")
+		}
+	})
+
+	t.Run("cancel immediately", func(t *testing.T) {
+		user := createTestUserForWebhook(t, "cancelnow@example.com", "cus_cancel_now")
+		sub := createTestSubscription(t, user.ID, "sub_cancel_now", models.SubscriptionStatusActive)
+
+		canceled, err := CancelPlan(ctx, svc, sub, true)
+		if err != nil {
+			t.Fatalf("CancelPlan() error = %v", err)
+		}
+		if !svc.canceledImmediately {
+			t.Error("expected an immediate cancellation in Stripe")
+		}
+		if canceled.Status != models.SubscriptionStatusCanceled {
+			t.Errorf("status = %q, want %q", canceled.Status, models.SubscriptionStatusCanceled)
+		}
+
+		if _, err := findUserSubscription(ctx, user.ID); !errors.Is(err, ErrNoActiveSubscription) {
+			t.Errorf("findUserSubscription() error = %v, want %v", err, ErrNoActiveSubscription)
+		}
+
+		var stored models.User
+		database.DB.First(&stored, user.ID)
+		if stored.Role == models.RolePremium {
+			t.Error("user should lose premium role after immediate cancellation")
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/jobs"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
+)
+
+// trialEndsAt returns when a Stripe subscription's trial ends, or nil if it has no trial
+func trialEndsAt(sub *stripe.Subscription) *string {
+	if sub.TrialEnd <= 0 {
+		return nil
+	}
+	trialEnd := time.Unix(sub.TrialEnd, 0).Format(time.RFC3339)
+	return &trialEnd
+}
+
+// CheckoutTrialDays returns the free trial to offer for a price. Trials are once per user:
+// they are not offered to a user who has had a trial or subscription, personally or for any
+// organization, nor to an organization that has had a subscription or whose owner has.
+func CheckoutTrialDays(ctx context.Context, config *Config, priceID string, userID uint, orgID *uint) int64 {
+	trialDays := config.TrialDays(priceID)
+	if trialDays <= 0 || database.DB == nil {
+		return 0
+	}
+	db := database.DB.WithContext(ctx)
+
+	userIDs := []uint{userID}
+	if orgID != nil {
+		var ownerIDs []uint
+		if err := db.Model(&models.OrganizationMember{}).
+			Where("organization_id = ? AND role = ?", *orgID, models.OrgRoleOwner).
+			Pluck("user_id", &ownerIDs).Error; err != nil {
+			log.Error().Err(err).Uint("org_id", *orgID).Msg("failed to check trial eligibility")
+			return 0
+		}
+		userIDs = append(userIDs, ownerIDs...)
+	}
+
+	var trialed int64
+	if err := db.Model(&models.User{}).Where("id IN ? AND trial_used_at IS NOT NULL", userIDs).Count(&trialed).Error; err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("failed to check trial eligibility")
+		return 0
+	}
+	if trialed > 0 {
+		return 0
+	}
+
+	query := db.Unscoped().Model(&models.Subscription{}).Where("user_id IN ?", userIDs)
+	if orgID != nil {
+		query = query.Or("organization_id = ?", *orgID)
+	}
+	var previous int64
+	if err := query.Count(&previous).Error; err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("failed to check trial eligibility")
+		return 0
+	}
+	if previous > 0 {
+		return 0
+	}
+	return trialDays
+}
+
+// markTrialUsed records that a user has had their free trial
+func markTrialUsed(ctx context.Context, userID uint) {
+	if err := database.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND trial_used_at IS NULL", userID).
+		Update("trial_used_at", time.Now()).Error; err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("failed to record trial use")
+	}
+}
+
+// StartOrganizationTrial subscribes an organization to a plan's free trial without
+// collecting a card. The subscription is recorded immediately rather than waiting for
+// the webhook, which is idempotent when it arrives.
+func StartOrganizationTrial(ctx context.Context, config *Config, org *models.Organization, owner *models.User, priceID string) (*models.Subscription, error) {
+	if !IsAvailable() {
+		return nil, ErrDisabled
+	}
+	orgID := org.ID
+	trialDays := CheckoutTrialDays(ctx, config, priceID, owner.ID, &orgID)
+	if trialDays <= 0 {
+		return nil, ErrTrialNotAvailable
+	}
+	svc := GetService()
+
+	if org.StripeCustomerID == nil || *org.StripeCustomerID == "" {
+		customerID, err := svc.CreateCustomer(ctx, owner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create customer: %w", err)
+		}
+		if err := services.NewOrgService(database.DB).SetOrganizationStripeCustomer(ctx, org.ID, customerID); err != nil {
+			return nil, fmt.Errorf("failed to save customer: %w", err)
+		}
+		org.StripeCustomerID = &customerID
+	}
+
+	sub, err := svc.CreateTrialSubscription(ctx, *org.StripeCustomerID, priceID, trialDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trial subscription: %w", err)
+	}
+	if err := handleSubscriptionCreated(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.WithContext(ctx).First(org, org.ID).Error; err != nil {
+		return nil, err
+	}
+	var subscription models.Subscription
+	if err := database.DB.WithContext(ctx).Where("stripe_subscription_id = ?", sub.ID).First(&subscription).Error; err != nil {
+		return nil, err
+	}
+
+	log.Info().Uint("org_id", org.ID).Str("price_id", priceID).Int64("trial_days", trialDays).Msg("organization trial started")
+	return &subscription, nil
+}
+
+// handleTrialWillEnd records the trial end and reminds the billing contacts. Stripe sends
+// this three days before a trial ends.
+func handleTrialWillEnd(ctx context.Context, sub *stripe.Subscription) error {
+	log.Info().
+		Str("subscription_id", sub.ID).
+		Int64("trial_end", sub.TrialEnd).
+		Msg("subscription trial will end")
+
+	var subscription models.Subscription
+	if err := database.DB.WithContext(ctx).Where("stripe_subscription_id = ?", sub.ID).First(&subscription).Error; err != nil {
+		log.Error().Err(err).Str("subscription_id", sub.ID).Msg("subscription not found")
+		return err
+	}
+
+	if err := database.DB.WithContext(ctx).Model(&subscription).Updates(map[string]any{
+		"trial_ends_at": trialEndsAt(sub),
+		"updated_at":    time.Now().Format(time.RFC3339),
+	}).Error; err != nil {
+		return err
+	}
+	services.InvalidateEntitlements(ctx, services.SubscriptionSubject(&subscription))
+
+	// The trial may already have been ended early by a plan change or cancellation
+	if sub.Status != stripe.SubscriptionStatusTrialing || sub.TrialEnd <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	trialEnd := time.Unix(sub.TrialEnd, 0)
+	hasPaymentMethod := sub.DefaultPaymentMethod != nil || sub.DefaultSource != nil
+
+	recipients, orgName, orgSlug, err := billingRecipients(ctx, subscription.UserID, subscription.OrganizationID)
+	if err != nil {
+		log.Error().Err(err).Str("subscription_id", sub.ID).Msg("failed to load trial reminder recipients")
+	} else {
+		reminders := make([]jobs.SendTrialEndingEmailArgs, 0, len(recipients))
+		for _, user := range recipients {
+			reminders = append(reminders, jobs.SendTrialEndingEmailArgs{
+				UserID:           user.ID,
+				Email:            user.Email,
+				Name:             user.Name,
+				OrgName:          orgName,
+				OrgSlug:          orgSlug,
+				TrialEndsAt:      trialEnd.Format("January 2, 2006"),
+				DaysRemaining:    daysUntil(trialEnd, now),
+				HasPaymentMethod: hasPaymentMethod,
+			})
+		}
+		if err := jobs.EnqueueTrialEndingEmails(ctx, reminders); err != nil {
+			log.Warn().Err(err).Str("subscription_id", sub.ID).Msg("failed to enqueue trial ending emails")
+		}
+	}
+
+	message := fmt.Sprintf("Your free trial ends on %s. Add a payment method to keep your plan.", trialEnd.Format("January 2, 2006"))
+	if hasPaymentMethod {
+		message = fmt.Sprintf("Your free trial ends on %s. Your subscription will start automatically.", trialEnd.Format("January 2, 2006"))
+	}
+	trialEndStr := trialEnd.Format(time.RFC3339)
+	if subscription.OrganizationID != nil {
+		broadcastOrgSubscriptionEvent(ctx, *subscription.OrganizationID, "trial_will_end", string(sub.Status), "", subscription.StripePriceID, sub.CancelAtPeriodEnd, trialEndStr, message)
+	} else {
+		broadcastSubscriptionEvent(subscription.UserID, "trial_will_end", string(sub.Status), "premium", subscription.StripePriceID, sub.CancelAtPeriodEnd, trialEndStr, message)
+	}
+	return nil
+}
+
+// TrialProcessor enforces trial end dates for subscriptions whose webhook was missed or
+// delayed, converting or downgrading them to match Stripe
+type TrialProcessor struct {
+	db  *gorm.DB
+	svc Service
+	now func() time.Time
+}
+
+// NewTrialProcessor creates a trial processor
+func NewTrialProcessor(db *gorm.DB, svc Service) *TrialProcessor {
+	return &TrialProcessor{
+		db:  db,
+		svc: svc,
+		now: time.Now,
+	}
+}
+
+// ExpireTrials settles every trialing subscription whose trial end has passed
+func (p *TrialProcessor) ExpireTrials(ctx context.Context) error {
+	now := p.now()
+
+	var expired []models.Subscription
+	if err := p.db.WithContext(ctx).
+		Where("status = ? AND trial_ends_at <= ?", models.SubscriptionStatusTrialing, now.Format(time.RFC3339)).
+		Find(&expired).Error; err != nil {
+		return fmt.Errorf("failed to load expired trials: %w", err)
+	}
+
+	failed := 0
+	for i := range expired {
+		if err := p.settle(ctx, &expired[i], now); err != nil {
+			failed++
+			log.Error().
+				Err(err).
+				Str("subscription_id", expired[i].StripeSubscriptionID).
+				Msg("failed to settle expired trial")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d expired trials failed", failed)
+	}
+	return nil
+}
+
+// settle applies the subscription's current Stripe state. Without Stripe there is no way
+// to have paid, so the trial is ended and the subscription downgraded.
+func (p *TrialProcessor) settle(ctx context.Context, subscription *models.Subscription, now time.Time) error {
+	if p.svc == nil || !p.svc.IsAvailable() {
+		return handleSubscriptionDeleted(ctx, &stripe.Subscription{ID: subscription.StripeSubscriptionID})
+	}
+
+	live, err := p.svc.GetSubscription(ctx, subscription.StripeSubscriptionID)
+	if err != nil {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeResourceMissing {
+			return handleSubscriptionDeleted(ctx, &stripe.Subscription{ID: subscription.StripeSubscriptionID})
+		}
+		return err
+	}
+
+	switch live.Status {
+	case stripe.SubscriptionStatusTrialing:
+		if live.TrialEnd > now.Unix() {
+			// The trial was extended; record the new end date
+			return handleSubscriptionUpdated(ctx, live)
+		}
+		// Stripe has not ended the trial yet; the next run picks it up
+		return nil
+	case stripe.SubscriptionStatusCanceled, stripe.SubscriptionStatusIncompleteExpired:
+		return handleSubscriptionDeleted(ctx, live)
+	default:
+		// Converted (active) or awaiting payment (past_due, unpaid, paused)
+		return handleSubscriptionUpdated(ctx, live)
+	}
+}
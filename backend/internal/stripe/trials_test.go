@@ -0,0 +1,214 @@
+package stripe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+
+	stripe "github.com/stripe/stripe-go/v76"
+)
+
+// fakeTrialService reports a fixed state for subscriptions looked up after their trial ended
+type fakeTrialService struct {
+	noOpService
+	status   stripe.SubscriptionStatus
+	trialEnd int64
+}
+
+func (f *fakeTrialService) IsAvailable() bool {
+	return true
+}
+
+func (f *fakeTrialService) GetSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error) {
+	sub := fakeStripeSubscription(subscriptionID, "price_test_123", f.status, false)
+	sub.TrialEnd = f.trialEnd
+	return sub, nil
+}
+
+// ============ Unit Tests ============
+
+func TestTrialEndsAt(t *testing.T) {
+	if got := trialEndsAt(&stripe.Subscription{}); got != nil {
+		t.Errorf("trialEndsAt() = %q, want nil without a trial", *got)
+	}
+
+	got := trialEndsAt(&stripe.Subscription{TrialEnd: 1700000000})
+	if got == nil || *got != time.Unix(1700000000, 0).Format(time.RFC3339) {
+		t.Errorf("trialEndsAt() = %v, want the trial end", got)
+	}
+}
+
+func TestCheckoutTrialDays_NoTrialConfigured(t *testing.T) {
+	config := &Config{PremiumPriceID: "price_premium"}
+
+	if got := CheckoutTrialDays(context.Background(), config, "price_premium", 1, nil); got != 0 {
+		t.Errorf("CheckoutTrialDays() = %d, want 0 when the plan has no trial", got)
+	}
+}
+
+// ============ Integration Tests ============
+
+func TestCheckoutTrialDays_OncePerSubject(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	config := &Config{PremiumPriceID: "price_premium", PremiumTrialDays: 14}
+	user := createTestUserForWebhook(t, "trial-once@example.com", "cus_trial_once")
+
+	if got := CheckoutTrialDays(ctx, config, "price_premium", user.ID, nil); got != 14 {
+		t.Errorf("CheckoutTrialDays() = %d, want 14 for a first subscription", got)
+	}
+
+	sub := createTestSubscription(t, user.ID, "sub_trial_once", models.SubscriptionStatusCanceled)
+	database.DB.Delete(sub)
+
+	if got := CheckoutTrialDays(ctx, config, "price_premium", user.ID, nil); got != 0 {
+		t.Errorf("CheckoutTrialDays() = %d, want 0 after a previous subscription", got)
+	}
+}
+
+func TestCheckoutTrialDays_OncePerUserAcrossOrganizations(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	config := &Config{PremiumPriceID: "price_premium", PremiumTrialDays: 14}
+	first := createTestOrganization(t, "trial-first-org", "cus_trial_first_org")
+	ownerID := first.CreatedByUserID
+
+	if got := CheckoutTrialDays(ctx, config, "price_premium", ownerID, &first.ID); got != 14 {
+		t.Fatalf("CheckoutTrialDays() = %d, want 14 for a first organization", got)
+	}
+
+	created := fakeStripeSubscription("sub_trial_first_org", "price_premium", stripe.SubscriptionStatusTrialing, false)
+	created.Customer = &stripe.Customer{ID: "cus_trial_first_org"}
+	created.TrialEnd = time.Now().Add(14 * 24 * time.Hour).Unix()
+	if err := handleSubscriptionCreated(ctx, created); err != nil {
+		t.Fatalf("handleSubscriptionCreated() error = %v", err)
+	}
+
+	// Purging the organization removes its subscription records
+	database.DB.Unscoped().Where("organization_id = ?", first.ID).Delete(&models.Subscription{})
+	database.DB.Where("organization_id = ?", first.ID).Delete(&models.OrganizationMember{})
+	database.DB.Delete(&models.Organization{}, first.ID)
+
+	second := &models.Organization{Name: "Second Org", Slug: "trial-second-org", Plan: models.OrgPlanFree, CreatedByUserID: ownerID}
+	if err := database.DB.Create(second).Error; err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	createTestOrgMember(t, second.ID, ownerID, models.OrgRoleOwner)
+
+	if got := CheckoutTrialDays(ctx, config, "price_premium", ownerID, &second.ID); got != 0 {
+		t.Errorf("CheckoutTrialDays() = %d, want 0 for the owner's next organization", got)
+	}
+	if got := CheckoutTrialDays(ctx, config, "price_premium", ownerID, nil); got != 0 {
+		t.Errorf("CheckoutTrialDays() = %d, want 0 for the owner's personal plan", got)
+	}
+
+	// An admin starting checkout cannot claim a trial for an organization whose owner had one
+	admin := createTestUserForWebhook(t, "trial-second-admin@example.com", "cus_trial_second_admin")
+	createTestOrgMember(t, second.ID, admin.ID, models.OrgRoleAdmin)
+	if got := CheckoutTrialDays(ctx, config, "price_premium", admin.ID, &second.ID); got != 0 {
+		t.Errorf("CheckoutTrialDays() = %d, want 0 when the owner already had a trial", got)
+	}
+}
+
+func TestTrialLifecycle_Integration(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := createTestUserForWebhook(t, "trial@example.com", "cus_trial")
+	trialEnd := time.Now().Add(3 * 24 * time.Hour).Unix()
+
+	created := fakeStripeSubscription("sub_trial", "price_test_123", stripe.SubscriptionStatusTrialing, false)
+	created.Customer = &stripe.Customer{ID: "cus_trial"}
+	created.TrialEnd = trialEnd
+	if err := handleSubscriptionCreated(ctx, created); err != nil {
+		t.Fatalf("handleSubscriptionCreated() error = %v", err)
+	}
+
+	var subscription models.Subscription
+	if err := database.DB.Where("stripe_subscription_id = ?", "sub_trial").First(&subscription).Error; err != nil {
+		t.Fatalf("subscription not recorded: %v", err)
+	}
+	if !subscription.IsTrialing() || subscription.TrialEndsAt == nil {
+		t.Fatalf("subscription = %+v, want trialing with a trial end", subscription)
+	}
+	var stored models.User
+	database.DB.First(&stored, user.ID)
+	if stored.Role != models.RolePremium {
+		t.Errorf("role during trial = %q, want %q", stored.Role, models.RolePremium)
+	}
+
+	if err := dispatchEventObject(ctx, "customer.subscription.trial_will_end", created); err != nil {
+		t.Fatalf("trial_will_end error = %v", err)
+	}
+
+	// The trial ends without payment and Stripe cancels the subscription
+	processor := NewTrialProcessor(database.DB, &fakeTrialService{status: stripe.SubscriptionStatusCanceled, trialEnd: trialEnd})
+	processor.now = func() time.Time { return time.Unix(trialEnd, 0).Add(time.Hour) }
+	if err := processor.ExpireTrials(ctx); err != nil {
+		t.Fatalf("ExpireTrials() error = %v", err)
+	}
+
+	database.DB.First(&subscription, subscription.ID)
+	if subscription.Status != models.SubscriptionStatusCanceled {
+		t.Errorf("status = %q, want %q", subscription.Status, models.SubscriptionStatusCanceled)
+	}
+	database.DB.First(&stored, user.ID)
+	if stored.Role != models.RoleUser {
+		t.Errorf("role after trial = %q, want %q", stored.Role, models.RoleUser)
+	}
+}
+
+func TestExpireTrials_ConvertedTrialStaysOnPlan(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	org := createTestOrganization(t, "trial-org", "cus_trial_org")
+	sub := createTestOrgSubscription(t, org.ID, org.CreatedByUserID, "sub_trial_org", models.SubscriptionStatusTrialing)
+	pastEnd := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	database.DB.Model(sub).Update("trial_ends_at", pastEnd)
+
+	processor := NewTrialProcessor(database.DB, &fakeTrialService{status: stripe.SubscriptionStatusActive})
+	if err := processor.ExpireTrials(ctx); err != nil {
+		t.Fatalf("ExpireTrials() error = %v", err)
+	}
+
+	var stored models.Subscription
+	database.DB.First(&stored, sub.ID)
+	if stored.Status != models.SubscriptionStatusActive {
+		t.Errorf("status = %q, want %q", stored.Status, models.SubscriptionStatusActive)
+	}
+	var storedOrg models.Organization
+	database.DB.First(&storedOrg, org.ID)
+	if storedOrg.Plan == models.OrgPlanFree {
+		t.Error("a converted trial should keep the paid plan")
+	}
+}
+
+func TestSubscriptionPaused_Downgrades(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	org := createTestOrganization(t, "paused-org", "cus_paused_org")
+	createTestOrgSubscription(t, org.ID, org.CreatedByUserID, "sub_paused_org", models.SubscriptionStatusTrialing)
+
+	paused := fakeStripeSubscription("sub_paused_org", "price_org_test_123", stripe.SubscriptionStatusPaused, false)
+	if err := handleSubscriptionUpdated(ctx, paused); err != nil {
+		t.Fatalf("handleSubscriptionUpdated() error = %v", err)
+	}
+
+	var storedOrg models.Organization
+	database.DB.First(&storedOrg, org.ID)
+	if storedOrg.Plan != models.OrgPlanFree {
+		t.Errorf("plan = %q, want %q", storedOrg.Plan, models.OrgPlanFree)
+	}
+}
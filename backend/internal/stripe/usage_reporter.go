@@ -0,0 +1,403 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
+)
+
+// Metered usage metrics reported to Stripe
+const (
+	MetricAPICalls       = "api_calls"
+	MetricStorageGBHours = "storage_gb_hours"
+	MetricAITokens       = "ai_tokens"
+)
+
+// bytesPerGB is the divisor used to convert byte-hours into GB-hours
+const bytesPerGB = 1 << 30
+
+// UsageReporter aggregates metered usage per billed customer and reports it to Stripe billing meters.
+// Every report is recorded in usage_reports so what was billed for a period can be reconciled.
+type UsageReporter struct {
+	db     *gorm.DB
+	svc    Service
+	config *Config
+	now    func() time.Time
+}
+
+// NewUsageReporter creates a usage reporter
+func NewUsageReporter(db *gorm.DB, svc Service, config *Config) *UsageReporter {
+	return &UsageReporter{
+		db:     db,
+		svc:    svc,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// meteredSubject is a user or organization with an active subscription and a Stripe customer
+type meteredSubject struct {
+	UserID     *uint
+	OrgID      *uint
+	CustomerID string
+}
+
+// key returns a stable identifier for the subject used in idempotency keys
+func (s meteredSubject) key() string {
+	if s.OrgID != nil {
+		return fmt.Sprintf("org_%d", *s.OrgID)
+	}
+	return fmt.Sprintf("user_%d", *s.UserID)
+}
+
+// scope restricts a query to rows belonging to the subject
+func (s meteredSubject) scope(db *gorm.DB) *gorm.DB {
+	if s.OrgID != nil {
+		return db.Where("organization_id = ?", *s.OrgID)
+	}
+	return db.Where("user_id = ? AND organization_id IS NULL", *s.UserID)
+}
+
+// meteredPeriod is a calendar-month billing period, matching UsageService periods
+type meteredPeriod struct {
+	Start time.Time
+	End   time.Time // Exclusive
+}
+
+// periodContaining returns the billing period that contains t
+func periodContaining(t time.Time) meteredPeriod {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return meteredPeriod{Start: start, End: start.AddDate(0, 1, 0)}
+}
+
+// startDate returns the period start in the format stored on usage rows
+func (p meteredPeriod) startDate() string {
+	return p.Start.Format("2006-01-02")
+}
+
+// endDate returns the last day of the period in the format stored on usage rows
+func (p meteredPeriod) endDate() string {
+	return p.End.Add(-time.Second).Format("2006-01-02")
+}
+
+// meters returns the configured metrics and their meter event names
+func (r *UsageReporter) meters() map[string]string {
+	meters := make(map[string]string)
+	if r.config.MeterAPICalls != "" {
+		meters[MetricAPICalls] = r.config.MeterAPICalls
+	}
+	if r.config.MeterStorageGBHours != "" {
+		meters[MetricStorageGBHours] = r.config.MeterStorageGBHours
+	}
+	if r.config.MeterAITokens != "" {
+		meters[MetricAITokens] = r.config.MeterAITokens
+	}
+	return meters
+}
+
+// ReportUsage reports usage deltas for the current and previous billing periods.
+// Late usage for the previous period is flushed once the month rolls over.
+func (r *UsageReporter) ReportUsage(ctx context.Context) error {
+	meters := r.meters()
+	if len(meters) == 0 {
+		return nil
+	}
+
+	subjects, err := r.subjects(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load metered subjects: %w", err)
+	}
+
+	now := r.now()
+	current := periodContaining(now)
+	previous := periodContaining(current.Start.Add(-time.Second))
+
+	failed := 0
+	for _, subject := range subjects {
+		for _, period := range []meteredPeriod{previous, current} {
+			for metric, eventName := range meters {
+				if err := r.reportMetric(ctx, subject, period, metric, eventName, now); err != nil {
+					failed++
+					log.Error().
+						Err(err).
+						Str("subject", subject.key()).
+						Str("metric", metric).
+						Str("period_start", period.startDate()).
+						Msg("failed to report metered usage")
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d metered usage reports failed", failed)
+	}
+	return nil
+}
+
+// subjects returns billed users and organizations with a subscription that accrues usage
+func (r *UsageReporter) subjects(ctx context.Context) ([]meteredSubject, error) {
+	var subs []models.Subscription
+	if err := r.db.WithContext(ctx).
+		Where("status IN ?", []string{
+			models.SubscriptionStatusActive,
+			models.SubscriptionStatusTrialing,
+			models.SubscriptionStatusPastDue,
+		}).
+		Find(&subs).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var subjects []meteredSubject
+	for _, sub := range subs {
+		var subject meteredSubject
+		if sub.OrganizationID != nil && *sub.OrganizationID > 0 {
+			var org models.Organization
+			if err := r.db.WithContext(ctx).First(&org, *sub.OrganizationID).Error; err != nil || org.StripeCustomerID == nil {
+				continue
+			}
+			orgID := org.ID
+			subject = meteredSubject{OrgID: &orgID, CustomerID: *org.StripeCustomerID}
+		} else {
+			var user models.User
+			if err := r.db.WithContext(ctx).First(&user, sub.UserID).Error; err != nil || user.StripeCustomerID == nil {
+				continue
+			}
+			userID := user.ID
+			subject = meteredSubject{UserID: &userID, CustomerID: *user.StripeCustomerID}
+		}
+
+		if subject.CustomerID == "" || seen[subject.key()] {
+			continue
+		}
+		seen[subject.key()] = true
+		subjects = append(subjects, subject)
+	}
+
+	return subjects, nil
+}
+
+// reportMetric sends the unreported part of a metric for one subject and period
+func (r *UsageReporter) reportMetric(ctx context.Context, subject meteredSubject, period meteredPeriod, metric, eventName string, now time.Time) error {
+	if !now.After(period.Start) {
+		return nil
+	}
+
+	measured, err := r.measure(ctx, subject, period, metric, now)
+	if err != nil {
+		return fmt.Errorf("failed to measure usage: %w", err)
+	}
+
+	quantity := billableQuantity(metric, measured)
+
+	var last models.UsageReport
+	reported := int64(0)
+	err = subject.scope(r.db.WithContext(ctx)).
+		Where("metric = ? AND period_start = ? AND status = ?", metric, period.startDate(), models.UsageReportStatusReported).
+		Order("cumulative_quantity DESC").
+		First(&last).Error
+	if err == nil {
+		reported = last.CumulativeQuantity
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to load last usage report: %w", err)
+	}
+
+	delta := quantity - reported
+	if delta <= 0 {
+		return nil
+	}
+
+	// Usage for a closed period is stamped inside that period so it lands on the right invoice
+	eventTime := now
+	if !eventTime.Before(period.End) {
+		eventTime = period.End.Add(-time.Second)
+	}
+
+	nowStr := now.Format(time.RFC3339)
+	report := models.UsageReport{
+		UserID:             subject.UserID,
+		OrganizationID:     subject.OrgID,
+		StripeCustomerID:   subject.CustomerID,
+		Metric:             metric,
+		MeterEventName:     eventName,
+		PeriodStart:        period.startDate(),
+		PeriodEnd:          period.endDate(),
+		Quantity:           delta,
+		CumulativeQuantity: quantity,
+		MeasuredValue:      measured,
+		MeasuredAt:         nowStr,
+		Identifier:         usageReportIdentifier(subject, metric, period, quantity),
+		Status:             models.UsageReportStatusReported,
+		ReportedAt:         &nowStr,
+		CreatedAt:          nowStr,
+		UpdatedAt:          nowStr,
+	}
+
+	reportErr := r.svc.ReportMeterEvent(ctx, eventName, subject.CustomerID, delta, eventTime.Unix(), report.Identifier)
+	if reportErr != nil {
+		errMsg := reportErr.Error()
+		report.Status = models.UsageReportStatusFailed
+		report.Error = &errMsg
+		report.ReportedAt = nil
+	}
+
+	// A retry with an unchanged total reuses the identifier, so update the earlier attempt in place
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "identifier"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "error", "reported_at", "measured_value", "measured_at", "updated_at"}),
+		}).
+		Create(&report).Error; err != nil {
+		return fmt.Errorf("failed to record usage report: %w", err)
+	}
+
+	if reportErr != nil {
+		return reportErr
+	}
+
+	log.Info().
+		Str("subject", subject.key()).
+		Str("metric", metric).
+		Str("period_start", period.startDate()).
+		Int64("quantity", delta).
+		Int64("cumulative", quantity).
+		Msg("metered usage reported")
+
+	return nil
+}
+
+// measure returns the raw usage for a metric: a count, a token total or storage byte-hours
+func (r *UsageReporter) measure(ctx context.Context, subject meteredSubject, period meteredPeriod, metric string, now time.Time) (int64, error) {
+	switch metric {
+	case MetricAPICalls:
+		return r.sumUsageEvents(ctx, subject, period, services.UsageTypeAPICall)
+	case MetricAITokens:
+		return r.sumUsageEvents(ctx, subject, period, services.UsageTypeAITokens)
+	case MetricStorageGBHours:
+		return r.sampleStorage(ctx, subject, period, now)
+	default:
+		return 0, fmt.Errorf("unknown metric %q", metric)
+	}
+}
+
+// sumUsageEvents totals recorded usage events of one type in a period
+func (r *UsageReporter) sumUsageEvents(ctx context.Context, subject meteredSubject, period meteredPeriod, eventType string) (int64, error) {
+	var total int64
+	err := subject.scope(r.db.WithContext(ctx).Model(&models.UsageEvent{})).
+		Where("event_type = ? AND billing_period_start = ?", eventType, period.startDate()).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// sampleStorage integrates current storage over the time since the last sample and
+// returns the byte-hours accrued in the period so far
+func (r *UsageReporter) sampleStorage(ctx context.Context, subject meteredSubject, period meteredPeriod, now time.Time) (int64, error) {
+	var usagePeriod models.UsagePeriod
+	err := subject.scope(r.db.WithContext(ctx)).
+		Where("period_start = ?", period.startDate()).
+		First(&usagePeriod).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if now.After(period.End) {
+			// Don't backfill closed periods that were never sampled
+			return 0, nil
+		}
+		limitsJSON, _ := json.Marshal(services.DefaultUsageLimits)
+		nowStr := now.Format(time.RFC3339)
+		usagePeriod = models.UsagePeriod{
+			UserID:         subject.UserID,
+			OrganizationID: subject.OrgID,
+			PeriodStart:    period.startDate(),
+			PeriodEnd:      period.endDate(),
+			UsageTotals:    "{}",
+			UsageLimits:    string(limitsJSON),
+			CreatedAt:      nowStr,
+			UpdatedAt:      nowStr,
+		}
+		if err := r.db.WithContext(ctx).Create(&usagePeriod).Error; err != nil {
+			return 0, err
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	sampledAt := period.Start
+	if usagePeriod.StorageSampledAt != nil {
+		if t, err := time.Parse(time.RFC3339, *usagePeriod.StorageSampledAt); err == nil {
+			sampledAt = t
+		}
+	}
+
+	end := now
+	if end.After(period.End) {
+		end = period.End
+	}
+	if !end.After(sampledAt) {
+		return usagePeriod.StorageByteHours, nil
+	}
+
+	bytes, err := r.currentStorageBytes(ctx, subject)
+	if err != nil {
+		return 0, err
+	}
+
+	byteHours := usagePeriod.StorageByteHours + accrueByteHours(bytes, end.Sub(sampledAt))
+	endStr := end.Format(time.RFC3339)
+	if err := r.db.WithContext(ctx).Model(&usagePeriod).Updates(map[string]interface{}{
+		"storage_byte_hours": byteHours,
+		"storage_sampled_at": endStr,
+		"updated_at":         now.Format(time.RFC3339),
+	}).Error; err != nil {
+		return 0, err
+	}
+
+	return byteHours, nil
+}
+
+// currentStorageBytes returns the bytes currently stored by a user, or by an organization's active members
+func (r *UsageReporter) currentStorageBytes(ctx context.Context, subject meteredSubject) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.File{})
+	if subject.OrgID != nil {
+		query = query.Where("user_id IN (?)", r.db.Model(&models.OrganizationMember{}).
+			Select("user_id").
+			Where("organization_id = ? AND status = ?", *subject.OrgID, models.MemberStatusActive))
+	} else {
+		query = query.Where("user_id = ?", *subject.UserID)
+	}
+
+	var total int64
+	err := query.Select("COALESCE(SUM(file_size), 0)").Scan(&total).Error
+	return total, err
+}
+
+// accrueByteHours returns storage held for a duration in byte-hours
+func accrueByteHours(bytes int64, d time.Duration) int64 {
+	if bytes <= 0 || d <= 0 {
+		return 0
+	}
+	return int64(float64(bytes) * d.Hours())
+}
+
+// billableQuantity converts a raw measurement into the unit billed by the meter
+func billableQuantity(metric string, measured int64) int64 {
+	if metric == MetricStorageGBHours {
+		return measured / bytesPerGB
+	}
+	return measured
+}
+
+// usageReportIdentifier builds a deterministic meter event identifier. It is derived from the
+// cumulative total so re-running the job after a crash sends the same key and is deduplicated.
+func usageReportIdentifier(subject meteredSubject, metric string, period meteredPeriod, cumulative int64) string {
+	return fmt.Sprintf("usage_%s_%s_%s_%d", subject.key(), metric, period.startDate(), cumulative)
+}
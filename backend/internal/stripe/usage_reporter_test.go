@@ -0,0 +1,298 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/models"
+	"react-golang-starter/internal/services"
+)
+
+// fakeMeterService records meter events instead of calling Stripe
+type fakeMeterService struct {
+	noOpService
+	events []fakeMeterEvent
+	err    error
+}
+
+type fakeMeterEvent struct {
+	EventName  string
+	CustomerID string
+	Value      int64
+	Timestamp  int64
+	Identifier string
+}
+
+func (f *fakeMeterService) ReportMeterEvent(ctx context.Context, eventName, customerID string, value, timestamp int64, identifier string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, fakeMeterEvent{
+		EventName:  eventName,
+		CustomerID: customerID,
+		Value:      value,
+		Timestamp:  timestamp,
+		Identifier: identifier,
+	})
+	return nil
+}
+
+func (f *fakeMeterService) IsAvailable() bool {
+	return true
+}
+
+// ============ Unit Tests ============
+
+func TestPeriodContaining(t *testing.T) {
+	now := time.Date(2024, time.February, 15, 10, 30, 0, 0, time.UTC)
+	period := periodContaining(now)
+
+	if period.startDate() != "2024-02-01" {
+		t.Errorf("startDate() = %q, want 2024-02-01", period.startDate())
+	}
+	if period.endDate() != "2024-02-29" {
+		t.Errorf("endDate() = %q, want 2024-02-29", period.endDate())
+	}
+	if !period.End.Equal(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("End = %v, want 2024-03-01", period.End)
+	}
+}
+
+func TestBillableQuantity(t *testing.T) {
+	tests := []struct {
+		metric   string
+		measured int64
+		want     int64
+	}{
+		{MetricAPICalls, 1500, 1500},
+		{MetricAITokens, 42000, 42000},
+		{MetricStorageGBHours, 3 * bytesPerGB, 3},
+		{MetricStorageGBHours, bytesPerGB - 1, 0},
+	}
+
+	for _, tt := range tests {
+		if got := billableQuantity(tt.metric, tt.measured); got != tt.want {
+			t.Errorf("billableQuantity(%q, %d) = %d, want %d", tt.metric, tt.measured, got, tt.want)
+		}
+	}
+}
+
+func TestAccrueByteHours(t *testing.T) {
+	if got := accrueByteHours(bytesPerGB, 2*time.Hour); got != 2*bytesPerGB {
+		t.Errorf("accrueByteHours(1GB, 2h) = %d, want %d", got, 2*bytesPerGB)
+	}
+	if got := accrueByteHours(bytesPerGB, 30*time.Minute); got != bytesPerGB/2 {
+		t.Errorf("accrueByteHours(1GB, 30m) = %d, want %d", got, bytesPerGB/2)
+	}
+	if got := accrueByteHours(0, time.Hour); got != 0 {
+		t.Errorf("accrueByteHours(0, 1h) = %d, want 0", got)
+	}
+	if got := accrueByteHours(bytesPerGB, -time.Hour); got != 0 {
+		t.Errorf("accrueByteHours(1GB, -1h) = %d, want 0", got)
+	}
+}
+
+func TestUsageReportIdentifier_Deterministic(t *testing.T) {
+	userID := uint(7)
+	orgID := uint(3)
+	period := periodContaining(time.Date(2024, time.May, 10, 0, 0, 0, 0, time.UTC))
+
+	userKey := usageReportIdentifier(meteredSubject{UserID: &userID}, MetricAPICalls, period, 120)
+	if userKey != "usage_user_7_api_calls_2024-05-01_120" {
+		t.Errorf("identifier = %q", userKey)
+	}
+	if userKey != usageReportIdentifier(meteredSubject{UserID: &userID}, MetricAPICalls, period, 120) {
+		t.Error("identifier should be deterministic for the same cumulative total")
+	}
+
+	orgKey := usageReportIdentifier(meteredSubject{OrgID: &orgID}, MetricAPICalls, period, 120)
+	if orgKey != "usage_org_3_api_calls_2024-05-01_120" {
+		t.Errorf("identifier = %q", orgKey)
+	}
+}
+
+func TestUsageReporter_NoMetersConfigured(t *testing.T) {
+	svc := &fakeMeterService{}
+	reporter := NewUsageReporter(nil, svc, &Config{})
+
+	if err := reporter.ReportUsage(context.Background()); err != nil {
+		t.Errorf("ReportUsage() error = %v, want nil", err)
+	}
+	if len(svc.events) != 0 {
+		t.Errorf("ReportUsage() sent %d events, want 0", len(svc.events))
+	}
+}
+
+// ============ Integration Tests ============
+
+func setupMeteredUser(t *testing.T, customerID string) *models.User {
+	t.Helper()
+	user := createTestUserForWebhook(t, customerID+"@example.com", customerID)
+	createTestSubscription(t, user.ID, "sub_"+customerID, models.SubscriptionStatusActive)
+	return user
+}
+
+func recordTestUsage(t *testing.T, userID uint, eventType string, quantity int64, periodStart, periodEnd string) {
+	t.Helper()
+	event := models.UsageEvent{
+		UserID:             &userID,
+		EventType:          eventType,
+		Resource:           "/api/test",
+		Quantity:           quantity,
+		Unit:               "count",
+		Metadata:           "{}",
+		BillingPeriodStart: periodStart,
+		BillingPeriodEnd:   periodEnd,
+		CreatedAt:          time.Now().Format(time.RFC3339),
+	}
+	if err := database.DB.Create(&event).Error; err != nil {
+		t.Fatalf("failed to create usage event: %v", err)
+	}
+}
+
+func TestUsageReporter_ReportsDeltas(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := setupMeteredUser(t, "cus_metered_1")
+
+	now := time.Now()
+	period := periodContaining(now)
+	recordTestUsage(t, user.ID, services.UsageTypeAPICall, 100, period.startDate(), period.endDate())
+	recordTestUsage(t, user.ID, services.UsageTypeAITokens, 5000, period.startDate(), period.endDate())
+
+	svc := &fakeMeterService{}
+	reporter := NewUsageReporter(database.DB, svc, &Config{
+		MeterAPICalls: "api_calls",
+		MeterAITokens: "ai_tokens",
+	})
+	reporter.now = func() time.Time { return now }
+
+	if err := reporter.ReportUsage(ctx); err != nil {
+		t.Fatalf("ReportUsage() error = %v", err)
+	}
+	if len(svc.events) != 2 {
+		t.Fatalf("expected 2 meter events, got %d", len(svc.events))
+	}
+	for _, event := range svc.events {
+		if event.CustomerID != "cus_metered_1" {
+			t.Errorf("event customer = %q, want cus_metered_1", event.CustomerID)
+		}
+	}
+
+	// A second run with no new usage reports nothing
+	if err := reporter.ReportUsage(ctx); err != nil {
+		t.Fatalf("ReportUsage() error = %v", err)
+	}
+	if len(svc.events) != 2 {
+		t.Fatalf("expected no new meter events, got %d total", len(svc.events))
+	}
+
+	// New usage is reported as a delta
+	recordTestUsage(t, user.ID, services.UsageTypeAPICall, 25, period.startDate(), period.endDate())
+	if err := reporter.ReportUsage(ctx); err != nil {
+		t.Fatalf("ReportUsage() error = %v", err)
+	}
+	if len(svc.events) != 3 {
+		t.Fatalf("expected 3 meter events, got %d", len(svc.events))
+	}
+	if svc.events[2].Value != 25 {
+		t.Errorf("delta value = %d, want 25", svc.events[2].Value)
+	}
+
+	// The ledger sums to what Stripe was told
+	var total int64
+	database.DB.Model(&models.UsageReport{}).
+		Where("user_id = ? AND metric = ? AND period_start = ? AND status = ?",
+			user.ID, MetricAPICalls, period.startDate(), models.UsageReportStatusReported).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&total)
+	if total != 125 {
+		t.Errorf("reported api_calls total = %d, want 125", total)
+	}
+}
+
+func TestUsageReporter_FailedReportIsRetried(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := setupMeteredUser(t, "cus_metered_2")
+
+	now := time.Now()
+	period := periodContaining(now)
+	recordTestUsage(t, user.ID, services.UsageTypeAPICall, 40, period.startDate(), period.endDate())
+
+	svc := &fakeMeterService{err: errors.New("stripe unavailable")}
+	reporter := NewUsageReporter(database.DB, svc, &Config{MeterAPICalls: "api_calls"})
+	reporter.now = func() time.Time { return now }
+
+	if err := reporter.ReportUsage(ctx); err == nil {
+		t.Fatal("ReportUsage() should return error when Stripe fails")
+	}
+
+	var failed models.UsageReport
+	if err := database.DB.Where("user_id = ? AND status = ?", user.ID, models.UsageReportStatusFailed).First(&failed).Error; err != nil {
+		t.Fatalf("expected failed usage report: %v", err)
+	}
+
+	svc.err = nil
+	if err := reporter.ReportUsage(ctx); err != nil {
+		t.Fatalf("ReportUsage() retry error = %v", err)
+	}
+	if len(svc.events) != 1 || svc.events[0].Identifier != failed.Identifier {
+		t.Fatalf("retry should reuse identifier %q, got %+v", failed.Identifier, svc.events)
+	}
+
+	var count int64
+	database.DB.Model(&models.UsageReport{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("expected the failed report to be updated in place, got %d rows", count)
+	}
+}
+
+func TestUsageReporter_StorageGBHours(t *testing.T) {
+	cleanup := testWebhookSetup(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := setupMeteredUser(t, "cus_metered_3")
+
+	file := models.File{
+		UserID:      user.ID,
+		FileName:    "big.bin",
+		FileSize:    2 * bytesPerGB,
+		ContentType: "application/octet-stream",
+		StorageType: "local",
+	}
+	if err := database.DB.Create(&file).Error; err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	svc := &fakeMeterService{}
+	reporter := NewUsageReporter(database.DB, svc, &Config{MeterStorageGBHours: "storage_gb_hours"})
+
+	// Ten hours into the period, 2 GB held the whole time is 20 GB-hours
+	period := periodContaining(time.Now())
+	now := period.Start.Add(10 * time.Hour)
+	reporter.now = func() time.Time { return now }
+
+	if err := reporter.ReportUsage(ctx); err != nil {
+		t.Fatalf("ReportUsage() error = %v", err)
+	}
+	if len(svc.events) != 1 || svc.events[0].Value != 20 {
+		t.Fatalf("expected one event of 20 GB-hours, got %+v", svc.events)
+	}
+
+	// Another hour accrues 2 more GB-hours
+	now = now.Add(time.Hour)
+	if err := reporter.ReportUsage(ctx); err != nil {
+		t.Fatalf("ReportUsage() error = %v", err)
+	}
+	if len(svc.events) != 2 || svc.events[1].Value != 2 {
+		t.Fatalf("expected a 2 GB-hour delta, got %+v", svc.events)
+	}
+}
@@ -3,8 +3,10 @@ package stripe
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -14,6 +16,7 @@ import (
 	"gorm.io/gorm"
 
 	"react-golang-starter/internal/database"
+	"react-golang-starter/internal/jobs"
 	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/services"
 	ws "react-golang-starter/internal/websocket"
@@ -158,64 +161,72 @@ func HandleWebhook(config *Config) http.HandlerFunc {
 
 		log.Info().Str("event_type", string(event.Type)).Str("event_id", event.ID).Msg("received stripe webhook")
 
-		// Handle the event
-		switch event.Type {
-		case "checkout.session.completed":
-			var session stripe.CheckoutSession
-			if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
-				log.Error().Err(err).Msg("failed to unmarshal checkout session")
-				break
+		// Without a database there is nowhere to persist the event, so process it inline
+		if database.DB == nil {
+			if err := dispatchEvent(r.Context(), string(event.Type), event.Data.Raw); err != nil {
+				log.Error().Err(err).Str("event_id", event.ID).Msg("failed to process stripe webhook")
 			}
-			handleCheckoutSessionCompleted(r.Context(), &session)
-
-		case "customer.subscription.created":
-			var sub stripe.Subscription
-			if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
-				log.Error().Err(err).Msg("failed to unmarshal subscription")
-				break
-			}
-			handleSubscriptionCreated(r.Context(), &sub)
+			writeWebhookAck(w, "Webhook processed")
+			return
+		}
 
-		case "customer.subscription.updated":
-			var sub stripe.Subscription
-			if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
-				log.Error().Err(err).Msg("failed to unmarshal subscription")
-				break
+		// Persist the event first; the unique event ID makes redeliveries a no-op
+		record, created, err := recordEvent(r.Context(), &event)
+		if err != nil {
+			// Respond with an error so Stripe redelivers the event later
+			log.Error().Err(err).Str("event_id", event.ID).Msg("failed to persist stripe webhook")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			if err := json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:   "Internal Server Error",
+				Message: "Failed to store webhook event",
+				Code:    http.StatusInternalServerError,
+			}); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
-			handleSubscriptionUpdated(r.Context(), &sub)
-
-		case "customer.subscription.deleted":
-			var sub stripe.Subscription
-			if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
-				log.Error().Err(err).Msg("failed to unmarshal subscription")
-				break
+			return
+		}
+		if !created {
+			// Storing and queuing are not atomic, so a redelivery recovers an event left stalled
+			if resumed, err := resumeStalledEvent(r.Context(), event.ID); err != nil {
+				log.Error().Err(err).Str("event_id", event.ID).Msg("failed to check redelivered stripe webhook")
+			} else if resumed {
+				writeWebhookAck(w, "Webhook processed")
+				return
 			}
-			handleSubscriptionDeleted(r.Context(), &sub)
+			log.Info().Str("event_id", event.ID).Msg("duplicate stripe webhook ignored")
+			writeWebhookAck(w, "Webhook already received")
+			return
+		}
 
-		case "invoice.payment_failed":
-			var invoice stripe.Invoice
-			if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
-				log.Error().Err(err).Msg("failed to unmarshal invoice")
-				break
+		// Process through the job queue for retries; fall back to inline processing.
+		// If the process stops before the event is queued, the event stays pending and is
+		// resumed by a redelivery or the stalled event sweep.
+		if err := jobs.EnqueueStripeWebhook(r.Context(), event.ID, string(event.Type), event.Data.Raw); err != nil {
+			log.Debug().Err(err).Str("event_id", event.ID).Msg("processing stripe webhook inline")
+			if err := processStoredEvent(r.Context(), record, true); err != nil {
+				// Failure is recorded on the stored event and can be replayed by an admin
+				log.Error().Err(err).Str("event_id", event.ID).Msg("failed to process stripe webhook")
 			}
-			handlePaymentFailed(r.Context(), &invoice)
-
-		default:
-			log.Debug().Str("event_type", string(event.Type)).Msg("unhandled webhook event type")
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(models.SuccessResponse{
-			Success: true,
-			Message: "Webhook processed",
-		}); err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		}
+		writeWebhookAck(w, "Webhook processed")
+	}
+}
+
+// writeWebhookAck acknowledges receipt of a webhook event
+func writeWebhookAck(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.SuccessResponse{
+		Success: true,
+		Message: message,
+	}); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
 // handleCheckoutSessionCompleted processes successful checkout sessions
-func handleCheckoutSessionCompleted(ctx context.Context, session *stripe.CheckoutSession) {
+func handleCheckoutSessionCompleted(ctx context.Context, session *stripe.CheckoutSession) error {
 	log.Info().
 		Str("session_id", session.ID).
 		Str("customer_id", session.Customer.ID).
@@ -226,16 +237,17 @@ func handleCheckoutSessionCompleted(ctx context.Context, session *stripe.Checkou
 	var user models.User
 	if err := database.DB.WithContext(ctx).Where("stripe_customer_id = ?", session.Customer.ID).First(&user).Error; err != nil {
 		log.Error().Err(err).Str("customer_id", session.Customer.ID).Msg("user not found for customer")
-		return
+		return nil
 	}
 
 	// The subscription will be created/updated via the subscription webhook events
 	// Just log success here
 	log.Info().Uint("user_id", user.ID).Msg("checkout completed for user")
+	return nil
 }
 
 // handleSubscriptionCreated processes new subscription creation
-func handleSubscriptionCreated(ctx context.Context, sub *stripe.Subscription) {
+func handleSubscriptionCreated(ctx context.Context, sub *stripe.Subscription) error {
 	log.Info().
 		Str("subscription_id", sub.ID).
 		Str("customer_id", sub.Customer.ID).
@@ -246,7 +258,7 @@ func handleSubscriptionCreated(ctx context.Context, sub *stripe.Subscription) {
 	owner, err := findCustomerOwner(ctx, sub.Customer.ID)
 	if err != nil {
 		log.Error().Err(err).Str("customer_id", sub.Customer.ID).Msg("customer owner not found")
-		return
+		return nil
 	}
 
 	// Get the price ID from the first item
@@ -255,17 +267,27 @@ func handleSubscriptionCreated(ctx context.Context, sub *stripe.Subscription) {
 		priceID = sub.Items.Data[0].Price.ID
 	}
 
+	// Replayed or redelivered creations must not insert a second record
+	var existing int64
+	if err := database.DB.WithContext(ctx).Model(&models.Subscription{}).Where("stripe_subscription_id = ?", sub.ID).Count(&existing).Error; err != nil {
+		return fmt.Errorf("failed to check existing subscription: %w", err)
+	}
+	if existing > 0 {
+		log.Info().Str("subscription_id", sub.ID).Msg("subscription already recorded")
+		return nil
+	}
+
 	if owner.Org != nil {
 		// Organization subscription
-		handleOrgSubscriptionCreated(ctx, owner.Org, sub, priceID)
-	} else {
-		// User subscription
-		handleUserSubscriptionCreated(ctx, owner.User, sub, priceID)
+		return handleOrgSubscriptionCreated(ctx, owner.Org, sub, priceID)
 	}
+
+	// User subscription
+	return handleUserSubscriptionCreated(ctx, owner.User, sub, priceID)
 }
 
 // handleUserSubscriptionCreated processes user-level subscription creation
-func handleUserSubscriptionCreated(ctx context.Context, user *models.User, sub *stripe.Subscription, priceID string) {
+func handleUserSubscriptionCreated(ctx context.Context, user *models.User, sub *stripe.Subscription, priceID string) error {
 	subscription := models.Subscription{
 		UserID:               user.ID,
 		StripeSubscriptionID: sub.ID,
@@ -274,13 +296,19 @@ func handleUserSubscriptionCreated(ctx context.Context, user *models.User, sub *
 		CurrentPeriodStart:   time.Unix(sub.CurrentPeriodStart, 0).Format(time.RFC3339),
 		CurrentPeriodEnd:     time.Unix(sub.CurrentPeriodEnd, 0).Format(time.RFC3339),
 		CancelAtPeriodEnd:    sub.CancelAtPeriodEnd,
+		TrialEndsAt:          trialEndsAt(sub),
+		Discount:             subscriptionDiscount(ctx, sub),
 		CreatedAt:            time.Now().Format(time.RFC3339),
 		UpdatedAt:            time.Now().Format(time.RFC3339),
 	}
 
 	if err := database.DB.WithContext(ctx).Create(&subscription).Error; err != nil {
 		log.Error().Err(err).Uint("user_id", user.ID).Msg("failed to create subscription record")
-		return
+		return err
+	}
+	recordCouponRedemption(ctx, subscription.Discount.CouponID)
+	if subscription.TrialEndsAt != nil {
+		markTrialUsed(ctx, subscription.UserID)
 	}
 
 	// Update user role to premium if subscription is active
@@ -293,8 +321,14 @@ func handleUserSubscriptionCreated(ctx context.Context, user *models.User, sub *
 	}
 
 	syncUsageLimits(ctx, user.ID, priceID)
+	services.InvalidateEntitlements(ctx, services.UserSubject(user.ID))
 	log.Info().Uint("user_id", user.ID).Msg("subscription created for user")
 
+	message := "Your subscription is now active!"
+	if sub.Status == stripe.SubscriptionStatusTrialing {
+		message = "Your free trial has started!"
+	}
+
 	// Broadcast subscription created event
 	broadcastSubscriptionEvent(
 		user.ID,
@@ -304,17 +338,18 @@ func handleUserSubscriptionCreated(ctx context.Context, user *models.User, sub *
 		priceID,
 		sub.CancelAtPeriodEnd,
 		time.Unix(sub.CurrentPeriodEnd, 0).Format(time.RFC3339),
-		"Your subscription is now active!",
+		message,
 	)
+	return nil
 }
 
 // handleOrgSubscriptionCreated processes organization-level subscription creation
-func handleOrgSubscriptionCreated(ctx context.Context, org *models.Organization, sub *stripe.Subscription, priceID string) {
+func handleOrgSubscriptionCreated(ctx context.Context, org *models.Organization, sub *stripe.Subscription, priceID string) error {
 	// Get the org owner to set as billing contact
 	var owner models.OrganizationMember
 	if err := database.DB.WithContext(ctx).Where("organization_id = ? AND role = ?", org.ID, models.OrgRoleOwner).First(&owner).Error; err != nil {
 		log.Error().Err(err).Uint("org_id", org.ID).Msg("org owner not found")
-		return
+		return err
 	}
 
 	orgID := org.ID
@@ -327,13 +362,20 @@ func handleOrgSubscriptionCreated(ctx context.Context, org *models.Organization,
 		CurrentPeriodStart:   time.Unix(sub.CurrentPeriodStart, 0).Format(time.RFC3339),
 		CurrentPeriodEnd:     time.Unix(sub.CurrentPeriodEnd, 0).Format(time.RFC3339),
 		CancelAtPeriodEnd:    sub.CancelAtPeriodEnd,
+		TrialEndsAt:          trialEndsAt(sub),
+		SeatQuantity:         seatQuantity(sub),
+		Discount:             subscriptionDiscount(ctx, sub),
 		CreatedAt:            time.Now().Format(time.RFC3339),
 		UpdatedAt:            time.Now().Format(time.RFC3339),
 	}
 
 	if err := database.DB.WithContext(ctx).Create(&subscription).Error; err != nil {
 		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to create org subscription record")
-		return
+		return err
+	}
+	recordCouponRedemption(ctx, subscription.Discount.CouponID)
+	if subscription.TrialEndsAt != nil {
+		markTrialUsed(ctx, subscription.UserID)
 	}
 
 	// Update org plan based on price ID
@@ -344,9 +386,22 @@ func handleOrgSubscriptionCreated(ctx context.Context, org *models.Organization,
 	}).Error; err != nil {
 		log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to update org plan")
 	}
+	services.InvalidateEntitlements(ctx, services.OrgSubject(org.ID))
+
+	// Checkout bills a single seat; bring the quantity up to the current member count
+	if jobs.IsAvailable() {
+		if err := jobs.EnqueueSeatQuantitySync(ctx, org.ID); err != nil {
+			log.Error().Err(err).Uint("org_id", org.ID).Msg("failed to enqueue seat quantity sync")
+		}
+	}
 
 	log.Info().Uint("org_id", org.ID).Str("plan", string(newPlan)).Msg("subscription created for organization")
 
+	message := "Organization subscription is now active!"
+	if sub.Status == stripe.SubscriptionStatusTrialing {
+		message = "Organization free trial has started!"
+	}
+
 	// Broadcast subscription created event to all org members
 	broadcastOrgSubscriptionEvent(
 		ctx,
@@ -357,14 +412,35 @@ func handleOrgSubscriptionCreated(ctx context.Context, org *models.Organization,
 		priceID,
 		sub.CancelAtPeriodEnd,
 		time.Unix(sub.CurrentPeriodEnd, 0).Format(time.RFC3339),
-		"Organization subscription is now active!",
+		message,
 	)
+	return nil
+}
+
+// seatQuantity returns the quantity billed on a subscription's first item
+func seatQuantity(sub *stripe.Subscription) int64 {
+	if sub.Items == nil || len(sub.Items.Data) == 0 {
+		return 0
+	}
+	return sub.Items.Data[0].Quantity
+}
+
+// portalSeatQuantity returns the seat quantity when it was set outside the member count
+// sync, i.e. by the customer in the billing portal. Synced quantities are recorded under
+// SeatSyncMetadataKey; a quantity that differs from it was changed elsewhere.
+func portalSeatQuantity(sub *stripe.Subscription) (int64, bool) {
+	quantity := seatQuantity(sub)
+	if quantity <= 0 || sub.Metadata[SeatSyncMetadataKey] == strconv.FormatInt(quantity, 10) {
+		return 0, false
+	}
+	return quantity, true
 }
 
 // getPlanFromPriceID maps Stripe price IDs to organization plans
-// Uses environment-configured price IDs to determine tier:
+// Uses environment-configured price IDs and the entitlements plan catalog to determine tier:
 // - STRIPE_ENTERPRISE_PRICE_ID -> Enterprise
 // - STRIPE_PREMIUM_PRICE_ID -> Pro
+// - Price IDs listed in the plan catalog -> that plan
 // - Any other non-empty price ID -> Pro (fallback for paid plans)
 // - Empty price ID -> Free
 func getPlanFromPriceID(priceID string) models.OrganizationPlan {
@@ -384,12 +460,16 @@ func getPlanFromPriceID(priceID string) models.OrganizationPlan {
 		return models.OrgPlanPro
 	}
 
+	if plan, ok := services.GetEntitlementsService().Catalog().PlanForPrice(priceID); ok {
+		return models.OrganizationPlan(plan)
+	}
+
 	// Default: any paid subscription without specific mapping is Pro
 	return models.OrgPlanPro
 }
 
 // handleSubscriptionUpdated processes subscription updates
-func handleSubscriptionUpdated(ctx context.Context, sub *stripe.Subscription) {
+func handleSubscriptionUpdated(ctx context.Context, sub *stripe.Subscription) error {
 	log.Info().
 		Str("subscription_id", sub.ID).
 		Str("status", string(sub.Status)).
@@ -399,7 +479,7 @@ func handleSubscriptionUpdated(ctx context.Context, sub *stripe.Subscription) {
 	var subscription models.Subscription
 	if err := database.DB.WithContext(ctx).Where("stripe_subscription_id = ?", sub.ID).First(&subscription).Error; err != nil {
 		log.Error().Err(err).Str("subscription_id", sub.ID).Msg("subscription not found")
-		return
+		return err
 	}
 
 	// Get the price ID from the first item (may have changed on plan upgrade/downgrade)
@@ -414,6 +494,14 @@ func handleSubscriptionUpdated(ctx context.Context, sub *stripe.Subscription) {
 	subscription.CurrentPeriodStart = time.Unix(sub.CurrentPeriodStart, 0).Format(time.RFC3339)
 	subscription.CurrentPeriodEnd = time.Unix(sub.CurrentPeriodEnd, 0).Format(time.RFC3339)
 	subscription.CancelAtPeriodEnd = sub.CancelAtPeriodEnd
+	subscription.TrialEndsAt = trialEndsAt(sub)
+	subscription.Discount = subscriptionDiscount(ctx, sub)
+	if subscription.IsOrganizationSubscription() {
+		subscription.SeatQuantity = seatQuantity(sub)
+		if quantity, ok := portalSeatQuantity(sub); ok {
+			subscription.MinSeatQuantity = quantity
+		}
+	}
 	subscription.UpdatedAt = time.Now().Format(time.RFC3339)
 
 	if sub.CanceledAt > 0 {
@@ -422,12 +510,26 @@ func handleSubscriptionUpdated(ctx context.Context, sub *stripe.Subscription) {
 
 	if err := database.DB.WithContext(ctx).Save(&subscription).Error; err != nil {
 		log.Error().Err(err).Str("subscription_id", sub.ID).Msg("failed to update subscription")
-		return
+		return err
+	}
+
+	// A paid subscription ends any dunning; one that is still unpaid after its grace
+	// period keeps the downgrade instead of being restored by this update. A subscription
+	// paused because its trial ended without a payment method is downgraded too.
+	if sub.Status == stripe.SubscriptionStatusActive || sub.Status == stripe.SubscriptionStatusTrialing {
+		if err := resolveDunning(ctx, sub.ID); err != nil {
+			log.Error().Err(err).Str("subscription_id", sub.ID).Msg("failed to resolve dunning")
+		}
 	}
+	downgraded := sub.Status == stripe.SubscriptionStatusPaused ||
+		((sub.Status == stripe.SubscriptionStatusPastDue || sub.Status == stripe.SubscriptionStatusUnpaid) &&
+			hasExpiredDunning(ctx, subscription.ID))
 
 	// Build status message
 	var message string
-	if sub.CancelAtPeriodEnd {
+	if sub.Status == stripe.SubscriptionStatusPaused {
+		message = "Your free trial has ended. Add a payment method to restore your plan."
+	} else if sub.CancelAtPeriodEnd {
 		message = "Subscription will be canceled at the end of the billing period"
 	} else if sub.Status == stripe.SubscriptionStatusPastDue {
 		message = "Payment failed - please update your payment method"
@@ -439,10 +541,14 @@ func handleSubscriptionUpdated(ctx context.Context, sub *stripe.Subscription) {
 	if subscription.OrganizationID != nil && *subscription.OrganizationID > 0 {
 		// Organization subscription - update org plan
 		newPlan := getPlanFromPriceID(priceID)
+		if downgraded {
+			newPlan = models.OrgPlanFree
+		}
 		if err := database.DB.WithContext(ctx).Model(&models.Organization{}).Where("id = ?", *subscription.OrganizationID).
 			Update("plan", newPlan).Error; err != nil {
 			log.Error().Err(err).Uint("org_id", *subscription.OrganizationID).Msg("failed to update org plan")
 		}
+		services.InvalidateEntitlements(ctx, services.OrgSubject(*subscription.OrganizationID))
 		log.Info().Uint("org_id", *subscription.OrganizationID).Str("plan", string(newPlan)).Msg("subscription updated for organization")
 
 		// Broadcast to org members
@@ -459,8 +565,14 @@ func handleSubscriptionUpdated(ctx context.Context, sub *stripe.Subscription) {
 		)
 	} else {
 		// User subscription
-		syncUserRole(ctx, subscription.UserID, sub.Status)
-		syncUsageLimits(ctx, subscription.UserID, priceID)
+		if downgraded {
+			syncUserRole(ctx, subscription.UserID, stripe.SubscriptionStatusUnpaid)
+			syncUsageLimits(ctx, subscription.UserID, "")
+		} else {
+			syncUserRole(ctx, subscription.UserID, sub.Status)
+			syncUsageLimits(ctx, subscription.UserID, priceID)
+		}
+		services.InvalidateEntitlements(ctx, services.UserSubject(subscription.UserID))
 		log.Info().Uint("user_id", subscription.UserID).Msg("subscription updated for user")
 
 		// Broadcast to user
@@ -475,10 +587,11 @@ func handleSubscriptionUpdated(ctx context.Context, sub *stripe.Subscription) {
 			message,
 		)
 	}
+	return nil
 }
 
 // handleSubscriptionDeleted processes subscription cancellation/deletion
-func handleSubscriptionDeleted(ctx context.Context, sub *stripe.Subscription) {
+func handleSubscriptionDeleted(ctx context.Context, sub *stripe.Subscription) error {
 	log.Info().
 		Str("subscription_id", sub.ID).
 		Msg("subscription deleted")
@@ -487,17 +600,37 @@ func handleSubscriptionDeleted(ctx context.Context, sub *stripe.Subscription) {
 	var subscription models.Subscription
 	if err := database.DB.WithContext(ctx).Where("stripe_subscription_id = ?", sub.ID).First(&subscription).Error; err != nil {
 		log.Error().Err(err).Str("subscription_id", sub.ID).Msg("subscription not found")
-		return
+		return err
 	}
 
 	// Update subscription record
+	wasTrialing := subscription.IsTrialing()
 	subscription.Status = models.SubscriptionStatusCanceled
 	subscription.CanceledAt = time.Now().Format(time.RFC3339)
 	subscription.UpdatedAt = time.Now().Format(time.RFC3339)
 
 	if err := database.DB.WithContext(ctx).Save(&subscription).Error; err != nil {
 		log.Error().Err(err).Str("subscription_id", sub.ID).Msg("failed to update subscription")
-		return
+		return err
+	}
+
+	// The subscription is gone, so any open dunning case ends with it
+	if err := database.DB.WithContext(ctx).Model(&models.Dunning{}).
+		Where("subscription_id = ? AND status = ?", subscription.ID, models.DunningStatusActive).
+		Updates(map[string]any{
+			"status":           models.DunningStatusExpired,
+			"resolved_at":      subscription.CanceledAt,
+			"next_reminder_at": nil,
+			"updated_at":       subscription.UpdatedAt,
+		}).Error; err != nil {
+		log.Error().Err(err).Str("subscription_id", sub.ID).Msg("failed to close dunning")
+	}
+
+	orgMessage := "Subscription has been canceled. You are now on the free plan."
+	userMessage := "Your subscription has been canceled."
+	if wasTrialing {
+		orgMessage = "The free trial has ended. You are now on the free plan."
+		userMessage = "Your free trial has ended. You are now on the free plan."
 	}
 
 	// Handle org vs user subscription deletion
@@ -510,6 +643,7 @@ func handleSubscriptionDeleted(ctx context.Context, sub *stripe.Subscription) {
 			}).Error; err != nil {
 			log.Error().Err(err).Uint("org_id", *subscription.OrganizationID).Msg("failed to downgrade org plan")
 		}
+		services.InvalidateEntitlements(ctx, services.OrgSubject(*subscription.OrganizationID))
 		log.Info().Uint("org_id", *subscription.OrganizationID).Msg("subscription deleted for organization")
 
 		// Broadcast to org members
@@ -522,12 +656,13 @@ func handleSubscriptionDeleted(ctx context.Context, sub *stripe.Subscription) {
 			"",
 			false,
 			"",
-			"Subscription has been canceled. You are now on the free plan.",
+			orgMessage,
 		)
 	} else {
 		// User subscription
 		syncUserRole(ctx, subscription.UserID, stripe.SubscriptionStatusCanceled)
 		syncUsageLimits(ctx, subscription.UserID, "")
+		services.InvalidateEntitlements(ctx, services.UserSubject(subscription.UserID))
 		log.Info().Uint("user_id", subscription.UserID).Msg("subscription deleted for user")
 
 		// Broadcast to user
@@ -539,27 +674,28 @@ func handleSubscriptionDeleted(ctx context.Context, sub *stripe.Subscription) {
 			"",
 			false,
 			"",
-			"Your subscription has been canceled.",
+			userMessage,
 		)
 	}
+	return nil
 }
 
 // handlePaymentFailed processes failed payment events
-func handlePaymentFailed(ctx context.Context, invoice *stripe.Invoice) {
+func handlePaymentFailed(ctx context.Context, invoice *stripe.Invoice) error {
 	log.Warn().
 		Str("invoice_id", invoice.ID).
 		Str("customer_id", invoice.Customer.ID).
 		Msg("payment failed")
 
 	if invoice.Subscription == nil {
-		return
+		return nil
 	}
 
 	// Find subscription by Stripe subscription ID
 	var subscription models.Subscription
 	if err := database.DB.WithContext(ctx).Where("stripe_subscription_id = ?", invoice.Subscription.ID).First(&subscription).Error; err != nil {
 		log.Error().Err(err).Str("subscription_id", invoice.Subscription.ID).Msg("subscription not found")
-		return
+		return err
 	}
 
 	// Update subscription status to past_due
@@ -568,11 +704,20 @@ func handlePaymentFailed(ctx context.Context, invoice *stripe.Invoice) {
 
 	if err := database.DB.WithContext(ctx).Save(&subscription).Error; err != nil {
 		log.Error().Err(err).Str("subscription_id", invoice.Subscription.ID).Msg("failed to update subscription")
-		return
+		return err
 	}
 
 	log.Warn().Uint("user_id", subscription.UserID).Msg("subscription marked as past_due")
 
+	// Open (or continue) the dunning case; the plan is kept until the grace period ends
+	dunning, err := startDunning(ctx, &subscription, invoice)
+	if err != nil {
+		log.Error().Err(err).Str("subscription_id", invoice.Subscription.ID).Msg("failed to start dunning")
+		return err
+	}
+	graceEnds, _ := time.Parse(time.RFC3339, dunning.GraceEndsAt)
+	message := fmt.Sprintf("Payment failed. Please update your payment method by %s to avoid service interruption.", graceEnds.Format("January 2, 2006"))
+
 	// Broadcast payment failed event
 	if subscription.OrganizationID != nil && *subscription.OrganizationID > 0 {
 		broadcastOrgSubscriptionEvent(
@@ -583,8 +728,8 @@ func handlePaymentFailed(ctx context.Context, invoice *stripe.Invoice) {
 			"",
 			subscription.StripePriceID,
 			false,
-			"",
-			"Payment failed. Please update your payment method to avoid service interruption.",
+			dunning.GraceEndsAt,
+			message,
 		)
 	} else {
 		broadcastSubscriptionEvent(
@@ -594,10 +739,11 @@ func handlePaymentFailed(ctx context.Context, invoice *stripe.Invoice) {
 			"premium",
 			subscription.StripePriceID,
 			false,
-			"",
-			"Payment failed. Please update your payment method to avoid service interruption.",
+			dunning.GraceEndsAt,
+			message,
 		)
 	}
+	return nil
 }
 
 // syncUserRole updates the user's role based on their subscription status
@@ -312,6 +312,47 @@ func TestWebhookEdgeCases_Integration(t *testing.T) {
 	})
 }
 
+func TestSeatQuantity(t *testing.T) {
+	if got := seatQuantity(&stripe.Subscription{}); got != 0 {
+		t.Errorf("seatQuantity() = %d, want 0 without items", got)
+	}
+
+	sub := &stripe.Subscription{
+		Items: &stripe.SubscriptionItemList{
+			Data: []*stripe.SubscriptionItem{{Quantity: 12}},
+		},
+	}
+	if got := seatQuantity(sub); got != 12 {
+		t.Errorf("seatQuantity() = %d, want 12", got)
+	}
+}
+
+func TestPortalSeatQuantity(t *testing.T) {
+	sub := &stripe.Subscription{
+		Items: &stripe.SubscriptionItemList{
+			Data: []*stripe.SubscriptionItem{{Quantity: 12}},
+		},
+	}
+
+	if got, ok := portalSeatQuantity(sub); !ok || got != 12 {
+		t.Errorf("portalSeatQuantity() = %d, %v, want 12 for a quantity that was never synced", got, ok)
+	}
+
+	sub.Metadata = map[string]string{SeatSyncMetadataKey: "12"}
+	if _, ok := portalSeatQuantity(sub); ok {
+		t.Error("portalSeatQuantity() should ignore the quantity set by the member count sync")
+	}
+
+	sub.Items.Data[0].Quantity = 20
+	if got, ok := portalSeatQuantity(sub); !ok || got != 20 {
+		t.Errorf("portalSeatQuantity() = %d, %v, want 20 after a billing portal change", got, ok)
+	}
+
+	if _, ok := portalSeatQuantity(&stripe.Subscription{}); ok {
+		t.Error("portalSeatQuantity() should ignore subscriptions without items")
+	}
+}
+
 func TestGetPlanFromPriceID(t *testing.T) {
 	// Test without env vars set (default behavior)
 	t.Run("without env vars", func(t *testing.T) {
@@ -311,6 +311,28 @@ func TestHandleOrgSubscriptionUpdated_Integration(t *testing.T) {
 			t.Errorf("Expected price ID 'price_new_tier_xyz', got: %s", dbSub.StripePriceID)
 		}
 	})
+
+	t.Run("records billed seat quantity", func(t *testing.T) {
+		org := createTestOrganization(t, "seat-quantity-org", "cus_org_seats_123")
+
+		var owner models.OrganizationMember
+		database.DB.Where("organization_id = ? AND role = ?", org.ID, models.OrgRoleOwner).First(&owner)
+
+		createTestOrgSubscription(t, org.ID, owner.UserID, "sub_org_seats", models.SubscriptionStatusActive)
+
+		stripeSub := fakeStripeSubscription("sub_org_seats", "price_org_test_123", stripe.SubscriptionStatusActive, false)
+		stripeSub.Items.Data[0].Quantity = 7
+
+		if err := handleSubscriptionUpdated(context.Background(), stripeSub); err != nil {
+			t.Fatalf("handleSubscriptionUpdated() error = %v", err)
+		}
+
+		var dbSub models.Subscription
+		database.DB.Where("stripe_subscription_id = ?", "sub_org_seats").First(&dbSub)
+		if dbSub.SeatQuantity != 7 {
+			t.Errorf("Expected seat quantity 7, got: %d", dbSub.SeatQuantity)
+		}
+	})
 }
 
 func TestHandleOrgSubscriptionDeleted_Integration(t *testing.T) {
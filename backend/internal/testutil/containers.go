@@ -144,6 +144,7 @@ func runMigrations(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.User{},
 		&models.TokenBlacklist{},
+		&models.OrgTokenRevocation{},
 		&models.Organization{},
 		&models.OrganizationMember{},
 		&models.OrganizationInvitation{},
@@ -152,7 +153,19 @@ func runMigrations(db *gorm.DB) error {
 		&models.AuditLog{},
 		&models.FeatureFlag{},
 		&models.UserFeatureFlag{},
+		&models.OrganizationFeatureFlag{},
+		&models.StripeEvent{},
+		&models.UsageReport{},
+		&models.Invoice{},
+		&models.Dunning{},
+		&models.PlanEntitlement{},
+		&models.Coupon{},
+		&models.QuotaExemption{},
+		&models.UsageCost{},
 		&models.File{},
+		&models.FileChunk{},
+		&models.FileUpload{},
+		&models.FileUploadChunk{},
 		&models.UserAPIKey{},
 		&models.UserPreferences{},
 		&models.UserTwoFactor{},
@@ -112,6 +112,7 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 		migrateErr = db.AutoMigrate(
 			&models.User{},
 			&models.TokenBlacklist{},
+			&models.OrgTokenRevocation{},
 			&models.Organization{},
 			&models.OrganizationMember{},
 			&models.OrganizationInvitation{},
@@ -120,7 +121,19 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 			&models.AuditLog{},
 			&models.FeatureFlag{},
 			&models.UserFeatureFlag{},
+			&models.OrganizationFeatureFlag{},
+			&models.StripeEvent{},
+			&models.UsageReport{},
+			&models.Invoice{},
+			&models.Dunning{},
+			&models.PlanEntitlement{},
+			&models.Coupon{},
+			&models.QuotaExemption{},
+			&models.UsageCost{},
 			&models.File{},
+			&models.FileChunk{},
+			&models.FileUpload{},
+			&models.FileUploadChunk{},
 			&models.UserAPIKey{},
 			&models.UserPreferences{},
 			&models.UserTwoFactor{},
@@ -136,6 +149,9 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 			&models.UsageEvent{},
 			&models.UsagePeriod{},
 			&models.UsageAlert{},
+			&models.UsageAlertPreference{},
+			&models.UsageHourlyRollup{},
+			&models.UsageDailyRollup{},
 		)
 	})
 	if migrateErr != nil {
@@ -234,15 +250,31 @@ func TruncateTables(t *testing.T, db *gorm.DB, tables ...string) {
 			"user_two_factors",
 			"user_preferences",
 			"user_feature_flags",
+			"organization_feature_flags",
+			"stripe_events",
+			"usage_reports",
+			"dunnings",
+			"plan_entitlements",
+			"coupons",
+			"quota_exemptions",
+			"usage_costs",
+			"usage_rollups_hourly",
+			"usage_rollups_daily",
+			"usage_alert_preferences",
+			"invoices",
 			"feature_flags",
 			"audit_logs",
 			"user_api_keys",
+			"file_upload_chunks",
+			"file_uploads",
+			"file_chunks",
 			"files",
 			"oauth_providers",
 			"subscriptions",
 			"organization_invitations",
 			"organization_members",
 			"organizations",
+			"org_token_revocations",
 			"token_blacklist",
 			"users",
 		}
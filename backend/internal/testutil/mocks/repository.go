@@ -8,6 +8,8 @@ import (
 
 	"react-golang-starter/internal/models"
 	"react-golang-starter/internal/repository"
+
+	"gorm.io/gorm"
 )
 
 // Common errors for mocks
@@ -391,6 +393,7 @@ type MockOrganizationRepository struct {
 	FindByIDErr                   error
 	FindByStripeCustomerIDErr     error
 	FindByStripeSubscriptionIDErr error
+	FindDueForDeletionErr         error
 	CountBySlugErr                error
 	CreateErr                     error
 	UpdateErr                     error
@@ -404,6 +407,7 @@ type MockOrganizationRepository struct {
 	FindByIDCalls                   int
 	FindByStripeCustomerIDCalls     int
 	FindByStripeSubscriptionIDCalls int
+	FindDueForDeletionCalls         int
 	CountBySlugCalls                int
 	CreateCalls                     int
 	UpdateCalls                     int
@@ -494,6 +498,22 @@ func (m *MockOrganizationRepository) FindByStripeSubscriptionID(ctx context.Cont
 	return nil, ErrNotFound
 }
 
+func (m *MockOrganizationRepository) FindDueForDeletion(ctx context.Context, before time.Time) ([]models.Organization, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.FindDueForDeletionCalls++
+	if m.FindDueForDeletionErr != nil {
+		return nil, m.FindDueForDeletionErr
+	}
+	var result []models.Organization
+	for _, org := range m.orgs {
+		if org.DeletionScheduledAt != nil && !org.DeletionScheduledAt.After(before) {
+			result = append(result, *org)
+		}
+	}
+	return result, nil
+}
+
 func (m *MockOrganizationRepository) CountBySlug(ctx context.Context, slug string) (int64, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -1168,7 +1188,8 @@ func (m *MockSubscriptionRepository) FindByOrgID(ctx context.Context, orgID uint
 	if sub, ok := m.subs[orgID]; ok {
 		return sub, nil
 	}
-	return nil, ErrNotFound
+	// Match the GORM repository so callers can treat a missing subscription as none
+	return nil, gorm.ErrRecordNotFound
 }
 
 func (m *MockSubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) error {
@@ -0,0 +1,1066 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+// FakeStripeSecretKey is the API key the fake Stripe server accepts.
+const FakeStripeSecretKey = "sk_test_fake"
+
+// FakeStripeWebhookSecret is the default secret used to sign fake webhooks.
+const FakeStripeWebhookSecret = "whsec_fake"
+
+// FakeStripeServer is an in-process stand-in for the Stripe API. It keeps
+// customers, prices, checkout sessions, subscriptions and invoices in memory,
+// speaks the form-encoded wire format stripe-go uses, and emits signed webhook
+// events so the real signature checks and handlers run end-to-end.
+type FakeStripeServer struct {
+	// Server is the test server; nil when the fake is served through Handler
+	Server *httptest.Server
+	// BaseURL is the externally reachable URL used in checkout, portal and invoice links
+	BaseURL string
+
+	// WebhookURL receives emitted events; empty keeps them pending
+	WebhookURL string
+	// WebhookSecret signs delivered events
+	WebhookSecret string
+
+	mu               sync.Mutex
+	seq              int
+	now              func() time.Time
+	customers        map[string]*FakeStripeCustomer
+	prices           map[string]*FakeStripePrice
+	sessions         map[string]*FakeStripeCheckoutSession
+	subscriptions    map[string]*FakeStripeSubscription
+	invoices         map[string]*FakeStripeInvoice
+	portalReturnURLs map[string]string
+	pending          []json.RawMessage
+	delivered        []json.RawMessage
+	requests         []string
+}
+
+// FakeStripeCustomer is a customer held by the fake server.
+type FakeStripeCustomer struct {
+	ID       string
+	Email    string
+	Name     string
+	Metadata map[string]string
+	Created  int64
+}
+
+// FakeStripePrice is a recurring price held by the fake server.
+type FakeStripePrice struct {
+	ID         string
+	ProductID  string
+	UnitAmount int64
+	Currency   string
+	Interval   string
+}
+
+// FakeStripeCheckoutSession is a checkout session held by the fake server.
+type FakeStripeCheckoutSession struct {
+	ID             string
+	CustomerID     string
+	PriceID        string
+	Quantity       int64
+	TrialDays      int64
+	CouponID       string
+	SuccessURL     string
+	CancelURL      string
+	Status         string
+	SubscriptionID string
+	Created        int64
+}
+
+// FakeStripeSubscription is a single-item subscription held by the fake server.
+type FakeStripeSubscription struct {
+	ID                 string
+	CustomerID         string
+	ItemID             string
+	PriceID            string
+	Quantity           int64
+	Status             string
+	CurrentPeriodStart int64
+	CurrentPeriodEnd   int64
+	TrialEnd           int64
+	CancelAtPeriodEnd  bool
+	CanceledAt         int64
+	CouponID           string
+	Created            int64
+}
+
+// FakeStripeInvoice is an invoice held by the fake server.
+type FakeStripeInvoice struct {
+	ID             string
+	Number         string
+	CustomerID     string
+	SubscriptionID string
+	Status         string
+	Currency       string
+	Amount         int64
+	AmountPaid     int64
+	PeriodStart    int64
+	PeriodEnd      int64
+	Created        int64
+}
+
+// NewFakeStripeServer starts a fake Stripe server seeded with the default
+// price IDs the billing code knows about. Call Close when done.
+func NewFakeStripeServer() *FakeStripeServer {
+	f := NewFakeStripe()
+	f.Server = httptest.NewServer(f.Handler())
+	f.BaseURL = f.Server.URL
+	return f
+}
+
+// NewFakeStripe creates the fake Stripe state without starting a server, for
+// serving Handler on a fixed address during local development.
+func NewFakeStripe() *FakeStripeServer {
+	f := &FakeStripeServer{
+		WebhookSecret:    FakeStripeWebhookSecret,
+		now:              time.Now,
+		customers:        make(map[string]*FakeStripeCustomer),
+		prices:           make(map[string]*FakeStripePrice),
+		sessions:         make(map[string]*FakeStripeCheckoutSession),
+		subscriptions:    make(map[string]*FakeStripeSubscription),
+		invoices:         make(map[string]*FakeStripeInvoice),
+		portalReturnURLs: make(map[string]string),
+	}
+
+	for _, p := range []FakeStripePrice{
+		{ID: "price_pro_monthly", ProductID: "prod_pro", UnitAmount: 2900, Currency: "usd", Interval: "month"},
+		{ID: "price_pro_yearly", ProductID: "prod_pro", UnitAmount: 29000, Currency: "usd", Interval: "year"},
+		{ID: "price_enterprise_monthly", ProductID: "prod_enterprise", UnitAmount: 9900, Currency: "usd", Interval: "month"},
+		{ID: "price_enterprise_yearly", ProductID: "prod_enterprise", UnitAmount: 99000, Currency: "usd", Interval: "year"},
+	} {
+		f.AddPrice(p)
+	}
+	return f
+}
+
+// Handler returns the fake API. Besides the /v1 endpoints it serves the hosted
+// checkout page: visiting a session's URL completes the checkout, delivers the
+// resulting webhooks and redirects to the success URL.
+func (f *FakeStripeServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/", f.serveHTTP)
+	mux.HandleFunc("GET /checkout/{id}", f.serveCheckoutPage)
+	mux.HandleFunc("GET /portal/{id}", f.servePortalPage)
+	return mux
+}
+
+// StartFakeStripe starts a fake Stripe server, points the stripe-go API
+// backend and key at it, and restores both when the test completes.
+func StartFakeStripe(t *testing.T) *FakeStripeServer {
+	t.Helper()
+
+	f := NewFakeStripeServer()
+	oldKey := stripe.Key
+	oldBackend := stripe.GetBackend(stripe.APIBackend)
+
+	f.Install()
+	t.Cleanup(func() {
+		stripe.Key = oldKey
+		stripe.SetBackend(stripe.APIBackend, oldBackend)
+		f.Close()
+	})
+	return f
+}
+
+// Install points the global stripe-go API backend and key at the fake server.
+func (f *FakeStripeServer) Install() {
+	stripe.Key = FakeStripeSecretKey
+	stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		URL:               stripe.String(f.URL()),
+		MaxNetworkRetries: stripe.Int64(0),
+		LeveledLogger:     &stripe.LeveledLogger{Level: stripe.LevelNull},
+	}))
+}
+
+// URL returns the base URL of the fake server.
+func (f *FakeStripeServer) URL() string {
+	return f.BaseURL
+}
+
+// Close shuts down the fake server.
+func (f *FakeStripeServer) Close() {
+	if f.Server != nil {
+		f.Server.Close()
+	}
+}
+
+// SetClock overrides the time used for created and period timestamps.
+func (f *FakeStripeServer) SetClock(now func() time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// AddPrice registers a price the fake server will accept.
+func (f *FakeStripeServer) AddPrice(p FakeStripePrice) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p.Currency == "" {
+		p.Currency = "usd"
+	}
+	if p.Interval == "" {
+		p.Interval = "month"
+	}
+	f.prices[p.ID] = &p
+}
+
+// Customer returns a copy of a customer, or nil if it does not exist.
+func (f *FakeStripeServer) Customer(id string) *FakeStripeCustomer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.customers[id]; ok {
+		copied := *c
+		return &copied
+	}
+	return nil
+}
+
+// Subscription returns a copy of a subscription, or nil if it does not exist.
+func (f *FakeStripeServer) Subscription(id string) *FakeStripeSubscription {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.subscriptions[id]; ok {
+		copied := *s
+		return &copied
+	}
+	return nil
+}
+
+// CheckoutSession returns a copy of a checkout session, or nil if it does not exist.
+func (f *FakeStripeServer) CheckoutSession(id string) *FakeStripeCheckoutSession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.sessions[id]; ok {
+		copied := *s
+		return &copied
+	}
+	return nil
+}
+
+// Requests returns the "METHOD /path" of every API request received so far.
+func (f *FakeStripeServer) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// ============ Simulated customer actions ============
+
+// CompleteCheckout simulates the customer finishing a checkout session: it
+// creates the subscription and its first invoice and queues the same events
+// Stripe sends. It returns the new subscription ID.
+func (f *FakeStripeServer) CompleteCheckout(sessionID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cs, ok := f.sessions[sessionID]
+	if !ok {
+		return "", fmt.Errorf("no such checkout session: %s", sessionID)
+	}
+	if cs.Status != "open" {
+		return "", fmt.Errorf("checkout session %s is %s", sessionID, cs.Status)
+	}
+
+	sub := f.newSubscriptionLocked(cs.CustomerID, cs.PriceID, cs.Quantity, cs.TrialDays, cs.CouponID)
+	cs.Status = "complete"
+	cs.SubscriptionID = sub.ID
+
+	f.emitLocked("checkout.session.completed", f.checkoutSessionJSON(cs))
+	f.emitLocked("customer.subscription.created", f.subscriptionJSON(sub))
+	f.invoiceSubscriptionLocked(sub, true)
+	return sub.ID, nil
+}
+
+// RenewSubscription advances a subscription to its next billing period and
+// bills it. A failed payment moves the subscription to past_due.
+func (f *FakeStripeServer) RenewSubscription(subscriptionID string, paymentSucceeds bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sub, ok := f.subscriptions[subscriptionID]
+	if !ok {
+		return fmt.Errorf("no such subscription: %s", subscriptionID)
+	}
+
+	if sub.CancelAtPeriodEnd {
+		sub.Status = string(stripe.SubscriptionStatusCanceled)
+		sub.CanceledAt = f.now().Unix()
+		f.emitLocked("customer.subscription.deleted", f.subscriptionJSON(sub))
+		return nil
+	}
+
+	length := sub.CurrentPeriodEnd - sub.CurrentPeriodStart
+	sub.CurrentPeriodStart = sub.CurrentPeriodEnd
+	sub.CurrentPeriodEnd = sub.CurrentPeriodStart + length
+	sub.TrialEnd = 0
+	if paymentSucceeds {
+		sub.Status = string(stripe.SubscriptionStatusActive)
+	} else {
+		sub.Status = string(stripe.SubscriptionStatusPastDue)
+	}
+
+	f.invoiceSubscriptionLocked(sub, paymentSucceeds)
+	f.emitLocked("customer.subscription.updated", f.subscriptionJSON(sub))
+	return nil
+}
+
+// ============ Webhook emission ============
+
+// Emit queues a webhook event of the given type carrying object as its data.
+func (f *FakeStripeServer) Emit(eventType string, object map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.emitLocked(eventType, object)
+}
+
+// DeliveredEvents returns the event payloads accepted by the webhook endpoint.
+func (f *FakeStripeServer) DeliveredEvents() []json.RawMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]json.RawMessage(nil), f.delivered...)
+}
+
+// PendingEvents returns the queued event payloads that have not been delivered.
+func (f *FakeStripeServer) PendingEvents() []json.RawMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]json.RawMessage(nil), f.pending...)
+}
+
+// DeliverWebhooks posts every pending event to WebhookURL in order, signed
+// with WebhookSecret. Delivery stops at the first non-2xx response; that
+// event and the rest stay pending.
+func (f *FakeStripeServer) DeliverWebhooks(ctx context.Context) error {
+	f.mu.Lock()
+	url, secret := f.WebhookURL, f.WebhookSecret
+	f.mu.Unlock()
+
+	if url == "" {
+		return fmt.Errorf("webhook URL not configured")
+	}
+
+	for {
+		f.mu.Lock()
+		if len(f.pending) == 0 {
+			f.mu.Unlock()
+			return nil
+		}
+		payload := f.pending[0]
+		f.mu.Unlock()
+
+		req, err := SignedWebhookRequest(ctx, url, payload, secret)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("deliver webhook: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook endpoint returned %d", resp.StatusCode)
+		}
+
+		f.mu.Lock()
+		f.pending = f.pending[1:]
+		f.delivered = append(f.delivered, payload)
+		f.mu.Unlock()
+	}
+}
+
+// SignedWebhookRequest builds a POST carrying payload with a valid
+// Stripe-Signature header for secret.
+func SignedWebhookRequest(ctx context.Context, url string, payload []byte, secret string) (*http.Request, error) {
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload: payload,
+		Secret:  secret,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Stripe-Signature", signed.Header)
+	return req, nil
+}
+
+func (f *FakeStripeServer) emitLocked(eventType string, object map[string]any) {
+	event := map[string]any{
+		"id":               f.nextIDLocked("evt"),
+		"object":           "event",
+		"api_version":      stripe.APIVersion,
+		"created":          f.now().Unix(),
+		"type":             eventType,
+		"livemode":         false,
+		"pending_webhooks": 1,
+		"data":             map[string]any{"object": object},
+	}
+	payload, _ := json.Marshal(event)
+	f.pending = append(f.pending, payload)
+}
+
+// ============ HTTP API ============
+
+func (f *FakeStripeServer) serveCheckoutPage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	cs := f.CheckoutSession(id)
+	if cs == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if _, err := f.CompleteCheckout(id); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if f.WebhookURL != "" {
+		if err := f.DeliverWebhooks(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	http.Redirect(w, r, cs.SuccessURL, http.StatusSeeOther)
+}
+
+func (f *FakeStripeServer) servePortalPage(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	returnURL, ok := f.portalReturnURLs[r.PathValue("id")]
+	f.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, returnURL, http.StatusSeeOther)
+}
+
+// fakeStripeResources are the API resources the fake server implements
+var fakeStripeResources = []string{
+	"billing_portal/sessions",
+	"billing/meter_events",
+	"checkout/sessions",
+	"customers",
+	"invoices",
+	"prices",
+	"subscriptions",
+}
+
+// splitStripePath splits /v1/<resource>[/<id>] into its resource and ID
+func splitStripePath(path string) (resource, id string, ok bool) {
+	path = strings.TrimPrefix(path, "/v1/")
+	for _, resource := range fakeStripeResources {
+		if path == resource {
+			return resource, "", true
+		}
+		if rest, found := strings.CutPrefix(path, resource+"/"); found && rest != "" && !strings.Contains(rest, "/") {
+			return resource, rest, true
+		}
+	}
+	return "", "", false
+}
+
+func (f *FakeStripeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+FakeStripeSecretKey {
+		writeStripeError(w, http.StatusUnauthorized, "invalid_request_error", "Invalid API Key provided")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeStripeError(w, http.StatusBadRequest, "invalid_request_error", "Invalid request body")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	resource, id, ok := splitStripePath(r.URL.Path)
+	if !ok {
+		writeStripeError(w, http.StatusNotFound, "invalid_request_error", "Unrecognized request URL")
+		return
+	}
+	form := r.Form
+
+	var (
+		body map[string]any
+		err  *fakeStripeError
+	)
+	switch {
+	case resource == "customers" && id == "" && r.Method == http.MethodPost:
+		body = f.createCustomerLocked(form)
+	case resource == "customers" && r.Method == http.MethodGet:
+		body, err = f.getCustomerLocked(id)
+	case resource == "prices" && id == "" && r.Method == http.MethodGet:
+		body = f.listPricesLocked(form)
+	case resource == "prices" && r.Method == http.MethodGet:
+		body, err = f.getPriceLocked(id, form)
+	case resource == "checkout/sessions" && id == "" && r.Method == http.MethodPost:
+		body, err = f.createCheckoutSessionLocked(form)
+	case resource == "checkout/sessions" && r.Method == http.MethodGet:
+		body, err = f.getCheckoutSessionLocked(id)
+	case resource == "billing_portal/sessions" && r.Method == http.MethodPost:
+		body, err = f.createPortalSessionLocked(form)
+	case resource == "subscriptions" && id == "" && r.Method == http.MethodPost:
+		body, err = f.createSubscriptionLocked(form)
+	case resource == "subscriptions" && r.Method == http.MethodGet:
+		body, err = f.getSubscriptionLocked(id)
+	case resource == "subscriptions" && r.Method == http.MethodPost:
+		body, err = f.updateSubscriptionLocked(id, form)
+	case resource == "subscriptions" && r.Method == http.MethodDelete:
+		body, err = f.cancelSubscriptionLocked(id)
+	case resource == "invoices" && id == "upcoming" && r.Method == http.MethodGet:
+		body, err = f.upcomingInvoiceLocked(form)
+	case resource == "invoices" && id == "" && r.Method == http.MethodGet:
+		body = f.listInvoicesLocked(form)
+	case resource == "invoices" && r.Method == http.MethodGet:
+		body, err = f.getInvoiceLocked(id)
+	case resource == "billing/meter_events" && r.Method == http.MethodPost:
+		body = map[string]any{"object": "billing.meter_event", "event_name": form.Get("event_name"), "identifier": form.Get("identifier")}
+	default:
+		err = &fakeStripeError{http.StatusNotFound, "Unrecognized request URL"}
+	}
+
+	if err != nil {
+		writeStripeError(w, err.status, "invalid_request_error", err.message)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+type fakeStripeError struct {
+	status  int
+	message string
+}
+
+func notFound(kind, id string) *fakeStripeError {
+	return &fakeStripeError{http.StatusNotFound, fmt.Sprintf("No such %s: '%s'", kind, id)}
+}
+
+func writeStripeError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"type": errType, "message": message},
+	})
+}
+
+func (f *FakeStripeServer) nextIDLocked(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s_fake%d", prefix, f.seq)
+}
+
+// ---- customers ----
+
+func (f *FakeStripeServer) createCustomerLocked(form map[string][]string) map[string]any {
+	c := &FakeStripeCustomer{
+		ID:       f.nextIDLocked("cus"),
+		Email:    formValue(form, "email"),
+		Name:     formValue(form, "name"),
+		Metadata: formMap(form, "metadata"),
+		Created:  f.now().Unix(),
+	}
+	f.customers[c.ID] = c
+
+	body := customerJSON(c)
+	f.emitLocked("customer.created", body)
+	return body
+}
+
+func (f *FakeStripeServer) getCustomerLocked(id string) (map[string]any, *fakeStripeError) {
+	c, ok := f.customers[id]
+	if !ok {
+		return nil, notFound("customer", id)
+	}
+	return customerJSON(c), nil
+}
+
+func customerJSON(c *FakeStripeCustomer) map[string]any {
+	return map[string]any{
+		"id":       c.ID,
+		"object":   "customer",
+		"email":    c.Email,
+		"name":     c.Name,
+		"metadata": c.Metadata,
+		"created":  c.Created,
+	}
+}
+
+// ---- prices ----
+
+func (f *FakeStripeServer) listPricesLocked(form map[string][]string) map[string]any {
+	ids := make([]string, 0, len(f.prices))
+	for id := range f.prices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	expand := hasExpand(form, "data.product")
+	data := make([]any, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, priceJSON(f.prices[id], expand))
+	}
+	return listJSON("/v1/prices", data)
+}
+
+func (f *FakeStripeServer) getPriceLocked(id string, form map[string][]string) (map[string]any, *fakeStripeError) {
+	p, ok := f.prices[id]
+	if !ok {
+		return nil, notFound("price", id)
+	}
+	return priceJSON(p, hasExpand(form, "product")), nil
+}
+
+func priceJSON(p *FakeStripePrice, expandProduct bool) map[string]any {
+	var product any = p.ProductID
+	if expandProduct {
+		product = map[string]any{"id": p.ProductID, "object": "product", "name": p.ProductID, "active": true}
+	}
+	return map[string]any{
+		"id":          p.ID,
+		"object":      "price",
+		"active":      true,
+		"currency":    p.Currency,
+		"unit_amount": p.UnitAmount,
+		"product":     product,
+		"type":        "recurring",
+		"recurring":   map[string]any{"interval": p.Interval, "interval_count": 1},
+	}
+}
+
+// ---- checkout and portal sessions ----
+
+func (f *FakeStripeServer) createCheckoutSessionLocked(form map[string][]string) (map[string]any, *fakeStripeError) {
+	customerID := formValue(form, "customer")
+	if _, ok := f.customers[customerID]; !ok {
+		return nil, notFound("customer", customerID)
+	}
+	priceID := formValue(form, "line_items[0][price]")
+	if _, ok := f.prices[priceID]; !ok {
+		return nil, notFound("price", priceID)
+	}
+
+	cs := &FakeStripeCheckoutSession{
+		ID:         f.nextIDLocked("cs"),
+		CustomerID: customerID,
+		PriceID:    priceID,
+		Quantity:   formInt(form, "line_items[0][quantity]", 1),
+		TrialDays:  formInt(form, "subscription_data[trial_period_days]", 0),
+		CouponID:   formValue(form, "discounts[0][coupon]"),
+		SuccessURL: formValue(form, "success_url"),
+		CancelURL:  formValue(form, "cancel_url"),
+		Status:     "open",
+		Created:    f.now().Unix(),
+	}
+	cs.SuccessURL = strings.ReplaceAll(cs.SuccessURL, "{CHECKOUT_SESSION_ID}", cs.ID)
+	f.sessions[cs.ID] = cs
+	return f.checkoutSessionJSON(cs), nil
+}
+
+func (f *FakeStripeServer) getCheckoutSessionLocked(id string) (map[string]any, *fakeStripeError) {
+	cs, ok := f.sessions[id]
+	if !ok {
+		return nil, notFound("checkout.session", id)
+	}
+	return f.checkoutSessionJSON(cs), nil
+}
+
+func (f *FakeStripeServer) checkoutSessionJSON(cs *FakeStripeCheckoutSession) map[string]any {
+	body := map[string]any{
+		"id":          cs.ID,
+		"object":      "checkout.session",
+		"mode":        "subscription",
+		"customer":    cs.CustomerID,
+		"status":      cs.Status,
+		"success_url": cs.SuccessURL,
+		"cancel_url":  cs.CancelURL,
+		"url":         f.URL() + "/checkout/" + cs.ID,
+		"created":     cs.Created,
+	}
+	if cs.SubscriptionID != "" {
+		body["subscription"] = cs.SubscriptionID
+		body["payment_status"] = "paid"
+	}
+	return body
+}
+
+func (f *FakeStripeServer) createPortalSessionLocked(form map[string][]string) (map[string]any, *fakeStripeError) {
+	customerID := formValue(form, "customer")
+	if _, ok := f.customers[customerID]; !ok {
+		return nil, notFound("customer", customerID)
+	}
+	id := f.nextIDLocked("bps")
+	f.portalReturnURLs[id] = formValue(form, "return_url")
+	return map[string]any{
+		"id":         id,
+		"object":     "billing_portal.session",
+		"customer":   customerID,
+		"return_url": formValue(form, "return_url"),
+		"url":        f.URL() + "/portal/" + id,
+	}, nil
+}
+
+// ---- subscriptions ----
+
+func (f *FakeStripeServer) createSubscriptionLocked(form map[string][]string) (map[string]any, *fakeStripeError) {
+	customerID := formValue(form, "customer")
+	if _, ok := f.customers[customerID]; !ok {
+		return nil, notFound("customer", customerID)
+	}
+	priceID := formValue(form, "items[0][price]")
+	if _, ok := f.prices[priceID]; !ok {
+		return nil, notFound("price", priceID)
+	}
+
+	sub := f.newSubscriptionLocked(customerID, priceID, formInt(form, "items[0][quantity]", 1), formInt(form, "trial_period_days", 0), formValue(form, "coupon"))
+	body := f.subscriptionJSON(sub)
+	f.emitLocked("customer.subscription.created", body)
+	if sub.TrialEnd == 0 {
+		f.invoiceSubscriptionLocked(sub, true)
+	}
+	return body, nil
+}
+
+func (f *FakeStripeServer) newSubscriptionLocked(customerID, priceID string, quantity, trialDays int64, couponID string) *FakeStripeSubscription {
+	now := f.now()
+	sub := &FakeStripeSubscription{
+		ID:                 f.nextIDLocked("sub"),
+		CustomerID:         customerID,
+		ItemID:             f.nextIDLocked("si"),
+		PriceID:            priceID,
+		Quantity:           quantity,
+		Status:             string(stripe.SubscriptionStatusActive),
+		CurrentPeriodStart: now.Unix(),
+		CurrentPeriodEnd:   f.periodEnd(now, priceID).Unix(),
+		CouponID:           couponID,
+		Created:            now.Unix(),
+	}
+	if trialDays > 0 {
+		trialEnd := now.AddDate(0, 0, int(trialDays)).Unix()
+		sub.Status = string(stripe.SubscriptionStatusTrialing)
+		sub.TrialEnd = trialEnd
+		sub.CurrentPeriodEnd = trialEnd
+	}
+	f.subscriptions[sub.ID] = sub
+	return sub
+}
+
+func (f *FakeStripeServer) periodEnd(start time.Time, priceID string) time.Time {
+	if p, ok := f.prices[priceID]; ok && p.Interval == "year" {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+func (f *FakeStripeServer) getSubscriptionLocked(id string) (map[string]any, *fakeStripeError) {
+	sub, ok := f.subscriptions[id]
+	if !ok {
+		return nil, notFound("subscription", id)
+	}
+	return f.subscriptionJSON(sub), nil
+}
+
+func (f *FakeStripeServer) updateSubscriptionLocked(id string, form map[string][]string) (map[string]any, *fakeStripeError) {
+	sub, ok := f.subscriptions[id]
+	if !ok {
+		return nil, notFound("subscription", id)
+	}
+	if sub.Status == string(stripe.SubscriptionStatusCanceled) {
+		return nil, &fakeStripeError{http.StatusBadRequest, "A canceled subscription can only update its cancellation_details and metadata."}
+	}
+
+	if v := formValue(form, "cancel_at_period_end"); v != "" {
+		sub.CancelAtPeriodEnd = v == "true"
+	}
+	if itemID := formValue(form, "items[0][id]"); itemID != "" && itemID != sub.ItemID {
+		return nil, notFound("subscription_item", itemID)
+	}
+	if priceID := formValue(form, "items[0][price]"); priceID != "" {
+		if _, ok := f.prices[priceID]; !ok {
+			return nil, notFound("price", priceID)
+		}
+		sub.PriceID = priceID
+	}
+	if quantity := formInt(form, "items[0][quantity]", 0); quantity > 0 {
+		sub.Quantity = quantity
+	}
+
+	body := f.subscriptionJSON(sub)
+	f.emitLocked("customer.subscription.updated", body)
+	return body, nil
+}
+
+func (f *FakeStripeServer) cancelSubscriptionLocked(id string) (map[string]any, *fakeStripeError) {
+	sub, ok := f.subscriptions[id]
+	if !ok {
+		return nil, notFound("subscription", id)
+	}
+	sub.Status = string(stripe.SubscriptionStatusCanceled)
+	sub.CanceledAt = f.now().Unix()
+
+	body := f.subscriptionJSON(sub)
+	f.emitLocked("customer.subscription.deleted", body)
+	return body, nil
+}
+
+func (f *FakeStripeServer) subscriptionJSON(sub *FakeStripeSubscription) map[string]any {
+	var price any = sub.PriceID
+	if p, ok := f.prices[sub.PriceID]; ok {
+		price = priceJSON(p, false)
+	}
+	item := map[string]any{
+		"id":       sub.ItemID,
+		"object":   "subscription_item",
+		"price":    price,
+		"quantity": sub.Quantity,
+	}
+
+	body := map[string]any{
+		"id":                   sub.ID,
+		"object":               "subscription",
+		"customer":             sub.CustomerID,
+		"status":               sub.Status,
+		"current_period_start": sub.CurrentPeriodStart,
+		"current_period_end":   sub.CurrentPeriodEnd,
+		"cancel_at_period_end": sub.CancelAtPeriodEnd,
+		"created":              sub.Created,
+		"items":                listJSON("/v1/subscription_items?subscription="+sub.ID, []any{item}),
+	}
+	if sub.TrialEnd > 0 {
+		body["trial_end"] = sub.TrialEnd
+	}
+	if sub.CanceledAt > 0 {
+		body["canceled_at"] = sub.CanceledAt
+	}
+	if sub.CouponID != "" {
+		body["discount"] = map[string]any{
+			"object":       "discount",
+			"coupon":       map[string]any{"id": sub.CouponID, "object": "coupon", "valid": true},
+			"subscription": sub.ID,
+		}
+	}
+	return body
+}
+
+// ---- invoices ----
+
+// invoiceSubscriptionLocked bills the current period of a subscription and
+// queues the invoice events for the payment outcome
+func (f *FakeStripeServer) invoiceSubscriptionLocked(sub *FakeStripeSubscription, paid bool) *FakeStripeInvoice {
+	amount := int64(0)
+	currency := "usd"
+	if p, ok := f.prices[sub.PriceID]; ok && sub.TrialEnd == 0 {
+		amount = p.UnitAmount * sub.Quantity
+		currency = p.Currency
+	}
+
+	inv := &FakeStripeInvoice{
+		ID:             f.nextIDLocked("in"),
+		CustomerID:     sub.CustomerID,
+		SubscriptionID: sub.ID,
+		Status:         "open",
+		Currency:       currency,
+		Amount:         amount,
+		PeriodStart:    sub.CurrentPeriodStart,
+		PeriodEnd:      sub.CurrentPeriodEnd,
+		Created:        f.now().Unix(),
+	}
+	inv.Number = fmt.Sprintf("FAKE-%04d", len(f.invoices)+1)
+	f.invoices[inv.ID] = inv
+
+	f.emitLocked("invoice.finalized", f.invoiceJSON(inv))
+	if paid {
+		inv.Status = "paid"
+		inv.AmountPaid = inv.Amount
+		f.emitLocked("invoice.paid", f.invoiceJSON(inv))
+	} else {
+		f.emitLocked("invoice.payment_failed", f.invoiceJSON(inv))
+	}
+	return inv
+}
+
+func (f *FakeStripeServer) getInvoiceLocked(id string) (map[string]any, *fakeStripeError) {
+	inv, ok := f.invoices[id]
+	if !ok {
+		return nil, notFound("invoice", id)
+	}
+	return f.invoiceJSON(inv), nil
+}
+
+func (f *FakeStripeServer) listInvoicesLocked(form map[string][]string) map[string]any {
+	customerID := formValue(form, "customer")
+	subscriptionID := formValue(form, "subscription")
+
+	invoices := make([]*FakeStripeInvoice, 0, len(f.invoices))
+	for _, inv := range f.invoices {
+		if (customerID == "" || inv.CustomerID == customerID) && (subscriptionID == "" || inv.SubscriptionID == subscriptionID) {
+			invoices = append(invoices, inv)
+		}
+	}
+	// Newest first, like Stripe
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].Number > invoices[j].Number })
+
+	data := make([]any, 0, len(invoices))
+	for _, inv := range invoices {
+		data = append(data, f.invoiceJSON(inv))
+	}
+	return listJSON("/v1/invoices", data)
+}
+
+// upcomingInvoiceLocked previews a price change with simple time-based proration:
+// a credit for the unused part of the current price, a charge for the rest of the
+// period on the new price, and the next full period
+func (f *FakeStripeServer) upcomingInvoiceLocked(form map[string][]string) (map[string]any, *fakeStripeError) {
+	subID := formValue(form, "subscription")
+	sub, ok := f.subscriptions[subID]
+	if !ok {
+		return nil, notFound("subscription", subID)
+	}
+	oldPrice := f.prices[sub.PriceID]
+	newPrice := oldPrice
+	if priceID := formValue(form, "subscription_items[0][price]"); priceID != "" {
+		if newPrice, ok = f.prices[priceID]; !ok {
+			return nil, notFound("price", priceID)
+		}
+	}
+	if oldPrice == nil {
+		return nil, notFound("price", sub.PriceID)
+	}
+
+	prorationDate := formInt(form, "subscription_proration_date", f.now().Unix())
+	period := sub.CurrentPeriodEnd - sub.CurrentPeriodStart
+	remaining := sub.CurrentPeriodEnd - prorationDate
+	if remaining < 0 || period <= 0 {
+		remaining = 0
+	}
+
+	line := func(description string, amount int64, proration bool, start, end int64) any {
+		return map[string]any{
+			"object":      "line_item",
+			"description": description,
+			"amount":      amount,
+			"currency":    newPrice.Currency,
+			"proration":   proration,
+			"period":      map[string]any{"start": start, "end": end},
+		}
+	}
+
+	var lines []any
+	var total int64
+	if newPrice.ID != oldPrice.ID && remaining > 0 {
+		credit := -oldPrice.UnitAmount * sub.Quantity * remaining / period
+		charge := newPrice.UnitAmount * sub.Quantity * remaining / period
+		lines = append(lines,
+			line("Unused time on "+oldPrice.ID, credit, true, prorationDate, sub.CurrentPeriodEnd),
+			line("Remaining time on "+newPrice.ID, charge, true, prorationDate, sub.CurrentPeriodEnd),
+		)
+		total += credit + charge
+	}
+	next := newPrice.UnitAmount * sub.Quantity
+	nextEnd := f.periodEnd(time.Unix(sub.CurrentPeriodEnd, 0), newPrice.ID).Unix()
+	lines = append(lines, line(fmt.Sprintf("%d × %s", sub.Quantity, newPrice.ID), next, false, sub.CurrentPeriodEnd, nextEnd))
+	total += next
+
+	amountDue := total
+	if amountDue < 0 {
+		amountDue = 0
+	}
+	return map[string]any{
+		"object":               "invoice",
+		"customer":             sub.CustomerID,
+		"subscription":         sub.ID,
+		"status":               "draft",
+		"currency":             newPrice.Currency,
+		"subtotal":             total,
+		"total":                total,
+		"amount_due":           amountDue,
+		"next_payment_attempt": sub.CurrentPeriodEnd,
+		"lines":                listJSON("/v1/invoices/upcoming/lines", lines),
+	}, nil
+}
+
+func (f *FakeStripeServer) invoiceJSON(inv *FakeStripeInvoice) map[string]any {
+	return map[string]any{
+		"id":                 inv.ID,
+		"object":             "invoice",
+		"number":             inv.Number,
+		"customer":           inv.CustomerID,
+		"subscription":       inv.SubscriptionID,
+		"status":             inv.Status,
+		"currency":           inv.Currency,
+		"subtotal":           inv.Amount,
+		"total":              inv.Amount,
+		"amount_due":         inv.Amount,
+		"amount_paid":        inv.AmountPaid,
+		"amount_remaining":   inv.Amount - inv.AmountPaid,
+		"period_start":       inv.PeriodStart,
+		"period_end":         inv.PeriodEnd,
+		"created":            inv.Created,
+		"hosted_invoice_url": f.URL() + "/invoices/" + inv.ID,
+		"invoice_pdf":        f.URL() + "/invoices/" + inv.ID + "/pdf",
+	}
+}
+
+// ---- form helpers ----
+
+func listJSON(url string, data []any) map[string]any {
+	return map[string]any{"object": "list", "url": url, "has_more": false, "data": data}
+}
+
+func formValue(form map[string][]string, key string) string {
+	if v := form[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func formInt(form map[string][]string, key string, fallback int64) int64 {
+	n, err := strconv.ParseInt(formValue(form, key), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// formMap collects bracketed keys such as metadata[user_id] into a map
+func formMap(form map[string][]string, prefix string) map[string]string {
+	values := make(map[string]string)
+	for key, v := range form {
+		if strings.HasPrefix(key, prefix+"[") && strings.HasSuffix(key, "]") && len(v) > 0 {
+			values[key[len(prefix)+1:len(key)-1]] = v[0]
+		}
+	}
+	return values
+}
+
+// hasExpand reports whether the request asked to expand field
+func hasExpand(form map[string][]string, field string) bool {
+	for key, values := range form {
+		if key != "expand[]" && !strings.HasPrefix(key, "expand[") {
+			continue
+		}
+		for _, v := range values {
+			if v == field {
+				return true
+			}
+		}
+	}
+	return false
+}
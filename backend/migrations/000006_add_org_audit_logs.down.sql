@@ -0,0 +1,3 @@
+-- Remove organization scoping from audit logs
+DROP INDEX IF EXISTS idx_audit_logs_org_created;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS organization_id;
@@ -0,0 +1,11 @@
+-- Scope audit log entries to an organization for the tenant-facing audit trail.
+-- Audit history must survive organization deletion with its organization_id intact,
+-- so organization_id is intentionally not a foreign key
+ALTER TABLE audit_logs
+ADD COLUMN organization_id INTEGER;
+
+-- Index for cursor-paginated org audit queries (newest first)
+CREATE INDEX idx_audit_logs_org_created ON audit_logs(organization_id, created_at DESC, id DESC)
+    WHERE organization_id IS NOT NULL;
+
+COMMENT ON COLUMN audit_logs.organization_id IS 'Organization the action belongs to; NULL for non-tenant actions';
@@ -0,0 +1,9 @@
+-- Remove organization deletion scheduling
+DROP INDEX IF EXISTS idx_data_exports_organization_id;
+ALTER TABLE data_exports DROP COLUMN IF EXISTS organization_id;
+
+DROP INDEX IF EXISTS idx_organizations_deletion_scheduled;
+ALTER TABLE organizations
+DROP COLUMN IF EXISTS deletion_scheduled_at,
+DROP COLUMN IF EXISTS deletion_requested_by_user_id,
+DROP COLUMN IF EXISTS deletion_requested_at;
@@ -0,0 +1,20 @@
+-- Organization deletion with grace period
+-- Deleting an organization schedules it for removal; owners can restore it until deletion_scheduled_at
+ALTER TABLE organizations
+ADD COLUMN deletion_requested_at TIMESTAMPTZ,
+ADD COLUMN deletion_requested_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN deletion_scheduled_at TIMESTAMPTZ;
+
+-- Index for the purge job to find organizations past their grace period
+CREATE INDEX idx_organizations_deletion_scheduled ON organizations(deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
+
+COMMENT ON COLUMN organizations.deletion_scheduled_at IS 'When the organization will be permanently deleted; NULL if not pending deletion';
+
+-- Organization exports belong to the requesting owner and must outlive the organization,
+-- so organization_id is intentionally not a foreign key
+ALTER TABLE data_exports
+ADD COLUMN organization_id INTEGER;
+
+CREATE INDEX idx_data_exports_organization_id ON data_exports(organization_id)
+    WHERE organization_id IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Remove org-scoped token revocations
+DROP TABLE IF EXISTS org_token_revocations;
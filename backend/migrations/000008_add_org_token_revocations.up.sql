@@ -0,0 +1,14 @@
+-- Revocation cutoffs for org-scoped access tokens (org_id/org_role JWT claims).
+-- Tokens for (user_id, organization_id) issued before revoked_at are rejected.
+CREATE TABLE IF NOT EXISTS org_token_revocations (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    organization_id INTEGER NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reason VARCHAR(50)
+);
+
+CREATE UNIQUE INDEX idx_org_token_revocation ON org_token_revocations(user_id, organization_id);
+CREATE INDEX idx_org_token_revocations_revoked_at ON org_token_revocations(revoked_at);
+
+COMMENT ON TABLE org_token_revocations IS 'Invalidates org-scoped JWTs after role changes or membership removal';
@@ -0,0 +1,2 @@
+-- Remove organization feature flag overrides
+DROP TABLE IF EXISTS organization_feature_flags;
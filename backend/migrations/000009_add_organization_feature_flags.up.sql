@@ -0,0 +1,13 @@
+-- Organization-wide feature flag overrides (applied after user overrides, before role/plan/rollout)
+CREATE TABLE IF NOT EXISTS organization_feature_flags (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    feature_flag_id INTEGER NOT NULL REFERENCES feature_flags(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(organization_id, feature_flag_id)
+);
+
+CREATE INDEX idx_organization_feature_flags_org_id ON organization_feature_flags(organization_id);
+CREATE INDEX idx_organization_feature_flags_flag_id ON organization_feature_flags(feature_flag_id);
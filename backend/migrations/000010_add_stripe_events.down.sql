@@ -0,0 +1,2 @@
+-- Remove durable Stripe webhook event store
+DROP TABLE IF EXISTS stripe_events;
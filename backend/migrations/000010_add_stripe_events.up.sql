@@ -0,0 +1,19 @@
+-- Durable store of verified Stripe webhook events for deduplication, retries and replay
+CREATE TABLE IF NOT EXISTS stripe_events (
+    id SERIAL PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL UNIQUE,
+    event_type VARCHAR(100) NOT NULL,
+    object_id VARCHAR(255),
+    stripe_created_at BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    processed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stripe_events_event_type ON stripe_events(event_type);
+CREATE INDEX idx_stripe_events_status ON stripe_events(status);
+CREATE INDEX idx_stripe_events_object ON stripe_events(object_id, stripe_created_at);
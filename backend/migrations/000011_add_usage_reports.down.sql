@@ -0,0 +1,4 @@
+-- Remove metered usage reconciliation ledger
+ALTER TABLE usage_periods DROP COLUMN IF EXISTS storage_sampled_at;
+ALTER TABLE usage_periods DROP COLUMN IF EXISTS storage_byte_hours;
+DROP TABLE IF EXISTS usage_reports;
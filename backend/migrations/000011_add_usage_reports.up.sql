@@ -0,0 +1,31 @@
+-- Reconciliation ledger of metered usage reported to Stripe
+CREATE TABLE IF NOT EXISTS usage_reports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+    stripe_customer_id VARCHAR(255) NOT NULL,
+    metric VARCHAR(50) NOT NULL,
+    meter_event_name VARCHAR(100) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    quantity BIGINT NOT NULL DEFAULT 0,
+    cumulative_quantity BIGINT NOT NULL DEFAULT 0,
+    measured_value BIGINT NOT NULL DEFAULT 0,
+    measured_at TIMESTAMPTZ,
+    identifier VARCHAR(255) NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    reported_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_usage_reports_user_id ON usage_reports(user_id);
+CREATE INDEX idx_usage_reports_organization_id ON usage_reports(organization_id);
+CREATE INDEX idx_usage_reports_metric ON usage_reports(metric);
+CREATE INDEX idx_usage_reports_period_start ON usage_reports(period_start);
+CREATE INDEX idx_usage_reports_status ON usage_reports(status);
+
+-- Storage integrated over time (byte-hours) for storage GB-hour metering
+ALTER TABLE usage_periods ADD COLUMN IF NOT EXISTS storage_byte_hours BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE usage_periods ADD COLUMN IF NOT EXISTS storage_sampled_at TIMESTAMPTZ;
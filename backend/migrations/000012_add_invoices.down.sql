@@ -0,0 +1,2 @@
+-- Remove invoice mirror
+DROP TABLE IF EXISTS invoices;
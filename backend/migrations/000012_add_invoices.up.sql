@@ -0,0 +1,32 @@
+-- Local mirror of Stripe invoices for billing history
+CREATE TABLE IF NOT EXISTS invoices (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+    stripe_invoice_id VARCHAR(255) NOT NULL UNIQUE,
+    stripe_customer_id VARCHAR(255) NOT NULL,
+    stripe_subscription_id VARCHAR(255),
+    number VARCHAR(100),
+    status VARCHAR(20) NOT NULL,
+    currency VARCHAR(10),
+    subtotal BIGINT NOT NULL DEFAULT 0,
+    total BIGINT NOT NULL DEFAULT 0,
+    amount_due BIGINT NOT NULL DEFAULT 0,
+    amount_paid BIGINT NOT NULL DEFAULT 0,
+    amount_remaining BIGINT NOT NULL DEFAULT 0,
+    period_start TIMESTAMPTZ,
+    period_end TIMESTAMPTZ,
+    hosted_invoice_url TEXT,
+    invoice_pdf TEXT,
+    due_date TIMESTAMPTZ,
+    paid_at TIMESTAMPTZ,
+    issued_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_invoices_user_id ON invoices(user_id);
+CREATE INDEX idx_invoices_organization_id ON invoices(organization_id);
+CREATE INDEX idx_invoices_stripe_customer_id ON invoices(stripe_customer_id);
+CREATE INDEX idx_invoices_status ON invoices(status);
+CREATE INDEX idx_invoices_issued_at ON invoices(issued_at);
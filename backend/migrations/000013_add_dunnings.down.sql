@@ -0,0 +1,2 @@
+-- Remove dunning cases
+DROP TABLE IF EXISTS dunnings;
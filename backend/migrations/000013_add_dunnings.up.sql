@@ -0,0 +1,29 @@
+-- Dunning cases for failed subscription payments
+CREATE TABLE IF NOT EXISTS dunnings (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+    stripe_subscription_id VARCHAR(255) NOT NULL,
+    stripe_invoice_id VARCHAR(255),
+    amount_due BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(10),
+    status VARCHAR(20) NOT NULL,
+    grace_ends_at TIMESTAMPTZ NOT NULL,
+    reminders_sent INTEGER NOT NULL DEFAULT 0,
+    next_reminder_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_dunnings_subscription_id ON dunnings(subscription_id);
+CREATE INDEX idx_dunnings_user_id ON dunnings(user_id);
+CREATE INDEX idx_dunnings_organization_id ON dunnings(organization_id);
+CREATE INDEX idx_dunnings_stripe_subscription_id ON dunnings(stripe_subscription_id);
+CREATE INDEX idx_dunnings_status ON dunnings(status);
+CREATE INDEX idx_dunnings_grace_ends_at ON dunnings(grace_ends_at);
+CREATE INDEX idx_dunnings_next_reminder_at ON dunnings(next_reminder_at);
+
+-- At most one open dunning case per subscription
+CREATE UNIQUE INDEX idx_dunnings_active_subscription ON dunnings(subscription_id) WHERE status = 'active';
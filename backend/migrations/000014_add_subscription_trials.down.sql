@@ -0,0 +1,4 @@
+-- Remove free trial tracking
+ALTER TABLE users DROP COLUMN IF EXISTS trial_used_at;
+DROP INDEX IF EXISTS idx_subscriptions_trial_ends_at;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS trial_ends_at;
@@ -0,0 +1,7 @@
+-- Free trial end date for trialing subscriptions
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS trial_ends_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_trial_ends_at ON subscriptions(trial_ends_at) WHERE status = 'trialing';
+
+-- Trials are once per user, including for organizations they have since deleted
+ALTER TABLE users ADD COLUMN IF NOT EXISTS trial_used_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- Remove the plan catalog overrides
+DROP TABLE IF EXISTS plan_entitlements;
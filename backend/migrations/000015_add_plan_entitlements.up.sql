@@ -0,0 +1,18 @@
+-- Plan catalog overrides: limits, seats and features for each plan
+CREATE TABLE IF NOT EXISTS plan_entitlements (
+    id SERIAL PRIMARY KEY,
+    plan VARCHAR(50) NOT NULL,
+    name VARCHAR(100),
+    rank INTEGER NOT NULL DEFAULT 0,
+    price_ids TEXT[],
+    seats INTEGER NOT NULL DEFAULT 0,
+    api_calls BIGINT NOT NULL DEFAULT 0,
+    storage_bytes BIGINT NOT NULL DEFAULT 0,
+    compute_ms BIGINT NOT NULL DEFAULT 0,
+    file_uploads BIGINT NOT NULL DEFAULT 0,
+    features TEXT[],
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_plan_entitlements_plan ON plan_entitlements(plan);
@@ -0,0 +1,3 @@
+-- Remove seat quantity tracking
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS min_seat_quantity;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS seat_quantity;
@@ -0,0 +1,5 @@
+-- Seats billed on an organization's subscription item, mirrored from Stripe
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS seat_quantity BIGINT NOT NULL DEFAULT 0;
+
+-- Seats the customer set in the billing portal, kept when fewer members are active
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS min_seat_quantity BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+-- Remove coupons and subscription discounts
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS discount_ends_at;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS discount_duration;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS discount_currency;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS discount_amount_off;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS discount_percent_off;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS discount_name;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS discount_code;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS discount_coupon_id;
+
+DROP TABLE IF EXISTS coupons;
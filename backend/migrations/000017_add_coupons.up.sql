@@ -0,0 +1,33 @@
+-- Admin-created coupons redeemable by code at checkout
+CREATE TABLE IF NOT EXISTS coupons (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(100),
+    stripe_coupon_id VARCHAR(255) NOT NULL,
+    percent_off DOUBLE PRECISION NOT NULL DEFAULT 0,
+    amount_off BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3),
+    duration VARCHAR(20) NOT NULL,
+    duration_in_months BIGINT NOT NULL DEFAULT 0,
+    max_redemptions BIGINT NOT NULL DEFAULT 0,
+    times_redeemed BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_coupons_code ON coupons(code);
+CREATE INDEX idx_coupons_stripe_coupon_id ON coupons(stripe_coupon_id);
+CREATE INDEX idx_coupons_active ON coupons(active);
+
+-- Discount applied to a subscription, mirrored from Stripe
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS discount_coupon_id VARCHAR(255);
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS discount_code VARCHAR(100);
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS discount_name VARCHAR(100);
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS discount_percent_off DOUBLE PRECISION;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS discount_amount_off BIGINT;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS discount_currency VARCHAR(3);
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS discount_duration VARCHAR(20);
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS discount_ends_at TIMESTAMPTZ;
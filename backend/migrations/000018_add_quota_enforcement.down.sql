@@ -0,0 +1,4 @@
+-- Remove quota exemptions and per-plan enforcement modes
+DROP TABLE IF EXISTS quota_exemptions;
+ALTER TABLE plan_entitlements DROP COLUMN IF EXISTS soft_limit_percent;
+ALTER TABLE plan_entitlements DROP COLUMN IF EXISTS quota_mode;
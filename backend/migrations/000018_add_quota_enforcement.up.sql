@@ -0,0 +1,18 @@
+-- Per-plan quota enforcement mode, overriding the plan catalog file
+ALTER TABLE plan_entitlements ADD COLUMN IF NOT EXISTS quota_mode VARCHAR(20);
+ALTER TABLE plan_entitlements ADD COLUMN IF NOT EXISTS soft_limit_percent INTEGER NOT NULL DEFAULT 0;
+
+-- Users and organizations exempt from quota enforcement
+CREATE TABLE IF NOT EXISTS quota_exemptions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+    reason TEXT,
+    expires_at TIMESTAMPTZ,
+    created_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT quota_exemptions_one_subject CHECK ((user_id IS NULL) <> (organization_id IS NULL))
+);
+
+CREATE INDEX idx_quota_exemptions_user_id ON quota_exemptions(user_id);
+CREATE INDEX idx_quota_exemptions_organization_id ON quota_exemptions(organization_id);
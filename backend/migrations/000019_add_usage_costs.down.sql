@@ -0,0 +1,2 @@
+-- Remove weighted usage costs
+DROP TABLE IF EXISTS usage_costs;
@@ -0,0 +1,13 @@
+-- Weighted usage costs by route pattern and event type, overriding usage_costs in config.yaml
+CREATE TABLE IF NOT EXISTS usage_costs (
+    id SERIAL PRIMARY KEY,
+    route_pattern VARCHAR(255) NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    event_type VARCHAR(50) NOT NULL DEFAULT 'api_call',
+    cost BIGINT NOT NULL DEFAULT 1 CHECK (cost >= 0),
+    description TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_usage_costs_rule ON usage_costs(route_pattern, method, event_type);
@@ -0,0 +1,40 @@
+-- Move usage events back into a single table
+ALTER TABLE usage_events RENAME TO usage_events_partitioned;
+ALTER SEQUENCE usage_events_id_seq OWNED BY NONE;
+
+CREATE TABLE usage_events (
+    id BIGINT PRIMARY KEY DEFAULT nextval('usage_events_id_seq'),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+    event_type VARCHAR(50) NOT NULL,
+    resource VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL DEFAULT 1,
+    unit VARCHAR(20) NOT NULL DEFAULT 'count',
+    metadata JSONB DEFAULT '{}',
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    billing_period_start DATE NOT NULL,
+    billing_period_end DATE NOT NULL
+);
+
+INSERT INTO usage_events
+SELECT id, created_at, user_id, organization_id, event_type, resource, quantity, unit,
+       metadata, ip_address, user_agent, billing_period_start, billing_period_end
+FROM usage_events_partitioned;
+
+DROP TABLE usage_events_partitioned;
+ALTER SEQUENCE usage_events_id_seq OWNED BY usage_events.id;
+
+CREATE INDEX idx_usage_events_user_id ON usage_events(user_id);
+CREATE INDEX idx_usage_events_org_id ON usage_events(organization_id);
+CREATE INDEX idx_usage_events_event_type ON usage_events(event_type);
+CREATE INDEX idx_usage_events_created_at ON usage_events(created_at);
+CREATE INDEX idx_usage_events_billing_period ON usage_events(billing_period_start, billing_period_end);
+CREATE INDEX idx_usage_events_user_period ON usage_events(user_id, billing_period_start, billing_period_end);
+
+DROP FUNCTION IF EXISTS create_usage_events_partition(DATE);
+
+-- Drop usage rollups
+DROP TABLE IF EXISTS usage_rollups_daily;
+DROP TABLE IF EXISTS usage_rollups_hourly;
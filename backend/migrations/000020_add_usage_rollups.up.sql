@@ -0,0 +1,123 @@
+-- Usage rollups: usage aggregated per hour and per day, rebuilt from usage_events by the
+-- usage rollup job. Daily rollups outlive the raw events and hourly rollups.
+CREATE TABLE IF NOT EXISTS usage_rollups_hourly (
+    id BIGSERIAL PRIMARY KEY,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+    event_type VARCHAR(50) NOT NULL,
+    resource VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL DEFAULT 0,
+    event_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_usage_rollups_hourly_bucket ON usage_rollups_hourly(bucket_start);
+CREATE INDEX idx_usage_rollups_hourly_user ON usage_rollups_hourly(user_id, bucket_start);
+CREATE INDEX idx_usage_rollups_hourly_org ON usage_rollups_hourly(organization_id, bucket_start);
+
+CREATE TABLE IF NOT EXISTS usage_rollups_daily (
+    id BIGSERIAL PRIMARY KEY,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+    event_type VARCHAR(50) NOT NULL,
+    resource VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL DEFAULT 0,
+    event_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_usage_rollups_daily_bucket ON usage_rollups_daily(bucket_start);
+CREATE INDEX idx_usage_rollups_daily_user ON usage_rollups_daily(user_id, bucket_start);
+CREATE INDEX idx_usage_rollups_daily_org ON usage_rollups_daily(organization_id, bucket_start);
+
+-- Partition usage_events by month so expired events are dropped a partition at a time.
+-- The primary key must include the partition key.
+ALTER TABLE usage_events RENAME TO usage_events_unpartitioned;
+ALTER SEQUENCE usage_events_id_seq OWNED BY NONE;
+
+CREATE TABLE usage_events (
+    id BIGINT NOT NULL DEFAULT nextval('usage_events_id_seq'),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+    event_type VARCHAR(50) NOT NULL,
+    resource VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL DEFAULT 1,
+    unit VARCHAR(20) NOT NULL DEFAULT 'count',
+    metadata JSONB DEFAULT '{}',
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    billing_period_start DATE NOT NULL,
+    billing_period_end DATE NOT NULL,
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+-- Creates the partition holding one month of usage events, named usage_events_pYYYYMM.
+-- Called by the usage retention job to keep partitions ahead of the current month.
+-- Postgres refuses to add a partition while the default partition holds rows in its range,
+-- so the month's events are moved out of the default partition into the new table before
+-- it is attached, all in the caller's transaction.
+CREATE OR REPLACE FUNCTION create_usage_events_partition(month_start DATE)
+RETURNS VOID AS $$
+DECLARE
+    from_date DATE := date_trunc('month', month_start)::DATE;
+    to_date DATE := (date_trunc('month', month_start) + INTERVAL '1 month')::DATE;
+    partition_name TEXT := 'usage_events_p' || to_char(from_date, 'YYYYMM');
+BEGIN
+    -- Serialize concurrent runs so only one of them creates the partition
+    PERFORM pg_advisory_xact_lock(hashtext('create_usage_events_partition'));
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('CREATE TABLE %I (LIKE usage_events INCLUDING DEFAULTS)', partition_name);
+
+    IF to_regclass('usage_events_default') IS NOT NULL THEN
+        EXECUTE format(
+            'WITH moved AS (
+                DELETE FROM usage_events_default WHERE created_at >= %L AND created_at < %L RETURNING *
+            )
+            INSERT INTO %I SELECT * FROM moved',
+            from_date, to_date, partition_name
+        );
+    END IF;
+
+    EXECUTE format(
+        'ALTER TABLE usage_events ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+        partition_name, from_date, to_date
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Partitions from the oldest existing event through three months ahead
+DO $$
+DECLARE
+    month_start DATE := date_trunc('month', COALESCE(
+        (SELECT MIN(created_at) FROM usage_events_unpartitioned), NOW()
+    ))::DATE;
+BEGIN
+    WHILE month_start <= date_trunc('month', NOW() + INTERVAL '3 months')::DATE LOOP
+        PERFORM create_usage_events_partition(month_start);
+        month_start := (month_start + INTERVAL '1 month')::DATE;
+    END LOOP;
+END;
+$$;
+
+-- Catches events outside the created partitions, e.g. if the retention job stops running.
+-- create_usage_events_partition moves them out when their month's partition is created.
+CREATE TABLE usage_events_default PARTITION OF usage_events DEFAULT;
+
+INSERT INTO usage_events
+SELECT id, created_at, user_id, organization_id, event_type, resource, quantity, unit,
+       metadata, ip_address, user_agent, billing_period_start, billing_period_end
+FROM usage_events_unpartitioned;
+
+DROP TABLE usage_events_unpartitioned;
+ALTER SEQUENCE usage_events_id_seq OWNED BY usage_events.id;
+
+CREATE INDEX idx_usage_events_user_id ON usage_events(user_id);
+CREATE INDEX idx_usage_events_org_id ON usage_events(organization_id);
+CREATE INDEX idx_usage_events_event_type ON usage_events(event_type);
+CREATE INDEX idx_usage_events_created_at ON usage_events(created_at);
+CREATE INDEX idx_usage_events_billing_period ON usage_events(billing_period_start, billing_period_end);
+CREATE INDEX idx_usage_events_user_period ON usage_events(user_id, billing_period_start, billing_period_end);
@@ -0,0 +1,2 @@
+-- Remove usage alert delivery preferences
+DROP TABLE IF EXISTS usage_alert_preferences;
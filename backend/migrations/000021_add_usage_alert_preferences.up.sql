@@ -0,0 +1,26 @@
+-- Usage alert delivery preferences per user or organization (email thresholds and org webhooks)
+CREATE TABLE IF NOT EXISTS usage_alert_preferences (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+
+    -- Email delivery and the thresholds that trigger a notification
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    threshold_80 BOOLEAN NOT NULL DEFAULT TRUE,
+    threshold_90 BOOLEAN NOT NULL DEFAULT TRUE,
+    threshold_100 BOOLEAN NOT NULL DEFAULT TRUE,
+
+    -- Webhook endpoint, organizations only; payloads are signed with webhook_secret
+    webhook_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    webhook_url VARCHAR(2048),
+    webhook_secret VARCHAR(255),
+
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT usage_alert_preferences_owner CHECK ((user_id IS NULL) <> (organization_id IS NULL)),
+    CONSTRAINT usage_alert_preferences_webhook_org CHECK (NOT webhook_enabled OR organization_id IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX idx_usage_alert_preferences_user ON usage_alert_preferences(user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_usage_alert_preferences_org ON usage_alert_preferences(organization_id) WHERE organization_id IS NOT NULL;
@@ -0,0 +1,6 @@
+-- Remove AI token limits and budgets
+DROP INDEX IF EXISTS idx_usage_events_ai_tokens;
+
+ALTER TABLE organizations DROP COLUMN IF EXISTS ai_token_budget;
+
+ALTER TABLE plan_entitlements DROP COLUMN IF EXISTS ai_tokens;
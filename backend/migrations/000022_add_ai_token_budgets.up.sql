@@ -0,0 +1,10 @@
+-- AI token limits per plan and monthly AI token budgets per organization
+ALTER TABLE plan_entitlements ADD COLUMN IF NOT EXISTS ai_tokens BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS ai_token_budget BIGINT NOT NULL DEFAULT 0
+    CHECK (ai_token_budget >= 0);
+
+-- Per-model AI usage reads token events of the current period
+CREATE INDEX IF NOT EXISTS idx_usage_events_ai_tokens
+    ON usage_events(billing_period_start, resource)
+    WHERE event_type = 'ai_tokens';
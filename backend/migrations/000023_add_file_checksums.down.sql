@@ -0,0 +1,2 @@
+-- Remove file checksums
+ALTER TABLE files DROP COLUMN IF EXISTS checksum;
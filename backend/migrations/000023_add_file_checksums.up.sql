@@ -0,0 +1,2 @@
+-- SHA-256 checksums of file content, computed while uploads stream and served as ETags
+ALTER TABLE files ADD COLUMN IF NOT EXISTS checksum VARCHAR(64);
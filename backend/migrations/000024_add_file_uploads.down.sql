@@ -0,0 +1,3 @@
+-- Remove resumable uploads
+DROP TABLE IF EXISTS file_upload_chunks;
+DROP TABLE IF EXISTS file_uploads;
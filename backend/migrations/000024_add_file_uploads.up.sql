@@ -0,0 +1,33 @@
+-- Resumable uploads (tus protocol) and their content until the upload completes
+CREATE TABLE IF NOT EXISTS file_uploads (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255),
+
+    -- Total size and the bytes received so far
+    length BIGINT NOT NULL CHECK (length >= 0),
+    upload_offset BIGINT NOT NULL DEFAULT 0 CHECK (upload_offset >= 0 AND upload_offset <= length),
+    metadata TEXT,
+
+    -- Set once the upload is assembled into a file
+    file_id INTEGER REFERENCES files(id) ON DELETE SET NULL,
+    -- Set while a request assembles the completed upload, so only one does
+    assembling_at TIMESTAMPTZ,
+
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_file_uploads_user_id ON file_uploads(user_id);
+CREATE INDEX idx_file_uploads_expires_at ON file_uploads(expires_at);
+
+CREATE TABLE IF NOT EXISTS file_upload_chunks (
+    id BIGSERIAL PRIMARY KEY,
+    upload_id VARCHAR(36) NOT NULL REFERENCES file_uploads(id) ON DELETE CASCADE,
+    chunk_offset BIGINT NOT NULL,
+    data BYTEA NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_file_upload_chunks_offset ON file_upload_chunks(upload_id, chunk_offset);
@@ -0,0 +1,10 @@
+-- Move chunked content back inline
+UPDATE files f SET content = c.content
+FROM (
+    SELECT file_id, string_agg(data, ''::bytea ORDER BY chunk_offset) AS content
+    FROM file_chunks
+    GROUP BY file_id
+) c
+WHERE f.id = c.file_id;
+
+DROP TABLE IF EXISTS file_chunks;
@@ -0,0 +1,20 @@
+-- Database-stored file content in 1 MiB rows, so uploads append rows instead of rewriting
+-- one growing bytea and downloads read a row instead of decompressing the whole value
+CREATE TABLE IF NOT EXISTS file_chunks (
+    file_id INTEGER NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    chunk_offset BIGINT NOT NULL,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (file_id, chunk_offset)
+);
+
+-- Uploads are mostly already compressed; store chunks out of line without recompressing
+ALTER TABLE file_chunks ALTER COLUMN data SET STORAGE EXTERNAL;
+
+-- Move existing inline content into chunks
+INSERT INTO file_chunks (file_id, chunk_offset, data)
+SELECT f.id, o.chunk_offset, substring(f.content FROM o.chunk_offset + 1 FOR 1048576)
+FROM files f
+CROSS JOIN LATERAL generate_series(0, octet_length(f.content) - 1, 1048576) AS o(chunk_offset)
+WHERE f.storage_type = 'database' AND octet_length(f.content) > 0;
+
+UPDATE files SET content = NULL WHERE storage_type = 'database' AND content IS NOT NULL;